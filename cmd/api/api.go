@@ -5,26 +5,39 @@ import (
 	"time"
 
 	"github.com/dudakovict/gotify/cmd/api/authgrp"
+	"github.com/dudakovict/gotify/cmd/api/groupgrp"
+	"github.com/dudakovict/gotify/cmd/api/keygrp"
 	"github.com/dudakovict/gotify/cmd/api/ntfgrp"
+	"github.com/dudakovict/gotify/cmd/api/rolegrp"
 	"github.com/dudakovict/gotify/cmd/api/subgrp"
 	"github.com/dudakovict/gotify/cmd/api/topicgrp"
 	"github.com/dudakovict/gotify/cmd/api/usergrp"
 	"github.com/dudakovict/gotify/cmd/api/vrfgrp"
+	"github.com/dudakovict/gotify/internal/core/sso"
+	"github.com/dudakovict/gotify/internal/stores"
 	"github.com/dudakovict/gotify/internal/worker"
+	"github.com/dudakovict/gotify/pkg/encrypt"
 	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/dudakovict/gotify/pkg/signer"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/fiber/v2"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
 // Config contains the configuration for setting up the API routes.
 type Config struct {
 	Log                  *zerolog.Logger
-	DB                   *sqlx.DB
+	Stores               stores.Stores
 	Maker                maker.Maker
 	TaskDistributor      worker.TaskDistributor
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
+	MFAEncrypter         *encrypt.Encrypter
+	MFAIssuer            string
+	MFAEnforce           bool
+	WebAuthn             *webauthn.WebAuthn
+	SSOProvider          *sso.Provider
+	UnsubscribeSigner    *signer.Signer
 }
 
 // Routes sets up the API routes for the application.
@@ -33,40 +46,67 @@ func Routes(app *fiber.App, cfg Config) {
 
 	authgrp.Routes(api, authgrp.Config{
 		Log:                  cfg.Log,
-		DB:                   cfg.DB,
+		Stores:               cfg.Stores,
 		Maker:                cfg.Maker,
 		TaskDistributor:      cfg.TaskDistributor,
 		AccessTokenDuration:  cfg.AccessTokenDuration,
 		RefreshTokenDuration: cfg.RefreshTokenDuration,
+		MFAEncrypter:         cfg.MFAEncrypter,
+		MFAIssuer:            cfg.MFAIssuer,
+		MFAEnforce:           cfg.MFAEnforce,
+		WebAuthn:             cfg.WebAuthn,
+		SSOProvider:          cfg.SSOProvider,
+	})
+
+	groupgrp.Routes(api, groupgrp.Config{
+		Log:    cfg.Log,
+		Stores: cfg.Stores,
+		Maker:  cfg.Maker,
+	})
+
+	keygrp.Routes(api, keygrp.Config{
+		Log:    cfg.Log,
+		Stores: cfg.Stores,
+		Maker:  cfg.Maker,
 	})
 
 	ntfgrp.Routes(api, ntfgrp.Config{
 		Log:             cfg.Log,
-		DB:              cfg.DB,
+		Stores:          cfg.Stores,
 		Maker:           cfg.Maker,
 		TaskDistributor: cfg.TaskDistributor,
 	})
 
+	rolegrp.Routes(api, rolegrp.Config{
+		Log:    cfg.Log,
+		Stores: cfg.Stores,
+		Maker:  cfg.Maker,
+	})
+
 	subgrp.Routes(api, subgrp.Config{
-		Log:   cfg.Log,
-		DB:    cfg.DB,
-		Maker: cfg.Maker,
+		Log:             cfg.Log,
+		Stores:          cfg.Stores,
+		Maker:           cfg.Maker,
+		TaskDistributor: cfg.TaskDistributor,
+		Signer:          cfg.UnsubscribeSigner,
 	})
 
 	topicgrp.Routes(api, topicgrp.Config{
-		Log:   cfg.Log,
-		DB:    cfg.DB,
-		Maker: cfg.Maker,
+		Log:             cfg.Log,
+		Stores:          cfg.Stores,
+		Maker:           cfg.Maker,
+		TaskDistributor: cfg.TaskDistributor,
 	})
 
 	usergrp.Routes(api, usergrp.Config{
-		Log:   cfg.Log,
-		DB:    cfg.DB,
-		Maker: cfg.Maker,
+		Log:             cfg.Log,
+		Stores:          cfg.Stores,
+		Maker:           cfg.Maker,
+		TaskDistributor: cfg.TaskDistributor,
 	})
 
 	vrfgrp.Routes(api, vrfgrp.Config{
-		Log: cfg.Log,
-		DB:  cfg.DB,
+		Log:    cfg.Log,
+		Stores: cfg.Stores,
 	})
 }
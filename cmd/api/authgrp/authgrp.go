@@ -2,37 +2,64 @@
 package authgrp
 
 import (
-	"context"
+	"encoding/json"
 	"errors"
+	"slices"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/mfa"
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	"github.com/dudakovict/gotify/internal/core/role"
 	"github.com/dudakovict/gotify/internal/core/session"
+	"github.com/dudakovict/gotify/internal/core/sso"
 	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/dudakovict/gotify/pkg/mid"
 	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 )
 
+// Set of pseudo roles carried by the short lived tokens issued during a two
+// step login. They are never granted to users so these tokens can't be used
+// to access any other route.
+const (
+	mfaChallengeRole = "MFA_CHALLENGE"
+	mfaEnrollRole    = "MFA_ENROLL"
+)
+
+const mfaChallengeDuration = 5 * time.Minute
+
 type handlers struct {
 	user                 *user.Core
 	session              *session.Core
+	mfa                  *mfa.Core
+	passkey              *passkey.Core
+	sso                  *sso.Core
+	role                 *role.Core
 	maker                maker.Maker
 	taskDistributor      worker.TaskDistributor
 	accessTokenDuration  time.Duration
 	refreshTokenDuration time.Duration
+	mfaEnforce           bool
 }
 
-func new(user *user.Core, session *session.Core, maker maker.Maker, taskDistributor worker.TaskDistributor, accessTokenDuration time.Duration, refreshTokenDuration time.Duration) *handlers {
+func new(user *user.Core, session *session.Core, mfa *mfa.Core, passkey *passkey.Core, sso *sso.Core, role *role.Core, maker maker.Maker, taskDistributor worker.TaskDistributor, accessTokenDuration time.Duration, refreshTokenDuration time.Duration, mfaEnforce bool) *handlers {
 	return &handlers{
 		user:                 user,
 		session:              session,
+		mfa:                  mfa,
+		passkey:              passkey,
+		sso:                  sso,
+		role:                 role,
 		maker:                maker,
 		taskDistributor:      taskDistributor,
 		accessTokenDuration:  accessTokenDuration,
 		refreshTokenDuration: refreshTokenDuration,
+		mfaEnforce:           mfaEnforce,
 	}
 }
 
@@ -102,11 +129,11 @@ func (h *handlers) register(c *fiber.Ctx) error {
 				asynq.Queue(worker.QueueCritical),
 			}
 
-			return h.taskDistributor.DistributeTaskSendVerifyEmail(context.Background(), taskPayload, opts...)
+			return h.taskDistributor.DistributeTaskSendVerifyEmail(c.UserContext(), taskPayload, opts...)
 		},
 	}
 
-	usr, err := h.user.CreateTx(nuTx)
+	usr, err := h.user.CreateTx(c.UserContext(), nuTx)
 	if err != nil {
 		if errors.Is(err, user.ErrUniqueEmail) {
 			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
@@ -132,12 +159,14 @@ type loginResponse struct {
 }
 
 // @Summary Login user
-// @Description Logs in a user with the provided email and password.
+// @Description Logs in a user with the provided email and password. Users with
+// @Description two-factor authentication receive an MFA challenge instead of tokens.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param body body loginRequest true "Login Request Body"
 // @Success 200 {object} loginResponse
+// @Success 202 {object} mfaChallengeResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
@@ -153,7 +182,7 @@ func (h *handlers) login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	usr, err := h.user.Authenticate(req.Email, req.Password)
+	usr, err := h.user.Authenticate(c.UserContext(), req.Email, req.Password)
 	if err != nil {
 		switch {
 		case errors.Is(err, user.ErrNotFound):
@@ -165,22 +194,99 @@ func (h *handlers) login(c *fiber.Ctx) error {
 		}
 	}
 
-	roles := make([]string, len(usr.Roles))
-	for i, role := range usr.Roles {
-		roles[i] = role.Name()
+	return h.authenticated(c, usr, false)
+}
+
+// authenticated completes a login once the user has proven who they are.
+// Users with mfa enabled get an MFA challenge in place of the session tokens,
+// unless the login was multi-factor already, and users who are required to
+// use mfa but haven't enrolled can only enroll.
+func (h *handlers) authenticated(c *fiber.Ctx, usr user.User, multiFactor bool) error {
+	enabled, err := h.mfa.IsEnabled(c.UserContext(), usr.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	accessToken, accessPayload, err := h.maker.CreateToken(usr.ID, roles, h.accessTokenDuration)
+	if enabled && !multiFactor {
+		return h.mfaChallenge(c, usr, mfaChallengeRole)
+	}
+
+	if !enabled {
+		required, err := h.mfaRequired(c, usr)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+
+		if required {
+			return h.mfaChallenge(c, usr, mfaEnrollRole)
+		}
+	}
+
+	rsp, err := h.newSession(c, usr)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	refreshToken, refreshPayload, err := h.maker.CreateToken(usr.ID, roles, h.refreshTokenDuration)
+	return c.Status(fiber.StatusOK).JSON(rsp)
+}
+
+type mfaChallengeResponse struct {
+	MFARequired             bool      `json:"mfa_required"`
+	EnrollmentRequired      bool      `json:"enrollment_required"`
+	ChallengeToken          string    `json:"challenge_token"`
+	ChallengeTokenExpiresAt time.Time `json:"challenge_token_expires_at"`
+}
+
+// mfaChallenge responds with a short lived token in place of the session
+// tokens. With mfaChallengeRole the token is exchanged for a session at
+// /login/mfa, with mfaEnrollRole it can only be used to enroll.
+func (h *handlers) mfaChallenge(c *fiber.Ctx, usr user.User, role string) error {
+	token, payload, err := h.maker.CreateToken(usr.ID, []string{role}, mfaChallengeDuration)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	session, err := h.session.Create(session.Session{
+	rsp := mfaChallengeResponse{
+		MFARequired:             true,
+		EnrollmentRequired:      role == mfaEnrollRole,
+		ChallengeToken:          token,
+		ChallengeTokenExpiresAt: payload.ExpiresAt,
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(rsp)
+}
+
+// mfaRequired reports whether the user has to use mfa. When it is enforced
+// this is up to the roles of the user, which require it by granting the
+// mfa:required permission.
+func (h *handlers) mfaRequired(c *fiber.Ctx, usr user.User) (bool, error) {
+	if !h.mfaEnforce {
+		return false, nil
+	}
+
+	perms, err := h.role.Permissions(c.UserContext(), roleNames(usr))
+	if err != nil {
+		return false, err
+	}
+
+	return perms.Has(role.PermMFARequired), nil
+}
+
+// newSession creates a session for the user and the tokens that go with it.
+func (h *handlers) newSession(c *fiber.Ctx, usr user.User) (loginResponse, error) {
+	roles := roleNames(usr)
+
+	accessToken, accessPayload, err := h.maker.CreateToken(usr.ID, roles, h.accessTokenDuration)
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	refreshToken, refreshPayload, err := h.maker.CreateToken(usr.ID, roles, h.refreshTokenDuration)
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	session, err := h.session.Create(c.UserContext(), session.Session{
 		ID:           refreshPayload.ID,
 		UserID:       usr.ID,
 		RefreshToken: refreshToken,
@@ -190,7 +296,7 @@ func (h *handlers) login(c *fiber.Ctx) error {
 		ExpiresAt:    refreshPayload.ExpiresAt,
 	})
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		return loginResponse{}, err
 	}
 
 	rsp := loginResponse{
@@ -202,6 +308,82 @@ func (h *handlers) login(c *fiber.Ctx) error {
 		User:                  newUserResponse(usr),
 	}
 
+	return rsp, nil
+}
+
+// roleNames returns the names of the roles of the user.
+func roleNames(usr user.User) []string {
+	names := make([]string, len(usr.Roles))
+	for i, r := range usr.Roles {
+		names[i] = r.Name()
+	}
+
+	return names
+}
+
+type loginMFARequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code"`
+}
+
+// @Summary Complete a two-step login
+// @Description Exchanges an MFA challenge token and a TOTP or recovery code for session tokens.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body loginMFARequest true "Login MFA Request Body"
+// @Success 200 {object} loginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/mfa [post]
+func (h *handlers) loginMFA(c *fiber.Ctx) error {
+	var req loginMFARequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	payload, err := h.maker.VerifyToken(req.ChallengeToken)
+	if err != nil || !slices.Equal(payload.Roles, []string{mfaChallengeRole}) {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthenticationFailure))
+	}
+
+	usr, err := h.user.QueryByID(c.UserContext(), payload.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	if req.Code != "" {
+		err = h.mfa.Verify(c.UserContext(), usr.ID, req.Code)
+	} else {
+		err = h.mfa.Recover(c.UserContext(), usr.ID, req.RecoveryCode)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, mfa.ErrInvalidCode), errors.Is(err, mfa.ErrNotEnabled):
+			return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthenticationFailure))
+		case errors.Is(err, mfa.ErrLocked):
+			return c.Status(fiber.StatusTooManyRequests).JSON(errorResponse(mfa.ErrLocked))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	rsp, err := h.newSession(c, usr)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
 	return c.Status(fiber.StatusOK).JSON(rsp)
 }
 
@@ -241,7 +423,7 @@ func (h *handlers) token(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	sessn, err := h.session.QueryByID(refreshPayload.ID)
+	sessn, err := h.session.QueryByID(c.UserContext(), refreshPayload.ID)
 	if err != nil {
 		if errors.Is(err, session.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
@@ -265,6 +447,15 @@ func (h *handlers) token(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(errors.New("session is expired")))
 	}
 
+	// Sessions outlive the deletion of their user, so they can be used again
+	// once the user is restored.
+	if _, err := h.user.QueryByID(c.UserContext(), sessn.UserID); err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
 	accessToken, accessPayload, err := h.maker.CreateToken(refreshPayload.UserID, refreshPayload.Roles, h.accessTokenDuration)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
@@ -277,3 +468,525 @@ func (h *handlers) token(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(rsp)
 }
+
+type mfaEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// @Summary Enroll in two-factor authentication
+// @Description Generates a new TOTP secret and provisioning URI for the authenticated user.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} mfaEnrollResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /mfa/enroll [post]
+func (h *handlers) mfaEnroll(c *fiber.Ctx) error {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	enr, err := h.mfa.Enroll(c.UserContext(), usr)
+	if err != nil {
+		if errors.Is(err, mfa.ErrAlreadyEnabled) {
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	rsp := mfaEnrollResponse{
+		Secret: enr.Secret,
+		URI:    enr.URI,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rsp)
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type mfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// @Summary Confirm two-factor authentication
+// @Description Enables two-factor authentication once a code from the enrolled authenticator is provided.
+// @Description Returns one-time recovery codes which are only shown once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body mfaCodeRequest true "MFA Code Request Body"
+// @Success 200 {object} mfaRecoveryCodesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /mfa/confirm [post]
+func (h *handlers) mfaConfirm(c *fiber.Ctx) error {
+	var req mfaCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	codes, err := h.mfa.Confirm(c.UserContext(), usr.ID, req.Code)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(mfaRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary Regenerate recovery codes
+// @Description Invalidates all recovery codes of the authenticated user and returns a new set.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body mfaCodeRequest true "MFA Code Request Body"
+// @Success 200 {object} mfaRecoveryCodesResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /mfa/recovery-codes [post]
+func (h *handlers) mfaRecoveryCodes(c *fiber.Ctx) error {
+	var req mfaCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	codes, err := h.mfa.RegenerateRecoveryCodes(c.UserContext(), usr.ID, req.Code)
+	if err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(mfaRecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// @Summary Disable two-factor authentication
+// @Description Disables two-factor authentication for the authenticated user.
+// @Tags Auth
+// @Accept json
+// @Param body body mfaCodeRequest true "MFA Code Request Body"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /mfa [delete]
+func (h *handlers) mfaDisable(c *fiber.Ctx) error {
+	var req mfaCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	required, err := h.mfaRequired(c, usr)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	if required {
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(errors.New("mfa is required by the roles of the user")))
+	}
+
+	if err := h.mfa.Disable(c.UserContext(), usr.ID, req.Code); err != nil {
+		return mfaErrorResponse(c, err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func mfaErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(err))
+	case errors.Is(err, mfa.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+	case errors.Is(err, mfa.ErrAlreadyEnabled), errors.Is(err, mfa.ErrNotEnabled):
+		return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+}
+
+type passkeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func newPasskeyResponse(pk passkey.Passkey) passkeyResponse {
+	rsp := passkeyResponse{
+		ID:         pk.ID,
+		Name:       pk.Name,
+		Transports: pk.Transports,
+		CreatedAt:  pk.CreatedAt,
+	}
+
+	if !pk.LastUsedAt.IsZero() {
+		rsp.LastUsedAt = &pk.LastUsedAt
+	}
+
+	return rsp
+}
+
+type passkeyRegisterBeginResponse struct {
+	CeremonyID uuid.UUID                    `json:"ceremony_id"`
+	Options    *protocol.CredentialCreation `json:"options" swaggertype:"object"`
+}
+
+// @Summary Begin passkey registration
+// @Description Starts a WebAuthn registration ceremony for the authenticated user.
+// @Description The options are passed to navigator.credentials.create().
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} passkeyRegisterBeginResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /passkeys/register/begin [post]
+func (h *handlers) passkeyRegisterBegin(c *fiber.Ctx) error {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	options, ceremonyID, err := h.passkey.BeginRegistration(c.UserContext(), usr)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	rsp := passkeyRegisterBeginResponse{
+		CeremonyID: ceremonyID,
+		Options:    options,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rsp)
+}
+
+type passkeyRegisterFinishRequest struct {
+	CeremonyID uuid.UUID       `json:"ceremony_id" validate:"required"`
+	Name       string          `json:"name" validate:"required,max=64"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object" validate:"required"`
+}
+
+// @Summary Finish passkey registration
+// @Description Verifies the authenticator response and stores the new passkey.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body passkeyRegisterFinishRequest true "Passkey Register Finish Request Body"
+// @Success 201 {object} passkeyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /passkeys/register/finish [post]
+func (h *handlers) passkeyRegisterFinish(c *fiber.Ctx) error {
+	var req passkeyRegisterFinishRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	pk, err := h.passkey.FinishRegistration(c.UserContext(), usr, req.CeremonyID, req.Name, req.Credential)
+	if err != nil {
+		return passkeyErrorResponse(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newPasskeyResponse(pk))
+}
+
+// @Summary Get passkeys
+// @Description Retrieves the passkeys registered by the authenticated user.
+// @Tags Auth
+// @Produce json
+// @Success 200 {array} passkeyResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /passkeys [get]
+func (h *handlers) passkeyQuery(c *fiber.Ctx) error {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	pks, err := h.passkey.QueryByUserID(c.UserContext(), usr.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	rsp := make([]passkeyResponse, len(pks))
+	for i, pk := range pks {
+		rsp[i] = newPasskeyResponse(pk)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rsp)
+}
+
+// @Summary Delete a passkey
+// @Description Deletes a passkey of the authenticated user.
+// @Tags Auth
+// @Param id path string true "Passkey ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /passkeys/{id} [delete]
+func (h *handlers) passkeyDelete(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	pk, err := h.passkey.QueryByID(c.UserContext(), id)
+	if err != nil {
+		return passkeyErrorResponse(c, err)
+	}
+
+	// Passkeys of other users are reported as missing so that their IDs
+	// can't be probed.
+	if pk.UserID != usr.ID {
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(passkey.ErrNotFound))
+	}
+
+	if err := h.passkey.Delete(c.UserContext(), pk); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type loginPasskeyBeginRequest struct {
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+type loginPasskeyBeginResponse struct {
+	CeremonyID uuid.UUID                     `json:"ceremony_id"`
+	Options    *protocol.CredentialAssertion `json:"options" swaggertype:"object"`
+}
+
+// @Summary Begin passkey login
+// @Description Starts a WebAuthn login ceremony. Without an email the authenticator
+// @Description offers the discoverable credentials it holds for this site.
+// @Description The options are passed to navigator.credentials.get().
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body loginPasskeyBeginRequest false "Login Passkey Begin Request Body"
+// @Success 200 {object} loginPasskeyBeginResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/passkey/begin [post]
+func (h *handlers) loginPasskeyBegin(c *fiber.Ctx) error {
+	var req loginPasskeyBeginRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	options, ceremonyID, err := h.passkey.BeginLogin(c.UserContext(), req.Email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return passkeyErrorResponse(c, err)
+	}
+
+	rsp := loginPasskeyBeginResponse{
+		CeremonyID: ceremonyID,
+		Options:    options,
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rsp)
+}
+
+type loginPasskeyFinishRequest struct {
+	CeremonyID uuid.UUID       `json:"ceremony_id" validate:"required"`
+	Credential json.RawMessage `json:"credential" swaggertype:"object" validate:"required"`
+}
+
+// @Summary Finish passkey login
+// @Description Verifies the authenticator response and creates a session. Passkeys
+// @Description require user verification so no second factor is asked for, but users
+// @Description required to use two-factor authentication who haven't enrolled get an
+// @Description MFA challenge that can only be used to enroll.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body loginPasskeyFinishRequest true "Login Passkey Finish Request Body"
+// @Success 200 {object} loginResponse
+// @Success 202 {object} mfaChallengeResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/passkey/finish [post]
+func (h *handlers) loginPasskeyFinish(c *fiber.Ctx) error {
+	var req loginPasskeyFinishRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, err := h.passkey.FinishLogin(c.UserContext(), req.CeremonyID, req.Credential)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(passkey.ErrAuthenticationFailure))
+		}
+		return passkeyErrorResponse(c, err)
+	}
+
+	return h.authenticated(c, usr, true)
+}
+
+func passkeyErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, passkey.ErrAuthenticationFailure):
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(passkey.ErrAuthenticationFailure))
+	case errors.Is(err, passkey.ErrNotFound), errors.Is(err, passkey.ErrCeremonyNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+	case errors.Is(err, passkey.ErrCeremonyExpired):
+		return c.Status(fiber.StatusGone).JSON(errorResponse(err))
+	case errors.Is(err, passkey.ErrUniqueCredential):
+		return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+}
+
+// @Summary Begin single sign-on
+// @Description Redirects to the OpenID Connect identity provider to sign in.
+// @Tags Auth
+// @Success 302 "Found"
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/oidc [get]
+func (h *handlers) loginOIDC(c *fiber.Ctx) error {
+	url, err := h.sso.Begin(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+type loginOIDCCallbackRequest struct {
+	State            string `query:"state" validate:"required"`
+	Code             string `query:"code" validate:"required_without=Error"`
+	Error            string `query:"error"`
+	ErrorDescription string `query:"error_description"`
+}
+
+// @Summary Complete single sign-on
+// @Description Handles the redirect back from the identity provider. The user is
+// @Description linked by verified email or provisioned, and receives session tokens.
+// @Description Users with two-factor authentication receive an MFA challenge instead of tokens.
+// @Tags Auth
+// @Produce json
+// @Param state query string true "State"
+// @Param code query string false "Authorization Code"
+// @Param error query string false "Error"
+// @Success 200 {object} loginResponse
+// @Success 202 {object} mfaChallengeResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /login/oidc/callback [get]
+func (h *handlers) loginOIDCCallback(c *fiber.Ctx) error {
+	var req loginOIDCCallbackRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if req.Error != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error":             sso.ErrAuthenticationFailure.Error(),
+			"error_code":        req.Error,
+			"error_description": req.ErrorDescription,
+		})
+	}
+
+	usr, err := h.sso.Finish(c.UserContext(), req.State, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, sso.ErrAuthenticationFailure):
+			return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(sso.ErrAuthenticationFailure))
+		case errors.Is(err, sso.ErrEmailNotVerified), errors.Is(err, sso.ErrNotProvisioned), errors.Is(err, sso.ErrNoRoles):
+			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+		case errors.Is(err, sso.ErrStateNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		case errors.Is(err, sso.ErrStateExpired):
+			return c.Status(fiber.StatusGone).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return h.authenticated(c, usr, false)
+}
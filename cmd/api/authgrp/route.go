@@ -1,37 +1,89 @@
 package authgrp
 
 import (
+	"slices"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/mfa"
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	"github.com/dudakovict/gotify/internal/core/role"
 	"github.com/dudakovict/gotify/internal/core/session"
-	sessiondb "github.com/dudakovict/gotify/internal/core/session/store"
+	"github.com/dudakovict/gotify/internal/core/sso"
 	"github.com/dudakovict/gotify/internal/core/user"
-	userdb "github.com/dudakovict/gotify/internal/core/user/store"
+	"github.com/dudakovict/gotify/internal/stores"
 	"github.com/dudakovict/gotify/internal/worker"
+	"github.com/dudakovict/gotify/pkg/encrypt"
 	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/fiber/v2"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
 type Config struct {
 	Log                  *zerolog.Logger
-	DB                   *sqlx.DB
+	Stores               stores.Stores
 	Maker                maker.Maker
 	TaskDistributor      worker.TaskDistributor
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
+	MFAEncrypter         *encrypt.Encrypter
+	MFAIssuer            string
+	MFAEnforce           bool
+	WebAuthn             *webauthn.WebAuthn
+	SSOProvider          *sso.Provider
 }
 
 func Routes(api fiber.Router, cfg Config) {
-	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
-	sessnCore := session.NewCore(cfg.Log, sessiondb.NewStore(cfg.Log, cfg.DB))
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	sessnCore := session.NewCore(cfg.Log, cfg.Stores.Session)
+	mfaCore := mfa.NewCore(cfg.Log, cfg.Stores.MFA, cfg.MFAEncrypter, cfg.MFAIssuer)
+	pkCore := passkey.NewCore(cfg.Log, usrCore, cfg.Stores.Passkey, cfg.WebAuthn)
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
 
-	hdl := new(usrCore, sessnCore, cfg.Maker, cfg.TaskDistributor, cfg.AccessTokenDuration, cfg.RefreshTokenDuration)
+	var ssoCore *sso.Core
+	if cfg.SSOProvider != nil {
+		ssoCore = sso.NewCore(cfg.Log, usrCore, roleCore, cfg.Stores.SSO, cfg.SSOProvider)
+	}
+
+	hdl := new(usrCore, sessnCore, mfaCore, pkCore, ssoCore, roleCore, cfg.Maker, cfg.TaskDistributor, cfg.AccessTokenDuration, cfg.RefreshTokenDuration, cfg.MFAEnforce)
 
 	api.Post("/register", hdl.register)
 	api.Post("/login", hdl.login)
+	api.Post("/login/mfa", hdl.loginMFA)
+	api.Post("/login/passkey/begin", hdl.loginPasskeyBegin)
+	api.Post("/login/passkey/finish", hdl.loginPasskeyFinish)
 	api.Post("/token", hdl.token)
+
+	mfagrp := api.Group(
+		"/mfa",
+		mid.Authenticate(cfg.Maker, nil),
+		mid.GetUser(usrCore),
+	)
+
+	mfagrp.Post("/enroll", allowTokens(mfaEnrollRole), hdl.mfaEnroll)
+	mfagrp.Post("/confirm", allowTokens(mfaEnrollRole), hdl.mfaConfirm)
+	mfagrp.Post("/recovery-codes", allowTokens(), hdl.mfaRecoveryCodes)
+	mfagrp.Delete("", allowTokens(), hdl.mfaDisable)
+
+	pkgrp := api.Group(
+		"/passkeys",
+		mid.Authenticate(cfg.Maker, nil),
+		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
+	)
+
+	pkgrp.Post("/register/begin", hdl.passkeyRegisterBegin)
+	pkgrp.Post("/register/finish", hdl.passkeyRegisterFinish)
+	pkgrp.Get("", hdl.passkeyQuery)
+	pkgrp.Delete("/:id", hdl.passkeyDelete)
+
+	// Single sign-on is only available when an identity provider is
+	// configured.
+	if ssoCore != nil {
+		api.Get("/login/oidc", hdl.loginOIDC)
+		api.Get("/login/oidc/callback", hdl.loginOIDCCallback)
+	}
 }
 
 func errorResponse(err error) fiber.Map {
@@ -39,3 +91,32 @@ func errorResponse(err error) fiber.Map {
 		"error": err.Error(),
 	}
 }
+
+// allowTokens only lets through tokens carrying one of the roles of the user
+// or one of the pseudo roles, so that mfa enrollment tokens can reach the
+// enrollment routes and challenge tokens can't reach any.
+func allowTokens(pseudoRoles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		payload, ok := c.Locals(mid.AuthPayloadKey).(*maker.Payload)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+		}
+
+		usr, ok := c.Locals(mid.UserKey).(user.User)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+		}
+
+		for _, r := range payload.Roles {
+			hasRole := slices.ContainsFunc(usr.Roles, func(ur user.Role) bool {
+				return ur.Name() == r
+			})
+
+			if hasRole || slices.Contains(pseudoRoles, r) {
+				return c.Next()
+			}
+		}
+
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+}
@@ -0,0 +1,421 @@
+// Package groupgrp maintains the group of handlers for group access.
+package groupgrp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/dudakovict/gotify/pkg/util"
+	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type handlers struct {
+	group *group.Core
+	user  *user.Core
+}
+
+func new(group *group.Core, user *user.Core) *handlers {
+	return &handlers{
+		group: group,
+		user:  user,
+	}
+}
+
+type groupResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newGroupResponse(grp group.Group) groupResponse {
+	return groupResponse{
+		ID:          grp.ID,
+		Name:        grp.Name,
+		Description: grp.Description,
+		CreatedAt:   grp.CreatedAt,
+		UpdatedAt:   grp.UpdatedAt,
+	}
+}
+
+type memberResponse struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newMemberResponse(mbr group.Member) memberResponse {
+	return memberResponse{
+		GroupID:   mbr.GroupID,
+		UserID:    mbr.UserID,
+		CreatedAt: mbr.CreatedAt,
+	}
+}
+
+type createGroupRequest struct {
+	Name        string `json:"name" validate:"required,max=128"`
+	Description string `json:"description" validate:"max=256"`
+}
+
+// @Summary Create a new group
+// @Description Creates a new group of users, like a team, which can be
+// @Description subscribed to topics as a unit.
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Param body body createGroupRequest true "Create Group Request Body"
+// @Success 201 {object} groupResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups [post]
+func (h *handlers) create(c *fiber.Ctx) error {
+	var req createGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	ng := group.NewGroup{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	grp, err := h.group.Create(c.UserContext(), ng)
+	if err != nil {
+		switch {
+		case errors.Is(err, group.ErrInvalidName):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, group.ErrUniqueName):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newGroupResponse(grp))
+}
+
+type updateGroupRequest struct {
+	Name        *string `json:"name" validate:"omitempty,max=128"`
+	Description *string `json:"description" validate:"omitempty,max=256"`
+}
+
+// @Summary Update a group
+// @Description Updates the name and description of a group.
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param body body updateGroupRequest true "Update Group Request Body"
+// @Param If-Match header string true "ETag of the group when it was read"
+// @Success 200 {object} groupResponse
+// @Header 200 {string} ETag "Version of the group"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups/{id} [put]
+func (h *handlers) update(c *fiber.Ctx) error {
+	var req updateGroupRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	grp, err := h.queryGroup(c)
+	if err != nil {
+		return queryGroupErrorResponse(c, err)
+	}
+
+	if err := mid.IfMatch(c, grp.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	ug := group.UpdateGroup{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	updGrp, err := h.group.Update(c.UserContext(), grp, ug)
+	if err != nil {
+		switch {
+		case errors.Is(err, group.ErrInvalidName):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, group.ErrUniqueName):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		case errors.Is(err, group.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	mid.SetETag(c, updGrp.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newGroupResponse(updGrp))
+}
+
+// @Summary Delete a group
+// @Description Deletes a group by its ID, along with its memberships and
+// @Description subscriptions.
+// @Tags Group
+// @Param id path string true "Group ID"
+// @Param If-Match header string true "ETag of the group when it was read"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups/{id} [delete]
+func (h *handlers) delete(c *fiber.Ctx) error {
+	grp, err := h.queryGroup(c)
+	if err != nil {
+		return queryGroupErrorResponse(c, err)
+	}
+
+	if err := mid.IfMatch(c, grp.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	if err := h.group.Delete(c.UserContext(), grp); err != nil {
+		if errors.Is(err, group.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// @Summary Query groups
+// @Description Queries groups based on page number and rows per page,
+// @Description filtered and ordered as asked. The page comes with the total
+// @Description number of groups matching the filters.
+// @Tags Group
+// @Produce json
+// @Param page query integer false "Page Number"
+// @Param rows query integer false "Rows Per Page"
+// @Param order_by query string false "Order, like name,desc" Enums(id, name, created_at)
+// @Param name query string false "Part of the name"
+// @Success 200 {object} util.PageResponse[groupResponse]
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups [get]
+func (h *handlers) query(c *fiber.Ctx) error {
+	page, err := util.Parse(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	var filter group.QueryFilter
+	if name := c.Query("name"); name != "" {
+		filter.Name = &name
+	}
+
+	orderBy, err := order.Parse(c.Query("order_by"), group.DefaultOrderBy, group.OrderByFields...)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	grps, err := h.group.Query(c.UserContext(), filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	total, err := h.group.Count(c.UserContext(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	grpsResponse := make([]groupResponse, len(grps))
+	for i, grp := range grps {
+		grpsResponse[i] = newGroupResponse(grp)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(util.NewPageResponse(grpsResponse, total, page))
+}
+
+// @Summary Query a group by ID
+// @Description Queries a group by its ID.
+// @Tags Group
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} groupResponse
+// @Header 200 {string} ETag "Version of the group"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups/{id} [get]
+func (h *handlers) queryByID(c *fiber.Ctx) error {
+	grp, err := h.queryGroup(c)
+	if err != nil {
+		return queryGroupErrorResponse(c, err)
+	}
+
+	mid.SetETag(c, grp.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newGroupResponse(grp))
+}
+
+// @Summary Query the members of a group
+// @Description Queries the users in a group.
+// @Tags Group
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {array} memberResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups/{id}/members [get]
+func (h *handlers) queryMembers(c *fiber.Ctx) error {
+	grp, err := h.queryGroup(c)
+	if err != nil {
+		return queryGroupErrorResponse(c, err)
+	}
+
+	mbrs, err := h.group.QueryMembers(c.UserContext(), grp)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	mbrsResponse := make([]memberResponse, len(mbrs))
+	for i, mbr := range mbrs {
+		mbrsResponse[i] = newMemberResponse(mbr)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(mbrsResponse)
+}
+
+type addMemberRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
+
+// @Summary Add a user to a group
+// @Description Adds a user to a group. The user gets the notifications of
+// @Description the topics the group is subscribed to from then on.
+// @Tags Group
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param body body addMemberRequest true "Add Member Request Body"
+// @Success 201 {object} memberResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups/{id}/members [post]
+func (h *handlers) addMember(c *fiber.Ctx) error {
+	var req addMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	grp, err := h.queryGroup(c)
+	if err != nil {
+		return queryGroupErrorResponse(c, err)
+	}
+
+	if _, err := h.user.QueryByID(c.UserContext(), req.UserID); err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	mbr, err := h.group.AddMember(c.UserContext(), grp, req.UserID)
+	if err != nil {
+		if errors.Is(err, group.ErrUniqueMember) {
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newMemberResponse(mbr))
+}
+
+// @Summary Remove a user from a group
+// @Description Removes a user from a group. The user stops getting the
+// @Description notifications of the topics the group is subscribed to.
+// @Tags Group
+// @Param id path string true "Group ID"
+// @Param user_id path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /groups/{id}/members/{user_id} [delete]
+func (h *handlers) removeMember(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	grp, err := h.queryGroup(c)
+	if err != nil {
+		return queryGroupErrorResponse(c, err)
+	}
+
+	if err := h.group.RemoveMember(c.UserContext(), grp, userID); err != nil {
+		if errors.Is(err, group.ErrMemberNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// errInvalidID is returned by queryGroup when the group ID in the path isn't
+// a valid UUID.
+var errInvalidID = errors.New("invalid group id")
+
+// queryGroup loads the group from the id path parameter.
+func (h *handlers) queryGroup(c *fiber.Ctx) (group.Group, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return group.Group{}, errInvalidID
+	}
+
+	return h.group.QueryByID(c.UserContext(), id)
+}
+
+// queryGroupErrorResponse maps the errors of queryGroup to responses.
+func queryGroupErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errInvalidID):
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	case errors.Is(err, group.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+}
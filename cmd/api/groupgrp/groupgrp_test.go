@@ -0,0 +1,88 @@
+package groupgrp
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dudakovict/gotify/internal/core/group"
+	mockgrp "github.com/dudakovict/gotify/internal/core/group/mock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelete(t *testing.T) {
+	grp := group.Group{
+		ID:      uuid.New(),
+		Name:    "on-call",
+		Version: 3,
+	}
+
+	testCases := []struct {
+		name       string
+		ifMatch    string
+		buildStubs func(storer *mockgrp.MockStorer)
+		wantStatus int
+	}{
+		{
+			name:    "OK",
+			ifMatch: `"3"`,
+			buildStubs: func(storer *mockgrp.MockStorer) {
+				storer.EXPECT().QueryByID(gomock.Any(), grp.ID).Times(1).Return(grp, nil)
+				storer.EXPECT().Delete(gomock.Any(), grp).Times(1).Return(nil)
+			},
+			wantStatus: fiber.StatusNoContent,
+		},
+		{
+			name: "NoIfMatch",
+			buildStubs: func(storer *mockgrp.MockStorer) {
+				storer.EXPECT().QueryByID(gomock.Any(), grp.ID).Times(1).Return(grp, nil)
+				storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: fiber.StatusPreconditionRequired,
+		},
+		{
+			name:    "StaleIfMatch",
+			ifMatch: `"2"`,
+			buildStubs: func(storer *mockgrp.MockStorer) {
+				storer.EXPECT().QueryByID(gomock.Any(), grp.ID).Times(1).Return(grp, nil)
+				storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+			},
+			wantStatus: fiber.StatusPreconditionFailed,
+		},
+		{
+			name:    "VersionConflict",
+			ifMatch: `"3"`,
+			buildStubs: func(storer *mockgrp.MockStorer) {
+				storer.EXPECT().QueryByID(gomock.Any(), grp.ID).Times(1).Return(grp, nil)
+				storer.EXPECT().Delete(gomock.Any(), grp).Times(1).Return(group.ErrVersionConflict)
+			},
+			wantStatus: fiber.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			storer := mockgrp.NewMockStorer(ctrl)
+			tc.buildStubs(storer)
+
+			h := new(group.NewCore(nil, storer), nil)
+
+			app := fiber.New()
+			app.Delete("/groups/:id", h.delete)
+
+			req := httptest.NewRequest(fiber.MethodDelete, "/groups/"+grp.ID.String(), nil)
+			if tc.ifMatch != "" {
+				req.Header.Set(fiber.HeaderIfMatch, tc.ifMatch)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, tc.wantStatus, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package groupgrp
+
+import (
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/internal/stores"
+	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+type Config struct {
+	Log    *zerolog.Logger
+	Stores stores.Stores
+	Maker  maker.Maker
+}
+
+func Routes(api fiber.Router, cfg Config) {
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
+	grpCore := group.NewCore(cfg.Log, cfg.Stores.Group)
+
+	hdl := new(grpCore, usrCore)
+
+	groupgrp := api.Group(
+		"/groups",
+		mid.Authenticate(cfg.Maker, nil),
+		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
+	)
+
+	groupgrp.Get("", mid.Require(role.PermGroupRead), hdl.query)
+	groupgrp.Post("", mid.Require(role.PermGroupManage), hdl.create)
+	groupgrp.Get("/:id", mid.Require(role.PermGroupRead), hdl.queryByID)
+	groupgrp.Put("/:id", mid.Require(role.PermGroupManage), hdl.update)
+	groupgrp.Delete("/:id", mid.Require(role.PermGroupManage), hdl.delete)
+	groupgrp.Get("/:id/members", mid.Require(role.PermGroupRead), hdl.queryMembers)
+	groupgrp.Post("/:id/members", mid.Require(role.PermGroupManage), hdl.addMember)
+	groupgrp.Delete("/:id/members/:user_id", mid.Require(role.PermGroupManage), hdl.removeMember)
+}
+
+func errorResponse(err error) fiber.Map {
+	return fiber.Map{
+		"error": err.Error(),
+	}
+}
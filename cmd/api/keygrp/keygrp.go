@@ -0,0 +1,337 @@
+// Package keygrp maintains the group of handlers for api key access.
+package keygrp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type handlers struct {
+	apikey *apikey.Core
+}
+
+func new(apikey *apikey.Core) *handlers {
+	return &handlers{
+		apikey: apikey,
+	}
+}
+
+type scope struct {
+	Name     string      `json:"name" validate:"required"`
+	TopicIDs []uuid.UUID `json:"topic_ids,omitempty"`
+}
+
+func toCoreScopes(scopes []scope) []apikey.Scope {
+	ss := make([]apikey.Scope, len(scopes))
+	for i, s := range scopes {
+		ss[i] = apikey.Scope{
+			Name:     s.Name,
+			TopicIDs: s.TopicIDs,
+		}
+	}
+	return ss
+}
+
+type apiKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []scope    `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func newAPIKeyResponse(key apikey.APIKey) apiKeyResponse {
+	scopes := make([]scope, len(key.Scopes))
+	for i, s := range key.Scopes {
+		scopes[i] = scope{
+			Name:     s.Name,
+			TopicIDs: s.TopicIDs,
+		}
+	}
+
+	rsp := apiKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    apikey.TokenPrefix + key.Prefix,
+		Scopes:    scopes,
+		CreatedAt: key.CreatedAt,
+		UpdatedAt: key.UpdatedAt,
+	}
+
+	if !key.ExpiresAt.IsZero() {
+		rsp.ExpiresAt = &key.ExpiresAt
+	}
+
+	if !key.LastUsedAt.IsZero() {
+		rsp.LastUsedAt = &key.LastUsedAt
+	}
+
+	return rsp
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=64"`
+	Scopes    []scope    `json:"scopes" validate:"required,min=1,dive"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	Token string `json:"token"`
+}
+
+// @Summary Create a new api key
+// @Description Creates a new api key for the authenticated user. The token is
+// @Description only returned once and is sent as a bearer token.
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Param body body createAPIKeyRequest true "Create API Key Request Body"
+// @Success 201 {object} createAPIKeyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys [post]
+func (h *handlers) create(c *fiber.Ctx) error {
+	var req createAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	nk := apikey.NewAPIKey{
+		UserID: usr.ID,
+		Name:   req.Name,
+		Scopes: toCoreScopes(req.Scopes),
+	}
+
+	if req.ExpiresAt != nil {
+		if req.ExpiresAt.Before(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(errors.New("expires_at must be in the future")))
+		}
+		nk.ExpiresAt = *req.ExpiresAt
+	}
+
+	key, token, err := h.apikey.Create(c.UserContext(), nk)
+	if err != nil {
+		if errors.Is(err, apikey.ErrInvalidScope) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	rsp := createAPIKeyResponse{
+		apiKeyResponse: newAPIKeyResponse(key),
+		Token:          token,
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(rsp)
+}
+
+type updateAPIKeyRequest struct {
+	Name   *string `json:"name" validate:"omitempty,max=64"`
+	Scopes []scope `json:"scopes" validate:"omitempty,min=1,dive"`
+}
+
+// @Summary Update an api key
+// @Description Updates the name and scopes of an api key of the authenticated user.
+// @Tags APIKey
+// @Accept json
+// @Produce json
+// @Param id path string true "API Key ID"
+// @Param body body updateAPIKeyRequest true "Update API Key Request Body"
+// @Param If-Match header string true "ETag of the api key when it was read"
+// @Success 200 {object} apiKeyResponse
+// @Header 200 {string} ETag "Version of the api key"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys/{id} [put]
+func (h *handlers) update(c *fiber.Ctx) error {
+	var req updateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	key, err := h.queryOwned(c)
+	if err != nil {
+		return queryOwnedErrorResponse(c, err)
+	}
+
+	if err := mid.IfMatch(c, key.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	uk := apikey.UpdateAPIKey{
+		Name: req.Name,
+	}
+
+	if req.Scopes != nil {
+		uk.Scopes = toCoreScopes(req.Scopes)
+	}
+
+	key, err = h.apikey.Update(c.UserContext(), key, uk)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrInvalidScope):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, apikey.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	mid.SetETag(c, key.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newAPIKeyResponse(key))
+}
+
+// @Summary Revoke an api key
+// @Description Revokes an api key of the authenticated user.
+// @Tags APIKey
+// @Param id path string true "API Key ID"
+// @Param If-Match header string true "ETag of the api key when it was read"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys/{id} [delete]
+func (h *handlers) delete(c *fiber.Ctx) error {
+	key, err := h.queryOwned(c)
+	if err != nil {
+		return queryOwnedErrorResponse(c, err)
+	}
+
+	if err := mid.IfMatch(c, key.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	if err := h.apikey.Delete(c.UserContext(), key); err != nil {
+		if errors.Is(err, apikey.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// @Summary Query api keys
+// @Description Queries the api keys of the authenticated user.
+// @Tags APIKey
+// @Produce json
+// @Success 200 {array} apiKeyResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys [get]
+func (h *handlers) query(c *fiber.Ctx) error {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	keys, err := h.apikey.QueryByUserID(c.UserContext(), usr.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	keysResponse := make([]apiKeyResponse, len(keys))
+	for i, key := range keys {
+		keysResponse[i] = newAPIKeyResponse(key)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(keysResponse)
+}
+
+// @Summary Query an api key by ID
+// @Description Queries an api key of the authenticated user by its ID.
+// @Tags APIKey
+// @Produce json
+// @Param id path string true "API Key ID"
+// @Success 200 {object} apiKeyResponse
+// @Header 200 {string} ETag "Version of the api key"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api-keys/{id} [get]
+func (h *handlers) queryByID(c *fiber.Ctx) error {
+	key, err := h.queryOwned(c)
+	if err != nil {
+		return queryOwnedErrorResponse(c, err)
+	}
+
+	mid.SetETag(c, key.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newAPIKeyResponse(key))
+}
+
+// queryOwned finds the api key in the path and makes sure it belongs to the
+// authenticated user. Keys of other users are reported as missing.
+func (h *handlers) queryOwned(c *fiber.Ctx) (apikey.APIKey, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("%w: %s", errInvalidID, err)
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return apikey.APIKey{}, user.ErrAuthorizationFailure
+	}
+
+	key, err := h.apikey.QueryByID(c.UserContext(), id)
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+
+	if key.UserID != usr.ID {
+		return apikey.APIKey{}, apikey.ErrNotFound
+	}
+
+	return key, nil
+}
+
+var errInvalidID = errors.New("invalid id")
+
+func queryOwnedErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errInvalidID):
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	case errors.Is(err, user.ErrAuthorizationFailure):
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(err))
+	case errors.Is(err, apikey.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(apikey.ErrNotFound))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+}
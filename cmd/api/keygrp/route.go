@@ -0,0 +1,48 @@
+package keygrp
+
+import (
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/internal/stores"
+	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+type Config struct {
+	Log    *zerolog.Logger
+	Stores stores.Stores
+	Maker  maker.Maker
+}
+
+func Routes(api fiber.Router, cfg Config) {
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	keyCore := apikey.NewCore(cfg.Log, cfg.Stores.APIKey)
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
+
+	hdl := new(keyCore)
+
+	// Api keys can't be used to manage api keys.
+	keygrp := api.Group(
+		"/api-keys",
+		mid.Authenticate(cfg.Maker, nil),
+		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
+	)
+
+	// Users only ever see and manage their own api keys, which takes no
+	// permission. The keys can't do more than the roles of their user allow.
+	keygrp.Get("", hdl.query)         // self
+	keygrp.Post("", hdl.create)       // self
+	keygrp.Get("/:id", hdl.queryByID) // self
+	keygrp.Put("/:id", hdl.update)    // self
+	keygrp.Delete("/:id", hdl.delete) // self
+}
+
+func errorResponse(err error) fiber.Map {
+	return fiber.Map{
+		"error": err.Error(),
+	}
+}
@@ -2,17 +2,21 @@
 package ntfgrp
 
 import (
-	"context"
 	"errors"
-	"slices"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/apikey"
 	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/topic"
 	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/mid"
 	"github.com/dudakovict/gotify/pkg/util"
 	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
@@ -20,39 +24,59 @@ import (
 
 type handlers struct {
 	notification    *notification.Core
+	topic           *topic.Core
 	taskDistributor worker.TaskDistributor
 }
 
-func new(notification *notification.Core, taskDistributor worker.TaskDistributor) *handlers {
+func new(notification *notification.Core, topic *topic.Core, taskDistributor worker.TaskDistributor) *handlers {
 	return &handlers{
 		notification:    notification,
+		topic:           topic,
 		taskDistributor: taskDistributor,
 	}
 }
 
 type createNotificationRequest struct {
-	TopicID uuid.UUID `json:"topic_id" validate:"required"`
-	Message string    `json:"message" validate:"required"`
+	TopicID  uuid.UUID `json:"topic_id" validate:"required"`
+	Title    string    `json:"title" validate:"max=255"`
+	Message  string    `json:"message" validate:"required"`
+	Priority string    `json:"priority" validate:"omitempty,oneof=low normal high urgent"`
+	Tags     []string  `json:"tags" validate:"max=16,dive,required,max=64"`
 }
 
 type notificationResponse struct {
-	ID        uuid.UUID `json:"id"`
-	TopicID   uuid.UUID `json:"topic_id"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID  `json:"id"`
+	TopicID   uuid.UUID  `json:"topic_id"`
+	Title     string     `json:"title,omitempty"`
+	Message   string     `json:"message"`
+	Priority  string     `json:"priority"`
+	Tags      []string   `json:"tags"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func newNotificationResponse(ntf notification.Notification) notificationResponse {
-	return notificationResponse{
+	rsp := notificationResponse{
 		ID:        ntf.ID,
 		TopicID:   ntf.TopicID,
+		Title:     ntf.Title,
 		Message:   ntf.Message,
+		Priority:  ntf.Priority.Name(),
+		Tags:      ntf.Tags,
 		CreatedAt: ntf.CreatedAt,
 	}
+
+	if !ntf.DeletedAt.IsZero() {
+		rsp.DeletedAt = &ntf.DeletedAt
+	}
+
+	return rsp
 }
 
 // @Summary Create a new notification
-// @Description Creates a new notification with the provided topic ID and message.
+// @Description Creates a new notification with the provided topic ID, title,
+// @Description message, priority and tags. The priority is normal unless
+// @Description given. Archived topics don't accept new notifications.
 // @Tags Notification
 // @Accept json
 // @Produce json
@@ -60,6 +84,8 @@ func newNotificationResponse(ntf notification.Notification) notificationResponse
 // @Success 201 {object} notificationResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /notifications [post]
 func (h *handlers) create(c *fiber.Ctx) error {
@@ -72,14 +98,40 @@ func (h *handlers) create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
+	if !mid.KeyAllows(c, apikey.ScopeNotificationsPublish, req.TopicID) {
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	tpc, err := h.checkTopic(c, req.TopicID, topic.RightPublish)
+	if err != nil {
+		return checkTopicErrorResponse(c, err)
+	}
+
+	if tpc.Archived() {
+		return c.Status(fiber.StatusConflict).JSON(errorResponse(topic.ErrArchived))
+	}
+
+	nn := notification.NewNotification{
+		TopicID: req.TopicID,
+		Title:   req.Title,
+		Message: req.Message,
+		Tags:    req.Tags,
+	}
+
+	if req.Priority != "" {
+		priority, err := notification.ParsePriority(req.Priority)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		nn.Priority = priority
+	}
+
 	nnTx := notification.NewNotificationTx{
-		NewNotification: notification.NewNotification{
-			TopicID: req.TopicID,
-			Message: req.Message,
-		},
+		NewNotification: nn,
 		AfterCreate: func(ntf notification.Notification) error {
 			taskPayload := &worker.PayloadSendNotification{
 				NotificationID: ntf.ID,
+				TopicID:        ntf.TopicID,
 			}
 
 			opts := []asynq.Option{
@@ -88,11 +140,11 @@ func (h *handlers) create(c *fiber.Ctx) error {
 				asynq.Queue(worker.QueueCritical),
 			}
 
-			return h.taskDistributor.DistributeTaskSendNotification(context.Background(), taskPayload, opts...)
+			return h.taskDistributor.DistributeTaskSendNotification(c.UserContext(), taskPayload, opts...)
 		},
 	}
 
-	ntf, err := h.notification.CreateTx(nnTx)
+	ntf, err := h.notification.CreateTx(c.UserContext(), nnTx)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
@@ -101,7 +153,9 @@ func (h *handlers) create(c *fiber.Ctx) error {
 }
 
 // @Summary Delete a notification by ID
-// @Description Deletes a notification with the specified ID.
+// @Description Deletes a notification with the specified ID. It is left out
+// @Description of the history and the inboxes right away and can be restored
+// @Description until it is purged after the retention period.
 // @Tags Notification
 // @Produce json
 // @Param id path string true "Notification ID"
@@ -117,7 +171,7 @@ func (h *handlers) delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	ntf, err := h.notification.QueryByID(id)
+	ntf, err := h.notification.QueryByID(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, notification.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
@@ -125,49 +179,359 @@ func (h *handlers) delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	usr, ok := c.Locals(mid.UserKey).(user.User)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	// Notifications can be deleted by those allowed to delete any notification
+	// and by those managing the topic.
+	if !mid.Can(c, role.PermNotificationDelete) {
+		if _, err := h.checkTopic(c, ntf.TopicID, topic.RightManage); err != nil {
+			return checkTopicErrorResponse(c, err)
+		}
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) {
-		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+	if err := h.notification.Delete(c.UserContext(), ntf); err != nil {
+		if errors.Is(err, notification.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	if err := h.notification.Delete(ntf); err != nil {
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// @Summary Restore a notification
+// @Description Restores a deleted notification by its ID. It is not sent
+// @Description again.
+// @Tags Notification
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} notificationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /notifications/{id}/restore [post]
+func (h *handlers) restore(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	queryNtf, err := h.notification.QueryDeletedByID(c.UserContext(), id)
+	if err != nil {
 		if errors.Is(err, notification.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	return c.SendStatus(fiber.StatusNoContent)
+	ntf, err := h.notification.Restore(c.UserContext(), queryNtf)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newNotificationResponse(ntf))
 }
 
 // @Summary Query notifications
-// @Description Queries notifications based on page number, rows per page, and topic ID.
+// @Description Queries the notifications of a topic based on page number and
+// @Description rows per page, filtered and ordered as asked. The page comes with
+// @Description the total number of notifications matching the filters. Given a
+// @Description cursor instead of a page, empty for the first page, the newest
+// @Description notifications come first and the page comes as a
+// @Description util.CursorResponse with the cursors of the next and previous
+// @Description pages, which don't shift as new notifications arrive.
 // @Tags Notification
 // @Produce json
-// @Param page query integer true "Page Number"
-// @Param rows query integer true "Rows Per Page"
+// @Param page query integer false "Page Number"
+// @Param cursor query string false "Cursor"
+// @Param rows query integer false "Rows Per Page"
+// @Param order_by query string false "Order, like created_at,asc" Enums(id, created_at)
 // @Param topic_id query string true "Topic ID"
-// @Success 200 {array} notificationResponse
+// @Param priority query string false "Priority"
+// @Param tag query string false "Tag"
+// @Param start_created_date query string false "Created at or after, in RFC 3339"
+// @Param end_created_date query string false "Created at or before, in RFC 3339"
+// @Param deleted query boolean false "List the deleted notifications instead"
+// @Success 200 {object} util.PageResponse[notificationResponse]
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /notifications [get]
 func (h *handlers) query(c *fiber.Ctx) error {
+	topicID, err := uuid.Parse(c.Query("topic_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if !mid.KeyAllows(c, apikey.ScopeNotificationsRead, topicID) {
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	if _, err := h.checkTopic(c, topicID, topic.RightSubscribe); err != nil {
+		return checkTopicErrorResponse(c, err)
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+	filter.TopicID = &topicID
+
+	if filter.Deleted && !mid.Can(c, role.PermNotificationDelete) {
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	if util.UsesCursor(c) {
+		return h.queryByCursor(c, filter)
+	}
+
 	page, err := util.Parse(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	topicID, err := uuid.Parse(c.Query("topic_id"))
+	orderBy, err := order.Parse(c.Query("order_by"), notification.DefaultOrderBy, notification.OrderByFields...)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	ntfs, err := h.notification.Query(c.UserContext(), filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	total, err := h.notification.Count(c.UserContext(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	ntfsResponse := make([]notificationResponse, len(ntfs))
+	for i, ntf := range ntfs {
+		ntfsResponse[i] = newNotificationResponse(ntf)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(util.NewPageResponse(ntfsResponse, total, page))
+}
+
+// queryByCursor responds with the page of notifications read with the cursor
+// of the request.
+func (h *handlers) queryByCursor(c *fiber.Ctx, filter notification.QueryFilter) error {
+	cp, err := util.ParseCursor(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	ntfs, page, err := h.notification.QueryByCursor(c.UserContext(), filter, cp.Cursor, cp.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	ntfsResponse := make([]notificationResponse, len(ntfs))
+	for i, ntf := range ntfs {
+		ntfsResponse[i] = newNotificationResponse(ntf)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(util.NewCursorResponse(ntfsResponse, page, cp.RowsPerPage))
+}
+
+// parseFilter parses the filters of the notification query from the query
+// string. The topic is left to the caller.
+func parseFilter(c *fiber.Ctx) (notification.QueryFilter, error) {
+	var filter notification.QueryFilter
+
+	if value := c.Query("priority"); value != "" {
+		priority, err := notification.ParsePriority(value)
+		if err != nil {
+			return notification.QueryFilter{}, err
+		}
+		filter.Priority = &priority
+	}
+
+	if tag := c.Query("tag"); tag != "" {
+		filter.Tag = &tag
+	}
+
+	if value := c.Query("start_created_date"); value != "" {
+		date, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return notification.QueryFilter{}, fmt.Errorf("invalid start_created_date: %w", err)
+		}
+		filter.StartCreatedDate = &date
+	}
+
+	if value := c.Query("end_created_date"); value != "" {
+		date, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return notification.QueryFilter{}, fmt.Errorf("invalid end_created_date: %w", err)
+		}
+		filter.EndCreatedDate = &date
+	}
+
+	if value := c.Query("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return notification.QueryFilter{}, fmt.Errorf("invalid deleted: %w", err)
+		}
+		filter.Deleted = deleted
+	}
+
+	return filter, nil
+}
+
+type highlightResponse struct {
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+type searchResultResponse struct {
+	notificationResponse
+	Highlight highlightResponse `json:"highlight"`
+}
+
+func newSearchResultResponse(r notification.SearchResult) searchResultResponse {
+	return searchResultResponse{
+		notificationResponse: newNotificationResponse(r.Notification),
+		Highlight: highlightResponse{
+			Title:   r.TitleSnippet,
+			Message: r.MessageSnippet,
+		},
+	}
+}
+
+// @Summary Search notifications
+// @Description Searches the title and message of the notifications in the
+// @Description topics the user can read, the most relevant first. Every
+// @Description result comes with its title and a snippet of its message, the
+// @Description matched words wrapped in <mark> tags. The rest of the snippets
+// @Description is HTML escaped, so they can be rendered as they are.
+// @Tags Notification
+// @Produce json
+// @Param q query string true "Text to search for"
+// @Param page query integer false "Page Number"
+// @Param rows query integer false "Rows Per Page"
+// @Param topic_id query string false "Topic ID"
+// @Param priority query string false "Priority"
+// @Param start_created_date query string false "Created at or after, in RFC 3339"
+// @Param end_created_date query string false "Created at or before, in RFC 3339"
+// @Success 200 {object} util.PageResponse[searchResultResponse]
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /notifications/search [get]
+func (h *handlers) search(c *fiber.Ctx) error {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	page, err := util.Parse(c)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	ntfs, err := h.notification.Query(page.Number, page.RowsPerPage, topicID)
+	filter, err := parseSearchFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if filter.TopicID != nil {
+		if !mid.KeyAllows(c, apikey.ScopeNotificationsRead, *filter.TopicID) {
+			return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+		}
+
+		if _, err := h.checkTopic(c, *filter.TopicID, topic.RightSubscribe); err != nil {
+			return checkTopicErrorResponse(c, err)
+		}
+	}
+
+	if !mid.Can(c, role.PermTopicManage) {
+		filter.ReadableBy = &usr.ID
+	}
+	filter.TopicIDs = mid.KeyTopics(c, apikey.ScopeNotificationsRead)
+
+	rs, err := h.notification.Search(c.UserContext(), filter, page.Number, page.RowsPerPage)
+	if err != nil {
+		if errors.Is(err, notification.ErrEmptySearch) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	total, err := h.notification.CountSearch(c.UserContext(), filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	rsResponse := make([]searchResultResponse, len(rs))
+	for i, r := range rs {
+		rsResponse[i] = newSearchResultResponse(r)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(util.NewPageResponse(rsResponse, total, page))
+}
+
+// parseSearchFilter parses the search from the query string. It narrows the
+// search with the same filters as the notification query, the topic included.
+func parseSearchFilter(c *fiber.Ctx) (notification.SearchFilter, error) {
+	qf, err := parseFilter(c)
+	if err != nil {
+		return notification.SearchFilter{}, err
+	}
+
+	filter := notification.SearchFilter{
+		Text:             c.Query("q"),
+		Priority:         qf.Priority,
+		StartCreatedDate: qf.StartCreatedDate,
+		EndCreatedDate:   qf.EndCreatedDate,
+	}
+
+	if value := c.Query("topic_id"); value != "" {
+		topicID, err := uuid.Parse(value)
+		if err != nil {
+			return notification.SearchFilter{}, fmt.Errorf("invalid topic_id: %w", err)
+		}
+		filter.TopicID = &topicID
+	}
+
+	return filter, nil
+}
+
+// @Summary Query the inbox
+// @Description Queries the notifications delivered in-app to the signed in user,
+// @Description the newest first. Given a cursor instead of a page, empty for the
+// @Description first page, the page comes as a util.CursorResponse with the
+// @Description cursors of the next and previous pages.
+// @Tags Notification
+// @Produce json
+// @Param page query integer false "Page Number"
+// @Param cursor query string false "Cursor"
+// @Param rows query integer false "Rows Per Page"
+// @Success 200 {object} util.PageResponse[notificationResponse]
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /notifications/inbox [get]
+func (h *handlers) queryInbox(c *fiber.Ctx) error {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	if util.UsesCursor(c) {
+		return h.queryInboxByCursor(c, usr)
+	}
+
+	page, err := util.Parse(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	ntfs, err := h.notification.QueryInbox(c.UserContext(), page.Number, page.RowsPerPage, usr.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	total, err := h.notification.CountInbox(c.UserContext(), usr.ID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
@@ -177,7 +541,28 @@ func (h *handlers) query(c *fiber.Ctx) error {
 		ntfsResponse[i] = newNotificationResponse(ntf)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(ntfsResponse)
+	return c.Status(fiber.StatusOK).JSON(util.NewPageResponse(ntfsResponse, total, page))
+}
+
+// queryInboxByCursor responds with the page of the user's inbox read with the
+// cursor of the request.
+func (h *handlers) queryInboxByCursor(c *fiber.Ctx, usr user.User) error {
+	cp, err := util.ParseCursor(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	ntfs, page, err := h.notification.QueryInboxByCursor(c.UserContext(), usr.ID, cp.Cursor, cp.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	ntfsResponse := make([]notificationResponse, len(ntfs))
+	for i, ntf := range ntfs {
+		ntfsResponse[i] = newNotificationResponse(ntf)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(util.NewCursorResponse(ntfsResponse, page, cp.RowsPerPage))
 }
 
 // @Summary Query a notification by ID
@@ -197,7 +582,7 @@ func (h *handlers) queryByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	ntf, err := h.notification.QueryByID(id)
+	ntf, err := h.notification.QueryByID(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, notification.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
@@ -205,5 +590,149 @@ func (h *handlers) queryByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
+	if !mid.KeyAllows(c, apikey.ScopeNotificationsRead, ntf.TopicID) {
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	if _, err := h.checkTopic(c, ntf.TopicID, topic.RightSubscribe); err != nil {
+		if errors.Is(err, topic.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(notification.ErrNotFound))
+		}
+		return checkTopicErrorResponse(c, err)
+	}
+
 	return c.Status(fiber.StatusOK).JSON(newNotificationResponse(ntf))
 }
+
+type logEntryResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	Skipped        bool       `json:"skipped"`
+	Channel        string     `json:"channel,omitempty"`
+	Address        string     `json:"address,omitempty"`
+	SubscriptionID *uuid.UUID `json:"subscription_id,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newLogEntryResponse(entry notification.LogEntry) logEntryResponse {
+	rsp := logEntryResponse{
+		ID:        entry.ID,
+		Skipped:   entry.Skipped,
+		Channel:   entry.Channel.Name(),
+		Address:   entry.Address,
+		Reason:    entry.Reason,
+		CreatedAt: entry.CreatedAt,
+	}
+
+	if entry.Skipped {
+		rsp.SubscriptionID = &entry.SubscriptionID
+	}
+
+	return rsp
+}
+
+// @Summary Query the deliveries of a notification
+// @Description Queries the log of the deliveries of a notification, the ones
+// @Description made along with the ones skipped and why, the newest first. The
+// @Description log is read a page at a time with a cursor, empty for the first
+// @Description page, and comes as a util.CursorResponse with the cursors of the
+// @Description next and previous pages. Only those managing the topic can read
+// @Description it.
+// @Tags Notification
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Param cursor query string false "Cursor"
+// @Param rows query integer false "Rows Per Page"
+// @Success 200 {object} util.CursorResponse[logEntryResponse]
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /notifications/{id}/deliveries [get]
+func (h *handlers) queryDeliveries(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	cp, err := util.ParseCursor(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	ntf, err := h.notification.QueryByID(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, notification.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	if _, err := h.checkTopic(c, ntf.TopicID, topic.RightManage); err != nil {
+		if errors.Is(err, topic.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(notification.ErrNotFound))
+		}
+		return checkTopicErrorResponse(c, err)
+	}
+
+	entries, page, err := h.notification.QueryLogByCursor(c.UserContext(), ntf.ID, cp.Cursor, cp.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	entriesResponse := make([]logEntryResponse, len(entries))
+	for i, entry := range entries {
+		entriesResponse[i] = newLogEntryResponse(entry)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(util.NewCursorResponse(entriesResponse, page, cp.RowsPerPage))
+}
+
+// checkTopic finds the topic and makes sure the user has the right on it.
+// Topics the user can't see at all are reported as not found.
+func (h *handlers) checkTopic(c *fiber.Ctx, topicID uuid.UUID, right topic.Right) (topic.Topic, error) {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return topic.Topic{}, user.ErrAuthorizationFailure
+	}
+
+	tpc, err := h.topic.QueryByID(c.UserContext(), topicID)
+	if err != nil {
+		return topic.Topic{}, err
+	}
+
+	visible, err := mid.TopicAllows(c, h.topic, tpc, usr.ID, topic.RightSubscribe)
+	if err != nil {
+		return topic.Topic{}, err
+	}
+
+	if !visible {
+		return topic.Topic{}, topic.ErrNotFound
+	}
+
+	allowed, err := mid.TopicAllows(c, h.topic, tpc, usr.ID, right)
+	if err != nil {
+		return topic.Topic{}, err
+	}
+
+	if !allowed {
+		return topic.Topic{}, errForbidden
+	}
+
+	return tpc, nil
+}
+
+var errForbidden = errors.New("missing right on topic")
+
+func checkTopicErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, user.ErrAuthorizationFailure):
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(err))
+	case errors.Is(err, errForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+	case errors.Is(err, topic.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(topic.ErrNotFound))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+}
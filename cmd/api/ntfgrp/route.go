@@ -1,41 +1,51 @@
 package ntfgrp
 
 import (
+	"github.com/dudakovict/gotify/internal/core/apikey"
 	"github.com/dudakovict/gotify/internal/core/notification"
-	notificationdb "github.com/dudakovict/gotify/internal/core/notification/store"
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/topic"
 	"github.com/dudakovict/gotify/internal/core/user"
-	userdb "github.com/dudakovict/gotify/internal/core/user/store"
+	"github.com/dudakovict/gotify/internal/stores"
 	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/maker"
 	"github.com/dudakovict/gotify/pkg/mid"
 	"github.com/gofiber/fiber/v2"
-	"github.com/jmoiron/sqlx"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
 type Config struct {
 	Log             *zerolog.Logger
-	DB              *sqlx.DB
+	Stores          stores.Stores
 	Maker           maker.Maker
 	TaskDistributor worker.TaskDistributor
 }
 
 func Routes(api fiber.Router, cfg Config) {
-	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
-	ntfCore := notification.NewCore(cfg.Log, notificationdb.NewStore(cfg.Log, cfg.DB))
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	ntfCore := notification.NewCore(cfg.Log, cfg.Stores.Notification)
+	keyCore := apikey.NewCore(cfg.Log, cfg.Stores.APIKey)
+	tpcCore := topic.NewCore(cfg.Log, cfg.Stores.Topic, uuid.New)
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
 
-	hdl := new(ntfCore, cfg.TaskDistributor)
+	hdl := new(ntfCore, tpcCore, cfg.TaskDistributor)
 
 	ntfgrp := api.Group(
 		"/notifications",
-		mid.Authenticate(cfg.Maker, []string{user.RoleUser.Name()}),
+		mid.Authenticate(cfg.Maker, keyCore),
 		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
 	)
 
-	ntfgrp.Get("", hdl.query)
-	ntfgrp.Post("", hdl.create)
-	ntfgrp.Get("/:id", hdl.queryByID)
-	ntfgrp.Delete("/:id", hdl.delete)
+	ntfgrp.Get("", mid.Require(role.PermNotificationRead), mid.RequireScope(apikey.ScopeNotificationsRead), hdl.query)
+	ntfgrp.Post("", mid.Require(role.PermNotificationCreate), mid.RequireScope(apikey.ScopeNotificationsPublish), hdl.create)
+	ntfgrp.Get("/inbox", mid.DenyAPIKeys(), mid.Require(role.PermNotificationRead), hdl.queryInbox)
+	ntfgrp.Get("/search", mid.Require(role.PermNotificationRead), mid.RequireScope(apikey.ScopeNotificationsRead), hdl.search)
+	ntfgrp.Get("/:id", mid.Require(role.PermNotificationRead), mid.RequireScope(apikey.ScopeNotificationsRead), hdl.queryByID)
+	ntfgrp.Delete("/:id", mid.DenyAPIKeys(), hdl.delete) // manage right on the topic or notification:delete
+	ntfgrp.Post("/:id/restore", mid.DenyAPIKeys(), mid.Require(role.PermNotificationDelete), hdl.restore)
+	ntfgrp.Get("/:id/deliveries", mid.DenyAPIKeys(), hdl.queryDeliveries) // manage right on the topic
 }
 
 func errorResponse(err error) fiber.Map {
@@ -0,0 +1,268 @@
+// Package rolegrp maintains the group of handlers for role access.
+package rolegrp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/gofiber/fiber/v2"
+)
+
+type handlers struct {
+	role *role.Core
+}
+
+func new(role *role.Core) *handlers {
+	return &handlers{
+		role: role,
+	}
+}
+
+type roleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	BuiltIn     bool      `json:"built_in"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newRoleResponse(r role.Role) roleResponse {
+	perms := make([]string, len(r.Permissions))
+	for i, perm := range r.Permissions {
+		perms[i] = perm.Name()
+	}
+
+	return roleResponse{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: perms,
+		BuiltIn:     r.BuiltIn,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
+
+type createRoleRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description" validate:"max=256"`
+	Permissions []string `json:"permissions" validate:"required"`
+}
+
+// @Summary Create a new role
+// @Description Creates a new role granting the provided permissions.
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Param body body createRoleRequest true "Create Role Request Body"
+// @Success 201 {object} roleResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /roles [post]
+func (h *handlers) create(c *fiber.Ctx) error {
+	var req createRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	perms, err := parsePermissions(req.Permissions)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	nr := role.NewRole{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: perms,
+	}
+
+	r, err := h.role.Create(c.UserContext(), nr)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidRole):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, role.ErrUniqueName):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newRoleResponse(r))
+}
+
+type updateRoleRequest struct {
+	Description *string  `json:"description" validate:"omitempty,max=256"`
+	Permissions []string `json:"permissions"`
+}
+
+// @Summary Update a role
+// @Description Updates the description and permissions of a role. Built-in
+// @Description roles can't be updated.
+// @Tags Role
+// @Accept json
+// @Produce json
+// @Param name path string true "Role Name"
+// @Param body body updateRoleRequest true "Update Role Request Body"
+// @Param If-Match header string true "ETag of the role when it was read"
+// @Success 200 {object} roleResponse
+// @Header 200 {string} ETag "Version of the role"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /roles/{name} [put]
+func (h *handlers) update(c *fiber.Ctx) error {
+	var req updateRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	var perms []role.Permission
+	if req.Permissions != nil {
+		var err error
+		perms, err = parsePermissions(req.Permissions)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+	}
+
+	r, err := h.role.QueryByName(c.UserContext(), c.Params("name"))
+	if err != nil {
+		if errors.Is(err, role.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	if err := mid.IfMatch(c, r.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	ur := role.UpdateRole{
+		Description: req.Description,
+		Permissions: perms,
+	}
+
+	updR, err := h.role.Update(c.UserContext(), r, ur)
+	if err != nil {
+		switch {
+		case errors.Is(err, role.ErrBuiltIn):
+			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+		case errors.Is(err, role.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	mid.SetETag(c, updR.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newRoleResponse(updR))
+}
+
+// @Summary Delete a role
+// @Description Deletes a role by its name. Built-in roles and roles assigned
+// @Description to users can't be deleted.
+// @Tags Role
+// @Param name path string true "Role Name"
+// @Param If-Match header string true "ETag of the role when it was read"
+// @Success 204 "No Content"
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /roles/{name} [delete]
+func (h *handlers) delete(c *fiber.Ctx) error {
+	r, err := h.role.QueryByName(c.UserContext(), c.Params("name"))
+	if err != nil {
+		if errors.Is(err, role.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	if err := mid.IfMatch(c, r.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	if err := h.role.Delete(c.UserContext(), r); err != nil {
+		switch {
+		case errors.Is(err, role.ErrBuiltIn):
+			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+		case errors.Is(err, role.ErrInUse):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		case errors.Is(err, role.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// @Summary Query roles
+// @Description Queries all roles together with their permissions.
+// @Tags Role
+// @Produce json
+// @Success 200 {array} roleResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /roles [get]
+func (h *handlers) query(c *fiber.Ctx) error {
+	roles, err := h.role.Query(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	rolesResponse := make([]roleResponse, len(roles))
+	for i, r := range roles {
+		rolesResponse[i] = newRoleResponse(r)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(rolesResponse)
+}
+
+// @Summary Query a role by name
+// @Description Queries a role by its name.
+// @Tags Role
+// @Produce json
+// @Param name path string true "Role Name"
+// @Success 200 {object} roleResponse
+// @Header 200 {string} ETag "Version of the role"
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /roles/{name} [get]
+func (h *handlers) queryByName(c *fiber.Ctx) error {
+	r, err := h.role.QueryByName(c.UserContext(), c.Params("name"))
+	if err != nil {
+		if errors.Is(err, role.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	mid.SetETag(c, r.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newRoleResponse(r))
+}
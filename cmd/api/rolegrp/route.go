@@ -0,0 +1,57 @@
+package rolegrp
+
+import (
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/internal/stores"
+	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+)
+
+type Config struct {
+	Log    *zerolog.Logger
+	Stores stores.Stores
+	Maker  maker.Maker
+}
+
+func Routes(api fiber.Router, cfg Config) {
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
+
+	hdl := new(roleCore)
+
+	rolegrp := api.Group(
+		"/roles",
+		mid.Authenticate(cfg.Maker, nil),
+		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
+	)
+
+	rolegrp.Get("", mid.Require(role.PermRoleRead), hdl.query)
+	rolegrp.Post("", mid.Require(role.PermRoleManage), hdl.create)
+	rolegrp.Get("/:name", mid.Require(role.PermRoleRead), hdl.queryByName)
+	rolegrp.Put("/:name", mid.Require(role.PermRoleManage), hdl.update)
+	rolegrp.Delete("/:name", mid.Require(role.PermRoleManage), hdl.delete)
+}
+
+func errorResponse(err error) fiber.Map {
+	return fiber.Map{
+		"error": err.Error(),
+	}
+}
+
+func parsePermissions(perms []string) ([]role.Permission, error) {
+	rolePerms := make([]role.Permission, len(perms))
+
+	for i, perm := range perms {
+		rolePerm, err := role.ParsePermission(perm)
+		if err != nil {
+			return nil, err
+		}
+		rolePerms[i] = rolePerm
+	}
+
+	return rolePerms, nil
+}
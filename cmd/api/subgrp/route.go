@@ -1,43 +1,66 @@
 package subgrp
 
 import (
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/internal/core/role"
 	"github.com/dudakovict/gotify/internal/core/subscription"
-	subscriptiondb "github.com/dudakovict/gotify/internal/core/subscription/store"
 	"github.com/dudakovict/gotify/internal/core/topic"
-	topicdb "github.com/dudakovict/gotify/internal/core/topic/store"
 	"github.com/dudakovict/gotify/internal/core/user"
-	userdb "github.com/dudakovict/gotify/internal/core/user/store"
+	"github.com/dudakovict/gotify/internal/stores"
+	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/maker"
 	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/dudakovict/gotify/pkg/signer"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
 type Config struct {
-	Log   *zerolog.Logger
-	DB    *sqlx.DB
-	Maker maker.Maker
+	Log             *zerolog.Logger
+	Stores          stores.Stores
+	Maker           maker.Maker
+	TaskDistributor worker.TaskDistributor
+	Signer          *signer.Signer
 }
 
 func Routes(api fiber.Router, cfg Config) {
-	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
-	subCore := subscription.NewCore(cfg.Log, subscriptiondb.NewStore(cfg.Log, cfg.DB))
-	tpcCore := topic.NewCore(cfg.Log, topicdb.NewStore(cfg.Log, cfg.DB), uuid.New)
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	subCore := subscription.NewCore(cfg.Log, cfg.Stores.Subscription)
+	tpcCore := topic.NewCore(cfg.Log, cfg.Stores.Topic, uuid.New)
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
+	grpCore := group.NewCore(cfg.Log, cfg.Stores.Group)
 
-	hdl := new(subCore, tpcCore)
+	hdl := new(subCore, tpcCore, grpCore, cfg.TaskDistributor, cfg.Signer)
+
+	// Unsubscribe links are followed from emails, so they are trusted for
+	// their signature instead of a session.
+	api.Get("/unsubscribe", hdl.unsubscribeForm)
+	api.Post("/unsubscribe", hdl.unsubscribe)
+
+	// Guests subscribe with nothing but their email address, which they
+	// confirm before getting any notifications.
+	api.Post("/guest-subscriptions", hdl.createGuest)
 
 	subgrp := api.Group(
 		"/subscriptions",
-		mid.Authenticate(cfg.Maker, []string{user.RoleUser.Name()}),
+		mid.Authenticate(cfg.Maker, nil),
 		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
 	)
 
-	subgrp.Get("", hdl.query)
-	subgrp.Post("", hdl.create)
-	subgrp.Get("/:id", hdl.queryByID)
-	subgrp.Delete("/:id", hdl.delete)
+	subgrp.Get("", mid.Require(role.PermSubscriptionManage), hdl.query)
+	subgrp.Post("", mid.Require(role.PermSubscriptionCreate), hdl.create)
+	subgrp.Post("/bulk/users", mid.Require(role.PermSubscriptionManage), hdl.bulkUsers)
+	subgrp.Post("/bulk/topics", mid.Require(role.PermSubscriptionManage), hdl.bulkTopics)
+	subgrp.Post("/import", mid.Require(role.PermSubscriptionManage), hdl.importCSV)
+	subgrp.Get("/jobs/:id", mid.Require(role.PermSubscriptionManage), hdl.queryJobByID)
+	subgrp.Get("/:id", mid.Require(role.PermSubscriptionRead), hdl.queryByID)
+	subgrp.Put("/:id", mid.Require(role.PermSubscriptionCreate), hdl.update)
+	subgrp.Post("/:id/mute", mid.Require(role.PermSubscriptionCreate), hdl.mute)
+	subgrp.Post("/:id/unmute", mid.Require(role.PermSubscriptionCreate), hdl.unmute)
+	subgrp.Delete("/:id", mid.Require(role.PermSubscriptionDelete), hdl.delete)
+	subgrp.Post("/:id/restore", mid.Require(role.PermSubscriptionManage), hdl.restore)
 }
 
 func errorResponse(err error) fiber.Map {
@@ -2,55 +2,224 @@
 package subgrp
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"errors"
+	"fmt"
+	"io"
+	"regexp"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/role"
 	"github.com/dudakovict/gotify/internal/core/subscription"
 	"github.com/dudakovict/gotify/internal/core/topic"
 	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/dudakovict/gotify/pkg/signer"
 	"github.com/dudakovict/gotify/pkg/util"
 	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
 )
 
 type handlers struct {
-	subscription *subscription.Core
-	topic        *topic.Core
+	subscription    *subscription.Core
+	topic           *topic.Core
+	group           *group.Core
+	taskDistributor worker.TaskDistributor
+	signer          *signer.Signer
 }
 
-func new(subscription *subscription.Core, topic *topic.Core) *handlers {
+func new(subscription *subscription.Core, topic *topic.Core, group *group.Core, taskDistributor worker.TaskDistributor, signer *signer.Signer) *handlers {
 	return &handlers{
-		subscription: subscription,
-		topic:        topic,
+		subscription:    subscription,
+		topic:           topic,
+		group:           group,
+		taskDistributor: taskDistributor,
+		signer:          signer,
 	}
 }
 
+type filter struct {
+	MinPriority string   `json:"min_priority,omitempty" validate:"omitempty,oneof=low normal high urgent"`
+	Tags        []string `json:"tags,omitempty" validate:"max=16,dive,required,max=64"`
+	Keyword     string   `json:"keyword,omitempty" validate:"max=255"`
+	Regex       string   `json:"regex,omitempty" validate:"max=255"`
+}
+
+func toCoreFilter(f filter) (subscription.Filter, error) {
+	sf := subscription.Filter{
+		Tags:    f.Tags,
+		Keyword: f.Keyword,
+	}
+
+	if f.MinPriority != "" {
+		priority, err := notification.ParsePriority(f.MinPriority)
+		if err != nil {
+			return subscription.Filter{}, err
+		}
+		sf.MinPriority = priority
+	}
+
+	if f.Regex != "" {
+		re, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return subscription.Filter{}, fmt.Errorf("invalid regex: %w", err)
+		}
+		sf.Regex = re
+	}
+
+	return sf, nil
+}
+
+func newFilter(sf subscription.Filter) *filter {
+	if sf.IsZero() {
+		return nil
+	}
+
+	f := filter{
+		MinPriority: sf.MinPriority.Name(),
+		Tags:        sf.Tags,
+		Keyword:     sf.Keyword,
+	}
+
+	if sf.Regex != nil {
+		f.Regex = sf.Regex.String()
+	}
+
+	return &f
+}
+
+type route struct {
+	Channel string `json:"channel" validate:"required,oneof=email webhook inapp sms"`
+	Address string `json:"address,omitempty" validate:"max=255"`
+
+	// Pending is only ever set in responses, for addresses that get no
+	// notifications until their owner confirms them.
+	Pending bool `json:"pending,omitempty"`
+}
+
+func toCoreRoutes(rts []route) ([]notification.Route, error) {
+	routes := make([]notification.Route, len(rts))
+	for i, rt := range rts {
+		channel, err := notification.ParseChannel(rt.Channel)
+		if err != nil {
+			return nil, err
+		}
+		routes[i] = notification.Route{Channel: channel, Address: rt.Address}
+	}
+
+	return routes, nil
+}
+
+func newRoutes(routes []notification.Route) []route {
+	rts := make([]route, len(routes))
+	for i, r := range routes {
+		rts[i] = route{Channel: r.Channel.Name(), Address: r.Address, Pending: r.Pending()}
+	}
+
+	return rts
+}
+
+// isRouteError reports whether the error is about an invalid route.
+func isRouteError(err error) bool {
+	return errors.Is(err, notification.ErrInvalidChannel) ||
+		errors.Is(err, notification.ErrMissingAddress) ||
+		errors.Is(err, notification.ErrInvalidAddress)
+}
+
 type createSubscriptionRequest struct {
-	TopicID uuid.UUID `json:"topic_id" validate:"required"`
-	UserID  uuid.UUID `json:"user_id" validate:"required"`
+	TopicID uuid.UUID `json:"topic_id" validate:"required_without=Pattern,excluded_with=Pattern"`
+	Pattern string    `json:"pattern" validate:"required_without=TopicID,max=255"`
+	UserID  uuid.UUID `json:"user_id" validate:"required_without=GroupID,excluded_with=GroupID"`
+	GroupID uuid.UUID `json:"group_id" validate:"required_without=UserID"`
+	Filter  filter    `json:"filter"`
+	Routes  []route   `json:"channels" validate:"max=8,dive"`
 }
 
 type subscriptionResponse struct {
-	ID        uuid.UUID `json:"id"`
-	TopicID   uuid.UUID `json:"topic_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         uuid.UUID  `json:"id"`
+	TopicID    *uuid.UUID `json:"topic_id,omitempty"`
+	Pattern    string     `json:"pattern,omitempty"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	GroupID    *uuid.UUID `json:"group_id,omitempty"`
+	GuestEmail string     `json:"guest_email,omitempty"`
+	Confirmed  bool       `json:"confirmed"`
+	Filter     *filter    `json:"filter,omitempty"`
+	Routes     []route    `json:"channels"`
+	Muted      bool       `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 func newSubscriptionResponse(sub subscription.Subscription) subscriptionResponse {
-	return subscriptionResponse{
-		ID:        sub.ID,
-		TopicID:   sub.TopicID,
-		UserID:    sub.UserID,
-		CreatedAt: sub.CreatedAt,
+	rsp := subscriptionResponse{
+		ID:         sub.ID,
+		Pattern:    sub.Pattern.String(),
+		GuestEmail: sub.GuestEmail,
+		Confirmed:  sub.Confirmed,
+		Filter:     newFilter(sub.Filter),
+		Routes:     newRoutes(sub.Routes),
+		Muted:      sub.IsMuted(time.Now()),
+		CreatedAt:  sub.CreatedAt,
+	}
+
+	if sub.UserID != uuid.Nil {
+		rsp.UserID = &sub.UserID
+	}
+
+	if sub.GroupID != uuid.Nil {
+		rsp.GroupID = &sub.GroupID
+	}
+
+	if rsp.Muted && !sub.MutedUntil.IsZero() {
+		rsp.MutedUntil = &sub.MutedUntil
+	}
+
+	if sub.TopicID != uuid.Nil {
+		rsp.TopicID = &sub.TopicID
+	}
+
+	if !sub.UpdatedAt.IsZero() {
+		rsp.UpdatedAt = &sub.UpdatedAt
 	}
+
+	if !sub.DeletedAt.IsZero() {
+		rsp.DeletedAt = &sub.DeletedAt
+	}
+
+	return rsp
 }
 
 // @Summary Create a new subscription
-// @Description Creates a new subscription with the provided topic ID and user ID.
+// @Description Creates a new subscription of the user to a topic, or to every
+// @Description topic matching a pattern. Patterns use the segments of
+// @Description hierarchical topic names, where * matches one segment and #
+// @Description matches any number of them, like infra.* or infra.#. Pattern
+// @Description subscriptions only deliver notifications of topics the user may
+// @Description subscribe to. An optional filter limits the notifications to a
+// @Description minimum priority, required tags, a keyword or a regex matched
+// @Description against the title and message. The channels pick where the
+// @Description notifications go, optionally with an address other than the
+// @Description user's own; without them the user's default channels are used.
+// @Description Email addresses and phone numbers given are sent a link to
+// @Description confirm them, and are pending until they do. Emails go to the
+// @Description user's own address meanwhile. A group can be subscribed instead
+// @Description of a user by those managing subscriptions. Its members get the
+// @Description notifications on their default channels, as long as they may
+// @Description subscribe to the topic themselves, so private topics only reach
+// @Description the members with access to them.
 // @Tags Subscription
 // @Accept json
 // @Produce json
@@ -59,6 +228,7 @@ func newSubscriptionResponse(sub subscription.Subscription) subscriptionResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /subscriptions [post]
 func (h *handlers) create(c *fiber.Ctx) error {
@@ -76,39 +246,651 @@ func (h *handlers) create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) && usr.ID != req.UserID {
+	if !mid.Can(c, role.PermSubscriptionManage) && usr.ID != req.UserID {
 		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if _, err := h.topic.QueryByID(req.TopicID); err != nil {
+	if req.GroupID != uuid.Nil {
+		if _, err := h.group.QueryByID(c.UserContext(), req.GroupID); err != nil {
+			if errors.Is(err, group.ErrNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	f, err := toCoreFilter(req.Filter)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	routes, err := toCoreRoutes(req.Routes)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	ns := subscription.NewSubscription{
+		UserID:  req.UserID,
+		GroupID: req.GroupID,
+		Filter:  f,
+		Routes:  routes,
+	}
+
+	switch {
+	case req.Pattern != "":
+		pattern, err := subscription.ParsePattern(req.Pattern)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		ns.Pattern = pattern
+
+	default:
+		tpc, err := h.topic.QueryByID(c.UserContext(), req.TopicID)
+		if err != nil {
+			if errors.Is(err, topic.ErrNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+
+		// Only private topics can deny subscribing, and those are hidden from
+		// users without access. Groups have no access of their own, so only
+		// those managing topics subscribe them to private ones.
+		allowed, err := mid.TopicAllows(c, h.topic, tpc, req.UserID, topic.RightSubscribe)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+
+		if !allowed {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(topic.ErrNotFound))
+		}
+		ns.TopicID = tpc.ID
+	}
+
+	sub, err := h.subscription.Create(c.UserContext(), ns)
+	if err != nil {
+		switch {
+		case errors.Is(err, subscription.ErrInvalidTarget), errors.Is(err, subscription.ErrInvalidOwner), errors.Is(err, subscription.ErrGroupRoutes), isRouteError(err):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, subscription.ErrUniqueViolation):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	if slices.ContainsFunc(sub.Routes, notification.Route.Pending) {
+		if err := h.sendConfirmation(c.UserContext(), sub); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newSubscriptionResponse(sub))
+}
+
+type createGuestSubscriptionRequest struct {
+	TopicID uuid.UUID `json:"topic_id" validate:"required"`
+	Email   string    `json:"email" validate:"required,email,max=255"`
+}
+
+// @Summary Subscribe as a guest
+// @Description Subscribes an email address to a public topic without an
+// @Description account. The address gets an email to confirm the subscription
+// @Description and receives no notifications until it does. Asking again
+// @Description resends the confirmation once the last one has expired, and the
+// @Description response never tells whether the address was already subscribed
+// @Description or a confirmation sent.
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param body body createGuestSubscriptionRequest true "Create Guest Subscription Request Body"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /guest-subscriptions [post]
+func (h *handlers) createGuest(c *fiber.Ctx) error {
+	var req createGuestSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	tpc, err := h.topic.QueryByID(c.UserContext(), req.TopicID)
+	if err != nil {
 		if errors.Is(err, topic.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	ns := subscription.NewSubscription{
-		TopicID: req.TopicID,
-		UserID:  req.UserID,
+	// Guests have no rights on any topic, so private topics are hidden
+	// from them.
+	if tpc.Visibility != topic.VisibilityPublic {
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(topic.ErrNotFound))
+	}
+
+	_, err = h.subscription.CreateGuest(c.UserContext(), subscription.NewGuestSubscription{
+		TopicID: tpc.ID,
+		Email:   req.Email,
+		AfterCreate: func(sub subscription.Subscription) error {
+			return h.sendConfirmation(c.UserContext(), sub)
+		},
+	})
+	switch {
+	case errors.Is(err, subscription.ErrUniqueViolation):
+		sub, err := h.subscription.QueryByGuest(c.UserContext(), tpc.ID, req.Email)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+
+		if !sub.Confirmed {
+			if err := h.sendConfirmation(c.UserContext(), sub); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+			}
+		}
+
+	case errors.Is(err, subscription.ErrInvalidEmail):
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "check your email to confirm the subscription",
+	})
+}
+
+// sendConfirmation asks the guest to confirm the subscription, or the owners
+// of the addresses its routes deliver to to confirm them.
+func (h *handlers) sendConfirmation(ctx context.Context, sub subscription.Subscription) error {
+	taskPayload := &worker.PayloadSendConfirmSubscription{
+		SubscriptionID: sub.ID,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.ProcessIn(10 * time.Second),
+		asynq.Queue(worker.QueueCritical),
+	}
+
+	return h.taskDistributor.DistributeTaskSendConfirmSubscription(ctx, taskPayload, opts...)
+}
+
+type rowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+type jobResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Status    string     `json:"status"`
+	Total     int        `json:"total"`
+	Processed int        `json:"processed"`
+	Created   int        `json:"created"`
+	Existing  int        `json:"existing"`
+	Errors    []rowError `json:"errors"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func newJobResponse(job subscription.Job) jobResponse {
+	rsp := jobResponse{
+		ID:        job.ID,
+		Status:    job.Status.Name(),
+		Total:     len(job.Rows),
+		Processed: job.Processed,
+		Created:   job.Created,
+		Existing:  job.Existing,
+		Errors:    make([]rowError, len(job.Errors)),
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+
+	for i, rowErr := range job.Errors {
+		rsp.Errors[i] = rowError(rowErr)
+	}
+
+	return rsp
+}
+
+type bulkUsersRequest struct {
+	TopicID uuid.UUID   `json:"topic_id" validate:"required"`
+	UserIDs []uuid.UUID `json:"user_ids" validate:"required,min=1,max=10000,dive,required"`
+}
+
+// @Summary Subscribe users to a topic
+// @Description Starts a job subscribing every user in the list to the topic.
+// @Description Users already subscribed are counted as existing, and users
+// @Description who can't be subscribed are listed in the errors of the job.
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param body body bulkUsersRequest true "Bulk Users Request Body"
+// @Success 202 {object} jobResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/bulk/users [post]
+func (h *handlers) bulkUsers(c *fiber.Ctx) error {
+	var req bulkUsersRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	rows := make([]subscription.JobRow, len(req.UserIDs))
+	for i, userID := range req.UserIDs {
+		rows[i] = subscription.JobRow{UserID: userID, TopicID: req.TopicID}
+	}
+
+	return h.createJob(c, rows)
+}
+
+type bulkTopicsRequest struct {
+	UserID   uuid.UUID   `json:"user_id" validate:"required"`
+	TopicIDs []uuid.UUID `json:"topic_ids" validate:"required,min=1,max=10000,dive,required"`
+}
+
+// @Summary Subscribe a user to topics
+// @Description Starts a job subscribing the user to every topic in the list.
+// @Description Topics the user is already subscribed to are counted as
+// @Description existing, and topics the user can't subscribe to are listed in
+// @Description the errors of the job.
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param body body bulkTopicsRequest true "Bulk Topics Request Body"
+// @Success 202 {object} jobResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/bulk/topics [post]
+func (h *handlers) bulkTopics(c *fiber.Ctx) error {
+	var req bulkTopicsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	rows := make([]subscription.JobRow, len(req.TopicIDs))
+	for i, topicID := range req.TopicIDs {
+		rows[i] = subscription.JobRow{UserID: req.UserID, TopicID: topicID}
+	}
+
+	return h.createJob(c, rows)
+}
+
+// @Summary Import subscriptions from CSV
+// @Description Starts a job subscribing users to topics from a CSV file, sent
+// @Description as the file field of a form or as a text/csv body. The header
+// @Description names an email and a topic column, where topics are given by ID
+// @Description or by name. Rows are numbered from 1 after the header in the
+// @Description errors of the job.
+// @Tags Subscription
+// @Accept mpfd
+// @Accept text/csv
+// @Produce json
+// @Param file formData file false "CSV File"
+// @Success 202 {object} jobResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/import [post]
+func (h *handlers) importCSV(c *fiber.Ctx) error {
+	var r io.Reader = bytes.NewReader(c.Body())
+
+	if fh, err := c.FormFile("file"); err == nil {
+		f, err := fh.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	rows, err := parseImport(r)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	sub, err := h.subscription.Create(ns)
+	return h.createJob(c, rows)
+}
+
+var errInvalidCSV = errors.New("invalid csv")
+
+// parseImport reads the rows of a subscription import. Topics that parse as
+// IDs are looked up by ID, all others by name.
+func parseImport(r io.Reader) ([]subscription.JobRow, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
 	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %s", errInvalidCSV, err)
+	}
+
+	emailCol, topicCol := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "email":
+			emailCol = i
+		case "topic":
+			topicCol = i
+		}
+	}
+
+	if emailCol == -1 || topicCol == -1 {
+		return nil, fmt.Errorf("%w: the header needs an email and a topic column", errInvalidCSV)
+	}
+
+	var rows []subscription.JobRow
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidCSV, err)
+		}
+
+		if len(rows) == subscription.MaxJobRows {
+			return nil, fmt.Errorf("%w: at most %d rows are allowed", errInvalidCSV, subscription.MaxJobRows)
+		}
+
+		row := subscription.JobRow{
+			Email: strings.TrimSpace(record[emailCol]),
+		}
+
+		tpc := strings.TrimSpace(record[topicCol])
+		if topicID, err := uuid.Parse(tpc); err == nil {
+			row.TopicID = topicID
+		} else {
+			row.Topic = tpc
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// createJob starts a job subscribing the rows and hands it to the worker.
+func (h *handlers) createJob(c *fiber.Ctx, rows []subscription.JobRow) error {
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	job, err := h.subscription.CreateJob(c.UserContext(), subscription.NewJob{
+		Rows:      rows,
+		CreatedBy: usr.ID,
+		AfterCreate: func(job subscription.Job) error {
+			return h.runJob(c.UserContext(), job)
+		},
+	})
+	if err != nil {
+		if errors.Is(err, subscription.ErrInvalidJob) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(newSubscriptionResponse(sub))
+	return c.Status(fiber.StatusAccepted).JSON(newJobResponse(job))
+}
+
+// runJob hands the job to the worker.
+func (h *handlers) runJob(ctx context.Context, job subscription.Job) error {
+	taskPayload := &worker.PayloadProcessSubscriptionJob{
+		JobID: job.ID,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.ProcessIn(10 * time.Second),
+		asynq.Queue(worker.QueueDefault),
+	}
+
+	return h.taskDistributor.DistributeTaskProcessSubscriptionJob(ctx, taskPayload, opts...)
+}
+
+// @Summary Get a subscription job by ID
+// @Description Retrieves the progress of a bulk subscription job, along with
+// @Description the rows that couldn't be subscribed and why.
+// @Tags Subscription
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} jobResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/jobs/{id} [get]
+func (h *handlers) queryJobByID(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	job, err := h.subscription.QueryJobByID(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, subscription.ErrJobNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newJobResponse(job))
+}
+
+type updateSubscriptionRequest struct {
+	Filter *filter `json:"filter"`
+	Routes []route `json:"channels" validate:"omitempty,max=8,dive"`
+}
+
+// @Summary Update a subscription
+// @Description Replaces the filter and the channels of a subscription. An
+// @Description empty filter lets every notification through, and no channels
+// @Description fall back to the user's default channels. Addresses confirmed
+// @Description before stay confirmed, new ones are sent a link to confirm them.
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param body body updateSubscriptionRequest true "Update Subscription Request Body"
+// @Param If-Match header string true "ETag of the subscription when it was read"
+// @Success 200 {object} subscriptionResponse
+// @Header 200 {string} ETag "Version of the subscription"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/{id} [put]
+func (h *handlers) update(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	var req updateSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	sub, err := h.subscription.QueryByID(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, subscription.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	if !mid.Can(c, role.PermSubscriptionManage) && usr.ID != sub.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	if err := mid.IfMatch(c, sub.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	var us subscription.UpdateSubscription
+	if req.Filter != nil {
+		f, err := toCoreFilter(*req.Filter)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		us.Filter = &f
+	}
+
+	if req.Routes != nil {
+		us.Routes, err = toCoreRoutes(req.Routes)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+	}
+
+	sub, err = h.subscription.Update(c.UserContext(), sub, us)
+	if err != nil {
+		switch {
+		case isRouteError(err):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, subscription.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	if slices.ContainsFunc(sub.Routes, notification.Route.Pending) {
+		if err := h.sendConfirmation(c.UserContext(), sub); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	mid.SetETag(c, sub.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newSubscriptionResponse(sub))
+}
+
+type muteSubscriptionRequest struct {
+	Until *time.Time `json:"until"`
+}
+
+// @Summary Mute a subscription
+// @Description Stops a subscription from delivering notifications until the
+// @Description given time, or until it is unmuted when no time is given. The
+// @Description subscription keeps its filter and channels.
+// @Tags Subscription
+// @Accept json
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Param body body muteSubscriptionRequest false "Mute Subscription Request Body"
+// @Success 200 {object} subscriptionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/{id}/mute [post]
+func (h *handlers) mute(c *fiber.Ctx) error {
+	var req muteSubscriptionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+	}
+
+	sub, err := h.queryOwned(c)
+	if err != nil {
+		return queryOwnedErrorResponse(c, err)
+	}
+
+	var until time.Time
+	if req.Until != nil {
+		until = *req.Until
+	}
+
+	sub, err = h.subscription.Mute(c.UserContext(), sub, until)
+	if err != nil {
+		switch {
+		case errors.Is(err, subscription.ErrInvalidMute):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, subscription.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newSubscriptionResponse(sub))
+}
+
+// @Summary Unmute a subscription
+// @Description Makes a muted subscription deliver notifications again before
+// @Description its time is up.
+// @Tags Subscription
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} subscriptionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/{id}/unmute [post]
+func (h *handlers) unmute(c *fiber.Ctx) error {
+	sub, err := h.queryOwned(c)
+	if err != nil {
+		return queryOwnedErrorResponse(c, err)
+	}
+
+	sub, err = h.subscription.Unmute(c.UserContext(), sub)
+	if err != nil {
+		if errors.Is(err, subscription.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newSubscriptionResponse(sub))
 }
 
 // @Summary Delete a subscription by ID
-// @Description Deletes a subscription with the specified ID.
+// @Description Deletes a subscription with the specified ID. It stops
+// @Description delivering notifications right away and can be restored until
+// @Description it is purged after the retention period.
 // @Tags Subscription
 // @Produce json
 // @Param id path string true "Subscription ID"
+// @Param If-Match header string true "ETag of the subscription when it was read"
 // @Success 204 "No Content"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /subscriptions/{id} [delete]
 func (h *handlers) delete(c *fiber.Ctx) error {
@@ -117,7 +899,7 @@ func (h *handlers) delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	sub, err := h.subscription.QueryByID(id)
+	sub, err := h.subscription.QueryByID(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, subscription.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
@@ -130,24 +912,77 @@ func (h *handlers) delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) && usr.ID != sub.UserID {
+	if !mid.Can(c, role.PermSubscriptionManage) && usr.ID != sub.UserID {
 		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if err := h.subscription.Delete(sub); err != nil {
+	if err := mid.IfMatch(c, sub.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	if err := h.subscription.Delete(c.UserContext(), sub); err != nil {
+		if errors.Is(err, subscription.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// @Summary Restore a subscription
+// @Description Restores a deleted subscription by its ID, unless an equal
+// @Description subscription has been made since.
+// @Tags Subscription
+// @Produce json
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} subscriptionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /subscriptions/{id}/restore [post]
+func (h *handlers) restore(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	querySub, err := h.subscription.QueryDeletedByID(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, subscription.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	sub, err := h.subscription.Restore(c.UserContext(), querySub)
+	if err != nil {
+		if errors.Is(err, subscription.ErrUniqueViolation) {
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newSubscriptionResponse(sub))
+}
+
 // @Summary Query subscriptions
-// @Description Queries subscriptions based on page number and rows per page.
+// @Description Queries subscriptions based on page number and rows per page,
+// @Description filtered and ordered as asked. The page comes with the total
+// @Description number of subscriptions matching the filters.
 // @Tags Subscription
 // @Produce json
-// @Param page query integer true "Page Number"
-// @Param rows query integer true "Rows Per Page"
-// @Success 200 {array} subscriptionResponse
+// @Param page query integer false "Page Number"
+// @Param rows query integer false "Rows Per Page"
+// @Param order_by query string false "Order, like created_at,desc" Enums(id, created_at)
+// @Param topic_id query string false "Topic ID"
+// @Param user_id query string false "User ID"
+// @Param group_id query string false "Group ID"
+// @Param muted query boolean false "Muted"
+// @Param deleted query boolean false "List the deleted subscriptions instead"
+// @Success 200 {object} util.PageResponse[subscriptionResponse]
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -158,16 +993,22 @@ func (h *handlers) query(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	usr, ok := c.Locals(mid.UserKey).(user.User)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) {
-		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+	orderBy, err := order.Parse(c.Query("order_by"), subscription.DefaultOrderBy, subscription.OrderByFields...)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	subs, err := h.subscription.Query(page.Number, page.RowsPerPage)
+	subs, err := h.subscription.Query(c.UserContext(), filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	total, err := h.subscription.Count(c.UserContext(), filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
@@ -177,7 +1018,53 @@ func (h *handlers) query(c *fiber.Ctx) error {
 		subsResponse[i] = newSubscriptionResponse(sub)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(subsResponse)
+	return c.Status(fiber.StatusOK).JSON(util.NewPageResponse(subsResponse, total, page))
+}
+
+// parseFilter parses the filters of the subscription query from the query
+// string.
+func parseFilter(c *fiber.Ctx) (subscription.QueryFilter, error) {
+	var filter subscription.QueryFilter
+
+	ids := []struct {
+		param string
+		dest  **uuid.UUID
+	}{
+		{param: "topic_id", dest: &filter.TopicID},
+		{param: "user_id", dest: &filter.UserID},
+		{param: "group_id", dest: &filter.GroupID},
+	}
+
+	for _, id := range ids {
+		value := c.Query(id.param)
+		if value == "" {
+			continue
+		}
+
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return subscription.QueryFilter{}, fmt.Errorf("invalid %s: %w", id.param, err)
+		}
+		*id.dest = &parsed
+	}
+
+	if value := c.Query("muted"); value != "" {
+		muted, err := strconv.ParseBool(value)
+		if err != nil {
+			return subscription.QueryFilter{}, fmt.Errorf("invalid muted: %w", err)
+		}
+		filter.Muted = &muted
+	}
+
+	if value := c.Query("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return subscription.QueryFilter{}, fmt.Errorf("invalid deleted: %w", err)
+		}
+		filter.Deleted = deleted
+	}
+
+	return filter, nil
 }
 
 // @Summary Query a subscription by ID
@@ -186,6 +1073,7 @@ func (h *handlers) query(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Subscription ID"
 // @Success 200 {object} subscriptionResponse
+// @Header 200 {string} ETag "Version of the subscription"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
@@ -197,9 +1085,9 @@ func (h *handlers) queryByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	sub, err := h.subscription.QueryByID(id)
+	sub, err := h.subscription.QueryByID(c.UserContext(), id)
 	if err != nil {
-		if errors.Is(err, topic.ErrNotFound) {
+		if errors.Is(err, subscription.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
@@ -210,9 +1098,205 @@ func (h *handlers) queryByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) && usr.ID != sub.UserID {
+	if !mid.Can(c, role.PermSubscriptionManage) && usr.ID != sub.UserID {
 		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
+	mid.SetETag(c, sub.Version)
+
 	return c.Status(fiber.StatusOK).JSON(newSubscriptionResponse(sub))
 }
+
+// queryOwned finds the subscription in the path and makes sure it belongs to
+// the authenticated user, unless they manage subscriptions.
+func (h *handlers) queryOwned(c *fiber.Ctx) (subscription.Subscription, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return subscription.Subscription{}, fmt.Errorf("%w: %s", errInvalidID, err)
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return subscription.Subscription{}, errUnauthenticated
+	}
+
+	sub, err := h.subscription.QueryByID(c.UserContext(), id)
+	if err != nil {
+		return subscription.Subscription{}, err
+	}
+
+	if !mid.Can(c, role.PermSubscriptionManage) && usr.ID != sub.UserID {
+		return subscription.Subscription{}, user.ErrAuthorizationFailure
+	}
+
+	return sub, nil
+}
+
+var (
+	errInvalidID       = errors.New("invalid id")
+	errUnauthenticated = errors.New("user is not authenticated")
+)
+
+func queryOwnedErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errInvalidID):
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	case errors.Is(err, errUnauthenticated):
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	case errors.Is(err, subscription.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+	case errors.Is(err, user.ErrAuthorizationFailure):
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+}
+
+// @Summary Show the unsubscribe form
+// @Description Shows a form confirming the unsubscribe from the signed link in
+// @Description notification emails. Following the link alone changes nothing,
+// @Description so link scanners can't unsubscribe anyone.
+// @Tags Subscription
+// @Produce html
+// @Param id query string true "Subscription ID"
+// @Param signature query string true "Signature"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /unsubscribe [get]
+func (h *handlers) unsubscribeForm(c *fiber.Ctx) error {
+	if _, err := h.verifyUnsubscribe(c); err != nil {
+		return unsubscribeErrorResponse(c, err)
+	}
+
+	c.Type("html")
+	return c.Status(fiber.StatusOK).SendString(`<!DOCTYPE html>
+<html>
+<body>
+	<p>Do you want to stop receiving these notifications?</p>
+	<form method="post">
+		<input type="hidden" name="List-Unsubscribe" value="One-Click">
+		<button type="submit">Unsubscribe</button>
+	</form>
+</body>
+</html>`)
+}
+
+// @Summary Unsubscribe
+// @Description Deletes the subscription from the signed link in notification
+// @Description emails without signing in. Mail clients call it directly for
+// @Description one-click unsubscribes (RFC 8058). Unsubscribing again succeeds.
+// @Tags Subscription
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param id query string true "Subscription ID"
+// @Param signature query string true "Signature"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /unsubscribe [post]
+func (h *handlers) unsubscribe(c *fiber.Ctx) error {
+	target, err := h.verifyUnsubscribe(c)
+	if err != nil {
+		return unsubscribeErrorResponse(c, err)
+	}
+
+	if target.groupID != uuid.Nil {
+		return h.leaveGroup(c, target)
+	}
+
+	sub, err := h.subscription.QueryByID(c.UserContext(), target.subscriptionID)
+	switch {
+	case errors.Is(err, subscription.ErrNotFound):
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	default:
+		if err := h.subscription.Delete(c.UserContext(), sub); err != nil {
+			if errors.Is(err, subscription.ErrVersionConflict) {
+				return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	c.Type("html")
+	return c.Status(fiber.StatusOK).SendString(`<!DOCTYPE html>
+<html>
+<body>
+	<p>You are unsubscribed and won't receive these notifications anymore.</p>
+</body>
+</html>`)
+}
+
+// leaveGroup takes the user out of the group from the unsubscribe link sent
+// to the members of a group. Leaving again succeeds.
+func (h *handlers) leaveGroup(c *fiber.Ctx, target unsubscribeTarget) error {
+	grp, err := h.group.QueryByID(c.UserContext(), target.groupID)
+	switch {
+	case errors.Is(err, group.ErrNotFound):
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	default:
+		if err := h.group.RemoveMember(c.UserContext(), grp, target.userID); err != nil && !errors.Is(err, group.ErrMemberNotFound) {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	c.Type("html")
+	return c.Status(fiber.StatusOK).SendString(`<!DOCTYPE html>
+<html>
+<body>
+	<p>You left the group and won't receive its notifications anymore.</p>
+</body>
+</html>`)
+}
+
+// unsubscribeTarget is what an unsubscribe link is for: either a
+// subscription, or a user to take out of a group.
+type unsubscribeTarget struct {
+	subscriptionID uuid.UUID
+	groupID        uuid.UUID
+	userID         uuid.UUID
+}
+
+// verifyUnsubscribe makes sure the unsubscribe link was signed for the
+// subscription, or for the member of the group, and returns what it is for.
+func (h *handlers) verifyUnsubscribe(c *fiber.Ctx) (unsubscribeTarget, error) {
+	if c.Query("group_id") != "" {
+		groupID, err := uuid.Parse(c.Query("group_id"))
+		if err != nil {
+			return unsubscribeTarget{}, err
+		}
+
+		userID, err := uuid.Parse(c.Query("user_id"))
+		if err != nil {
+			return unsubscribeTarget{}, err
+		}
+
+		if err := h.signer.Verify(worker.GroupMember(groupID, userID), c.Query("signature")); err != nil {
+			return unsubscribeTarget{}, err
+		}
+
+		return unsubscribeTarget{groupID: groupID, userID: userID}, nil
+	}
+
+	id, err := uuid.Parse(c.Query("id"))
+	if err != nil {
+		return unsubscribeTarget{}, err
+	}
+
+	if err := h.signer.Verify(id.String(), c.Query("signature")); err != nil {
+		return unsubscribeTarget{}, err
+	}
+
+	return unsubscribeTarget{subscriptionID: id}, nil
+}
+
+func unsubscribeErrorResponse(c *fiber.Ctx, err error) error {
+	if errors.Is(err, signer.ErrInvalidSignature) {
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+}
@@ -1,39 +1,59 @@
 package topicgrp
 
 import (
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/subscription"
 	"github.com/dudakovict/gotify/internal/core/topic"
-	topicdb "github.com/dudakovict/gotify/internal/core/topic/store"
 	"github.com/dudakovict/gotify/internal/core/user"
-	userdb "github.com/dudakovict/gotify/internal/core/user/store"
+	"github.com/dudakovict/gotify/internal/stores"
+	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/maker"
 	"github.com/dudakovict/gotify/pkg/mid"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
 type Config struct {
-	Log   *zerolog.Logger
-	DB    *sqlx.DB
-	Maker maker.Maker
+	Log             *zerolog.Logger
+	Stores          stores.Stores
+	Maker           maker.Maker
+	TaskDistributor worker.TaskDistributor
 }
 
 func Routes(api fiber.Router, cfg Config) {
-	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
-	tpcCore := topic.NewCore(cfg.Log, topicdb.NewStore(cfg.Log, cfg.DB), uuid.New)
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	tpcCore := topic.NewCore(cfg.Log, cfg.Stores.Topic, uuid.New)
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
+	subCore := subscription.NewCore(cfg.Log, cfg.Stores.Subscription)
 
-	hdl := new(tpcCore)
+	hdl := new(tpcCore, usrCore, subCore, cfg.TaskDistributor)
 
 	tpcgrp := api.Group(
 		"/topics",
-		mid.Authenticate(cfg.Maker, []string{user.RoleUser.Name()}),
+		mid.Authenticate(cfg.Maker, nil),
 		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
 	)
 
-	tpcgrp.Get("", hdl.query)
-	tpcgrp.Post("", hdl.create)
-	tpcgrp.Get("/:id", hdl.queryByID)
+	tpcgrp.Get("", mid.Require(role.PermTopicRead), hdl.query)
+	tpcgrp.Post("", mid.Require(role.PermTopicCreate), hdl.create)
+	tpcgrp.Post("/invitations/:id/accept", mid.Require(role.PermTopicRead), hdl.acceptInvitation)
+	tpcgrp.Get("/:id", mid.Require(role.PermTopicRead), hdl.queryByID)
+
+	// Changing, deleting and managing the access to a topic is up to its owner
+	// and those granted the manage right on it, which the handlers check. The
+	// topic:manage permission grants the manage right on every topic.
+	tpcgrp.Put("/:id", hdl.update)                    // manage right or topic:manage
+	tpcgrp.Post("/:id/archive", hdl.archive)          // manage right or topic:manage
+	tpcgrp.Post("/:id/unarchive", hdl.unarchive)      // manage right or topic:manage
+	tpcgrp.Post("/:id/deletion", hdl.requestDeletion) // manage right or topic:manage
+	tpcgrp.Delete("/:id", hdl.delete)                 // manage right or topic:manage
+	tpcgrp.Get("/:id/acl", hdl.queryAccess)           // manage right or topic:manage
+	tpcgrp.Put("/:id/acl/:user_id", hdl.grant)        // manage right or topic:manage
+	tpcgrp.Delete("/:id/acl/:user_id", hdl.revoke)    // manage right or topic:manage
+	tpcgrp.Post("/:id/invitations", hdl.invite)       // manage right or topic:manage
+	tpcgrp.Post("/:id/restore", mid.Require(role.PermTopicManage), hdl.restore)
 }
 
 func errorResponse(err error) fiber.Map {
@@ -41,3 +61,17 @@ func errorResponse(err error) fiber.Map {
 		"error": err.Error(),
 	}
 }
+
+func parseRights(rights []string) ([]topic.Right, error) {
+	topicRights := make([]topic.Right, len(rights))
+
+	for i, right := range rights {
+		topicRight, err := topic.ParseRight(right)
+		if err != nil {
+			return nil, err
+		}
+		topicRights[i] = topicRight
+	}
+
+	return topicRights, nil
+}
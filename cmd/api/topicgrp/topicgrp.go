@@ -3,45 +3,100 @@ package topicgrp
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/subscription"
 	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/internal/worker"
+	"github.com/dudakovict/gotify/pkg/mid"
 	"github.com/dudakovict/gotify/pkg/util"
 	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
 )
 
 type handlers struct {
-	topic *topic.Core
+	topic           *topic.Core
+	user            *user.Core
+	subscription    *subscription.Core
+	taskDistributor worker.TaskDistributor
 }
 
-func new(topic *topic.Core) *handlers {
+func new(topic *topic.Core, user *user.Core, subscription *subscription.Core, taskDistributor worker.TaskDistributor) *handlers {
 	return &handlers{
-		topic: topic,
+		topic:           topic,
+		user:            user,
+		subscription:    subscription,
+		taskDistributor: taskDistributor,
 	}
 }
 
 type createTopicRequest struct {
-	Name string `json:"name" validate:"required"`
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=1024"`
+	Visibility  string `json:"visibility" validate:"omitempty,oneof=public private"`
+	Retention   string `json:"retention"`
 }
 
 type topicResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	OwnerID     *uuid.UUID `json:"owner_id,omitempty"`
+	Visibility  string     `json:"visibility"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	Retention   string     `json:"retention,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
 }
 
 func newTopicResponse(tpc topic.Topic) topicResponse {
-	return topicResponse{
-		ID:        tpc.ID,
-		Name:      tpc.Name,
-		CreatedAt: tpc.CreatedAt,
+	rsp := topicResponse{
+		ID:          tpc.ID,
+		Name:        tpc.Name,
+		Description: tpc.Description,
+		Visibility:  tpc.Visibility.Name(),
+		CreatedAt:   tpc.CreatedAt,
+	}
+
+	if tpc.OwnerID != uuid.Nil {
+		rsp.OwnerID = &tpc.OwnerID
 	}
+
+	if tpc.Archived() {
+		rsp.ArchivedAt = &tpc.ArchivedAt
+	}
+
+	if tpc.Retention != 0 {
+		rsp.Retention = tpc.Retention.String()
+	}
+
+	if !tpc.UpdatedAt.IsZero() {
+		rsp.UpdatedAt = &tpc.UpdatedAt
+	}
+
+	if !tpc.DeletedAt.IsZero() {
+		rsp.DeletedAt = &tpc.DeletedAt
+	}
+
+	return rsp
 }
 
 // @Summary Create a new topic
-// @Description Creates a new topic with the provided name.
+// @Description Creates a new topic with the provided name, description and
+// @Description visibility. Names can be hierarchical, with segments separated
+// @Description by dots or slashes like infra.db.primary. The authenticated user
+// @Description becomes its owner. Notifications older than the retention,
+// @Description a duration like 720h, are removed. Without one the global
+// @Description retention applies.
 // @Tags Topic
 // @Accept json
 // @Produce json
@@ -49,6 +104,7 @@ func newTopicResponse(tpc topic.Topic) topicResponse {
 // @Success 201 {object} topicResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /topics [post]
 func (h *handlers) create(c *fiber.Ctx) error {
@@ -61,25 +117,380 @@ func (h *handlers) create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
 	nt := topic.NewTopic{
-		Name: req.Name,
+		Name:        req.Name,
+		Description: req.Description,
+		OwnerID:     usr.ID,
 	}
 
-	tpc, err := h.topic.Create(nt)
+	if req.Visibility != "" {
+		visibility, err := topic.ParseVisibility(req.Visibility)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		nt.Visibility = visibility
+	}
+
+	if req.Retention != "" {
+		retention, err := time.ParseDuration(req.Retention)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		nt.Retention = retention
+	}
+
+	tpc, err := h.topic.Create(c.UserContext(), nt)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		switch {
+		case errors.Is(err, topic.ErrInvalidName), errors.Is(err, topic.ErrInvalidRetention):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, topic.ErrUniqueName):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(newTopicResponse(tpc))
 }
 
+type updateTopicRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description" validate:"omitempty,max=1024"`
+	Retention   *string `json:"retention"`
+}
+
+// @Summary Update a topic
+// @Description Renames a topic or changes its description or retention. A
+// @Description retention of 0s falls back to the global one. Requires the
+// @Description manage right on the topic.
+// @Tags Topic
+// @Accept json
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Param If-Match header string true "ETag of the topic when it was read"
+// @Param body body updateTopicRequest true "Update Topic Request Body"
+// @Success 200 {object} topicResponse
+// @Header 200 {string} ETag "Version of the topic"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id} [put]
+func (h *handlers) update(c *fiber.Ctx) error {
+	var req updateTopicRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	if err := mid.IfMatch(c, tpc.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	ut := topic.UpdateTopic{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	if req.Retention != nil {
+		retention, err := time.ParseDuration(*req.Retention)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		ut.Retention = &retention
+	}
+
+	tpc, err = h.topic.Update(c.UserContext(), tpc, ut)
+	if err != nil {
+		switch {
+		case errors.Is(err, topic.ErrInvalidName), errors.Is(err, topic.ErrInvalidRetention):
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		case errors.Is(err, topic.ErrUniqueName):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		case errors.Is(err, topic.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	mid.SetETag(c, tpc.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newTopicResponse(tpc))
+}
+
+// @Summary Archive a topic
+// @Description Archives a topic. Archived topics keep their history but don't
+// @Description accept new notifications. Requires the manage right on the
+// @Description topic.
+// @Tags Topic
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Success 200 {object} topicResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/archive [post]
+func (h *handlers) archive(c *fiber.Ctx) error {
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	tpc, err = h.topic.Archive(c.UserContext(), tpc)
+	if err != nil {
+		if errors.Is(err, topic.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newTopicResponse(tpc))
+}
+
+// @Summary Unarchive a topic
+// @Description Makes an archived topic accept new notifications again.
+// @Description Requires the manage right on the topic.
+// @Tags Topic
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Success 200 {object} topicResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/unarchive [post]
+func (h *handlers) unarchive(c *fiber.Ctx) error {
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	tpc, err = h.topic.Unarchive(c.UserContext(), tpc)
+	if err != nil {
+		if errors.Is(err, topic.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newTopicResponse(tpc))
+}
+
+type deletionResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// @Summary Request the deletion of a topic
+// @Description Returns the token that confirms deleting a topic. Requesting
+// @Description it again replaces the previous token. Requires the manage right
+// @Description on the topic.
+// @Tags Topic
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Success 201 {object} deletionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/deletion [post]
+func (h *handlers) requestDeletion(c *fiber.Ctx) error {
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	d, token, err := h.topic.RequestDeletion(c.UserContext(), tpc, usr.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	rsp := deletionResponse{
+		Token:     token,
+		ExpiresAt: d.ExpiresAt,
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(rsp)
+}
+
+// @Summary Delete a topic
+// @Description Deletes a topic once confirmed with the token of the deletion
+// @Description request. Subscribers, confirmed guests included, are emailed
+// @Description about it and notifications still waiting to be sent are
+// @Description cancelled. The topic is kept, with its notifications,
+// @Description subscriptions and access, until it is purged after the
+// @Description retention period, and can be restored until then.
+// @Description Requires the manage right on the topic.
+// @Tags Topic
+// @Param id path string true "Topic ID"
+// @Param token query string true "Deletion Token"
+// @Param If-Match header string true "ETag of the topic when it was read"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id} [delete]
+func (h *handlers) delete(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(errTokenRequired))
+	}
+
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	if err := mid.IfMatch(c, tpc.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	usrs, err := h.user.QueryByTopicID(c.UserContext(), tpc.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	// Confirmed guests are told too, as they have no user to be found by.
+	guests, err := h.subscription.QueryConfirmedGuests(c.UserContext(), tpc.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	emails := make([]string, 0, len(usrs)+len(guests))
+	for _, usr := range usrs {
+		emails = append(emails, usr.Email)
+	}
+	for _, sub := range guests {
+		if !slices.Contains(emails, sub.GuestEmail) {
+			emails = append(emails, sub.GuestEmail)
+		}
+	}
+
+	dtTx := topic.DeleteTopicTx{
+		Token: token,
+		AfterDelete: func(tpc topic.Topic) error {
+			payload := &worker.PayloadSendTopicDeleted{
+				TopicName: tpc.Name,
+				Emails:    emails,
+			}
+
+			opts := []asynq.Option{
+				asynq.MaxRetry(10),
+				asynq.Queue(worker.QueueDefault),
+			}
+
+			if err := h.taskDistributor.DistributeTaskSendTopicDeleted(c.UserContext(), payload, opts...); err != nil {
+				return err
+			}
+
+			return h.taskDistributor.CancelTopicTasks(c.UserContext(), tpc.ID)
+		},
+	}
+
+	if err := h.topic.DeleteTx(c.UserContext(), tpc, dtTx); err != nil {
+		switch {
+		case errors.Is(err, topic.ErrDeletionNotFound):
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		case errors.Is(err, topic.ErrDeletionFailure):
+			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+		case errors.Is(err, topic.ErrDeletionExpired):
+			return c.Status(fiber.StatusGone).JSON(errorResponse(err))
+		case errors.Is(err, topic.ErrVersionConflict):
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// @Summary Restore a topic
+// @Description Restores a deleted topic by its ID, unless its name has been
+// @Description taken by another topic since. Notifications cancelled when it
+// @Description was deleted are not sent again.
+// @Tags Topic
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Success 200 {object} topicResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/restore [post]
+func (h *handlers) restore(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	queryTpc, err := h.topic.QueryDeletedByID(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, topic.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	tpc, err := h.topic.Restore(c.UserContext(), queryTpc)
+	if err != nil {
+		if errors.Is(err, topic.ErrUniqueName) {
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newTopicResponse(tpc))
+}
+
 // @Summary Query topics
-// @Description Queries topics based on page number and rows per page.
+// @Description Queries the public topics and the private topics the user has
+// @Description access to based on page number and rows per page, filtered and
+// @Description ordered as asked. The page comes with the total number of topics
+// @Description matching the filters.
 // @Tags Topic
 // @Produce json
-// @Param page query integer true "Page Number"
-// @Param rows query integer true "Rows Per Page"
-// @Success 200 {array} topicResponse
+// @Param page query integer false "Page Number"
+// @Param rows query integer false "Rows Per Page"
+// @Param order_by query string false "Order, like name,desc" Enums(id, name, created_at)
+// @Param name query string false "Part of the name"
+// @Param visibility query string false "Visibility" Enums(public, private)
+// @Param owner_id query string false "Owner ID"
+// @Param archived query boolean false "Archived"
+// @Param deleted query boolean false "List the deleted topics instead"
+// @Success 200 {object} util.PageResponse[topicResponse]
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -90,7 +501,34 @@ func (h *handlers) query(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	tpcs, err := h.topic.Query(page.Number, page.RowsPerPage)
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if !mid.Can(c, role.PermTopicManage) {
+		if filter.Deleted {
+			return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
+		}
+		filter.AccessibleTo = &usr.ID
+	}
+
+	orderBy, err := order.Parse(c.Query("order_by"), topic.DefaultOrderBy, topic.OrderByFields...)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	tpcs, err := h.topic.Query(c.UserContext(), filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	total, err := h.topic.Count(c.UserContext(), filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
@@ -100,7 +538,50 @@ func (h *handlers) query(c *fiber.Ctx) error {
 		tpcsResponse[i] = newTopicResponse(tpc)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(tpcsResponse)
+	return c.Status(fiber.StatusOK).JSON(util.NewPageResponse(tpcsResponse, total, page))
+}
+
+// parseFilter parses the filters of the topic query from the query string.
+func parseFilter(c *fiber.Ctx) (topic.QueryFilter, error) {
+	var filter topic.QueryFilter
+
+	if name := c.Query("name"); name != "" {
+		filter.Name = &name
+	}
+
+	if value := c.Query("visibility"); value != "" {
+		visibility, err := topic.ParseVisibility(value)
+		if err != nil {
+			return topic.QueryFilter{}, err
+		}
+		filter.Visibility = &visibility
+	}
+
+	if value := c.Query("owner_id"); value != "" {
+		ownerID, err := uuid.Parse(value)
+		if err != nil {
+			return topic.QueryFilter{}, fmt.Errorf("invalid owner_id: %w", err)
+		}
+		filter.OwnerID = &ownerID
+	}
+
+	if value := c.Query("archived"); value != "" {
+		archived, err := strconv.ParseBool(value)
+		if err != nil {
+			return topic.QueryFilter{}, fmt.Errorf("invalid archived: %w", err)
+		}
+		filter.Archived = &archived
+	}
+
+	if value := c.Query("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return topic.QueryFilter{}, fmt.Errorf("invalid deleted: %w", err)
+		}
+		filter.Deleted = deleted
+	}
+
+	return filter, nil
 }
 
 // @Summary Query a topic by ID
@@ -109,24 +590,386 @@ func (h *handlers) query(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Topic ID"
 // @Success 200 {object} topicResponse
+// @Header 200 {string} ETag "Version of the topic"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /topics/{id} [get]
 func (h *handlers) queryByID(c *fiber.Ctx) error {
-	id, err := uuid.Parse(c.Params("id"))
+	tpc, err := h.queryAllowed(c, topic.RightSubscribe)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	mid.SetETag(c, tpc.Version)
+
+	return c.Status(fiber.StatusOK).JSON(newTopicResponse(tpc))
+}
+
+type accessResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Rights    []string  `json:"rights"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newAccessResponse(a topic.Access) accessResponse {
+	rights := make([]string, len(a.Rights))
+	for i, right := range a.Rights {
+		rights[i] = right.Name()
+	}
+
+	return accessResponse{
+		UserID:    a.UserID,
+		Rights:    rights,
+		CreatedAt: a.CreatedAt,
+		UpdatedAt: a.UpdatedAt,
+	}
+}
+
+// @Summary Query the access list of a topic
+// @Description Queries the users granted access to a topic and their rights.
+// @Description Requires the manage right on the topic.
+// @Tags Topic
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Success 200 {array} accessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/acl [get]
+func (h *handlers) queryAccess(c *fiber.Ctx) error {
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	as, err := h.topic.QueryAccessByTopicID(c.UserContext(), tpc.ID)
 	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	asResponse := make([]accessResponse, len(as))
+	for i, a := range as {
+		asResponse[i] = newAccessResponse(a)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(asResponse)
+}
+
+type grantAccessRequest struct {
+	Rights []string `json:"rights" validate:"required,min=1"`
+}
+
+// @Summary Grant a user access to a topic
+// @Description Sets the rights of a user on a topic, replacing any granted
+// @Description before. Requires the manage right on the topic.
+// @Tags Topic
+// @Accept json
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Param user_id path string true "User ID"
+// @Param body body grantAccessRequest true "Grant Access Request Body"
+// @Success 200 {object} accessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/acl/{user_id} [put]
+func (h *handlers) grant(c *fiber.Ctx) error {
+	var req grantAccessRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	tpc, err := h.topic.QueryByID(id)
+	rights, err := parseRights(req.Rights)
 	if err != nil {
-		if errors.Is(err, topic.ErrNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	if _, err := h.user.QueryByID(c.UserContext(), userID); err != nil {
+		if errors.Is(err, user.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	return c.Status(fiber.StatusOK).JSON(newTopicResponse(tpc))
+	a, err := h.topic.Grant(c.UserContext(), tpc, userID, rights)
+	if err != nil {
+		if errors.Is(err, topic.ErrOwnerAccess) || errors.Is(err, topic.ErrRightsRequired) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newAccessResponse(a))
+}
+
+// @Summary Revoke the access of a user to a topic
+// @Description Removes the rights of a user on a topic. Subscriptions to
+// @Description private topics are removed as well. Requires the manage right
+// @Description on the topic.
+// @Tags Topic
+// @Param id path string true "Topic ID"
+// @Param user_id path string true "User ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/acl/{user_id} [delete]
+func (h *handlers) revoke(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	if err := h.topic.Revoke(c.UserContext(), tpc, userID); err != nil {
+		if errors.Is(err, topic.ErrAccessNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type inviteRequest struct {
+	Email  string   `json:"email" validate:"required,email"`
+	Rights []string `json:"rights" validate:"required,min=1"`
+}
+
+type invitationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	TopicID   uuid.UUID `json:"topic_id"`
+	Email     string    `json:"email"`
+	Rights    []string  `json:"rights"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newInvitationResponse(inv topic.Invitation) invitationResponse {
+	rights := make([]string, len(inv.Rights))
+	for i, right := range inv.Rights {
+		rights[i] = right.Name()
+	}
+
+	return invitationResponse{
+		ID:        inv.ID,
+		TopicID:   inv.TopicID,
+		Email:     inv.Email,
+		Rights:    rights,
+		ExpiresAt: inv.ExpiresAt,
+		CreatedAt: inv.CreatedAt,
+	}
+}
+
+// @Summary Invite someone to a topic
+// @Description Emails an invitation granting the rights on the topic. The
+// @Description invitee accepts it after signing in with the invited email.
+// @Description Requires the manage right on the topic.
+// @Tags Topic
+// @Accept json
+// @Produce json
+// @Param id path string true "Topic ID"
+// @Param body body inviteRequest true "Invite Request Body"
+// @Success 201 {object} invitationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/{id}/invitations [post]
+func (h *handlers) invite(c *fiber.Ctx) error {
+	var req inviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	rights, err := parseRights(req.Rights)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	tpc, err := h.queryAllowed(c, topic.RightManage)
+	if err != nil {
+		return queryAllowedErrorResponse(c, err)
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	ni := topic.NewInvitation{
+		TopicID:   tpc.ID,
+		Email:     req.Email,
+		Rights:    rights,
+		InvitedBy: usr.ID,
+	}
+
+	inv, token, err := h.topic.Invite(c.UserContext(), ni)
+	if err != nil {
+		if errors.Is(err, topic.ErrRightsRequired) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	payload := &worker.PayloadSendTopicInvitation{
+		InvitationID: inv.ID,
+		Token:        token,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.ProcessIn(10 * time.Second),
+		asynq.Queue(worker.QueueCritical),
+	}
+
+	if err := h.taskDistributor.DistributeTaskSendTopicInvitation(c.UserContext(), payload, opts...); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(newInvitationResponse(inv))
+}
+
+type acceptInvitationRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// @Summary Accept an invitation to a topic
+// @Description Grants the authenticated user the rights of the invitation. It
+// @Description has to be accepted with the invited email.
+// @Tags Topic
+// @Accept json
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Param body body acceptInvitationRequest true "Accept Invitation Request Body"
+// @Success 200 {object} accessResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /topics/invitations/{id}/accept [post]
+func (h *handlers) acceptInvitation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	var req acceptInvitationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := validate.Check(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	}
+
+	a, err := h.topic.Accept(c.UserContext(), id, req.Token, usr.ID, usr.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, topic.ErrInvitationNotFound), errors.Is(err, topic.ErrInvitationFailure):
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(topic.ErrInvitationNotFound))
+		case errors.Is(err, topic.ErrInvitationMismatch):
+			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+		case errors.Is(err, topic.ErrInvitationAccepted), errors.Is(err, topic.ErrInvitationExpired):
+			return c.Status(fiber.StatusGone).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newAccessResponse(a))
+}
+
+// queryAllowed finds the topic in the request and makes sure the user has the
+// right on it. Topics the user can't see at all are reported as not found.
+func (h *handlers) queryAllowed(c *fiber.Ctx, right topic.Right) (topic.Topic, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return topic.Topic{}, fmt.Errorf("%w: %s", errInvalidID, err)
+	}
+
+	usr, ok := c.Locals(mid.UserKey).(user.User)
+	if !ok {
+		return topic.Topic{}, user.ErrAuthorizationFailure
+	}
+
+	tpc, err := h.topic.QueryByID(c.UserContext(), id)
+	if err != nil {
+		return topic.Topic{}, err
+	}
+
+	visible, err := mid.TopicAllows(c, h.topic, tpc, usr.ID, topic.RightSubscribe)
+	if err != nil {
+		return topic.Topic{}, err
+	}
+
+	if !visible {
+		return topic.Topic{}, topic.ErrNotFound
+	}
+
+	allowed, err := mid.TopicAllows(c, h.topic, tpc, usr.ID, right)
+	if err != nil {
+		return topic.Topic{}, err
+	}
+
+	if !allowed {
+		return topic.Topic{}, errForbidden
+	}
+
+	return tpc, nil
+}
+
+var (
+	errInvalidID     = errors.New("invalid id")
+	errForbidden     = errors.New("missing right on topic")
+	errTokenRequired = errors.New("token is required")
+)
+
+func queryAllowedErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, errInvalidID):
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	case errors.Is(err, user.ErrAuthorizationFailure):
+		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(err))
+	case errors.Is(err, errForbidden):
+		return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
+	case errors.Is(err, topic.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(errorResponse(topic.ErrNotFound))
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
 }
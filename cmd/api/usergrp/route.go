@@ -1,37 +1,46 @@
 package usergrp
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/dudakovict/gotify/internal/core/role"
 	"github.com/dudakovict/gotify/internal/core/user"
-	userdb "github.com/dudakovict/gotify/internal/core/user/store"
+	"github.com/dudakovict/gotify/internal/stores"
+	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/maker"
 	"github.com/dudakovict/gotify/pkg/mid"
 	"github.com/gofiber/fiber/v2"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
 type Config struct {
-	Log   *zerolog.Logger
-	DB    *sqlx.DB
-	Maker maker.Maker
+	Log             *zerolog.Logger
+	Stores          stores.Stores
+	Maker           maker.Maker
+	TaskDistributor worker.TaskDistributor
 }
 
 func Routes(api fiber.Router, cfg Config) {
-	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+
+	roleCore := role.NewCore(cfg.Log, cfg.Stores.Role)
 
-	hdl := new(usrCore)
+	hdl := new(usrCore, roleCore, cfg.TaskDistributor)
 
 	usrgrp := api.Group(
 		"/users",
-		mid.Authenticate(cfg.Maker, []string{user.RoleUser.Name()}),
+		mid.Authenticate(cfg.Maker, nil),
 		mid.GetUser(usrCore),
+		mid.Authorize(roleCore),
 	)
 
-	usrgrp.Get("", hdl.query)
-	usrgrp.Post("", hdl.create)
-	usrgrp.Get("/:id", hdl.queryByID)
-	usrgrp.Put("/:id", hdl.update)
-	usrgrp.Delete("/:id", hdl.delete)
+	usrgrp.Get("", mid.Require(role.PermUserRead), hdl.query)
+	usrgrp.Post("", mid.Require(role.PermUserCreate), hdl.create)
+	usrgrp.Get("/:id", hdl.queryByID) // self or user:read
+	usrgrp.Put("/:id", hdl.update)    // self or user:update
+	usrgrp.Delete("/:id", hdl.delete) // self or user:delete
+	usrgrp.Post("/:id/restore", mid.Require(role.PermUserDelete), hdl.restore)
 }
 
 func errorResponse(err error) fiber.Map {
@@ -40,16 +49,21 @@ func errorResponse(err error) fiber.Map {
 	}
 }
 
-func parseRoles(roles []string) ([]user.Role, error) {
+// parseRoles parses the role names and makes sure the roles are defined.
+func (h *handlers) parseRoles(ctx context.Context, roles []string) ([]user.Role, error) {
 	userRoles := make([]user.Role, len(roles))
 
-	for i, role := range roles {
-		userRole, err := user.ParseRole(role)
+	for i, r := range roles {
+		userRole, err := user.ParseRole(r)
 		if err != nil {
 			return nil, err
 		}
 		userRoles[i] = userRole
 	}
 
+	if err := h.role.Check(ctx, roles); err != nil {
+		return nil, fmt.Errorf("check: %w", err)
+	}
+
 	return userRoles, nil
 }
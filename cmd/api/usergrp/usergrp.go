@@ -2,25 +2,37 @@
 package usergrp
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"slices"
+	"strconv"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/role"
 	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/mid"
 	"github.com/dudakovict/gotify/pkg/util"
 	"github.com/dudakovict/gotify/pkg/validate"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
 )
 
 type handlers struct {
-	user *user.Core
+	user            *user.Core
+	role            *role.Core
+	taskDistributor worker.TaskDistributor
 }
 
-func new(user *user.Core) *handlers {
+func new(user *user.Core, role *role.Core, taskDistributor worker.TaskDistributor) *handlers {
 	return &handlers{
-		user: user,
+		user:            user,
+		role:            role,
+		taskDistributor: taskDistributor,
 	}
 }
 
@@ -30,12 +42,45 @@ type createUserRequest struct {
 	Password string   `json:"password" validate:"required,min=6"`
 }
 
+type channelDefault struct {
+	Channel     string `json:"channel" validate:"required,oneof=email webhook inapp sms"`
+	Address     string `json:"address,omitempty" validate:"max=255"`
+	MinPriority string `json:"min_priority,omitempty" validate:"omitempty,oneof=low normal high urgent"`
+
+	// Pending is only ever set in responses, for addresses that get no
+	// notifications until their owner confirms them.
+	Pending bool `json:"pending,omitempty"`
+}
+
+func toCoreChannels(chs []channelDefault) ([]user.ChannelDefault, error) {
+	channels := make([]user.ChannelDefault, len(chs))
+	for i, ch := range chs {
+		channel, err := notification.ParseChannel(ch.Channel)
+		if err != nil {
+			return nil, err
+		}
+		channels[i].Channel = channel
+		channels[i].Address = ch.Address
+
+		if ch.MinPriority != "" {
+			channels[i].MinPriority, err = notification.ParsePriority(ch.MinPriority)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return channels, nil
+}
+
 type userResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	Roles     []string  `json:"roles"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID        `json:"id"`
+	Email     string           `json:"email"`
+	Roles     []string         `json:"roles"`
+	Channels  []channelDefault `json:"channels"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+	DeletedAt *time.Time       `json:"deleted_at,omitempty"`
 }
 
 func newUserResponse(usr user.User) userResponse {
@@ -44,13 +89,30 @@ func newUserResponse(usr user.User) userResponse {
 		roles[i] = role.Name()
 	}
 
-	return userResponse{
+	channels := make([]channelDefault, len(usr.Channels))
+	for i, cd := range usr.Channels {
+		channels[i] = channelDefault{
+			Channel:     cd.Channel.Name(),
+			Address:     cd.Address,
+			MinPriority: cd.MinPriority.Name(),
+			Pending:     cd.Pending(),
+		}
+	}
+
+	rsp := userResponse{
 		ID:        usr.ID,
 		Email:     usr.Email,
 		Roles:     roles,
+		Channels:  channels,
 		CreatedAt: usr.CreatedAt,
 		UpdatedAt: usr.UpdatedAt,
 	}
+
+	if !usr.DeletedAt.IsZero() {
+		rsp.DeletedAt = &usr.DeletedAt
+	}
+
+	return rsp
 }
 
 // @Summary Create a new user
@@ -74,17 +136,11 @@ func (h *handlers) create(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	usr, ok := c.Locals(mid.UserKey).(user.User)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
-	}
-
-	if !slices.Contains(usr.Roles, user.RoleAdmin) {
-		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
-	}
-
-	roles, err := parseRoles(req.Roles)
+	roles, err := h.parseRoles(c.UserContext(), req.Roles)
 	if err != nil {
+		if errors.Is(err, role.ErrNotFound) || errors.Is(err, user.ErrInvalidRole) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
@@ -94,7 +150,7 @@ func (h *handlers) create(c *fiber.Ctx) error {
 		Password: req.Password,
 	}
 
-	newUsr, err := h.user.Create(nu)
+	newUsr, err := h.user.Create(c.UserContext(), nu)
 	if err != nil {
 		if errors.Is(err, user.ErrUniqueEmail) {
 			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
@@ -106,22 +162,33 @@ func (h *handlers) create(c *fiber.Ctx) error {
 }
 
 type updateUserRequest struct {
-	Email    *string  `json:"email" validate:"omitempty,email"`
-	Roles    []string `json:"roles"`
-	Password *string  `json:"password"`
-	Verified *bool    `json:"verified"`
+	Email    *string          `json:"email" validate:"omitempty,email"`
+	Roles    []string         `json:"roles"`
+	Password *string          `json:"password"`
+	Verified *bool            `json:"verified"`
+	Channels []channelDefault `json:"channels" validate:"omitempty,max=8,dive"`
 }
 
 // @Summary Update a user
 // @Description Updates a user with the provided email, roles, password, and verification status.
+// @Description The channels are where the user gets notifications when a
+// @Description subscription doesn't pick its own, optionally only from a
+// @Description minimum priority up. Without any the user gets emails. Email
+// @Description addresses and phone numbers given are sent a link to confirm
+// @Description them, and are pending until they do. Emails go to the user's
+// @Description own address meanwhile.
 // @Tags User
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
+// @Param If-Match header string true "ETag of the user when it was read"
 // @Param body body updateUserRequest true "Update User Request Body"
 // @Success 200 {object} userResponse
+// @Header 200 {string} ETag "Version of the user"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /users/{id} [put]
 func (h *handlers) update(c *fiber.Ctx) error {
@@ -144,19 +211,33 @@ func (h *handlers) update(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) && usr.ID != id {
+	// Users can change their own email and password, but roles and the
+	// verification status are only managed by those allowed to update users.
+	canUpdate := mid.Can(c, role.PermUserUpdate)
+	if !canUpdate && (usr.ID != id || req.Roles != nil || req.Verified != nil) {
 		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
 	var roles []user.Role
 	if req.Roles != nil {
-		roles, err = parseRoles(req.Roles)
+		roles, err = h.parseRoles(c.UserContext(), req.Roles)
 		if err != nil {
+			if errors.Is(err, role.ErrNotFound) || errors.Is(err, user.ErrInvalidRole) {
+				return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+			}
 			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 		}
 	}
 
-	queryUsr, err := h.user.QueryByID(id)
+	var channels []user.ChannelDefault
+	if req.Channels != nil {
+		channels, err = toCoreChannels(req.Channels)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+	}
+
+	queryUsr, err := h.user.QueryByID(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, user.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
@@ -164,31 +245,71 @@ func (h *handlers) update(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
+	if err := mid.IfMatch(c, queryUsr.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
 	uu := user.UpdateUser{
 		Email:    req.Email,
 		Roles:    roles,
 		Password: req.Password,
 		Verified: req.Verified,
+		Channels: channels,
 	}
 
-	updUsr, err := h.user.Update(queryUsr, uu)
+	updUsr, err := h.user.Update(c.UserContext(), queryUsr, uu)
 	if err != nil {
 		if errors.Is(err, user.ErrUniqueEmail) {
 			return c.Status(fiber.StatusForbidden).JSON(errorResponse(err))
 		}
+		if errors.Is(err, notification.ErrMissingAddress) || errors.Is(err, notification.ErrInvalidAddress) {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+		}
+		if errors.Is(err, user.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
+	if slices.ContainsFunc(updUsr.Channels, func(cd user.ChannelDefault) bool { return cd.Pending() }) {
+		if err := h.sendConfirmation(c.UserContext(), updUsr); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	mid.SetETag(c, updUsr.Version)
+
 	return c.Status(fiber.StatusOK).JSON(newUserResponse(updUsr))
 }
 
+// sendConfirmation asks the owners of the addresses the default channels of
+// the user deliver to to confirm them.
+func (h *handlers) sendConfirmation(ctx context.Context, usr user.User) error {
+	taskPayload := &worker.PayloadSendConfirmChannels{
+		UserID: usr.ID,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(10),
+		asynq.ProcessIn(10 * time.Second),
+		asynq.Queue(worker.QueueCritical),
+	}
+
+	return h.taskDistributor.DistributeTaskSendConfirmChannels(ctx, taskPayload, opts...)
+}
+
 // @Summary Delete a user
-// @Description Deletes a user by its ID.
+// @Description Deletes a user by its ID. The user is kept, with its
+// @Description subscriptions and history, until it is purged after the
+// @Description retention period, and can be restored until then.
 // @Tags User
 // @Param id path string true "User ID"
+// @Param If-Match header string true "ETag of the user when it was read"
 // @Success 204 "No Content"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 428 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /users/{id} [delete]
 func (h *handlers) delete(c *fiber.Ctx) error {
@@ -202,11 +323,11 @@ func (h *handlers) delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) && usr.ID != id {
+	if !mid.Can(c, role.PermUserDelete) && usr.ID != id {
 		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	queryUsr, err := h.user.QueryByID(id)
+	queryUsr, err := h.user.QueryByID(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, user.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
@@ -214,20 +335,72 @@ func (h *handlers) delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	if err := h.user.Delete(queryUsr); err != nil {
+	if err := mid.IfMatch(c, queryUsr.Version); err != nil {
+		return c.Status(mid.PreconditionStatus(err)).JSON(errorResponse(err))
+	}
+
+	if err := h.user.Delete(c.UserContext(), queryUsr); err != nil {
+		if errors.Is(err, user.ErrVersionConflict) {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(errorResponse(err))
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// @Summary Restore a user
+// @Description Restores a deleted user by its ID, unless its email has been
+// @Description taken by another user since.
+// @Tags User
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} userResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /users/{id}/restore [post]
+func (h *handlers) restore(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	queryUsr, err := h.user.QueryDeletedByID(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	usr, err := h.user.Restore(c.UserContext(), queryUsr)
+	if err != nil {
+		if errors.Is(err, user.ErrUniqueEmail) {
+			return c.Status(fiber.StatusConflict).JSON(errorResponse(err))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newUserResponse(usr))
+}
+
 // @Summary Query users
-// @Description Queries users based on page number and rows per page.
+// @Description Queries users based on page number and rows per page, filtered
+// @Description and ordered as asked. The page comes with the total number of
+// @Description users matching the filters.
 // @Tags User
 // @Produce json
-// @Param page query integer true "Page Number"
-// @Param rows query integer true "Rows Per Page"
-// @Success 200 {array} userResponse
+// @Param page query integer false "Page Number"
+// @Param rows query integer false "Rows Per Page"
+// @Param order_by query string false "Order, like email,desc" Enums(id, email, created_at)
+// @Param email query string false "Part of the email"
+// @Param role query string false "Role"
+// @Param verified query boolean false "Verified"
+// @Param deleted query boolean false "List the deleted users instead"
+// @Success 200 {object} util.PageResponse[userResponse]
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -238,16 +411,26 @@ func (h *handlers) query(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	usr, ok := c.Locals(mid.UserKey).(user.User)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) {
+	if filter.Deleted && !mid.Can(c, role.PermUserDelete) {
 		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	users, err := h.user.Query(page.Number, page.RowsPerPage)
+	orderBy, err := order.Parse(c.Query("order_by"), user.DefaultOrderBy, user.OrderByFields...)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	users, err := h.user.Query(c.UserContext(), filter, orderBy, page.Number, page.RowsPerPage)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+	}
+
+	total, err := h.user.Count(c.UserContext(), filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
@@ -257,7 +440,42 @@ func (h *handlers) query(c *fiber.Ctx) error {
 		usrsResponse[i] = newUserResponse(user)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(usrsResponse)
+	return c.Status(fiber.StatusOK).JSON(util.NewPageResponse(usrsResponse, total, page))
+}
+
+// parseFilter parses the filters of the user query from the query string.
+func parseFilter(c *fiber.Ctx) (user.QueryFilter, error) {
+	var filter user.QueryFilter
+
+	if email := c.Query("email"); email != "" {
+		filter.Email = &email
+	}
+
+	if value := c.Query("role"); value != "" {
+		role, err := user.ParseRole(value)
+		if err != nil {
+			return user.QueryFilter{}, err
+		}
+		filter.Role = &role
+	}
+
+	if value := c.Query("verified"); value != "" {
+		verified, err := strconv.ParseBool(value)
+		if err != nil {
+			return user.QueryFilter{}, fmt.Errorf("invalid verified: %w", err)
+		}
+		filter.Verified = &verified
+	}
+
+	if value := c.Query("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return user.QueryFilter{}, fmt.Errorf("invalid deleted: %w", err)
+		}
+		filter.Deleted = deleted
+	}
+
+	return filter, nil
 }
 
 // @Summary Query a user by ID
@@ -266,6 +484,7 @@ func (h *handlers) query(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} userResponse
+// @Header 200 {string} ETag "Version of the user"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 403 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
@@ -282,11 +501,11 @@ func (h *handlers) queryByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	if !slices.Contains(usr.Roles, user.RoleAdmin) && usr.ID != id {
+	if !mid.Can(c, role.PermUserRead) && usr.ID != id {
 		return c.Status(fiber.StatusForbidden).JSON(errorResponse(user.ErrAuthorizationFailure))
 	}
 
-	queryUsr, err := h.user.QueryByID(id)
+	queryUsr, err := h.user.QueryByID(c.UserContext(), id)
 	if err != nil {
 		if errors.Is(err, user.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(errorResponse(err))
@@ -294,5 +513,7 @@ func (h *handlers) queryByID(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
+	mid.SetETag(c, queryUsr.Version)
+
 	return c.Status(fiber.StatusOK).JSON(newUserResponse(queryUsr))
 }
@@ -1,27 +1,32 @@
 package vrfgrp
 
 import (
+	"github.com/dudakovict/gotify/internal/core/subscription"
 	"github.com/dudakovict/gotify/internal/core/user"
-	userdb "github.com/dudakovict/gotify/internal/core/user/store"
 	"github.com/dudakovict/gotify/internal/core/verification"
-	verificationdb "github.com/dudakovict/gotify/internal/core/verification/store"
+	"github.com/dudakovict/gotify/internal/stores"
 	"github.com/gofiber/fiber/v2"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
 type Config struct {
-	Log *zerolog.Logger
-	DB  *sqlx.DB
+	Log    *zerolog.Logger
+	Stores stores.Stores
 }
 
 func Routes(api fiber.Router, cfg Config) {
-	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
-	vrfCore := verification.NewCore(cfg.Log, usrCore, verificationdb.NewStore(cfg.Log, cfg.DB))
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	subCore := subscription.NewCore(cfg.Log, cfg.Stores.Subscription)
+	vrfCore := verification.NewCore(cfg.Log, usrCore, subCore, cfg.Stores.Verification)
 
 	hdl := new(vrfCore)
 
 	api.Get("/verify", hdl.verify)
+
+	// Confirmation links are followed from emails, so they only change
+	// anything once the form is submitted.
+	api.Get("/confirm", hdl.confirmForm)
+	api.Post("/confirm", hdl.confirm)
 }
 
 func errorResponse(err error) fiber.Map {
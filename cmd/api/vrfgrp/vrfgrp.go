@@ -2,6 +2,10 @@
 package vrfgrp
 
 import (
+	"errors"
+
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/dudakovict/gotify/internal/core/verification"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -35,7 +39,7 @@ func (h *handlers) verify(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
 
-	usr, err := h.verification.Verify(parsedID)
+	usr, err := h.verification.Verify(c.UserContext(), parsedID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
 	}
@@ -48,3 +52,77 @@ func (h *handlers) verify(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(rsp)
 }
+
+// @Summary Show the subscription confirmation form
+// @Description Shows a form confirming the guest subscription or the address
+// @Description from the link in the confirmation. Following the link alone
+// @Description changes nothing, so link scanners can't confirm anything.
+// @Tags Verification
+// @Produce html
+// @Param id query string true "Verification ID"
+// @Param code query string true "Verification Code"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]interface{}
+// @Router /confirm [get]
+func (h *handlers) confirmForm(c *fiber.Ctx) error {
+	if _, err := uuid.Parse(c.Query("id")); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	c.Type("html")
+	return c.Status(fiber.StatusOK).SendString(`<!DOCTYPE html>
+<html>
+<body>
+	<p>Do you want to receive these notifications?</p>
+	<form method="post">
+		<button type="submit">Confirm subscription</button>
+	</form>
+</body>
+</html>`)
+}
+
+// @Summary Confirm a guest subscription or an address
+// @Description Confirms the guest subscription from the link in the
+// @Description confirmation email, after which it delivers notifications. Links
+// @Description sent to the email addresses and phone numbers given for
+// @Description subscriptions and default channels confirm those addresses,
+// @Description which get no notifications before.
+// @Tags Verification
+// @Produce html
+// @Param id query string true "Verification ID"
+// @Param code query string true "Verification Code"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /confirm [post]
+func (h *handlers) confirm(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Query("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse(err))
+	}
+
+	if err := h.verification.Confirm(c.UserContext(), id, c.Query("code")); err != nil {
+		switch {
+		case errors.Is(err, verification.ErrNotFound),
+			errors.Is(err, subscription.ErrNotFound),
+			errors.Is(err, subscription.ErrRouteNotFound),
+			errors.Is(err, user.ErrNotFound),
+			errors.Is(err, user.ErrChannelNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(errorResponse(verification.ErrNotFound))
+		case errors.Is(err, verification.ErrExpired):
+			return c.Status(fiber.StatusGone).JSON(errorResponse(err))
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse(err))
+		}
+	}
+
+	c.Type("html")
+	return c.Status(fiber.StatusOK).SendString(`<!DOCTYPE html>
+<html>
+<body>
+	<p>Confirmed. You'll receive these notifications from now on.</p>
+</body>
+</html>`)
+}
@@ -2,32 +2,48 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"flag"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
 	swagger "github.com/arsmn/fiber-swagger/v2"
 	"github.com/dudakovict/gotify/cmd/api"
 	_ "github.com/dudakovict/gotify/docs/swagger"
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	"github.com/dudakovict/gotify/internal/core/sso"
+	"github.com/dudakovict/gotify/internal/stores"
 	"github.com/dudakovict/gotify/internal/worker"
 	"github.com/dudakovict/gotify/pkg/config"
+	"github.com/dudakovict/gotify/pkg/encrypt"
 	"github.com/dudakovict/gotify/pkg/mailer"
 	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/dudakovict/gotify/pkg/mid"
+	"github.com/dudakovict/gotify/pkg/signer"
+	"github.com/dudakovict/gotify/pkg/sms"
+	"github.com/dudakovict/gotify/pkg/webhook"
 	"github.com/dudakovict/gotify/platform/database"
 	"github.com/dudakovict/gotify/platform/logger"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/dudakovict/gotify/platform/migrations"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/gofiber/contrib/fiberzerolog"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	expvarmw "github.com/gofiber/fiber/v2/middleware/expvar"
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
 )
@@ -40,14 +56,25 @@ var build = "develop"
 // @host localhost:3000
 // @BasePath /api/v1
 func main() {
+	dev := flag.Bool("dev", false, "run without Postgres and Redis, keeping all data in memory")
+	flag.Parse()
+
 	log := logger.New()
 
 	log.Info().
 		Str("VERSION", build).
 		Int("GOMAXPROCS", runtime.GOMAXPROCS(0))
 
-	if err := run(log); err != nil {
-		log.Error().Err(err)
+	var err error
+	switch flag.Arg(0) {
+	case "migrate":
+		err = runMigrate(log, flag.Args()[1:])
+	default:
+		err = run(log, *dev)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Send()
 		os.Exit(1)
 	}
 }
@@ -58,92 +85,235 @@ var interruptSignals = []os.Signal{
 	syscall.SIGINT,
 }
 
-func run(log *zerolog.Logger) error {
+func run(log *zerolog.Logger, dev bool) error {
 	cfg, err := config.Load(".")
 	if err != nil {
 		return err
 	}
 
+	if dev {
+		if err := devDefaults(log, &cfg); err != nil {
+			return err
+		}
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
 	defer stop()
 
-	db, err := database.Open(database.Config{
-		User:         cfg.User,
-		Password:     cfg.Password,
-		Host:         cfg.Host,
-		Name:         cfg.Name,
-		MaxIdleConns: cfg.MaxIdleConns,
-		MaxOpenConns: cfg.MaxOpenConns,
-		DisableTLS:   cfg.DisableTLS,
-	})
+	var strs stores.Stores
+	if dev {
+		strs = stores.NewMemory(log, memdb.New())
+
+		log.Info().Msg("dev mode: keeping all data in memory")
+	} else {
+		db, err := openDB(log, ctx, cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if cfg.DisableAutoMigrate {
+			log.Info().Msg("auto migration disabled, run gotify migrate up to migrate the database")
+		} else {
+			if err := migrations.Migrate(ctx, db.DB, cfg.Driver); err != nil {
+				return fmt.Errorf("migrate: %w", err)
+			}
+
+			log.Info().Msg("db migrated successfully")
+		}
+
+		switch cfg.Driver {
+		case database.DriverSQLite:
+			strs = stores.NewSQLite(log, db)
+		default:
+			strs = stores.NewPostgres(log, db)
+		}
+	}
+
+	maker, err := maker.NewPasetoMaker(cfg.TokenSymmetricKey)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	if err := database.Ping(ctx, db); err != nil {
+	mfaEncrypter, err := encrypt.New(cfg.TokenSymmetricKey, "mfa")
+	if err != nil {
 		return err
 	}
 
-	log.Info().Msg("connected to database")
-
-	migration, err := migrate.New(cfg.MigrationURL, cfg.DatabaseURL)
+	unsubscribeSigner, err := signer.New(cfg.TokenSymmetricKey, "unsubscribe")
 	if err != nil {
-		log.Error().Err(err)
 		return err
 	}
 
-	if err := migration.Up(); err != nil && err != migrate.ErrNoChange {
-		return err
+	mfaIssuer := cfg.MFAIssuer
+	if mfaIssuer == "" {
+		mfaIssuer = "Gotify"
 	}
 
-	log.Info().Msg("db migrated successfully")
+	rpName := cfg.WebAuthnRPName
+	if rpName == "" {
+		rpName = mfaIssuer
+	}
 
-	maker, err := maker.NewPasetoMaker(cfg.TokenSymmetricKey)
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: rpName,
+		RPOrigins:     cfg.WebAuthnRPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login: webauthn.TimeoutConfig{
+				Enforce: true,
+				Timeout: passkey.CeremonyDuration,
+			},
+			Registration: webauthn.TimeoutConfig{
+				Enforce: true,
+				Timeout: passkey.CeremonyDuration,
+			},
+		},
+	})
 	if err != nil {
 		return err
 	}
 
-	redisOpt := asynq.RedisClientOpt{
-		Addr: cfg.RedisHost,
+	var ssoProvider *sso.Provider
+	if cfg.OIDC.IssuerURL != "" {
+		ssoProvider, err = sso.NewProvider(ctx, sso.ProviderConfig{
+			IssuerURL:     cfg.OIDC.IssuerURL,
+			ClientID:      cfg.OIDC.ClientID,
+			ClientSecret:  cfg.OIDC.ClientSecret,
+			RedirectURL:   cfg.OIDC.RedirectURL,
+			Scopes:        cfg.OIDC.Scopes,
+			GroupsClaim:   cfg.OIDC.GroupsClaim,
+			GroupRoles:    cfg.OIDC.GroupRoles,
+			AutoProvision: cfg.OIDC.AutoProvision,
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("issuer", cfg.OIDC.IssuerURL).Msg("oidc provider discovered")
 	}
 
-	mailer := mailer.NewGmailMailer(cfg.MailerName, cfg.EmailAddress, cfg.EmailPassword)
+	// In dev mode emails are captured instead of sent, and can be read back
+	// from the /dev/emails endpoint.
+	var (
+		mlr     mailer.Mailer
+		capture *mailer.CaptureMailer
+	)
+	if dev {
+		capture = mailer.NewCaptureMailer()
+		mlr = capture
+	} else {
+		mlr = mailer.NewGmailMailer(cfg.MailerName, cfg.EmailAddress, cfg.EmailPassword)
+	}
 
-	worker := worker.New(worker.Config{
-		Log:      log,
-		DB:       db,
-		RedisOpt: redisOpt,
-		Mailer:   mailer,
-	})
+	webhookTimeout := cfg.WebhookTimeout
+	if webhookTimeout == 0 {
+		webhookTimeout = 10 * time.Second
+	}
+
+	// Text messages are only sent when a provider is configured, deliveries
+	// to the sms channel are skipped otherwise.
+	var smsSender sms.Sender
+	if cfg.TwilioAccountSID != "" {
+		smsSender = sms.NewTwilioSender(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.SMSFromNumber)
+	}
+
+	deletedRetention := cfg.DeletedRetention
+	if deletedRetention == 0 {
+		deletedRetention = 30 * 24 * time.Hour
+	}
+
+	purgeBatchSize := cfg.PurgeBatchSize
+	if purgeBatchSize == 0 {
+		purgeBatchSize = 1000
+	}
+
+	// The links in the emails point to the public URL of the api, which is
+	// the address it listens on unless it sits behind a proxy.
+	publicURL := strings.TrimSuffix(cfg.PublicURL, "/")
+	if publicURL == "" {
+		publicURL = "http://" + cfg.HTTPServerAddress
+	}
+
+	workerCfg := worker.Config{
+		Log:                   log,
+		Stores:                strs,
+		Mailer:                mlr,
+		Webhook:               webhook.NewHTTPSender(webhookTimeout),
+		SMS:                   smsSender,
+		Signer:                unsubscribeSigner,
+		PublicURL:             publicURL,
+		DeletedRetention:      deletedRetention,
+		NotificationRetention: cfg.NotificationRetention,
+		PurgeBatchSize:        purgeBatchSize,
+	}
+
+	var wrk *worker.Worker
+	if dev {
+		wrk = worker.NewInProcess(workerCfg)
+	} else {
+		workerCfg.RedisOpt = asynq.RedisClientOpt{
+			Addr: cfg.RedisHost,
+		}
+		wrk = worker.New(workerCfg)
+	}
+
+	requestTimeout := cfg.RequestTimeout
+	if requestTimeout == 0 {
+		requestTimeout = 30 * time.Second
+	}
+
+	shutdownTimeout := cfg.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = 20 * time.Second
+	}
+
+	// Requests are cancelled when they run past the timeout, or when they
+	// are still running once the server is shut down.
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 
 	app := fiber.New()
 
 	app.Use(cors.New())
 	app.Use(pprof.New())
+	app.Use(expvarmw.New())
 	app.Use(recover.New())
 	app.Use(fiberzerolog.New(fiberzerolog.Config{
 		Logger: log,
 	}))
+	app.Use(mid.Deadline(requestCtx, requestTimeout))
 
 	app.Get("/swagger/*", swagger.HandlerDefault)
 	app.Get("/metrics", monitor.New(monitor.Config{
 		Title: "Gotify Metrics",
 	}))
 
+	if capture != nil {
+		app.Get("/dev/emails", func(c *fiber.Ctx) error {
+			return c.JSON(capture.Emails())
+		})
+	}
+
 	api.Routes(app, api.Config{
 		Log:                  log,
-		DB:                   db,
+		Stores:               strs,
 		Maker:                maker,
-		TaskDistributor:      worker.TaskDistributor,
+		TaskDistributor:      wrk.TaskDistributor,
 		AccessTokenDuration:  cfg.AccessTokenDuration,
 		RefreshTokenDuration: cfg.RefreshTokenDuration,
+		MFAEncrypter:         mfaEncrypter,
+		MFAIssuer:            mfaIssuer,
+		MFAEnforce:           cfg.MFAEnforce,
+		WebAuthn:             webAuthn,
+		SSOProvider:          ssoProvider,
+		UnsubscribeSigner:    unsubscribeSigner,
 	})
 
 	wg, ctx := errgroup.WithContext(ctx)
 
-	processTasks(log, ctx, wg, worker)
-	listen(log, ctx, wg, app, cfg.HTTPServerAddress)
+	processTasks(log, ctx, wg, wrk)
+	listen(log, ctx, wg, app, cfg.HTTPServerAddress, shutdownTimeout, cancelRequests)
 
 	if err := wg.Wait(); err != nil {
 		return err
@@ -152,7 +322,75 @@ func run(log *zerolog.Logger) error {
 	return nil
 }
 
-func listen(log *zerolog.Logger, ctx context.Context, wg *errgroup.Group, app *fiber.App, addr string) {
+// openDB connects to the database of the configured driver.
+func openDB(log *zerolog.Logger, ctx context.Context, cfg config.Config) (*sqlx.DB, error) {
+	var (
+		db  *sqlx.DB
+		err error
+	)
+
+	switch cfg.Driver {
+	case "", database.DriverPostgres:
+		db, err = database.Open(database.Config{
+			User:         cfg.User,
+			Password:     cfg.Password,
+			Host:         cfg.Host,
+			Name:         cfg.Name,
+			MaxIdleConns: cfg.MaxIdleConns,
+			MaxOpenConns: cfg.MaxOpenConns,
+			DisableTLS:   cfg.DisableTLS,
+		})
+	case database.DriverSQLite:
+		db, err = database.OpenSQLite(database.SQLiteConfig{
+			Path:         cfg.SQLitePath,
+			MaxOpenConns: cfg.MaxOpenConns,
+		})
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.Ping(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Info().Str("driver", db.DriverName()).Msg("connected to database")
+
+	return db, nil
+}
+
+// devDefaults fills in the settings dev mode can't run without, so the app
+// starts without an app.env file.
+func devDefaults(log *zerolog.Logger, cfg *config.Config) error {
+	if cfg.HTTPServerAddress == "" {
+		cfg.HTTPServerAddress = "localhost:3000"
+	}
+
+	if cfg.TokenSymmetricKey == "" {
+		key := make([]byte, 16)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		cfg.TokenSymmetricKey = hex.EncodeToString(key)
+
+		log.Warn().Msg("dev mode: using a random token key, tokens won't survive a restart")
+	}
+
+	if cfg.WebAuthnRPID == "" {
+		cfg.WebAuthnRPID = "localhost"
+	}
+
+	if len(cfg.WebAuthnRPOrigins) == 0 {
+		cfg.WebAuthnRPOrigins = []string{"http://" + cfg.HTTPServerAddress}
+	}
+
+	return nil
+}
+
+func listen(log *zerolog.Logger, ctx context.Context, wg *errgroup.Group, app *fiber.App, addr string, shutdownTimeout time.Duration, cancelRequests context.CancelFunc) {
 	wg.Go(func() error {
 		if err := app.Listen(addr); err != nil {
 			if errors.Is(err, http.ErrServerClosed) {
@@ -167,7 +405,11 @@ func listen(log *zerolog.Logger, ctx context.Context, wg *errgroup.Group, app *f
 		<-ctx.Done()
 		log.Info().Msg("gracefully shutdown HTTP server")
 
-		if err := app.Shutdown(); err != nil {
+		// Requests still running after the timeout are cancelled, so their
+		// database work doesn't outlive the server.
+		err := app.ShutdownWithTimeout(shutdownTimeout)
+		cancelRequests()
+		if err != nil {
 			log.Error().Err(err).Msg("failed to shutdown HTTP server")
 			return err
 		}
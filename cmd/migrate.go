@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dudakovict/gotify/pkg/config"
+	"github.com/dudakovict/gotify/platform/migrations"
+	"github.com/rs/zerolog"
+)
+
+const migrateUsage = `usage: gotify migrate <command>
+
+commands:
+  up [N]        apply all pending migrations, or the next N
+  down N|all    roll back the last N migrations, or all of them
+  status        list the migrations and whether they are applied
+  version       print the version of the database
+  force V       set the version without migrating, clearing the dirty flag`
+
+// errMigrateUsage is returned when the migrate command is called with the
+// wrong arguments, after printing the usage.
+var errMigrateUsage = errors.New("invalid migrate command")
+
+// runMigrate runs a migration command against the configured database, which
+// lets deployments with several replicas migrate once before rolling out
+// instead of every replica migrating when it starts.
+func runMigrate(log *zerolog.Logger, args []string) error {
+	if len(args) == 0 {
+		return migrateUsageError()
+	}
+
+	cmd, args := args[0], args[1:]
+
+	var n int
+	switch cmd {
+	case "up", "down":
+		steps, err := migrateSteps(args, cmd == "down")
+		if err != nil {
+			return err
+		}
+		n = steps
+
+	case "force":
+		if len(args) != 1 {
+			return migrateUsageError()
+		}
+
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		n = version
+
+	case "status", "version":
+		if len(args) != 0 {
+			return migrateUsageError()
+		}
+
+	default:
+		return migrateUsageError()
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), interruptSignals...)
+	defer stop()
+
+	db, err := openDB(log, ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	mgr, err := migrations.New(ctx, db.DB, cfg.Driver)
+	if err != nil {
+		return err
+	}
+	defer mgr.Close()
+
+	switch cmd {
+	case "up":
+		if err := mgr.Up(n); err != nil && !errors.Is(err, migrations.ErrNoChange) {
+			return err
+		}
+
+	case "down":
+		if err := mgr.Down(n); err != nil && !errors.Is(err, migrations.ErrNoChange) {
+			return err
+		}
+
+	case "force":
+		if err := mgr.Force(n); err != nil {
+			return err
+		}
+
+	case "status":
+		statuses, err := mgr.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+		for _, s := range statuses {
+			status := "pending"
+			if s.Applied {
+				status = "applied"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, status)
+		}
+		w.Flush()
+	}
+
+	version, dirty, err := mgr.Version()
+	if err != nil {
+		return err
+	}
+
+	log.Info().Uint("version", version).Bool("dirty", dirty).Msg("database version")
+
+	return nil
+}
+
+// migrateSteps parses the number of migrations to apply or roll back, where
+// zero means all of them. Rolling back everything has to be asked for with
+// all, so a mistyped command can't empty the database.
+func migrateSteps(args []string, explicit bool) (int, error) {
+	switch {
+	case len(args) == 0 && !explicit:
+		return 0, nil
+	case len(args) != 1:
+		return 0, migrateUsageError()
+	case args[0] == "all":
+		return 0, nil
+	}
+
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", args[0])
+	}
+
+	return steps, nil
+}
+
+// migrateUsageError prints the usage of the migrate command.
+func migrateUsageError() error {
+	fmt.Fprintln(os.Stderr, migrateUsage)
+	return errMigrateUsage
+}
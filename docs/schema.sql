@@ -4,12 +4,15 @@
 
 CREATE TABLE "users" (
   "id" UUID PRIMARY KEY,
-  "email" VARCHAR UNIQUE NOT NULL,
+  "email" VARCHAR NOT NULL,
   "roles" VARCHAR[] NOT NULL,
   "hashed_password" BYTEA NOT NULL,
   "verified" BOOL NOT NULL DEFAULT false,
+  "channels" JSONB NOT NULL DEFAULT '[]',
+  "version" INTEGER NOT NULL DEFAULT 1,
   "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
-  "updated_at" TIMESTAMPTZ
+  "updated_at" TIMESTAMPTZ,
+  "deleted_at" TIMESTAMPTZ
 );
 
 CREATE TABLE "sessions" (
@@ -25,7 +28,9 @@ CREATE TABLE "sessions" (
 
 CREATE TABLE "verifications" (
   "id" UUID PRIMARY KEY,
-  "user_id" UUID NOT NULL,
+  "user_id" UUID,
+  "subscription_id" UUID,
+  "channel" VARCHAR,
   "email" VARCHAR NOT NULL,
   "code" VARCHAR NOT NULL,
   "used" BOOL NOT NULL DEFAULT false,
@@ -36,31 +41,316 @@ CREATE TABLE "verifications" (
 CREATE TABLE "topics" (
   "id" UUID PRIMARY KEY,
   "name" VARCHAR NOT NULL,
-  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+  "description" VARCHAR NOT NULL DEFAULT '',
+  "owner_id" UUID,
+  "visibility" VARCHAR NOT NULL DEFAULT 'public',
+  "archived_at" TIMESTAMPTZ,
+  "retention_seconds" BIGINT NOT NULL DEFAULT 0,
+  "version" INTEGER NOT NULL DEFAULT 1,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ,
+  "deleted_at" TIMESTAMPTZ
 );
 
 CREATE TABLE "notifications" (
   "id" UUID PRIMARY KEY,
   "topic_id" UUID NOT NULL,
+  "title" VARCHAR NOT NULL DEFAULT '',
   "message" TEXT,
-  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+  "priority" VARCHAR NOT NULL DEFAULT 'normal',
+  "tags" VARCHAR[] NOT NULL DEFAULT '{}',
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "deleted_at" TIMESTAMPTZ
 );
 
 CREATE TABLE "subscriptions" (
   "id" UUID PRIMARY KEY,
+  "topic_id" UUID,
+  "pattern" VARCHAR,
+  "pattern_root" VARCHAR,
+  "pattern_regex" VARCHAR,
+  "user_id" UUID,
+  "guest_email" VARCHAR,
+  "group_id" UUID,
+  "confirmed" BOOLEAN NOT NULL DEFAULT true,
+  "filter" JSONB NOT NULL DEFAULT '{}',
+  "routes" JSONB NOT NULL DEFAULT '[]',
+  "muted" BOOLEAN NOT NULL DEFAULT false,
+  "muted_until" TIMESTAMPTZ,
+  "version" INTEGER NOT NULL DEFAULT 1,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ,
+  "deleted_at" TIMESTAMPTZ
+);
+
+CREATE TABLE "mfa_secrets" (
+  "user_id" UUID PRIMARY KEY,
+  "secret" VARCHAR NOT NULL,
+  "enabled" BOOL NOT NULL DEFAULT false,
+  "last_used_step" BIGINT NOT NULL DEFAULT 0,
+  "failed_attempts" INTEGER NOT NULL DEFAULT 0,
+  "locked_until" TIMESTAMPTZ,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ
+);
+
+CREATE TABLE "mfa_recovery_codes" (
+  "id" UUID PRIMARY KEY,
+  "user_id" UUID NOT NULL,
+  "hashed_code" VARCHAR NOT NULL,
+  "used" BOOL NOT NULL DEFAULT false,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "passkeys" (
+  "id" UUID PRIMARY KEY,
+  "user_id" UUID NOT NULL,
+  "name" VARCHAR NOT NULL,
+  "credential_id" BYTEA NOT NULL,
+  "public_key" BYTEA NOT NULL,
+  "attestation_type" VARCHAR NOT NULL,
+  "aaguid" BYTEA NOT NULL,
+  "sign_count" BIGINT NOT NULL DEFAULT 0,
+  "transports" VARCHAR[] NOT NULL DEFAULT '{}',
+  "backup_eligible" BOOL NOT NULL DEFAULT false,
+  "backup_state" BOOL NOT NULL DEFAULT false,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "last_used_at" TIMESTAMPTZ
+);
+
+CREATE TABLE "webauthn_ceremonies" (
+  "id" UUID PRIMARY KEY,
+  "user_id" UUID,
+  "data" BYTEA NOT NULL,
+  "expires_at" TIMESTAMPTZ NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "oidc_states" (
+  "id" VARCHAR PRIMARY KEY,
+  "nonce" VARCHAR NOT NULL,
+  "code_verifier" VARCHAR NOT NULL,
+  "expires_at" TIMESTAMPTZ NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "user_identities" (
+  "issuer" VARCHAR NOT NULL,
+  "subject" VARCHAR NOT NULL,
+  "user_id" UUID NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("issuer", "subject")
+);
+
+CREATE TABLE "api_keys" (
+  "id" UUID PRIMARY KEY,
+  "user_id" UUID NOT NULL,
+  "name" VARCHAR NOT NULL,
+  "prefix" VARCHAR NOT NULL,
+  "hashed_secret" VARCHAR NOT NULL,
+  "scopes" JSONB NOT NULL DEFAULT '[]',
+  "expires_at" TIMESTAMPTZ,
+  "last_used_at" TIMESTAMPTZ,
+  "version" INTEGER NOT NULL DEFAULT 1,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ
+);
+
+CREATE TABLE "roles" (
+  "name" VARCHAR PRIMARY KEY,
+  "description" VARCHAR NOT NULL DEFAULT '',
+  "permissions" VARCHAR[] NOT NULL DEFAULT '{}',
+  "built_in" BOOLEAN NOT NULL DEFAULT false,
+  "version" INTEGER NOT NULL DEFAULT 1,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ
+);
+
+CREATE TABLE "topic_acls" (
   "topic_id" UUID NOT NULL,
   "user_id" UUID NOT NULL,
+  "rights" VARCHAR[] NOT NULL DEFAULT '{}',
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ,
+  PRIMARY KEY ("topic_id", "user_id")
+);
+
+CREATE TABLE "topic_invitations" (
+  "id" UUID PRIMARY KEY,
+  "topic_id" UUID NOT NULL,
+  "email" VARCHAR NOT NULL,
+  "rights" VARCHAR[] NOT NULL DEFAULT '{}',
+  "hashed_token" VARCHAR NOT NULL,
+  "invited_by" UUID,
+  "expires_at" TIMESTAMPTZ NOT NULL,
+  "accepted_at" TIMESTAMPTZ,
   "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
 );
 
-CREATE UNIQUE INDEX "unique_sub_topic_user" ON "subscriptions" ("topic_id", "user_id");
+CREATE TABLE "topic_deletions" (
+  "topic_id" UUID PRIMARY KEY,
+  "hashed_token" VARCHAR NOT NULL,
+  "requested_by" UUID,
+  "expires_at" TIMESTAMPTZ NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "inbox" (
+  "notification_id" UUID NOT NULL,
+  "user_id" UUID NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "notification_id")
+);
+
+CREATE TABLE "notification_deliveries" (
+  "id" UUID UNIQUE NOT NULL,
+  "notification_id" UUID NOT NULL,
+  "channel" VARCHAR NOT NULL,
+  "address" VARCHAR NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("notification_id", "channel", "address")
+);
+
+CREATE TABLE "notification_skips" (
+  "id" UUID UNIQUE NOT NULL,
+  "notification_id" UUID NOT NULL,
+  "subscription_id" UUID NOT NULL,
+  "reason" VARCHAR NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("notification_id", "subscription_id")
+);
+
+CREATE TABLE "subscription_jobs" (
+  "id" UUID PRIMARY KEY,
+  "status" VARCHAR NOT NULL DEFAULT 'pending',
+  "rows" JSONB NOT NULL DEFAULT '[]',
+  "processed" INT NOT NULL DEFAULT 0,
+  "created" INT NOT NULL DEFAULT 0,
+  "existing" INT NOT NULL DEFAULT 0,
+  "errors" JSONB NOT NULL DEFAULT '[]',
+  "created_by" UUID,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "groups" (
+  "id" UUID PRIMARY KEY,
+  "name" VARCHAR UNIQUE NOT NULL,
+  "description" VARCHAR NOT NULL DEFAULT '',
+  "version" INTEGER NOT NULL DEFAULT 1,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  "updated_at" TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "group_members" (
+  "group_id" UUID NOT NULL,
+  "user_id" UUID NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("group_id", "user_id")
+);
+
+CREATE INDEX ON "sessions" ("expires_at");
+
+CREATE INDEX ON "verifications" ("expired_at");
+
+CREATE UNIQUE INDEX "users_email_key" ON "users" ("email") WHERE "deleted_at" IS NULL;
+
+CREATE INDEX ON "users" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
+CREATE UNIQUE INDEX "unique_topic_name" ON "topics" ("name") WHERE "deleted_at" IS NULL;
+
+CREATE INDEX ON "topics" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
+CREATE INDEX ON "notifications" ("created_at");
+
+CREATE INDEX ON "notifications" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
+CREATE UNIQUE INDEX "unique_sub_topic_user" ON "subscriptions" ("topic_id", "user_id") WHERE "deleted_at" IS NULL;
+
+CREATE UNIQUE INDEX "unique_sub_pattern_user" ON "subscriptions" ("pattern", "user_id") WHERE "deleted_at" IS NULL;
+
+CREATE UNIQUE INDEX "unique_sub_topic_guest" ON "subscriptions" ("topic_id", "guest_email") WHERE "deleted_at" IS NULL;
+
+CREATE UNIQUE INDEX "unique_sub_topic_group" ON "subscriptions" ("topic_id", "group_id") WHERE "deleted_at" IS NULL;
+
+CREATE UNIQUE INDEX "unique_sub_pattern_group" ON "subscriptions" ("pattern", "group_id") WHERE "deleted_at" IS NULL;
+
+CREATE INDEX ON "subscriptions" ("pattern_root");
+
+CREATE INDEX ON "subscriptions" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
+CREATE UNIQUE INDEX "unique_recovery_code_user" ON "mfa_recovery_codes" ("user_id", "hashed_code");
+
+CREATE UNIQUE INDEX "unique_passkey_credential" ON "passkeys" ("credential_id");
+
+CREATE INDEX ON "passkeys" ("user_id");
+
+CREATE UNIQUE INDEX "unique_api_key_prefix" ON "api_keys" ("prefix");
+
+CREATE INDEX ON "api_keys" ("user_id");
+
+CREATE INDEX ON "topic_acls" ("user_id");
+
+CREATE INDEX ON "topic_invitations" ("topic_id");
+
+CREATE INDEX ON "inbox" ("user_id", "created_at");
+
+CREATE INDEX ON "notifications" ("topic_id", "created_at", "id");
+
+CREATE INDEX "notifications_search_idx" ON "notifications" USING GIN ((setweight(to_tsvector('english', "title"), 'A') || setweight(to_tsvector('english', coalesce("message", '')), 'B')));
+
+CREATE INDEX ON "group_members" ("user_id");
 
 ALTER TABLE "sessions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
 
 ALTER TABLE "verifications" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
 
+ALTER TABLE "verifications" ADD FOREIGN KEY ("subscription_id") REFERENCES "subscriptions" ("id") ON DELETE CASCADE;
+
 ALTER TABLE "notifications" ADD FOREIGN KEY ("topic_id") REFERENCES "topics" ("id") ON DELETE CASCADE;
 
 ALTER TABLE "subscriptions" ADD FOREIGN KEY ("topic_id") REFERENCES "topics" ("id") ON DELETE CASCADE;
 
 ALTER TABLE "subscriptions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "mfa_secrets" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "mfa_recovery_codes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "passkeys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "webauthn_ceremonies" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "user_identities" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "topics" ADD FOREIGN KEY ("owner_id") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "topic_acls" ADD FOREIGN KEY ("topic_id") REFERENCES "topics" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "topic_acls" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "topic_invitations" ADD FOREIGN KEY ("topic_id") REFERENCES "topics" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "topic_invitations" ADD FOREIGN KEY ("invited_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "topic_deletions" ADD FOREIGN KEY ("topic_id") REFERENCES "topics" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "topic_deletions" ADD FOREIGN KEY ("requested_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "inbox" ADD FOREIGN KEY ("notification_id") REFERENCES "notifications" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "inbox" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "notification_deliveries" ADD FOREIGN KEY ("notification_id") REFERENCES "notifications" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "notification_skips" ADD FOREIGN KEY ("notification_id") REFERENCES "notifications" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "subscription_jobs" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("id") ON DELETE SET NULL;
+
+ALTER TABLE "group_members" ADD FOREIGN KEY ("group_id") REFERENCES "groups" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "group_members" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "subscriptions" ADD FOREIGN KEY ("group_id") REFERENCES "groups" ("id") ON DELETE CASCADE;
@@ -0,0 +1,235 @@
+// Package apikey provides a core business API.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound              = errors.New("api key not found")
+	ErrInvalidScope          = errors.New("invalid scope")
+	ErrAuthenticationFailure = errors.New("api key authentication failed")
+	ErrVersionConflict       = errors.New("api key was changed by someone else")
+)
+
+// Set of scopes an api key can be granted.
+const (
+	ScopeNotificationsPublish = "notifications:publish"
+	ScopeNotificationsRead    = "notifications:read"
+)
+
+var scopes = map[string]bool{
+	ScopeNotificationsPublish: true,
+	ScopeNotificationsRead:    true,
+}
+
+// TokenPrefix marks bearer tokens which are api keys rather than PASETO
+// tokens.
+const TokenPrefix = "gtf_"
+
+// lastUsedInterval limits how often the last used time of a key is written.
+const lastUsedInterval = time.Minute
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, key APIKey) error
+	Update(ctx context.Context, key APIKey) error
+	UpdateLastUsed(ctx context.Context, key APIKey) error
+	Delete(ctx context.Context, key APIKey) error
+	QueryByID(ctx context.Context, id uuid.UUID) (APIKey, error)
+	QueryByUserID(ctx context.Context, userID uuid.UUID) ([]APIKey, error)
+	QueryByPrefix(ctx context.Context, prefix string) (APIKey, error)
+}
+
+// Core manages the set of APIs for api key access.
+type Core struct {
+	log    *zerolog.Logger
+	storer Storer
+}
+
+// NewCore constructs an api key core API for use.
+func NewCore(log *zerolog.Logger, storer Storer) *Core {
+	return &Core{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// Create adds a new api key to the system and returns it together with the
+// token, which is only available at this point.
+func (c *Core) Create(ctx context.Context, nk NewAPIKey) (APIKey, string, error) {
+	if err := checkScopes(nk.Scopes); err != nil {
+		return APIKey{}, "", err
+	}
+
+	prefix, secret, err := generate()
+	if err != nil {
+		return APIKey{}, "", err
+	}
+
+	now := time.Now()
+	key := APIKey{
+		ID:           uuid.New(),
+		UserID:       nk.UserID,
+		Name:         nk.Name,
+		Prefix:       prefix,
+		HashedSecret: hash(secret),
+		Scopes:       nk.Scopes,
+		ExpiresAt:    nk.ExpiresAt,
+		Version:      1,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := c.storer.Create(ctx, key); err != nil {
+		return APIKey{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return key, TokenPrefix + prefix + "_" + secret, nil
+}
+
+// Update modifies information about an api key. It fails with
+// ErrVersionConflict when the key was changed since it was read.
+func (c *Core) Update(ctx context.Context, key APIKey, uk UpdateAPIKey) (APIKey, error) {
+	if uk.Name != nil {
+		key.Name = *uk.Name
+	}
+
+	if uk.Scopes != nil {
+		if err := checkScopes(uk.Scopes); err != nil {
+			return APIKey{}, err
+		}
+		key.Scopes = uk.Scopes
+	}
+
+	key.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, key); err != nil {
+		return APIKey{}, fmt.Errorf("update: %w", err)
+	}
+	key.Version++
+
+	return key, nil
+}
+
+// Delete revokes the specified api key. It fails with ErrVersionConflict when
+// the key was changed since it was read.
+func (c *Core) Delete(ctx context.Context, key APIKey) error {
+	if err := c.storer.Delete(ctx, key); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByID finds the api key by the specified ID.
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (APIKey, error) {
+	key, err := c.storer.QueryByID(ctx, id)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("query: %w", err)
+	}
+
+	return key, nil
+}
+
+// QueryByUserID retrieves the api keys of the user.
+func (c *Core) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	keys, err := c.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Authenticate finds the api key for the token, verifies its secret and
+// records that it was used. Recording the use leaves the version of the key
+// alone, so using a key doesn't get in the way of editing it.
+func (c *Core) Authenticate(ctx context.Context, token string) (APIKey, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(token, TokenPrefix), "_")
+	if !ok || !strings.HasPrefix(token, TokenPrefix) {
+		return APIKey{}, ErrAuthenticationFailure
+	}
+
+	key, err := c.storer.QueryByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return APIKey{}, ErrAuthenticationFailure
+		}
+		return APIKey{}, fmt.Errorf("query: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(key.HashedSecret)) != 1 {
+		return APIKey{}, ErrAuthenticationFailure
+	}
+
+	if key.Expired() {
+		return APIKey{}, ErrAuthenticationFailure
+	}
+
+	now := time.Now()
+	if now.Sub(key.LastUsedAt) > lastUsedInterval {
+		key.LastUsedAt = now
+
+		if err := c.storer.UpdateLastUsed(ctx, key); err != nil {
+			return APIKey{}, fmt.Errorf("updatelastused: %w", err)
+		}
+	}
+
+	return key, nil
+}
+
+// IsToken reports whether the bearer token is an api key.
+func IsToken(token string) bool {
+	return strings.HasPrefix(token, TokenPrefix)
+}
+
+func checkScopes(ss []Scope) error {
+	if len(ss) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+
+	for _, s := range ss {
+		if !scopes[s.Name] {
+			return fmt.Errorf("%w %q", ErrInvalidScope, s.Name)
+		}
+	}
+
+	return nil
+}
+
+// generate returns a random lookup prefix and secret for a new key.
+func generate() (string, string, error) {
+	b := make([]byte, 40)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("rand: %w", err)
+	}
+
+	prefix := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b[:5]))
+	secret := base64.RawURLEncoding.EncodeToString(b[5:])
+
+	return prefix, secret, nil
+}
+
+// hash returns the hex encoded SHA-256 of the secret. Secrets carry enough
+// entropy that a slow hash isn't needed.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,214 @@
+package apikey_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	mockkey "github.com/dudakovict/gotify/internal/core/apikey/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var created apikey.APIKey
+
+	storer := mockkey.NewMockStorer(ctrl)
+	storer.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, key apikey.APIKey) error {
+			created = key
+			return nil
+		})
+
+	core := apikey.NewCore(nil, storer)
+
+	_, token, err := core.Create(ctx, apikey.NewAPIKey{
+		UserID: uuid.New(),
+		Name:   "ci",
+		Scopes: []apikey.Scope{{Name: apikey.ScopeNotificationsPublish}},
+	})
+	require.NoError(t, err)
+	require.True(t, apikey.IsToken(token))
+	require.True(t, strings.HasPrefix(token, apikey.TokenPrefix+created.Prefix+"_"))
+	require.NotContains(t, created.HashedSecret, strings.TrimPrefix(token, apikey.TokenPrefix+created.Prefix+"_"))
+
+	testCases := []struct {
+		name          string
+		token         string
+		buildStubs    func(storer *mockkey.MockStorer)
+		checkResponse func(key apikey.APIKey, err error)
+	}{
+		{
+			name:  "OK",
+			token: token,
+			buildStubs: func(storer *mockkey.MockStorer) {
+				storer.EXPECT().
+					QueryByPrefix(gomock.Any(), created.Prefix).
+					Times(1).
+					Return(created, nil)
+
+				storer.EXPECT().
+					UpdateLastUsed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, key apikey.APIKey) error {
+						require.False(t, key.LastUsedAt.IsZero())
+						return nil
+					})
+			},
+			checkResponse: func(key apikey.APIKey, err error) {
+				require.NoError(t, err)
+				require.Equal(t, created.ID, key.ID)
+			},
+		},
+		{
+			name:  "RecentlyUsed",
+			token: token,
+			buildStubs: func(storer *mockkey.MockStorer) {
+				key := created
+				key.LastUsedAt = time.Now()
+
+				storer.EXPECT().
+					QueryByPrefix(gomock.Any(), created.Prefix).
+					Times(1).
+					Return(key, nil)
+
+				storer.EXPECT().
+					UpdateLastUsed(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(key apikey.APIKey, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:  "WrongSecret",
+			token: apikey.TokenPrefix + created.Prefix + "_secret",
+			buildStubs: func(storer *mockkey.MockStorer) {
+				storer.EXPECT().
+					QueryByPrefix(gomock.Any(), created.Prefix).
+					Times(1).
+					Return(created, nil)
+			},
+			checkResponse: func(key apikey.APIKey, err error) {
+				require.ErrorIs(t, err, apikey.ErrAuthenticationFailure)
+			},
+		},
+		{
+			name:  "Expired",
+			token: token,
+			buildStubs: func(storer *mockkey.MockStorer) {
+				key := created
+				key.ExpiresAt = time.Now().Add(-time.Minute)
+
+				storer.EXPECT().
+					QueryByPrefix(gomock.Any(), created.Prefix).
+					Times(1).
+					Return(key, nil)
+			},
+			checkResponse: func(key apikey.APIKey, err error) {
+				require.ErrorIs(t, err, apikey.ErrAuthenticationFailure)
+			},
+		},
+		{
+			name:  "Revoked",
+			token: token,
+			buildStubs: func(storer *mockkey.MockStorer) {
+				storer.EXPECT().
+					QueryByPrefix(gomock.Any(), created.Prefix).
+					Times(1).
+					Return(apikey.APIKey{}, apikey.ErrNotFound)
+			},
+			checkResponse: func(key apikey.APIKey, err error) {
+				require.ErrorIs(t, err, apikey.ErrAuthenticationFailure)
+			},
+		},
+		{
+			name:  "Malformed",
+			token: apikey.TokenPrefix + "secret",
+			buildStubs: func(storer *mockkey.MockStorer) {
+				storer.EXPECT().
+					QueryByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(key apikey.APIKey, err error) {
+				require.ErrorIs(t, err, apikey.ErrAuthenticationFailure)
+			},
+		},
+		{
+			name:  "InternalError",
+			token: token,
+			buildStubs: func(storer *mockkey.MockStorer) {
+				storer.EXPECT().
+					QueryByPrefix(gomock.Any(), created.Prefix).
+					Times(1).
+					Return(apikey.APIKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(key apikey.APIKey, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mockkey.NewMockStorer(ctrl)
+		core := apikey.NewCore(nil, storer)
+
+		tc.buildStubs(storer)
+
+		key, err := core.Authenticate(ctx, tc.token)
+
+		tc.checkResponse(key, err)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	topicID := uuid.New()
+
+	key := apikey.APIKey{
+		Scopes: []apikey.Scope{
+			{Name: apikey.ScopeNotificationsPublish, TopicIDs: []uuid.UUID{topicID}},
+			{Name: apikey.ScopeNotificationsRead},
+		},
+	}
+
+	require.True(t, key.Allows(apikey.ScopeNotificationsPublish, topicID))
+	require.False(t, key.Allows(apikey.ScopeNotificationsPublish, uuid.New()))
+	require.True(t, key.Allows(apikey.ScopeNotificationsRead, uuid.New()))
+	require.True(t, key.HasScope(apikey.ScopeNotificationsPublish))
+
+	require.Equal(t, []uuid.UUID{topicID}, key.Topics(apikey.ScopeNotificationsPublish))
+	require.Nil(t, key.Topics(apikey.ScopeNotificationsRead))
+}
+
+func TestCreateInvalidScope(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mockkey.NewMockStorer(ctrl)
+	core := apikey.NewCore(nil, storer)
+
+	_, _, err := core.Create(ctx, apikey.NewAPIKey{
+		UserID: uuid.New(),
+		Name:   "ci",
+		Scopes: []apikey.Scope{{Name: "users:delete"}},
+	})
+	require.ErrorIs(t, err, apikey.ErrInvalidScope)
+}
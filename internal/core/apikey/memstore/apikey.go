@@ -0,0 +1,174 @@
+// Package apikeymem contains api key related CRUD functionality, kept in
+// memory.
+package apikeymem
+
+import (
+	"context"
+	"slices"
+
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table      = "api_keys"
+	tableUsers = "users"
+)
+
+// Store manages the set of APIs for api key access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Unique("unique_api_key_prefix", memdb.Unique{
+		Table: table,
+		Key:   func(row any) (string, bool) { return row.(apikey.APIKey).Prefix, true },
+	})
+
+	db.Reference("api_keys_user_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: tableUsers,
+		Refers: func(row any, key string) bool { return row.(apikey.APIKey).UserID.String() == key },
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(apikey.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new api key into the database.
+func (s *Store) Create(ctx context.Context, key apikey.APIKey) error {
+	return memdb.Insert(s.db, table, key.ID.String(), clone(key))
+}
+
+// Update replaces an api key in the database, as long as it's still at the
+// version of the key.
+func (s *Store) Update(ctx context.Context, key apikey.APIKey) error {
+	stored, exists := memdb.Get[apikey.APIKey](s.db, table, key.ID.String())
+	if !exists || stored.Version != key.Version {
+		return apikey.ErrVersionConflict
+	}
+
+	key = clone(key)
+	stored.Name = key.Name
+	stored.Scopes = key.Scopes
+	stored.UpdatedAt = key.UpdatedAt
+	stored.Version++
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// UpdateLastUsed records when an api key was last used in the database,
+// whatever its version.
+func (s *Store) UpdateLastUsed(ctx context.Context, key apikey.APIKey) error {
+	stored, exists := memdb.Get[apikey.APIKey](s.db, table, key.ID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.LastUsedAt = key.LastUsedAt
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// Delete removes an api key from the database, as long as it's still at the
+// version of the key.
+func (s *Store) Delete(ctx context.Context, key apikey.APIKey) error {
+	stored, exists := memdb.Get[apikey.APIKey](s.db, table, key.ID.String())
+	if !exists || stored.Version != key.Version {
+		return apikey.ErrVersionConflict
+	}
+
+	memdb.Delete(s.db, table, key.ID.String())
+
+	return nil
+}
+
+// QueryByID gets the specified api key from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (apikey.APIKey, error) {
+	key, exists := memdb.Get[apikey.APIKey](s.db, table, id.String())
+	if !exists {
+		return apikey.APIKey{}, apikey.ErrNotFound
+	}
+
+	return clone(key), nil
+}
+
+// QueryByUserID retrieves the api keys of the specified user from the
+// database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]apikey.APIKey, error) {
+	keys := memdb.Rows(s.db, table, func(key apikey.APIKey) bool {
+		return key.UserID == userID
+	})
+
+	slices.SortStableFunc(keys, func(a, b apikey.APIKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	for i := range keys {
+		keys[i] = clone(keys[i])
+	}
+
+	return keys, nil
+}
+
+// QueryByPrefix gets the api key with the specified prefix from the database.
+// Keys of deleted users are left out, so they stop authenticating with them.
+func (s *Store) QueryByPrefix(ctx context.Context, prefix string) (apikey.APIKey, error) {
+	keys := memdb.Rows(s.db, table, func(key apikey.APIKey) bool {
+		return key.Prefix == prefix
+	})
+
+	if len(keys) == 0 {
+		return apikey.APIKey{}, apikey.ErrNotFound
+	}
+
+	if usr, exists := memdb.Get[user.User](s.db, tableUsers, keys[0].UserID.String()); !exists || !usr.DeletedAt.IsZero() {
+		return apikey.APIKey{}, apikey.ErrNotFound
+	}
+
+	return clone(keys[0]), nil
+}
+
+// =============================================================================
+
+// clone copies the api key, so the stored key shares no memory with the
+// callers.
+func clone(key apikey.APIKey) apikey.APIKey {
+	scopes := make([]apikey.Scope, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = apikey.Scope{
+			Name:     scope.Name,
+			TopicIDs: slices.Clone(scope.TopicIDs),
+		}
+	}
+	key.Scopes = scopes
+
+	return key
+}
@@ -0,0 +1,152 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dudakovict/gotify/internal/core/apikey (interfaces: Storer)
+
+// Package mockkey is a generated GoMock package.
+package mockkey
+
+import (
+	context "context"
+	reflect "reflect"
+
+	apikey "github.com/dudakovict/gotify/internal/core/apikey"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockStorer is a mock of Storer interface.
+type MockStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorerMockRecorder
+}
+
+// MockStorerMockRecorder is the mock recorder for MockStorer.
+type MockStorerMockRecorder struct {
+	mock *MockStorer
+}
+
+// NewMockStorer creates a new mock instance.
+func NewMockStorer(ctrl *gomock.Controller) *MockStorer {
+	mock := &MockStorer{ctrl: ctrl}
+	mock.recorder = &MockStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockStorer) Create(arg0 context.Context, arg1 apikey.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockStorer) Delete(arg0 context.Context, arg1 apikey.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// QueryByID mocks base method.
+func (m *MockStorer) QueryByID(arg0 context.Context, arg1 uuid.UUID) (apikey.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByID", arg0, arg1)
+	ret0, _ := ret[0].(apikey.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByID indicates an expected call of QueryByID.
+func (mr *MockStorerMockRecorder) QueryByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0, arg1)
+}
+
+// QueryByPrefix mocks base method.
+func (m *MockStorer) QueryByPrefix(arg0 context.Context, arg1 string) (apikey.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByPrefix", arg0, arg1)
+	ret0, _ := ret[0].(apikey.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByPrefix indicates an expected call of QueryByPrefix.
+func (mr *MockStorerMockRecorder) QueryByPrefix(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByPrefix", reflect.TypeOf((*MockStorer)(nil).QueryByPrefix), arg0, arg1)
+}
+
+// QueryByUserID mocks base method.
+func (m *MockStorer) QueryByUserID(arg0 context.Context, arg1 uuid.UUID) ([]apikey.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByUserID", arg0, arg1)
+	ret0, _ := ret[0].([]apikey.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByUserID indicates an expected call of QueryByUserID.
+func (mr *MockStorerMockRecorder) QueryByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByUserID", reflect.TypeOf((*MockStorer)(nil).QueryByUserID), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockStorer) Update(arg0 context.Context, arg1 apikey.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorerMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1)
+}
+
+// UpdateLastUsed mocks base method.
+func (m *MockStorer) UpdateLastUsed(arg0 context.Context, arg1 apikey.APIKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastUsed", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastUsed indicates an expected call of UpdateLastUsed.
+func (mr *MockStorerMockRecorder) UpdateLastUsed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastUsed", reflect.TypeOf((*MockStorer)(nil).UpdateLastUsed), arg0, arg1)
+}
+
+// WithinTran mocks base method.
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(apikey.Storer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTran indicates an expected call of WithinTran.
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
+}
@@ -0,0 +1,86 @@
+package apikey
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey represents a long lived credential a user issues for machines.
+type APIKey struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	Prefix       string
+	HashedSecret string
+	Scopes       []Scope
+	ExpiresAt    time.Time
+	LastUsedAt   time.Time
+	Version      int
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Scope grants an api key access to an operation. Without topic IDs the
+// operation is allowed on every topic.
+type Scope struct {
+	Name     string
+	TopicIDs []uuid.UUID
+}
+
+// NewAPIKey contains information needed to create a new api key.
+type NewAPIKey struct {
+	UserID    uuid.UUID
+	Name      string
+	Scopes    []Scope
+	ExpiresAt time.Time
+}
+
+// UpdateAPIKey contains information needed to update an api key.
+type UpdateAPIKey struct {
+	Name   *string
+	Scopes []Scope
+}
+
+// Expired reports whether the key has an expiry which has passed.
+func (k APIKey) Expired() bool {
+	return !k.ExpiresAt.IsZero() && time.Now().After(k.ExpiresAt)
+}
+
+// HasScope reports whether the key is granted the scope for any topic.
+func (k APIKey) HasScope(name string) bool {
+	for _, s := range k.Scopes {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the key is granted the scope for the topic.
+func (k APIKey) Allows(name string, topicID uuid.UUID) bool {
+	for _, s := range k.Scopes {
+		if s.Name == name && (len(s.TopicIDs) == 0 || slices.Contains(s.TopicIDs, topicID)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Topics returns the topics the key is granted the scope for, or nil when the
+// scope isn't limited to topics.
+func (k APIKey) Topics(name string) []uuid.UUID {
+	var topicIDs []uuid.UUID
+	for _, s := range k.Scopes {
+		if s.Name != name {
+			continue
+		}
+
+		if len(s.TopicIDs) == 0 {
+			return nil
+		}
+		topicIDs = append(topicIDs, s.TopicIDs...)
+	}
+	return topicIDs
+}
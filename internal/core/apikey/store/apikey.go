@@ -0,0 +1,233 @@
+// Package apikeydb contains api key related CRUD functionality.
+package apikeydb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for api key database access.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(apikey.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts a new api key into the database.
+func (s *Store) Create(ctx context.Context, key apikey.APIKey) error {
+	dbKey, err := toDBAPIKey(key)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	INSERT INTO api_keys
+		(id, user_id, name, prefix, hashed_secret, scopes, expires_at, last_used_at, version, created_at, updated_at)
+	VALUES
+		(:id, :user_id, :name, :prefix, :hashed_secret, :scopes, :expires_at, :last_used_at, :version, :created_at, :updated_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbKey); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces an api key in the database, as long as it's still at the
+// version of the key.
+func (s *Store) Update(ctx context.Context, key apikey.APIKey) error {
+	dbKey, err := toDBAPIKey(key)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		name = :name,
+		scopes = :scopes,
+		updated_at = :updated_at,
+		version = version + 1
+	WHERE
+		id = :id
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, dbKey)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return apikey.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// UpdateLastUsed records when an api key was last used in the database,
+// whatever its version.
+func (s *Store) UpdateLastUsed(ctx context.Context, key apikey.APIKey) error {
+	dbKey, err := toDBAPIKey(key)
+	if err != nil {
+		return err
+	}
+
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		last_used_at = :last_used_at
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbKey); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes an api key from the database, as long as it's still at the
+// version of the key.
+func (s *Store) Delete(ctx context.Context, key apikey.APIKey) error {
+	data := struct {
+		ID      uuid.UUID `db:"id"`
+		Version int       `db:"version"`
+	}{
+		ID:      key.ID,
+		Version: key.Version,
+	}
+
+	const q = `
+	DELETE FROM
+		api_keys
+	WHERE
+		id = :id
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return apikey.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// QueryByID gets the specified api key from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (apikey.APIKey, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM api_keys WHERE id = :id`
+
+	var dbKey dbAPIKey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbKey); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return apikey.APIKey{}, apikey.ErrNotFound
+		}
+		return apikey.APIKey{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreAPIKey(dbKey)
+}
+
+// QueryByUserID retrieves the api keys of the specified user from the
+// database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]apikey.APIKey, error) {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		api_keys
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		created_at`
+
+	var dbKeys []dbAPIKey
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbKeys); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreAPIKeySlice(dbKeys)
+}
+
+// QueryByPrefix gets the api key with the specified prefix from the database.
+// Keys of deleted users are left out, so they stop authenticating with them.
+func (s *Store) QueryByPrefix(ctx context.Context, prefix string) (apikey.APIKey, error) {
+	data := struct {
+		Prefix string `db:"prefix"`
+	}{
+		Prefix: prefix,
+	}
+
+	const q = `
+	SELECT
+		k.*
+	FROM
+		api_keys k
+	JOIN
+		users u
+	ON
+		u.id = k.user_id
+	WHERE
+		k.prefix = :prefix AND
+		u.deleted_at IS NULL`
+
+	var dbKey dbAPIKey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbKey); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return apikey.APIKey{}, apikey.ErrNotFound
+		}
+		return apikey.APIKey{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreAPIKey(dbKey)
+}
@@ -0,0 +1,115 @@
+package apikeydb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	"github.com/google/uuid"
+)
+
+type dbAPIKey struct {
+	ID           uuid.UUID    `db:"id"`
+	UserID       uuid.UUID    `db:"user_id"`
+	Name         string       `db:"name"`
+	Prefix       string       `db:"prefix"`
+	HashedSecret string       `db:"hashed_secret"`
+	Scopes       string       `db:"scopes"`
+	ExpiresAt    sql.NullTime `db:"expires_at"`
+	LastUsedAt   sql.NullTime `db:"last_used_at"`
+	Version      int          `db:"version"`
+	CreatedAt    time.Time    `db:"created_at"`
+	UpdatedAt    time.Time    `db:"updated_at"`
+}
+
+type dbScope struct {
+	Name     string      `json:"name"`
+	TopicIDs []uuid.UUID `json:"topic_ids,omitempty"`
+}
+
+func toDBAPIKey(key apikey.APIKey) (dbAPIKey, error) {
+	dbScopes := make([]dbScope, len(key.Scopes))
+	for i, s := range key.Scopes {
+		dbScopes[i] = dbScope{
+			Name:     s.Name,
+			TopicIDs: s.TopicIDs,
+		}
+	}
+
+	scopes, err := json.Marshal(dbScopes)
+	if err != nil {
+		return dbAPIKey{}, fmt.Errorf("marshal scopes: %w", err)
+	}
+
+	return dbAPIKey{
+		ID:           key.ID,
+		UserID:       key.UserID,
+		Name:         key.Name,
+		Prefix:       key.Prefix,
+		HashedSecret: key.HashedSecret,
+		Scopes:       string(scopes),
+		ExpiresAt:    toNullTime(key.ExpiresAt),
+		LastUsedAt:   toNullTime(key.LastUsedAt),
+		Version:      key.Version,
+		CreatedAt:    key.CreatedAt.UTC(),
+		UpdatedAt:    key.UpdatedAt.UTC(),
+	}, nil
+}
+
+func toCoreAPIKey(dbKey dbAPIKey) (apikey.APIKey, error) {
+	var dbScopes []dbScope
+	if err := json.Unmarshal([]byte(dbKey.Scopes), &dbScopes); err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unmarshal scopes: %w", err)
+	}
+
+	scopes := make([]apikey.Scope, len(dbScopes))
+	for i, s := range dbScopes {
+		scopes[i] = apikey.Scope{
+			Name:     s.Name,
+			TopicIDs: s.TopicIDs,
+		}
+	}
+
+	key := apikey.APIKey{
+		ID:           dbKey.ID,
+		UserID:       dbKey.UserID,
+		Name:         dbKey.Name,
+		Prefix:       dbKey.Prefix,
+		HashedSecret: dbKey.HashedSecret,
+		Scopes:       scopes,
+		Version:      dbKey.Version,
+		CreatedAt:    dbKey.CreatedAt.In(time.Local),
+		UpdatedAt:    dbKey.UpdatedAt.In(time.Local),
+	}
+
+	if dbKey.ExpiresAt.Valid {
+		key.ExpiresAt = dbKey.ExpiresAt.Time.In(time.Local)
+	}
+
+	if dbKey.LastUsedAt.Valid {
+		key.LastUsedAt = dbKey.LastUsedAt.Time.In(time.Local)
+	}
+
+	return key, nil
+}
+
+func toCoreAPIKeySlice(dbKeys []dbAPIKey) ([]apikey.APIKey, error) {
+	keys := make([]apikey.APIKey, len(dbKeys))
+	for i, dbKey := range dbKeys {
+		key, err := toCoreAPIKey(dbKey)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{
+		Time:  t.UTC(),
+		Valid: !t.IsZero(),
+	}
+}
@@ -0,0 +1,213 @@
+// Package group provides a core business API.
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound        = errors.New("group not found")
+	ErrUniqueName      = errors.New("group name is not unique")
+	ErrInvalidName     = errors.New("group name is required")
+	ErrMemberNotFound  = errors.New("user is not a member of the group")
+	ErrUniqueMember    = errors.New("user is already a member of the group")
+	ErrVersionConflict = errors.New("group was changed by someone else")
+)
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, grp Group) error
+	Update(ctx context.Context, grp Group) error
+	Delete(ctx context.Context, grp Group) error
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Group, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, id uuid.UUID) (Group, error)
+	AddMember(ctx context.Context, mbr Member) error
+	RemoveMember(ctx context.Context, mbr Member) error
+	QueryMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) (Member, error)
+	QueryMembers(ctx context.Context, groupIDs []uuid.UUID) ([]Member, error)
+}
+
+// Core manages the set of APIs for group access.
+type Core struct {
+	log    *zerolog.Logger
+	storer Storer
+}
+
+// NewCore constructs a group core API for use.
+func NewCore(log *zerolog.Logger, storer Storer) *Core {
+	return &Core{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// Create adds a new group to the system.
+func (c *Core) Create(ctx context.Context, ng NewGroup) (Group, error) {
+	name := strings.TrimSpace(ng.Name)
+	if name == "" {
+		return Group{}, ErrInvalidName
+	}
+
+	now := time.Now()
+	grp := Group{
+		ID:          uuid.New(),
+		Name:        name,
+		Description: ng.Description,
+		Version:     1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := c.storer.Create(ctx, grp); err != nil {
+		return Group{}, fmt.Errorf("create: %w", err)
+	}
+
+	return grp, nil
+}
+
+// Update modifies the name and description of a group.
+func (c *Core) Update(ctx context.Context, grp Group, ug UpdateGroup) (Group, error) {
+	if ug.Name != nil {
+		name := strings.TrimSpace(*ug.Name)
+		if name == "" {
+			return Group{}, ErrInvalidName
+		}
+		grp.Name = name
+	}
+
+	if ug.Description != nil {
+		grp.Description = *ug.Description
+	}
+
+	grp.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, grp); err != nil {
+		return Group{}, fmt.Errorf("update: %w", err)
+	}
+	grp.Version++
+
+	return grp, nil
+}
+
+// Delete removes the specified group along with its memberships and
+// subscriptions. It fails with ErrVersionConflict when the group was changed
+// since it was read.
+func (c *Core) Delete(ctx context.Context, grp Group) error {
+	if err := c.storer.Delete(ctx, grp); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing groups.
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Group, error) {
+	grps, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return grps, nil
+}
+
+// Count returns the number of groups matching the filter.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	count, err := c.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByID finds the group by the specified ID.
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (Group, error) {
+	grp, err := c.storer.QueryByID(ctx, id)
+	if err != nil {
+		return Group{}, fmt.Errorf("query: %w", err)
+	}
+
+	return grp, nil
+}
+
+// AddMember adds the user to the group. From then on the user gets the
+// notifications of the topics the group is subscribed to.
+func (c *Core) AddMember(ctx context.Context, grp Group, userID uuid.UUID) (Member, error) {
+	mbr := Member{
+		GroupID:   grp.ID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := c.storer.AddMember(ctx, mbr); err != nil {
+		return Member{}, fmt.Errorf("add member: %w", err)
+	}
+
+	return mbr, nil
+}
+
+// RemoveMember removes the user from the group.
+func (c *Core) RemoveMember(ctx context.Context, grp Group, userID uuid.UUID) error {
+	tran := func(s Storer) error {
+		mbr, err := s.QueryMember(ctx, grp.ID, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.RemoveMember(ctx, mbr); err != nil {
+			return fmt.Errorf("removemember: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		if errors.Is(err, ErrMemberNotFound) {
+			return ErrMemberNotFound
+		}
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	return nil
+}
+
+// QueryMembers retrieves the members of the group.
+func (c *Core) QueryMembers(ctx context.Context, grp Group) ([]Member, error) {
+	mbrs, err := c.storer.QueryMembers(ctx, []uuid.UUID{grp.ID})
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return mbrs, nil
+}
+
+// MemberIDs returns the users in each of the groups, keyed by group.
+func (c *Core) MemberIDs(ctx context.Context, groupIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	mbrs, err := c.storer.QueryMembers(ctx, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	ids := make(map[uuid.UUID][]uuid.UUID, len(groupIDs))
+	for _, mbr := range mbrs {
+		ids[mbr.GroupID] = append(ids[mbr.GroupID], mbr.UserID)
+	}
+
+	return ids, nil
+}
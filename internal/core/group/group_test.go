@@ -0,0 +1,124 @@
+package group_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dudakovict/gotify/internal/core/group"
+	mockgrp "github.com/dudakovict/gotify/internal/core/group/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mockgrp.NewMockStorer(ctrl)
+	storer.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, grp group.Group) error {
+			require.Equal(t, "on-call", grp.Name)
+			require.NotEqual(t, uuid.Nil, grp.ID)
+			return nil
+		})
+
+	core := group.NewCore(nil, storer)
+
+	_, err := core.Create(ctx, group.NewGroup{Name: "  on-call "})
+	require.NoError(t, err)
+
+	_, err = core.Create(ctx, group.NewGroup{Name: " "})
+	require.ErrorIs(t, err, group.ErrInvalidName)
+}
+
+func TestRemoveMember(t *testing.T) {
+	ctx := context.Background()
+
+	grp := group.Group{ID: uuid.New(), Name: "on-call"}
+	userID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		buildStubs func(storer *mockgrp.MockStorer)
+		check      func(err error)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(storer *mockgrp.MockStorer) {
+				mbr := group.Member{GroupID: grp.ID, UserID: userID}
+				storer.EXPECT().QueryMember(gomock.Any(), grp.ID, userID).Times(1).Return(mbr, nil)
+				storer.EXPECT().RemoveMember(gomock.Any(), mbr).Times(1).Return(nil)
+			},
+			check: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "NotMember",
+			buildStubs: func(storer *mockgrp.MockStorer) {
+				storer.EXPECT().QueryMember(gomock.Any(), grp.ID, userID).Times(1).Return(group.Member{}, group.ErrMemberNotFound)
+				storer.EXPECT().RemoveMember(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, group.ErrMemberNotFound)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mockgrp.NewMockStorer(ctrl)
+		storer.EXPECT().
+			WithinTran(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(_ context.Context, fn func(group.Storer) error) error { return fn(storer) })
+
+		core := group.NewCore(nil, storer)
+
+		tc.buildStubs(storer)
+
+		tc.check(core.RemoveMember(ctx, grp, userID))
+	}
+}
+
+func TestMemberIDs(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	oncall, sre := uuid.New(), uuid.New()
+	alice, bob := uuid.New(), uuid.New()
+
+	storer := mockgrp.NewMockStorer(ctrl)
+	storer.EXPECT().
+		QueryMembers(gomock.Any(), []uuid.UUID{oncall, sre}).
+		Times(1).
+		Return([]group.Member{
+			{GroupID: oncall, UserID: alice},
+			{GroupID: sre, UserID: bob},
+			{GroupID: oncall, UserID: bob},
+		}, nil)
+
+	core := group.NewCore(nil, storer)
+
+	ids, err := core.MemberIDs(ctx, []uuid.UUID{oncall, sre})
+	require.NoError(t, err)
+	require.Equal(t, map[uuid.UUID][]uuid.UUID{
+		oncall: {alice, bob},
+		sre:    {bob},
+	}, ids)
+
+	ids, err = core.MemberIDs(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
@@ -0,0 +1,217 @@
+// Package groupmem contains group related CRUD functionality, kept in memory.
+package groupmem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table        = "groups"
+	tableMembers = "group_members"
+)
+
+// orderByFields maps the fields groups can be ordered by to their
+// comparisons.
+var orderByFields = map[string]memdb.Compare[group.Group]{
+	group.OrderByID:        compareID,
+	group.OrderByName:      func(a, b group.Group) int { return strings.Compare(a.Name, b.Name) },
+	group.OrderByCreatedAt: func(a, b group.Group) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+// Store manages the set of APIs for group access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Unique("groups_name_key", memdb.Unique{
+		Table: table,
+		Key:   func(row any) (string, bool) { return row.(group.Group).Name, true },
+	})
+
+	db.Reference("group_members_group_id_fkey", memdb.Reference{
+		Table:  tableMembers,
+		Parent: table,
+		Refers: func(row any, key string) bool { return row.(group.Member).GroupID.String() == key },
+	})
+
+	db.Reference("group_members_user_id_fkey", memdb.Reference{
+		Table:  tableMembers,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(group.Member).UserID.String() == key },
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(group.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new group into the database.
+func (s *Store) Create(ctx context.Context, grp group.Group) error {
+	if err := memdb.Insert(s.db, table, grp.ID.String(), grp); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return group.ErrUniqueName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update replaces a group in the database, as long as it's still at the
+// version of the group.
+func (s *Store) Update(ctx context.Context, grp group.Group) error {
+	stored, exists := memdb.Get[group.Group](s.db, table, grp.ID.String())
+	if !exists || stored.Version != grp.Version {
+		return group.ErrVersionConflict
+	}
+
+	grp.CreatedAt = stored.CreatedAt
+	grp.Version++
+
+	if err := memdb.Put(s.db, table, grp.ID.String(), grp); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return group.ErrUniqueName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes a group from the database, as long as it's still at the
+// version of the group. Memberships and subscriptions of the group are
+// removed with it.
+func (s *Store) Delete(ctx context.Context, grp group.Group) error {
+	stored, exists := memdb.Get[group.Group](s.db, table, grp.ID.String())
+	if !exists || stored.Version != grp.Version {
+		return group.ErrVersionConflict
+	}
+
+	memdb.Delete(s.db, table, grp.ID.String())
+
+	return nil
+}
+
+// Query retrieves a list of existing groups from the database.
+func (s *Store) Query(ctx context.Context, filter group.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]group.Group, error) {
+	grps := memdb.Rows(s.db, table, matches(filter))
+
+	if err := memdb.Sort(grps, orderBy, orderByFields, compareID); err != nil {
+		return nil, err
+	}
+
+	return memdb.Page(grps, pageNumber, rowsPerPage), nil
+}
+
+// Count returns the number of groups in the database matching the filter.
+func (s *Store) Count(ctx context.Context, filter group.QueryFilter) (int, error) {
+	return len(memdb.Rows(s.db, table, matches(filter))), nil
+}
+
+// QueryByID gets the specified group from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (group.Group, error) {
+	grp, exists := memdb.Get[group.Group](s.db, table, id.String())
+	if !exists {
+		return group.Group{}, group.ErrNotFound
+	}
+
+	return grp, nil
+}
+
+// AddMember inserts a group membership into the database.
+func (s *Store) AddMember(ctx context.Context, mbr group.Member) error {
+	if err := memdb.Insert(s.db, tableMembers, memberKey(mbr.GroupID, mbr.UserID), mbr); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return group.ErrUniqueMember
+		}
+		return err
+	}
+
+	return nil
+}
+
+// RemoveMember removes a group membership from the database.
+func (s *Store) RemoveMember(ctx context.Context, mbr group.Member) error {
+	memdb.Delete(s.db, tableMembers, memberKey(mbr.GroupID, mbr.UserID))
+
+	return nil
+}
+
+// QueryMember gets the membership of the user in the group from the
+// database.
+func (s *Store) QueryMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) (group.Member, error) {
+	mbr, exists := memdb.Get[group.Member](s.db, tableMembers, memberKey(groupID, userID))
+	if !exists {
+		return group.Member{}, group.ErrMemberNotFound
+	}
+
+	return mbr, nil
+}
+
+// QueryMembers retrieves the members of the specified groups from the
+// database.
+func (s *Store) QueryMembers(ctx context.Context, groupIDs []uuid.UUID) ([]group.Member, error) {
+	mbrs := memdb.Rows(s.db, tableMembers, func(mbr group.Member) bool {
+		return slices.Contains(groupIDs, mbr.GroupID)
+	})
+
+	slices.SortStableFunc(mbrs, func(a, b group.Member) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return mbrs, nil
+}
+
+// =============================================================================
+
+// matches returns whether a group matches the filter. The name matches any
+// part of it, regardless of case.
+func matches(filter group.QueryFilter) func(grp group.Group) bool {
+	return func(grp group.Group) bool {
+		if filter.Name != nil && !strings.Contains(strings.ToLower(grp.Name), strings.ToLower(*filter.Name)) {
+			return false
+		}
+
+		return true
+	}
+}
+
+func compareID(a, b group.Group) int {
+	return memdb.CompareIDs(a.ID, b.ID)
+}
+
+func memberKey(groupID uuid.UUID, userID uuid.UUID) string {
+	return groupID.String() + "/" + userID.String()
+}
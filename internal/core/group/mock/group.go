@@ -0,0 +1,197 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dudakovict/gotify/internal/core/group (interfaces: Storer)
+
+// Package mockgrp is a generated GoMock package.
+package mockgrp
+
+import (
+	context "context"
+	reflect "reflect"
+
+	group "github.com/dudakovict/gotify/internal/core/group"
+	order "github.com/dudakovict/gotify/platform/order"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockStorer is a mock of Storer interface.
+type MockStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorerMockRecorder
+}
+
+// MockStorerMockRecorder is the mock recorder for MockStorer.
+type MockStorerMockRecorder struct {
+	mock *MockStorer
+}
+
+// NewMockStorer creates a new mock instance.
+func NewMockStorer(ctrl *gomock.Controller) *MockStorer {
+	mock := &MockStorer{ctrl: ctrl}
+	mock.recorder = &MockStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
+	return m.recorder
+}
+
+// AddMember mocks base method.
+func (m *MockStorer) AddMember(arg0 context.Context, arg1 group.Member) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMember", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddMember indicates an expected call of AddMember.
+func (mr *MockStorerMockRecorder) AddMember(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMember", reflect.TypeOf((*MockStorer)(nil).AddMember), arg0, arg1)
+}
+
+// Count mocks base method.
+func (m *MockStorer) Count(arg0 context.Context, arg1 group.QueryFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockStorerMockRecorder) Count(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockStorer)(nil).Count), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockStorer) Create(arg0 context.Context, arg1 group.Group) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockStorer) Delete(arg0 context.Context, arg1 group.Group) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// Query mocks base method.
+func (m *MockStorer) Query(arg0 context.Context, arg1 group.QueryFilter, arg2 order.By, arg3, arg4 int) ([]group.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].([]group.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockStorerMockRecorder) Query(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1, arg2, arg3, arg4)
+}
+
+// QueryByID mocks base method.
+func (m *MockStorer) QueryByID(arg0 context.Context, arg1 uuid.UUID) (group.Group, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByID", arg0, arg1)
+	ret0, _ := ret[0].(group.Group)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByID indicates an expected call of QueryByID.
+func (mr *MockStorerMockRecorder) QueryByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0, arg1)
+}
+
+// QueryMember mocks base method.
+func (m *MockStorer) QueryMember(arg0 context.Context, arg1, arg2 uuid.UUID) (group.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryMember", arg0, arg1, arg2)
+	ret0, _ := ret[0].(group.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryMember indicates an expected call of QueryMember.
+func (mr *MockStorerMockRecorder) QueryMember(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryMember", reflect.TypeOf((*MockStorer)(nil).QueryMember), arg0, arg1, arg2)
+}
+
+// QueryMembers mocks base method.
+func (m *MockStorer) QueryMembers(arg0 context.Context, arg1 []uuid.UUID) ([]group.Member, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryMembers", arg0, arg1)
+	ret0, _ := ret[0].([]group.Member)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryMembers indicates an expected call of QueryMembers.
+func (mr *MockStorerMockRecorder) QueryMembers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryMembers", reflect.TypeOf((*MockStorer)(nil).QueryMembers), arg0, arg1)
+}
+
+// RemoveMember mocks base method.
+func (m *MockStorer) RemoveMember(arg0 context.Context, arg1 group.Member) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMember", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveMember indicates an expected call of RemoveMember.
+func (mr *MockStorerMockRecorder) RemoveMember(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMember", reflect.TypeOf((*MockStorer)(nil).RemoveMember), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockStorer) Update(arg0 context.Context, arg1 group.Group) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorerMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1)
+}
+
+// WithinTran mocks base method.
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(group.Storer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTran indicates an expected call of WithinTran.
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
+}
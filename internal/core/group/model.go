@@ -0,0 +1,39 @@
+package group
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Group represents a named set of users, like a team, which can be
+// subscribed to topics as a unit.
+type Group struct {
+	ID          uuid.UUID
+	Name        string
+	Description string
+	Version     int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// NewGroup contains information needed to create a new group.
+type NewGroup struct {
+	Name        string
+	Description string
+}
+
+// UpdateGroup defines what information may be provided to modify an existing
+// group. All fields are optional so clients can send just the fields they want
+// changed.
+type UpdateGroup struct {
+	Name        *string
+	Description *string
+}
+
+// Member represents a user belonging to a group.
+type Member struct {
+	GroupID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
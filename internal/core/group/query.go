@@ -0,0 +1,22 @@
+package group
+
+import "github.com/dudakovict/gotify/platform/order"
+
+// Set of fields the groups can be ordered by.
+const (
+	OrderByID        = "id"
+	OrderByName      = "name"
+	OrderByCreatedAt = "created_at"
+)
+
+// OrderByFields lists the fields the groups can be ordered by.
+var OrderByFields = []string{OrderByID, OrderByName, OrderByCreatedAt}
+
+// DefaultOrderBy is the order groups are listed in unless asked otherwise.
+var DefaultOrderBy = order.NewBy(OrderByName, order.ASC)
+
+// QueryFilter holds the fields groups can be filtered by. Fields left nil
+// match every group, and the name matches any part of it.
+type QueryFilter struct {
+	Name *string
+}
@@ -0,0 +1,285 @@
+// Package groupdb contains group related CRUD functionality.
+package groupdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for group database access.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(group.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts a new group into the database.
+func (s *Store) Create(ctx context.Context, grp group.Group) error {
+	const q = `
+	INSERT INTO groups
+		(id, name, description, version, created_at, updated_at)
+	VALUES
+		(:id, :name, :description, :version, :created_at, :updated_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBGroup(grp)); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return group.ErrUniqueName
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a group in the database, as long as it's still at the
+// version of the group.
+func (s *Store) Update(ctx context.Context, grp group.Group) error {
+	const q = `
+	UPDATE
+		groups
+	SET
+		name = :name,
+		description = :description,
+		updated_at = :updated_at,
+		version = version + 1
+	WHERE
+		id = :id
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, toDBGroup(grp))
+	if err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return group.ErrUniqueName
+		}
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return group.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Delete removes a group from the database, as long as it's still at the
+// version of the group. Memberships and subscriptions of the group are
+// removed with it.
+func (s *Store) Delete(ctx context.Context, grp group.Group) error {
+	data := struct {
+		ID      uuid.UUID `db:"id"`
+		Version int       `db:"version"`
+	}{
+		ID:      grp.ID,
+		Version: grp.Version,
+	}
+
+	const q = `
+	DELETE FROM
+		groups
+	WHERE
+		id = :id
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return group.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Query retrieves a list of existing groups from the database.
+func (s *Store) Query(ctx context.Context, filter group.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]group.Group, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM groups`)
+	applyFilter(b, filter)
+
+	if err := b.OrderBy(orderBy, orderByFields, "id"); err != nil {
+		return nil, err
+	}
+	b.Page(pageNumber, rowsPerPage)
+
+	q, data := b.Query()
+
+	var dbGrps []dbGroup
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbGrps); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreGroupSlice(dbGrps), nil
+}
+
+// Count returns the number of groups in the database matching the filter.
+func (s *Store) Count(ctx context.Context, filter group.QueryFilter) (int, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM groups`)
+	applyFilter(b, filter)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByID gets the specified group from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (group.Group, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM groups WHERE id = :id`
+
+	var dbGrp dbGroup
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbGrp); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return group.Group{}, group.ErrNotFound
+		}
+		return group.Group{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreGroup(dbGrp), nil
+}
+
+// AddMember inserts a group membership into the database.
+func (s *Store) AddMember(ctx context.Context, mbr group.Member) error {
+	const q = `
+	INSERT INTO group_members
+		(group_id, user_id, created_at)
+	VALUES
+		(:group_id, :user_id, :created_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBMember(mbr)); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return group.ErrUniqueMember
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveMember removes a group membership from the database.
+func (s *Store) RemoveMember(ctx context.Context, mbr group.Member) error {
+	data := struct {
+		GroupID uuid.UUID `db:"group_id"`
+		UserID  uuid.UUID `db:"user_id"`
+	}{
+		GroupID: mbr.GroupID,
+		UserID:  mbr.UserID,
+	}
+
+	const q = `
+	DELETE FROM
+		group_members
+	WHERE
+		group_id = :group_id AND user_id = :user_id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryMember gets the membership of the user in the group from the
+// database.
+func (s *Store) QueryMember(ctx context.Context, groupID uuid.UUID, userID uuid.UUID) (group.Member, error) {
+	data := struct {
+		GroupID uuid.UUID `db:"group_id"`
+		UserID  uuid.UUID `db:"user_id"`
+	}{
+		GroupID: groupID,
+		UserID:  userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		group_members
+	WHERE
+		group_id = :group_id AND user_id = :user_id`
+
+	var dbMbr dbMember
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbMbr); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return group.Member{}, group.ErrMemberNotFound
+		}
+		return group.Member{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreMember(dbMbr), nil
+}
+
+// QueryMembers retrieves the members of the specified groups from the
+// database.
+func (s *Store) QueryMembers(ctx context.Context, groupIDs []uuid.UUID) ([]group.Member, error) {
+	strIDs := make(pq.StringArray, len(groupIDs))
+	for i, id := range groupIDs {
+		strIDs[i] = id.String()
+	}
+
+	data := struct {
+		GroupIDs pq.StringArray `db:"group_ids"`
+	}{
+		GroupIDs: strIDs,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		group_members
+	WHERE
+		group_id = ANY(:group_ids)
+	ORDER BY
+		created_at`
+
+	var dbMbrs []dbMember
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbMbrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreMemberSlice(dbMbrs), nil
+}
@@ -0,0 +1,79 @@
+package groupdb
+
+import (
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/google/uuid"
+)
+
+type dbGroup struct {
+	ID          uuid.UUID `db:"id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Version     int       `db:"version"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+func toDBGroup(grp group.Group) dbGroup {
+	return dbGroup{
+		ID:          grp.ID,
+		Name:        grp.Name,
+		Description: grp.Description,
+		Version:     grp.Version,
+		CreatedAt:   grp.CreatedAt.UTC(),
+		UpdatedAt:   grp.UpdatedAt.UTC(),
+	}
+}
+
+func toCoreGroup(dbGrp dbGroup) group.Group {
+	return group.Group{
+		ID:          dbGrp.ID,
+		Name:        dbGrp.Name,
+		Description: dbGrp.Description,
+		Version:     dbGrp.Version,
+		CreatedAt:   dbGrp.CreatedAt.In(time.Local),
+		UpdatedAt:   dbGrp.UpdatedAt.In(time.Local),
+	}
+}
+
+func toCoreGroupSlice(dbGrps []dbGroup) []group.Group {
+	grps := make([]group.Group, len(dbGrps))
+	for i, dbGrp := range dbGrps {
+		grps[i] = toCoreGroup(dbGrp)
+	}
+	return grps
+}
+
+// =============================================================================
+
+type dbMember struct {
+	GroupID   uuid.UUID `db:"group_id"`
+	UserID    uuid.UUID `db:"user_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func toDBMember(mbr group.Member) dbMember {
+	return dbMember{
+		GroupID:   mbr.GroupID,
+		UserID:    mbr.UserID,
+		CreatedAt: mbr.CreatedAt.UTC(),
+	}
+}
+
+func toCoreMember(dbMbr dbMember) group.Member {
+	return group.Member{
+		GroupID:   dbMbr.GroupID,
+		UserID:    dbMbr.UserID,
+		CreatedAt: dbMbr.CreatedAt.In(time.Local),
+	}
+}
+
+func toCoreMemberSlice(dbMbrs []dbMember) []group.Member {
+	mbrs := make([]group.Member, len(dbMbrs))
+	for i, dbMbr := range dbMbrs {
+		mbrs[i] = toCoreMember(dbMbr)
+	}
+	return mbrs
+}
@@ -0,0 +1,20 @@
+package groupdb
+
+import (
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/platform/database"
+)
+
+// orderByFields maps the fields groups can be ordered by to their columns.
+var orderByFields = map[string]string{
+	group.OrderByID:        "id",
+	group.OrderByName:      "name",
+	group.OrderByCreatedAt: "created_at",
+}
+
+// applyFilter adds the conditions of the filter to the query.
+func applyFilter(b *database.QueryBuilder, filter group.QueryFilter) {
+	if filter.Name != nil {
+		b.Where("name ILIKE :name", map[string]any{"name": "%" + *filter.Name + "%"})
+	}
+}
@@ -0,0 +1,188 @@
+// Package mfamem contains mfa related CRUD functionality, kept in memory.
+package mfamem
+
+import (
+	"context"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/mfa"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table              = "mfa_secrets"
+	tableRecoveryCodes = "mfa_recovery_codes"
+)
+
+// Store manages the set of APIs for mfa access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Unique("unique_recovery_code_user", memdb.Unique{
+		Table: tableRecoveryCodes,
+		Key: func(row any) (string, bool) {
+			rc := row.(mfa.RecoveryCode)
+			return rc.UserID.String() + "/" + rc.HashedCode, true
+		},
+	})
+
+	db.Reference("mfa_secrets_user_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(mfa.MFA).UserID.String() == key },
+	})
+
+	db.Reference("mfa_recovery_codes_user_id_fkey", memdb.Reference{
+		Table:  tableRecoveryCodes,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(mfa.RecoveryCode).UserID.String() == key },
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(mfa.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts new mfa settings into the database.
+func (s *Store) Create(ctx context.Context, m mfa.MFA) error {
+	return memdb.Insert(s.db, table, m.UserID.String(), m)
+}
+
+// Update replaces mfa settings in the database.
+func (s *Store) Update(ctx context.Context, m mfa.MFA) error {
+	stored, exists := memdb.Get[mfa.MFA](s.db, table, m.UserID.String())
+	if !exists {
+		return nil
+	}
+
+	m.CreatedAt = stored.CreatedAt
+
+	return memdb.Put(s.db, table, m.UserID.String(), m)
+}
+
+// UseStep records the time step of the code the user just used, unless a
+// step as new was used already, which is reported as mfa.ErrInvalidCode.
+func (s *Store) UseStep(ctx context.Context, userID uuid.UUID, step int64, now time.Time) error {
+	memdb.Lock(s.db, table, userID.String())
+
+	stored, exists := memdb.Get[mfa.MFA](s.db, table, userID.String())
+	if !exists || stored.LastUsedStep >= step {
+		return mfa.ErrInvalidCode
+	}
+
+	stored.LastUsedStep = step
+	stored.UpdatedAt = now
+
+	return memdb.Put(s.db, table, userID.String(), stored)
+}
+
+// AddFailedAttempt counts an attempt at a code against the user. The count is
+// incremented under a lock so that concurrent attempts are all counted.
+func (s *Store) AddFailedAttempt(ctx context.Context, userID uuid.UUID) error {
+	memdb.Lock(s.db, table, userID.String())
+
+	stored, exists := memdb.Get[mfa.MFA](s.db, table, userID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.FailedAttempts++
+
+	return memdb.Put(s.db, table, userID.String(), stored)
+}
+
+// Delete removes mfa settings from the database.
+func (s *Store) Delete(ctx context.Context, m mfa.MFA) error {
+	memdb.Delete(s.db, table, m.UserID.String())
+
+	return nil
+}
+
+// QueryByUserID gets the mfa settings of the specified user from the database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) (mfa.MFA, error) {
+	m, exists := memdb.Get[mfa.MFA](s.db, table, userID.String())
+	if !exists {
+		return mfa.MFA{}, mfa.ErrNotFound
+	}
+
+	return m, nil
+}
+
+// CreateRecoveryCodes inserts a batch of recovery codes into the database.
+func (s *Store) CreateRecoveryCodes(ctx context.Context, codes []mfa.RecoveryCode) error {
+	for _, rc := range codes {
+		if err := memdb.Insert(s.db, tableRecoveryCodes, rc.ID.String(), rc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateRecoveryCode marks a recovery code as used. A code used already is
+// reported as mfa.ErrInvalidCode, so it can't be used twice at once.
+func (s *Store) UpdateRecoveryCode(ctx context.Context, code mfa.RecoveryCode) error {
+	memdb.Lock(s.db, tableRecoveryCodes, code.ID.String())
+
+	stored, exists := memdb.Get[mfa.RecoveryCode](s.db, tableRecoveryCodes, code.ID.String())
+	if !exists || stored.Used {
+		return mfa.ErrInvalidCode
+	}
+
+	stored.Used = code.Used
+
+	return memdb.Put(s.db, tableRecoveryCodes, stored.ID.String(), stored)
+}
+
+// DeleteRecoveryCodes removes all recovery codes of the specified user.
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	rcs := memdb.Rows(s.db, tableRecoveryCodes, func(rc mfa.RecoveryCode) bool {
+		return rc.UserID == userID
+	})
+
+	for _, rc := range rcs {
+		memdb.Delete(s.db, tableRecoveryCodes, rc.ID.String())
+	}
+
+	return nil
+}
+
+// QueryRecoveryCode gets the specified recovery code of a user from the
+// database by its hash.
+func (s *Store) QueryRecoveryCode(ctx context.Context, userID uuid.UUID, hashedCode string) (mfa.RecoveryCode, error) {
+	rcs := memdb.Rows(s.db, tableRecoveryCodes, func(rc mfa.RecoveryCode) bool {
+		return rc.UserID == userID && rc.HashedCode == hashedCode
+	})
+
+	if len(rcs) == 0 {
+		return mfa.RecoveryCode{}, mfa.ErrNotFound
+	}
+
+	return rcs[0], nil
+}
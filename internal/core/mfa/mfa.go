@@ -0,0 +1,484 @@
+// Package mfa provides a core business API.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/pkg/encrypt"
+	"github.com/dudakovict/gotify/pkg/totp"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound       = errors.New("mfa not found")
+	ErrAlreadyEnabled = errors.New("mfa is already enabled")
+	ErrNotEnabled     = errors.New("mfa is not enabled")
+	ErrInvalidCode    = errors.New("mfa code is invalid")
+	ErrLocked         = errors.New("mfa login is locked after too many failed attempts")
+)
+
+// Number of recovery codes generated for a user.
+const recoveryCodeCount = 10
+
+// Number of failed attempts at logging in with a code a user gets, and for how
+// long mfa login is locked after that.
+const (
+	maxFailedAttempts = 5
+	lockoutDuration   = 15 * time.Minute
+)
+
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, m MFA) error
+	Update(ctx context.Context, m MFA) error
+	UseStep(ctx context.Context, userID uuid.UUID, step int64, now time.Time) error
+	AddFailedAttempt(ctx context.Context, userID uuid.UUID) error
+	Delete(ctx context.Context, m MFA) error
+	QueryByUserID(ctx context.Context, userID uuid.UUID) (MFA, error)
+	CreateRecoveryCodes(ctx context.Context, codes []RecoveryCode) error
+	UpdateRecoveryCode(ctx context.Context, code RecoveryCode) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	QueryRecoveryCode(ctx context.Context, userID uuid.UUID, hashedCode string) (RecoveryCode, error)
+}
+
+// Core manages the set of APIs for mfa access.
+type Core struct {
+	log       *zerolog.Logger
+	storer    Storer
+	encrypter *encrypt.Encrypter
+	issuer    string
+}
+
+// NewCore constructs a mfa core API for use.
+func NewCore(log *zerolog.Logger, storer Storer, encrypter *encrypt.Encrypter, issuer string) *Core {
+	return &Core{
+		log:       log,
+		storer:    storer,
+		encrypter: encrypter,
+		issuer:    issuer,
+	}
+}
+
+// Enroll generates a new secret for the user. The secret is not used for
+// authentication until it is confirmed with a valid code.
+func (c *Core) Enroll(ctx context.Context, usr user.User) (Enrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("generatesecret: %w", err)
+	}
+
+	encrypted, err := c.encrypter.Encrypt([]byte(secret))
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("encrypt: %w", err)
+	}
+
+	tran := func(s Storer) error {
+		m, err := s.QueryByUserID(ctx, usr.ID)
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("query: %w", err)
+			}
+
+			m = MFA{
+				UserID:          usr.ID,
+				EncryptedSecret: encrypted,
+				CreatedAt:       time.Now(),
+			}
+
+			if err := s.Create(ctx, m); err != nil {
+				return fmt.Errorf("create: %w", err)
+			}
+
+			return nil
+		}
+
+		if m.Enabled {
+			return ErrAlreadyEnabled
+		}
+
+		m.EncryptedSecret = encrypted
+		m.LastUsedStep = 0
+		m.UpdatedAt = time.Now()
+
+		if err := s.Update(ctx, m); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return Enrollment{}, fmt.Errorf("tran: %w", err)
+	}
+
+	enr := Enrollment{
+		Secret: secret,
+		URI:    totp.URI(c.issuer, usr.Email, secret),
+	}
+
+	return enr, nil
+}
+
+// Confirm enables mfa for the user once they prove their authenticator app
+// is set up, and returns a fresh set of recovery codes.
+func (c *Core) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	var codes []string
+
+	tran := func(s Storer) error {
+		m, err := s.QueryByUserID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		if m.Enabled {
+			return ErrAlreadyEnabled
+		}
+
+		if err := c.useCode(ctx, s, &m, code); err != nil {
+			return err
+		}
+
+		m.Enabled = true
+		m.UpdatedAt = time.Now()
+
+		if err := s.Update(ctx, m); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+
+		codes, err = c.replaceRecoveryCodes(ctx, s, userID)
+		return err
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return nil, fmt.Errorf("tran: %w", err)
+	}
+
+	return codes, nil
+}
+
+// Verify checks a code produced by the user's authenticator app. A code can
+// only be used once. Too many failed attempts lock mfa login for a while.
+func (c *Core) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	if err := c.attempt(ctx, userID); err != nil {
+		return fmt.Errorf("attempt: %w", err)
+	}
+
+	tran := func(s Storer) error {
+		m, err := c.queryEnabled(ctx, s, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := c.useCode(ctx, s, &m, code); err != nil {
+			return err
+		}
+
+		return c.resetAttempts(ctx, s, m)
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	return nil
+}
+
+// Recover checks and consumes one of the user's recovery codes. Failed
+// attempts count towards the same lock as Verify.
+func (c *Core) Recover(ctx context.Context, userID uuid.UUID, recoveryCode string) error {
+	if err := c.attempt(ctx, userID); err != nil {
+		return fmt.Errorf("attempt: %w", err)
+	}
+
+	tran := func(s Storer) error {
+		m, err := c.queryEnabled(ctx, s, userID)
+		if err != nil {
+			return err
+		}
+
+		rc, err := s.QueryRecoveryCode(ctx, userID, hashRecoveryCode(recoveryCode))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrInvalidCode
+			}
+			return fmt.Errorf("queryrecoverycode: %w", err)
+		}
+
+		if rc.Used {
+			return ErrInvalidCode
+		}
+
+		rc.Used = true
+
+		if err := s.UpdateRecoveryCode(ctx, rc); err != nil {
+			if errors.Is(err, ErrInvalidCode) {
+				return ErrInvalidCode
+			}
+			return fmt.Errorf("updaterecoverycode: %w", err)
+		}
+
+		return c.resetAttempts(ctx, s, m)
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes invalidates the user's recovery codes and returns
+// a fresh set.
+func (c *Core) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	var codes []string
+
+	tran := func(s Storer) error {
+		m, err := c.queryEnabled(ctx, s, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := c.useCode(ctx, s, &m, code); err != nil {
+			return err
+		}
+
+		codes, err = c.replaceRecoveryCodes(ctx, s, userID)
+		return err
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return nil, fmt.Errorf("tran: %w", err)
+	}
+
+	return codes, nil
+}
+
+// Disable turns off mfa for the user and removes the secret and all recovery
+// codes.
+func (c *Core) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	tran := func(s Storer) error {
+		m, err := c.queryEnabled(ctx, s, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := c.useCode(ctx, s, &m, code); err != nil {
+			return err
+		}
+
+		if err := s.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return fmt.Errorf("deleterecoverycodes: %w", err)
+		}
+
+		if err := s.Delete(ctx, m); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	return nil
+}
+
+// IsEnabled reports whether the user has confirmed mfa.
+func (c *Core) IsEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	m, err := c.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("query: %w", err)
+	}
+
+	return m.Enabled, nil
+}
+
+func (c *Core) queryEnabled(ctx context.Context, s Storer, userID uuid.UUID) (MFA, error) {
+	m, err := s.QueryByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return MFA{}, ErrNotEnabled
+		}
+		return MFA{}, fmt.Errorf("query: %w", err)
+	}
+
+	if !m.Enabled {
+		return MFA{}, ErrNotEnabled
+	}
+
+	return m, nil
+}
+
+// attempt counts an attempt at logging in with a code as failed before the
+// code is checked, so concurrent guesses are all counted, and a successful
+// login resets the count. The attempt after maxFailedAttempts locks mfa login
+// for lockoutDuration and starts the count over.
+func (c *Core) attempt(ctx context.Context, userID uuid.UUID) error {
+	var locked bool
+
+	tran := func(s Storer) error {
+		if err := s.AddFailedAttempt(ctx, userID); err != nil {
+			return fmt.Errorf("addfailedattempt: %w", err)
+		}
+
+		m, err := c.queryEnabled(ctx, s, userID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		if now.Before(m.LockedUntil) {
+			return ErrLocked
+		}
+
+		if m.FailedAttempts <= maxFailedAttempts {
+			return nil
+		}
+
+		m.FailedAttempts = 0
+		m.LockedUntil = now.Add(lockoutDuration)
+		m.UpdatedAt = now
+
+		if err := s.Update(ctx, m); err != nil {
+			return fmt.Errorf("update: %w", err)
+		}
+
+		locked = true
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	if locked {
+		return ErrLocked
+	}
+
+	return nil
+}
+
+func (c *Core) resetAttempts(ctx context.Context, s Storer, m MFA) error {
+	m.FailedAttempts = 0
+	m.UpdatedAt = time.Now()
+
+	if err := s.Update(ctx, m); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
+
+// useCode validates the code against the stored secret and records the
+// matched step, on m and in the store, so the same code can't be replayed.
+// The store only takes a step newer than the last one used, so concurrent
+// requests with the same code can't both pass.
+func (c *Core) useCode(ctx context.Context, s Storer, m *MFA, code string) error {
+	secret, err := c.encrypter.Decrypt(m.EncryptedSecret)
+	if err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+
+	step, ok, err := totp.Validate(string(secret), code, time.Now())
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	if !ok || step <= m.LastUsedStep {
+		return ErrInvalidCode
+	}
+
+	now := time.Now()
+
+	if err := s.UseStep(ctx, m.UserID, step, now); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			return ErrInvalidCode
+		}
+		return fmt.Errorf("usestep: %w", err)
+	}
+
+	m.LastUsedStep = step
+	m.UpdatedAt = now
+
+	return nil
+}
+
+func (c *Core) replaceRecoveryCodes(ctx context.Context, s Storer, userID uuid.UUID) ([]string, error) {
+	if err := s.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("deleterecoverycodes: %w", err)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	rcs := make([]RecoveryCode, recoveryCodeCount)
+
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("generaterecoverycode: %w", err)
+		}
+
+		codes[i] = code
+		rcs[i] = RecoveryCode{
+			ID:         uuid.New(),
+			UserID:     userID,
+			HashedCode: hashRecoveryCode(code),
+			CreatedAt:  time.Now(),
+		}
+	}
+
+	if err := s.CreateRecoveryCodes(ctx, rcs); err != nil {
+		return nil, fmt.Errorf("createrecoverycodes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode generates a random code in the form xxxxx-xxxxx, every
+// character equally likely.
+func generateRecoveryCode() (string, error) {
+	n := big.NewInt(int64(len(recoveryCodeAlphabet)))
+
+	var sb strings.Builder
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+
+		v, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[v.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+// hashRecoveryCode hashes a recovery code. Codes are random and high entropy
+// so a fast hash is enough and allows looking them up directly.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,302 @@
+package mfa_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/mfa"
+	mockmfa "github.com/dudakovict/gotify/internal/core/mfa/mock"
+	"github.com/dudakovict/gotify/pkg/encrypt"
+	"github.com/dudakovict/gotify/pkg/totp"
+	"github.com/dudakovict/gotify/pkg/util"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	enc, err := encrypt.New(util.RandomString(32), "mfa")
+	require.NoError(t, err)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	encrypted, err := enc.Encrypt([]byte(secret))
+	require.NoError(t, err)
+
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+
+	userID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		code          string
+		buildStubs    func(storer *mockmfa.MockStorer)
+		checkResponse func(err error)
+	}{
+		{
+			name: "OK",
+			code: code,
+			buildStubs: func(storer *mockmfa.MockStorer) {
+				storer.EXPECT().
+					AddFailedAttempt(gomock.Any(), userID).
+					Times(1).
+					Return(nil)
+
+				storer.EXPECT().
+					QueryByUserID(gomock.Any(), userID).
+					Times(2).
+					Return(mfa.MFA{UserID: userID, EncryptedSecret: encrypted, Enabled: true, FailedAttempts: 1}, nil)
+
+				storer.EXPECT().
+					UseStep(gomock.Any(), userID, gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, _ uuid.UUID, step int64, _ time.Time) error {
+						require.NotZero(t, step)
+						return nil
+					})
+
+				storer.EXPECT().
+					Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, m mfa.MFA) error {
+						require.Zero(t, m.FailedAttempts)
+						return nil
+					})
+			},
+			checkResponse: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "Replay",
+			code: code,
+			buildStubs: func(storer *mockmfa.MockStorer) {
+				storer.EXPECT().
+					AddFailedAttempt(gomock.Any(), userID).
+					Times(1).
+					Return(nil)
+
+				storer.EXPECT().
+					QueryByUserID(gomock.Any(), userID).
+					Times(2).
+					Return(mfa.MFA{UserID: userID, EncryptedSecret: encrypted, Enabled: true, LastUsedStep: totp.Step(time.Now())}, nil)
+
+				storer.EXPECT().
+					UseStep(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(err error) {
+				require.ErrorIs(t, err, mfa.ErrInvalidCode)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: "000000x",
+			buildStubs: func(storer *mockmfa.MockStorer) {
+				storer.EXPECT().
+					AddFailedAttempt(gomock.Any(), userID).
+					Times(1).
+					Return(nil)
+
+				storer.EXPECT().
+					QueryByUserID(gomock.Any(), userID).
+					Times(2).
+					Return(mfa.MFA{UserID: userID, EncryptedSecret: encrypted, Enabled: true}, nil)
+			},
+			checkResponse: func(err error) {
+				require.ErrorIs(t, err, mfa.ErrInvalidCode)
+			},
+		},
+		{
+			name: "Locked",
+			code: code,
+			buildStubs: func(storer *mockmfa.MockStorer) {
+				storer.EXPECT().
+					AddFailedAttempt(gomock.Any(), userID).
+					Times(1).
+					Return(nil)
+
+				storer.EXPECT().
+					QueryByUserID(gomock.Any(), userID).
+					Times(1).
+					Return(mfa.MFA{UserID: userID, EncryptedSecret: encrypted, Enabled: true, LockedUntil: time.Now().Add(time.Minute)}, nil)
+
+				storer.EXPECT().
+					UseStep(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(err error) {
+				require.ErrorIs(t, err, mfa.ErrLocked)
+			},
+		},
+		{
+			name: "TooManyAttempts",
+			code: code,
+			buildStubs: func(storer *mockmfa.MockStorer) {
+				storer.EXPECT().
+					AddFailedAttempt(gomock.Any(), userID).
+					Times(1).
+					Return(nil)
+
+				storer.EXPECT().
+					QueryByUserID(gomock.Any(), userID).
+					Times(1).
+					Return(mfa.MFA{UserID: userID, EncryptedSecret: encrypted, Enabled: true, FailedAttempts: 6}, nil)
+
+				storer.EXPECT().
+					Update(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, m mfa.MFA) error {
+						require.Zero(t, m.FailedAttempts)
+						require.True(t, m.LockedUntil.After(time.Now()))
+						return nil
+					})
+
+				storer.EXPECT().
+					UseStep(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(err error) {
+				require.ErrorIs(t, err, mfa.ErrLocked)
+			},
+		},
+		{
+			name: "NotEnabled",
+			code: code,
+			buildStubs: func(storer *mockmfa.MockStorer) {
+				storer.EXPECT().
+					AddFailedAttempt(gomock.Any(), userID).
+					Times(1).
+					Return(nil)
+
+				storer.EXPECT().
+					QueryByUserID(gomock.Any(), userID).
+					Times(1).
+					Return(mfa.MFA{UserID: userID, EncryptedSecret: encrypted}, nil)
+			},
+			checkResponse: func(err error) {
+				require.ErrorIs(t, err, mfa.ErrNotEnabled)
+			},
+		},
+		{
+			name: "InternalError",
+			code: code,
+			buildStubs: func(storer *mockmfa.MockStorer) {
+				storer.EXPECT().
+					AddFailedAttempt(gomock.Any(), userID).
+					Times(1).
+					Return(nil)
+
+				storer.EXPECT().
+					QueryByUserID(gomock.Any(), userID).
+					Times(1).
+					Return(mfa.MFA{}, sql.ErrConnDone)
+			},
+			checkResponse: func(err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mockmfa.NewMockStorer(ctrl)
+		storer.EXPECT().
+			WithinTran(gomock.Any(), gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(_ context.Context, fn func(mfa.Storer) error) error {
+				return fn(storer)
+			})
+
+		core := mfa.NewCore(nil, storer, enc, "Gotify")
+
+		tc.buildStubs(storer)
+
+		err := core.Verify(ctx, userID, tc.code)
+
+		tc.checkResponse(err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	ctx := context.Background()
+
+	enc, err := encrypt.New(util.RandomString(32), "mfa")
+	require.NoError(t, err)
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	encrypted, err := enc.Encrypt([]byte(secret))
+	require.NoError(t, err)
+
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+
+	userID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mockmfa.NewMockStorer(ctrl)
+	storer.EXPECT().
+		WithinTran(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, fn func(mfa.Storer) error) error {
+			return fn(storer)
+		})
+
+	storer.EXPECT().
+		QueryByUserID(gomock.Any(), userID).
+		Times(1).
+		Return(mfa.MFA{UserID: userID, EncryptedSecret: encrypted}, nil)
+
+	storer.EXPECT().
+		UseStep(gomock.Any(), userID, gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+
+	storer.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, m mfa.MFA) error {
+			require.True(t, m.Enabled)
+			return nil
+		})
+
+	storer.EXPECT().
+		DeleteRecoveryCodes(gomock.Any(), userID).
+		Times(1).
+		Return(nil)
+
+	var stored []mfa.RecoveryCode
+	storer.EXPECT().
+		CreateRecoveryCodes(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, rcs []mfa.RecoveryCode) error {
+			stored = rcs
+			return nil
+		})
+
+	core := mfa.NewCore(nil, storer, enc, "Gotify")
+
+	codes, err := core.Confirm(ctx, userID, code)
+	require.NoError(t, err)
+	require.Len(t, codes, len(stored))
+
+	for i, rc := range stored {
+		require.Equal(t, userID, rc.UserID)
+		require.NotEqual(t, codes[i], rc.HashedCode)
+		require.NotContains(t, rc.HashedCode, codes[i])
+	}
+}
@@ -0,0 +1,194 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dudakovict/gotify/internal/core/mfa (interfaces: Storer)
+
+// Package mockmfa is a generated GoMock package.
+package mockmfa
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	mfa "github.com/dudakovict/gotify/internal/core/mfa"
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockStorer is a mock of Storer interface.
+type MockStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorerMockRecorder
+}
+
+// MockStorerMockRecorder is the mock recorder for MockStorer.
+type MockStorerMockRecorder struct {
+	mock *MockStorer
+}
+
+// NewMockStorer creates a new mock instance.
+func NewMockStorer(ctrl *gomock.Controller) *MockStorer {
+	mock := &MockStorer{ctrl: ctrl}
+	mock.recorder = &MockStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
+	return m.recorder
+}
+
+// AddFailedAttempt mocks base method.
+func (m *MockStorer) AddFailedAttempt(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFailedAttempt", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddFailedAttempt indicates an expected call of AddFailedAttempt.
+func (mr *MockStorerMockRecorder) AddFailedAttempt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFailedAttempt", reflect.TypeOf((*MockStorer)(nil).AddFailedAttempt), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockStorer) Create(arg0 context.Context, arg1 mfa.MFA) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
+}
+
+// CreateRecoveryCodes mocks base method.
+func (m *MockStorer) CreateRecoveryCodes(arg0 context.Context, arg1 []mfa.RecoveryCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecoveryCodes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRecoveryCodes indicates an expected call of CreateRecoveryCodes.
+func (mr *MockStorerMockRecorder) CreateRecoveryCodes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecoveryCodes", reflect.TypeOf((*MockStorer)(nil).CreateRecoveryCodes), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockStorer) Delete(arg0 context.Context, arg1 mfa.MFA) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// DeleteRecoveryCodes mocks base method.
+func (m *MockStorer) DeleteRecoveryCodes(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecoveryCodes", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecoveryCodes indicates an expected call of DeleteRecoveryCodes.
+func (mr *MockStorerMockRecorder) DeleteRecoveryCodes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecoveryCodes", reflect.TypeOf((*MockStorer)(nil).DeleteRecoveryCodes), arg0, arg1)
+}
+
+// QueryByUserID mocks base method.
+func (m *MockStorer) QueryByUserID(arg0 context.Context, arg1 uuid.UUID) (mfa.MFA, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByUserID", arg0, arg1)
+	ret0, _ := ret[0].(mfa.MFA)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByUserID indicates an expected call of QueryByUserID.
+func (mr *MockStorerMockRecorder) QueryByUserID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByUserID", reflect.TypeOf((*MockStorer)(nil).QueryByUserID), arg0, arg1)
+}
+
+// QueryRecoveryCode mocks base method.
+func (m *MockStorer) QueryRecoveryCode(arg0 context.Context, arg1 uuid.UUID, arg2 string) (mfa.RecoveryCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryRecoveryCode", arg0, arg1, arg2)
+	ret0, _ := ret[0].(mfa.RecoveryCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryRecoveryCode indicates an expected call of QueryRecoveryCode.
+func (mr *MockStorerMockRecorder) QueryRecoveryCode(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRecoveryCode", reflect.TypeOf((*MockStorer)(nil).QueryRecoveryCode), arg0, arg1, arg2)
+}
+
+// Update mocks base method.
+func (m *MockStorer) Update(arg0 context.Context, arg1 mfa.MFA) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorerMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1)
+}
+
+// UpdateRecoveryCode mocks base method.
+func (m *MockStorer) UpdateRecoveryCode(arg0 context.Context, arg1 mfa.RecoveryCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRecoveryCode", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRecoveryCode indicates an expected call of UpdateRecoveryCode.
+func (mr *MockStorerMockRecorder) UpdateRecoveryCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRecoveryCode", reflect.TypeOf((*MockStorer)(nil).UpdateRecoveryCode), arg0, arg1)
+}
+
+// UseStep mocks base method.
+func (m *MockStorer) UseStep(arg0 context.Context, arg1 uuid.UUID, arg2 int64, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseStep", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UseStep indicates an expected call of UseStep.
+func (mr *MockStorerMockRecorder) UseStep(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseStep", reflect.TypeOf((*MockStorer)(nil).UseStep), arg0, arg1, arg2, arg3)
+}
+
+// WithinTran mocks base method.
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(mfa.Storer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTran indicates an expected call of WithinTran.
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
+}
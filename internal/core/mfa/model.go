@@ -0,0 +1,34 @@
+package mfa
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFA represents the two-factor authentication settings of a user.
+type MFA struct {
+	UserID          uuid.UUID
+	EncryptedSecret string
+	Enabled         bool
+	LastUsedStep    int64
+	FailedAttempts  int
+	LockedUntil     time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// RecoveryCode represents a hashed one-time recovery code.
+type RecoveryCode struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	HashedCode string
+	Used       bool
+	CreatedAt  time.Time
+}
+
+// Enrollment contains information needed to set up an authenticator app.
+type Enrollment struct {
+	Secret string
+	URI    string
+}
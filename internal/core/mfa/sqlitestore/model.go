@@ -0,0 +1,86 @@
+package mfalite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/mfa"
+	"github.com/google/uuid"
+)
+
+type dbMFA struct {
+	UserID         uuid.UUID    `db:"user_id"`
+	Secret         string       `db:"secret"`
+	Enabled        bool         `db:"enabled"`
+	LastUsedStep   int64        `db:"last_used_step"`
+	FailedAttempts int          `db:"failed_attempts"`
+	LockedUntil    sql.NullTime `db:"locked_until"`
+	CreatedAt      time.Time    `db:"created_at"`
+	UpdatedAt      time.Time    `db:"updated_at"`
+}
+
+func toDBMFA(m mfa.MFA) dbMFA {
+	return dbMFA{
+		UserID:         m.UserID,
+		Secret:         m.EncryptedSecret,
+		Enabled:        m.Enabled,
+		LastUsedStep:   m.LastUsedStep,
+		FailedAttempts: m.FailedAttempts,
+		LockedUntil:    toNullTime(m.LockedUntil),
+		CreatedAt:      m.CreatedAt.UTC(),
+		UpdatedAt:      m.UpdatedAt.UTC(),
+	}
+}
+
+func toCoreMFA(dbM dbMFA) mfa.MFA {
+	m := mfa.MFA{
+		UserID:          dbM.UserID,
+		EncryptedSecret: dbM.Secret,
+		Enabled:         dbM.Enabled,
+		LastUsedStep:    dbM.LastUsedStep,
+		FailedAttempts:  dbM.FailedAttempts,
+		CreatedAt:       dbM.CreatedAt.In(time.Local),
+		UpdatedAt:       dbM.UpdatedAt.In(time.Local),
+	}
+
+	if dbM.LockedUntil.Valid {
+		m.LockedUntil = dbM.LockedUntil.Time.In(time.Local)
+	}
+
+	return m
+}
+
+type dbRecoveryCode struct {
+	ID         uuid.UUID `db:"id"`
+	UserID     uuid.UUID `db:"user_id"`
+	HashedCode string    `db:"hashed_code"`
+	Used       bool      `db:"used"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+func toDBRecoveryCode(rc mfa.RecoveryCode) dbRecoveryCode {
+	return dbRecoveryCode{
+		ID:         rc.ID,
+		UserID:     rc.UserID,
+		HashedCode: rc.HashedCode,
+		Used:       rc.Used,
+		CreatedAt:  rc.CreatedAt.UTC(),
+	}
+}
+
+func toCoreRecoveryCode(dbRc dbRecoveryCode) mfa.RecoveryCode {
+	return mfa.RecoveryCode{
+		ID:         dbRc.ID,
+		UserID:     dbRc.UserID,
+		HashedCode: dbRc.HashedCode,
+		Used:       dbRc.Used,
+		CreatedAt:  dbRc.CreatedAt.In(time.Local),
+	}
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{
+		Time:  t.UTC(),
+		Valid: !t.IsZero(),
+	}
+}
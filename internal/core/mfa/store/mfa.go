@@ -0,0 +1,286 @@
+// Package mfadb contains mfa related CRUD functionality.
+package mfadb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/mfa"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for mfa database access.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(mfa.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts new mfa settings into the database.
+func (s *Store) Create(ctx context.Context, m mfa.MFA) error {
+	const q = `
+	INSERT INTO mfa_secrets
+		(user_id, secret, enabled, last_used_step, failed_attempts, locked_until, created_at, updated_at)
+	VALUES
+		(:user_id, :secret, :enabled, :last_used_step, :failed_attempts, :locked_until, :created_at, :updated_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBMFA(m)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces mfa settings in the database.
+func (s *Store) Update(ctx context.Context, m mfa.MFA) error {
+	const q = `
+	UPDATE
+		mfa_secrets
+	SET
+		secret = :secret,
+		enabled = :enabled,
+		last_used_step = :last_used_step,
+		failed_attempts = :failed_attempts,
+		locked_until = :locked_until,
+		updated_at = :updated_at
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBMFA(m)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// UseStep records the time step of the code the user just used, unless a
+// step as new was used already, which is reported as mfa.ErrInvalidCode.
+func (s *Store) UseStep(ctx context.Context, userID uuid.UUID, step int64, now time.Time) error {
+	data := struct {
+		UserID       uuid.UUID `db:"user_id"`
+		LastUsedStep int64     `db:"last_used_step"`
+		UpdatedAt    time.Time `db:"updated_at"`
+	}{
+		UserID:       userID,
+		LastUsedStep: step,
+		UpdatedAt:    now.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		mfa_secrets
+	SET
+		last_used_step = :last_used_step,
+		updated_at = :updated_at
+	WHERE
+		user_id = :user_id
+		AND last_used_step < :last_used_step`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return mfa.ErrInvalidCode
+	}
+
+	return nil
+}
+
+// AddFailedAttempt counts an attempt at a code against the user. The count is
+// incremented in the database so that concurrent attempts are all counted.
+func (s *Store) AddFailedAttempt(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	UPDATE
+		mfa_secrets
+	SET
+		failed_attempts = failed_attempts + 1
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes mfa settings from the database.
+func (s *Store) Delete(ctx context.Context, m mfa.MFA) error {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: m.UserID,
+	}
+
+	const q = `
+	DELETE FROM
+		mfa_secrets
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByUserID gets the mfa settings of the specified user from the database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) (mfa.MFA, error) {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `SELECT * FROM mfa_secrets WHERE user_id = :user_id`
+
+	var dbM dbMFA
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbM); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return mfa.MFA{}, mfa.ErrNotFound
+		}
+		return mfa.MFA{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreMFA(dbM), nil
+}
+
+// CreateRecoveryCodes inserts a batch of recovery codes into the database.
+func (s *Store) CreateRecoveryCodes(ctx context.Context, codes []mfa.RecoveryCode) error {
+	if len(codes) == 0 {
+		return nil
+	}
+
+	dbRcs := make([]dbRecoveryCode, len(codes))
+	for i, rc := range codes {
+		dbRcs[i] = toDBRecoveryCode(rc)
+	}
+
+	const q = `
+	INSERT INTO mfa_recovery_codes
+		(id, user_id, hashed_code, used, created_at)
+	VALUES
+		(:id, :user_id, :hashed_code, :used, :created_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbRcs); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateRecoveryCode marks a recovery code as used. A code used already is
+// reported as mfa.ErrInvalidCode, so it can't be used twice at once.
+func (s *Store) UpdateRecoveryCode(ctx context.Context, code mfa.RecoveryCode) error {
+	const q = `
+	UPDATE
+		mfa_recovery_codes
+	SET
+		used = :used
+	WHERE
+		id = :id
+		AND NOT used`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, toDBRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return mfa.ErrInvalidCode
+	}
+
+	return nil
+}
+
+// DeleteRecoveryCodes removes all recovery codes of the specified user.
+func (s *Store) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	DELETE FROM
+		mfa_recovery_codes
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryRecoveryCode gets the specified recovery code of a user from the
+// database by its hash.
+func (s *Store) QueryRecoveryCode(ctx context.Context, userID uuid.UUID, hashedCode string) (mfa.RecoveryCode, error) {
+	data := struct {
+		UserID     uuid.UUID `db:"user_id"`
+		HashedCode string    `db:"hashed_code"`
+	}{
+		UserID:     userID,
+		HashedCode: hashedCode,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		mfa_recovery_codes
+	WHERE
+		user_id = :user_id
+		AND hashed_code = :hashed_code`
+
+	var dbRc dbRecoveryCode
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbRc); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return mfa.RecoveryCode{}, mfa.ErrNotFound
+		}
+		return mfa.RecoveryCode{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreRecoveryCode(dbRc), nil
+}
@@ -0,0 +1,190 @@
+package notification
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+)
+
+// Set of channels a notification can be delivered on.
+var (
+	ChannelEmail   = Channel{"email"}
+	ChannelWebhook = Channel{"webhook"}
+	ChannelInApp   = Channel{"inapp"}
+	ChannelSMS     = Channel{"sms"}
+)
+
+// phoneNumber matches phone numbers in the E.164 format.
+var phoneNumber = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Set of known channels.
+var channels = map[string]Channel{
+	ChannelEmail.name:   ChannelEmail,
+	ChannelWebhook.name: ChannelWebhook,
+	ChannelInApp.name:   ChannelInApp,
+	ChannelSMS.name:     ChannelSMS,
+}
+
+// Channel represents a way of delivering notifications to a user.
+type Channel struct {
+	name string
+}
+
+// ParseChannel parses the string value and returns a channel if one exists.
+func ParseChannel(value string) (Channel, error) {
+	channel, exists := channels[value]
+	if !exists {
+		return Channel{}, fmt.Errorf("%w %q", ErrInvalidChannel, value)
+	}
+
+	return channel, nil
+}
+
+// MustParseChannel parses the string value and returns a channel if one
+// exists. If an error occurs the function panics.
+func MustParseChannel(value string) Channel {
+	channel, err := ParseChannel(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return channel
+}
+
+// Name returns the name of the channel.
+func (c Channel) Name() string {
+	return c.name
+}
+
+// NeedsAddress reports whether deliveries on the channel need an address
+// given, as the user has none on record for it.
+func (c Channel) NeedsAddress() bool {
+	return c == ChannelWebhook || c == ChannelSMS
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (c *Channel) UnmarshalText(data []byte) error {
+	channel, err := ParseChannel(string(data))
+	if err != nil {
+		return err
+	}
+
+	*c = channel
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (c Channel) MarshalText() ([]byte, error) {
+	return []byte(c.name), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (c Channel) Equal(c2 Channel) bool {
+	return c.name == c2.name
+}
+
+// =============================================================================
+
+// Route is a channel along with the address to deliver on it. An empty
+// address means the one the user has on record for the channel. Email
+// addresses and phone numbers given instead may belong to someone else, so
+// they are only delivered to once their owner confirmed them.
+type Route struct {
+	Channel   Channel
+	Address   string
+	Confirmed bool
+}
+
+// NeedsConfirmation reports whether the route delivers to an address that
+// has to be confirmed by its owner first.
+func (r Route) NeedsConfirmation() bool {
+	return r.Address != "" && (r.Channel == ChannelEmail || r.Channel == ChannelSMS)
+}
+
+// Deliverable returns the route notifications actually go out on. Until its
+// address is confirmed, the one the user has on record is used instead, and
+// routes on channels the user has no address for are dropped.
+func (r Route) Deliverable() (Route, bool) {
+	if r.Pending() {
+		if r.Channel.NeedsAddress() {
+			return Route{}, false
+		}
+		return Route{Channel: r.Channel}, true
+	}
+
+	return Route{Channel: r.Channel, Address: r.Address}, true
+}
+
+// Pending reports whether the route delivers to an address that wasn't
+// confirmed yet.
+func (r Route) Pending() bool {
+	return r.NeedsConfirmation() && !r.Confirmed
+}
+
+// KeepConfirmed returns the routes with the addresses that were confirmed
+// among the previous routes still confirmed, and every other one not.
+func KeepConfirmed(routes []Route, previous []Route) []Route {
+	kept := make([]Route, len(routes))
+	for i, r := range routes {
+		r.Confirmed = r.NeedsConfirmation() && slices.ContainsFunc(previous, func(p Route) bool {
+			return p.Confirmed && p.Channel == r.Channel && p.Address == r.Address
+		})
+		kept[i] = r
+	}
+
+	return kept
+}
+
+// ConfirmAddress marks the routes delivering to the address on the channel as
+// confirmed, and reports whether there were any.
+func ConfirmAddress(routes []Route, channel Channel, address string) bool {
+	var found bool
+	for i, r := range routes {
+		if r.NeedsConfirmation() && r.Channel == channel && r.Address == address {
+			routes[i].Confirmed = true
+			found = true
+		}
+	}
+
+	return found
+}
+
+// Validate makes sure the route has an address when its channel needs one,
+// and that the address fits the channel.
+func (r Route) Validate() error {
+	if r.Channel == (Channel{}) {
+		return fmt.Errorf("%w %q", ErrInvalidChannel, "")
+	}
+
+	if r.Address == "" {
+		if r.Channel.NeedsAddress() {
+			return fmt.Errorf("%w: %s", ErrMissingAddress, r.Channel.name)
+		}
+		return nil
+	}
+
+	switch r.Channel {
+	case ChannelEmail:
+		if _, err := mail.ParseAddress(r.Address); err != nil {
+			return fmt.Errorf("%w %q", ErrInvalidAddress, r.Address)
+		}
+
+	case ChannelWebhook:
+		u, err := url.Parse(r.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w %q", ErrInvalidAddress, r.Address)
+		}
+
+	case ChannelSMS:
+		if !phoneNumber.MatchString(r.Address) {
+			return fmt.Errorf("%w %q", ErrInvalidAddress, r.Address)
+		}
+
+	case ChannelInApp:
+		return fmt.Errorf("%w %q", ErrInvalidAddress, r.Address)
+	}
+
+	return nil
+}
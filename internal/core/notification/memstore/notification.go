@@ -0,0 +1,516 @@
+// Package notificationmem contains notification related CRUD functionality,
+// kept in memory.
+package notificationmem
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/platform/cursor"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table           = "notifications"
+	tableInbox      = "inbox"
+	tableDeliveries = "notification_deliveries"
+	tableSkips      = "notification_skips"
+	tableTopics     = "topics"
+	tableAccess     = "topic_acls"
+)
+
+// orderByFields maps the fields notifications can be ordered by to their
+// comparisons.
+var orderByFields = map[string]memdb.Compare[notification.Notification]{
+	notification.OrderByID: compareID,
+	notification.OrderByCreatedAt: func(a, b notification.Notification) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+}
+
+// entry is a notification in the inbox of a user.
+type entry struct {
+	NotificationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+// Store manages the set of APIs for notification access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Reference("notifications_topic_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: tableTopics,
+		Refers: func(row any, key string) bool { return row.(notification.Notification).TopicID.String() == key },
+	})
+
+	db.Reference("inbox_notification_id_fkey", memdb.Reference{
+		Table:  tableInbox,
+		Parent: table,
+		Refers: func(row any, key string) bool { return row.(entry).NotificationID.String() == key },
+	})
+
+	db.Reference("notification_deliveries_notification_id_fkey", memdb.Reference{
+		Table:  tableDeliveries,
+		Parent: table,
+		Refers: func(row any, key string) bool { return row.(notification.Delivery).NotificationID.String() == key },
+	})
+
+	db.Reference("notification_skips_notification_id_fkey", memdb.Reference{
+		Table:  tableSkips,
+		Parent: table,
+		Refers: func(row any, key string) bool { return row.(notification.Skip).NotificationID.String() == key },
+	})
+
+	db.Reference("inbox_user_id_fkey", memdb.Reference{
+		Table:  tableInbox,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(entry).UserID.String() == key },
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(notification.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new notification into the database.
+func (s *Store) Create(ctx context.Context, ntf notification.Notification) error {
+	return memdb.Insert(s.db, table, ntf.ID.String(), clone(ntf))
+}
+
+// Delete marks a notification as deleted in the database.
+func (s *Store) Delete(ctx context.Context, ntf notification.Notification) error {
+	stored, exists := memdb.Get[notification.Notification](s.db, table, ntf.ID.String())
+	if !exists || !stored.DeletedAt.IsZero() {
+		return nil
+	}
+
+	stored.DeletedAt = ntf.DeletedAt
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// Restore clears the deletion of a notification in the database.
+func (s *Store) Restore(ctx context.Context, ntf notification.Notification) error {
+	stored, exists := memdb.Get[notification.Notification](s.db, table, ntf.ID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.DeletedAt = time.Time{}
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// Purge removes up to limit notifications deleted before the given time from
+// the database.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	ntfs := memdb.Rows(s.db, table, func(ntf notification.Notification) bool {
+		return !ntf.DeletedAt.IsZero() && ntf.DeletedAt.Before(before)
+	})
+
+	if len(ntfs) > limit {
+		ntfs = ntfs[:limit]
+	}
+
+	for _, ntf := range ntfs {
+		memdb.Delete(s.db, table, ntf.ID.String())
+	}
+
+	return len(ntfs), nil
+}
+
+// PurgeOlder removes up to limit notifications created before the given time
+// from the database. With a topic it removes the notifications of that topic,
+// otherwise those of the topics without a retention of their own.
+func (s *Store) PurgeOlder(ctx context.Context, topicID uuid.UUID, before time.Time, limit int) (int, error) {
+	ntfs := memdb.Rows(s.db, table, func(ntf notification.Notification) bool {
+		if !ntf.CreatedAt.Before(before) {
+			return false
+		}
+
+		if topicID != uuid.Nil {
+			return ntf.TopicID == topicID
+		}
+
+		tpc, exists := memdb.Get[topic.Topic](s.db, tableTopics, ntf.TopicID.String())
+		return exists && tpc.Retention == 0
+	})
+
+	if len(ntfs) > limit {
+		ntfs = ntfs[:limit]
+	}
+
+	for _, ntf := range ntfs {
+		memdb.Delete(s.db, table, ntf.ID.String())
+	}
+
+	return len(ntfs), nil
+}
+
+// Query retrieves a list of notifications from the database.
+func (s *Store) Query(ctx context.Context, filter notification.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]notification.Notification, error) {
+	ntfs := memdb.Rows(s.db, table, matches(filter))
+
+	if err := memdb.Sort(ntfs, orderBy, orderByFields, compareID); err != nil {
+		return nil, err
+	}
+
+	return cloneSlice(memdb.Page(ntfs, pageNumber, rowsPerPage)), nil
+}
+
+// Count returns the number of notifications in the database matching the
+// filter.
+func (s *Store) Count(ctx context.Context, filter notification.QueryFilter) (int, error) {
+	return len(memdb.Rows(s.db, table, matches(filter))), nil
+}
+
+// QueryByCursor retrieves the page of notifications read with the cursor from
+// the database, in the direction of the cursor.
+func (s *Store) QueryByCursor(ctx context.Context, filter notification.QueryFilter, cur cursor.Cursor, rowsPerPage int) ([]notification.Notification, error) {
+	ntfs := memdb.Rows(s.db, table, matches(filter))
+
+	return cloneSlice(memdb.Keyset(ntfs, cur, rowsPerPage, mark)), nil
+}
+
+// QueryByID gets the specified notification from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (notification.Notification, error) {
+	ntf, exists := memdb.Get[notification.Notification](s.db, table, id.String())
+	if !exists || !ntf.DeletedAt.IsZero() {
+		return notification.Notification{}, notification.ErrNotFound
+	}
+
+	return clone(ntf), nil
+}
+
+// QueryDeletedByID gets the specified deleted notification from the database
+// by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (notification.Notification, error) {
+	ntf, exists := memdb.Get[notification.Notification](s.db, table, id.String())
+	if !exists || ntf.DeletedAt.IsZero() {
+		return notification.Notification{}, notification.ErrNotFound
+	}
+
+	return clone(ntf), nil
+}
+
+// Search retrieves a list of the notifications matching the search from the
+// database, the newest first, along with their highlighted snippets.
+func (s *Store) Search(ctx context.Context, filter notification.SearchFilter, pageNumber int, rowsPerPage int) ([]notification.SearchResult, error) {
+	ntfs := memdb.Rows(s.db, table, s.searchMatches(filter))
+
+	if err := memdb.Sort(ntfs, order.NewBy(notification.OrderByCreatedAt, order.DESC), orderByFields, compareID); err != nil {
+		return nil, err
+	}
+
+	terms := notification.SearchTerms(filter.Text)
+
+	ntfs = cloneSlice(memdb.Page(ntfs, pageNumber, rowsPerPage))
+
+	rs := make([]notification.SearchResult, len(ntfs))
+	for i, ntf := range ntfs {
+		rs[i] = notification.NewSearchResult(ntf, terms)
+	}
+
+	return rs, nil
+}
+
+// CountSearch returns the number of notifications in the database matching
+// the search.
+func (s *Store) CountSearch(ctx context.Context, filter notification.SearchFilter) (int, error) {
+	return len(memdb.Rows(s.db, table, s.searchMatches(filter))), nil
+}
+
+// AddToInbox inserts the notification into the inbox of the users in the
+// database, skipping the inboxes already holding it.
+func (s *Store) AddToInbox(ctx context.Context, ntf notification.Notification, userIDs []uuid.UUID) error {
+	for _, userID := range userIDs {
+		e := entry{
+			NotificationID: ntf.ID,
+			UserID:         userID,
+		}
+
+		if err := memdb.Put(s.db, tableInbox, entryKey(e), e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryInbox retrieves a list of the notifications in the inbox of the user
+// from the database, leaving out the deleted ones and those of deleted topics.
+func (s *Store) QueryInbox(ctx context.Context, pageNumber int, rowsPerPage int, userID uuid.UUID) ([]notification.Notification, error) {
+	ntfs := s.inbox(userID)
+
+	slices.SortFunc(ntfs, func(a, b notification.Notification) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return compareID(b, a)
+	})
+
+	return cloneSlice(memdb.Page(ntfs, pageNumber, rowsPerPage)), nil
+}
+
+// CountInbox returns the number of notifications in the inbox of the user in
+// the database, leaving out the deleted ones and those of deleted topics.
+func (s *Store) CountInbox(ctx context.Context, userID uuid.UUID) (int, error) {
+	return len(s.inbox(userID)), nil
+}
+
+// QueryInboxByCursor retrieves the page of the user's inbox read with the
+// cursor from the database, in the direction of the cursor.
+func (s *Store) QueryInboxByCursor(ctx context.Context, userID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]notification.Notification, error) {
+	return cloneSlice(memdb.Keyset(s.inbox(userID), cur, rowsPerPage, mark)), nil
+}
+
+// AddDelivery inserts the delivery of a notification into the database,
+// unless it is there already.
+func (s *Store) AddDelivery(ctx context.Context, dlv notification.Delivery) error {
+	key := deliveryKey(dlv)
+	if _, exists := memdb.Get[notification.Delivery](s.db, tableDeliveries, key); exists {
+		return nil
+	}
+
+	return memdb.Put(s.db, tableDeliveries, key, dlv)
+}
+
+// QueryDeliveries retrieves the deliveries of the notification from the
+// database.
+func (s *Store) QueryDeliveries(ctx context.Context, notificationID uuid.UUID) ([]notification.Delivery, error) {
+	dlvs := memdb.Rows(s.db, tableDeliveries, func(dlv notification.Delivery) bool {
+		return dlv.NotificationID == notificationID
+	})
+
+	return dlvs, nil
+}
+
+// AddSkip inserts the skipped delivery of a notification into the database,
+// unless it is there already.
+func (s *Store) AddSkip(ctx context.Context, skp notification.Skip) error {
+	key := skipKey(skp)
+	if _, exists := memdb.Get[notification.Skip](s.db, tableSkips, key); exists {
+		return nil
+	}
+
+	return memdb.Put(s.db, tableSkips, key, skp)
+}
+
+// QuerySkips retrieves the skipped deliveries of the notification from the
+// database.
+func (s *Store) QuerySkips(ctx context.Context, notificationID uuid.UUID) ([]notification.Skip, error) {
+	skps := memdb.Rows(s.db, tableSkips, func(skp notification.Skip) bool {
+		return skp.NotificationID == notificationID
+	})
+
+	return skps, nil
+}
+
+// QueryLogByCursor retrieves the page of the log of the notification's
+// deliveries, made and skipped, read with the cursor from the database, in the
+// direction of the cursor.
+func (s *Store) QueryLogByCursor(ctx context.Context, notificationID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]notification.LogEntry, error) {
+	var entries []notification.LogEntry
+
+	for _, dlv := range memdb.Rows(s.db, tableDeliveries, func(dlv notification.Delivery) bool {
+		return dlv.NotificationID == notificationID
+	}) {
+		entries = append(entries, notification.LogEntry{
+			ID:             dlv.ID,
+			NotificationID: dlv.NotificationID,
+			Channel:        dlv.Channel,
+			Address:        dlv.Address,
+			CreatedAt:      dlv.CreatedAt,
+		})
+	}
+
+	for _, skp := range memdb.Rows(s.db, tableSkips, func(skp notification.Skip) bool {
+		return skp.NotificationID == notificationID
+	}) {
+		entries = append(entries, notification.LogEntry{
+			ID:             skp.ID,
+			NotificationID: skp.NotificationID,
+			Skipped:        true,
+			SubscriptionID: skp.SubscriptionID,
+			Reason:         skp.Reason,
+			CreatedAt:      skp.CreatedAt,
+		})
+	}
+
+	return memdb.Keyset(entries, cur, rowsPerPage, markLogEntry), nil
+}
+
+// =============================================================================
+
+// inbox returns the notifications in the inbox of the user, in no particular
+// order. Deleted notifications and those of deleted topics are left out.
+func (s *Store) inbox(userID uuid.UUID) []notification.Notification {
+	entries := memdb.Rows(s.db, tableInbox, func(e entry) bool {
+		return e.UserID == userID
+	})
+
+	ntfs := make([]notification.Notification, 0, len(entries))
+	for _, e := range entries {
+		ntf, exists := memdb.Get[notification.Notification](s.db, table, e.NotificationID.String())
+		if !exists || !ntf.DeletedAt.IsZero() {
+			continue
+		}
+
+		if tpc, exists := memdb.Get[topic.Topic](s.db, tableTopics, ntf.TopicID.String()); !exists || !tpc.DeletedAt.IsZero() {
+			continue
+		}
+
+		ntfs = append(ntfs, ntf)
+	}
+
+	return ntfs
+}
+
+// matches returns whether a notification matches the filter. Deleted
+// notifications only match when the filter asks for them.
+func matches(filter notification.QueryFilter) func(ntf notification.Notification) bool {
+	return func(ntf notification.Notification) bool {
+		if ntf.DeletedAt.IsZero() == filter.Deleted {
+			return false
+		}
+
+		if filter.TopicID != nil && ntf.TopicID != *filter.TopicID {
+			return false
+		}
+
+		if filter.Priority != nil && !ntf.Priority.Equal(*filter.Priority) {
+			return false
+		}
+
+		if filter.Tag != nil && !slices.Contains(ntf.Tags, strings.ToLower(strings.TrimSpace(*filter.Tag))) {
+			return false
+		}
+
+		if filter.StartCreatedDate != nil && ntf.CreatedAt.Before(*filter.StartCreatedDate) {
+			return false
+		}
+
+		if filter.EndCreatedDate != nil && ntf.CreatedAt.After(*filter.EndCreatedDate) {
+			return false
+		}
+
+		return true
+	}
+}
+
+// searchMatches returns whether a notification matches the search. Its title
+// or message has to contain every word of the text, and its topic must be
+// live and readable by the user searching, when one is given.
+func (s *Store) searchMatches(filter notification.SearchFilter) func(ntf notification.Notification) bool {
+	match := matches(notification.QueryFilter{
+		TopicID:          filter.TopicID,
+		Priority:         filter.Priority,
+		StartCreatedDate: filter.StartCreatedDate,
+		EndCreatedDate:   filter.EndCreatedDate,
+	})
+	terms := notification.SearchTerms(filter.Text)
+
+	return func(ntf notification.Notification) bool {
+		if !match(ntf) {
+			return false
+		}
+
+		if len(filter.TopicIDs) > 0 && !slices.Contains(filter.TopicIDs, ntf.TopicID) {
+			return false
+		}
+
+		text := strings.ToLower(ntf.Title + " " + ntf.Message)
+		for _, term := range terms {
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+
+		tpc, exists := memdb.Get[topic.Topic](s.db, tableTopics, ntf.TopicID.String())
+		if !exists || !tpc.DeletedAt.IsZero() {
+			return false
+		}
+
+		return filter.ReadableBy == nil || s.readable(tpc, *filter.ReadableBy)
+	}
+}
+
+// readable returns whether the user may read the notifications of the topic.
+func (s *Store) readable(tpc topic.Topic, userID uuid.UUID) bool {
+	if tpc.Visibility.Equal(topic.VisibilityPublic) || tpc.OwnerID == userID {
+		return true
+	}
+
+	a, exists := memdb.Get[topic.Access](s.db, tableAccess, tpc.ID.String()+"/"+userID.String())
+	return exists && a.Has(topic.RightSubscribe)
+}
+
+func mark(ntf notification.Notification) (time.Time, uuid.UUID) {
+	return ntf.CreatedAt, ntf.ID
+}
+
+func markLogEntry(entry notification.LogEntry) (time.Time, uuid.UUID) {
+	return entry.CreatedAt, entry.ID
+}
+
+func compareID(a, b notification.Notification) int {
+	return memdb.CompareIDs(a.ID, b.ID)
+}
+
+func entryKey(e entry) string {
+	return e.UserID.String() + "/" + e.NotificationID.String()
+}
+
+func deliveryKey(dlv notification.Delivery) string {
+	return dlv.NotificationID.String() + "/" + dlv.Channel.Name() + "/" + dlv.Address
+}
+
+func skipKey(skp notification.Skip) string {
+	return skp.NotificationID.String() + "/" + skp.SubscriptionID.String()
+}
+
+// clone copies the notification, so the stored notification shares no memory
+// with the callers.
+func clone(ntf notification.Notification) notification.Notification {
+	ntf.Tags = slices.Clone(ntf.Tags)
+	return ntf
+}
+
+func cloneSlice(ntfs []notification.Notification) []notification.Notification {
+	for i := range ntfs {
+		ntfs[i] = clone(ntfs[i])
+	}
+	return ntfs
+}
@@ -5,9 +5,13 @@
 package mockntf
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	notification "github.com/dudakovict/gotify/internal/core/notification"
+	cursor "github.com/dudakovict/gotify/platform/cursor"
+	order "github.com/dudakovict/gotify/platform/order"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 )
@@ -35,74 +39,325 @@ func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
 	return m.recorder
 }
 
+// AddDelivery mocks base method.
+func (m *MockStorer) AddDelivery(arg0 context.Context, arg1 notification.Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDelivery", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDelivery indicates an expected call of AddDelivery.
+func (mr *MockStorerMockRecorder) AddDelivery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDelivery", reflect.TypeOf((*MockStorer)(nil).AddDelivery), arg0, arg1)
+}
+
+// AddSkip mocks base method.
+func (m *MockStorer) AddSkip(arg0 context.Context, arg1 notification.Skip) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddSkip", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddSkip indicates an expected call of AddSkip.
+func (mr *MockStorerMockRecorder) AddSkip(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSkip", reflect.TypeOf((*MockStorer)(nil).AddSkip), arg0, arg1)
+}
+
+// AddToInbox mocks base method.
+func (m *MockStorer) AddToInbox(arg0 context.Context, arg1 notification.Notification, arg2 []uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddToInbox", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddToInbox indicates an expected call of AddToInbox.
+func (mr *MockStorerMockRecorder) AddToInbox(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToInbox", reflect.TypeOf((*MockStorer)(nil).AddToInbox), arg0, arg1, arg2)
+}
+
+// Count mocks base method.
+func (m *MockStorer) Count(arg0 context.Context, arg1 notification.QueryFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockStorerMockRecorder) Count(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockStorer)(nil).Count), arg0, arg1)
+}
+
+// CountInbox mocks base method.
+func (m *MockStorer) CountInbox(arg0 context.Context, arg1 uuid.UUID) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountInbox", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountInbox indicates an expected call of CountInbox.
+func (mr *MockStorerMockRecorder) CountInbox(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountInbox", reflect.TypeOf((*MockStorer)(nil).CountInbox), arg0, arg1)
+}
+
+// CountSearch mocks base method.
+func (m *MockStorer) CountSearch(arg0 context.Context, arg1 notification.SearchFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountSearch", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountSearch indicates an expected call of CountSearch.
+func (mr *MockStorerMockRecorder) CountSearch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountSearch", reflect.TypeOf((*MockStorer)(nil).CountSearch), arg0, arg1)
+}
+
 // Create mocks base method.
-func (m *MockStorer) Create(arg0 notification.Notification) error {
+func (m *MockStorer) Create(arg0 context.Context, arg1 notification.Notification) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockStorerMockRecorder) Create(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
 }
 
 // Delete mocks base method.
-func (m *MockStorer) Delete(arg0 notification.Notification) error {
+func (m *MockStorer) Delete(arg0 context.Context, arg1 notification.Notification) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockStorerMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// Purge mocks base method.
+func (m *MockStorer) Purge(arg0 context.Context, arg1 time.Time, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorerMockRecorder) Purge(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorer)(nil).Purge), arg0, arg1, arg2)
+}
+
+// PurgeOlder mocks base method.
+func (m *MockStorer) PurgeOlder(arg0 context.Context, arg1 uuid.UUID, arg2 time.Time, arg3 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeOlder", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeOlder indicates an expected call of PurgeOlder.
+func (mr *MockStorerMockRecorder) PurgeOlder(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeOlder", reflect.TypeOf((*MockStorer)(nil).PurgeOlder), arg0, arg1, arg2, arg3)
 }
 
 // Query mocks base method.
-func (m *MockStorer) Query(arg0, arg1 int, arg2 uuid.UUID) ([]notification.Notification, error) {
+func (m *MockStorer) Query(arg0 context.Context, arg1 notification.QueryFilter, arg2 order.By, arg3, arg4 int) ([]notification.Notification, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Query", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "Query", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].([]notification.Notification)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Query indicates an expected call of Query.
-func (mr *MockStorerMockRecorder) Query(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Query(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1, arg2, arg3, arg4)
+}
+
+// QueryByCursor mocks base method.
+func (m *MockStorer) QueryByCursor(arg0 context.Context, arg1 notification.QueryFilter, arg2 cursor.Cursor, arg3 int) ([]notification.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByCursor", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]notification.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByCursor indicates an expected call of QueryByCursor.
+func (mr *MockStorerMockRecorder) QueryByCursor(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByCursor", reflect.TypeOf((*MockStorer)(nil).QueryByCursor), arg0, arg1, arg2, arg3)
 }
 
 // QueryByID mocks base method.
-func (m *MockStorer) QueryByID(arg0 uuid.UUID) (notification.Notification, error) {
+func (m *MockStorer) QueryByID(arg0 context.Context, arg1 uuid.UUID) (notification.Notification, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryByID", arg0)
+	ret := m.ctrl.Call(m, "QueryByID", arg0, arg1)
 	ret0, _ := ret[0].(notification.Notification)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryByID indicates an expected call of QueryByID.
-func (mr *MockStorerMockRecorder) QueryByID(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) QueryByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0, arg1)
+}
+
+// QueryDeletedByID mocks base method.
+func (m *MockStorer) QueryDeletedByID(arg0 context.Context, arg1 uuid.UUID) (notification.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryDeletedByID", arg0, arg1)
+	ret0, _ := ret[0].(notification.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryDeletedByID indicates an expected call of QueryDeletedByID.
+func (mr *MockStorerMockRecorder) QueryDeletedByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryDeletedByID", reflect.TypeOf((*MockStorer)(nil).QueryDeletedByID), arg0, arg1)
+}
+
+// QueryDeliveries mocks base method.
+func (m *MockStorer) QueryDeliveries(arg0 context.Context, arg1 uuid.UUID) ([]notification.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryDeliveries", arg0, arg1)
+	ret0, _ := ret[0].([]notification.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryDeliveries indicates an expected call of QueryDeliveries.
+func (mr *MockStorerMockRecorder) QueryDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryDeliveries", reflect.TypeOf((*MockStorer)(nil).QueryDeliveries), arg0, arg1)
+}
+
+// QueryInbox mocks base method.
+func (m *MockStorer) QueryInbox(arg0 context.Context, arg1, arg2 int, arg3 uuid.UUID) ([]notification.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryInbox", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]notification.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryInbox indicates an expected call of QueryInbox.
+func (mr *MockStorerMockRecorder) QueryInbox(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryInbox", reflect.TypeOf((*MockStorer)(nil).QueryInbox), arg0, arg1, arg2, arg3)
+}
+
+// QueryInboxByCursor mocks base method.
+func (m *MockStorer) QueryInboxByCursor(arg0 context.Context, arg1 uuid.UUID, arg2 cursor.Cursor, arg3 int) ([]notification.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryInboxByCursor", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]notification.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryInboxByCursor indicates an expected call of QueryInboxByCursor.
+func (mr *MockStorerMockRecorder) QueryInboxByCursor(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryInboxByCursor", reflect.TypeOf((*MockStorer)(nil).QueryInboxByCursor), arg0, arg1, arg2, arg3)
+}
+
+// QueryLogByCursor mocks base method.
+func (m *MockStorer) QueryLogByCursor(arg0 context.Context, arg1 uuid.UUID, arg2 cursor.Cursor, arg3 int) ([]notification.LogEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryLogByCursor", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]notification.LogEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryLogByCursor indicates an expected call of QueryLogByCursor.
+func (mr *MockStorerMockRecorder) QueryLogByCursor(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryLogByCursor", reflect.TypeOf((*MockStorer)(nil).QueryLogByCursor), arg0, arg1, arg2, arg3)
+}
+
+// QuerySkips mocks base method.
+func (m *MockStorer) QuerySkips(arg0 context.Context, arg1 uuid.UUID) ([]notification.Skip, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuerySkips", arg0, arg1)
+	ret0, _ := ret[0].([]notification.Skip)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QuerySkips indicates an expected call of QuerySkips.
+func (mr *MockStorerMockRecorder) QuerySkips(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuerySkips", reflect.TypeOf((*MockStorer)(nil).QuerySkips), arg0, arg1)
+}
+
+// Restore mocks base method.
+func (m *MockStorer) Restore(arg0 context.Context, arg1 notification.Notification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorerMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorer)(nil).Restore), arg0, arg1)
+}
+
+// Search mocks base method.
+func (m *MockStorer) Search(arg0 context.Context, arg1 notification.SearchFilter, arg2, arg3 int) ([]notification.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]notification.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockStorerMockRecorder) Search(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockStorer)(nil).Search), arg0, arg1, arg2, arg3)
 }
 
 // WithinTran mocks base method.
-func (m *MockStorer) WithinTran(arg0 func(notification.Storer) error) error {
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(notification.Storer) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WithinTran", arg0)
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // WithinTran indicates an expected call of WithinTran.
-func (mr *MockStorerMockRecorder) WithinTran(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
 }
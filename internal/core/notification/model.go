@@ -10,14 +10,22 @@ import (
 type Notification struct {
 	ID        uuid.UUID
 	TopicID   uuid.UUID
+	Title     string
 	Message   string
+	Priority  Priority
+	Tags      []string
 	CreatedAt time.Time
+	DeletedAt time.Time
 }
 
 // NewNotification contains information needed to create a new notification.
+// Notifications have the normal priority unless another one is given.
 type NewNotification struct {
-	TopicID uuid.UUID
-	Message string
+	TopicID  uuid.UUID
+	Title    string
+	Message  string
+	Priority Priority
+	Tags     []string
 }
 
 // NewNotificationTx contains information needed to create a new notification
@@ -26,3 +34,42 @@ type NewNotificationTx struct {
 	NewNotification
 	AfterCreate func(ntf Notification) error
 }
+
+// Delivery records that a notification was delivered on a channel to an
+// address, so that retrying the delivery doesn't send it there again.
+type Delivery struct {
+	ID             uuid.UUID
+	NotificationID uuid.UUID
+	Channel        Channel
+	Address        string
+	CreatedAt      time.Time
+}
+
+// SkipMuted is the reason of the deliveries skipped as the subscription they
+// were for was muted.
+const SkipMuted = "muted"
+
+// Skip records that a notification wasn't delivered through a subscription,
+// along with the reason, so that what wasn't sent can be audited like what
+// was.
+type Skip struct {
+	ID             uuid.UUID
+	NotificationID uuid.UUID
+	SubscriptionID uuid.UUID
+	Reason         string
+	CreatedAt      time.Time
+}
+
+// LogEntry is a delivery of a notification in the log of its deliveries,
+// either made, with the channel and the address it went to, or skipped, with
+// the subscription it was for and the reason.
+type LogEntry struct {
+	ID             uuid.UUID
+	NotificationID uuid.UUID
+	Skipped        bool
+	Channel        Channel
+	Address        string
+	SubscriptionID uuid.UUID
+	Reason         string
+	CreatedAt      time.Time
+}
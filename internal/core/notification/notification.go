@@ -2,26 +2,52 @@
 package notification
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/dudakovict/gotify/platform/cursor"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound = errors.New("notification not found")
+	ErrNotFound        = errors.New("notification not found")
+	ErrInvalidPriority = errors.New("invalid priority")
+	ErrInvalidChannel  = errors.New("invalid channel")
+	ErrMissingAddress  = errors.New("channel needs an address")
+	ErrInvalidAddress  = errors.New("invalid address")
+	ErrEmptySearch     = errors.New("search needs some text")
 )
 
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
-	WithinTran(fn func(s Storer) error) error
-	Create(ntf Notification) error
-	Delete(ntf Notification) error
-	Query(pageNumber int, rowsPerPage int, topicID uuid.UUID) ([]Notification, error)
-	QueryByID(id uuid.UUID) (Notification, error)
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, ntf Notification) error
+	Delete(ctx context.Context, ntf Notification) error
+	Restore(ctx context.Context, ntf Notification) error
+	Purge(ctx context.Context, before time.Time, limit int) (int, error)
+	PurgeOlder(ctx context.Context, topicID uuid.UUID, before time.Time, limit int) (int, error)
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Notification, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByCursor(ctx context.Context, filter QueryFilter, cur cursor.Cursor, rowsPerPage int) ([]Notification, error)
+	QueryByID(ctx context.Context, id uuid.UUID) (Notification, error)
+	QueryDeletedByID(ctx context.Context, id uuid.UUID) (Notification, error)
+	Search(ctx context.Context, filter SearchFilter, pageNumber int, rowsPerPage int) ([]SearchResult, error)
+	CountSearch(ctx context.Context, filter SearchFilter) (int, error)
+	AddToInbox(ctx context.Context, ntf Notification, userIDs []uuid.UUID) error
+	QueryInbox(ctx context.Context, pageNumber int, rowsPerPage int, userID uuid.UUID) ([]Notification, error)
+	CountInbox(ctx context.Context, userID uuid.UUID) (int, error)
+	QueryInboxByCursor(ctx context.Context, userID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]Notification, error)
+	AddDelivery(ctx context.Context, dlv Delivery) error
+	QueryDeliveries(ctx context.Context, notificationID uuid.UUID) ([]Delivery, error)
+	AddSkip(ctx context.Context, skp Skip) error
+	QuerySkips(ctx context.Context, notificationID uuid.UUID) ([]Skip, error)
+	QueryLogByCursor(ctx context.Context, notificationID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]LogEntry, error)
 }
 
 // Core manages the set of APIs for notification access.
@@ -38,22 +64,18 @@ func NewCore(log *zerolog.Logger, storer Storer) *Core {
 	}
 }
 
-func (c *Core) CreateTx(nnTx NewNotificationTx) (Notification, error) {
-	ntf := Notification{
-		ID:      uuid.New(),
-		TopicID: nnTx.TopicID,
-		Message: nnTx.Message,
-	}
+func (c *Core) CreateTx(ctx context.Context, nnTx NewNotificationTx) (Notification, error) {
+	ntf := newNotification(nnTx.NewNotification)
 
 	tran := func(s Storer) error {
-		if err := s.Create(ntf); err != nil {
+		if err := s.Create(ctx, ntf); err != nil {
 			return fmt.Errorf("create: %w", err)
 		}
 
 		return nnTx.AfterCreate(ntf)
 	}
 
-	if err := c.storer.WithinTran(tran); err != nil {
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
 		return Notification{}, fmt.Errorf("tran: %w", err)
 	}
 
@@ -61,32 +83,65 @@ func (c *Core) CreateTx(nnTx NewNotificationTx) (Notification, error) {
 }
 
 // Create adds a new notification to the system.
-func (c *Core) Create(nn NewNotification) (Notification, error) {
-	ntf := Notification{
-		ID:      uuid.New(),
-		TopicID: nn.TopicID,
-		Message: nn.Message,
-	}
+func (c *Core) Create(ctx context.Context, nn NewNotification) (Notification, error) {
+	ntf := newNotification(nn)
 
-	if err := c.storer.Create(ntf); err != nil {
+	if err := c.storer.Create(ctx, ntf); err != nil {
 		return Notification{}, fmt.Errorf("create: %w", err)
 	}
 
 	return ntf, nil
 }
 
-// Delete removes the specified notification.
-func (c *Core) Delete(ntf Notification) error {
-	if err := c.storer.Delete(ntf); err != nil {
+// Delete marks the specified notification as deleted. Deleted notifications
+// are left out of every query, inboxes included, until they are restored.
+func (c *Core) Delete(ctx context.Context, ntf Notification) error {
+	ntf.DeletedAt = time.Now()
+
+	if err := c.storer.Delete(ctx, ntf); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
 
 	return nil
 }
 
-// Query retrieves a list of notifications.
-func (c *Core) Query(pageNumber int, rowsPerPage int, topicID uuid.UUID) ([]Notification, error) {
-	ntfs, err := c.storer.Query(pageNumber, rowsPerPage, topicID)
+// Restore brings back a deleted notification.
+func (c *Core) Restore(ctx context.Context, ntf Notification) (Notification, error) {
+	ntf.DeletedAt = time.Time{}
+
+	if err := c.storer.Restore(ctx, ntf); err != nil {
+		return Notification{}, fmt.Errorf("restore: %w", err)
+	}
+
+	return ntf, nil
+}
+
+// Purge removes up to limit notifications deleted before the given time for
+// good and returns how many were removed.
+func (c *Core) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	n, err := c.storer.Purge(ctx, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purge: %w", err)
+	}
+
+	return n, nil
+}
+
+// PurgeOlder removes up to limit notifications created before the given time
+// and returns how many were removed. Without a topic, only the notifications
+// of topics without a retention of their own are removed.
+func (c *Core) PurgeOlder(ctx context.Context, topicID uuid.UUID, before time.Time, limit int) (int, error) {
+	n, err := c.storer.PurgeOlder(ctx, topicID, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purgeolder: %w", err)
+	}
+
+	return n, nil
+}
+
+// Query retrieves a list of existing notifications.
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Notification, error) {
+	ntfs, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -94,12 +149,218 @@ func (c *Core) Query(pageNumber int, rowsPerPage int, topicID uuid.UUID) ([]Noti
 	return ntfs, nil
 }
 
+// Count returns the number of notifications matching the filter.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	count, err := c.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByCursor retrieves the page of notifications read with the cursor, the
+// newest first, along with the cursors of the pages next to it. Unlike pages
+// read by number, the pages don't shift as new notifications arrive.
+func (c *Core) QueryByCursor(ctx context.Context, filter QueryFilter, cur cursor.Cursor, rowsPerPage int) ([]Notification, cursor.Page, error) {
+	ntfs, err := c.storer.QueryByCursor(ctx, filter, cur, rowsPerPage)
+	if err != nil {
+		return nil, cursor.Page{}, fmt.Errorf("query: %w", err)
+	}
+
+	ntfs, page := cursor.Paginate(ntfs, cur, rowsPerPage, mark)
+	return ntfs, page, nil
+}
+
 // QueryByID finds the notification by the specified ID.
-func (c *Core) QueryByID(id uuid.UUID) (Notification, error) {
-	ntf, err := c.storer.QueryByID(id)
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (Notification, error) {
+	ntf, err := c.storer.QueryByID(ctx, id)
 	if err != nil {
 		return Notification{}, fmt.Errorf("query: %w", err)
 	}
 
 	return ntf, nil
 }
+
+// QueryDeletedByID finds the deleted notification by the specified ID.
+func (c *Core) QueryDeletedByID(ctx context.Context, id uuid.UUID) (Notification, error) {
+	ntf, err := c.storer.QueryDeletedByID(ctx, id)
+	if err != nil {
+		return Notification{}, fmt.Errorf("query: %w", err)
+	}
+
+	return ntf, nil
+}
+
+// Search finds the notifications whose title or message match the text of the
+// filter, the most relevant first, with the matched words highlighted.
+func (c *Core) Search(ctx context.Context, filter SearchFilter, pageNumber int, rowsPerPage int) ([]SearchResult, error) {
+	if len(SearchTerms(filter.Text)) == 0 {
+		return nil, ErrEmptySearch
+	}
+
+	rs, err := c.storer.Search(ctx, filter, pageNumber, rowsPerPage)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	return rs, nil
+}
+
+// CountSearch returns the number of notifications found by the search.
+func (c *Core) CountSearch(ctx context.Context, filter SearchFilter) (int, error) {
+	if len(SearchTerms(filter.Text)) == 0 {
+		return 0, ErrEmptySearch
+	}
+
+	count, err := c.storer.CountSearch(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("countsearch: %w", err)
+	}
+
+	return count, nil
+}
+
+// AddToInbox delivers the notification in-app to the specified users. Adding
+// it again to the same inbox has no effect.
+func (c *Core) AddToInbox(ctx context.Context, ntf Notification, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	if err := c.storer.AddToInbox(ctx, ntf, userIDs); err != nil {
+		return fmt.Errorf("addtoinbox: %w", err)
+	}
+
+	return nil
+}
+
+// QueryInbox retrieves the notifications delivered in-app to the user, the
+// newest first. Notifications of deleted topics are left out.
+func (c *Core) QueryInbox(ctx context.Context, pageNumber int, rowsPerPage int, userID uuid.UUID) ([]Notification, error) {
+	ntfs, err := c.storer.QueryInbox(ctx, pageNumber, rowsPerPage, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return ntfs, nil
+}
+
+// CountInbox returns the number of notifications delivered in-app to the
+// user. Notifications of deleted topics are left out.
+func (c *Core) CountInbox(ctx context.Context, userID uuid.UUID) (int, error) {
+	count, err := c.storer.CountInbox(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryInboxByCursor retrieves the page of the user's inbox read with the
+// cursor, the newest first, along with the cursors of the pages next to it.
+func (c *Core) QueryInboxByCursor(ctx context.Context, userID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]Notification, cursor.Page, error) {
+	ntfs, err := c.storer.QueryInboxByCursor(ctx, userID, cur, rowsPerPage)
+	if err != nil {
+		return nil, cursor.Page{}, fmt.Errorf("query: %w", err)
+	}
+
+	ntfs, page := cursor.Paginate(ntfs, cur, rowsPerPage, mark)
+	return ntfs, page, nil
+}
+
+// AddDelivery records that the notification was delivered on the channel to
+// the address. Recording the same delivery again has no effect.
+func (c *Core) AddDelivery(ctx context.Context, ntf Notification, channel Channel, address string) error {
+	dlv := Delivery{
+		ID:             uuid.New(),
+		NotificationID: ntf.ID,
+		Channel:        channel,
+		Address:        address,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := c.storer.AddDelivery(ctx, dlv); err != nil {
+		return fmt.Errorf("adddelivery: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDeliveries retrieves the deliveries of the notification made so far.
+func (c *Core) QueryDeliveries(ctx context.Context, notificationID uuid.UUID) ([]Delivery, error) {
+	dlvs, err := c.storer.QueryDeliveries(ctx, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return dlvs, nil
+}
+
+// AddSkip records that the notification wasn't delivered through the
+// subscription for the reason. Recording the same skip again has no effect.
+func (c *Core) AddSkip(ctx context.Context, ntf Notification, subscriptionID uuid.UUID, reason string) error {
+	skp := Skip{
+		ID:             uuid.New(),
+		NotificationID: ntf.ID,
+		SubscriptionID: subscriptionID,
+		Reason:         reason,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := c.storer.AddSkip(ctx, skp); err != nil {
+		return fmt.Errorf("addskip: %w", err)
+	}
+
+	return nil
+}
+
+// QuerySkips retrieves the deliveries of the notification skipped so far.
+func (c *Core) QuerySkips(ctx context.Context, notificationID uuid.UUID) ([]Skip, error) {
+	skps, err := c.storer.QuerySkips(ctx, notificationID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return skps, nil
+}
+
+// QueryLogByCursor retrieves the page of the log of the notification's
+// deliveries read with the cursor, the deliveries made and those skipped
+// together, the newest first, along with the cursors of the pages next to it.
+func (c *Core) QueryLogByCursor(ctx context.Context, notificationID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]LogEntry, cursor.Page, error) {
+	entries, err := c.storer.QueryLogByCursor(ctx, notificationID, cur, rowsPerPage)
+	if err != nil {
+		return nil, cursor.Page{}, fmt.Errorf("query: %w", err)
+	}
+
+	entries, page := cursor.Paginate(entries, cur, rowsPerPage, markLogEntry)
+	return entries, page, nil
+}
+
+// mark returns what cursors mark a notification by.
+func mark(ntf Notification) (time.Time, uuid.UUID) {
+	return ntf.CreatedAt, ntf.ID
+}
+
+// markLogEntry returns what cursors mark an entry of a delivery log by.
+func markLogEntry(entry LogEntry) (time.Time, uuid.UUID) {
+	return entry.CreatedAt, entry.ID
+}
+
+// newNotification fills in the defaults of a new notification.
+func newNotification(nn NewNotification) Notification {
+	priority := nn.Priority
+	if priority == (Priority{}) {
+		priority = PriorityNormal
+	}
+
+	return Notification{
+		ID:       uuid.New(),
+		TopicID:  nn.TopicID,
+		Title:    nn.Title,
+		Message:  nn.Message,
+		Priority: priority,
+		Tags:     NormalizeTags(nn.Tags),
+	}
+}
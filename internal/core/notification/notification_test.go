@@ -0,0 +1,44 @@
+package notification_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	mockntf "github.com/dudakovict/gotify/internal/core/notification/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchTerms(t *testing.T) {
+	require.Equal(t, []string{"disk", "db", "01"}, notification.SearchTerms(`"Disk" db-01 disk`))
+	require.Empty(t, notification.SearchTerms(" -- "))
+}
+
+func TestHighlight(t *testing.T) {
+	terms := []string{"disk"}
+
+	require.Equal(t, "<mark>Disk</mark> usage is high", notification.Highlight("Disk usage is high", terms, 0))
+	require.Equal(t, "nothing here", notification.Highlight("nothing here", terms, 0))
+	require.Equal(t,
+		`&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <mark>&lt;b&gt;disk&lt;/b&gt;</mark> &amp; more`,
+		notification.Highlight(`<script>alert("x")</script> <b>disk</b> & more`, terms, 0),
+	)
+
+	words := strings.Fields(strings.Repeat("word ", 20) + "disk " + strings.Repeat("word ", 20))
+	snippet := strings.Fields(notification.Highlight(strings.Join(words, " "), terms, 10))
+	require.Len(t, snippet, 10)
+	require.Equal(t, "<mark>disk</mark>", snippet[2])
+}
+
+func TestSearchEmpty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mockntf.NewMockStorer(ctrl)
+	core := notification.NewCore(nil, storer)
+
+	_, err := core.Search(context.Background(), notification.SearchFilter{Text: " ! "}, 1, 10)
+	require.ErrorIs(t, err, notification.ErrEmptySearch)
+}
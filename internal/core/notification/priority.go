@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Set of priorities a notification can have, from the lowest to the highest.
+var (
+	PriorityLow    = Priority{"low", 1}
+	PriorityNormal = Priority{"normal", 2}
+	PriorityHigh   = Priority{"high", 3}
+	PriorityUrgent = Priority{"urgent", 4}
+)
+
+// Set of known priorities.
+var priorities = map[string]Priority{
+	PriorityLow.name:    PriorityLow,
+	PriorityNormal.name: PriorityNormal,
+	PriorityHigh.name:   PriorityHigh,
+	PriorityUrgent.name: PriorityUrgent,
+}
+
+// Priority represents how urgent a notification is.
+type Priority struct {
+	name  string
+	level int
+}
+
+// ParsePriority parses the string value and returns a priority if one exists.
+func ParsePriority(value string) (Priority, error) {
+	priority, exists := priorities[value]
+	if !exists {
+		return Priority{}, fmt.Errorf("%w %q", ErrInvalidPriority, value)
+	}
+
+	return priority, nil
+}
+
+// MustParsePriority parses the string value and returns a priority if one
+// exists. If an error occurs the function panics.
+func MustParsePriority(value string) Priority {
+	priority, err := ParsePriority(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return priority
+}
+
+// Name returns the name of the priority.
+func (p Priority) Name() string {
+	return p.name
+}
+
+// AtLeast reports whether the priority is as high as the other one.
+func (p Priority) AtLeast(p2 Priority) bool {
+	return p.level >= p2.level
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (p *Priority) UnmarshalText(data []byte) error {
+	priority, err := ParsePriority(string(data))
+	if err != nil {
+		return err
+	}
+
+	*p = priority
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.name), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (p Priority) Equal(p2 Priority) bool {
+	return p.name == p2.name
+}
+
+// =============================================================================
+
+// NormalizeTags lower cases and trims the tags, dropping empty and repeated
+// ones, so tags compare the same no matter how they were typed.
+func NormalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,34 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+)
+
+// Set of fields the notifications can be ordered by.
+const (
+	OrderByID        = "id"
+	OrderByCreatedAt = "created_at"
+)
+
+// OrderByFields lists the fields the notifications can be ordered by.
+var OrderByFields = []string{OrderByID, OrderByCreatedAt}
+
+// DefaultOrderBy is the order notifications are listed in unless asked
+// otherwise, the newest first.
+var DefaultOrderBy = order.NewBy(OrderByCreatedAt, order.DESC)
+
+// QueryFilter holds the fields notifications can be filtered by. Fields left
+// nil match every notification. The dates include notifications created from
+// the start up to the end. Deleted notifications are only listed when Deleted
+// is set, and then only them.
+type QueryFilter struct {
+	TopicID          *uuid.UUID
+	Priority         *Priority
+	Tag              *string
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
+	Deleted          bool
+}
@@ -0,0 +1,120 @@
+package notification
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// Set of markers the matched words of a snippet are wrapped in. The rest of
+// the snippet is HTML escaped, so the markers are the only markup in it.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// snippetWords is how many words of a message a snippet shows at most.
+const snippetWords = 35
+
+// SearchFilter holds what notifications are searched for. The text is
+// matched against the title and the message, and the other fields narrow the
+// search like those of QueryFilter. ReadableBy leaves only the notifications
+// of topics the user may read, and TopicIDs, when given, only those of the
+// listed topics. Deleted notifications and those of deleted topics are never
+// found.
+type SearchFilter struct {
+	Text             string
+	TopicID          *uuid.UUID
+	Priority         *Priority
+	StartCreatedDate *time.Time
+	EndCreatedDate   *time.Time
+	ReadableBy       *uuid.UUID
+	TopicIDs         []uuid.UUID
+}
+
+// SearchResult is a notification found by a search, along with its title and
+// a snippet of its message, HTML escaped, where the matched words are
+// highlighted.
+type SearchResult struct {
+	Notification
+	TitleSnippet   string
+	MessageSnippet string
+}
+
+// SearchTerms splits the text searched for into its words, lower cased. Stores
+// without full-text search find the notifications containing every word.
+func SearchTerms(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if !slices.Contains(terms, field) {
+			terms = append(terms, field)
+		}
+	}
+
+	return terms
+}
+
+// Highlight returns a snippet of the text starting shortly before the first
+// word containing one of the terms, with every such word wrapped in the
+// highlight markers. The words are HTML escaped, as the text is written by the
+// publisher. Texts of up to maxWords words are returned whole, and maxWords of
+// zero or less keeps every word.
+func Highlight(text string, terms []string, maxWords int) string {
+	words := strings.Fields(text)
+
+	matched := make([]bool, len(words))
+	first := -1
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				matched[i] = true
+				break
+			}
+		}
+
+		if matched[i] && first == -1 {
+			first = i
+		}
+	}
+
+	start, end := 0, len(words)
+	if maxWords > 0 && len(words) > maxWords {
+		start = max(0, min(first-maxWords/4, len(words)-maxWords))
+		end = start + maxWords
+	}
+
+	var b strings.Builder
+	for i := start; i < end; i++ {
+		if i > start {
+			b.WriteByte(' ')
+		}
+
+		word := html.EscapeString(words[i])
+		if matched[i] {
+			b.WriteString(HighlightStart + word + HighlightStop)
+			continue
+		}
+		b.WriteString(word)
+	}
+
+	return b.String()
+}
+
+// NewSearchResult builds the result of a search for the terms, highlighting
+// them in the title and in a snippet of the message.
+func NewSearchResult(ntf Notification, terms []string) SearchResult {
+	return SearchResult{
+		Notification:   ntf,
+		TitleSnippet:   Highlight(ntf.Title, terms, 0),
+		MessageSnippet: Highlight(ntf.Message, terms, snippetWords),
+	}
+}
@@ -0,0 +1,192 @@
+package notificationlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/google/uuid"
+)
+
+type dbNotification struct {
+	ID        uuid.UUID          `db:"id"`
+	TopicID   uuid.UUID          `db:"topic_id"`
+	Title     string             `db:"title"`
+	Message   string             `db:"message"`
+	Priority  string             `db:"priority"`
+	Tags      database.JSONArray `db:"tags"`
+	CreatedAt time.Time          `db:"created_at"`
+	DeletedAt sql.NullTime       `db:"deleted_at"`
+}
+
+func toDBNotification(ntf notification.Notification) dbNotification {
+	tags := database.JSONArray(ntf.Tags)
+	if tags == nil {
+		tags = database.JSONArray{}
+	}
+
+	return dbNotification{
+		ID:        ntf.ID,
+		TopicID:   ntf.TopicID,
+		Title:     ntf.Title,
+		Message:   ntf.Message,
+		Priority:  ntf.Priority.Name(),
+		Tags:      tags,
+		CreatedAt: ntf.CreatedAt.UTC(),
+		DeletedAt: sql.NullTime{
+			Time:  ntf.DeletedAt.UTC(),
+			Valid: !ntf.DeletedAt.IsZero(),
+		},
+	}
+}
+
+func toCoreNotification(dbNtf dbNotification) (notification.Notification, error) {
+	priority, err := notification.ParsePriority(dbNtf.Priority)
+	if err != nil {
+		return notification.Notification{}, fmt.Errorf("parse priority: %w", err)
+	}
+
+	ntf := notification.Notification{
+		ID:        dbNtf.ID,
+		TopicID:   dbNtf.TopicID,
+		Title:     dbNtf.Title,
+		Message:   dbNtf.Message,
+		Priority:  priority,
+		Tags:      dbNtf.Tags,
+		CreatedAt: dbNtf.CreatedAt,
+	}
+
+	if dbNtf.DeletedAt.Valid {
+		ntf.DeletedAt = dbNtf.DeletedAt.Time.In(time.Local)
+	}
+
+	return ntf, nil
+}
+
+func toCoreNotificationSlice(dbNtfs []dbNotification) ([]notification.Notification, error) {
+	ntfs := make([]notification.Notification, len(dbNtfs))
+
+	for i, dbNtf := range dbNtfs {
+		var err error
+		ntfs[i], err = toCoreNotification(dbNtf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ntfs, nil
+}
+
+type dbDelivery struct {
+	ID             uuid.UUID `db:"id"`
+	NotificationID uuid.UUID `db:"notification_id"`
+	Channel        string    `db:"channel"`
+	Address        string    `db:"address"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func toDBDelivery(dlv notification.Delivery) dbDelivery {
+	return dbDelivery{
+		ID:             dlv.ID,
+		NotificationID: dlv.NotificationID,
+		Channel:        dlv.Channel.Name(),
+		Address:        dlv.Address,
+		CreatedAt:      dlv.CreatedAt.UTC(),
+	}
+}
+
+func toCoreDeliverySlice(dbDlvs []dbDelivery) ([]notification.Delivery, error) {
+	dlvs := make([]notification.Delivery, len(dbDlvs))
+
+	for i, dbDlv := range dbDlvs {
+		channel, err := notification.ParseChannel(dbDlv.Channel)
+		if err != nil {
+			return nil, fmt.Errorf("parse channel: %w", err)
+		}
+
+		dlvs[i] = notification.Delivery{
+			ID:             dbDlv.ID,
+			NotificationID: dbDlv.NotificationID,
+			Channel:        channel,
+			Address:        dbDlv.Address,
+			CreatedAt:      dbDlv.CreatedAt,
+		}
+	}
+
+	return dlvs, nil
+}
+
+type dbSkip struct {
+	ID             uuid.UUID `db:"id"`
+	NotificationID uuid.UUID `db:"notification_id"`
+	SubscriptionID uuid.UUID `db:"subscription_id"`
+	Reason         string    `db:"reason"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func toDBSkip(skp notification.Skip) dbSkip {
+	return dbSkip{
+		ID:             skp.ID,
+		NotificationID: skp.NotificationID,
+		SubscriptionID: skp.SubscriptionID,
+		Reason:         skp.Reason,
+		CreatedAt:      skp.CreatedAt.UTC(),
+	}
+}
+
+func toCoreSkipSlice(dbSkps []dbSkip) []notification.Skip {
+	skps := make([]notification.Skip, len(dbSkps))
+
+	for i, dbSkp := range dbSkps {
+		skps[i] = notification.Skip{
+			ID:             dbSkp.ID,
+			NotificationID: dbSkp.NotificationID,
+			SubscriptionID: dbSkp.SubscriptionID,
+			Reason:         dbSkp.Reason,
+			CreatedAt:      dbSkp.CreatedAt,
+		}
+	}
+
+	return skps
+}
+
+type dbLogEntry struct {
+	ID             uuid.UUID     `db:"id"`
+	NotificationID uuid.UUID     `db:"notification_id"`
+	Skipped        bool          `db:"skipped"`
+	Channel        string        `db:"channel"`
+	Address        string        `db:"address"`
+	SubscriptionID uuid.NullUUID `db:"subscription_id"`
+	Reason         string        `db:"reason"`
+	CreatedAt      time.Time     `db:"created_at"`
+}
+
+func toCoreLogEntrySlice(dbEntries []dbLogEntry) ([]notification.LogEntry, error) {
+	entries := make([]notification.LogEntry, len(dbEntries))
+
+	for i, dbEntry := range dbEntries {
+		entry := notification.LogEntry{
+			ID:             dbEntry.ID,
+			NotificationID: dbEntry.NotificationID,
+			Skipped:        dbEntry.Skipped,
+			Address:        dbEntry.Address,
+			SubscriptionID: dbEntry.SubscriptionID.UUID,
+			Reason:         dbEntry.Reason,
+			CreatedAt:      dbEntry.CreatedAt,
+		}
+
+		if !entry.Skipped {
+			channel, err := notification.ParseChannel(dbEntry.Channel)
+			if err != nil {
+				return nil, fmt.Errorf("parse channel: %w", err)
+			}
+			entry.Channel = channel
+		}
+
+		entries[i] = entry
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,102 @@
+package notificationlite
+
+import (
+	"strings"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/platform/database"
+)
+
+// orderByFields maps the fields notifications can be ordered by to their
+// columns.
+var orderByFields = map[string]string{
+	notification.OrderByID:        "id",
+	notification.OrderByCreatedAt: "created_at",
+}
+
+// applyFilter adds the conditions of the filter to the query.
+func applyFilter(b *database.QueryBuilder, filter notification.QueryFilter) {
+	if filter.Deleted {
+		b.Where("deleted_at IS NOT NULL", nil)
+	} else {
+		b.Where("deleted_at IS NULL", nil)
+	}
+
+	if filter.TopicID != nil {
+		b.Where("topic_id = :topic_id", map[string]any{"topic_id": *filter.TopicID})
+	}
+
+	if filter.Priority != nil {
+		b.Where("priority = :priority", map[string]any{"priority": filter.Priority.Name()})
+	}
+
+	if filter.Tag != nil {
+		b.Where("EXISTS (SELECT 1 FROM json_each(tags) WHERE value = :tag)", map[string]any{"tag": strings.ToLower(strings.TrimSpace(*filter.Tag))})
+	}
+
+	if filter.StartCreatedDate != nil {
+		b.Where("created_at >= :start_created_date", map[string]any{"start_created_date": filter.StartCreatedDate.UTC()})
+	}
+
+	if filter.EndCreatedDate != nil {
+		b.Where("created_at <= :end_created_date", map[string]any{"end_created_date": filter.EndCreatedDate.UTC()})
+	}
+}
+
+// applySearchFilter adds the conditions of the search to the query. SQLite
+// has no full-text search here, so the notifications containing every word
+// of the text in their title or message are found.
+func applySearchFilter(b *database.QueryBuilder, filter notification.SearchFilter) {
+	applyFilter(b, notification.QueryFilter{
+		TopicID:          filter.TopicID,
+		Priority:         filter.Priority,
+		StartCreatedDate: filter.StartCreatedDate,
+		EndCreatedDate:   filter.EndCreatedDate,
+	})
+
+	const matching = `
+	NOT EXISTS (
+		SELECT 1 FROM json_each(:terms)
+		WHERE instr(lower(title || ' ' || coalesce(message, '')), value) = 0
+	)`
+
+	b.Where(matching, map[string]any{"terms": database.JSONArray(notification.SearchTerms(filter.Text))})
+
+	if len(filter.TopicIDs) > 0 {
+		ids := make(database.JSONArray, len(filter.TopicIDs))
+		for i, id := range filter.TopicIDs {
+			ids[i] = id.String()
+		}
+		b.Where("topic_id IN (SELECT value FROM json_each(:topic_ids))", map[string]any{"topic_ids": ids})
+	}
+
+	if filter.ReadableBy == nil {
+		b.Where("topic_id IN (SELECT t.id FROM topics t WHERE t.deleted_at IS NULL)", nil)
+		return
+	}
+
+	const readable = `
+	topic_id IN (
+		SELECT
+			t.id
+		FROM
+			topics t
+		WHERE
+			t.deleted_at IS NULL AND (
+				t.visibility = :public_visibility OR
+				t.owner_id = :readable_by OR
+				EXISTS (
+					SELECT 1 FROM topic_acls a, json_each(a.rights) r
+					WHERE a.topic_id = t.id AND a.user_id = :readable_by AND r.value IN (:right_subscribe, :right_manage)
+				)
+			)
+	)`
+
+	b.Where(readable, map[string]any{
+		"public_visibility": topic.VisibilityPublic.Name(),
+		"readable_by":       *filter.ReadableBy,
+		"right_subscribe":   topic.RightSubscribe.Name(),
+		"right_manage":      topic.RightManage.Name(),
+	})
+}
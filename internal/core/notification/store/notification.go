@@ -2,20 +2,25 @@
 package notificationdb
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/platform/cursor"
 	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
 // Store manages the set of APIs for notification database access.
 type Store struct {
 	log    *zerolog.Logger
-	db     sqlx.Ext
+	db     sqlx.ExtContext
 	inTran bool
 }
 
@@ -28,7 +33,7 @@ func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
 }
 
 // WithinTran runs passed function and do commit/rollback at the end.
-func (s *Store) WithinTran(fn func(notification.Storer) error) error {
+func (s *Store) WithinTran(ctx context.Context, fn func(notification.Storer) error) error {
 	if s.inTran {
 		return fn(s)
 	}
@@ -42,26 +47,51 @@ func (s *Store) WithinTran(fn func(notification.Storer) error) error {
 		return fn(s)
 	}
 
-	return database.WithinTran(s.log, s.db.(*sqlx.DB), f)
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
 }
 
 // Create inserts a new notification into the database.
-func (s *Store) Create(ntf notification.Notification) error {
+func (s *Store) Create(ctx context.Context, ntf notification.Notification) error {
 	const q = `
 	INSERT INTO notifications 
-		(id, topic_id, message, created_at)
+		(id, topic_id, title, message, priority, tags, created_at)
 	VALUES 
-		(:id, :topic_id, :message, :created_at)`
+		(:id, :topic_id, :title, :message, :priority, :tags, :created_at)`
 
-	if err := database.NamedExec(s.log, s.db, q, toDBNotification(ntf)); err != nil {
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBNotification(ntf)); err != nil {
 		return fmt.Errorf("namedexec: %w", err)
 	}
 
 	return nil
 }
 
-// Delete removes a notification from the database.
-func (s *Store) Delete(ntf notification.Notification) error {
+// Delete marks a notification as deleted in the database.
+func (s *Store) Delete(ctx context.Context, ntf notification.Notification) error {
+	data := struct {
+		ID        string    `db:"id"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}{
+		ID:        ntf.ID.String(),
+		DeletedAt: ntf.DeletedAt.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		notifications
+	SET
+		deleted_at = :deleted_at
+	WHERE
+		id = :id AND deleted_at IS NULL`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Restore clears the deletion of a notification in the database.
+func (s *Store) Restore(ctx context.Context, ntf notification.Notification) error {
 	data := struct {
 		ID string `db:"id"`
 	}{
@@ -69,53 +99,472 @@ func (s *Store) Delete(ntf notification.Notification) error {
 	}
 
 	const q = `
-	DELETE FROM
+	UPDATE
 		notifications
+	SET
+		deleted_at = NULL
 	WHERE
 		id = :id`
 
-	if err := database.NamedExec(s.log, s.db, q, data); err != nil {
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
 		return fmt.Errorf("namedexec: %w", err)
 	}
 
 	return nil
 }
 
-// Query retrieves a list of notification from the database.
-func (s *Store) Query(pageNumber int, rowsPerPage int, topicID uuid.UUID) ([]notification.Notification, error) {
-	data := map[string]interface{}{
-		"offset":        (pageNumber - 1) * rowsPerPage,
-		"rows_per_page": rowsPerPage,
-		"topic_id":      topicID.String(),
+// Purge removes up to limit notifications deleted before the given time from
+// the database.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+		Limit  int       `db:"limit"`
+	}{
+		Before: before.UTC(),
+		Limit:  limit,
+	}
+
+	const q = `
+	DELETE FROM
+		notifications
+	WHERE
+		id IN (SELECT id FROM notifications WHERE deleted_at < :before LIMIT :limit)`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	return n, nil
+}
+
+// PurgeOlder removes up to limit notifications created before the given time
+// from the database. With a topic it removes the notifications of that topic,
+// otherwise those of the topics without a retention of their own.
+func (s *Store) PurgeOlder(ctx context.Context, topicID uuid.UUID, before time.Time, limit int) (int, error) {
+	data := struct {
+		TopicID uuid.UUID `db:"topic_id"`
+		Before  time.Time `db:"before"`
+		Limit   int       `db:"limit"`
+	}{
+		TopicID: topicID,
+		Before:  before.UTC(),
+		Limit:   limit,
+	}
+
+	q := `
+	DELETE FROM
+		notifications
+	WHERE
+		id IN (SELECT id FROM notifications WHERE topic_id = :topic_id AND created_at < :before LIMIT :limit)`
+
+	if topicID == uuid.Nil {
+		q = `
+	DELETE FROM
+		notifications
+	WHERE
+		id IN (
+			SELECT
+				n.id
+			FROM
+				notifications n
+			JOIN
+				topics t ON t.id = n.topic_id
+			WHERE
+				t.retention_seconds = 0
+				AND n.created_at < :before
+			LIMIT :limit
+		)`
+	}
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	return n, nil
+}
+
+// Query retrieves a list of notifications from the database.
+func (s *Store) Query(ctx context.Context, filter notification.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]notification.Notification, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM notifications`)
+	applyFilter(b, filter)
+
+	if err := b.OrderBy(orderBy, orderByFields, "id"); err != nil {
+		return nil, err
 	}
+	b.Page(pageNumber, rowsPerPage)
 
-	const q = `SELECT * FROM notifications`
+	q, data := b.Query()
 
 	var dbNtfs []dbNotification
-	if err := database.NamedQuerySlice(s.log, s.db, q, data, &dbNtfs); err != nil {
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbNtfs); err != nil {
 		return nil, fmt.Errorf("namedqueryslice: %w", err)
 	}
 
-	return toCoreNotificationSlice(dbNtfs), nil
+	return toCoreNotificationSlice(dbNtfs)
+}
+
+// Count returns the number of notifications in the database matching the
+// filter.
+func (s *Store) Count(ctx context.Context, filter notification.QueryFilter) (int, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM notifications`)
+	applyFilter(b, filter)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByCursor retrieves the page of notifications read with the cursor from
+// the database, in the direction of the cursor.
+func (s *Store) QueryByCursor(ctx context.Context, filter notification.QueryFilter, cur cursor.Cursor, rowsPerPage int) ([]notification.Notification, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM notifications`)
+	applyFilter(b, filter)
+	b.Keyset(cur, "created_at", "id", rowsPerPage)
+
+	q, data := b.Query()
+
+	var dbNtfs []dbNotification
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbNtfs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreNotificationSlice(dbNtfs)
 }
 
 // QueryByID gets the specified notification from the database by id.
-func (s *Store) QueryByID(id uuid.UUID) (notification.Notification, error) {
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (notification.Notification, error) {
 	data := struct {
 		ID uuid.UUID `db:"id"`
 	}{
 		ID: id,
 	}
 
-	const q = `SELECT * FROM notifications WHERE id = :id`
+	const q = `SELECT * FROM notifications WHERE id = :id AND deleted_at IS NULL`
 
 	var dbNtf dbNotification
-	if err := database.NamedQueryStruct(s.log, s.db, q, data, &dbNtf); err != nil {
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbNtf); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return notification.Notification{}, notification.ErrNotFound
 		}
 		return notification.Notification{}, fmt.Errorf("namedquerystruct: %w", err)
 	}
 
-	return toCoreNotification(dbNtf), nil
+	return toCoreNotification(dbNtf)
+}
+
+// QueryDeletedByID gets the specified deleted notification from the database
+// by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (notification.Notification, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM notifications WHERE id = :id AND deleted_at IS NOT NULL`
+
+	var dbNtf dbNotification
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbNtf); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return notification.Notification{}, notification.ErrNotFound
+		}
+		return notification.Notification{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreNotification(dbNtf)
+}
+
+// Search retrieves a list of the notifications matching the search from the
+// database, the most relevant first, along with their highlighted snippets.
+func (s *Store) Search(ctx context.Context, filter notification.SearchFilter, pageNumber int, rowsPerPage int) ([]notification.SearchResult, error) {
+	b := database.NewQueryBuilder(`
+	SELECT
+		notifications.*,
+		ts_headline('english', ` + escapeHTML("title") + `, query, '` + titleHeadline + `') AS title_snippet,
+		ts_headline('english', ` + escapeHTML("coalesce(message, '')") + `, query, '` + messageHeadline + `') AS message_snippet,
+		ts_rank(` + document + `, query) AS rank
+	FROM
+		notifications,
+		websearch_to_tsquery('english', :text) query`)
+	applySearchFilter(b, filter)
+
+	if err := b.OrderBy(searchOrder, searchOrderByFields, "created_at"); err != nil {
+		return nil, err
+	}
+	b.Page(pageNumber, rowsPerPage)
+
+	q, data := b.Query()
+
+	var dbRs []dbSearchResult
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbRs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreSearchResultSlice(dbRs)
+}
+
+// CountSearch returns the number of notifications in the database matching
+// the search.
+func (s *Store) CountSearch(ctx context.Context, filter notification.SearchFilter) (int, error) {
+	b := database.NewQueryBuilder(`
+	SELECT
+		notifications.id
+	FROM
+		notifications,
+		websearch_to_tsquery('english', :text) query`)
+	applySearchFilter(b, filter)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
+// AddToInbox inserts the notification into the inbox of the users in the
+// database, skipping the inboxes already holding it.
+func (s *Store) AddToInbox(ctx context.Context, ntf notification.Notification, userIDs []uuid.UUID) error {
+	strIDs := make(pq.StringArray, len(userIDs))
+	for i, id := range userIDs {
+		strIDs[i] = id.String()
+	}
+
+	data := struct {
+		NotificationID uuid.UUID      `db:"notification_id"`
+		UserIDs        pq.StringArray `db:"user_ids"`
+	}{
+		NotificationID: ntf.ID,
+		UserIDs:        strIDs,
+	}
+
+	const q = `
+	INSERT INTO inbox
+		(notification_id, user_id)
+	SELECT
+		:notification_id, unnest(CAST(:user_ids AS UUID[]))
+	ON CONFLICT DO NOTHING`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryInbox retrieves a list of the notifications in the inbox of the user
+// from the database, leaving out the deleted ones and those of deleted topics.
+func (s *Store) QueryInbox(ctx context.Context, pageNumber int, rowsPerPage int, userID uuid.UUID) ([]notification.Notification, error) {
+	data := map[string]interface{}{
+		"user_id":       userID,
+		"offset":        (pageNumber - 1) * rowsPerPage,
+		"rows_per_page": rowsPerPage,
+	}
+
+	const q = `
+	SELECT
+		n.*
+	FROM
+		inbox i
+	JOIN
+		notifications n
+	ON
+		n.id = i.notification_id
+	JOIN
+		topics t
+	ON
+		t.id = n.topic_id
+	WHERE
+		i.user_id = :user_id AND
+		n.deleted_at IS NULL AND
+		t.deleted_at IS NULL
+	ORDER BY
+		n.created_at DESC, n.id DESC
+	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+
+	var dbNtfs []dbNotification
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbNtfs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreNotificationSlice(dbNtfs)
+}
+
+// CountInbox returns the number of notifications in the inbox of the user in
+// the database, leaving out the deleted ones and those of deleted topics.
+func (s *Store) CountInbox(ctx context.Context, userID uuid.UUID) (int, error) {
+	b := database.NewQueryBuilder(`
+	SELECT
+		n.*
+	FROM
+		inbox i
+	JOIN
+		notifications n
+	ON
+		n.id = i.notification_id
+	JOIN
+		topics t
+	ON
+		t.id = n.topic_id`)
+	b.Where("i.user_id = :user_id", map[string]any{"user_id": userID})
+	b.Where("n.deleted_at IS NULL AND t.deleted_at IS NULL", nil)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryInboxByCursor retrieves the page of the user's inbox read with the
+// cursor from the database, in the direction of the cursor.
+func (s *Store) QueryInboxByCursor(ctx context.Context, userID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]notification.Notification, error) {
+	b := database.NewQueryBuilder(`
+	SELECT
+		n.*
+	FROM
+		inbox i
+	JOIN
+		notifications n
+	ON
+		n.id = i.notification_id
+	JOIN
+		topics t
+	ON
+		t.id = n.topic_id`)
+	b.Where("i.user_id = :user_id", map[string]any{"user_id": userID})
+	b.Where("n.deleted_at IS NULL AND t.deleted_at IS NULL", nil)
+	b.Keyset(cur, "n.created_at", "n.id", rowsPerPage)
+
+	q, data := b.Query()
+
+	var dbNtfs []dbNotification
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbNtfs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreNotificationSlice(dbNtfs)
+}
+
+// AddDelivery inserts the delivery of a notification into the database,
+// unless it is there already.
+func (s *Store) AddDelivery(ctx context.Context, dlv notification.Delivery) error {
+	const q = `
+	INSERT INTO notification_deliveries
+		(id, notification_id, channel, address, created_at)
+	VALUES
+		(:id, :notification_id, :channel, :address, :created_at)
+	ON CONFLICT DO NOTHING`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBDelivery(dlv)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDeliveries retrieves the deliveries of the notification from the
+// database.
+func (s *Store) QueryDeliveries(ctx context.Context, notificationID uuid.UUID) ([]notification.Delivery, error) {
+	data := struct {
+		NotificationID uuid.UUID `db:"notification_id"`
+	}{
+		NotificationID: notificationID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		notification_deliveries
+	WHERE
+		notification_id = :notification_id`
+
+	var dbDlvs []dbDelivery
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbDlvs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreDeliverySlice(dbDlvs)
+}
+
+// AddSkip inserts the skipped delivery of a notification into the database,
+// unless it is there already.
+func (s *Store) AddSkip(ctx context.Context, skp notification.Skip) error {
+	const q = `
+	INSERT INTO notification_skips
+		(id, notification_id, subscription_id, reason, created_at)
+	VALUES
+		(:id, :notification_id, :subscription_id, :reason, :created_at)
+	ON CONFLICT DO NOTHING`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBSkip(skp)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QuerySkips retrieves the skipped deliveries of the notification from the
+// database.
+func (s *Store) QuerySkips(ctx context.Context, notificationID uuid.UUID) ([]notification.Skip, error) {
+	data := struct {
+		NotificationID uuid.UUID `db:"notification_id"`
+	}{
+		NotificationID: notificationID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		notification_skips
+	WHERE
+		notification_id = :notification_id`
+
+	var dbSkps []dbSkip
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSkps); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreSkipSlice(dbSkps), nil
+}
+
+// QueryLogByCursor retrieves the page of the log of the notification's
+// deliveries, made and skipped, read with the cursor from the database, in the
+// direction of the cursor.
+func (s *Store) QueryLogByCursor(ctx context.Context, notificationID uuid.UUID, cur cursor.Cursor, rowsPerPage int) ([]notification.LogEntry, error) {
+	b := database.NewQueryBuilder(`
+	SELECT
+		*
+	FROM (
+		SELECT
+			id, notification_id, FALSE AS skipped, channel, address,
+			NULL AS subscription_id, '' AS reason, created_at
+		FROM
+			notification_deliveries
+		UNION ALL
+		SELECT
+			id, notification_id, TRUE AS skipped, '' AS channel, '' AS address,
+			subscription_id, reason, created_at
+		FROM
+			notification_skips
+	) AS l`)
+	b.Where("notification_id = :notification_id", map[string]any{"notification_id": notificationID})
+	b.Keyset(cur, "created_at", "id", rowsPerPage)
+
+	q, data := b.Query()
+
+	var dbEntries []dbLogEntry
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbEntries); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreLogEntrySlice(dbEntries)
 }
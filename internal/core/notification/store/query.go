@@ -0,0 +1,135 @@
+package notificationdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/lib/pq"
+)
+
+// orderByFields maps the fields notifications can be ordered by to their
+// columns.
+var orderByFields = map[string]string{
+	notification.OrderByID:        "id",
+	notification.OrderByCreatedAt: "created_at",
+}
+
+// applyFilter adds the conditions of the filter to the query.
+func applyFilter(b *database.QueryBuilder, filter notification.QueryFilter) {
+	if filter.Deleted {
+		b.Where("deleted_at IS NOT NULL", nil)
+	} else {
+		b.Where("deleted_at IS NULL", nil)
+	}
+
+	if filter.TopicID != nil {
+		b.Where("topic_id = :topic_id", map[string]any{"topic_id": *filter.TopicID})
+	}
+
+	if filter.Priority != nil {
+		b.Where("priority = :priority", map[string]any{"priority": filter.Priority.Name()})
+	}
+
+	if filter.Tag != nil {
+		b.Where(":tag = ANY(tags)", map[string]any{"tag": strings.ToLower(strings.TrimSpace(*filter.Tag))})
+	}
+
+	if filter.StartCreatedDate != nil {
+		b.Where("created_at >= :start_created_date", map[string]any{"start_created_date": filter.StartCreatedDate.UTC()})
+	}
+
+	if filter.EndCreatedDate != nil {
+		b.Where("created_at <= :end_created_date", map[string]any{"end_created_date": filter.EndCreatedDate.UTC()})
+	}
+}
+
+// document is what notifications are searched by, their title weighing more
+// than their message. The search index is built on the same expression.
+const document = `setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', coalesce(message, '')), 'B')`
+
+// escapeHTML returns the SQL expression escaping the HTML of the text
+// expression the way html.EscapeString does, so the snippets built from it
+// hold no markup but the highlight markers.
+func escapeHTML(expr string) string {
+	for _, r := range []struct{ old, new string }{
+		{"&", "&amp;"},
+		{"'", "&#39;"},
+		{"<", "&lt;"},
+		{">", "&gt;"},
+		{`"`, "&#34;"},
+	} {
+		expr = fmt.Sprintf("replace(%s, '%s', '%s')", expr, strings.ReplaceAll(r.old, "'", "''"), r.new)
+	}
+
+	return expr
+}
+
+// Set of options the snippets of the search results are built with. Titles
+// are highlighted whole and messages cut around the matched words.
+const (
+	titleHeadline   = `StartSel=` + notification.HighlightStart + `, StopSel=` + notification.HighlightStop + `, HighlightAll=true`
+	messageHeadline = `StartSel=` + notification.HighlightStart + `, StopSel=` + notification.HighlightStop + `, MaxWords=35, MinWords=15`
+)
+
+// searchOrder is the order search results are listed in, the most relevant
+// first and the newest of those equally relevant.
+var searchOrder = order.NewBy("rank", order.DESC)
+
+// searchOrderByFields maps the fields search results are ordered by to their
+// columns.
+var searchOrderByFields = map[string]string{
+	"rank": "rank",
+}
+
+// applySearchFilter adds the conditions of the search to the query, which
+// selects from notifications and the text searched for as query.
+func applySearchFilter(b *database.QueryBuilder, filter notification.SearchFilter) {
+	applyFilter(b, notification.QueryFilter{
+		TopicID:          filter.TopicID,
+		Priority:         filter.Priority,
+		StartCreatedDate: filter.StartCreatedDate,
+		EndCreatedDate:   filter.EndCreatedDate,
+	})
+
+	b.Where("("+document+") @@ query", map[string]any{"text": filter.Text})
+
+	if len(filter.TopicIDs) > 0 {
+		ids := make(pq.StringArray, len(filter.TopicIDs))
+		for i, id := range filter.TopicIDs {
+			ids[i] = id.String()
+		}
+		b.Where("topic_id = ANY(CAST(:topic_ids AS UUID[]))", map[string]any{"topic_ids": ids})
+	}
+
+	if filter.ReadableBy == nil {
+		b.Where("topic_id IN (SELECT t.id FROM topics t WHERE t.deleted_at IS NULL)", nil)
+		return
+	}
+
+	const readable = `
+	topic_id IN (
+		SELECT
+			t.id
+		FROM
+			topics t
+		WHERE
+			t.deleted_at IS NULL AND (
+				t.visibility = :public_visibility OR
+				t.owner_id = :readable_by OR
+				EXISTS (
+					SELECT 1 FROM topic_acls a
+					WHERE a.topic_id = t.id AND a.user_id = :readable_by AND a.rights && CAST(:readable_rights AS VARCHAR[])
+				)
+			)
+	)`
+
+	b.Where(readable, map[string]any{
+		"public_visibility": topic.VisibilityPublic.Name(),
+		"readable_by":       *filter.ReadableBy,
+		"readable_rights":   pq.StringArray{topic.RightSubscribe.Name(), topic.RightManage.Name()},
+	})
+}
@@ -0,0 +1,168 @@
+// Package passkeymem contains passkey related CRUD functionality, kept in
+// memory.
+package passkeymem
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table           = "passkeys"
+	tableCeremonies = "webauthn_ceremonies"
+)
+
+// Store manages the set of APIs for passkey access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Unique("unique_passkey_credential", memdb.Unique{
+		Table: table,
+		Key:   func(row any) (string, bool) { return string(row.(passkey.Passkey).CredentialID), true },
+	})
+
+	db.Reference("passkeys_user_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(passkey.Passkey).UserID.String() == key },
+	})
+
+	db.Reference("webauthn_ceremonies_user_id_fkey", memdb.Reference{
+		Table:  tableCeremonies,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(passkey.Ceremony).UserID.String() == key },
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(passkey.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new passkey into the database.
+func (s *Store) Create(ctx context.Context, pk passkey.Passkey) error {
+	if err := memdb.Insert(s.db, table, pk.ID.String(), clone(pk)); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return passkey.ErrUniqueCredential
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update replaces the mutable state of a passkey in the database.
+func (s *Store) Update(ctx context.Context, pk passkey.Passkey) error {
+	stored, exists := memdb.Get[passkey.Passkey](s.db, table, pk.ID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.Name = pk.Name
+	stored.SignCount = pk.SignCount
+	stored.BackupState = pk.BackupState
+	stored.LastUsedAt = pk.LastUsedAt
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// Delete removes a passkey from the database.
+func (s *Store) Delete(ctx context.Context, pk passkey.Passkey) error {
+	memdb.Delete(s.db, table, pk.ID.String())
+
+	return nil
+}
+
+// QueryByID gets the specified passkey from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (passkey.Passkey, error) {
+	pk, exists := memdb.Get[passkey.Passkey](s.db, table, id.String())
+	if !exists {
+		return passkey.Passkey{}, passkey.ErrNotFound
+	}
+
+	return clone(pk), nil
+}
+
+// QueryByUserID retrieves the passkeys of the specified user from the database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]passkey.Passkey, error) {
+	pks := memdb.Rows(s.db, table, func(pk passkey.Passkey) bool {
+		return pk.UserID == userID
+	})
+
+	slices.SortStableFunc(pks, func(a, b passkey.Passkey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	for i := range pks {
+		pks[i] = clone(pks[i])
+	}
+
+	return pks, nil
+}
+
+// CreateCeremony inserts a new ceremony into the database.
+func (s *Store) CreateCeremony(ctx context.Context, cer passkey.Ceremony) error {
+	cer.Data = slices.Clone(cer.Data)
+
+	return memdb.Insert(s.db, tableCeremonies, cer.ID.String(), cer)
+}
+
+// DeleteCeremony removes a ceremony from the database.
+func (s *Store) DeleteCeremony(ctx context.Context, cer passkey.Ceremony) error {
+	memdb.Delete(s.db, tableCeremonies, cer.ID.String())
+
+	return nil
+}
+
+// QueryCeremonyByID gets the specified ceremony from the database.
+func (s *Store) QueryCeremonyByID(ctx context.Context, id uuid.UUID) (passkey.Ceremony, error) {
+	cer, exists := memdb.Get[passkey.Ceremony](s.db, tableCeremonies, id.String())
+	if !exists {
+		return passkey.Ceremony{}, passkey.ErrCeremonyNotFound
+	}
+
+	cer.Data = slices.Clone(cer.Data)
+
+	return cer, nil
+}
+
+// =============================================================================
+
+// clone copies the passkey, so the stored passkey shares no memory with the
+// callers.
+func clone(pk passkey.Passkey) passkey.Passkey {
+	pk.CredentialID = slices.Clone(pk.CredentialID)
+	pk.PublicKey = slices.Clone(pk.PublicKey)
+	pk.AAGUID = slices.Clone(pk.AAGUID)
+	pk.Transports = slices.Clone(pk.Transports)
+	return pk
+}
@@ -0,0 +1,34 @@
+package passkey
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Passkey represents a FIDO2 credential registered by a user.
+type Passkey struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	Name            string
+	CredentialID    []byte
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+	BackupEligible  bool
+	BackupState     bool
+	CreatedAt       time.Time
+	LastUsedAt      time.Time
+}
+
+// Ceremony represents the server side state of a registration or login
+// ceremony which is in progress.
+type Ceremony struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Data      []byte
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
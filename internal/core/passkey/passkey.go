@@ -0,0 +1,371 @@
+// Package passkey provides a core business API.
+package passkey
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound              = errors.New("passkey not found")
+	ErrUniqueCredential      = errors.New("passkey is already registered")
+	ErrCeremonyNotFound      = errors.New("ceremony not found")
+	ErrCeremonyExpired       = errors.New("ceremony has expired")
+	ErrAuthenticationFailure = errors.New("passkey authentication failed")
+)
+
+// CeremonyDuration is how long a client has to complete a ceremony.
+const CeremonyDuration = 5 * time.Minute
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, pk Passkey) error
+	Update(ctx context.Context, pk Passkey) error
+	Delete(ctx context.Context, pk Passkey) error
+	QueryByID(ctx context.Context, id uuid.UUID) (Passkey, error)
+	QueryByUserID(ctx context.Context, userID uuid.UUID) ([]Passkey, error)
+	CreateCeremony(ctx context.Context, cer Ceremony) error
+	DeleteCeremony(ctx context.Context, cer Ceremony) error
+	QueryCeremonyByID(ctx context.Context, id uuid.UUID) (Ceremony, error)
+}
+
+// Core manages the set of APIs for passkey access.
+type Core struct {
+	log      *zerolog.Logger
+	usrCore  *user.Core
+	storer   Storer
+	webAuthn *webauthn.WebAuthn
+}
+
+// NewCore constructs a passkey core API for use.
+func NewCore(log *zerolog.Logger, usrCore *user.Core, storer Storer, webAuthn *webauthn.WebAuthn) *Core {
+	return &Core{
+		log:      log,
+		usrCore:  usrCore,
+		storer:   storer,
+		webAuthn: webAuthn,
+	}
+}
+
+// BeginRegistration starts the registration of a new passkey for the user.
+func (c *Core) BeginRegistration(ctx context.Context, usr user.User) (*protocol.CredentialCreation, uuid.UUID, error) {
+	pks, err := c.storer.QueryByUserID(ctx, usr.ID)
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("query: %w", err)
+	}
+
+	waUsr := newWebAuthnUser(usr, pks)
+
+	exclusions := make([]protocol.CredentialDescriptor, len(pks))
+	for i, cred := range waUsr.WebAuthnCredentials() {
+		exclusions[i] = cred.Descriptor()
+	}
+
+	opts := []webauthn.RegistrationOption{
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationRequired,
+		}),
+	}
+
+	creation, session, err := c.webAuthn.BeginRegistration(waUsr, opts...)
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("beginregistration: %w", err)
+	}
+
+	cer, err := c.createCeremony(ctx, usr.ID, session)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	return creation, cer.ID, nil
+}
+
+// FinishRegistration verifies the authenticator response for a registration
+// ceremony and stores the new passkey.
+func (c *Core) FinishRegistration(ctx context.Context, usr user.User, ceremonyID uuid.UUID, name string, response []byte) (Passkey, error) {
+	session, err := c.consumeCeremony(ctx, ceremonyID, usr.ID)
+	if err != nil {
+		return Passkey{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return Passkey{}, fmt.Errorf("parse: %w", authenticationFailure(err))
+	}
+
+	pks, err := c.storer.QueryByUserID(ctx, usr.ID)
+	if err != nil {
+		return Passkey{}, fmt.Errorf("query: %w", err)
+	}
+
+	cred, err := c.webAuthn.CreateCredential(newWebAuthnUser(usr, pks), session, parsed)
+	if err != nil {
+		return Passkey{}, fmt.Errorf("createcredential: %w", authenticationFailure(err))
+	}
+
+	transports := make([]string, len(cred.Transport))
+	for i, t := range cred.Transport {
+		transports[i] = string(t)
+	}
+
+	pk := Passkey{
+		ID:              uuid.New(),
+		UserID:          usr.ID,
+		Name:            name,
+		CredentialID:    cred.ID,
+		PublicKey:       cred.PublicKey,
+		AttestationType: cred.AttestationType,
+		AAGUID:          cred.Authenticator.AAGUID,
+		SignCount:       cred.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  cred.Flags.BackupEligible,
+		BackupState:     cred.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+
+	if err := c.storer.Create(ctx, pk); err != nil {
+		return Passkey{}, fmt.Errorf("create: %w", err)
+	}
+
+	return pk, nil
+}
+
+// BeginLogin starts a login ceremony. Without an email the ceremony is a
+// discoverable login where the authenticator picks the account.
+func (c *Core) BeginLogin(ctx context.Context, email string) (*protocol.CredentialAssertion, uuid.UUID, error) {
+	opts := []webauthn.LoginOption{
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	}
+
+	if email == "" {
+		assertion, session, err := c.webAuthn.BeginDiscoverableLogin(opts...)
+		if err != nil {
+			return nil, uuid.Nil, fmt.Errorf("begindiscoverablelogin: %w", err)
+		}
+
+		cer, err := c.createCeremony(ctx, uuid.Nil, session)
+		if err != nil {
+			return nil, uuid.Nil, err
+		}
+
+		return assertion, cer.ID, nil
+	}
+
+	usr, err := c.usrCore.QueryByEmail(ctx, email)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	pks, err := c.storer.QueryByUserID(ctx, usr.ID)
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("query: %w", err)
+	}
+
+	if len(pks) == 0 {
+		return nil, uuid.Nil, ErrNotFound
+	}
+
+	assertion, session, err := c.webAuthn.BeginLogin(newWebAuthnUser(usr, pks), opts...)
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("beginlogin: %w", err)
+	}
+
+	cer, err := c.createCeremony(ctx, usr.ID, session)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	return assertion, cer.ID, nil
+}
+
+// FinishLogin verifies the authenticator response for a login ceremony and
+// returns the authenticated user.
+func (c *Core) FinishLogin(ctx context.Context, ceremonyID uuid.UUID, response []byte) (user.User, error) {
+	cer, err := c.storer.QueryCeremonyByID(ctx, ceremonyID)
+	if err != nil {
+		return user.User{}, fmt.Errorf("query: %w", err)
+	}
+
+	session, err := c.consumeCeremony(ctx, ceremonyID, cer.UserID)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(response))
+	if err != nil {
+		return user.User{}, fmt.Errorf("parse: %w", authenticationFailure(err))
+	}
+
+	var waUsr *webAuthnUser
+	handler := func(rawID []byte, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+
+		waUsr, err = c.queryWebAuthnUser(ctx, userID)
+		return waUsr, err
+	}
+
+	var cred *webauthn.Credential
+	if cer.UserID == uuid.Nil {
+		cred, err = c.webAuthn.ValidateDiscoverableLogin(handler, session, parsed)
+	} else {
+		if _, err := handler(nil, cer.UserID[:]); err != nil {
+			return user.User{}, fmt.Errorf("query: %w", err)
+		}
+		cred, err = c.webAuthn.ValidateLogin(waUsr, session, parsed)
+	}
+	if err != nil {
+		return user.User{}, fmt.Errorf("validatelogin: %w", authenticationFailure(err))
+	}
+
+	// A sign count that doesn't increase means the credential may have been
+	// cloned. Authenticators that don't implement counters always report 0.
+	if cred.Authenticator.CloneWarning {
+		return user.User{}, fmt.Errorf("sign count: %w", ErrAuthenticationFailure)
+	}
+
+	pk, ok := waUsr.passkey(cred.ID)
+	if !ok {
+		return user.User{}, ErrAuthenticationFailure
+	}
+
+	pk.SignCount = cred.Authenticator.SignCount
+	pk.BackupState = cred.Flags.BackupState
+	pk.LastUsedAt = time.Now()
+
+	if err := c.storer.Update(ctx, pk); err != nil {
+		return user.User{}, fmt.Errorf("update: %w", err)
+	}
+
+	return waUsr.usr, nil
+}
+
+// Delete removes the specified passkey.
+func (c *Core) Delete(ctx context.Context, pk Passkey) error {
+	if err := c.storer.Delete(ctx, pk); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByID finds the passkey by the specified ID.
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (Passkey, error) {
+	pk, err := c.storer.QueryByID(ctx, id)
+	if err != nil {
+		return Passkey{}, fmt.Errorf("query: %w", err)
+	}
+
+	return pk, nil
+}
+
+// QueryByUserID retrieves the passkeys registered by the user.
+func (c *Core) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]Passkey, error) {
+	pks, err := c.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return pks, nil
+}
+
+func (c *Core) queryWebAuthnUser(ctx context.Context, userID uuid.UUID) (*webAuthnUser, error) {
+	usr, err := c.usrCore.QueryByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pks, err := c.storer.QueryByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newWebAuthnUser(usr, pks), nil
+}
+
+func (c *Core) createCeremony(ctx context.Context, userID uuid.UUID, session *webauthn.SessionData) (Ceremony, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return Ceremony{}, fmt.Errorf("marshal: %w", err)
+	}
+
+	now := time.Now()
+	cer := Ceremony{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Data:      data,
+		ExpiresAt: now.Add(CeremonyDuration),
+		CreatedAt: now,
+	}
+
+	if err := c.storer.CreateCeremony(ctx, cer); err != nil {
+		return Ceremony{}, fmt.Errorf("createceremony: %w", err)
+	}
+
+	return cer, nil
+}
+
+// consumeCeremony loads the session of a ceremony and deletes it so that it
+// can only be completed once.
+func (c *Core) consumeCeremony(ctx context.Context, id uuid.UUID, userID uuid.UUID) (webauthn.SessionData, error) {
+	var session webauthn.SessionData
+
+	tran := func(s Storer) error {
+		cer, err := s.QueryCeremonyByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if cer.UserID != userID {
+			return ErrCeremonyNotFound
+		}
+
+		if err := s.DeleteCeremony(ctx, cer); err != nil {
+			return fmt.Errorf("deleteceremony: %w", err)
+		}
+
+		if time.Now().After(cer.ExpiresAt) {
+			return ErrCeremonyExpired
+		}
+
+		return json.Unmarshal(cer.Data, &session)
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		if errors.Is(err, ErrCeremonyExpired) {
+			return webauthn.SessionData{}, ErrCeremonyExpired
+		}
+		return webauthn.SessionData{}, fmt.Errorf("tran: %w", err)
+	}
+
+	return session, nil
+}
+
+// authenticationFailure wraps protocol errors so callers can match them
+// while keeping the details for the logs.
+func authenticationFailure(err error) error {
+	var perr *protocol.Error
+	if errors.As(err, &perr) {
+		return fmt.Errorf("%w: %s: %s", ErrAuthenticationFailure, perr.Type, perr.DevInfo)
+	}
+
+	return fmt.Errorf("%w: %s", ErrAuthenticationFailure, err)
+}
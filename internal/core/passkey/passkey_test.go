@@ -0,0 +1,329 @@
+package passkey_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	mockpk "github.com/dudakovict/gotify/internal/core/passkey/mock"
+	"github.com/dudakovict/gotify/internal/core/user"
+	mockusr "github.com/dudakovict/gotify/internal/core/user/mock"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rpID   = "localhost"
+	origin = "http://localhost:3000"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+
+	usr := user.User{
+		ID:    uuid.New(),
+		Email: "john@example.com",
+		Roles: []user.Role{user.RoleUser},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	core, pks, cers := newCore(t, ctrl, usr)
+	auth := newAuthenticator(t)
+
+	creation, ceremonyID, err := core.BeginRegistration(ctx, usr)
+	require.NoError(t, err)
+	require.Len(t, cers, 1)
+
+	body := auth.create(t, creation.Response.Challenge.String())
+
+	pk, err := core.FinishRegistration(ctx, usr, ceremonyID, "Laptop", body)
+	require.NoError(t, err)
+	require.Equal(t, usr.ID, pk.UserID)
+	require.Equal(t, auth.credentialID, pk.CredentialID)
+	require.Len(t, cers, 0)
+
+	// The ceremony is single use.
+	_, err = core.FinishRegistration(ctx, usr, ceremonyID, "Laptop", body)
+	require.ErrorIs(t, err, passkey.ErrCeremonyNotFound)
+
+	// Discoverable login without an email.
+	assertion, ceremonyID, err := core.BeginLogin(ctx, "")
+	require.NoError(t, err)
+
+	body = auth.get(t, assertion.Response.Challenge.String(), usr.ID[:])
+
+	got, err := core.FinishLogin(ctx, ceremonyID, body)
+	require.NoError(t, err)
+	require.Equal(t, usr.ID, got.ID)
+	require.Equal(t, auth.counter, pks[pk.ID].SignCount)
+	require.False(t, pks[pk.ID].LastUsedAt.IsZero())
+
+	// Login by email with a tampered signature.
+	assertion, ceremonyID, err = core.BeginLogin(ctx, usr.Email)
+	require.NoError(t, err)
+
+	other := newAuthenticator(t)
+	other.credentialID = auth.credentialID
+	other.counter = auth.counter
+
+	body = other.get(t, assertion.Response.Challenge.String(), usr.ID[:])
+
+	_, err = core.FinishLogin(ctx, ceremonyID, body)
+	require.ErrorIs(t, err, passkey.ErrAuthenticationFailure)
+
+	// An authenticator whose counter goes backwards may have been cloned.
+	assertion, ceremonyID, err = core.BeginLogin(ctx, usr.Email)
+	require.NoError(t, err)
+
+	auth.counter = 0
+	body = auth.get(t, assertion.Response.Challenge.String(), usr.ID[:])
+
+	_, err = core.FinishLogin(ctx, ceremonyID, body)
+	require.ErrorIs(t, err, passkey.ErrAuthenticationFailure)
+}
+
+func TestFinishLoginExpired(t *testing.T) {
+	ctx := context.Background()
+
+	usr := user.User{
+		ID:    uuid.New(),
+		Email: "john@example.com",
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	core, _, cers := newCore(t, ctrl, usr)
+
+	_, ceremonyID, err := core.BeginLogin(ctx, "")
+	require.NoError(t, err)
+
+	cer := cers[ceremonyID]
+	cer.ExpiresAt = time.Now().Add(-time.Second)
+	cers[ceremonyID] = cer
+
+	_, err = core.FinishLogin(ctx, ceremonyID, nil)
+	require.ErrorIs(t, err, passkey.ErrCeremonyExpired)
+	require.Len(t, cers, 0)
+}
+
+// newCore constructs a core backed by mock storers that keep their state in
+// the returned maps.
+func newCore(t *testing.T, ctrl *gomock.Controller, usr user.User) (*passkey.Core, map[uuid.UUID]passkey.Passkey, map[uuid.UUID]passkey.Ceremony) {
+	pks := make(map[uuid.UUID]passkey.Passkey)
+	cers := make(map[uuid.UUID]passkey.Ceremony)
+
+	storer := mockpk.NewMockStorer(ctrl)
+	storer.EXPECT().
+		WithinTran(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, fn func(passkey.Storer) error) error {
+			return fn(storer)
+		})
+
+	storer.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, pk passkey.Passkey) error {
+			for _, p := range pks {
+				if bytes.Equal(p.CredentialID, pk.CredentialID) {
+					return passkey.ErrUniqueCredential
+				}
+			}
+			pks[pk.ID] = pk
+			return nil
+		})
+
+	storer.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, pk passkey.Passkey) error {
+			pks[pk.ID] = pk
+			return nil
+		})
+
+	storer.EXPECT().
+		QueryByUserID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, userID uuid.UUID) ([]passkey.Passkey, error) {
+			var res []passkey.Passkey
+			for _, pk := range pks {
+				if pk.UserID == userID {
+					res = append(res, pk)
+				}
+			}
+			return res, nil
+		})
+
+	storer.EXPECT().
+		CreateCeremony(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, cer passkey.Ceremony) error {
+			cers[cer.ID] = cer
+			return nil
+		})
+
+	storer.EXPECT().
+		DeleteCeremony(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, cer passkey.Ceremony) error {
+			delete(cers, cer.ID)
+			return nil
+		})
+
+	storer.EXPECT().
+		QueryCeremonyByID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, id uuid.UUID) (passkey.Ceremony, error) {
+			cer, ok := cers[id]
+			if !ok {
+				return passkey.Ceremony{}, passkey.ErrCeremonyNotFound
+			}
+			return cer, nil
+		})
+
+	usrStorer := mockusr.NewMockStorer(ctrl)
+	usrStorer.EXPECT().
+		QueryByID(gomock.Any(), usr.ID).
+		AnyTimes().
+		Return(usr, nil)
+
+	usrStorer.EXPECT().
+		QueryByEmail(gomock.Any(), usr.Email).
+		AnyTimes().
+		Return(usr, nil)
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: "Gotify",
+		RPOrigins:     []string{origin},
+	})
+	require.NoError(t, err)
+
+	return passkey.NewCore(nil, user.NewCore(nil, usrStorer), storer, wa), pks, cers
+}
+
+// authenticator is a software authenticator producing "none" attestations
+// and ES256 assertions.
+type authenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	counter      uint32
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &authenticator{
+		key:          key,
+		credentialID: credentialID,
+	}
+}
+
+func (a *authenticator) create(t *testing.T, challenge string) []byte {
+	clientData := clientDataJSON(t, "webauthn.create", challenge)
+
+	cose, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  1,
+		XCoord: a.key.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	authData := a.authData(0x01 | 0x04 | 0x40)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.credentialID)))
+	authData = append(authData, a.credentialID...)
+	authData = append(authData, cose...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	require.NoError(t, err)
+
+	return marshal(t, map[string]any{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64(clientData),
+			"attestationObject": b64(attestation),
+		},
+	})
+}
+
+func (a *authenticator) get(t *testing.T, challenge string, userHandle []byte) []byte {
+	a.counter++
+
+	clientData := clientDataJSON(t, "webauthn.get", challenge)
+	authData := a.authData(0x01 | 0x04)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return marshal(t, map[string]any{
+		"id":    b64(a.credentialID),
+		"rawId": b64(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64(clientData),
+			"authenticatorData": b64(authData),
+			"signature":         b64(sig),
+			"userHandle":        b64(userHandle),
+		},
+	})
+}
+
+func (a *authenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	authData := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, a.counter)
+}
+
+func clientDataJSON(t *testing.T, typ string, challenge string) []byte {
+	return marshal(t, map[string]any{
+		"type":      typ,
+		"challenge": challenge,
+		"origin":    origin,
+	})
+}
+
+func marshal(t *testing.T, v any) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
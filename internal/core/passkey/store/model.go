@@ -0,0 +1,109 @@
+package passkeydb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type dbPasskey struct {
+	ID              uuid.UUID      `db:"id"`
+	UserID          uuid.UUID      `db:"user_id"`
+	Name            string         `db:"name"`
+	CredentialID    []byte         `db:"credential_id"`
+	PublicKey       []byte         `db:"public_key"`
+	AttestationType string         `db:"attestation_type"`
+	AAGUID          []byte         `db:"aaguid"`
+	SignCount       int64          `db:"sign_count"`
+	Transports      pq.StringArray `db:"transports"`
+	BackupEligible  bool           `db:"backup_eligible"`
+	BackupState     bool           `db:"backup_state"`
+	CreatedAt       time.Time      `db:"created_at"`
+	LastUsedAt      sql.NullTime   `db:"last_used_at"`
+}
+
+func toDBPasskey(pk passkey.Passkey) dbPasskey {
+	return dbPasskey{
+		ID:              pk.ID,
+		UserID:          pk.UserID,
+		Name:            pk.Name,
+		CredentialID:    pk.CredentialID,
+		PublicKey:       pk.PublicKey,
+		AttestationType: pk.AttestationType,
+		AAGUID:          pk.AAGUID,
+		SignCount:       int64(pk.SignCount),
+		Transports:      pk.Transports,
+		BackupEligible:  pk.BackupEligible,
+		BackupState:     pk.BackupState,
+		CreatedAt:       pk.CreatedAt.UTC(),
+		LastUsedAt: sql.NullTime{
+			Time:  pk.LastUsedAt.UTC(),
+			Valid: !pk.LastUsedAt.IsZero(),
+		},
+	}
+}
+
+func toCorePasskey(dbPk dbPasskey) passkey.Passkey {
+	pk := passkey.Passkey{
+		ID:              dbPk.ID,
+		UserID:          dbPk.UserID,
+		Name:            dbPk.Name,
+		CredentialID:    dbPk.CredentialID,
+		PublicKey:       dbPk.PublicKey,
+		AttestationType: dbPk.AttestationType,
+		AAGUID:          dbPk.AAGUID,
+		SignCount:       uint32(dbPk.SignCount),
+		Transports:      dbPk.Transports,
+		BackupEligible:  dbPk.BackupEligible,
+		BackupState:     dbPk.BackupState,
+		CreatedAt:       dbPk.CreatedAt.In(time.Local),
+	}
+
+	if dbPk.LastUsedAt.Valid {
+		pk.LastUsedAt = dbPk.LastUsedAt.Time.In(time.Local)
+	}
+
+	return pk
+}
+
+func toCorePasskeySlice(dbPks []dbPasskey) []passkey.Passkey {
+	pks := make([]passkey.Passkey, len(dbPks))
+	for i, dbPk := range dbPks {
+		pks[i] = toCorePasskey(dbPk)
+	}
+	return pks
+}
+
+type dbCeremony struct {
+	ID        uuid.UUID     `db:"id"`
+	UserID    uuid.NullUUID `db:"user_id"`
+	Data      []byte        `db:"data"`
+	ExpiresAt time.Time     `db:"expires_at"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
+func toDBCeremony(cer passkey.Ceremony) dbCeremony {
+	return dbCeremony{
+		ID: cer.ID,
+		UserID: uuid.NullUUID{
+			UUID:  cer.UserID,
+			Valid: cer.UserID != uuid.Nil,
+		},
+		Data:      cer.Data,
+		ExpiresAt: cer.ExpiresAt.UTC(),
+		CreatedAt: cer.CreatedAt.UTC(),
+	}
+}
+
+func toCoreCeremony(dbCer dbCeremony) passkey.Ceremony {
+	return passkey.Ceremony{
+		ID:        dbCer.ID,
+		UserID:    dbCer.UserID.UUID,
+		Data:      dbCer.Data,
+		ExpiresAt: dbCer.ExpiresAt.In(time.Local),
+		CreatedAt: dbCer.CreatedAt.In(time.Local),
+	}
+}
@@ -0,0 +1,210 @@
+// Package passkeydb contains passkey related CRUD functionality.
+package passkeydb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for passkey database access.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(passkey.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts a new passkey into the database.
+func (s *Store) Create(ctx context.Context, pk passkey.Passkey) error {
+	const q = `
+	INSERT INTO passkeys
+		(id, user_id, name, credential_id, public_key, attestation_type, aaguid, sign_count, transports, backup_eligible, backup_state, created_at, last_used_at)
+	VALUES
+		(:id, :user_id, :name, :credential_id, :public_key, :attestation_type, :aaguid, :sign_count, :transports, :backup_eligible, :backup_state, :created_at, :last_used_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBPasskey(pk)); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return passkey.ErrUniqueCredential
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces the mutable state of a passkey in the database.
+func (s *Store) Update(ctx context.Context, pk passkey.Passkey) error {
+	const q = `
+	UPDATE
+		passkeys
+	SET
+		name = :name,
+		sign_count = :sign_count,
+		backup_state = :backup_state,
+		last_used_at = :last_used_at
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBPasskey(pk)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a passkey from the database.
+func (s *Store) Delete(ctx context.Context, pk passkey.Passkey) error {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: pk.ID,
+	}
+
+	const q = `
+	DELETE FROM
+		passkeys
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryByID gets the specified passkey from the database.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (passkey.Passkey, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM passkeys WHERE id = :id`
+
+	var dbPk dbPasskey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbPk); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return passkey.Passkey{}, passkey.ErrNotFound
+		}
+		return passkey.Passkey{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCorePasskey(dbPk), nil
+}
+
+// QueryByUserID retrieves the passkeys of the specified user from the database.
+func (s *Store) QueryByUserID(ctx context.Context, userID uuid.UUID) ([]passkey.Passkey, error) {
+	data := struct {
+		UserID uuid.UUID `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		passkeys
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		created_at`
+
+	var dbPks []dbPasskey
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbPks); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCorePasskeySlice(dbPks), nil
+}
+
+// CreateCeremony inserts a new ceremony into the database.
+func (s *Store) CreateCeremony(ctx context.Context, cer passkey.Ceremony) error {
+	const q = `
+	INSERT INTO webauthn_ceremonies
+		(id, user_id, data, expires_at, created_at)
+	VALUES
+		(:id, :user_id, :data, :expires_at, :created_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBCeremony(cer)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCeremony removes a ceremony from the database.
+func (s *Store) DeleteCeremony(ctx context.Context, cer passkey.Ceremony) error {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: cer.ID,
+	}
+
+	const q = `
+	DELETE FROM
+		webauthn_ceremonies
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryCeremonyByID gets the specified ceremony from the database.
+func (s *Store) QueryCeremonyByID(ctx context.Context, id uuid.UUID) (passkey.Ceremony, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM webauthn_ceremonies WHERE id = :id`
+
+	var dbCer dbCeremony
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbCer); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return passkey.Ceremony{}, passkey.ErrCeremonyNotFound
+		}
+		return passkey.Ceremony{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreCeremony(dbCer), nil
+}
@@ -0,0 +1,82 @@
+package passkey
+
+import (
+	"bytes"
+
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// webAuthnUser adapts a user and its passkeys to the webauthn.User interface.
+type webAuthnUser struct {
+	usr      user.User
+	passkeys []Passkey
+}
+
+func newWebAuthnUser(usr user.User, pks []Passkey) *webAuthnUser {
+	return &webAuthnUser{
+		usr:      usr,
+		passkeys: pks,
+	}
+}
+
+// WebAuthnID returns the user handle, which is the raw user ID.
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.usr.ID[:]
+}
+
+// WebAuthnName returns the user's email.
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.usr.Email
+}
+
+// WebAuthnDisplayName returns the user's email as users have no display name.
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.usr.Email
+}
+
+// WebAuthnIcon is deprecated by the specification and left empty.
+func (u *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnCredentials returns the user's passkeys as webauthn credentials.
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, len(u.passkeys))
+	for i, pk := range u.passkeys {
+		transports := make([]protocol.AuthenticatorTransport, len(pk.Transports))
+		for j, t := range pk.Transports {
+			transports[j] = protocol.AuthenticatorTransport(t)
+		}
+
+		creds[i] = webauthn.Credential{
+			ID:              pk.CredentialID,
+			PublicKey:       pk.PublicKey,
+			AttestationType: pk.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   true,
+				BackupEligible: pk.BackupEligible,
+				BackupState:    pk.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    pk.AAGUID,
+				SignCount: pk.SignCount,
+			},
+		}
+	}
+
+	return creds
+}
+
+func (u *webAuthnUser) passkey(credentialID []byte) (Passkey, bool) {
+	for _, pk := range u.passkeys {
+		if bytes.Equal(pk.CredentialID, credentialID) {
+			return pk, true
+		}
+	}
+
+	return Passkey{}, false
+}
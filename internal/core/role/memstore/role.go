@@ -0,0 +1,190 @@
+// Package rolemem contains role related CRUD functionality, kept in memory.
+package rolemem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table      = "roles"
+	tableUsers = "users"
+)
+
+// builtIn lists the roles the migrations seed the database with.
+var builtIn = []role.Role{
+	{
+		Name:        "ADMIN",
+		Description: "Manages every resource in the system.",
+		Permissions: []role.Permission{
+			role.PermTopicCreate, role.PermTopicRead, role.PermNotificationCreate, role.PermNotificationRead,
+			role.PermNotificationDelete, role.PermSubscriptionCreate, role.PermSubscriptionRead,
+			role.PermSubscriptionDelete, role.PermSubscriptionManage, role.PermUserCreate, role.PermUserRead,
+			role.PermUserUpdate, role.PermUserDelete, role.PermRoleRead, role.PermRoleManage,
+			role.PermTopicManage, role.PermGroupRead, role.PermGroupManage, role.PermMFARequired,
+		},
+		BuiltIn: true,
+	},
+	{
+		Name:        "USER",
+		Description: "Publishes notifications and manages their own subscriptions.",
+		Permissions: []role.Permission{
+			role.PermTopicCreate, role.PermTopicRead, role.PermNotificationCreate, role.PermNotificationRead,
+			role.PermSubscriptionCreate, role.PermSubscriptionRead, role.PermSubscriptionDelete,
+			role.PermGroupRead,
+		},
+		BuiltIn: true,
+	},
+}
+
+// Store manages the set of APIs for role access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access. The database is seeded with
+// the built-in roles the first time.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	now := time.Now()
+	for _, r := range builtIn {
+		r.Permissions = slices.Clone(r.Permissions)
+		r.Version = 1
+		r.CreatedAt = now
+		r.UpdatedAt = now
+
+		// The roles are already there when the database was seeded before.
+		_ = memdb.Insert(db, table, r.Name, r)
+	}
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(role.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new role into the database.
+func (s *Store) Create(ctx context.Context, r role.Role) error {
+	if err := memdb.Insert(s.db, table, r.Name, clone(r)); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return role.ErrUniqueName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update replaces a role in the database, as long as it's still at the
+// version of the role.
+func (s *Store) Update(ctx context.Context, r role.Role) error {
+	stored, exists := memdb.Get[role.Role](s.db, table, r.Name)
+	if !exists || stored.Version != r.Version {
+		return role.ErrVersionConflict
+	}
+
+	stored.Description = r.Description
+	stored.Permissions = slices.Clone(r.Permissions)
+	stored.UpdatedAt = r.UpdatedAt
+	stored.Version++
+
+	return memdb.Put(s.db, table, stored.Name, stored)
+}
+
+// Delete removes a role from the database, as long as it's still at the
+// version of the role.
+func (s *Store) Delete(ctx context.Context, r role.Role) error {
+	stored, exists := memdb.Get[role.Role](s.db, table, r.Name)
+	if !exists || stored.Version != r.Version {
+		return role.ErrVersionConflict
+	}
+
+	memdb.Delete(s.db, table, r.Name)
+
+	return nil
+}
+
+// Query retrieves all roles from the database.
+func (s *Store) Query(ctx context.Context) ([]role.Role, error) {
+	return s.query(nil), nil
+}
+
+// QueryByName gets the specified role from the database.
+func (s *Store) QueryByName(ctx context.Context, name string) (role.Role, error) {
+	r, exists := memdb.Get[role.Role](s.db, table, name)
+	if !exists {
+		return role.Role{}, role.ErrNotFound
+	}
+
+	return clone(r), nil
+}
+
+// QueryByNames retrieves the roles with the specified names from the
+// database. Names without a role are skipped.
+func (s *Store) QueryByNames(ctx context.Context, names []string) ([]role.Role, error) {
+	return s.query(func(r role.Role) bool {
+		return slices.Contains(names, r.Name)
+	}), nil
+}
+
+// CountUsers returns how many users the role is assigned to.
+func (s *Store) CountUsers(ctx context.Context, name string) (int, error) {
+	usrs := memdb.Rows(s.db, tableUsers, func(usr user.User) bool {
+		return slices.ContainsFunc(usr.Roles, func(r user.Role) bool {
+			return r.Name() == name
+		})
+	})
+
+	return len(usrs), nil
+}
+
+// =============================================================================
+
+// query returns the roles the keep function keeps, ordered by name.
+func (s *Store) query(keep func(r role.Role) bool) []role.Role {
+	rs := memdb.Rows(s.db, table, keep)
+
+	slices.SortFunc(rs, func(a, b role.Role) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for i := range rs {
+		rs[i] = clone(rs[i])
+	}
+
+	return rs
+}
+
+// clone copies the role, so the stored role shares no memory with the
+// callers.
+func clone(r role.Role) role.Role {
+	r.Permissions = slices.Clone(r.Permissions)
+	return r
+}
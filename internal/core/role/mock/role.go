@@ -0,0 +1,152 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dudakovict/gotify/internal/core/role (interfaces: Storer)
+
+// Package mockrole is a generated GoMock package.
+package mockrole
+
+import (
+	context "context"
+	reflect "reflect"
+
+	role "github.com/dudakovict/gotify/internal/core/role"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockStorer is a mock of Storer interface.
+type MockStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorerMockRecorder
+}
+
+// MockStorerMockRecorder is the mock recorder for MockStorer.
+type MockStorerMockRecorder struct {
+	mock *MockStorer
+}
+
+// NewMockStorer creates a new mock instance.
+func NewMockStorer(ctrl *gomock.Controller) *MockStorer {
+	mock := &MockStorer{ctrl: ctrl}
+	mock.recorder = &MockStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
+	return m.recorder
+}
+
+// CountUsers mocks base method.
+func (m *MockStorer) CountUsers(arg0 context.Context, arg1 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsers indicates an expected call of CountUsers.
+func (mr *MockStorerMockRecorder) CountUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockStorer)(nil).CountUsers), arg0, arg1)
+}
+
+// Create mocks base method.
+func (m *MockStorer) Create(arg0 context.Context, arg1 role.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockStorer) Delete(arg0 context.Context, arg1 role.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// Query mocks base method.
+func (m *MockStorer) Query(arg0 context.Context) ([]role.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Query", arg0)
+	ret0, _ := ret[0].([]role.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockStorerMockRecorder) Query(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0)
+}
+
+// QueryByName mocks base method.
+func (m *MockStorer) QueryByName(arg0 context.Context, arg1 string) (role.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByName", arg0, arg1)
+	ret0, _ := ret[0].(role.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByName indicates an expected call of QueryByName.
+func (mr *MockStorerMockRecorder) QueryByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByName", reflect.TypeOf((*MockStorer)(nil).QueryByName), arg0, arg1)
+}
+
+// QueryByNames mocks base method.
+func (m *MockStorer) QueryByNames(arg0 context.Context, arg1 []string) ([]role.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByNames", arg0, arg1)
+	ret0, _ := ret[0].([]role.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByNames indicates an expected call of QueryByNames.
+func (mr *MockStorerMockRecorder) QueryByNames(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByNames", reflect.TypeOf((*MockStorer)(nil).QueryByNames), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockStorer) Update(arg0 context.Context, arg1 role.Role) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorerMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1)
+}
+
+// WithinTran mocks base method.
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(role.Storer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTran indicates an expected call of WithinTran.
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
+}
@@ -0,0 +1,27 @@
+package role
+
+import "time"
+
+// Role represents a named set of permissions which can be assigned to users.
+type Role struct {
+	Name        string
+	Description string
+	Permissions []Permission
+	BuiltIn     bool
+	Version     int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// NewRole contains information needed to create a new role.
+type NewRole struct {
+	Name        string
+	Description string
+	Permissions []Permission
+}
+
+// UpdateRole contains information needed to update a role.
+type UpdateRole struct {
+	Description *string
+	Permissions []Permission
+}
@@ -0,0 +1,116 @@
+package role
+
+import "fmt"
+
+// Set of possible permissions a role can grant.
+var (
+	PermTopicCreate        = Permission{"topic:create"}
+	PermTopicRead          = Permission{"topic:read"}
+	PermTopicManage        = Permission{"topic:manage"}
+	PermNotificationCreate = Permission{"notification:create"}
+	PermNotificationRead   = Permission{"notification:read"}
+	PermNotificationDelete = Permission{"notification:delete"}
+	PermSubscriptionCreate = Permission{"subscription:create"}
+	PermSubscriptionRead   = Permission{"subscription:read"}
+	PermSubscriptionDelete = Permission{"subscription:delete"}
+	PermSubscriptionManage = Permission{"subscription:manage"}
+	PermUserCreate         = Permission{"user:create"}
+	PermUserRead           = Permission{"user:read"}
+	PermUserUpdate         = Permission{"user:update"}
+	PermUserDelete         = Permission{"user:delete"}
+	PermRoleRead           = Permission{"role:read"}
+	PermRoleManage         = Permission{"role:manage"}
+	PermGroupRead          = Permission{"group:read"}
+	PermGroupManage        = Permission{"group:manage"}
+	PermMFARequired        = Permission{"mfa:required"}
+)
+
+// Set of known permissions.
+var permissions = map[string]Permission{
+	PermTopicCreate.name:        PermTopicCreate,
+	PermTopicRead.name:          PermTopicRead,
+	PermTopicManage.name:        PermTopicManage,
+	PermNotificationCreate.name: PermNotificationCreate,
+	PermNotificationRead.name:   PermNotificationRead,
+	PermNotificationDelete.name: PermNotificationDelete,
+	PermSubscriptionCreate.name: PermSubscriptionCreate,
+	PermSubscriptionRead.name:   PermSubscriptionRead,
+	PermSubscriptionDelete.name: PermSubscriptionDelete,
+	PermSubscriptionManage.name: PermSubscriptionManage,
+	PermUserCreate.name:         PermUserCreate,
+	PermUserRead.name:           PermUserRead,
+	PermUserUpdate.name:         PermUserUpdate,
+	PermUserDelete.name:         PermUserDelete,
+	PermRoleRead.name:           PermRoleRead,
+	PermRoleManage.name:         PermRoleManage,
+	PermGroupRead.name:          PermGroupRead,
+	PermGroupManage.name:        PermGroupManage,
+	PermMFARequired.name:        PermMFARequired,
+}
+
+// Permission represents an operation a role can be granted.
+type Permission struct {
+	name string
+}
+
+// ParsePermission parses the string value and returns a permission if one
+// exists.
+func ParsePermission(value string) (Permission, error) {
+	perm, exists := permissions[value]
+	if !exists {
+		return Permission{}, fmt.Errorf("%w %q", ErrInvalidPermission, value)
+	}
+
+	return perm, nil
+}
+
+// MustParsePermission parses the string value and returns a permission if one
+// exists. If an error occurs the function panics.
+func MustParsePermission(value string) Permission {
+	perm, err := ParsePermission(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return perm
+}
+
+// Name returns the name of the permission.
+func (p Permission) Name() string {
+	return p.name
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (p *Permission) UnmarshalText(data []byte) error {
+	perm, err := ParsePermission(string(data))
+	if err != nil {
+		return err
+	}
+
+	p.name = perm.name
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (p Permission) MarshalText() ([]byte, error) {
+	return []byte(p.name), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (p Permission) Equal(p2 Permission) bool {
+	return p.name == p2.name
+}
+
+// Set is a set of permissions granted by one or more roles.
+type Set map[Permission]struct{}
+
+// Has reports whether all the permissions are in the set.
+func (s Set) Has(perms ...Permission) bool {
+	for _, perm := range perms {
+		if _, exists := s[perm]; !exists {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,211 @@
+// Package role provides a core business API.
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/rs/zerolog"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrNotFound          = errors.New("role not found")
+	ErrUniqueName        = errors.New("role name is not unique")
+	ErrInvalidPermission = errors.New("invalid permission")
+	ErrBuiltIn           = errors.New("built-in roles cannot be changed")
+	ErrInUse             = errors.New("role is assigned to users")
+	ErrVersionConflict   = errors.New("role was changed by someone else")
+)
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, r Role) error
+	Update(ctx context.Context, r Role) error
+	Delete(ctx context.Context, r Role) error
+	Query(ctx context.Context) ([]Role, error)
+	QueryByName(ctx context.Context, name string) (Role, error)
+	QueryByNames(ctx context.Context, names []string) ([]Role, error)
+	CountUsers(ctx context.Context, name string) (int, error)
+}
+
+// Core manages the set of APIs for role access.
+type Core struct {
+	log    *zerolog.Logger
+	storer Storer
+}
+
+// NewCore constructs a role core API for use.
+func NewCore(log *zerolog.Logger, storer Storer) *Core {
+	return &Core{
+		log:    log,
+		storer: storer,
+	}
+}
+
+// Create adds a new role to the system.
+func (c *Core) Create(ctx context.Context, nr NewRole) (Role, error) {
+	if _, err := user.ParseRole(nr.Name); err != nil {
+		return Role{}, err
+	}
+
+	now := time.Now()
+	r := Role{
+		Name:        nr.Name,
+		Description: nr.Description,
+		Permissions: compact(nr.Permissions),
+		Version:     1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := c.storer.Create(ctx, r); err != nil {
+		return Role{}, fmt.Errorf("create: %w", err)
+	}
+
+	return r, nil
+}
+
+// Update modifies information about a role. Built-in roles are required for
+// the system to work and can't be modified. It fails with ErrVersionConflict
+// when the role was changed since it was read.
+func (c *Core) Update(ctx context.Context, r Role, ur UpdateRole) (Role, error) {
+	if r.BuiltIn {
+		return Role{}, ErrBuiltIn
+	}
+
+	if ur.Description != nil {
+		r.Description = *ur.Description
+	}
+
+	if ur.Permissions != nil {
+		r.Permissions = compact(ur.Permissions)
+	}
+
+	r.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, r); err != nil {
+		return Role{}, fmt.Errorf("update: %w", err)
+	}
+	r.Version++
+
+	return r, nil
+}
+
+// Delete removes a role from the system. Roles still assigned to users can't
+// be deleted. It fails with ErrVersionConflict when the role was changed since
+// it was read.
+func (c *Core) Delete(ctx context.Context, r Role) error {
+	if r.BuiltIn {
+		return ErrBuiltIn
+	}
+
+	tran := func(s Storer) error {
+		count, err := s.CountUsers(ctx, r.Name)
+		if err != nil {
+			return fmt.Errorf("countusers: %w", err)
+		}
+
+		if count > 0 {
+			return ErrInUse
+		}
+
+		if err := s.Delete(ctx, r); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		for _, target := range []error{ErrInUse, ErrVersionConflict} {
+			if errors.Is(err, target) {
+				return target
+			}
+		}
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves all roles from the system.
+func (c *Core) Query(ctx context.Context) ([]Role, error) {
+	roles, err := c.storer.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return roles, nil
+}
+
+// QueryByName finds the role by the specified name.
+func (c *Core) QueryByName(ctx context.Context, name string) (Role, error) {
+	r, err := c.storer.QueryByName(ctx, name)
+	if err != nil {
+		return Role{}, fmt.Errorf("query: name[%s]: %w", name, err)
+	}
+
+	return r, nil
+}
+
+// Check makes sure that every one of the roles exists so they can be assigned
+// to a user.
+func (c *Core) Check(ctx context.Context, names []string) error {
+	roles, err := c.storer.QueryByNames(ctx, names)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+
+	for _, name := range names {
+		found := slices.ContainsFunc(roles, func(r Role) bool {
+			return r.Name == name
+		})
+
+		if !found {
+			return fmt.Errorf("%w %q", ErrNotFound, name)
+		}
+	}
+
+	return nil
+}
+
+// Permissions returns the union of the permissions granted by the roles.
+// Unknown roles don't grant anything.
+func (c *Core) Permissions(ctx context.Context, names []string) (Set, error) {
+	set := make(Set)
+	if len(names) == 0 {
+		return set, nil
+	}
+
+	roles, err := c.storer.QueryByNames(ctx, names)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	for _, r := range roles {
+		for _, perm := range r.Permissions {
+			set[perm] = struct{}{}
+		}
+	}
+
+	return set, nil
+}
+
+// compact removes duplicated permissions while keeping their order.
+func compact(perms []Permission) []Permission {
+	result := make([]Permission, 0, len(perms))
+	for _, perm := range perms {
+		if !slices.Contains(result, perm) {
+			result = append(result, perm)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,138 @@
+package role_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dudakovict/gotify/internal/core/role"
+	mockrole "github.com/dudakovict/gotify/internal/core/role/mock"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissions(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	names := []string{"PUBLISHER", "AUDITOR"}
+
+	storer := mockrole.NewMockStorer(ctrl)
+	storer.EXPECT().
+		QueryByNames(gomock.Any(), names).
+		Times(1).
+		Return([]role.Role{
+			{Name: "AUDITOR", Permissions: []role.Permission{role.PermNotificationRead}},
+			{Name: "PUBLISHER", Permissions: []role.Permission{role.PermNotificationCreate, role.PermNotificationRead}},
+		}, nil)
+
+	core := role.NewCore(nil, storer)
+
+	set, err := core.Permissions(ctx, names)
+	require.NoError(t, err)
+	require.Len(t, set, 2)
+	require.True(t, set.Has(role.PermNotificationCreate, role.PermNotificationRead))
+	require.False(t, set.Has(role.PermNotificationDelete))
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mockrole.NewMockStorer(ctrl)
+	storer.EXPECT().
+		QueryByNames(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return([]role.Role{{Name: user.RoleUser.Name()}}, nil)
+
+	core := role.NewCore(nil, storer)
+
+	require.NoError(t, core.Check(ctx, []string{user.RoleUser.Name()}))
+	require.ErrorIs(t, core.Check(ctx, []string{user.RoleUser.Name(), "MISSING"}), role.ErrNotFound)
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mockrole.NewMockStorer(ctrl)
+	storer.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, r role.Role) error {
+			require.Equal(t, []role.Permission{role.PermTopicRead}, r.Permissions)
+			require.False(t, r.BuiltIn)
+			return nil
+		})
+
+	core := role.NewCore(nil, storer)
+
+	_, err := core.Create(ctx, role.NewRole{
+		Name:        "READER",
+		Permissions: []role.Permission{role.PermTopicRead, role.PermTopicRead},
+	})
+	require.NoError(t, err)
+
+	_, err = core.Create(ctx, role.NewRole{Name: "reader"})
+	require.ErrorIs(t, err, user.ErrInvalidRole)
+}
+
+func TestBuiltIn(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mockrole.NewMockStorer(ctrl)
+	storer.EXPECT().Update(gomock.Any(), gomock.Any()).Times(0)
+	storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+	core := role.NewCore(nil, storer)
+
+	r := role.Role{Name: user.RoleAdmin.Name(), BuiltIn: true}
+
+	_, err := core.Update(ctx, r, role.UpdateRole{Permissions: []role.Permission{}})
+	require.ErrorIs(t, err, role.ErrBuiltIn)
+	require.ErrorIs(t, core.Delete(ctx, r), role.ErrBuiltIn)
+}
+
+func TestDeleteInUse(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r := role.Role{Name: "PUBLISHER"}
+
+	storer := mockrole.NewMockStorer(ctrl)
+	storer.EXPECT().
+		WithinTran(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, fn func(role.Storer) error) error {
+			return fn(storer)
+		})
+	storer.EXPECT().
+		CountUsers(gomock.Any(), r.Name).
+		Times(1).
+		Return(2, nil)
+	storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+
+	core := role.NewCore(nil, storer)
+
+	require.ErrorIs(t, core.Delete(ctx, r), role.ErrInUse)
+}
+
+func TestParsePermission(t *testing.T) {
+	perm, err := role.ParsePermission("topic:create")
+	require.NoError(t, err)
+	require.Equal(t, role.PermTopicCreate, perm)
+
+	_, err = role.ParsePermission("topic:destroy")
+	require.ErrorIs(t, err, role.ErrInvalidPermission)
+}
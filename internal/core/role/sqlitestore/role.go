@@ -0,0 +1,213 @@
+// Package rolelite contains role related CRUD functionality, stored in SQLite.
+package rolelite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for role access in SQLite.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(role.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts a new role into the database.
+func (s *Store) Create(ctx context.Context, r role.Role) error {
+	const q = `
+	INSERT INTO roles
+		(name, description, permissions, built_in, version, created_at, updated_at)
+	VALUES
+		(:name, :description, :permissions, :built_in, :version, :created_at, :updated_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBRole(r)); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return role.ErrUniqueName
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a role in the database, as long as it's still at the
+// version of the role.
+func (s *Store) Update(ctx context.Context, r role.Role) error {
+	const q = `
+	UPDATE
+		roles
+	SET
+		description = :description,
+		permissions = :permissions,
+		updated_at = :updated_at,
+		version = version + 1
+	WHERE
+		name = :name
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, toDBRole(r))
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return role.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Delete removes a role from the database, as long as it's still at the
+// version of the role.
+func (s *Store) Delete(ctx context.Context, r role.Role) error {
+	data := struct {
+		Name    string `db:"name"`
+		Version int    `db:"version"`
+	}{
+		Name:    r.Name,
+		Version: r.Version,
+	}
+
+	const q = `
+	DELETE FROM
+		roles
+	WHERE
+		name = :name
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return role.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Query retrieves all roles from the database.
+func (s *Store) Query(ctx context.Context) ([]role.Role, error) {
+	const q = `
+	SELECT
+		*
+	FROM
+		roles
+	ORDER BY
+		name`
+
+	var dbRs []dbRole
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, struct{}{}, &dbRs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreRoleSlice(dbRs)
+}
+
+// QueryByName gets the specified role from the database.
+func (s *Store) QueryByName(ctx context.Context, name string) (role.Role, error) {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `SELECT * FROM roles WHERE name = :name`
+
+	var dbR dbRole
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbR); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return role.Role{}, role.ErrNotFound
+		}
+		return role.Role{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreRole(dbR)
+}
+
+// QueryByNames retrieves the roles with the specified names from the
+// database. Names without a role are skipped.
+func (s *Store) QueryByNames(ctx context.Context, names []string) ([]role.Role, error) {
+	data := struct {
+		Names database.JSONArray `db:"names"`
+	}{
+		Names: names,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		roles
+	WHERE
+		name IN (SELECT value FROM json_each(:names))
+	ORDER BY
+		name`
+
+	var dbRs []dbRole
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbRs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreRoleSlice(dbRs)
+}
+
+// CountUsers returns how many users the role is assigned to.
+func (s *Store) CountUsers(ctx context.Context, name string) (int, error) {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `
+	SELECT
+		count(*) AS count
+	FROM
+		users
+	WHERE
+		EXISTS (SELECT 1 FROM json_each(roles) WHERE value = :name)`
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &count); err != nil {
+		return 0, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return count.Count, nil
+}
@@ -0,0 +1,73 @@
+package roledb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/lib/pq"
+)
+
+type dbRole struct {
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Permissions pq.StringArray `db:"permissions"`
+	BuiltIn     bool           `db:"built_in"`
+	Version     int            `db:"version"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+func toDBRole(r role.Role) dbRole {
+	perms := make([]string, len(r.Permissions))
+	for i, perm := range r.Permissions {
+		perms[i] = perm.Name()
+	}
+
+	return dbRole{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: perms,
+		BuiltIn:     r.BuiltIn,
+		Version:     r.Version,
+		CreatedAt:   r.CreatedAt.UTC(),
+		UpdatedAt:   r.UpdatedAt.UTC(),
+	}
+}
+
+func toCoreRole(dbR dbRole) (role.Role, error) {
+	perms := make([]role.Permission, len(dbR.Permissions))
+	for i, value := range dbR.Permissions {
+		var err error
+		perms[i], err = role.ParsePermission(value)
+		if err != nil {
+			return role.Role{}, fmt.Errorf("parse permission: %w", err)
+		}
+	}
+
+	r := role.Role{
+		Name:        dbR.Name,
+		Description: dbR.Description,
+		Permissions: perms,
+		BuiltIn:     dbR.BuiltIn,
+		Version:     dbR.Version,
+		CreatedAt:   dbR.CreatedAt.In(time.Local),
+		UpdatedAt:   dbR.UpdatedAt.In(time.Local),
+	}
+
+	return r, nil
+}
+
+func toCoreRoleSlice(dbRs []dbRole) ([]role.Role, error) {
+	roles := make([]role.Role, len(dbRs))
+
+	for i, dbR := range dbRs {
+		var err error
+		roles[i], err = toCoreRole(dbR)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
@@ -0,0 +1,71 @@
+// Package sessionmem contains session related CRUD functionality, kept in
+// memory.
+package sessionmem
+
+import (
+	"context"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/session"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const table = "sessions"
+
+// Store manages the set of APIs for session access in memory.
+type Store struct {
+	log *zerolog.Logger
+	db  memdb.Querier
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Reference("sessions_user_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(session.Session).UserID.String() == key },
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new session into the database.
+func (s *Store) Create(ctx context.Context, sessn session.Session) error {
+	if sessn.CreatedAt.IsZero() {
+		sessn.CreatedAt = time.Now()
+	}
+
+	return memdb.Insert(s.db, table, sessn.ID.String(), sessn)
+}
+
+// QueryByID gets the specified session from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (session.Session, error) {
+	sessn, exists := memdb.Get[session.Session](s.db, table, id.String())
+	if !exists {
+		return session.Session{}, session.ErrNotFound
+	}
+
+	return sessn, nil
+}
+
+// PurgeExpired removes up to limit sessions that expired before the given time from the database.
+func (s *Store) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	sessns := memdb.Rows(s.db, table, func(sessn session.Session) bool {
+		return sessn.ExpiresAt.Before(before)
+	})
+
+	if len(sessns) > limit {
+		sessns = sessns[:limit]
+	}
+
+	for _, sessn := range sessns {
+		memdb.Delete(s.db, table, sessn.ID.String())
+	}
+
+	return len(sessns), nil
+}
@@ -5,7 +5,9 @@
 package mocksessn
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	session "github.com/dudakovict/gotify/internal/core/session"
 	gomock "github.com/golang/mock/gomock"
@@ -36,30 +38,45 @@ func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockStorer) Create(arg0 session.Session) error {
+func (m *MockStorer) Create(arg0 context.Context, arg1 session.Session) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockStorerMockRecorder) Create(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
+}
+
+// PurgeExpired mocks base method.
+func (m *MockStorer) PurgeExpired(arg0 context.Context, arg1 time.Time, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeExpired", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeExpired indicates an expected call of PurgeExpired.
+func (mr *MockStorerMockRecorder) PurgeExpired(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeExpired", reflect.TypeOf((*MockStorer)(nil).PurgeExpired), arg0, arg1, arg2)
 }
 
 // QueryByID mocks base method.
-func (m *MockStorer) QueryByID(arg0 uuid.UUID) (session.Session, error) {
+func (m *MockStorer) QueryByID(arg0 context.Context, arg1 uuid.UUID) (session.Session, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryByID", arg0)
+	ret := m.ctrl.Call(m, "QueryByID", arg0, arg1)
 	ret0, _ := ret[0].(session.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryByID indicates an expected call of QueryByID.
-func (mr *MockStorerMockRecorder) QueryByID(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) QueryByID(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0, arg1)
 }
@@ -2,8 +2,10 @@
 package session
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -17,8 +19,9 @@ var (
 // Storer interface declares the behavior this package needs to perist and
 // retrieve data.
 type Storer interface {
-	Create(sessn Session) error
-	QueryByID(id uuid.UUID) (Session, error)
+	Create(ctx context.Context, sessn Session) error
+	QueryByID(ctx context.Context, id uuid.UUID) (Session, error)
+	PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error)
 }
 
 // Core manages the set of APIs for session access.
@@ -36,8 +39,8 @@ func NewCore(log *zerolog.Logger, storer Storer) *Core {
 }
 
 // Create adds a new session to the system.
-func (c *Core) Create(sessn Session) (Session, error) {
-	if err := c.storer.Create(sessn); err != nil {
+func (c *Core) Create(ctx context.Context, sessn Session) (Session, error) {
+	if err := c.storer.Create(ctx, sessn); err != nil {
 		return Session{}, fmt.Errorf("create: %w", err)
 	}
 
@@ -45,11 +48,22 @@ func (c *Core) Create(sessn Session) (Session, error) {
 }
 
 // QueryByID finds the session by the specified ID.
-func (c *Core) QueryByID(id uuid.UUID) (Session, error) {
-	sessn, err := c.storer.QueryByID(id)
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (Session, error) {
+	sessn, err := c.storer.QueryByID(ctx, id)
 	if err != nil {
 		return Session{}, fmt.Errorf("query: %w", err)
 	}
 
 	return sessn, nil
 }
+
+// PurgeExpired removes up to limit sessions that expired before the given
+// time and returns how many were removed.
+func (c *Core) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	n, err := c.storer.PurgeExpired(ctx, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purgeexpired: %w", err)
+	}
+
+	return n, nil
+}
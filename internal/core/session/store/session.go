@@ -2,8 +2,10 @@
 package sessiondb
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dudakovict/gotify/internal/core/session"
 	"github.com/dudakovict/gotify/platform/database"
@@ -15,7 +17,7 @@ import (
 // Store manages the set of APIs for session database access.
 type Store struct {
 	log *zerolog.Logger
-	db  sqlx.Ext
+	db  sqlx.ExtContext
 }
 
 // NewStore constructs the api for data access.
@@ -27,14 +29,14 @@ func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
 }
 
 // Create inserts a new session into the database.
-func (s *Store) Create(sessn session.Session) error {
+func (s *Store) Create(ctx context.Context, sessn session.Session) error {
 	const q = `
 	INSERT INTO sessions
 		(id, user_id, refresh_token, user_agent, client_ip, is_blocked, expires_at)
 	VALUES
 		(:id, :user_id, :refresh_token, :user_agent, :client_ip, :is_blocked, :expires_at)`
 
-	if err := database.NamedExec(s.log, s.db, q, toDBSession(sessn)); err != nil {
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBSession(sessn)); err != nil {
 		return fmt.Errorf("creating session: %w", err)
 	}
 
@@ -42,7 +44,7 @@ func (s *Store) Create(sessn session.Session) error {
 }
 
 // QueryByID gets the specified session from the database by id.
-func (s *Store) QueryByID(id uuid.UUID) (session.Session, error) {
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (session.Session, error) {
 	data := struct {
 		ID uuid.UUID `db:"id"`
 	}{
@@ -52,7 +54,7 @@ func (s *Store) QueryByID(id uuid.UUID) (session.Session, error) {
 	const q = `SELECT * FROM sessions WHERE id = :id`
 
 	var dbSessn dbSession
-	if err := database.NamedQueryStruct(s.log, s.db, q, data, &dbSessn); err != nil {
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSessn); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return session.Session{}, session.ErrNotFound
 		}
@@ -61,3 +63,27 @@ func (s *Store) QueryByID(id uuid.UUID) (session.Session, error) {
 
 	return toCoreSession(dbSessn), nil
 }
+
+// PurgeExpired removes up to limit sessions that expired before the given time from the database.
+func (s *Store) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+		Limit  int       `db:"limit"`
+	}{
+		Before: before.UTC(),
+		Limit:  limit,
+	}
+
+	const q = `
+	DELETE FROM
+		sessions
+	WHERE
+		id IN (SELECT id FROM sessions WHERE expires_at < :before LIMIT :limit)`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,98 @@
+// Package ssomem contains sso related CRUD functionality, kept in memory.
+package ssomem
+
+import (
+	"context"
+
+	"github.com/dudakovict/gotify/internal/core/sso"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/rs/zerolog"
+)
+
+const (
+	tableStates     = "oidc_states"
+	tableIdentities = "user_identities"
+)
+
+// Store manages the set of APIs for sso access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Reference("user_identities_user_id_fkey", memdb.Reference{
+		Table:  tableIdentities,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(sso.Identity).UserID.String() == key },
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(sso.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// CreateState inserts a new authorization state into the database.
+func (s *Store) CreateState(ctx context.Context, st sso.State) error {
+	return memdb.Insert(s.db, tableStates, st.ID, st)
+}
+
+// DeleteState removes an authorization state from the database.
+func (s *Store) DeleteState(ctx context.Context, st sso.State) error {
+	memdb.Delete(s.db, tableStates, st.ID)
+
+	return nil
+}
+
+// QueryStateByID gets the specified authorization state from the database.
+func (s *Store) QueryStateByID(ctx context.Context, id string) (sso.State, error) {
+	st, exists := memdb.Get[sso.State](s.db, tableStates, id)
+	if !exists {
+		return sso.State{}, sso.ErrStateNotFound
+	}
+
+	return st, nil
+}
+
+// CreateIdentity links an identity to a user in the database.
+func (s *Store) CreateIdentity(ctx context.Context, idn sso.Identity) error {
+	return memdb.Insert(s.db, tableIdentities, identityKey(idn.Issuer, idn.Subject), idn)
+}
+
+// QueryIdentity gets the identity issued by the identity provider for the
+// specified subject from the database.
+func (s *Store) QueryIdentity(ctx context.Context, issuer string, subject string) (sso.Identity, error) {
+	idn, exists := memdb.Get[sso.Identity](s.db, tableIdentities, identityKey(issuer, subject))
+	if !exists {
+		return sso.Identity{}, sso.ErrIdentityNotFound
+	}
+
+	return idn, nil
+}
+
+// =============================================================================
+
+func identityKey(issuer string, subject string) string {
+	return issuer + "\x00" + subject
+}
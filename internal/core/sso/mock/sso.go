@@ -0,0 +1,122 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/dudakovict/gotify/internal/core/sso (interfaces: Storer)
+
+// Package mocksso is a generated GoMock package.
+package mocksso
+
+import (
+	context "context"
+	reflect "reflect"
+
+	sso "github.com/dudakovict/gotify/internal/core/sso"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockStorer is a mock of Storer interface.
+type MockStorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorerMockRecorder
+}
+
+// MockStorerMockRecorder is the mock recorder for MockStorer.
+type MockStorerMockRecorder struct {
+	mock *MockStorer
+}
+
+// NewMockStorer creates a new mock instance.
+func NewMockStorer(ctrl *gomock.Controller) *MockStorer {
+	mock := &MockStorer{ctrl: ctrl}
+	mock.recorder = &MockStorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
+	return m.recorder
+}
+
+// CreateIdentity mocks base method.
+func (m *MockStorer) CreateIdentity(arg0 context.Context, arg1 sso.Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdentity", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateIdentity indicates an expected call of CreateIdentity.
+func (mr *MockStorerMockRecorder) CreateIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdentity", reflect.TypeOf((*MockStorer)(nil).CreateIdentity), arg0, arg1)
+}
+
+// CreateState mocks base method.
+func (m *MockStorer) CreateState(arg0 context.Context, arg1 sso.State) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateState indicates an expected call of CreateState.
+func (mr *MockStorerMockRecorder) CreateState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateState", reflect.TypeOf((*MockStorer)(nil).CreateState), arg0, arg1)
+}
+
+// DeleteState mocks base method.
+func (m *MockStorer) DeleteState(arg0 context.Context, arg1 sso.State) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteState indicates an expected call of DeleteState.
+func (mr *MockStorerMockRecorder) DeleteState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteState", reflect.TypeOf((*MockStorer)(nil).DeleteState), arg0, arg1)
+}
+
+// QueryIdentity mocks base method.
+func (m *MockStorer) QueryIdentity(arg0 context.Context, arg1, arg2 string) (sso.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryIdentity", arg0, arg1, arg2)
+	ret0, _ := ret[0].(sso.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryIdentity indicates an expected call of QueryIdentity.
+func (mr *MockStorerMockRecorder) QueryIdentity(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryIdentity", reflect.TypeOf((*MockStorer)(nil).QueryIdentity), arg0, arg1, arg2)
+}
+
+// QueryStateByID mocks base method.
+func (m *MockStorer) QueryStateByID(arg0 context.Context, arg1 string) (sso.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryStateByID", arg0, arg1)
+	ret0, _ := ret[0].(sso.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryStateByID indicates an expected call of QueryStateByID.
+func (mr *MockStorerMockRecorder) QueryStateByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryStateByID", reflect.TypeOf((*MockStorer)(nil).QueryStateByID), arg0, arg1)
+}
+
+// WithinTran mocks base method.
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(sso.Storer) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithinTran indicates an expected call of WithinTran.
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
+}
@@ -0,0 +1,25 @@
+package sso
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// State represents an authorization request which is waiting for the
+// identity provider to redirect the user back.
+type State struct {
+	ID           string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// Identity links an account at the identity provider to a user.
+type Identity struct {
+	Issuer    string
+	Subject   string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
@@ -0,0 +1,126 @@
+package sso
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"golang.org/x/oauth2"
+)
+
+// ProviderConfig contains the settings needed to talk to an OpenID Connect
+// identity provider.
+type ProviderConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	GroupsClaim   string
+	GroupRoles    []string
+	AutoProvision bool
+}
+
+// Provider represents a discovered OpenID Connect identity provider.
+type Provider struct {
+	issuer        string
+	oauth2        oauth2.Config
+	verifier      *oidc.IDTokenVerifier
+	groupsClaim   string
+	groupRoles    map[string]user.Role
+	autoProvision bool
+}
+
+// NewProvider fetches the discovery document of the identity provider and
+// constructs a provider for use.
+//
+// Group roles are given as "group=ROLE" pairs. When none are configured the
+// roles of the users are left as they are, and users provisioned on their
+// first sign in get the USER role.
+func NewProvider(ctx context.Context, cfg ProviderConfig) (*Provider, error) {
+	groupRoles, err := parseGroupRoles(cfg.GroupRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("newprovider: %w", err)
+	}
+
+	scopes := []string{oidc.ScopeOpenID, "email", "profile"}
+	for _, scope := range cfg.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	groupsClaim := cfg.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+
+	p := Provider{
+		issuer: cfg.IssuerURL,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier:      provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		groupsClaim:   groupsClaim,
+		groupRoles:    groupRoles,
+		autoProvision: cfg.AutoProvision,
+	}
+
+	return &p, nil
+}
+
+// mapsRoles reports whether the roles of the users are synced from their
+// groups at the identity provider.
+func (p *Provider) mapsRoles() bool {
+	return len(p.groupRoles) > 0
+}
+
+// roles maps the groups of a user to roles. The user gets the USER role as it
+// is needed to use the api at all, unless none of the groups are mapped.
+func (p *Provider) roles(groups []string) []user.Role {
+	var roles []user.Role
+	for _, group := range groups {
+		role, exists := p.groupRoles[group]
+		if !exists || slices.Contains(roles, role) {
+			continue
+		}
+		roles = append(roles, role)
+	}
+
+	if len(roles) > 0 && !slices.Contains(roles, user.RoleUser) {
+		roles = append([]user.Role{user.RoleUser}, roles...)
+	}
+
+	return roles
+}
+
+func parseGroupRoles(values []string) (map[string]user.Role, error) {
+	groupRoles := make(map[string]user.Role, len(values))
+	for _, value := range values {
+		group, name, found := strings.Cut(value, "=")
+		if !found || group == "" {
+			return nil, fmt.Errorf("invalid group role %q", value)
+		}
+
+		role, err := user.ParseRole(name)
+		if err != nil {
+			return nil, err
+		}
+
+		groupRoles[group] = role
+	}
+
+	return groupRoles, nil
+}
@@ -0,0 +1,319 @@
+// Package sso provides a core business API.
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/rs/zerolog"
+	"golang.org/x/oauth2"
+)
+
+// Set of error variables for CRUD operations.
+var (
+	ErrStateNotFound         = errors.New("state not found")
+	ErrStateExpired          = errors.New("state has expired")
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrEmailNotVerified      = errors.New("email is not verified by the identity provider")
+	ErrNotProvisioned        = errors.New("user is not provisioned")
+	ErrNoRoles               = errors.New("user is not a member of any group with access")
+	ErrAuthenticationFailure = errors.New("sso authentication failed")
+)
+
+// StateDuration is how long a user has to sign in at the identity provider.
+const StateDuration = 10 * time.Minute
+
+// Storer interface declares the behavior this package needs to perists and
+// retrieve data.
+type Storer interface {
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	CreateState(ctx context.Context, st State) error
+	DeleteState(ctx context.Context, st State) error
+	QueryStateByID(ctx context.Context, id string) (State, error)
+	CreateIdentity(ctx context.Context, idn Identity) error
+	QueryIdentity(ctx context.Context, issuer string, subject string) (Identity, error)
+}
+
+// Core manages the set of APIs for single sign-on access.
+type Core struct {
+	log      *zerolog.Logger
+	usrCore  *user.Core
+	roleCore *role.Core
+	storer   Storer
+	provider *Provider
+}
+
+// NewCore constructs a sso core API for use.
+func NewCore(log *zerolog.Logger, usrCore *user.Core, roleCore *role.Core, storer Storer, provider *Provider) *Core {
+	return &Core{
+		log:      log,
+		usrCore:  usrCore,
+		roleCore: roleCore,
+		storer:   storer,
+		provider: provider,
+	}
+}
+
+// Begin starts an authorization code flow with PKCE and returns the URL the
+// user has to be redirected to.
+func (c *Core) Begin(ctx context.Context) (string, error) {
+	id, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	st := State{
+		ID:           id,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ExpiresAt:    now.Add(StateDuration),
+		CreatedAt:    now,
+	}
+
+	if err := c.storer.CreateState(ctx, st); err != nil {
+		return "", fmt.Errorf("createstate: %w", err)
+	}
+
+	url := c.provider.oauth2.AuthCodeURL(st.ID, oidc.Nonce(st.Nonce), oauth2.S256ChallengeOption(st.CodeVerifier))
+
+	return url, nil
+}
+
+type claims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// Finish exchanges the authorization code returned by the identity provider
+// and returns the linked user, provisioning one if needed.
+func (c *Core) Finish(ctx context.Context, state string, code string) (user.User, error) {
+	st, err := c.consumeState(ctx, state)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	token, err := c.provider.oauth2.Exchange(ctx, code, oauth2.VerifierOption(st.CodeVerifier))
+	if err != nil {
+		return user.User{}, fmt.Errorf("exchange: %w: %s", ErrAuthenticationFailure, err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return user.User{}, fmt.Errorf("id token missing: %w", ErrAuthenticationFailure)
+	}
+
+	idToken, err := c.provider.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return user.User{}, fmt.Errorf("verify: %w: %s", ErrAuthenticationFailure, err)
+	}
+
+	if idToken.Nonce != st.Nonce {
+		return user.User{}, fmt.Errorf("nonce mismatch: %w", ErrAuthenticationFailure)
+	}
+
+	var clms claims
+	if err := idToken.Claims(&clms); err != nil {
+		return user.User{}, fmt.Errorf("claims: %w: %s", ErrAuthenticationFailure, err)
+	}
+
+	if clms.Email == "" || !clms.EmailVerified {
+		return user.User{}, ErrEmailNotVerified
+	}
+
+	// With a group mapping the identity provider is the source of truth for
+	// the roles of the user. Without one the roles are managed locally.
+	var roles []user.Role
+	if c.provider.mapsRoles() {
+		var rawClaims map[string]any
+		if err := idToken.Claims(&rawClaims); err != nil {
+			return user.User{}, fmt.Errorf("claims: %w: %s", ErrAuthenticationFailure, err)
+		}
+
+		roles = c.provider.roles(groups(rawClaims[c.provider.groupsClaim]))
+		if len(roles) == 0 {
+			return user.User{}, ErrNoRoles
+		}
+
+		names := make([]string, len(roles))
+		for i, r := range roles {
+			names[i] = r.Name()
+		}
+
+		if err := c.roleCore.Check(ctx, names); err != nil {
+			return user.User{}, fmt.Errorf("check: %w", err)
+		}
+	}
+
+	usr, err := c.resolveUser(ctx, idToken.Issuer, idToken.Subject, clms.Email)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	var uu user.UpdateUser
+	if roles != nil && !sameRoles(usr.Roles, roles) {
+		uu.Roles = roles
+	}
+
+	// The identity provider has verified the email of the user.
+	if !usr.Verified {
+		verified := true
+		uu.Verified = &verified
+	}
+
+	if uu.Roles != nil || uu.Verified != nil {
+		usr, err = c.usrCore.Update(ctx, usr, uu)
+		if err != nil {
+			return user.User{}, fmt.Errorf("update: %w", err)
+		}
+	}
+
+	return usr, nil
+}
+
+// resolveUser finds the user linked to the identity. Unlinked identities are
+// linked to the user with the same email, or to a newly provisioned user.
+func (c *Core) resolveUser(ctx context.Context, issuer string, subject string, email string) (user.User, error) {
+	idn, err := c.storer.QueryIdentity(ctx, issuer, subject)
+	switch {
+	case err == nil:
+		usr, err := c.usrCore.QueryByID(ctx, idn.UserID)
+		if err != nil {
+			return user.User{}, fmt.Errorf("query: %w", err)
+		}
+		return usr, nil
+
+	case !errors.Is(err, ErrIdentityNotFound):
+		return user.User{}, fmt.Errorf("queryidentity: %w", err)
+	}
+
+	usr, err := c.usrCore.QueryByEmail(ctx, email)
+	switch {
+	case errors.Is(err, user.ErrNotFound):
+		if !c.provider.autoProvision {
+			return user.User{}, ErrNotProvisioned
+		}
+
+		password, err := randomString()
+		if err != nil {
+			return user.User{}, err
+		}
+
+		// The password is never handed out so the user can only sign in
+		// through the identity provider until they reset it.
+		usr, err = c.usrCore.Create(ctx, user.NewUser{
+			Email:    email,
+			Roles:    []user.Role{user.RoleUser},
+			Password: password,
+		})
+		if err != nil {
+			return user.User{}, fmt.Errorf("create: %w", err)
+		}
+
+	case err != nil:
+		return user.User{}, fmt.Errorf("query: %w", err)
+	}
+
+	idn = Identity{
+		Issuer:    issuer,
+		Subject:   subject,
+		UserID:    usr.ID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := c.storer.CreateIdentity(ctx, idn); err != nil {
+		return user.User{}, fmt.Errorf("createidentity: %w", err)
+	}
+
+	return usr, nil
+}
+
+// consumeState loads a state and deletes it so that it can only be used once.
+func (c *Core) consumeState(ctx context.Context, id string) (State, error) {
+	var st State
+
+	tran := func(s Storer) error {
+		var err error
+		st, err = s.QueryStateByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.DeleteState(ctx, st); err != nil {
+			return fmt.Errorf("deletestate: %w", err)
+		}
+
+		if time.Now().After(st.ExpiresAt) {
+			return ErrStateExpired
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		if errors.Is(err, ErrStateExpired) {
+			return State{}, ErrStateExpired
+		}
+		return State{}, fmt.Errorf("tran: %w", err)
+	}
+
+	return st, nil
+}
+
+// sameRoles reports whether both lists hold the same roles, in any order.
+func sameRoles(a []user.Role, b []user.Role) bool {
+	for _, r := range a {
+		if !slices.Contains(b, r) {
+			return false
+		}
+	}
+
+	for _, r := range b {
+		if !slices.Contains(a, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// groups converts the groups claim, which is either a list or a single
+// string depending on the identity provider.
+func groups(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		groups := make([]string, 0, len(v))
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	default:
+		return nil
+	}
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("rand: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,568 @@
+package sso_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/role"
+	mockrole "github.com/dudakovict/gotify/internal/core/role/mock"
+	"github.com/dudakovict/gotify/internal/core/sso"
+	mocksso "github.com/dudakovict/gotify/internal/core/sso/mock"
+	"github.com/dudakovict/gotify/internal/core/user"
+	mockusr "github.com/dudakovict/gotify/internal/core/user/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	clientID    = "gotify"
+	redirectURL = "http://localhost:3000/api/v1/login/oidc/callback"
+)
+
+func TestFinish(t *testing.T) {
+	idp := newFakeIdP(t)
+	defer idp.Close()
+
+	existing := user.User{
+		ID:       uuid.New(),
+		Email:    "jane@example.com",
+		Roles:    []user.Role{user.RoleUser},
+		Verified: true,
+	}
+
+	testCases := []struct {
+		name          string
+		autoProvision bool
+		groupRoles    []string
+		roles         []user.Role
+		claims        map[string]any
+		identities    []sso.Identity
+		checkResponse func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error)
+	}{
+		{
+			name:          "Provision",
+			autoProvision: true,
+			claims: map[string]any{
+				"sub":            "1",
+				"email":          "john@example.com",
+				"email_verified": true,
+				"groups":         []string{"gotify-admins"},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "john@example.com", usr.Email)
+				require.Equal(t, []user.Role{user.RoleUser, user.RoleAdmin}, usr.Roles)
+				require.True(t, usr.Verified)
+				require.Equal(t, usr, users[usr.ID])
+				require.Equal(t, usr.ID, identities[idp.URL+"|1"].UserID)
+			},
+		},
+		{
+			name: "LinkByEmail",
+			claims: map[string]any{
+				"sub":            "2",
+				"email":          existing.Email,
+				"email_verified": true,
+				"groups":         []string{"gotify-users"},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.NoError(t, err)
+				require.Equal(t, existing.ID, usr.ID)
+				require.Equal(t, []user.Role{user.RoleUser}, usr.Roles)
+				require.True(t, usr.Verified)
+				require.Len(t, users, 1)
+				require.Equal(t, existing.ID, identities[idp.URL+"|2"].UserID)
+			},
+		},
+		{
+			name: "LinkedIdentity",
+			claims: map[string]any{
+				"sub":            "3",
+				"email":          "jane@corp.example.com",
+				"email_verified": true,
+				"groups":         "gotify-admins",
+			},
+			identities: []sso.Identity{
+				{Issuer: idp.URL, Subject: "3", UserID: existing.ID},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.NoError(t, err)
+				require.Equal(t, existing.ID, usr.ID)
+				require.Equal(t, []user.Role{user.RoleUser, user.RoleAdmin}, usr.Roles)
+				require.Len(t, users, 1)
+			},
+		},
+		{
+			name:  "SameRolesInAnyOrder",
+			roles: []user.Role{user.RoleAdmin, user.RoleUser},
+			claims: map[string]any{
+				"sub":            "7",
+				"email":          existing.Email,
+				"email_verified": true,
+				"groups":         []string{"gotify-admins"},
+			},
+			identities: []sso.Identity{
+				{Issuer: idp.URL, Subject: "7", UserID: existing.ID},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []user.Role{user.RoleAdmin, user.RoleUser}, usr.Roles)
+				require.Equal(t, existing.Version, users[existing.ID].Version)
+			},
+		},
+		{
+			name:       "NoGroupRoles",
+			groupRoles: []string{},
+			roles:      []user.Role{user.RoleAdmin},
+			claims: map[string]any{
+				"sub":            "8",
+				"email":          existing.Email,
+				"email_verified": true,
+			},
+			identities: []sso.Identity{
+				{Issuer: idp.URL, Subject: "8", UserID: existing.ID},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []user.Role{user.RoleAdmin}, usr.Roles)
+				require.Equal(t, existing.Version, users[existing.ID].Version)
+			},
+		},
+		{
+			name:       "UnknownRole",
+			groupRoles: []string{"gotify-auditors=AUDITOR"},
+			claims: map[string]any{
+				"sub":            "9",
+				"email":          existing.Email,
+				"email_verified": true,
+				"groups":         []string{"gotify-auditors"},
+			},
+			identities: []sso.Identity{
+				{Issuer: idp.URL, Subject: "9", UserID: existing.ID},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.ErrorIs(t, err, role.ErrNotFound)
+				require.Equal(t, existing, users[existing.ID])
+			},
+		},
+		{
+			name:          "EmailNotVerified",
+			autoProvision: true,
+			claims: map[string]any{
+				"sub":    "4",
+				"email":  "john@example.com",
+				"groups": []string{"gotify-users"},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.ErrorIs(t, err, sso.ErrEmailNotVerified)
+				require.Len(t, identities, 0)
+			},
+		},
+		{
+			name:          "NoRoles",
+			autoProvision: true,
+			claims: map[string]any{
+				"sub":            "5",
+				"email":          "john@example.com",
+				"email_verified": true,
+				"groups":         []string{"marketing"},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.ErrorIs(t, err, sso.ErrNoRoles)
+				require.Len(t, users, 1)
+			},
+		},
+		{
+			name: "NotProvisioned",
+			claims: map[string]any{
+				"sub":            "6",
+				"email":          "john@example.com",
+				"email_verified": true,
+				"groups":         []string{"gotify-users"},
+			},
+			checkResponse: func(usr user.User, users map[uuid.UUID]user.User, identities map[string]sso.Identity, err error) {
+				require.ErrorIs(t, err, sso.ErrNotProvisioned)
+				require.Len(t, users, 1)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			usr := existing
+			if tc.roles != nil {
+				usr.Roles = tc.roles
+			}
+
+			users := map[uuid.UUID]user.User{existing.ID: usr}
+			identities := make(map[string]sso.Identity)
+			for _, idn := range tc.identities {
+				identities[idn.Issuer+"|"+idn.Subject] = idn
+			}
+
+			groupRoles := tc.groupRoles
+			if groupRoles == nil {
+				groupRoles = []string{"gotify-admins=ADMIN", "gotify-users=USER"}
+			}
+
+			core := newCore(t, ctrl, idp, tc.autoProvision, groupRoles, users, identities)
+
+			idp.claims = tc.claims
+
+			usr, err := login(t, core, idp, "")
+
+			tc.checkResponse(usr, users, identities, err)
+		})
+	}
+}
+
+func TestFinishPKCE(t *testing.T) {
+	ctx := context.Background()
+
+	idp := newFakeIdP(t)
+	defer idp.Close()
+
+	idp.claims = map[string]any{
+		"sub":            "1",
+		"email":          "john@example.com",
+		"email_verified": true,
+		"groups":         []string{"gotify-users"},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	core := newCore(t, ctrl, idp, true, []string{"gotify-users=USER"}, make(map[uuid.UUID]user.User), make(map[string]sso.Identity))
+
+	// A code intercepted from another authorization request can't be
+	// redeemed without its code verifier.
+	authURL, err := core.Begin(ctx)
+	require.NoError(t, err)
+
+	stolen, _ := idp.authorize(t, authURL)
+
+	_, err = login(t, core, idp, stolen)
+	require.ErrorIs(t, err, sso.ErrAuthenticationFailure)
+
+	// The state can only be used once.
+	authURL, err = core.Begin(ctx)
+	require.NoError(t, err)
+
+	code, state := idp.authorize(t, authURL)
+
+	_, err = core.Finish(ctx, state, code)
+	require.NoError(t, err)
+
+	_, err = core.Finish(ctx, state, code)
+	require.ErrorIs(t, err, sso.ErrStateNotFound)
+}
+
+// login runs the authorization code flow against the fake identity provider.
+// When a code is given it is redeemed in place of the one issued.
+func login(t *testing.T, core *sso.Core, idp *fakeIdP, code string) (user.User, error) {
+	authURL, err := core.Begin(context.Background())
+	require.NoError(t, err)
+
+	issued, state := idp.authorize(t, authURL)
+	if code == "" {
+		code = issued
+	}
+
+	return core.Finish(context.Background(), state, code)
+}
+
+// newCore constructs a core backed by mock storers that keep their state in
+// the given maps.
+func newCore(t *testing.T, ctrl *gomock.Controller, idp *fakeIdP, autoProvision bool, groupRoles []string, users map[uuid.UUID]user.User, identities map[string]sso.Identity) *sso.Core {
+	states := make(map[string]sso.State)
+
+	storer := mocksso.NewMockStorer(ctrl)
+	storer.EXPECT().
+		WithinTran(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, fn func(sso.Storer) error) error {
+			return fn(storer)
+		})
+
+	storer.EXPECT().
+		CreateState(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, st sso.State) error {
+			states[st.ID] = st
+			return nil
+		})
+
+	storer.EXPECT().
+		DeleteState(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, st sso.State) error {
+			delete(states, st.ID)
+			return nil
+		})
+
+	storer.EXPECT().
+		QueryStateByID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, id string) (sso.State, error) {
+			st, ok := states[id]
+			if !ok {
+				return sso.State{}, sso.ErrStateNotFound
+			}
+			return st, nil
+		})
+
+	storer.EXPECT().
+		CreateIdentity(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, idn sso.Identity) error {
+			identities[idn.Issuer+"|"+idn.Subject] = idn
+			return nil
+		})
+
+	storer.EXPECT().
+		QueryIdentity(gomock.Any(), gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, issuer string, subject string) (sso.Identity, error) {
+			idn, ok := identities[issuer+"|"+subject]
+			if !ok {
+				return sso.Identity{}, sso.ErrIdentityNotFound
+			}
+			return idn, nil
+		})
+
+	usrStorer := mockusr.NewMockStorer(ctrl)
+	usrStorer.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, usr user.User) error {
+			users[usr.ID] = usr
+			return nil
+		})
+
+	usrStorer.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, usr user.User) error {
+			if users[usr.ID].Version != usr.Version {
+				return user.ErrVersionConflict
+			}
+			usr.Version++
+			users[usr.ID] = usr
+			return nil
+		})
+
+	usrStorer.EXPECT().
+		QueryByID(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, id uuid.UUID) (user.User, error) {
+			usr, ok := users[id]
+			if !ok {
+				return user.User{}, user.ErrNotFound
+			}
+			return usr, nil
+		})
+
+	usrStorer.EXPECT().
+		QueryByEmail(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, email string) (user.User, error) {
+			for _, usr := range users {
+				if usr.Email == email {
+					return usr, nil
+				}
+			}
+			return user.User{}, user.ErrNotFound
+		})
+
+	roleStorer := mockrole.NewMockStorer(ctrl)
+	roleStorer.EXPECT().
+		QueryByNames(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, names []string) ([]role.Role, error) {
+			var roles []role.Role
+			for _, name := range names {
+				if name == user.RoleUser.Name() || name == user.RoleAdmin.Name() {
+					roles = append(roles, role.Role{Name: name, BuiltIn: true})
+				}
+			}
+			return roles, nil
+		})
+
+	provider, err := sso.NewProvider(context.Background(), sso.ProviderConfig{
+		IssuerURL:     idp.URL,
+		ClientID:      clientID,
+		ClientSecret:  "secret",
+		RedirectURL:   redirectURL,
+		Scopes:        []string{"groups"},
+		GroupRoles:    groupRoles,
+		AutoProvision: autoProvision,
+	})
+	require.NoError(t, err)
+
+	return sso.NewCore(nil, user.NewCore(nil, usrStorer), role.NewCore(nil, roleStorer), storer, provider)
+}
+
+// fakeIdP is an in-process OpenID Connect identity provider which signs in
+// every user with the configured claims.
+type fakeIdP struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any
+
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+type authRequest struct {
+	nonce         string
+	codeChallenge string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := fakeIdP{
+		key:   key,
+		codes: make(map[string]authRequest),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+
+	idp.Server = httptest.NewServer(mux)
+
+	return &idp
+}
+
+func (idp *fakeIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                idp.URL,
+		"authorization_endpoint":                idp.URL + "/authorize",
+		"token_endpoint":                        idp.URL + "/token",
+		"jwks_uri":                              idp.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (idp *fakeIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]any{
+			{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   b64(idp.key.N.Bytes()),
+				"e":   b64(big.NewInt(int64(idp.key.E)).Bytes()),
+			},
+		},
+	})
+}
+
+// authorize plays the part of the browser at the authorization endpoint and
+// returns the code and state the user is redirected back with.
+func (idp *fakeIdP) authorize(t *testing.T, authURL string) (string, string) {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	q := u.Query()
+	require.Equal(t, idp.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	require.Equal(t, "code", q.Get("response_type"))
+	require.Equal(t, clientID, q.Get("client_id"))
+	require.Equal(t, redirectURL, q.Get("redirect_uri"))
+	require.Equal(t, "S256", q.Get("code_challenge_method"))
+	require.Contains(t, strings.Fields(q.Get("scope")), "openid")
+	require.NotEmpty(t, q.Get("nonce"))
+
+	code := uuid.NewString()
+
+	idp.mu.Lock()
+	idp.codes[code] = authRequest{
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+	}
+	idp.mu.Unlock()
+
+	return code, q.Get("state")
+}
+
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	req, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || b64(verifier[:]) != req.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   idp.URL,
+		"aud":   clientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": req.nonce,
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": uuid.NewString(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idp.sign(claims),
+	})
+}
+
+func (idp *fakeIdP) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]any{"alg": "RS256", "typ": "JWT", "kid": "test"})
+	payload, _ := json.Marshal(claims)
+
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+
+	return signingInput + "." + b64(sig)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
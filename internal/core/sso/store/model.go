@@ -0,0 +1,61 @@
+package ssodb
+
+import (
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/sso"
+	"github.com/google/uuid"
+)
+
+type dbState struct {
+	ID           string    `db:"id"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func toDBState(st sso.State) dbState {
+	return dbState{
+		ID:           st.ID,
+		Nonce:        st.Nonce,
+		CodeVerifier: st.CodeVerifier,
+		ExpiresAt:    st.ExpiresAt.UTC(),
+		CreatedAt:    st.CreatedAt.UTC(),
+	}
+}
+
+func toCoreState(dbSt dbState) sso.State {
+	return sso.State{
+		ID:           dbSt.ID,
+		Nonce:        dbSt.Nonce,
+		CodeVerifier: dbSt.CodeVerifier,
+		ExpiresAt:    dbSt.ExpiresAt.In(time.Local),
+		CreatedAt:    dbSt.CreatedAt.In(time.Local),
+	}
+}
+
+type dbIdentity struct {
+	Issuer    string    `db:"issuer"`
+	Subject   string    `db:"subject"`
+	UserID    uuid.UUID `db:"user_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func toDBIdentity(idn sso.Identity) dbIdentity {
+	return dbIdentity{
+		Issuer:    idn.Issuer,
+		Subject:   idn.Subject,
+		UserID:    idn.UserID,
+		CreatedAt: idn.CreatedAt.UTC(),
+	}
+}
+
+func toCoreIdentity(dbIdn dbIdentity) sso.Identity {
+	return sso.Identity{
+		Issuer:    dbIdn.Issuer,
+		Subject:   dbIdn.Subject,
+		UserID:    dbIdn.UserID,
+		CreatedAt: dbIdn.CreatedAt.In(time.Local),
+	}
+}
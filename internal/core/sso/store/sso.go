@@ -0,0 +1,149 @@
+// Package ssodb contains sso related CRUD functionality.
+package ssodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dudakovict/gotify/internal/core/sso"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for sso database access.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(sso.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// CreateState inserts a new authorization state into the database.
+func (s *Store) CreateState(ctx context.Context, st sso.State) error {
+	const q = `
+	INSERT INTO oidc_states
+		(id, nonce, code_verifier, expires_at, created_at)
+	VALUES
+		(:id, :nonce, :code_verifier, :expires_at, :created_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBState(st)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteState removes an authorization state from the database.
+func (s *Store) DeleteState(ctx context.Context, st sso.State) error {
+	data := struct {
+		ID string `db:"id"`
+	}{
+		ID: st.ID,
+	}
+
+	const q = `
+	DELETE FROM
+		oidc_states
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryStateByID gets the specified authorization state from the database.
+func (s *Store) QueryStateByID(ctx context.Context, id string) (sso.State, error) {
+	data := struct {
+		ID string `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM oidc_states WHERE id = :id`
+
+	var dbSt dbState
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSt); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return sso.State{}, sso.ErrStateNotFound
+		}
+		return sso.State{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreState(dbSt), nil
+}
+
+// CreateIdentity links an identity to a user in the database.
+func (s *Store) CreateIdentity(ctx context.Context, idn sso.Identity) error {
+	const q = `
+	INSERT INTO user_identities
+		(issuer, subject, user_id, created_at)
+	VALUES
+		(:issuer, :subject, :user_id, :created_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBIdentity(idn)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryIdentity gets the identity issued by the identity provider for the
+// specified subject from the database.
+func (s *Store) QueryIdentity(ctx context.Context, issuer string, subject string) (sso.Identity, error) {
+	data := struct {
+		Issuer  string `db:"issuer"`
+		Subject string `db:"subject"`
+	}{
+		Issuer:  issuer,
+		Subject: subject,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		user_identities
+	WHERE
+		issuer = :issuer
+		AND subject = :subject`
+
+	var dbIdn dbIdentity
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbIdn); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return sso.Identity{}, sso.ErrIdentityNotFound
+		}
+		return sso.Identity{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreIdentity(dbIdn), nil
+}
@@ -0,0 +1,56 @@
+package subscription
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+)
+
+// Filter narrows down the notifications a subscription delivers. Every part
+// that is set has to match.
+type Filter struct {
+	// MinPriority is the lowest priority delivered.
+	MinPriority notification.Priority
+
+	// Tags have to all be on the notification.
+	Tags []string
+
+	// Keyword has to be in the title or the message, ignoring case.
+	Keyword string
+
+	// Regex has to match the title or the message.
+	Regex *regexp.Regexp
+}
+
+// IsZero reports whether the filter lets every notification through.
+func (f Filter) IsZero() bool {
+	return f.MinPriority == (notification.Priority{}) && len(f.Tags) == 0 && f.Keyword == "" && f.Regex == nil
+}
+
+// Match reports whether the notification passes the filter.
+func (f Filter) Match(ntf notification.Notification) bool {
+	if f.MinPriority != (notification.Priority{}) && !ntf.Priority.AtLeast(f.MinPriority) {
+		return false
+	}
+
+	for _, tag := range f.Tags {
+		if !slices.Contains(ntf.Tags, tag) {
+			return false
+		}
+	}
+
+	if f.Keyword != "" {
+		keyword := strings.ToLower(f.Keyword)
+		if !strings.Contains(strings.ToLower(ntf.Title), keyword) && !strings.Contains(strings.ToLower(ntf.Message), keyword) {
+			return false
+		}
+	}
+
+	if f.Regex != nil && !f.Regex.MatchString(ntf.Title) && !f.Regex.MatchString(ntf.Message) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,117 @@
+package subscription
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxJobRows is the most subscriptions a single job can make.
+const MaxJobRows = 10000
+
+// Set of statuses a job goes through. Jobs are pending until the worker
+// picks them up, and done once every row was processed.
+var (
+	JobPending = JobStatus{"pending"}
+	JobRunning = JobStatus{"running"}
+	JobDone    = JobStatus{"done"}
+)
+
+// Set of known job statuses.
+var jobStatuses = map[string]JobStatus{
+	JobPending.name: JobPending,
+	JobRunning.name: JobRunning,
+	JobDone.name:    JobDone,
+}
+
+// JobStatus represents how far a job got.
+type JobStatus struct {
+	name string
+}
+
+// ParseJobStatus parses the string value and returns a job status if one
+// exists.
+func ParseJobStatus(value string) (JobStatus, error) {
+	status, exists := jobStatuses[value]
+	if !exists {
+		return JobStatus{}, fmt.Errorf("%w %q", ErrInvalidJobStatus, value)
+	}
+
+	return status, nil
+}
+
+// Name returns the name of the job status.
+func (s JobStatus) Name() string {
+	return s.name
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (s *JobStatus) UnmarshalText(data []byte) error {
+	status, err := ParseJobStatus(string(data))
+	if err != nil {
+		return err
+	}
+
+	s.name = status.name
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (s JobStatus) MarshalText() ([]byte, error) {
+	return []byte(s.name), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (s JobStatus) Equal(s2 JobStatus) bool {
+	return s.name == s2.name
+}
+
+// =============================================================================
+
+// Job represents a bulk subscription run by the worker. Its rows are
+// subscribed in order, and the progress is kept so a retried job carries on
+// where it stopped. Rows already subscribed count as existing, rows that
+// can't be subscribed are reported with the reason.
+type Job struct {
+	ID        uuid.UUID
+	Status    JobStatus
+	Rows      []JobRow
+	Processed int
+	Created   int
+	Existing  int
+	Errors    []RowError
+	CreatedBy uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Finished reports whether every row of the job was processed.
+func (j Job) Finished() bool {
+	return j.Processed >= len(j.Rows)
+}
+
+// JobRow is a subscription a job makes. The user is given either by ID or by
+// email, and the topic either by ID or by name.
+type JobRow struct {
+	UserID  uuid.UUID
+	Email   string
+	TopicID uuid.UUID
+	Topic   string
+}
+
+// RowError reports why a row of a job couldn't be subscribed. Rows are
+// numbered from 1.
+type RowError struct {
+	Row   int
+	Error string
+}
+
+// NewJob contains information needed to create a new job. AfterCreate runs in
+// the same transaction, so the job is only kept when handing it to the worker
+// succeeds.
+type NewJob struct {
+	Rows        []JobRow
+	CreatedBy   uuid.UUID
+	AfterCreate func(job Job) error
+}
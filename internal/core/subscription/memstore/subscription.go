@@ -0,0 +1,440 @@
+// Package subscriptionmem contains subscription related CRUD functionality,
+// kept in memory.
+package subscriptionmem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table       = "subscriptions"
+	tableJobs   = "subscription_jobs"
+	tableTopics = "topics"
+	tableAccess = "topic_acls"
+	tableUsers  = "users"
+)
+
+// orderByFields maps the fields subscriptions can be ordered by to their
+// comparisons.
+var orderByFields = map[string]memdb.Compare[subscription.Subscription]{
+	subscription.OrderByID: compareID,
+	subscription.OrderByCreatedAt: func(a, b subscription.Subscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	},
+}
+
+// uniques lists the pairs of columns no two subscriptions share, unless one
+// of the columns is NULL. Deleted subscriptions share them with any other.
+var uniques = map[string]func(sub subscription.Subscription) (string, bool){
+	"unique_sub_topic_user": func(sub subscription.Subscription) (string, bool) {
+		return pair(sub.TopicID != uuid.Nil && sub.UserID != uuid.Nil, sub.TopicID.String(), sub.UserID.String())
+	},
+	"unique_sub_pattern_user": func(sub subscription.Subscription) (string, bool) {
+		return pair(!sub.Pattern.IsZero() && sub.UserID != uuid.Nil, sub.Pattern.String(), sub.UserID.String())
+	},
+	"unique_sub_topic_guest": func(sub subscription.Subscription) (string, bool) {
+		return pair(sub.TopicID != uuid.Nil && sub.IsGuest(), sub.TopicID.String(), sub.GuestEmail)
+	},
+	"unique_sub_topic_group": func(sub subscription.Subscription) (string, bool) {
+		return pair(sub.TopicID != uuid.Nil && sub.IsGroup(), sub.TopicID.String(), sub.GroupID.String())
+	},
+	"unique_sub_pattern_group": func(sub subscription.Subscription) (string, bool) {
+		return pair(!sub.Pattern.IsZero() && sub.IsGroup(), sub.Pattern.String(), sub.GroupID.String())
+	},
+}
+
+// Store manages the set of APIs for subscription access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	for name, key := range uniques {
+		key := key
+		db.Unique(name, memdb.Unique{
+			Table: table,
+			Key: func(row any) (string, bool) {
+				sub := row.(subscription.Subscription)
+				if !sub.DeletedAt.IsZero() {
+					return "", false
+				}
+				return key(sub)
+			},
+		})
+	}
+
+	db.Reference("subscriptions_topic_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: tableTopics,
+		Refers: func(row any, key string) bool { return row.(subscription.Subscription).TopicID.String() == key },
+	})
+
+	db.Reference("subscriptions_user_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: tableUsers,
+		Refers: func(row any, key string) bool { return row.(subscription.Subscription).UserID.String() == key },
+	})
+
+	db.Reference("subscriptions_group_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: "groups",
+		Refers: func(row any, key string) bool { return row.(subscription.Subscription).GroupID.String() == key },
+	})
+
+	db.Reference("subscription_jobs_created_by_fkey", memdb.Reference{
+		Table:  tableJobs,
+		Parent: tableUsers,
+		Action: memdb.SetNull,
+		Refers: func(row any, key string) bool { return row.(subscription.Job).CreatedBy.String() == key },
+		Clear: func(row any) any {
+			job := row.(subscription.Job)
+			job.CreatedBy = uuid.Nil
+			return job
+		},
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(subscription.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new subscription into the database.
+func (s *Store) Create(ctx context.Context, sub subscription.Subscription) error {
+	if err := memdb.Insert(s.db, table, sub.ID.String(), clone(sub)); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return subscription.ErrUniqueViolation
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update replaces the filter, the routes, the muting and the confirmation of a
+// subscription in the database, as long as it's still at the version of the
+// subscription.
+func (s *Store) Update(ctx context.Context, sub subscription.Subscription) error {
+	stored, exists := memdb.Get[subscription.Subscription](s.db, table, sub.ID.String())
+	if !exists || stored.Version != sub.Version {
+		return subscription.ErrVersionConflict
+	}
+
+	sub = clone(sub)
+	stored.Filter = sub.Filter
+	stored.Routes = sub.Routes
+	stored.Muted = sub.Muted
+	stored.MutedUntil = sub.MutedUntil
+	stored.Confirmed = sub.Confirmed
+	stored.UpdatedAt = sub.UpdatedAt
+	stored.Version++
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// Delete marks a subscription as deleted in the database, as long as it's
+// still at the version of the subscription.
+func (s *Store) Delete(ctx context.Context, sub subscription.Subscription) error {
+	stored, exists := memdb.Get[subscription.Subscription](s.db, table, sub.ID.String())
+	if !exists || !stored.DeletedAt.IsZero() || stored.Version != sub.Version {
+		return subscription.ErrVersionConflict
+	}
+
+	stored.DeletedAt = sub.DeletedAt
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// Restore clears the deletion of a subscription in the database.
+func (s *Store) Restore(ctx context.Context, sub subscription.Subscription) error {
+	stored, exists := memdb.Get[subscription.Subscription](s.db, table, sub.ID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.DeletedAt = time.Time{}
+	stored.UpdatedAt = sub.UpdatedAt
+
+	if err := memdb.Put(s.db, table, stored.ID.String(), stored); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return subscription.ErrUniqueViolation
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Purge removes up to limit subscriptions deleted before the given time from
+// the database.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	subs := memdb.Rows(s.db, table, func(sub subscription.Subscription) bool {
+		return !sub.DeletedAt.IsZero() && sub.DeletedAt.Before(before)
+	})
+
+	if len(subs) > limit {
+		subs = subs[:limit]
+	}
+
+	for _, sub := range subs {
+		memdb.Delete(s.db, table, sub.ID.String())
+	}
+
+	return len(subs), nil
+}
+
+// Query retrieves a list of existing subscriptions from the database.
+func (s *Store) Query(ctx context.Context, filter subscription.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]subscription.Subscription, error) {
+	subs := memdb.Rows(s.db, table, matches(filter, time.Now()))
+
+	if err := memdb.Sort(subs, orderBy, orderByFields, compareID); err != nil {
+		return nil, err
+	}
+
+	return cloneSlice(memdb.Page(subs, pageNumber, rowsPerPage)), nil
+}
+
+// Count returns the number of subscriptions in the database matching the
+// filter.
+func (s *Store) Count(ctx context.Context, filter subscription.QueryFilter) (int, error) {
+	return len(memdb.Rows(s.db, table, matches(filter, time.Now()))), nil
+}
+
+// QueryByID gets the specified subscription from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (subscription.Subscription, error) {
+	sub, exists := memdb.Get[subscription.Subscription](s.db, table, id.String())
+	if !exists || !sub.DeletedAt.IsZero() {
+		return subscription.Subscription{}, subscription.ErrNotFound
+	}
+
+	return clone(sub), nil
+}
+
+// QueryDeletedByID gets the specified deleted subscription from the database
+// by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (subscription.Subscription, error) {
+	sub, exists := memdb.Get[subscription.Subscription](s.db, table, id.String())
+	if !exists || sub.DeletedAt.IsZero() {
+		return subscription.Subscription{}, subscription.ErrNotFound
+	}
+
+	return clone(sub), nil
+}
+
+// QueryByGuest gets the subscription of the email address to the topic from
+// the database.
+func (s *Store) QueryByGuest(ctx context.Context, topicID uuid.UUID, email string) (subscription.Subscription, error) {
+	subs := memdb.Rows(s.db, table, func(sub subscription.Subscription) bool {
+		return sub.TopicID == topicID && sub.GuestEmail == email && sub.DeletedAt.IsZero()
+	})
+
+	if len(subs) == 0 {
+		return subscription.Subscription{}, subscription.ErrNotFound
+	}
+
+	return clone(subs[0]), nil
+}
+
+// QueryConfirmedGuests gets the confirmed guest subscriptions to the topic
+// from the database.
+func (s *Store) QueryConfirmedGuests(ctx context.Context, topicID uuid.UUID) ([]subscription.Subscription, error) {
+	subs := memdb.Rows(s.db, table, func(sub subscription.Subscription) bool {
+		return sub.TopicID == topicID && sub.IsGuest() && sub.Confirmed && sub.DeletedAt.IsZero()
+	})
+
+	return cloneSlice(subs), nil
+}
+
+// QueryMatching gets the subscriptions to the topic from the database, either
+// direct or through a pattern matching its name. Both only match for users
+// allowed to subscribe to the topic, those of guests only match public topics
+// and those of groups match regardless, to be narrowed down to the members
+// once expanded. Unconfirmed guest subscriptions are left out, and so are
+// deleted ones along with those of deleted users.
+func (s *Store) QueryMatching(ctx context.Context, topicID uuid.UUID) ([]subscription.Subscription, error) {
+	tpc, exists := memdb.Get[topic.Topic](s.db, tableTopics, topicID.String())
+
+	subs := memdb.Rows(s.db, table, func(sub subscription.Subscription) bool {
+		if !sub.DeletedAt.IsZero() || s.userDeleted(sub.UserID) {
+			return false
+		}
+
+		if !exists {
+			return false
+		}
+
+		if sub.TopicID == topicID {
+			return sub.Confirmed && (sub.IsGroup() || s.allowed(tpc, sub.UserID))
+		}
+
+		if !sub.Pattern.Match(tpc.Name) {
+			return false
+		}
+
+		return sub.IsGroup() || s.allowed(tpc, sub.UserID)
+	})
+
+	return cloneSlice(subs), nil
+}
+
+// CreateJob inserts a new bulk subscription job into the database.
+func (s *Store) CreateJob(ctx context.Context, job subscription.Job) error {
+	return memdb.Insert(s.db, tableJobs, job.ID.String(), cloneJob(job))
+}
+
+// UpdateJob replaces the status and the progress of a job in the database.
+func (s *Store) UpdateJob(ctx context.Context, job subscription.Job) error {
+	stored, exists := memdb.Get[subscription.Job](s.db, tableJobs, job.ID.String())
+	if !exists {
+		return nil
+	}
+
+	job = cloneJob(job)
+	stored.Status = job.Status
+	stored.Processed = job.Processed
+	stored.Created = job.Created
+	stored.Existing = job.Existing
+	stored.Errors = job.Errors
+	stored.UpdatedAt = job.UpdatedAt
+
+	return memdb.Put(s.db, tableJobs, stored.ID.String(), stored)
+}
+
+// QueryJobByID gets the specified job from the database by id.
+func (s *Store) QueryJobByID(ctx context.Context, id uuid.UUID) (subscription.Job, error) {
+	job, exists := memdb.Get[subscription.Job](s.db, tableJobs, id.String())
+	if !exists {
+		return subscription.Job{}, subscription.ErrJobNotFound
+	}
+
+	return cloneJob(job), nil
+}
+
+// =============================================================================
+
+// allowed reports whether the user may subscribe to the topic: it's public,
+// owned by the user, or the user was granted the right to subscribe to it.
+// Guests, with no user, may only subscribe to public topics.
+func (s *Store) allowed(tpc topic.Topic, userID uuid.UUID) bool {
+	if tpc.Visibility.Equal(topic.VisibilityPublic) {
+		return true
+	}
+
+	if userID == uuid.Nil {
+		return false
+	}
+
+	if tpc.OwnerID == userID {
+		return true
+	}
+
+	a, exists := memdb.Get[topic.Access](s.db, tableAccess, tpc.ID.String()+"/"+userID.String())
+	return exists && a.Has(topic.RightSubscribe)
+}
+
+// userDeleted reports whether the user of a subscription has been deleted.
+func (s *Store) userDeleted(userID uuid.UUID) bool {
+	if userID == uuid.Nil {
+		return false
+	}
+
+	usr, exists := memdb.Get[user.User](s.db, tableUsers, userID.String())
+	return exists && !usr.DeletedAt.IsZero()
+}
+
+// matches returns whether a subscription matches the filter at the time.
+// Deleted subscriptions only match when the filter asks for them.
+func matches(filter subscription.QueryFilter, now time.Time) func(sub subscription.Subscription) bool {
+	return func(sub subscription.Subscription) bool {
+		if sub.DeletedAt.IsZero() == filter.Deleted {
+			return false
+		}
+
+		if filter.TopicID != nil && sub.TopicID != *filter.TopicID {
+			return false
+		}
+
+		if filter.UserID != nil && sub.UserID != *filter.UserID {
+			return false
+		}
+
+		if filter.GroupID != nil && sub.GroupID != *filter.GroupID {
+			return false
+		}
+
+		if filter.Muted != nil && sub.IsMuted(now) != *filter.Muted {
+			return false
+		}
+
+		return true
+	}
+}
+
+func compareID(a, b subscription.Subscription) int {
+	return memdb.CompareIDs(a.ID, b.ID)
+}
+
+// pair returns the values joined as the key of a unique constraint, when the
+// constraint applies.
+func pair(applies bool, a string, b string) (string, bool) {
+	if !applies {
+		return "", false
+	}
+
+	return a + "/" + b, true
+}
+
+// clone copies the subscription, so the stored subscription shares no memory
+// with the callers.
+func clone(sub subscription.Subscription) subscription.Subscription {
+	sub.Filter.Tags = slices.Clone(sub.Filter.Tags)
+	sub.Routes = slices.Clone(sub.Routes)
+	return sub
+}
+
+func cloneSlice(subs []subscription.Subscription) []subscription.Subscription {
+	for i := range subs {
+		subs[i] = clone(subs[i])
+	}
+	return subs
+}
+
+// cloneJob copies the job, so the stored job shares no memory with the
+// callers.
+func cloneJob(job subscription.Job) subscription.Job {
+	job.Rows = slices.Clone(job.Rows)
+	job.Errors = slices.Clone(job.Errors)
+	return job
+}
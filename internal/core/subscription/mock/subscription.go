@@ -5,9 +5,12 @@
 package mocksub
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	subscription "github.com/dudakovict/gotify/internal/core/subscription"
+	order "github.com/dudakovict/gotify/platform/order"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 )
@@ -35,74 +38,235 @@ func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *MockStorer) Count(arg0 context.Context, arg1 subscription.QueryFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockStorerMockRecorder) Count(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockStorer)(nil).Count), arg0, arg1)
+}
+
 // Create mocks base method.
-func (m *MockStorer) Create(arg0 subscription.Subscription) error {
+func (m *MockStorer) Create(arg0 context.Context, arg1 subscription.Subscription) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockStorerMockRecorder) Create(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
+}
+
+// CreateJob mocks base method.
+func (m *MockStorer) CreateJob(arg0 context.Context, arg1 subscription.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJob", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateJob indicates an expected call of CreateJob.
+func (mr *MockStorerMockRecorder) CreateJob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockStorer)(nil).CreateJob), arg0, arg1)
 }
 
 // Delete mocks base method.
-func (m *MockStorer) Delete(arg0 subscription.Subscription) error {
+func (m *MockStorer) Delete(arg0 context.Context, arg1 subscription.Subscription) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockStorerMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// Purge mocks base method.
+func (m *MockStorer) Purge(arg0 context.Context, arg1 time.Time, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorerMockRecorder) Purge(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorer)(nil).Purge), arg0, arg1, arg2)
 }
 
 // Query mocks base method.
-func (m *MockStorer) Query(arg0, arg1 int) ([]subscription.Subscription, error) {
+func (m *MockStorer) Query(arg0 context.Context, arg1 subscription.QueryFilter, arg2 order.By, arg3, arg4 int) ([]subscription.Subscription, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Query", arg0, arg1)
+	ret := m.ctrl.Call(m, "Query", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].([]subscription.Subscription)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Query indicates an expected call of Query.
-func (mr *MockStorerMockRecorder) Query(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Query(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1, arg2, arg3, arg4)
+}
+
+// QueryByGuest mocks base method.
+func (m *MockStorer) QueryByGuest(arg0 context.Context, arg1 uuid.UUID, arg2 string) (subscription.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByGuest", arg0, arg1, arg2)
+	ret0, _ := ret[0].(subscription.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByGuest indicates an expected call of QueryByGuest.
+func (mr *MockStorerMockRecorder) QueryByGuest(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByGuest", reflect.TypeOf((*MockStorer)(nil).QueryByGuest), arg0, arg1, arg2)
 }
 
 // QueryByID mocks base method.
-func (m *MockStorer) QueryByID(arg0 uuid.UUID) (subscription.Subscription, error) {
+func (m *MockStorer) QueryByID(arg0 context.Context, arg1 uuid.UUID) (subscription.Subscription, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryByID", arg0)
+	ret := m.ctrl.Call(m, "QueryByID", arg0, arg1)
 	ret0, _ := ret[0].(subscription.Subscription)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryByID indicates an expected call of QueryByID.
-func (mr *MockStorerMockRecorder) QueryByID(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) QueryByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0, arg1)
+}
+
+// QueryConfirmedGuests mocks base method.
+func (m *MockStorer) QueryConfirmedGuests(arg0 context.Context, arg1 uuid.UUID) ([]subscription.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryConfirmedGuests", arg0, arg1)
+	ret0, _ := ret[0].([]subscription.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryConfirmedGuests indicates an expected call of QueryConfirmedGuests.
+func (mr *MockStorerMockRecorder) QueryConfirmedGuests(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryConfirmedGuests", reflect.TypeOf((*MockStorer)(nil).QueryConfirmedGuests), arg0, arg1)
+}
+
+// QueryDeletedByID mocks base method.
+func (m *MockStorer) QueryDeletedByID(arg0 context.Context, arg1 uuid.UUID) (subscription.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryDeletedByID", arg0, arg1)
+	ret0, _ := ret[0].(subscription.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryDeletedByID indicates an expected call of QueryDeletedByID.
+func (mr *MockStorerMockRecorder) QueryDeletedByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryDeletedByID", reflect.TypeOf((*MockStorer)(nil).QueryDeletedByID), arg0, arg1)
+}
+
+// QueryJobByID mocks base method.
+func (m *MockStorer) QueryJobByID(arg0 context.Context, arg1 uuid.UUID) (subscription.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryJobByID", arg0, arg1)
+	ret0, _ := ret[0].(subscription.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryJobByID indicates an expected call of QueryJobByID.
+func (mr *MockStorerMockRecorder) QueryJobByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryJobByID", reflect.TypeOf((*MockStorer)(nil).QueryJobByID), arg0, arg1)
+}
+
+// QueryMatching mocks base method.
+func (m *MockStorer) QueryMatching(arg0 context.Context, arg1 uuid.UUID) ([]subscription.Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryMatching", arg0, arg1)
+	ret0, _ := ret[0].([]subscription.Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryMatching indicates an expected call of QueryMatching.
+func (mr *MockStorerMockRecorder) QueryMatching(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryMatching", reflect.TypeOf((*MockStorer)(nil).QueryMatching), arg0, arg1)
+}
+
+// Restore mocks base method.
+func (m *MockStorer) Restore(arg0 context.Context, arg1 subscription.Subscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorerMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorer)(nil).Restore), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockStorer) Update(arg0 context.Context, arg1 subscription.Subscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorerMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1)
+}
+
+// UpdateJob mocks base method.
+func (m *MockStorer) UpdateJob(arg0 context.Context, arg1 subscription.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJob", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateJob indicates an expected call of UpdateJob.
+func (mr *MockStorerMockRecorder) UpdateJob(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJob", reflect.TypeOf((*MockStorer)(nil).UpdateJob), arg0, arg1)
 }
 
 // WithinTran mocks base method.
-func (m *MockStorer) WithinTran(arg0 func(subscription.Storer) error) error {
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(subscription.Storer) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WithinTran", arg0)
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // WithinTran indicates an expected call of WithinTran.
-func (mr *MockStorerMockRecorder) WithinTran(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
 }
@@ -3,19 +3,84 @@ package subscription
 import (
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
 	"github.com/google/uuid"
 )
 
-// Subscription represents information about an individual subscription.
+// Subscription represents information about an individual subscription. It
+// is either to a single topic or to every topic matching the pattern. The
+// notifications go out on its routes, or on the user's default channels when
+// it has none. Muted subscriptions deliver nothing until the time they are
+// muted until, or until they are unmuted when that is zero. Guest
+// subscriptions belong to an email address instead of a user and deliver
+// nothing until the address confirms them. Group subscriptions deliver to the
+// members of the group at the time of the notification.
 type Subscription struct {
-	ID        uuid.UUID
-	TopicID   uuid.UUID
-	UserID    uuid.UUID
-	CreatedAt time.Time
+	ID         uuid.UUID
+	TopicID    uuid.UUID
+	Pattern    Pattern
+	UserID     uuid.UUID
+	GuestEmail string
+	GroupID    uuid.UUID
+	Confirmed  bool
+	Filter     Filter
+	Routes     []notification.Route
+	Muted      bool
+	MutedUntil time.Time
+	Version    int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  time.Time
+}
+
+// IsMuted reports whether the subscription is muted at the time.
+func (s Subscription) IsMuted(now time.Time) bool {
+	return s.Muted && (s.MutedUntil.IsZero() || now.Before(s.MutedUntil))
+}
+
+// IsGuest reports whether the subscription belongs to a guest.
+func (s Subscription) IsGuest() bool {
+	return s.GuestEmail != ""
+}
+
+// IsGroup reports whether the subscription belongs to a group.
+func (s Subscription) IsGroup() bool {
+	return s.GroupID != uuid.Nil
 }
 
 // NewSubscription contains information needed to create a new subscription.
+// Exactly one of the topic and the pattern has to be set, and exactly one of
+// the user and the group. Group subscriptions have no routes of their own, as
+// each member gets them on their default channels.
 type NewSubscription struct {
 	TopicID uuid.UUID
+	Pattern Pattern
 	UserID  uuid.UUID
+	GroupID uuid.UUID
+	Filter  Filter
+	Routes  []notification.Route
+}
+
+// NewGuestSubscription contains information needed to subscribe an email
+// address to a topic. AfterCreate runs in the same transaction, so the
+// subscription is only kept when asking for its confirmation succeeds.
+type NewGuestSubscription struct {
+	TopicID     uuid.UUID
+	Email       string
+	AfterCreate func(sub Subscription) error
+}
+
+// UpdateSubscription defines what information may be provided to modify an
+// existing subscription.
+type UpdateSubscription struct {
+	Filter *Filter
+	Routes []notification.Route
+}
+
+// Delivery is a route a notification goes out on, along with the
+// subscription picking it and the group it came through, if any.
+type Delivery struct {
+	notification.Route
+	SubscriptionID uuid.UUID
+	GroupID        uuid.UUID
 }
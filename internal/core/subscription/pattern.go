@@ -0,0 +1,155 @@
+package subscription
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dudakovict/gotify/internal/core/topic"
+)
+
+// Set of wildcards a pattern can use in place of a segment of a topic name.
+// A star matches exactly one segment and a hash matches any number of them,
+// including none, so infra.* matches infra.db and infra.# matches infra,
+// infra.db and infra.db.primary.
+const (
+	WildcardOne  = "*"
+	WildcardMany = "#"
+)
+
+// Pattern represents a set of topics by their hierarchical names.
+type Pattern struct {
+	value string
+}
+
+// ParsePattern parses the string value and returns a pattern if it is valid.
+func ParsePattern(value string) (Pattern, error) {
+	segments := topic.Segments(value)
+
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+			return Pattern{}, fmt.Errorf("%w %q: empty segment", ErrInvalidPattern, value)
+		case segment == WildcardOne || segment == WildcardMany:
+			if segment == WildcardMany && i > 0 && segments[i-1] == WildcardMany {
+				return Pattern{}, fmt.Errorf("%w %q: repeated %s", ErrInvalidPattern, value, WildcardMany)
+			}
+		case strings.ContainsAny(segment, WildcardOne+WildcardMany):
+			return Pattern{}, fmt.Errorf("%w %q: wildcards must be whole segments", ErrInvalidPattern, value)
+		}
+	}
+
+	return Pattern{value}, nil
+}
+
+// MustParsePattern parses the string value and returns a pattern if it is
+// valid. If an error occurs the function panics.
+func MustParsePattern(value string) Pattern {
+	pattern, err := ParsePattern(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return pattern
+}
+
+// String returns the pattern as it was given.
+func (p Pattern) String() string {
+	return p.value
+}
+
+// IsZero reports whether the pattern is missing.
+func (p Pattern) IsZero() bool {
+	return p.value == ""
+}
+
+// Root returns the first segment of the pattern, or an empty string when the
+// pattern starts with a wildcard. Only topics under the root can match, which
+// lets stores narrow down the patterns to check.
+func (p Pattern) Root() string {
+	root := topic.Segments(p.value)[0]
+	if root == WildcardOne || root == WildcardMany {
+		return ""
+	}
+
+	return root
+}
+
+// Regexp returns the pattern as an anchored regular expression matching the
+// full topic names. It only uses syntax Go and PostgreSQL agree on.
+func (p Pattern) Regexp() string {
+	const (
+		sep     = "[" + topic.Separators + "]"
+		segment = "[^" + topic.Separators + "]+"
+	)
+
+	segments := topic.Segments(p.value)
+
+	var b strings.Builder
+	b.WriteString("^")
+
+	afterMany := false
+	for i, s := range segments {
+		switch s {
+		case WildcardMany:
+			switch {
+			case len(segments) == 1:
+				b.WriteString(".*")
+			case i == 0:
+				b.WriteString("(.+" + sep + ")?")
+			default:
+				b.WriteString("(" + sep + ".+)?")
+				if i < len(segments)-1 {
+					b.WriteString(sep)
+				}
+			}
+			afterMany = true
+			continue
+		}
+
+		if i > 0 && !afterMany {
+			b.WriteString(sep)
+		}
+		afterMany = false
+
+		if s == WildcardOne {
+			b.WriteString(segment)
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(s))
+	}
+
+	b.WriteString("$")
+
+	return b.String()
+}
+
+// Match reports whether the topic name is matched by the pattern.
+func (p Pattern) Match(name string) bool {
+	if p.IsZero() {
+		return false
+	}
+
+	return regexp.MustCompile(p.Regexp()).MatchString(name)
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (p *Pattern) UnmarshalText(data []byte) error {
+	pattern, err := ParsePattern(string(data))
+	if err != nil {
+		return err
+	}
+
+	p.value = pattern.value
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (p Pattern) MarshalText() ([]byte, error) {
+	return []byte(p.value), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (p Pattern) Equal(p2 Pattern) bool {
+	return p.value == p2.value
+}
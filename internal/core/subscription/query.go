@@ -0,0 +1,30 @@
+package subscription
+
+import (
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+)
+
+// Set of fields the subscriptions can be ordered by.
+const (
+	OrderByID        = "id"
+	OrderByCreatedAt = "created_at"
+)
+
+// OrderByFields lists the fields the subscriptions can be ordered by.
+var OrderByFields = []string{OrderByID, OrderByCreatedAt}
+
+// DefaultOrderBy is the order subscriptions are listed in unless asked
+// otherwise.
+var DefaultOrderBy = order.NewBy(OrderByCreatedAt, order.ASC)
+
+// QueryFilter holds the fields subscriptions can be filtered by. Fields left
+// nil match every subscription. Deleted subscriptions are only listed when
+// Deleted is set, and then only them.
+type QueryFilter struct {
+	TopicID *uuid.UUID
+	UserID  *uuid.UUID
+	GroupID *uuid.UUID
+	Muted   *bool
+	Deleted bool
+}
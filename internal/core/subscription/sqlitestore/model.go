@@ -0,0 +1,339 @@
+package subscriptionlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/google/uuid"
+)
+
+// Subscription represents information about an individual subscription. The
+// root and the regular expression of a pattern are stored with it so the
+// subscribers of a topic can be resolved in the database.
+type dbSubscription struct {
+	ID           uuid.UUID      `db:"id"`
+	TopicID      uuid.NullUUID  `db:"topic_id"`
+	Pattern      sql.NullString `db:"pattern"`
+	PatternRoot  sql.NullString `db:"pattern_root"`
+	PatternRegex sql.NullString `db:"pattern_regex"`
+	UserID       uuid.NullUUID  `db:"user_id"`
+	GuestEmail   sql.NullString `db:"guest_email"`
+	GroupID      uuid.NullUUID  `db:"group_id"`
+	Confirmed    bool           `db:"confirmed"`
+	Filter       string         `db:"filter"`
+	Routes       string         `db:"routes"`
+	Muted        bool           `db:"muted"`
+	MutedUntil   sql.NullTime   `db:"muted_until"`
+	Version      int            `db:"version"`
+	CreatedAt    time.Time      `db:"created_at"`
+	UpdatedAt    sql.NullTime   `db:"updated_at"`
+	DeletedAt    sql.NullTime   `db:"deleted_at"`
+}
+
+type dbFilter struct {
+	MinPriority string   `json:"min_priority,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Keyword     string   `json:"keyword,omitempty"`
+	Regex       string   `json:"regex,omitempty"`
+}
+
+type dbRoute struct {
+	Channel   string `json:"channel"`
+	Address   string `json:"address,omitempty"`
+	Confirmed bool   `json:"confirmed,omitempty"`
+}
+
+func toDBSubscription(sub subscription.Subscription) (dbSubscription, error) {
+	f := dbFilter{
+		MinPriority: sub.Filter.MinPriority.Name(),
+		Tags:        sub.Filter.Tags,
+		Keyword:     sub.Filter.Keyword,
+	}
+
+	if sub.Filter.Regex != nil {
+		f.Regex = sub.Filter.Regex.String()
+	}
+
+	filter, err := json.Marshal(f)
+	if err != nil {
+		return dbSubscription{}, fmt.Errorf("marshal filter: %w", err)
+	}
+
+	rts := make([]dbRoute, len(sub.Routes))
+	for i, route := range sub.Routes {
+		rts[i] = dbRoute{
+			Channel:   route.Channel.Name(),
+			Address:   route.Address,
+			Confirmed: route.Confirmed,
+		}
+	}
+
+	routes, err := json.Marshal(rts)
+	if err != nil {
+		return dbSubscription{}, fmt.Errorf("marshal routes: %w", err)
+	}
+
+	dbSub := dbSubscription{
+		ID: sub.ID,
+		TopicID: uuid.NullUUID{
+			UUID:  sub.TopicID,
+			Valid: sub.TopicID != uuid.Nil,
+		},
+		UserID: uuid.NullUUID{
+			UUID:  sub.UserID,
+			Valid: sub.UserID != uuid.Nil,
+		},
+		GuestEmail: sql.NullString{
+			String: sub.GuestEmail,
+			Valid:  sub.GuestEmail != "",
+		},
+		GroupID: uuid.NullUUID{
+			UUID:  sub.GroupID,
+			Valid: sub.GroupID != uuid.Nil,
+		},
+		Confirmed: sub.Confirmed,
+		Filter:    string(filter),
+		Routes:    string(routes),
+		Muted:     sub.Muted,
+		MutedUntil: sql.NullTime{
+			Time:  sub.MutedUntil.UTC(),
+			Valid: !sub.MutedUntil.IsZero(),
+		},
+		Version:   sub.Version,
+		CreatedAt: sub.CreatedAt.UTC(),
+		UpdatedAt: sql.NullTime{
+			Time:  sub.UpdatedAt.UTC(),
+			Valid: !sub.UpdatedAt.IsZero(),
+		},
+		DeletedAt: sql.NullTime{
+			Time:  sub.DeletedAt.UTC(),
+			Valid: !sub.DeletedAt.IsZero(),
+		},
+	}
+
+	if !sub.Pattern.IsZero() {
+		dbSub.Pattern = sql.NullString{String: sub.Pattern.String(), Valid: true}
+		dbSub.PatternRoot = sql.NullString{String: sub.Pattern.Root(), Valid: true}
+		dbSub.PatternRegex = sql.NullString{String: sub.Pattern.Regexp(), Valid: true}
+	}
+
+	return dbSub, nil
+}
+
+func toCoreSubscription(dbSub dbSubscription) (subscription.Subscription, error) {
+	sub := subscription.Subscription{
+		ID:         dbSub.ID,
+		TopicID:    dbSub.TopicID.UUID,
+		UserID:     dbSub.UserID.UUID,
+		GuestEmail: dbSub.GuestEmail.String,
+		GroupID:    dbSub.GroupID.UUID,
+		Confirmed:  dbSub.Confirmed,
+		Version:    dbSub.Version,
+		CreatedAt:  dbSub.CreatedAt,
+	}
+
+	if dbSub.Pattern.Valid {
+		pattern, err := subscription.ParsePattern(dbSub.Pattern.String)
+		if err != nil {
+			return subscription.Subscription{}, fmt.Errorf("parse pattern: %w", err)
+		}
+		sub.Pattern = pattern
+	}
+
+	var f dbFilter
+	if err := json.Unmarshal([]byte(dbSub.Filter), &f); err != nil {
+		return subscription.Subscription{}, fmt.Errorf("unmarshal filter: %w", err)
+	}
+
+	sub.Filter = subscription.Filter{
+		Tags:    f.Tags,
+		Keyword: f.Keyword,
+	}
+
+	if f.MinPriority != "" {
+		priority, err := notification.ParsePriority(f.MinPriority)
+		if err != nil {
+			return subscription.Subscription{}, fmt.Errorf("parse priority: %w", err)
+		}
+		sub.Filter.MinPriority = priority
+	}
+
+	if f.Regex != "" {
+		re, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return subscription.Subscription{}, fmt.Errorf("compile regex: %w", err)
+		}
+		sub.Filter.Regex = re
+	}
+
+	var rts []dbRoute
+	if err := json.Unmarshal([]byte(dbSub.Routes), &rts); err != nil {
+		return subscription.Subscription{}, fmt.Errorf("unmarshal routes: %w", err)
+	}
+
+	for _, rt := range rts {
+		channel, err := notification.ParseChannel(rt.Channel)
+		if err != nil {
+			return subscription.Subscription{}, fmt.Errorf("parse channel: %w", err)
+		}
+		sub.Routes = append(sub.Routes, notification.Route{Channel: channel, Address: rt.Address, Confirmed: rt.Confirmed})
+	}
+
+	sub.Muted = dbSub.Muted
+	if dbSub.MutedUntil.Valid {
+		sub.MutedUntil = dbSub.MutedUntil.Time.In(time.Local)
+	}
+
+	if dbSub.UpdatedAt.Valid {
+		sub.UpdatedAt = dbSub.UpdatedAt.Time.In(time.Local)
+	}
+
+	if dbSub.DeletedAt.Valid {
+		sub.DeletedAt = dbSub.DeletedAt.Time.In(time.Local)
+	}
+
+	return sub, nil
+}
+
+func toCoreSubscriptionSlice(dbSubs []dbSubscription) ([]subscription.Subscription, error) {
+	subs := make([]subscription.Subscription, len(dbSubs))
+
+	for i, dbSub := range dbSubs {
+		var err error
+		subs[i], err = toCoreSubscription(dbSub)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return subs, nil
+}
+
+// =============================================================================
+
+type dbJob struct {
+	ID        uuid.UUID     `db:"id"`
+	Status    string        `db:"status"`
+	Rows      string        `db:"rows"`
+	Processed int           `db:"processed"`
+	Created   int           `db:"created"`
+	Existing  int           `db:"existing"`
+	Errors    string        `db:"errors"`
+	CreatedBy uuid.NullUUID `db:"created_by"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+}
+
+type dbJobRow struct {
+	UserID  *uuid.UUID `json:"user_id,omitempty"`
+	Email   string     `json:"email,omitempty"`
+	TopicID *uuid.UUID `json:"topic_id,omitempty"`
+	Topic   string     `json:"topic,omitempty"`
+}
+
+type dbRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+func toDBJob(job subscription.Job) (dbJob, error) {
+	rows := make([]dbJobRow, len(job.Rows))
+	for i, row := range job.Rows {
+		rows[i] = dbJobRow{
+			Email: row.Email,
+			Topic: row.Topic,
+		}
+		if row.UserID != uuid.Nil {
+			rows[i].UserID = &row.UserID
+		}
+		if row.TopicID != uuid.Nil {
+			rows[i].TopicID = &row.TopicID
+		}
+	}
+
+	rws, err := json.Marshal(rows)
+	if err != nil {
+		return dbJob{}, fmt.Errorf("marshal rows: %w", err)
+	}
+
+	errs := make([]dbRowError, len(job.Errors))
+	for i, rowErr := range job.Errors {
+		errs[i] = dbRowError(rowErr)
+	}
+
+	es, err := json.Marshal(errs)
+	if err != nil {
+		return dbJob{}, fmt.Errorf("marshal errors: %w", err)
+	}
+
+	dbJb := dbJob{
+		ID:        job.ID,
+		Status:    job.Status.Name(),
+		Rows:      string(rws),
+		Processed: job.Processed,
+		Created:   job.Created,
+		Existing:  job.Existing,
+		Errors:    string(es),
+		CreatedBy: uuid.NullUUID{
+			UUID:  job.CreatedBy,
+			Valid: job.CreatedBy != uuid.Nil,
+		},
+		CreatedAt: job.CreatedAt.UTC(),
+		UpdatedAt: job.UpdatedAt.UTC(),
+	}
+
+	return dbJb, nil
+}
+
+func toCoreJob(dbJb dbJob) (subscription.Job, error) {
+	status, err := subscription.ParseJobStatus(dbJb.Status)
+	if err != nil {
+		return subscription.Job{}, fmt.Errorf("parse status: %w", err)
+	}
+
+	var rows []dbJobRow
+	if err := json.Unmarshal([]byte(dbJb.Rows), &rows); err != nil {
+		return subscription.Job{}, fmt.Errorf("unmarshal rows: %w", err)
+	}
+
+	var errs []dbRowError
+	if err := json.Unmarshal([]byte(dbJb.Errors), &errs); err != nil {
+		return subscription.Job{}, fmt.Errorf("unmarshal errors: %w", err)
+	}
+
+	job := subscription.Job{
+		ID:        dbJb.ID,
+		Status:    status,
+		Rows:      make([]subscription.JobRow, len(rows)),
+		Processed: dbJb.Processed,
+		Created:   dbJb.Created,
+		Existing:  dbJb.Existing,
+		CreatedBy: dbJb.CreatedBy.UUID,
+		CreatedAt: dbJb.CreatedAt.In(time.Local),
+		UpdatedAt: dbJb.UpdatedAt.In(time.Local),
+	}
+
+	for i, row := range rows {
+		job.Rows[i] = subscription.JobRow{
+			Email: row.Email,
+			Topic: row.Topic,
+		}
+		if row.UserID != nil {
+			job.Rows[i].UserID = *row.UserID
+		}
+		if row.TopicID != nil {
+			job.Rows[i].TopicID = *row.TopicID
+		}
+	}
+
+	for _, rowErr := range errs {
+		job.Errors = append(job.Errors, subscription.RowError(rowErr))
+	}
+
+	return job, nil
+}
@@ -0,0 +1,48 @@
+package subscriptionlite
+
+import (
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/dudakovict/gotify/platform/database"
+)
+
+// orderByFields maps the fields subscriptions can be ordered by to their
+// columns.
+var orderByFields = map[string]string{
+	subscription.OrderByID:        "id",
+	subscription.OrderByCreatedAt: "created_at",
+}
+
+// applyFilter adds the conditions of the filter to the query.
+func applyFilter(b *database.QueryBuilder, filter subscription.QueryFilter) {
+	if filter.Deleted {
+		b.Where("deleted_at IS NOT NULL", nil)
+	} else {
+		b.Where("deleted_at IS NULL", nil)
+	}
+
+	if filter.TopicID != nil {
+		b.Where("topic_id = :topic_id", map[string]any{"topic_id": *filter.TopicID})
+	}
+
+	if filter.UserID != nil {
+		b.Where("user_id = :user_id", map[string]any{"user_id": *filter.UserID})
+	}
+
+	if filter.GroupID != nil {
+		b.Where("group_id = :group_id", map[string]any{"group_id": *filter.GroupID})
+	}
+
+	if filter.Muted != nil {
+		const muted = `muted AND (muted_until IS NULL OR muted_until > :now)`
+		now := map[string]any{"now": time.Now().UTC()}
+
+		switch *filter.Muted {
+		case true:
+			b.Where(muted, now)
+		default:
+			b.Where("NOT ("+muted+")", now)
+		}
+	}
+}
@@ -2,20 +2,25 @@
 package subscriptiondb
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/dudakovict/gotify/internal/core/topic"
 	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
 // Store manages the set of APIs for subscription database access.
 type Store struct {
 	log    *zerolog.Logger
-	db     sqlx.Ext
+	db     sqlx.ExtContext
 	inTran bool
 }
 
@@ -28,7 +33,7 @@ func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
 }
 
 // WithinTran runs passed function and do commit/rollback at the end.
-func (s *Store) WithinTran(fn func(subscription.Storer) error) error {
+func (s *Store) WithinTran(ctx context.Context, fn func(subscription.Storer) error) error {
 	if s.inTran {
 		return fn(s)
 	}
@@ -42,18 +47,23 @@ func (s *Store) WithinTran(fn func(subscription.Storer) error) error {
 		return fn(s)
 	}
 
-	return database.WithinTran(s.log, s.db.(*sqlx.DB), f)
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
 }
 
 // Create inserts a new subscription into the database.
-func (s *Store) Create(sub subscription.Subscription) error {
+func (s *Store) Create(ctx context.Context, sub subscription.Subscription) error {
 	const q = `
 	INSERT INTO subscriptions
-		(id, topic_id, user_id, created_at)
+		(id, topic_id, pattern, pattern_root, pattern_regex, user_id, guest_email, group_id, confirmed, filter, routes, muted, muted_until, version, created_at, updated_at)
 	VALUES
-		(:id, :topic_id, :user_id, :created_at)`
+		(:id, :topic_id, :pattern, :pattern_root, :pattern_regex, :user_id, :guest_email, :group_id, :confirmed, :filter, :routes, :muted, :muted_until, :version, :created_at, :updated_at)`
 
-	if err := database.NamedExec(s.log, s.db, q, toDBSubscription(sub)); err != nil {
+	dbSub, err := toDBSubscription(sub)
+	if err != nil {
+		return err
+	}
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbSub); err != nil {
 		if errors.Is(err, database.ErrUniqueViolation) {
 			return subscription.ErrUniqueViolation
 		}
@@ -63,61 +73,384 @@ func (s *Store) Create(sub subscription.Subscription) error {
 	return nil
 }
 
-// Delete removes a subscription from the database.
-func (s *Store) Delete(sub subscription.Subscription) error {
+// Update replaces the filter, the routes, the muting and the confirmation of a
+// subscription in the database, as long as it's still at the version of the
+// subscription.
+func (s *Store) Update(ctx context.Context, sub subscription.Subscription) error {
+	const q = `
+	UPDATE
+		subscriptions
+	SET
+		filter = :filter,
+		routes = :routes,
+		muted = :muted,
+		muted_until = :muted_until,
+		confirmed = :confirmed,
+		updated_at = :updated_at,
+		version = version + 1
+	WHERE
+		id = :id
+		AND version = :version`
+
+	dbSub, err := toDBSubscription(sub)
+	if err != nil {
+		return err
+	}
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, dbSub)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return subscription.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Delete marks a subscription as deleted in the database, as long as it's
+// still at the version of the subscription.
+func (s *Store) Delete(ctx context.Context, sub subscription.Subscription) error {
 	data := struct {
-		ID string `db:"id"`
+		ID        string    `db:"id"`
+		DeletedAt time.Time `db:"deleted_at"`
+		Version   int       `db:"version"`
 	}{
-		ID: sub.ID.String(),
+		ID:        sub.ID.String(),
+		DeletedAt: sub.DeletedAt.UTC(),
+		Version:   sub.Version,
 	}
 
 	const q = `
-	DELETE FROM
+	UPDATE
 		subscriptions
+	SET
+		deleted_at = :deleted_at
+	WHERE
+		id = :id AND deleted_at IS NULL
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return subscription.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Restore clears the deletion of a subscription in the database.
+func (s *Store) Restore(ctx context.Context, sub subscription.Subscription) error {
+	data := struct {
+		ID        string    `db:"id"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}{
+		ID:        sub.ID.String(),
+		UpdatedAt: sub.UpdatedAt.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		subscriptions
+	SET
+		deleted_at = NULL,
+		updated_at = :updated_at
 	WHERE
 		id = :id`
 
-	if err := database.NamedExec(s.log, s.db, q, data); err != nil {
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return subscription.ErrUniqueViolation
+		}
 		return fmt.Errorf("namedexec: %w", err)
 	}
 
 	return nil
 }
 
+// Purge removes up to limit subscriptions deleted before the given time from
+// the database.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+		Limit  int       `db:"limit"`
+	}{
+		Before: before.UTC(),
+		Limit:  limit,
+	}
+
+	const q = `
+	DELETE FROM
+		subscriptions
+	WHERE
+		id IN (SELECT id FROM subscriptions WHERE deleted_at < :before LIMIT :limit)`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	return n, nil
+}
+
 // Query retrieves a list of existing subscriptions from the database.
-func (s *Store) Query(pageNumber int, rowsPerPage int) ([]subscription.Subscription, error) {
-	data := map[string]interface{}{
-		"offset":        (pageNumber - 1) * rowsPerPage,
-		"rows_per_page": rowsPerPage,
+func (s *Store) Query(ctx context.Context, filter subscription.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]subscription.Subscription, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM subscriptions`)
+	applyFilter(b, filter)
+
+	if err := b.OrderBy(orderBy, orderByFields, "id"); err != nil {
+		return nil, err
 	}
+	b.Page(pageNumber, rowsPerPage)
 
-	const q = `SELECT * FROM subscriptions`
+	q, data := b.Query()
 
 	var dbSubs []dbSubscription
-	if err := database.NamedQuerySlice(s.log, s.db, q, data, &dbSubs); err != nil {
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSubs); err != nil {
 		return nil, fmt.Errorf("namedqueryslice: %w", err)
 	}
 
-	return toCoreSubscriptionSlice(dbSubs), nil
+	return toCoreSubscriptionSlice(dbSubs)
+}
+
+// Count returns the number of subscriptions in the database matching the
+// filter.
+func (s *Store) Count(ctx context.Context, filter subscription.QueryFilter) (int, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM subscriptions`)
+	applyFilter(b, filter)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
 }
 
 // QueryByID gets the specified subscription from the database by id.
-func (s *Store) QueryByID(id uuid.UUID) (subscription.Subscription, error) {
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (subscription.Subscription, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM subscriptions WHERE id = :id AND deleted_at IS NULL`
+
+	var dbSub dbSubscription
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSub); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return subscription.Subscription{}, subscription.ErrNotFound
+		}
+		return subscription.Subscription{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreSubscription(dbSub)
+}
+
+// QueryDeletedByID gets the specified deleted subscription from the database
+// by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (subscription.Subscription, error) {
 	data := struct {
 		ID uuid.UUID `db:"id"`
 	}{
 		ID: id,
 	}
 
-	const q = `SELECT * FROM subscriptions WHERE id = :id`
+	const q = `SELECT * FROM subscriptions WHERE id = :id AND deleted_at IS NOT NULL`
 
 	var dbSub dbSubscription
-	if err := database.NamedQueryStruct(s.log, s.db, q, data, &dbSub); err != nil {
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSub); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return subscription.Subscription{}, subscription.ErrNotFound
 		}
 		return subscription.Subscription{}, fmt.Errorf("namedquerystruct: %w", err)
 	}
 
-	return toCoreSubscription(dbSub), nil
+	return toCoreSubscription(dbSub)
+}
+
+// QueryByGuest gets the subscription of the email address to the topic from
+// the database.
+func (s *Store) QueryByGuest(ctx context.Context, topicID uuid.UUID, email string) (subscription.Subscription, error) {
+	data := struct {
+		TopicID    uuid.UUID `db:"topic_id"`
+		GuestEmail string    `db:"guest_email"`
+	}{
+		TopicID:    topicID,
+		GuestEmail: email,
+	}
+
+	const q = `SELECT * FROM subscriptions WHERE topic_id = :topic_id AND guest_email = :guest_email AND deleted_at IS NULL`
+
+	var dbSub dbSubscription
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbSub); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return subscription.Subscription{}, subscription.ErrNotFound
+		}
+		return subscription.Subscription{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreSubscription(dbSub)
+}
+
+// QueryConfirmedGuests gets the confirmed guest subscriptions to the topic
+// from the database.
+func (s *Store) QueryConfirmedGuests(ctx context.Context, topicID uuid.UUID) ([]subscription.Subscription, error) {
+	data := struct {
+		TopicID uuid.UUID `db:"topic_id"`
+	}{
+		TopicID: topicID,
+	}
+
+	const q = `SELECT * FROM subscriptions WHERE topic_id = :topic_id AND guest_email IS NOT NULL AND confirmed AND deleted_at IS NULL`
+
+	var dbSubs []dbSubscription
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSubs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreSubscriptionSlice(dbSubs)
+}
+
+// QueryMatching gets the subscriptions to the topic from the database, either
+// direct or through a pattern matching its name. Patterns are narrowed down by
+// their root before matching. Both only match for users allowed to subscribe
+// to the topic, those of guests only match public topics and those of groups
+// match regardless, to be narrowed down to the members once expanded.
+// Unconfirmed guest subscriptions are left out, and so are deleted ones along
+// with those of deleted users.
+func (s *Store) QueryMatching(ctx context.Context, topicID uuid.UUID) ([]subscription.Subscription, error) {
+	data := struct {
+		TopicID          uuid.UUID      `db:"topic_id"`
+		PublicVisibility string         `db:"public_visibility"`
+		ReadableRights   pq.StringArray `db:"readable_rights"`
+	}{
+		TopicID:          topicID,
+		PublicVisibility: topic.VisibilityPublic.Name(),
+		ReadableRights:   pq.StringArray{topic.RightSubscribe.Name(), topic.RightManage.Name()},
+	}
+
+	// readable narrows the subscriptions down to those of users allowed to
+	// subscribe to the topic t. Groups are narrowed down to their members
+	// once expanded.
+	const readable = `(
+		s.group_id IS NOT NULL OR
+		t.visibility = :public_visibility OR
+		t.owner_id = s.user_id OR
+		EXISTS (
+			SELECT 1 FROM topic_acls a
+			WHERE a.topic_id = t.id AND a.user_id = s.user_id AND a.rights && CAST(:readable_rights AS VARCHAR[])
+		)
+	)`
+
+	const q = `
+	SELECT
+		s.*
+	FROM
+		topics t
+	JOIN
+		subscriptions s
+	ON
+		s.topic_id = t.id
+	WHERE
+		t.id = :topic_id AND
+		s.confirmed AND
+		s.deleted_at IS NULL AND
+		NOT EXISTS (SELECT 1 FROM users u WHERE u.id = s.user_id AND u.deleted_at IS NOT NULL) AND
+		` + readable + `
+	UNION ALL
+	SELECT
+		s.*
+	FROM
+		topics t
+	JOIN
+		subscriptions s
+	ON
+		s.pattern_root IN (split_part(translate(t.name, '/', '.'), '.', 1), '')
+	WHERE
+		t.id = :topic_id AND
+		s.deleted_at IS NULL AND
+		NOT EXISTS (SELECT 1 FROM users u WHERE u.id = s.user_id AND u.deleted_at IS NOT NULL) AND
+		t.name ~ s.pattern_regex AND
+		` + readable
+
+	var dbSubs []dbSubscription
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbSubs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreSubscriptionSlice(dbSubs)
+}
+
+// CreateJob inserts a new bulk subscription job into the database.
+func (s *Store) CreateJob(ctx context.Context, job subscription.Job) error {
+	const q = `
+	INSERT INTO subscription_jobs
+		(id, status, rows, processed, created, existing, errors, created_by, created_at, updated_at)
+	VALUES
+		(:id, :status, :rows, :processed, :created, :existing, :errors, :created_by, :created_at, :updated_at)`
+
+	dbJb, err := toDBJob(job)
+	if err != nil {
+		return err
+	}
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbJb); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateJob replaces the status and the progress of a job in the database.
+func (s *Store) UpdateJob(ctx context.Context, job subscription.Job) error {
+	const q = `
+	UPDATE
+		subscription_jobs
+	SET
+		status = :status,
+		processed = :processed,
+		created = :created,
+		existing = :existing,
+		errors = :errors,
+		updated_at = :updated_at
+	WHERE
+		id = :id`
+
+	dbJb, err := toDBJob(job)
+	if err != nil {
+		return err
+	}
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbJb); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryJobByID gets the specified job from the database by id.
+func (s *Store) QueryJobByID(ctx context.Context, id uuid.UUID) (subscription.Job, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM subscription_jobs WHERE id = :id`
+
+	var dbJb dbJob
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbJb); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return subscription.Job{}, subscription.ErrJobNotFound
+		}
+		return subscription.Job{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreJob(dbJb)
 }
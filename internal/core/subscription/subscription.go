@@ -2,27 +2,56 @@
 package subscription
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound        = errors.New("subscription not found")
-	ErrUniqueViolation = errors.New("subscription already exists")
+	ErrNotFound         = errors.New("subscription not found")
+	ErrUniqueViolation  = errors.New("subscription already exists")
+	ErrInvalidPattern   = errors.New("invalid pattern")
+	ErrInvalidTarget    = errors.New("either a topic or a pattern is required")
+	ErrInvalidOwner     = errors.New("either a user or a group is required")
+	ErrGroupRoutes      = errors.New("group subscriptions use the default channels of their members")
+	ErrInvalidMute      = errors.New("muted until has to be in the future")
+	ErrInvalidEmail     = errors.New("invalid email")
+	ErrJobNotFound      = errors.New("job not found")
+	ErrInvalidJob       = errors.New("invalid job")
+	ErrInvalidJobStatus = errors.New("invalid job status")
+	ErrVersionConflict  = errors.New("subscription was changed by someone else")
+	ErrRouteNotFound    = errors.New("subscription doesn't deliver to the address")
 )
 
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
-	WithinTran(fn func(s Storer) error) error
-	Create(sub Subscription) error
-	Delete(sub Subscription) error
-	Query(pageNumber int, rowsPerPage int) ([]Subscription, error)
-	QueryByID(id uuid.UUID) (Subscription, error)
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, sub Subscription) error
+	Update(ctx context.Context, sub Subscription) error
+	Delete(ctx context.Context, sub Subscription) error
+	Restore(ctx context.Context, sub Subscription) error
+	Purge(ctx context.Context, before time.Time, limit int) (int, error)
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Subscription, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, id uuid.UUID) (Subscription, error)
+	QueryDeletedByID(ctx context.Context, id uuid.UUID) (Subscription, error)
+	QueryByGuest(ctx context.Context, topicID uuid.UUID, email string) (Subscription, error)
+	QueryConfirmedGuests(ctx context.Context, topicID uuid.UUID) ([]Subscription, error)
+	QueryMatching(ctx context.Context, topicID uuid.UUID) ([]Subscription, error)
+	CreateJob(ctx context.Context, job Job) error
+	UpdateJob(ctx context.Context, job Job) error
+	QueryJobByID(ctx context.Context, id uuid.UUID) (Job, error)
 }
 
 // Core manages the set of APIs for subscription access.
@@ -40,32 +69,227 @@ func NewCore(log *zerolog.Logger, storer Storer) *Core {
 }
 
 // Create adds a new subscription to the system.
-func (c *Core) Create(ns NewSubscription) (Subscription, error) {
+func (c *Core) Create(ctx context.Context, ns NewSubscription) (Subscription, error) {
+	if (ns.TopicID == uuid.Nil) == ns.Pattern.IsZero() {
+		return Subscription{}, ErrInvalidTarget
+	}
+
+	if (ns.UserID == uuid.Nil) == (ns.GroupID == uuid.Nil) {
+		return Subscription{}, ErrInvalidOwner
+	}
+
+	if ns.GroupID != uuid.Nil && len(ns.Routes) > 0 {
+		return Subscription{}, ErrGroupRoutes
+	}
+
+	if err := validateRoutes(ns.Routes); err != nil {
+		return Subscription{}, err
+	}
+
 	sub := Subscription{
-		ID:      uuid.New(),
-		TopicID: ns.TopicID,
-		UserID:  ns.UserID,
+		ID:        uuid.New(),
+		TopicID:   ns.TopicID,
+		Pattern:   ns.Pattern,
+		UserID:    ns.UserID,
+		GroupID:   ns.GroupID,
+		Filter:    normalizeFilter(ns.Filter),
+		Routes:    notification.KeepConfirmed(ns.Routes, nil),
+		Confirmed: true,
+		Version:   1,
 	}
 
-	if err := c.storer.Create(sub); err != nil {
+	if err := c.storer.Create(ctx, sub); err != nil {
 		return Subscription{}, fmt.Errorf("create: %w", err)
 	}
 
 	return sub, nil
 }
 
-// Delete removes the specified subscription.
-func (c *Core) Delete(sub Subscription) error {
-	if err := c.storer.Delete(sub); err != nil {
+// CreateGuest subscribes an email address to a topic. The subscription
+// delivers nothing until it is confirmed.
+func (c *Core) CreateGuest(ctx context.Context, ngs NewGuestSubscription) (Subscription, error) {
+	if ngs.TopicID == uuid.Nil {
+		return Subscription{}, ErrInvalidTarget
+	}
+
+	addr, err := mail.ParseAddress(ngs.Email)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%w %q", ErrInvalidEmail, ngs.Email)
+	}
+
+	sub := Subscription{
+		ID:         uuid.New(),
+		TopicID:    ngs.TopicID,
+		GuestEmail: strings.ToLower(addr.Address),
+		Version:    1,
+	}
+
+	tran := func(s Storer) error {
+		if err := s.Create(ctx, sub); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+
+		if ngs.AfterCreate == nil {
+			return nil
+		}
+
+		return ngs.AfterCreate(sub)
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return Subscription{}, fmt.Errorf("tran: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Confirm makes a guest subscription deliver notifications. Confirming it
+// again does nothing.
+func (c *Core) Confirm(ctx context.Context, sub Subscription) (Subscription, error) {
+	if sub.Confirmed {
+		return sub, nil
+	}
+
+	sub.Confirmed = true
+	sub.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("update: %w", err)
+	}
+	sub.Version++
+
+	return sub, nil
+}
+
+// ConfirmRoute starts delivering to the address of the subscription routes on
+// the channel, once its owner confirmed it. Confirming it again does nothing.
+func (c *Core) ConfirmRoute(ctx context.Context, sub Subscription, channel notification.Channel, address string) (Subscription, error) {
+	routes := slices.Clone(sub.Routes)
+	if !notification.ConfirmAddress(routes, channel, address) {
+		return Subscription{}, ErrRouteNotFound
+	}
+
+	if slices.Equal(routes, sub.Routes) {
+		return sub, nil
+	}
+
+	sub.Routes = routes
+	sub.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("update: %w", err)
+	}
+	sub.Version++
+
+	return sub, nil
+}
+
+// Update modifies the filter and the routes of a subscription. Addresses
+// already confirmed stay confirmed, new ones need confirming.
+func (c *Core) Update(ctx context.Context, sub Subscription, us UpdateSubscription) (Subscription, error) {
+	if us.Filter != nil {
+		sub.Filter = normalizeFilter(*us.Filter)
+	}
+
+	if us.Routes != nil {
+		if err := validateRoutes(us.Routes); err != nil {
+			return Subscription{}, err
+		}
+		sub.Routes = notification.KeepConfirmed(us.Routes, sub.Routes)
+	}
+
+	sub.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("update: %w", err)
+	}
+	sub.Version++
+
+	return sub, nil
+}
+
+// Mute stops the subscription from delivering notifications until the time,
+// or until it is unmuted when the time is zero. Muting a muted subscription
+// replaces the time.
+func (c *Core) Mute(ctx context.Context, sub Subscription, until time.Time) (Subscription, error) {
+	now := time.Now()
+	if !until.IsZero() && !until.After(now) {
+		return Subscription{}, ErrInvalidMute
+	}
+
+	sub.Muted = true
+	sub.MutedUntil = until
+	sub.UpdatedAt = now
+
+	if err := c.storer.Update(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("update: %w", err)
+	}
+	sub.Version++
+
+	return sub, nil
+}
+
+// Unmute makes a muted subscription deliver notifications again before its
+// time is up. Unmuting a subscription that isn't muted does nothing.
+func (c *Core) Unmute(ctx context.Context, sub Subscription) (Subscription, error) {
+	if !sub.Muted {
+		return sub, nil
+	}
+
+	sub.Muted = false
+	sub.MutedUntil = time.Time{}
+	sub.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("update: %w", err)
+	}
+	sub.Version++
+
+	return sub, nil
+}
+
+// Delete marks the specified subscription as deleted. Deleted subscriptions
+// deliver nothing and are left out of every query until they are restored.
+// It fails with ErrVersionConflict when the subscription was changed since it
+// was read.
+func (c *Core) Delete(ctx context.Context, sub Subscription) error {
+	sub.DeletedAt = time.Now()
+
+	if err := c.storer.Delete(ctx, sub); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
 
 	return nil
 }
 
+// Restore brings back a deleted subscription. It fails with
+// ErrUniqueViolation when an equal subscription has been made in the
+// meantime.
+func (c *Core) Restore(ctx context.Context, sub Subscription) (Subscription, error) {
+	sub.DeletedAt = time.Time{}
+	sub.UpdatedAt = time.Now()
+
+	if err := c.storer.Restore(ctx, sub); err != nil {
+		return Subscription{}, fmt.Errorf("restore: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Purge removes up to limit subscriptions deleted before the given time for
+// good and returns how many were removed.
+func (c *Core) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	n, err := c.storer.Purge(ctx, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purge: %w", err)
+	}
+
+	return n, nil
+}
+
 // Query retrieves a list of existing subscriptions.
-func (c *Core) Query(pageNumber int, rowsPerPage int) ([]Subscription, error) {
-	subs, err := c.storer.Query(pageNumber, rowsPerPage)
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Subscription, error) {
+	subs, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -73,12 +297,282 @@ func (c *Core) Query(pageNumber int, rowsPerPage int) ([]Subscription, error) {
 	return subs, nil
 }
 
+// Count returns the number of subscriptions matching the filter.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	count, err := c.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
 // QueryByID finds the subscription by the specified ID.
-func (c *Core) QueryByID(id uuid.UUID) (Subscription, error) {
-	sub, err := c.storer.QueryByID(id)
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (Subscription, error) {
+	sub, err := c.storer.QueryByID(ctx, id)
 	if err != nil {
 		return Subscription{}, fmt.Errorf("query: %w", err)
 	}
 
 	return sub, nil
 }
+
+// QueryDeletedByID finds the deleted subscription by the specified ID.
+func (c *Core) QueryDeletedByID(ctx context.Context, id uuid.UUID) (Subscription, error) {
+	sub, err := c.storer.QueryDeletedByID(ctx, id)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("query: %w", err)
+	}
+
+	return sub, nil
+}
+
+// QueryByGuest finds the subscription of the email address to the topic.
+func (c *Core) QueryByGuest(ctx context.Context, topicID uuid.UUID, email string) (Subscription, error) {
+	sub, err := c.storer.QueryByGuest(ctx, topicID, strings.ToLower(email))
+	if err != nil {
+		return Subscription{}, fmt.Errorf("query: %w", err)
+	}
+
+	return sub, nil
+}
+
+// QueryConfirmedGuests retrieves the guest subscriptions to the topic that
+// were confirmed.
+func (c *Core) QueryConfirmedGuests(ctx context.Context, topicID uuid.UUID) ([]Subscription, error) {
+	subs, err := c.storer.QueryConfirmedGuests(ctx, topicID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return subs, nil
+}
+
+// QueryMatching retrieves the subscriptions a notification on the topic goes
+// to, whether they are to the topic itself or to a pattern matching it.
+// Subscriptions only match topics their users may subscribe to and those of
+// guests only public ones. Group subscriptions match regardless, and are left
+// to ExpandGroups to narrow down to the members allowed to subscribe.
+// Unconfirmed guest subscriptions match nothing.
+func (c *Core) QueryMatching(ctx context.Context, topicID uuid.UUID) ([]Subscription, error) {
+	subs, err := c.storer.QueryMatching(ctx, topicID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return subs, nil
+}
+
+// CreateJob adds a new bulk subscription job to the system, waiting for the
+// worker to pick it up.
+func (c *Core) CreateJob(ctx context.Context, nj NewJob) (Job, error) {
+	if len(nj.Rows) == 0 || len(nj.Rows) > MaxJobRows {
+		return Job{}, fmt.Errorf("%w: between 1 and %d rows are required", ErrInvalidJob, MaxJobRows)
+	}
+
+	now := time.Now()
+
+	job := Job{
+		ID:        uuid.New(),
+		Status:    JobPending,
+		Rows:      nj.Rows,
+		CreatedBy: nj.CreatedBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	tran := func(s Storer) error {
+		if err := s.CreateJob(ctx, job); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+
+		if nj.AfterCreate == nil {
+			return nil
+		}
+
+		return nj.AfterCreate(job)
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return Job{}, fmt.Errorf("tran: %w", err)
+	}
+
+	return job, nil
+}
+
+// UpdateJob saves the progress of a job, marking it done once every row was
+// processed.
+func (c *Core) UpdateJob(ctx context.Context, job Job) (Job, error) {
+	job.Status = JobRunning
+	if job.Finished() {
+		job.Status = JobDone
+	}
+
+	job.UpdatedAt = time.Now()
+
+	if err := c.storer.UpdateJob(ctx, job); err != nil {
+		return Job{}, fmt.Errorf("update: %w", err)
+	}
+
+	return job, nil
+}
+
+// QueryJobByID finds the job by the specified ID.
+func (c *Core) QueryJobByID(ctx context.Context, id uuid.UUID) (Job, error) {
+	job, err := c.storer.QueryJobByID(ctx, id)
+	if err != nil {
+		return Job{}, fmt.Errorf("query: %w", err)
+	}
+
+	return job, nil
+}
+
+// SplitMuted separates the subscriptions muted at the time from the ones
+// delivering notifications.
+func SplitMuted(subs []Subscription, now time.Time) (active []Subscription, muted []Subscription) {
+	for _, sub := range subs {
+		if sub.IsMuted(now) {
+			muted = append(muted, sub)
+			continue
+		}
+		active = append(active, sub)
+	}
+
+	return active, muted
+}
+
+// ExpandGroups replaces the group subscriptions with one for each member of
+// the group, keyed by group in members, that is allowed to subscribe to the
+// topic. The copies keep the group they came through and follow the
+// subscriptions of the users themselves, so those win when both pick the same
+// route.
+func ExpandGroups(subs []Subscription, members map[uuid.UUID][]uuid.UUID, allowed func(userID uuid.UUID) bool) []Subscription {
+	expanded := make([]Subscription, 0, len(subs))
+	var groupSubs []Subscription
+
+	for _, sub := range subs {
+		if sub.IsGroup() {
+			groupSubs = append(groupSubs, sub)
+			continue
+		}
+		expanded = append(expanded, sub)
+	}
+
+	for _, sub := range groupSubs {
+		for _, userID := range members[sub.GroupID] {
+			if !allowed(userID) {
+				continue
+			}
+
+			sub.UserID = userID
+			expanded = append(expanded, sub)
+		}
+	}
+
+	return expanded
+}
+
+// GroupIDs returns the groups owning any of the subscriptions, once each.
+func GroupIDs(subs []Subscription) []uuid.UUID {
+	var ids []uuid.UUID
+
+	for _, sub := range subs {
+		if sub.IsGroup() && !slices.Contains(ids, sub.GroupID) {
+			ids = append(ids, sub.GroupID)
+		}
+	}
+
+	return ids
+}
+
+// Recipients returns the users the notification is delivered to through the
+// subscriptions, in order. Users with several subscriptions get it once if any
+// of their filters lets it through. Guests are left out, as they are no users,
+// and so are groups that weren't expanded.
+func Recipients(subs []Subscription, ntf notification.Notification) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(subs))
+	userIDs := make([]uuid.UUID, 0, len(subs))
+
+	for _, sub := range subs {
+		if sub.IsGuest() || sub.UserID == uuid.Nil || seen[sub.UserID] || !sub.Filter.Match(ntf) {
+			continue
+		}
+
+		seen[sub.UserID] = true
+		userIDs = append(userIDs, sub.UserID)
+	}
+
+	return userIDs
+}
+
+// Deliveries returns where the notification goes for the user through the
+// subscriptions that let it through. Subscriptions without routes of their own
+// use the defaults. Addresses that weren't confirmed are replaced with the
+// ones the user has on record. Each route is returned once, along with the
+// first subscription picking it.
+func Deliveries(subs []Subscription, userID uuid.UUID, ntf notification.Notification, defaults []notification.Route) []Delivery {
+	var dlvs []Delivery
+
+	for _, sub := range subs {
+		if sub.UserID != userID || !sub.Filter.Match(ntf) {
+			continue
+		}
+
+		routes := sub.Routes
+		if len(routes) == 0 {
+			routes = defaults
+		}
+
+		for _, route := range routes {
+			route, ok := route.Deliverable()
+			if !ok {
+				continue
+			}
+
+			picked := slices.ContainsFunc(dlvs, func(d Delivery) bool {
+				return d.Route == route
+			})
+			if !picked {
+				dlvs = append(dlvs, Delivery{Route: route, SubscriptionID: sub.ID, GroupID: sub.GroupID})
+			}
+		}
+	}
+
+	return dlvs
+}
+
+// GuestDeliveries returns the emails the notification goes to through the
+// guest subscriptions that let it through.
+func GuestDeliveries(subs []Subscription, ntf notification.Notification) []Delivery {
+	var dlvs []Delivery
+
+	for _, sub := range subs {
+		if !sub.IsGuest() || !sub.Confirmed || !sub.Filter.Match(ntf) {
+			continue
+		}
+
+		route := notification.Route{Channel: notification.ChannelEmail, Address: sub.GuestEmail}
+		dlvs = append(dlvs, Delivery{Route: route, SubscriptionID: sub.ID})
+	}
+
+	return dlvs
+}
+
+// validateRoutes makes sure every route has an address when its channel
+// needs one.
+func validateRoutes(routes []notification.Route) error {
+	for _, route := range routes {
+		if err := route.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// normalizeFilter makes the tags of the filter compare like the tags of
+// notifications.
+func normalizeFilter(f Filter) Filter {
+	f.Tags = notification.NormalizeTags(f.Tags)
+	return f
+}
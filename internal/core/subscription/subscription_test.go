@@ -0,0 +1,811 @@
+package subscription_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	mocksub "github.com/dudakovict/gotify/internal/core/subscription/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternMatch(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{pattern: "infra.db.primary", name: "infra.db.primary", match: true},
+		{pattern: "infra.db.primary", name: "infra/db/primary", match: true},
+		{pattern: "infra.db.primary", name: "infra.db", match: false},
+		{pattern: "infra.*", name: "infra.db", match: true},
+		{pattern: "infra.*", name: "infra", match: false},
+		{pattern: "infra.*", name: "infra.db.primary", match: false},
+		{pattern: "infra/*", name: "infra.db", match: true},
+		{pattern: "infra.#", name: "infra", match: true},
+		{pattern: "infra.#", name: "infra.db", match: true},
+		{pattern: "infra.#", name: "infra.db.primary", match: true},
+		{pattern: "infra.#", name: "infrastructure", match: false},
+		{pattern: "infra.#", name: "ops.infra", match: false},
+		{pattern: "*.db.*", name: "infra.db.primary", match: true},
+		{pattern: "*.db.*", name: "infra.db", match: false},
+		{pattern: "#.primary", name: "primary", match: true},
+		{pattern: "#.primary", name: "infra.db.primary", match: true},
+		{pattern: "#.primary", name: "infra.db.replica", match: false},
+		{pattern: "infra.#.primary", name: "infra.primary", match: true},
+		{pattern: "infra.#.primary", name: "infra.db.eu.primary", match: true},
+		{pattern: "infra.#.primary", name: "infra.db.replica", match: false},
+		{pattern: "#", name: "anything.at.all", match: true},
+		{pattern: "a+b.*", name: "a+b.c", match: true},
+		{pattern: "a+b.*", name: "aab.c", match: false},
+	}
+
+	for _, tc := range testCases {
+		p, err := subscription.ParsePattern(tc.pattern)
+		require.NoError(t, err, tc.pattern)
+		require.Equal(t, tc.match, p.Match(tc.name), "%s ~ %s", tc.name, tc.pattern)
+
+		_, err = regexp.Compile(p.Regexp())
+		require.NoError(t, err, tc.pattern)
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	for _, value := range []string{"", "infra.", ".infra", "infra..db", "infra.d*", "infra.#.#", "in#fra"} {
+		_, err := subscription.ParsePattern(value)
+		require.ErrorIs(t, err, subscription.ErrInvalidPattern, value)
+	}
+}
+
+func TestPatternRoot(t *testing.T) {
+	require.Equal(t, "infra", subscription.MustParsePattern("infra.#").Root())
+	require.Equal(t, "infra", subscription.MustParsePattern("infra/db/*").Root())
+	require.Equal(t, "", subscription.MustParsePattern("*.db").Root())
+	require.Equal(t, "", subscription.MustParsePattern("#").Root())
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
+	topicID := uuid.New()
+	pattern := subscription.MustParsePattern("infra.#")
+
+	testCases := []struct {
+		name       string
+		ns         subscription.NewSubscription
+		buildStubs func(storer *mocksub.MockStorer)
+		check      func(err error)
+	}{
+		{
+			name: "Topic",
+			ns:   subscription.NewSubscription{TopicID: topicID, UserID: uuid.New()},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "Pattern",
+			ns:   subscription.NewSubscription{Pattern: pattern, UserID: uuid.New()},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "Both",
+			ns:   subscription.NewSubscription{TopicID: topicID, Pattern: pattern, UserID: uuid.New()},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidTarget)
+			},
+		},
+		{
+			name: "Neither",
+			ns:   subscription.NewSubscription{UserID: uuid.New()},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidTarget)
+			},
+		},
+		{
+			name: "Routes",
+			ns: subscription.NewSubscription{
+				TopicID: topicID,
+				UserID:  uuid.New(),
+				Routes: []notification.Route{
+					{Channel: notification.ChannelEmail, Address: "oncall@example.com"},
+					{Channel: notification.ChannelWebhook, Address: "https://example.com/hook"},
+					{Channel: notification.ChannelSMS, Address: "+385911234567"},
+					{Channel: notification.ChannelInApp},
+				},
+			},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "Group",
+			ns:   subscription.NewSubscription{TopicID: topicID, GroupID: uuid.New()},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "UserAndGroup",
+			ns:   subscription.NewSubscription{TopicID: topicID, UserID: uuid.New(), GroupID: uuid.New()},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidOwner)
+			},
+		},
+		{
+			name: "NoOwner",
+			ns:   subscription.NewSubscription{TopicID: topicID},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidOwner)
+			},
+		},
+		{
+			name: "GroupRoutes",
+			ns: subscription.NewSubscription{
+				TopicID: topicID,
+				GroupID: uuid.New(),
+				Routes:  []notification.Route{{Channel: notification.ChannelEmail, Address: "oncall@example.com"}},
+			},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, subscription.ErrGroupRoutes)
+			},
+		},
+		{
+			name: "MissingAddress",
+			ns: subscription.NewSubscription{
+				TopicID: topicID,
+				UserID:  uuid.New(),
+				Routes:  []notification.Route{{Channel: notification.ChannelWebhook}},
+			},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, notification.ErrMissingAddress)
+			},
+		},
+		{
+			name: "InvalidAddress",
+			ns: subscription.NewSubscription{
+				TopicID: topicID,
+				UserID:  uuid.New(),
+				Routes:  []notification.Route{{Channel: notification.ChannelWebhook, Address: "ftp://example.com"}},
+			},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error) {
+				require.ErrorIs(t, err, notification.ErrInvalidAddress)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocksub.NewMockStorer(ctrl)
+		core := subscription.NewCore(nil, storer)
+
+		tc.buildStubs(storer)
+
+		_, err := core.Create(ctx, tc.ns)
+
+		tc.check(err)
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	ntf := notification.Notification{
+		Title:    "Disk almost full",
+		Message:  "db-primary is at 93% capacity",
+		Priority: notification.PriorityHigh,
+		Tags:     []string{"disk", "prod"},
+	}
+
+	testCases := []struct {
+		name   string
+		filter subscription.Filter
+		match  bool
+	}{
+		{name: "Empty", filter: subscription.Filter{}, match: true},
+		{name: "PriorityBelow", filter: subscription.Filter{MinPriority: notification.PriorityNormal}, match: true},
+		{name: "PriorityEqual", filter: subscription.Filter{MinPriority: notification.PriorityHigh}, match: true},
+		{name: "PriorityAbove", filter: subscription.Filter{MinPriority: notification.PriorityUrgent}, match: false},
+		{name: "Tags", filter: subscription.Filter{Tags: []string{"prod", "disk"}}, match: true},
+		{name: "MissingTag", filter: subscription.Filter{Tags: []string{"prod", "network"}}, match: false},
+		{name: "KeywordTitle", filter: subscription.Filter{Keyword: "FULL"}, match: true},
+		{name: "KeywordMessage", filter: subscription.Filter{Keyword: "db-primary"}, match: true},
+		{name: "KeywordMissing", filter: subscription.Filter{Keyword: "replica"}, match: false},
+		{name: "Regex", filter: subscription.Filter{Regex: regexp.MustCompile(`at 9\d%`)}, match: true},
+		{name: "RegexMissing", filter: subscription.Filter{Regex: regexp.MustCompile(`^db-replica`)}, match: false},
+		{
+			name: "All",
+			filter: subscription.Filter{
+				MinPriority: notification.PriorityHigh,
+				Tags:        []string{"prod"},
+				Keyword:     "disk",
+				Regex:       regexp.MustCompile(`capacity$`),
+			},
+			match: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.match, tc.filter.Match(ntf), tc.name)
+	}
+}
+
+func TestRecipients(t *testing.T) {
+	ntf := notification.Notification{
+		Message:  "deploy finished",
+		Priority: notification.PriorityNormal,
+	}
+
+	oncall := uuid.New()
+	team := uuid.New()
+
+	subs := []subscription.Subscription{
+		{UserID: oncall, Filter: subscription.Filter{MinPriority: notification.PriorityHigh}},
+		{UserID: team},
+		{UserID: team, Filter: subscription.Filter{Keyword: "deploy"}},
+		{UserID: oncall, Filter: subscription.Filter{Tags: []string{"prod"}}},
+	}
+
+	require.Equal(t, []uuid.UUID{team}, subscription.Recipients(subs, ntf))
+
+	ntf.Priority = notification.PriorityUrgent
+	require.Equal(t, []uuid.UUID{oncall, team}, subscription.Recipients(subs, ntf))
+}
+
+func TestDeliveries(t *testing.T) {
+	ntf := notification.Notification{
+		Message:  "deploy finished",
+		Priority: notification.PriorityNormal,
+	}
+
+	userID := uuid.New()
+	email := notification.Route{Channel: notification.ChannelEmail}
+	inApp := notification.Route{Channel: notification.ChannelInApp}
+	hook := notification.Route{Channel: notification.ChannelWebhook, Address: "https://example.com/hook"}
+
+	sub1, sub2, sub3 := uuid.New(), uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name     string
+		subs     []subscription.Subscription
+		defaults []notification.Route
+		dlvs     []subscription.Delivery
+	}{
+		{
+			name:     "Defaults",
+			subs:     []subscription.Subscription{{ID: sub1, UserID: userID}},
+			defaults: []notification.Route{email},
+			dlvs:     []subscription.Delivery{{Route: email, SubscriptionID: sub1}},
+		},
+		{
+			name:     "OwnRoutes",
+			subs:     []subscription.Subscription{{ID: sub1, UserID: userID, Routes: []notification.Route{hook, inApp}}},
+			defaults: []notification.Route{email},
+			dlvs: []subscription.Delivery{
+				{Route: hook, SubscriptionID: sub1},
+				{Route: inApp, SubscriptionID: sub1},
+			},
+		},
+		{
+			name: "Merged",
+			subs: []subscription.Subscription{
+				{ID: sub1, UserID: userID, Routes: []notification.Route{inApp}},
+				{ID: sub2, UserID: userID},
+				{ID: sub3, UserID: userID, Routes: []notification.Route{email, inApp}},
+			},
+			defaults: []notification.Route{email},
+			dlvs: []subscription.Delivery{
+				{Route: inApp, SubscriptionID: sub1},
+				{Route: email, SubscriptionID: sub2},
+			},
+		},
+		{
+			name: "Filtered",
+			subs: []subscription.Subscription{
+				{ID: sub1, UserID: userID, Routes: []notification.Route{hook}, Filter: subscription.Filter{MinPriority: notification.PriorityHigh}},
+				{ID: sub2, UserID: userID, Routes: []notification.Route{inApp}},
+			},
+			dlvs: []subscription.Delivery{{Route: inApp, SubscriptionID: sub2}},
+		},
+		{
+			name: "OtherUser",
+			subs: []subscription.Subscription{{ID: sub1, UserID: uuid.New(), Routes: []notification.Route{hook}}},
+			dlvs: nil,
+		},
+		{
+			name: "NoDefaults",
+			subs: []subscription.Subscription{{ID: sub1, UserID: userID}},
+			dlvs: nil,
+		},
+		{
+			name: "PendingAddresses",
+			subs: []subscription.Subscription{{ID: sub1, UserID: userID, Routes: []notification.Route{
+				{Channel: notification.ChannelEmail, Address: "ops@example.com"},
+				{Channel: notification.ChannelSMS, Address: "+15550100"},
+			}}},
+			dlvs: []subscription.Delivery{{Route: email, SubscriptionID: sub1}},
+		},
+		{
+			name: "ConfirmedAddresses",
+			subs: []subscription.Subscription{{ID: sub1, UserID: userID, Routes: []notification.Route{
+				{Channel: notification.ChannelEmail, Address: "ops@example.com", Confirmed: true},
+				{Channel: notification.ChannelSMS, Address: "+15550100", Confirmed: true},
+			}}},
+			dlvs: []subscription.Delivery{
+				{Route: notification.Route{Channel: notification.ChannelEmail, Address: "ops@example.com"}, SubscriptionID: sub1},
+				{Route: notification.Route{Channel: notification.ChannelSMS, Address: "+15550100"}, SubscriptionID: sub1},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.dlvs, subscription.Deliveries(tc.subs, userID, ntf, tc.defaults), tc.name)
+	}
+}
+
+func TestExpandGroups(t *testing.T) {
+	ntf := notification.Notification{
+		Message:  "disk full",
+		Priority: notification.PriorityUrgent,
+	}
+
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	oncall, sre := uuid.New(), uuid.New()
+	direct, viaOncall, viaSRE := uuid.New(), uuid.New(), uuid.New()
+
+	subs := []subscription.Subscription{
+		{ID: viaOncall, GroupID: oncall},
+		{ID: direct, UserID: bob, Routes: []notification.Route{{Channel: notification.ChannelInApp}}},
+		{ID: viaSRE, GroupID: sre},
+	}
+
+	require.Equal(t, []uuid.UUID{oncall, sre}, subscription.GroupIDs(subs))
+
+	members := map[uuid.UUID][]uuid.UUID{
+		oncall: {alice, bob},
+		sre:    {bob, carol},
+	}
+
+	everyone := func(uuid.UUID) bool { return true }
+
+	expanded := subscription.ExpandGroups(subs, members, everyone)
+	require.Len(t, expanded, 5)
+	require.Equal(t, direct, expanded[0].ID)
+
+	// Bob is reached directly and through both groups, but gets it once.
+	require.Equal(t, []uuid.UUID{bob, alice, carol}, subscription.Recipients(expanded, ntf))
+
+	email := notification.Route{Channel: notification.ChannelEmail}
+	inApp := notification.Route{Channel: notification.ChannelInApp}
+	defaults := []notification.Route{email, inApp}
+
+	require.Equal(t, []subscription.Delivery{
+		{Route: inApp, SubscriptionID: direct},
+		{Route: email, SubscriptionID: viaOncall, GroupID: oncall},
+	}, subscription.Deliveries(expanded, bob, ntf, defaults))
+
+	require.Equal(t, []subscription.Delivery{
+		{Route: email, SubscriptionID: viaSRE, GroupID: sre},
+		{Route: inApp, SubscriptionID: viaSRE, GroupID: sre},
+	}, subscription.Deliveries(expanded, carol, ntf, defaults))
+
+	// Groups without members reach no one.
+	require.Empty(t, subscription.Recipients(subscription.ExpandGroups(subs[2:], nil, everyone), ntf))
+
+	// Members who may not subscribe to a private topic themselves don't get
+	// its notifications through a group either.
+	onlyBob := func(userID uuid.UUID) bool { return userID == bob }
+
+	expanded = subscription.ExpandGroups(subs, members, onlyBob)
+	require.Equal(t, []uuid.UUID{bob}, subscription.Recipients(expanded, ntf))
+}
+
+func TestMute(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name       string
+		until      time.Time
+		buildStubs func(storer *mocksub.MockStorer)
+		check      func(sub subscription.Subscription, err error)
+	}{
+		{
+			name:  "Indefinitely",
+			until: time.Time{},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(sub subscription.Subscription, err error) {
+				require.NoError(t, err)
+				require.True(t, sub.IsMuted(time.Now().Add(24*time.Hour)))
+			},
+		},
+		{
+			name:  "Until",
+			until: time.Now().Add(time.Hour),
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(sub subscription.Subscription, err error) {
+				require.NoError(t, err)
+				require.True(t, sub.IsMuted(time.Now()))
+				require.False(t, sub.IsMuted(time.Now().Add(2*time.Hour)))
+			},
+		},
+		{
+			name:  "Past",
+			until: time.Now().Add(-time.Hour),
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().Update(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(sub subscription.Subscription, err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidMute)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocksub.NewMockStorer(ctrl)
+		core := subscription.NewCore(nil, storer)
+
+		tc.buildStubs(storer)
+
+		sub, err := core.Mute(ctx, subscription.Subscription{ID: uuid.New()}, tc.until)
+
+		tc.check(sub, err)
+	}
+}
+
+func TestUnmute(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocksub.NewMockStorer(ctrl)
+	core := subscription.NewCore(nil, storer)
+
+	storer.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+	sub, err := core.Unmute(ctx, subscription.Subscription{ID: uuid.New(), Muted: true})
+	require.NoError(t, err)
+	require.False(t, sub.IsMuted(time.Now()))
+
+	// Unmuting again doesn't touch the store.
+	_, err = core.Unmute(ctx, sub)
+	require.NoError(t, err)
+}
+
+func TestSplitMuted(t *testing.T) {
+	now := time.Now()
+
+	active := subscription.Subscription{ID: uuid.New()}
+	muted := subscription.Subscription{ID: uuid.New(), Muted: true}
+	snoozed := subscription.Subscription{ID: uuid.New(), Muted: true, MutedUntil: now.Add(time.Hour)}
+	expired := subscription.Subscription{ID: uuid.New(), Muted: true, MutedUntil: now.Add(-time.Hour)}
+
+	gotActive, gotMuted := subscription.SplitMuted([]subscription.Subscription{active, muted, snoozed, expired}, now)
+	require.Equal(t, []subscription.Subscription{active, expired}, gotActive)
+	require.Equal(t, []subscription.Subscription{muted, snoozed}, gotMuted)
+}
+
+func TestCreateGuest(t *testing.T) {
+	ctx := context.Background()
+
+	topicID := uuid.New()
+
+	testCases := []struct {
+		name       string
+		ngs        subscription.NewGuestSubscription
+		buildStubs func(storer *mocksub.MockStorer)
+		check      func(sub subscription.Subscription, err error)
+	}{
+		{
+			name: "OK",
+			ngs:  subscription.NewGuestSubscription{TopicID: topicID, Email: "Guest@Example.com"},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().
+					WithinTran(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, fn func(subscription.Storer) error) error {
+						return fn(storer)
+					})
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(sub subscription.Subscription, err error) {
+				require.NoError(t, err)
+				require.True(t, sub.IsGuest())
+				require.False(t, sub.Confirmed)
+				require.Equal(t, "guest@example.com", sub.GuestEmail)
+				require.Equal(t, uuid.Nil, sub.UserID)
+			},
+		},
+		{
+			name: "AfterCreateFails",
+			ngs: subscription.NewGuestSubscription{
+				TopicID: topicID,
+				Email:   "guest@example.com",
+				AfterCreate: func(sub subscription.Subscription) error {
+					return errors.New("enqueue failed")
+				},
+			},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().
+					WithinTran(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, fn func(subscription.Storer) error) error {
+						return fn(storer)
+					})
+				storer.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(sub subscription.Subscription, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			ngs:  subscription.NewGuestSubscription{TopicID: topicID, Email: "guest"},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().WithinTran(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(sub subscription.Subscription, err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidEmail)
+			},
+		},
+		{
+			name: "NoTopic",
+			ngs:  subscription.NewGuestSubscription{Email: "guest@example.com"},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().WithinTran(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(sub subscription.Subscription, err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidTarget)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocksub.NewMockStorer(ctrl)
+		core := subscription.NewCore(nil, storer)
+
+		tc.buildStubs(storer)
+
+		sub, err := core.CreateGuest(ctx, tc.ngs)
+
+		tc.check(sub, err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocksub.NewMockStorer(ctrl)
+	core := subscription.NewCore(nil, storer)
+
+	storer.EXPECT().Update(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+
+	sub, err := core.Confirm(ctx, subscription.Subscription{ID: uuid.New(), GuestEmail: "guest@example.com"})
+	require.NoError(t, err)
+	require.True(t, sub.Confirmed)
+
+	// Confirming again doesn't touch the store.
+	_, err = core.Confirm(ctx, sub)
+	require.NoError(t, err)
+}
+
+func TestUpdateRoutes(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocksub.NewMockStorer(ctrl)
+	core := subscription.NewCore(nil, storer)
+
+	storer.EXPECT().Update(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+
+	kept := notification.Route{Channel: notification.ChannelEmail, Address: "ops@example.com"}
+	added := notification.Route{Channel: notification.ChannelSMS, Address: "+15550100"}
+
+	sub := subscription.Subscription{ID: uuid.New(), UserID: uuid.New(), Routes: []notification.Route{
+		{Channel: kept.Channel, Address: kept.Address, Confirmed: true},
+	}}
+
+	// Addresses can't be confirmed by sending them confirmed.
+	added.Confirmed = true
+
+	sub, err := core.Update(ctx, sub, subscription.UpdateSubscription{Routes: []notification.Route{kept, added}})
+	require.NoError(t, err)
+	require.True(t, sub.Routes[0].Confirmed)
+	require.False(t, sub.Routes[1].Confirmed)
+
+	sub, err = core.ConfirmRoute(ctx, sub, added.Channel, added.Address)
+	require.NoError(t, err)
+	require.True(t, sub.Routes[1].Confirmed)
+
+	// Confirming again doesn't touch the store.
+	_, err = core.ConfirmRoute(ctx, sub, added.Channel, added.Address)
+	require.NoError(t, err)
+
+	_, err = core.ConfirmRoute(ctx, sub, notification.ChannelEmail, "other@example.com")
+	require.ErrorIs(t, err, subscription.ErrRouteNotFound)
+}
+
+func TestGuestDeliveries(t *testing.T) {
+	ntf := notification.Notification{Message: "disk full", Priority: notification.PriorityNormal}
+
+	member := subscription.Subscription{ID: uuid.New(), UserID: uuid.New(), Confirmed: true}
+	confirmed := subscription.Subscription{ID: uuid.New(), GuestEmail: "one@example.com", Confirmed: true}
+	pending := subscription.Subscription{ID: uuid.New(), GuestEmail: "two@example.com"}
+	filtered := subscription.Subscription{
+		ID:         uuid.New(),
+		GuestEmail: "three@example.com",
+		Confirmed:  true,
+		Filter:     subscription.Filter{MinPriority: notification.PriorityHigh},
+	}
+
+	subs := []subscription.Subscription{member, confirmed, pending, filtered}
+
+	dlvs := subscription.GuestDeliveries(subs, ntf)
+	require.Equal(t, []subscription.Delivery{
+		{
+			Route:          notification.Route{Channel: notification.ChannelEmail, Address: "one@example.com"},
+			SubscriptionID: confirmed.ID,
+		},
+	}, dlvs)
+
+	// Guests are no users, so they are never recipients.
+	require.Equal(t, []uuid.UUID{member.UserID}, subscription.Recipients(subs, ntf))
+}
+
+func TestCreateJob(t *testing.T) {
+	ctx := context.Background()
+
+	row := subscription.JobRow{UserID: uuid.New(), TopicID: uuid.New()}
+
+	testCases := []struct {
+		name       string
+		rows       []subscription.JobRow
+		buildStubs func(storer *mocksub.MockStorer)
+		check      func(job subscription.Job, err error)
+	}{
+		{
+			name: "OK",
+			rows: []subscription.JobRow{row, row},
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().
+					WithinTran(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, fn func(subscription.Storer) error) error {
+						return fn(storer)
+					})
+				storer.EXPECT().CreateJob(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+			},
+			check: func(job subscription.Job, err error) {
+				require.NoError(t, err)
+				require.Equal(t, subscription.JobPending, job.Status)
+				require.Len(t, job.Rows, 2)
+				require.False(t, job.Finished())
+			},
+		},
+		{
+			name: "NoRows",
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().WithinTran(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(job subscription.Job, err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidJob)
+			},
+		},
+		{
+			name: "TooManyRows",
+			rows: make([]subscription.JobRow, subscription.MaxJobRows+1),
+			buildStubs: func(storer *mocksub.MockStorer) {
+				storer.EXPECT().WithinTran(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(job subscription.Job, err error) {
+				require.ErrorIs(t, err, subscription.ErrInvalidJob)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocksub.NewMockStorer(ctrl)
+		core := subscription.NewCore(nil, storer)
+
+		tc.buildStubs(storer)
+
+		job, err := core.CreateJob(ctx, subscription.NewJob{Rows: tc.rows, CreatedBy: uuid.New()})
+
+		tc.check(job, err)
+	}
+}
+
+func TestUpdateJob(t *testing.T) {
+	ctx := context.Background()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocksub.NewMockStorer(ctrl)
+	core := subscription.NewCore(nil, storer)
+
+	storer.EXPECT().UpdateJob(gomock.Any(), gomock.Any()).Times(2).Return(nil)
+
+	job := subscription.Job{
+		ID:     uuid.New(),
+		Status: subscription.JobPending,
+		Rows:   make([]subscription.JobRow, 2),
+	}
+
+	job.Processed = 1
+	job, err := core.UpdateJob(ctx, job)
+	require.NoError(t, err)
+	require.Equal(t, subscription.JobRunning, job.Status)
+
+	job.Processed = 2
+	job, err = core.UpdateJob(ctx, job)
+	require.NoError(t, err)
+	require.Equal(t, subscription.JobDone, job.Status)
+}
@@ -0,0 +1,450 @@
+// Package topicmem contains topic related CRUD functionality, kept in memory.
+package topicmem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table              = "topics"
+	tableAccess        = "topic_acls"
+	tableInvitations   = "topic_invitations"
+	tableDeletions     = "topic_deletions"
+	tableSubscriptions = "subscriptions"
+)
+
+// orderByFields maps the fields topics can be ordered by to their
+// comparisons.
+var orderByFields = map[string]memdb.Compare[topic.Topic]{
+	topic.OrderByID:        compareID,
+	topic.OrderByName:      func(a, b topic.Topic) int { return strings.Compare(a.Name, b.Name) },
+	topic.OrderByCreatedAt: func(a, b topic.Topic) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+// Store manages the set of APIs for topic access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Unique("unique_topic_name", memdb.Unique{
+		Table: table,
+		Key: func(row any) (string, bool) {
+			tpc := row.(topic.Topic)
+			return tpc.Name, tpc.DeletedAt.IsZero()
+		},
+	})
+
+	db.Reference("topics_owner_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: "users",
+		Action: memdb.SetNull,
+		Refers: func(row any, key string) bool { return row.(topic.Topic).OwnerID.String() == key },
+		Clear: func(row any) any {
+			tpc := row.(topic.Topic)
+			tpc.OwnerID = uuid.Nil
+			return tpc
+		},
+	})
+
+	db.Reference("topic_acls_topic_id_fkey", memdb.Reference{
+		Table:  tableAccess,
+		Parent: table,
+		Refers: func(row any, key string) bool { return row.(topic.Access).TopicID.String() == key },
+	})
+
+	db.Reference("topic_acls_user_id_fkey", memdb.Reference{
+		Table:  tableAccess,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(topic.Access).UserID.String() == key },
+	})
+
+	db.Reference("topic_invitations_topic_id_fkey", memdb.Reference{
+		Table:  tableInvitations,
+		Parent: table,
+		Refers: func(row any, key string) bool { return row.(topic.Invitation).TopicID.String() == key },
+	})
+
+	db.Reference("topic_invitations_invited_by_fkey", memdb.Reference{
+		Table:  tableInvitations,
+		Parent: "users",
+		Action: memdb.SetNull,
+		Refers: func(row any, key string) bool { return row.(topic.Invitation).InvitedBy.String() == key },
+		Clear: func(row any) any {
+			inv := row.(topic.Invitation)
+			inv.InvitedBy = uuid.Nil
+			return inv
+		},
+	})
+
+	db.Reference("topic_deletions_topic_id_fkey", memdb.Reference{
+		Table:  tableDeletions,
+		Parent: table,
+		Refers: func(row any, key string) bool { return row.(topic.Deletion).TopicID.String() == key },
+	})
+
+	db.Reference("topic_deletions_requested_by_fkey", memdb.Reference{
+		Table:  tableDeletions,
+		Parent: "users",
+		Action: memdb.SetNull,
+		Refers: func(row any, key string) bool { return row.(topic.Deletion).RequestedBy.String() == key },
+		Clear: func(row any) any {
+			d := row.(topic.Deletion)
+			d.RequestedBy = uuid.Nil
+			return d
+		},
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(topic.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new topic into the database.
+func (s *Store) Create(ctx context.Context, tpc topic.Topic) error {
+	if err := memdb.Insert(s.db, table, tpc.ID.String(), tpc); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return topic.ErrUniqueName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update replaces a topic document in the database, as long as it's still at
+// the version of the topic.
+func (s *Store) Update(ctx context.Context, tpc topic.Topic) error {
+	stored, exists := memdb.Get[topic.Topic](s.db, table, tpc.ID.String())
+	if !exists || stored.Version != tpc.Version {
+		return topic.ErrVersionConflict
+	}
+
+	stored.Name = tpc.Name
+	stored.Description = tpc.Description
+	stored.ArchivedAt = tpc.ArchivedAt
+	stored.Retention = tpc.Retention
+	stored.UpdatedAt = tpc.UpdatedAt
+	stored.Version++
+
+	if err := memdb.Put(s.db, table, stored.ID.String(), stored); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return topic.ErrUniqueName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Delete marks a topic as deleted in the database, as long as it's
+// still at the version of the topic.
+func (s *Store) Delete(ctx context.Context, tpc topic.Topic) error {
+	stored, exists := memdb.Get[topic.Topic](s.db, table, tpc.ID.String())
+	if !exists || !stored.DeletedAt.IsZero() || stored.Version != tpc.Version {
+		return topic.ErrVersionConflict
+	}
+
+	stored.DeletedAt = tpc.DeletedAt
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// Restore clears the deletion of a topic in the database.
+func (s *Store) Restore(ctx context.Context, tpc topic.Topic) error {
+	stored, exists := memdb.Get[topic.Topic](s.db, table, tpc.ID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.DeletedAt = time.Time{}
+	stored.UpdatedAt = tpc.UpdatedAt
+
+	if err := memdb.Put(s.db, table, stored.ID.String(), stored); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return topic.ErrUniqueName
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Purge removes up to limit topics deleted before the given time from the
+// database. Their notifications, subscriptions, access and invitations are
+// removed with them.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	tpcs := memdb.Rows(s.db, table, func(tpc topic.Topic) bool {
+		return !tpc.DeletedAt.IsZero() && tpc.DeletedAt.Before(before)
+	})
+
+	if len(tpcs) > limit {
+		tpcs = tpcs[:limit]
+	}
+
+	for _, tpc := range tpcs {
+		memdb.Delete(s.db, table, tpc.ID.String())
+	}
+
+	return len(tpcs), nil
+}
+
+// Query retrieves a list of existing topics from the database.
+func (s *Store) Query(ctx context.Context, filter topic.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]topic.Topic, error) {
+	tpcs := memdb.Rows(s.db, table, s.matches(filter))
+
+	if err := memdb.Sort(tpcs, orderBy, orderByFields, compareID); err != nil {
+		return nil, err
+	}
+
+	return memdb.Page(tpcs, pageNumber, rowsPerPage), nil
+}
+
+// Count returns the number of topics in the database matching the filter.
+func (s *Store) Count(ctx context.Context, filter topic.QueryFilter) (int, error) {
+	return len(memdb.Rows(s.db, table, s.matches(filter))), nil
+}
+
+// QueryByID gets the specified topic from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (topic.Topic, error) {
+	tpc, exists := memdb.Get[topic.Topic](s.db, table, id.String())
+	if !exists || !tpc.DeletedAt.IsZero() {
+		return topic.Topic{}, topic.ErrNotFound
+	}
+
+	return tpc, nil
+}
+
+// QueryDeletedByID gets the specified deleted topic from the database by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (topic.Topic, error) {
+	tpc, exists := memdb.Get[topic.Topic](s.db, table, id.String())
+	if !exists || tpc.DeletedAt.IsZero() {
+		return topic.Topic{}, topic.ErrNotFound
+	}
+
+	return tpc, nil
+}
+
+// QueryByName gets the specified topic from the database by name.
+func (s *Store) QueryByName(ctx context.Context, name string) (topic.Topic, error) {
+	tpcs := memdb.Rows(s.db, table, func(tpc topic.Topic) bool {
+		return tpc.Name == name && tpc.DeletedAt.IsZero()
+	})
+
+	if len(tpcs) == 0 {
+		return topic.Topic{}, topic.ErrNotFound
+	}
+
+	return tpcs[0], nil
+}
+
+// QueryWithRetention gets the topics with a retention of their own from the
+// database, deleted ones included.
+func (s *Store) QueryWithRetention(ctx context.Context) ([]topic.Topic, error) {
+	tpcs := memdb.Rows(s.db, table, func(tpc topic.Topic) bool {
+		return tpc.Retention > 0
+	})
+
+	return tpcs, nil
+}
+
+// UpsertAccess inserts or replaces the access of a user to a topic in the
+// database.
+func (s *Store) UpsertAccess(ctx context.Context, a topic.Access) error {
+	key := accessKey(a.TopicID, a.UserID)
+
+	if stored, exists := memdb.Get[topic.Access](s.db, tableAccess, key); exists {
+		a.CreatedAt = stored.CreatedAt
+	}
+	a.Rights = slices.Clone(a.Rights)
+
+	return memdb.Put(s.db, tableAccess, key, a)
+}
+
+// DeleteAccess removes the access of a user to a topic from the database.
+func (s *Store) DeleteAccess(ctx context.Context, a topic.Access) error {
+	memdb.Delete(s.db, tableAccess, accessKey(a.TopicID, a.UserID))
+
+	return nil
+}
+
+// QueryAccess gets the access of a user to a topic from the database.
+func (s *Store) QueryAccess(ctx context.Context, topicID uuid.UUID, userID uuid.UUID) (topic.Access, error) {
+	a, exists := memdb.Get[topic.Access](s.db, tableAccess, accessKey(topicID, userID))
+	if !exists {
+		return topic.Access{}, topic.ErrAccessNotFound
+	}
+
+	return a, nil
+}
+
+// QueryAccessByTopicID retrieves the access granted on a topic from the
+// database.
+func (s *Store) QueryAccessByTopicID(ctx context.Context, topicID uuid.UUID) ([]topic.Access, error) {
+	as := memdb.Rows(s.db, tableAccess, func(a topic.Access) bool {
+		return a.TopicID == topicID
+	})
+
+	slices.SortStableFunc(as, func(a, b topic.Access) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return as, nil
+}
+
+// DeleteSubscription marks the subscription of a user to a topic as deleted
+// in the database, if there is one.
+func (s *Store) DeleteSubscription(ctx context.Context, topicID uuid.UUID, userID uuid.UUID) error {
+	subs := memdb.Rows(s.db, tableSubscriptions, func(sub subscription.Subscription) bool {
+		return sub.TopicID == topicID && sub.UserID == userID && sub.DeletedAt.IsZero()
+	})
+
+	now := time.Now()
+	for _, sub := range subs {
+		sub.DeletedAt = now
+		if err := memdb.Put(s.db, tableSubscriptions, sub.ID.String(), sub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateInvitation inserts a new invitation into the database.
+func (s *Store) CreateInvitation(ctx context.Context, inv topic.Invitation) error {
+	inv.Rights = slices.Clone(inv.Rights)
+
+	return memdb.Insert(s.db, tableInvitations, inv.ID.String(), inv)
+}
+
+// UpdateInvitation replaces an invitation in the database.
+func (s *Store) UpdateInvitation(ctx context.Context, inv topic.Invitation) error {
+	stored, exists := memdb.Get[topic.Invitation](s.db, tableInvitations, inv.ID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.AcceptedAt = inv.AcceptedAt
+
+	return memdb.Put(s.db, tableInvitations, stored.ID.String(), stored)
+}
+
+// QueryInvitationByID gets the specified invitation from the database.
+func (s *Store) QueryInvitationByID(ctx context.Context, id uuid.UUID) (topic.Invitation, error) {
+	inv, exists := memdb.Get[topic.Invitation](s.db, tableInvitations, id.String())
+	if !exists {
+		return topic.Invitation{}, topic.ErrInvitationNotFound
+	}
+
+	return inv, nil
+}
+
+// UpsertDeletion inserts or replaces the deletion request of a topic in the
+// database.
+func (s *Store) UpsertDeletion(ctx context.Context, d topic.Deletion) error {
+	return memdb.Put(s.db, tableDeletions, d.TopicID.String(), d)
+}
+
+// QueryDeletion gets the deletion request of a topic from the database.
+func (s *Store) QueryDeletion(ctx context.Context, topicID uuid.UUID) (topic.Deletion, error) {
+	d, exists := memdb.Get[topic.Deletion](s.db, tableDeletions, topicID.String())
+	if !exists {
+		return topic.Deletion{}, topic.ErrDeletionNotFound
+	}
+
+	return d, nil
+}
+
+// DeleteDeletion removes the deletion request of a topic from the database.
+func (s *Store) DeleteDeletion(ctx context.Context, topicID uuid.UUID) error {
+	memdb.Delete(s.db, tableDeletions, topicID.String())
+
+	return nil
+}
+
+// =============================================================================
+
+// matches returns whether a topic matches the filter. The name matches any
+// part of it, regardless of case, and deleted topics only match when the
+// filter asks for them.
+func (s *Store) matches(filter topic.QueryFilter) func(tpc topic.Topic) bool {
+	return func(tpc topic.Topic) bool {
+		if tpc.DeletedAt.IsZero() == filter.Deleted {
+			return false
+		}
+
+		if filter.Name != nil && !strings.Contains(strings.ToLower(tpc.Name), strings.ToLower(*filter.Name)) {
+			return false
+		}
+
+		if filter.Visibility != nil && !tpc.Visibility.Equal(*filter.Visibility) {
+			return false
+		}
+
+		if filter.OwnerID != nil && tpc.OwnerID != *filter.OwnerID {
+			return false
+		}
+
+		if filter.Archived != nil && tpc.Archived() != *filter.Archived {
+			return false
+		}
+
+		if filter.AccessibleTo != nil && !s.accessible(tpc, *filter.AccessibleTo) {
+			return false
+		}
+
+		return true
+	}
+}
+
+// accessible reports whether the topic is public, owned by the user or the
+// user was granted access to it.
+func (s *Store) accessible(tpc topic.Topic, userID uuid.UUID) bool {
+	if tpc.Visibility.Equal(topic.VisibilityPublic) || tpc.OwnerID == userID {
+		return true
+	}
+
+	_, exists := memdb.Get[topic.Access](s.db, tableAccess, accessKey(tpc.ID, userID))
+	return exists
+}
+
+func compareID(a, b topic.Topic) int {
+	return memdb.CompareIDs(a.ID, b.ID)
+}
+
+func accessKey(topicID uuid.UUID, userID uuid.UUID) string {
+	return topicID.String() + "/" + userID.String()
+}
@@ -5,9 +5,12 @@
 package mocktpc
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	topic "github.com/dudakovict/gotify/internal/core/topic"
+	order "github.com/dudakovict/gotify/platform/order"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 )
@@ -35,60 +38,335 @@ func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *MockStorer) Count(arg0 context.Context, arg1 topic.QueryFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockStorerMockRecorder) Count(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockStorer)(nil).Count), arg0, arg1)
+}
+
 // Create mocks base method.
-func (m *MockStorer) Create(arg0 topic.Topic) error {
+func (m *MockStorer) Create(arg0 context.Context, arg1 topic.Topic) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockStorerMockRecorder) Create(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
+}
+
+// CreateInvitation mocks base method.
+func (m *MockStorer) CreateInvitation(arg0 context.Context, arg1 topic.Invitation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInvitation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateInvitation indicates an expected call of CreateInvitation.
+func (mr *MockStorerMockRecorder) CreateInvitation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInvitation", reflect.TypeOf((*MockStorer)(nil).CreateInvitation), arg0, arg1)
+}
+
+// Delete mocks base method.
+func (m *MockStorer) Delete(arg0 context.Context, arg1 topic.Topic) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// DeleteAccess mocks base method.
+func (m *MockStorer) DeleteAccess(arg0 context.Context, arg1 topic.Access) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAccess", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAccess indicates an expected call of DeleteAccess.
+func (mr *MockStorerMockRecorder) DeleteAccess(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccess", reflect.TypeOf((*MockStorer)(nil).DeleteAccess), arg0, arg1)
+}
+
+// DeleteDeletion mocks base method.
+func (m *MockStorer) DeleteDeletion(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDeletion", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDeletion indicates an expected call of DeleteDeletion.
+func (mr *MockStorerMockRecorder) DeleteDeletion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDeletion", reflect.TypeOf((*MockStorer)(nil).DeleteDeletion), arg0, arg1)
+}
+
+// DeleteSubscription mocks base method.
+func (m *MockStorer) DeleteSubscription(arg0 context.Context, arg1, arg2 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubscription", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubscription indicates an expected call of DeleteSubscription.
+func (mr *MockStorerMockRecorder) DeleteSubscription(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscription", reflect.TypeOf((*MockStorer)(nil).DeleteSubscription), arg0, arg1, arg2)
+}
+
+// Purge mocks base method.
+func (m *MockStorer) Purge(arg0 context.Context, arg1 time.Time, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorerMockRecorder) Purge(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorer)(nil).Purge), arg0, arg1, arg2)
 }
 
 // Query mocks base method.
-func (m *MockStorer) Query(arg0, arg1 int) ([]topic.Topic, error) {
+func (m *MockStorer) Query(arg0 context.Context, arg1 topic.QueryFilter, arg2 order.By, arg3, arg4 int) ([]topic.Topic, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Query", arg0, arg1)
+	ret := m.ctrl.Call(m, "Query", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].([]topic.Topic)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Query indicates an expected call of Query.
-func (mr *MockStorerMockRecorder) Query(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Query(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1, arg2, arg3, arg4)
+}
+
+// QueryAccess mocks base method.
+func (m *MockStorer) QueryAccess(arg0 context.Context, arg1, arg2 uuid.UUID) (topic.Access, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryAccess", arg0, arg1, arg2)
+	ret0, _ := ret[0].(topic.Access)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryAccess indicates an expected call of QueryAccess.
+func (mr *MockStorerMockRecorder) QueryAccess(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAccess", reflect.TypeOf((*MockStorer)(nil).QueryAccess), arg0, arg1, arg2)
+}
+
+// QueryAccessByTopicID mocks base method.
+func (m *MockStorer) QueryAccessByTopicID(arg0 context.Context, arg1 uuid.UUID) ([]topic.Access, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryAccessByTopicID", arg0, arg1)
+	ret0, _ := ret[0].([]topic.Access)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryAccessByTopicID indicates an expected call of QueryAccessByTopicID.
+func (mr *MockStorerMockRecorder) QueryAccessByTopicID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryAccessByTopicID", reflect.TypeOf((*MockStorer)(nil).QueryAccessByTopicID), arg0, arg1)
 }
 
 // QueryByID mocks base method.
-func (m *MockStorer) QueryByID(arg0 uuid.UUID) (topic.Topic, error) {
+func (m *MockStorer) QueryByID(arg0 context.Context, arg1 uuid.UUID) (topic.Topic, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryByID", arg0)
+	ret := m.ctrl.Call(m, "QueryByID", arg0, arg1)
 	ret0, _ := ret[0].(topic.Topic)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryByID indicates an expected call of QueryByID.
-func (mr *MockStorerMockRecorder) QueryByID(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) QueryByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0, arg1)
+}
+
+// QueryByName mocks base method.
+func (m *MockStorer) QueryByName(arg0 context.Context, arg1 string) (topic.Topic, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByName", arg0, arg1)
+	ret0, _ := ret[0].(topic.Topic)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByName indicates an expected call of QueryByName.
+func (mr *MockStorerMockRecorder) QueryByName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByName", reflect.TypeOf((*MockStorer)(nil).QueryByName), arg0, arg1)
+}
+
+// QueryDeletedByID mocks base method.
+func (m *MockStorer) QueryDeletedByID(arg0 context.Context, arg1 uuid.UUID) (topic.Topic, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryDeletedByID", arg0, arg1)
+	ret0, _ := ret[0].(topic.Topic)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryDeletedByID indicates an expected call of QueryDeletedByID.
+func (mr *MockStorerMockRecorder) QueryDeletedByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryDeletedByID", reflect.TypeOf((*MockStorer)(nil).QueryDeletedByID), arg0, arg1)
+}
+
+// QueryDeletion mocks base method.
+func (m *MockStorer) QueryDeletion(arg0 context.Context, arg1 uuid.UUID) (topic.Deletion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryDeletion", arg0, arg1)
+	ret0, _ := ret[0].(topic.Deletion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryDeletion indicates an expected call of QueryDeletion.
+func (mr *MockStorerMockRecorder) QueryDeletion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryDeletion", reflect.TypeOf((*MockStorer)(nil).QueryDeletion), arg0, arg1)
+}
+
+// QueryInvitationByID mocks base method.
+func (m *MockStorer) QueryInvitationByID(arg0 context.Context, arg1 uuid.UUID) (topic.Invitation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryInvitationByID", arg0, arg1)
+	ret0, _ := ret[0].(topic.Invitation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryInvitationByID indicates an expected call of QueryInvitationByID.
+func (mr *MockStorerMockRecorder) QueryInvitationByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryInvitationByID", reflect.TypeOf((*MockStorer)(nil).QueryInvitationByID), arg0, arg1)
+}
+
+// QueryWithRetention mocks base method.
+func (m *MockStorer) QueryWithRetention(arg0 context.Context) ([]topic.Topic, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryWithRetention", arg0)
+	ret0, _ := ret[0].([]topic.Topic)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryWithRetention indicates an expected call of QueryWithRetention.
+func (mr *MockStorerMockRecorder) QueryWithRetention(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryWithRetention", reflect.TypeOf((*MockStorer)(nil).QueryWithRetention), arg0)
+}
+
+// Restore mocks base method.
+func (m *MockStorer) Restore(arg0 context.Context, arg1 topic.Topic) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorerMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorer)(nil).Restore), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockStorer) Update(arg0 context.Context, arg1 topic.Topic) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorerMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1)
+}
+
+// UpdateInvitation mocks base method.
+func (m *MockStorer) UpdateInvitation(arg0 context.Context, arg1 topic.Invitation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateInvitation", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateInvitation indicates an expected call of UpdateInvitation.
+func (mr *MockStorerMockRecorder) UpdateInvitation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateInvitation", reflect.TypeOf((*MockStorer)(nil).UpdateInvitation), arg0, arg1)
+}
+
+// UpsertAccess mocks base method.
+func (m *MockStorer) UpsertAccess(arg0 context.Context, arg1 topic.Access) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertAccess", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertAccess indicates an expected call of UpsertAccess.
+func (mr *MockStorerMockRecorder) UpsertAccess(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertAccess", reflect.TypeOf((*MockStorer)(nil).UpsertAccess), arg0, arg1)
+}
+
+// UpsertDeletion mocks base method.
+func (m *MockStorer) UpsertDeletion(arg0 context.Context, arg1 topic.Deletion) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertDeletion", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertDeletion indicates an expected call of UpsertDeletion.
+func (mr *MockStorerMockRecorder) UpsertDeletion(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertDeletion", reflect.TypeOf((*MockStorer)(nil).UpsertDeletion), arg0, arg1)
 }
 
 // WithinTran mocks base method.
-func (m *MockStorer) WithinTran(arg0 func(topic.Storer) error) error {
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(topic.Storer) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WithinTran", arg0)
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // WithinTran indicates an expected call of WithinTran.
-func (mr *MockStorerMockRecorder) WithinTran(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
 }
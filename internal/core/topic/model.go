@@ -1,6 +1,8 @@
 package topic
 
 import (
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,12 +10,103 @@ import (
 
 // Topic represents information about an individual topic.
 type Topic struct {
-	ID        uuid.UUID
-	Name      string
-	CreatedAt time.Time
+	ID          uuid.UUID
+	Name        string
+	Description string
+	OwnerID     uuid.UUID
+	Visibility  Visibility
+	ArchivedAt  time.Time
+	Retention   time.Duration
+	Version     int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   time.Time
+}
+
+// Archived reports whether the topic was archived. Archived topics keep their
+// history but don't accept new notifications.
+func (t Topic) Archived() bool {
+	return !t.ArchivedAt.IsZero()
 }
 
-// NewTopic contains information needed to create a new topic.
+// NewTopic contains information needed to create a new topic. Topics keep
+// their notifications for the global retention period unless given one.
 type NewTopic struct {
-	Name string
+	Name        string
+	Description string
+	OwnerID     uuid.UUID
+	Visibility  Visibility
+	Retention   time.Duration
+}
+
+// UpdateTopic defines what information may be provided to modify an existing
+// topic. All fields are optional so clients can send just the fields they want
+// changed.
+type UpdateTopic struct {
+	Name        *string
+	Description *string
+	Retention   *time.Duration
+}
+
+// ValidateRetention checks that the retention is either zero, which falls
+// back to the global retention, or at least MinRetention.
+func ValidateRetention(retention time.Duration) error {
+	if retention != 0 && retention < MinRetention {
+		return fmt.Errorf("%w: %s", ErrInvalidRetention, retention)
+	}
+
+	return nil
+}
+
+// Deletion represents a pending request to delete a topic, which has to be
+// confirmed with its token.
+type Deletion struct {
+	TopicID     uuid.UUID
+	HashedToken string
+	RequestedBy uuid.UUID
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// DeleteTopicTx contains information needed to delete a topic in a
+// transaction.
+type DeleteTopicTx struct {
+	Token       string
+	AfterDelete func(tpc Topic) error
+}
+
+// Access represents the rights a user was granted on a topic.
+type Access struct {
+	TopicID   uuid.UUID
+	UserID    uuid.UUID
+	Rights    []Right
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Has reports whether the access grants the right.
+func (a Access) Has(right Right) bool {
+	return slices.Contains(a.Rights, RightManage) || slices.Contains(a.Rights, right)
+}
+
+// Invitation represents an invitation for an email address to get access to
+// a topic.
+type Invitation struct {
+	ID          uuid.UUID
+	TopicID     uuid.UUID
+	Email       string
+	Rights      []Right
+	HashedToken string
+	InvitedBy   uuid.UUID
+	ExpiresAt   time.Time
+	AcceptedAt  time.Time
+	CreatedAt   time.Time
+}
+
+// NewInvitation contains information needed to invite someone to a topic.
+type NewInvitation struct {
+	TopicID   uuid.UUID
+	Email     string
+	Rights    []Right
+	InvitedBy uuid.UUID
 }
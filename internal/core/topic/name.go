@@ -0,0 +1,36 @@
+package topic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Separators split topic names into the segments of a hierarchy, like
+// infra.db.primary or infra/db/primary. Subscription patterns treat both
+// alike.
+const Separators = "./"
+
+// wildcards are reserved for subscription patterns.
+const wildcards = "*#"
+
+// Segments splits the name into the segments of the hierarchy. Empty segments
+// are kept so callers can reject them.
+func Segments(name string) []string {
+	return strings.Split(strings.ReplaceAll(name, "/", "."), ".")
+}
+
+// ValidateName checks that every segment of the name is present and that the
+// name doesn't use the wildcards of subscription patterns.
+func ValidateName(name string) error {
+	for _, segment := range Segments(name) {
+		if segment == "" {
+			return fmt.Errorf("%w %q: empty segment", ErrInvalidName, name)
+		}
+
+		if strings.ContainsAny(segment, wildcards) {
+			return fmt.Errorf("%w %q: wildcards are reserved for subscriptions", ErrInvalidName, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package topic
+
+import (
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+)
+
+// Set of fields the topics can be ordered by.
+const (
+	OrderByID        = "id"
+	OrderByName      = "name"
+	OrderByCreatedAt = "created_at"
+)
+
+// OrderByFields lists the fields the topics can be ordered by.
+var OrderByFields = []string{OrderByID, OrderByName, OrderByCreatedAt}
+
+// DefaultOrderBy is the order topics are listed in unless asked otherwise.
+var DefaultOrderBy = order.NewBy(OrderByName, order.ASC)
+
+// QueryFilter holds the fields topics can be filtered by. Fields left nil
+// match every topic, and the name matches any part of it. AccessibleTo leaves
+// only the public topics and the private ones the user owns or was granted
+// access to. Deleted topics are only listed when Deleted is set, and then only
+// them.
+type QueryFilter struct {
+	Name         *string
+	Visibility   *Visibility
+	OwnerID      *uuid.UUID
+	Archived     *bool
+	AccessibleTo *uuid.UUID
+	Deleted      bool
+}
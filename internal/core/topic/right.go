@@ -0,0 +1,127 @@
+package topic
+
+import "fmt"
+
+// Set of rights a user can be granted on a topic. The manage right implies
+// all the others.
+var (
+	RightPublish   = Right{"publish"}
+	RightSubscribe = Right{"subscribe"}
+	RightManage    = Right{"manage"}
+)
+
+// Set of known rights.
+var rights = map[string]Right{
+	RightPublish.name:   RightPublish,
+	RightSubscribe.name: RightSubscribe,
+	RightManage.name:    RightManage,
+}
+
+// Right represents an operation a user is allowed to do on a topic.
+type Right struct {
+	name string
+}
+
+// ParseRight parses the string value and returns a right if one exists.
+func ParseRight(value string) (Right, error) {
+	right, exists := rights[value]
+	if !exists {
+		return Right{}, fmt.Errorf("%w %q", ErrInvalidRight, value)
+	}
+
+	return right, nil
+}
+
+// MustParseRight parses the string value and returns a right if one exists.
+// If an error occurs the function panics.
+func MustParseRight(value string) Right {
+	right, err := ParseRight(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return right
+}
+
+// Name returns the name of the right.
+func (r Right) Name() string {
+	return r.name
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (r *Right) UnmarshalText(data []byte) error {
+	right, err := ParseRight(string(data))
+	if err != nil {
+		return err
+	}
+
+	r.name = right.name
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (r Right) MarshalText() ([]byte, error) {
+	return []byte(r.name), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (r Right) Equal(r2 Right) bool {
+	return r.name == r2.name
+}
+
+// =============================================================================
+
+// Set of possible visibilities of a topic. Public topics can be found and
+// subscribed to by everyone, private topics only by users granted access.
+var (
+	VisibilityPublic  = Visibility{"public"}
+	VisibilityPrivate = Visibility{"private"}
+)
+
+// Set of known visibilities.
+var visibilities = map[string]Visibility{
+	VisibilityPublic.name:  VisibilityPublic,
+	VisibilityPrivate.name: VisibilityPrivate,
+}
+
+// Visibility represents who can see a topic.
+type Visibility struct {
+	name string
+}
+
+// ParseVisibility parses the string value and returns a visibility if one
+// exists.
+func ParseVisibility(value string) (Visibility, error) {
+	visibility, exists := visibilities[value]
+	if !exists {
+		return Visibility{}, fmt.Errorf("%w %q", ErrInvalidVisibility, value)
+	}
+
+	return visibility, nil
+}
+
+// Name returns the name of the visibility.
+func (v Visibility) Name() string {
+	return v.name
+}
+
+// UnmarshalText implement the unmarshal interface for JSON conversions.
+func (v *Visibility) UnmarshalText(data []byte) error {
+	visibility, err := ParseVisibility(string(data))
+	if err != nil {
+		return err
+	}
+
+	v.name = visibility.name
+	return nil
+}
+
+// MarshalText implement the marshal interface for JSON conversions.
+func (v Visibility) MarshalText() ([]byte, error) {
+	return []byte(v.name), nil
+}
+
+// Equal provides support for the go-cmp package and testing.
+func (v Visibility) Equal(v2 Visibility) bool {
+	return v.name == v2.name
+}
@@ -0,0 +1,259 @@
+package topiclite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/google/uuid"
+)
+
+// Topic represents information about an individual topic.
+type dbTopic struct {
+	ID          uuid.UUID     `db:"id"`
+	Name        string        `db:"name"`
+	Description string        `db:"description"`
+	OwnerID     uuid.NullUUID `db:"owner_id"`
+	Visibility  string        `db:"visibility"`
+	ArchivedAt  sql.NullTime  `db:"archived_at"`
+	Retention   int64         `db:"retention_seconds"`
+	Version     int           `db:"version"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   sql.NullTime  `db:"updated_at"`
+	DeletedAt   sql.NullTime  `db:"deleted_at"`
+}
+
+func toDBTopic(tpc topic.Topic) dbTopic {
+	return dbTopic{
+		ID:          tpc.ID,
+		Name:        tpc.Name,
+		Description: tpc.Description,
+		OwnerID:     toNullUUID(tpc.OwnerID),
+		Visibility:  tpc.Visibility.Name(),
+		ArchivedAt:  toNullTime(tpc.ArchivedAt),
+		Retention:   int64(tpc.Retention / time.Second),
+		Version:     tpc.Version,
+		CreatedAt:   tpc.CreatedAt.UTC(),
+		UpdatedAt:   toNullTime(tpc.UpdatedAt),
+		DeletedAt:   toNullTime(tpc.DeletedAt),
+	}
+}
+
+func toCoreTopic(dbTpc dbTopic) (topic.Topic, error) {
+	visibility, err := topic.ParseVisibility(dbTpc.Visibility)
+	if err != nil {
+		return topic.Topic{}, fmt.Errorf("parse visibility: %w", err)
+	}
+
+	tpc := topic.Topic{
+		ID:          dbTpc.ID,
+		Name:        dbTpc.Name,
+		Description: dbTpc.Description,
+		OwnerID:     dbTpc.OwnerID.UUID,
+		Visibility:  visibility,
+		Retention:   time.Duration(dbTpc.Retention) * time.Second,
+		Version:     dbTpc.Version,
+		CreatedAt:   dbTpc.CreatedAt,
+	}
+
+	if dbTpc.ArchivedAt.Valid {
+		tpc.ArchivedAt = dbTpc.ArchivedAt.Time.In(time.Local)
+	}
+
+	if dbTpc.UpdatedAt.Valid {
+		tpc.UpdatedAt = dbTpc.UpdatedAt.Time.In(time.Local)
+	}
+
+	if dbTpc.DeletedAt.Valid {
+		tpc.DeletedAt = dbTpc.DeletedAt.Time.In(time.Local)
+	}
+
+	return tpc, nil
+}
+
+func toCoreTopicSlice(dbTpcs []dbTopic) ([]topic.Topic, error) {
+	tpcs := make([]topic.Topic, len(dbTpcs))
+
+	for i, dbTpc := range dbTpcs {
+		var err error
+		tpcs[i], err = toCoreTopic(dbTpc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tpcs, nil
+}
+
+// =============================================================================
+
+type dbAccess struct {
+	TopicID   uuid.UUID          `db:"topic_id"`
+	UserID    uuid.UUID          `db:"user_id"`
+	Rights    database.JSONArray `db:"rights"`
+	CreatedAt time.Time          `db:"created_at"`
+	UpdatedAt time.Time          `db:"updated_at"`
+}
+
+func toDBAccess(a topic.Access) dbAccess {
+	return dbAccess{
+		TopicID:   a.TopicID,
+		UserID:    a.UserID,
+		Rights:    toDBRights(a.Rights),
+		CreatedAt: a.CreatedAt.UTC(),
+		UpdatedAt: a.UpdatedAt.UTC(),
+	}
+}
+
+func toCoreAccess(dbA dbAccess) (topic.Access, error) {
+	rights, err := toCoreRights(dbA.Rights)
+	if err != nil {
+		return topic.Access{}, err
+	}
+
+	a := topic.Access{
+		TopicID:   dbA.TopicID,
+		UserID:    dbA.UserID,
+		Rights:    rights,
+		CreatedAt: dbA.CreatedAt.In(time.Local),
+		UpdatedAt: dbA.UpdatedAt.In(time.Local),
+	}
+
+	return a, nil
+}
+
+func toCoreAccessSlice(dbAs []dbAccess) ([]topic.Access, error) {
+	as := make([]topic.Access, len(dbAs))
+
+	for i, dbA := range dbAs {
+		var err error
+		as[i], err = toCoreAccess(dbA)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return as, nil
+}
+
+// =============================================================================
+
+type dbInvitation struct {
+	ID          uuid.UUID          `db:"id"`
+	TopicID     uuid.UUID          `db:"topic_id"`
+	Email       string             `db:"email"`
+	Rights      database.JSONArray `db:"rights"`
+	HashedToken string             `db:"hashed_token"`
+	InvitedBy   uuid.NullUUID      `db:"invited_by"`
+	ExpiresAt   time.Time          `db:"expires_at"`
+	AcceptedAt  sql.NullTime       `db:"accepted_at"`
+	CreatedAt   time.Time          `db:"created_at"`
+}
+
+func toDBInvitation(inv topic.Invitation) dbInvitation {
+	return dbInvitation{
+		ID:          inv.ID,
+		TopicID:     inv.TopicID,
+		Email:       inv.Email,
+		Rights:      toDBRights(inv.Rights),
+		HashedToken: inv.HashedToken,
+		InvitedBy:   toNullUUID(inv.InvitedBy),
+		ExpiresAt:   inv.ExpiresAt.UTC(),
+		AcceptedAt:  toNullTime(inv.AcceptedAt),
+		CreatedAt:   inv.CreatedAt.UTC(),
+	}
+}
+
+func toCoreInvitation(dbInv dbInvitation) (topic.Invitation, error) {
+	rights, err := toCoreRights(dbInv.Rights)
+	if err != nil {
+		return topic.Invitation{}, err
+	}
+
+	inv := topic.Invitation{
+		ID:          dbInv.ID,
+		TopicID:     dbInv.TopicID,
+		Email:       dbInv.Email,
+		Rights:      rights,
+		HashedToken: dbInv.HashedToken,
+		InvitedBy:   dbInv.InvitedBy.UUID,
+		ExpiresAt:   dbInv.ExpiresAt.In(time.Local),
+		CreatedAt:   dbInv.CreatedAt.In(time.Local),
+	}
+
+	if dbInv.AcceptedAt.Valid {
+		inv.AcceptedAt = dbInv.AcceptedAt.Time.In(time.Local)
+	}
+
+	return inv, nil
+}
+
+// =============================================================================
+
+type dbDeletion struct {
+	TopicID     uuid.UUID     `db:"topic_id"`
+	HashedToken string        `db:"hashed_token"`
+	RequestedBy uuid.NullUUID `db:"requested_by"`
+	ExpiresAt   time.Time     `db:"expires_at"`
+	CreatedAt   time.Time     `db:"created_at"`
+}
+
+func toDBDeletion(d topic.Deletion) dbDeletion {
+	return dbDeletion{
+		TopicID:     d.TopicID,
+		HashedToken: d.HashedToken,
+		RequestedBy: toNullUUID(d.RequestedBy),
+		ExpiresAt:   d.ExpiresAt.UTC(),
+		CreatedAt:   d.CreatedAt.UTC(),
+	}
+}
+
+func toCoreDeletion(dbD dbDeletion) topic.Deletion {
+	return topic.Deletion{
+		TopicID:     dbD.TopicID,
+		HashedToken: dbD.HashedToken,
+		RequestedBy: dbD.RequestedBy.UUID,
+		ExpiresAt:   dbD.ExpiresAt.In(time.Local),
+		CreatedAt:   dbD.CreatedAt.In(time.Local),
+	}
+}
+
+// =============================================================================
+
+func toDBRights(rights []topic.Right) database.JSONArray {
+	names := make(database.JSONArray, len(rights))
+	for i, right := range rights {
+		names[i] = right.Name()
+	}
+
+	return names
+}
+
+func toCoreRights(names []string) ([]topic.Right, error) {
+	rights := make([]topic.Right, len(names))
+	for i, name := range names {
+		var err error
+		rights[i], err = topic.ParseRight(name)
+		if err != nil {
+			return nil, fmt.Errorf("parse right: %w", err)
+		}
+	}
+
+	return rights, nil
+}
+
+func toNullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{
+		UUID:  id,
+		Valid: id != uuid.Nil,
+	}
+}
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{
+		Time:  t.UTC(),
+		Valid: !t.IsZero(),
+	}
+}
@@ -0,0 +1,522 @@
+// Package topiclite contains topic related CRUD functionality, stored in
+// SQLite.
+package topiclite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for topic access in SQLite.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(topic.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts a new topic into the database.
+func (s *Store) Create(ctx context.Context, tpc topic.Topic) error {
+	const q = `
+	INSERT INTO topics
+		(id, name, description, owner_id, visibility, archived_at, retention_seconds, version, created_at, updated_at)
+	VALUES
+		(:id, :name, :description, :owner_id, :visibility, :archived_at, :retention_seconds, :version, :created_at, :updated_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBTopic(tpc)); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return topic.ErrUniqueName
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a topic document in the database, as long as it's still at
+// the version of the topic.
+func (s *Store) Update(ctx context.Context, tpc topic.Topic) error {
+	const q = `
+	UPDATE
+		topics
+	SET
+		name = :name,
+		description = :description,
+		archived_at = :archived_at,
+		retention_seconds = :retention_seconds,
+		updated_at = :updated_at,
+		version = version + 1
+	WHERE
+		id = :id
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, toDBTopic(tpc))
+	if err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return topic.ErrUniqueName
+		}
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return topic.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Delete marks a topic as deleted in the database, as long as it's
+// still at the version of the topic.
+func (s *Store) Delete(ctx context.Context, tpc topic.Topic) error {
+	data := struct {
+		ID        uuid.UUID `db:"id"`
+		DeletedAt time.Time `db:"deleted_at"`
+		Version   int       `db:"version"`
+	}{
+		ID:        tpc.ID,
+		DeletedAt: tpc.DeletedAt.UTC(),
+		Version:   tpc.Version,
+	}
+
+	const q = `
+	UPDATE
+		topics
+	SET
+		deleted_at = :deleted_at
+	WHERE
+		id = :id AND deleted_at IS NULL
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return topic.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Restore clears the deletion of a topic in the database.
+func (s *Store) Restore(ctx context.Context, tpc topic.Topic) error {
+	data := struct {
+		ID        uuid.UUID `db:"id"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}{
+		ID:        tpc.ID,
+		UpdatedAt: tpc.UpdatedAt.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		topics
+	SET
+		deleted_at = NULL,
+		updated_at = :updated_at
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return topic.ErrUniqueName
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes up to limit topics deleted before the given time from the
+// database. Their notifications, subscriptions, access and invitations are
+// removed with them.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+		Limit  int       `db:"limit"`
+	}{
+		Before: before.UTC(),
+		Limit:  limit,
+	}
+
+	const q = `
+	DELETE FROM
+		topics
+	WHERE
+		id IN (SELECT id FROM topics WHERE deleted_at < :before LIMIT :limit)`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	return n, nil
+}
+
+// Query retrieves a list of existing topics from the database.
+func (s *Store) Query(ctx context.Context, filter topic.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]topic.Topic, error) {
+	b := database.NewQueryBuilder(`SELECT t.* FROM topics t`)
+	applyFilter(b, filter)
+
+	if err := b.OrderBy(orderBy, orderByFields, "t.id"); err != nil {
+		return nil, err
+	}
+	b.Page(pageNumber, rowsPerPage)
+
+	q, data := b.Query()
+
+	var dbTpcs []dbTopic
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbTpcs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreTopicSlice(dbTpcs)
+}
+
+// Count returns the number of topics in the database matching the filter.
+func (s *Store) Count(ctx context.Context, filter topic.QueryFilter) (int, error) {
+	b := database.NewQueryBuilder(`SELECT t.* FROM topics t`)
+	applyFilter(b, filter)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByID gets the specified topic from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (topic.Topic, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM topics WHERE id = :id AND deleted_at IS NULL`
+
+	var dbTpc dbTopic
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTpc); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return topic.Topic{}, topic.ErrNotFound
+		}
+		return topic.Topic{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreTopic(dbTpc)
+}
+
+// QueryDeletedByID gets the specified deleted topic from the database by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (topic.Topic, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM topics WHERE id = :id AND deleted_at IS NOT NULL`
+
+	var dbTpc dbTopic
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTpc); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return topic.Topic{}, topic.ErrNotFound
+		}
+		return topic.Topic{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreTopic(dbTpc)
+}
+
+// QueryByName gets the specified topic from the database by name.
+func (s *Store) QueryByName(ctx context.Context, name string) (topic.Topic, error) {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `SELECT * FROM topics WHERE name = :name AND deleted_at IS NULL`
+
+	var dbTpc dbTopic
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbTpc); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return topic.Topic{}, topic.ErrNotFound
+		}
+		return topic.Topic{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreTopic(dbTpc)
+}
+
+// QueryWithRetention gets the topics with a retention of their own from the
+// database, deleted ones included.
+func (s *Store) QueryWithRetention(ctx context.Context) ([]topic.Topic, error) {
+	const q = `SELECT * FROM topics WHERE retention_seconds > 0`
+
+	var dbTpcs []dbTopic
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, struct{}{}, &dbTpcs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreTopicSlice(dbTpcs)
+}
+
+// UpsertAccess inserts or replaces the access of a user to a topic in the
+// database.
+func (s *Store) UpsertAccess(ctx context.Context, a topic.Access) error {
+	const q = `
+	INSERT INTO topic_acls
+		(topic_id, user_id, rights, created_at, updated_at)
+	VALUES
+		(:topic_id, :user_id, :rights, :created_at, :updated_at)
+	ON CONFLICT (topic_id, user_id) DO UPDATE SET
+		rights = EXCLUDED.rights,
+		updated_at = EXCLUDED.updated_at`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBAccess(a)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAccess removes the access of a user to a topic from the database.
+func (s *Store) DeleteAccess(ctx context.Context, a topic.Access) error {
+	const q = `
+	DELETE FROM
+		topic_acls
+	WHERE
+		topic_id = :topic_id AND user_id = :user_id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBAccess(a)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryAccess gets the access of a user to a topic from the database.
+func (s *Store) QueryAccess(ctx context.Context, topicID uuid.UUID, userID uuid.UUID) (topic.Access, error) {
+	data := struct {
+		TopicID uuid.UUID `db:"topic_id"`
+		UserID  uuid.UUID `db:"user_id"`
+	}{
+		TopicID: topicID,
+		UserID:  userID,
+	}
+
+	const q = `SELECT * FROM topic_acls WHERE topic_id = :topic_id AND user_id = :user_id`
+
+	var dbA dbAccess
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbA); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return topic.Access{}, topic.ErrAccessNotFound
+		}
+		return topic.Access{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreAccess(dbA)
+}
+
+// QueryAccessByTopicID retrieves the access granted on a topic from the
+// database.
+func (s *Store) QueryAccessByTopicID(ctx context.Context, topicID uuid.UUID) ([]topic.Access, error) {
+	data := struct {
+		TopicID uuid.UUID `db:"topic_id"`
+	}{
+		TopicID: topicID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		topic_acls
+	WHERE
+		topic_id = :topic_id
+	ORDER BY
+		created_at`
+
+	var dbAs []dbAccess
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbAs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreAccessSlice(dbAs)
+}
+
+// DeleteSubscription marks the subscription of a user to a topic as deleted
+// in the database, if there is one.
+func (s *Store) DeleteSubscription(ctx context.Context, topicID uuid.UUID, userID uuid.UUID) error {
+	data := struct {
+		TopicID   uuid.UUID `db:"topic_id"`
+		UserID    uuid.UUID `db:"user_id"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}{
+		TopicID:   topicID,
+		UserID:    userID,
+		DeletedAt: time.Now().UTC(),
+	}
+
+	const q = `
+	UPDATE
+		subscriptions
+	SET
+		deleted_at = :deleted_at
+	WHERE
+		topic_id = :topic_id AND user_id = :user_id AND deleted_at IS NULL`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// CreateInvitation inserts a new invitation into the database.
+func (s *Store) CreateInvitation(ctx context.Context, inv topic.Invitation) error {
+	const q = `
+	INSERT INTO topic_invitations
+		(id, topic_id, email, rights, hashed_token, invited_by, expires_at, accepted_at, created_at)
+	VALUES
+		(:id, :topic_id, :email, :rights, :hashed_token, :invited_by, :expires_at, :accepted_at, :created_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBInvitation(inv)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateInvitation replaces an invitation in the database.
+func (s *Store) UpdateInvitation(ctx context.Context, inv topic.Invitation) error {
+	const q = `
+	UPDATE
+		topic_invitations
+	SET
+		accepted_at = :accepted_at
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBInvitation(inv)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryInvitationByID gets the specified invitation from the database.
+func (s *Store) QueryInvitationByID(ctx context.Context, id uuid.UUID) (topic.Invitation, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM topic_invitations WHERE id = :id`
+
+	var dbInv dbInvitation
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbInv); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return topic.Invitation{}, topic.ErrInvitationNotFound
+		}
+		return topic.Invitation{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreInvitation(dbInv)
+}
+
+// UpsertDeletion inserts or replaces the deletion request of a topic in the
+// database.
+func (s *Store) UpsertDeletion(ctx context.Context, d topic.Deletion) error {
+	const q = `
+	INSERT INTO topic_deletions
+		(topic_id, hashed_token, requested_by, expires_at, created_at)
+	VALUES
+		(:topic_id, :hashed_token, :requested_by, :expires_at, :created_at)
+	ON CONFLICT (topic_id) DO UPDATE SET
+		hashed_token = EXCLUDED.hashed_token,
+		requested_by = EXCLUDED.requested_by,
+		expires_at = EXCLUDED.expires_at,
+		created_at = EXCLUDED.created_at`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBDeletion(d)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDeletion gets the deletion request of a topic from the database.
+func (s *Store) QueryDeletion(ctx context.Context, topicID uuid.UUID) (topic.Deletion, error) {
+	data := struct {
+		TopicID uuid.UUID `db:"topic_id"`
+	}{
+		TopicID: topicID,
+	}
+
+	const q = `SELECT * FROM topic_deletions WHERE topic_id = :topic_id`
+
+	var dbD dbDeletion
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbD); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return topic.Deletion{}, topic.ErrDeletionNotFound
+		}
+		return topic.Deletion{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreDeletion(dbD), nil
+}
+
+// DeleteDeletion removes the deletion request of a topic from the database.
+func (s *Store) DeleteDeletion(ctx context.Context, topicID uuid.UUID) error {
+	data := struct {
+		TopicID uuid.UUID `db:"topic_id"`
+	}{
+		TopicID: topicID,
+	}
+
+	const q = `DELETE FROM topic_deletions WHERE topic_id = :topic_id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package topicdb
+
+import (
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/platform/database"
+)
+
+// orderByFields maps the fields topics can be ordered by to their columns.
+var orderByFields = map[string]string{
+	topic.OrderByID:        "t.id",
+	topic.OrderByName:      "t.name",
+	topic.OrderByCreatedAt: "t.created_at",
+}
+
+// applyFilter adds the conditions of the filter to the query.
+func applyFilter(b *database.QueryBuilder, filter topic.QueryFilter) {
+	if filter.Deleted {
+		b.Where("t.deleted_at IS NOT NULL", nil)
+	} else {
+		b.Where("t.deleted_at IS NULL", nil)
+	}
+
+	if filter.Name != nil {
+		b.Where("t.name ILIKE :name", map[string]any{"name": "%" + *filter.Name + "%"})
+	}
+
+	if filter.Visibility != nil {
+		b.Where("t.visibility = :visibility", map[string]any{"visibility": filter.Visibility.Name()})
+	}
+
+	if filter.OwnerID != nil {
+		b.Where("t.owner_id = :owner_id", map[string]any{"owner_id": *filter.OwnerID})
+	}
+
+	if filter.Archived != nil {
+		switch *filter.Archived {
+		case true:
+			b.Where("t.archived_at IS NOT NULL", nil)
+		default:
+			b.Where("t.archived_at IS NULL", nil)
+		}
+	}
+
+	if filter.AccessibleTo != nil {
+		const accessible = `
+		t.visibility = :public_visibility OR
+		t.owner_id = :accessible_to OR
+		EXISTS (SELECT 1 FROM topic_acls a WHERE a.topic_id = t.id AND a.user_id = :accessible_to)`
+
+		b.Where(accessible, map[string]any{
+			"public_visibility": topic.VisibilityPublic.Name(),
+			"accessible_to":     *filter.AccessibleTo,
+		})
+	}
+}
@@ -2,27 +2,86 @@
 package topic
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/dudakovict/gotify/pkg/util"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 )
 
 // Set of error variables for CRUD operations.
 var (
-	ErrNotFound   = errors.New("topic not found")
-	ErrUniqueName = errors.New("topic already exists")
+	ErrNotFound           = errors.New("topic not found")
+	ErrUniqueName         = errors.New("topic already exists")
+	ErrInvalidName        = errors.New("invalid topic name")
+	ErrInvalidRight       = errors.New("invalid right")
+	ErrInvalidVisibility  = errors.New("invalid visibility")
+	ErrAccessNotFound     = errors.New("access not found")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationExpired  = errors.New("invitation has expired")
+	ErrInvitationAccepted = errors.New("invitation was already accepted")
+	ErrInvitationMismatch = errors.New("invitation was sent to another email")
+	ErrInvitationFailure  = errors.New("invalid invitation token")
+	ErrOwnerAccess        = errors.New("the owner of a topic always has every right")
+	ErrRightsRequired     = errors.New("at least one right is required")
+	ErrArchived           = errors.New("topic is archived")
+	ErrDeletionNotFound   = errors.New("topic deletion was not requested")
+	ErrDeletionExpired    = errors.New("topic deletion request has expired")
+	ErrDeletionFailure    = errors.New("invalid deletion token")
+	ErrInvalidRetention   = errors.New("retention has to be at least an hour")
+	ErrVersionConflict    = errors.New("topic was changed by someone else")
+)
+
+const (
+	// InvitationDuration is how long an invitation can be accepted for.
+	InvitationDuration = 7 * 24 * time.Hour
+
+	// DeletionDuration is how long a request to delete a topic can be
+	// confirmed for.
+	DeletionDuration = 15 * time.Minute
+
+	// MinRetention is the shortest retention a topic can have, since the
+	// notifications are purged once an hour.
+	MinRetention = time.Hour
 )
 
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
-	WithinTran(fn func(s Storer) error) error
-	Create(topic Topic) error
-	Query(pageNumber int, rowsPerPage int) ([]Topic, error)
-	QueryByID(id uuid.UUID) (Topic, error)
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, topic Topic) error
+	Update(ctx context.Context, topic Topic) error
+	Delete(ctx context.Context, topic Topic) error
+	Restore(ctx context.Context, topic Topic) error
+	Purge(ctx context.Context, before time.Time, limit int) (int, error)
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Topic, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, id uuid.UUID) (Topic, error)
+	QueryDeletedByID(ctx context.Context, id uuid.UUID) (Topic, error)
+	QueryByName(ctx context.Context, name string) (Topic, error)
+	QueryWithRetention(ctx context.Context) ([]Topic, error)
+	UpsertAccess(ctx context.Context, a Access) error
+	DeleteAccess(ctx context.Context, a Access) error
+	QueryAccess(ctx context.Context, topicID uuid.UUID, userID uuid.UUID) (Access, error)
+	QueryAccessByTopicID(ctx context.Context, topicID uuid.UUID) ([]Access, error)
+	DeleteSubscription(ctx context.Context, topicID uuid.UUID, userID uuid.UUID) error
+	CreateInvitation(ctx context.Context, inv Invitation) error
+	UpdateInvitation(ctx context.Context, inv Invitation) error
+	QueryInvitationByID(ctx context.Context, id uuid.UUID) (Invitation, error)
+	UpsertDeletion(ctx context.Context, d Deletion) error
+	QueryDeletion(ctx context.Context, topicID uuid.UUID) (Deletion, error)
+	DeleteDeletion(ctx context.Context, topicID uuid.UUID) error
 }
 
 // Core manages the set of APIs for user access.
@@ -41,23 +100,201 @@ func NewCore(log *zerolog.Logger, storer Storer, gen util.Generator) *Core {
 	}
 }
 
-// Create adds a new user to the system.
-func (c *Core) Create(nt NewTopic) (Topic, error) {
+// Create adds a new topic to the system. Topics are public unless another
+// visibility is given. Names can be hierarchical, see Separators.
+func (c *Core) Create(ctx context.Context, nt NewTopic) (Topic, error) {
+	if err := ValidateName(nt.Name); err != nil {
+		return Topic{}, err
+	}
+
+	if err := ValidateRetention(nt.Retention); err != nil {
+		return Topic{}, err
+	}
+
+	visibility := nt.Visibility
+	if visibility == (Visibility{}) {
+		visibility = VisibilityPublic
+	}
+
 	topic := Topic{
-		ID:   c.gen(),
-		Name: nt.Name,
+		ID:          c.gen(),
+		Name:        nt.Name,
+		Description: nt.Description,
+		OwnerID:     nt.OwnerID,
+		Visibility:  visibility,
+		Retention:   nt.Retention,
+		Version:     1,
 	}
 
-	if err := c.storer.Create(topic); err != nil {
+	if err := c.storer.Create(ctx, topic); err != nil {
 		return Topic{}, fmt.Errorf("create: %w", err)
 	}
 
 	return topic, nil
 }
 
+// Update modifies the name, description and retention of a topic.
+func (c *Core) Update(ctx context.Context, tpc Topic, ut UpdateTopic) (Topic, error) {
+	if ut.Name != nil {
+		if err := ValidateName(*ut.Name); err != nil {
+			return Topic{}, err
+		}
+		tpc.Name = *ut.Name
+	}
+
+	if ut.Description != nil {
+		tpc.Description = *ut.Description
+	}
+
+	if ut.Retention != nil {
+		if err := ValidateRetention(*ut.Retention); err != nil {
+			return Topic{}, err
+		}
+		tpc.Retention = *ut.Retention
+	}
+
+	tpc.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, tpc); err != nil {
+		return Topic{}, fmt.Errorf("update: %w", err)
+	}
+	tpc.Version++
+
+	return tpc, nil
+}
+
+// Archive stops the topic from accepting new notifications while keeping its
+// history. Archiving an archived topic does nothing.
+func (c *Core) Archive(ctx context.Context, tpc Topic) (Topic, error) {
+	if tpc.Archived() {
+		return tpc, nil
+	}
+
+	now := time.Now()
+	tpc.ArchivedAt = now
+	tpc.UpdatedAt = now
+
+	if err := c.storer.Update(ctx, tpc); err != nil {
+		return Topic{}, fmt.Errorf("update: %w", err)
+	}
+	tpc.Version++
+
+	return tpc, nil
+}
+
+// Unarchive makes an archived topic accept new notifications again.
+func (c *Core) Unarchive(ctx context.Context, tpc Topic) (Topic, error) {
+	if !tpc.Archived() {
+		return tpc, nil
+	}
+
+	tpc.ArchivedAt = time.Time{}
+	tpc.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, tpc); err != nil {
+		return Topic{}, fmt.Errorf("update: %w", err)
+	}
+	tpc.Version++
+
+	return tpc, nil
+}
+
+// RequestDeletion starts the deletion of a topic and returns the token that
+// confirms it, which is only available at this point. Requesting it again
+// replaces the previous token.
+func (c *Core) RequestDeletion(ctx context.Context, tpc Topic, userID uuid.UUID) (Deletion, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return Deletion{}, "", err
+	}
+
+	now := time.Now()
+	d := Deletion{
+		TopicID:     tpc.ID,
+		HashedToken: hash(token),
+		RequestedBy: userID,
+		ExpiresAt:   now.Add(DeletionDuration),
+		CreatedAt:   now,
+	}
+
+	if err := c.storer.UpsertDeletion(ctx, d); err != nil {
+		return Deletion{}, "", fmt.Errorf("upsertdeletion: %w", err)
+	}
+
+	return d, token, nil
+}
+
+// DeleteTx marks the topic as deleted once the deletion is confirmed with the
+// token, which can't be used again. Its notifications, subscriptions and
+// access are kept until the topic is purged, so it can be restored. It fails
+// with ErrVersionConflict when the topic was changed since it was read.
+func (c *Core) DeleteTx(ctx context.Context, tpc Topic, dtTx DeleteTopicTx) error {
+	tpc.DeletedAt = time.Now()
+
+	tran := func(s Storer) error {
+		d, err := s.QueryDeletion(ctx, tpc.ID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case subtle.ConstantTimeCompare([]byte(hash(dtTx.Token)), []byte(d.HashedToken)) != 1:
+			return ErrDeletionFailure
+		case time.Now().After(d.ExpiresAt):
+			return ErrDeletionExpired
+		}
+
+		if err := s.Delete(ctx, tpc); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+
+		if err := s.DeleteDeletion(ctx, tpc.ID); err != nil {
+			return fmt.Errorf("deletedeletion: %w", err)
+		}
+
+		return dtTx.AfterDelete(tpc)
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		for _, target := range []error{ErrDeletionNotFound, ErrDeletionFailure, ErrDeletionExpired, ErrVersionConflict} {
+			if errors.Is(err, target) {
+				return target
+			}
+		}
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	return nil
+}
+
+// Restore brings back a deleted topic. It fails with ErrUniqueName when the
+// name has been taken by another topic in the meantime.
+func (c *Core) Restore(ctx context.Context, tpc Topic) (Topic, error) {
+	tpc.DeletedAt = time.Time{}
+	tpc.UpdatedAt = time.Now()
+
+	if err := c.storer.Restore(ctx, tpc); err != nil {
+		return Topic{}, fmt.Errorf("restore: %w", err)
+	}
+
+	return tpc, nil
+}
+
+// Purge removes up to limit topics deleted before the given time for good,
+// together with their notifications, subscriptions and access, and returns
+// how many were removed.
+func (c *Core) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	n, err := c.storer.Purge(ctx, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purge: %w", err)
+	}
+
+	return n, nil
+}
+
 // Query retrieves a list of existing topics.
-func (c *Core) Query(pageNumber int, rowsPerPage int) ([]Topic, error) {
-	topics, err := c.storer.Query(pageNumber, rowsPerPage)
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]Topic, error) {
+	topics, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -65,12 +302,321 @@ func (c *Core) Query(pageNumber int, rowsPerPage int) ([]Topic, error) {
 	return topics, nil
 }
 
+// Count returns the number of topics matching the filter.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	count, err := c.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
 // QueryByID finds the topic by the specified ID.
-func (c *Core) QueryByID(id uuid.UUID) (Topic, error) {
-	topic, err := c.storer.QueryByID(id)
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (Topic, error) {
+	topic, err := c.storer.QueryByID(ctx, id)
 	if err != nil {
 		return Topic{}, fmt.Errorf("query: %w", err)
 	}
 
 	return topic, nil
 }
+
+// QueryDeletedByID finds the deleted topic by the specified ID.
+func (c *Core) QueryDeletedByID(ctx context.Context, id uuid.UUID) (Topic, error) {
+	topic, err := c.storer.QueryDeletedByID(ctx, id)
+	if err != nil {
+		return Topic{}, fmt.Errorf("query: %w", err)
+	}
+
+	return topic, nil
+}
+
+// QueryWithRetention retrieves the topics with a retention of their own,
+// deleted ones included.
+func (c *Core) QueryWithRetention(ctx context.Context) ([]Topic, error) {
+	topics, err := c.storer.QueryWithRetention(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return topics, nil
+}
+
+// QueryByName finds the topic by the specified name.
+func (c *Core) QueryByName(ctx context.Context, name string) (Topic, error) {
+	topic, err := c.storer.QueryByName(ctx, name)
+	if err != nil {
+		return Topic{}, fmt.Errorf("query: name[%s]: %w", name, err)
+	}
+
+	return topic, nil
+}
+
+// Allowed reports whether the user has the right on the topic. Owners have
+// every right and everyone may subscribe to public topics. Reading the
+// history of a topic requires the subscribe right.
+func (c *Core) Allowed(ctx context.Context, tpc Topic, userID uuid.UUID, right Right) (bool, error) {
+	if tpc.OwnerID != uuid.Nil && tpc.OwnerID == userID {
+		return true, nil
+	}
+
+	if right == RightSubscribe && tpc.Visibility == VisibilityPublic {
+		return true, nil
+	}
+
+	a, err := c.storer.QueryAccess(ctx, tpc.ID, userID)
+	if err != nil {
+		if errors.Is(err, ErrAccessNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("queryaccess: %w", err)
+	}
+
+	return a.Has(right), nil
+}
+
+// Subscribers returns whether users may subscribe to the topic, the same as
+// Allowed does for the subscribe right, answering for any number of users from
+// a single query.
+func (c *Core) Subscribers(ctx context.Context, tpc Topic) (func(userID uuid.UUID) bool, error) {
+	if tpc.Visibility == VisibilityPublic {
+		return func(uuid.UUID) bool { return true }, nil
+	}
+
+	as, err := c.storer.QueryAccessByTopicID(ctx, tpc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("queryaccessbytopicid: %w", err)
+	}
+
+	allowed := make(map[uuid.UUID]bool, len(as)+1)
+	if tpc.OwnerID != uuid.Nil {
+		allowed[tpc.OwnerID] = true
+	}
+
+	for _, a := range as {
+		if a.Has(RightSubscribe) {
+			allowed[a.UserID] = true
+		}
+	}
+
+	return func(userID uuid.UUID) bool { return allowed[userID] }, nil
+}
+
+// Grant sets the rights of the user on the topic, replacing any granted
+// before.
+func (c *Core) Grant(ctx context.Context, tpc Topic, userID uuid.UUID, rights []Right) (Access, error) {
+	if tpc.OwnerID == userID {
+		return Access{}, ErrOwnerAccess
+	}
+
+	if len(rights) == 0 {
+		return Access{}, ErrRightsRequired
+	}
+
+	now := time.Now()
+	a := Access{
+		TopicID:   tpc.ID,
+		UserID:    userID,
+		Rights:    compact(rights),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	tran := func(s Storer) error {
+		if err := s.UpsertAccess(ctx, a); err != nil {
+			return fmt.Errorf("upsertaccess: %w", err)
+		}
+
+		if tpc.Visibility == VisibilityPrivate && !a.Has(RightSubscribe) {
+			if err := s.DeleteSubscription(ctx, tpc.ID, userID); err != nil {
+				return fmt.Errorf("deletesubscription: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return Access{}, fmt.Errorf("tran: %w", err)
+	}
+
+	return a, nil
+}
+
+// Revoke removes the access of the user to the topic. Subscriptions to
+// private topics are removed together with the access.
+func (c *Core) Revoke(ctx context.Context, tpc Topic, userID uuid.UUID) error {
+	tran := func(s Storer) error {
+		a, err := s.QueryAccess(ctx, tpc.ID, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.DeleteAccess(ctx, a); err != nil {
+			return fmt.Errorf("deleteaccess: %w", err)
+		}
+
+		if tpc.Visibility == VisibilityPrivate {
+			if err := s.DeleteSubscription(ctx, tpc.ID, userID); err != nil {
+				return fmt.Errorf("deletesubscription: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		if errors.Is(err, ErrAccessNotFound) {
+			return ErrAccessNotFound
+		}
+		return fmt.Errorf("tran: %w", err)
+	}
+
+	return nil
+}
+
+// QueryAccessByTopicID retrieves the access granted to users on the topic.
+func (c *Core) QueryAccessByTopicID(ctx context.Context, topicID uuid.UUID) ([]Access, error) {
+	as, err := c.storer.QueryAccessByTopicID(ctx, topicID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return as, nil
+}
+
+// Invite creates an invitation to the topic and returns it together with the
+// token, which is only available at this point.
+func (c *Core) Invite(ctx context.Context, ni NewInvitation) (Invitation, string, error) {
+	if len(ni.Rights) == 0 {
+		return Invitation{}, "", ErrRightsRequired
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return Invitation{}, "", err
+	}
+
+	now := time.Now()
+	inv := Invitation{
+		ID:          c.gen(),
+		TopicID:     ni.TopicID,
+		Email:       ni.Email,
+		Rights:      compact(ni.Rights),
+		HashedToken: hash(token),
+		InvitedBy:   ni.InvitedBy,
+		ExpiresAt:   now.Add(InvitationDuration),
+		CreatedAt:   now,
+	}
+
+	if err := c.storer.CreateInvitation(ctx, inv); err != nil {
+		return Invitation{}, "", fmt.Errorf("createinvitation: %w", err)
+	}
+
+	return inv, token, nil
+}
+
+// QueryInvitationByID finds the invitation by the specified ID.
+func (c *Core) QueryInvitationByID(ctx context.Context, id uuid.UUID) (Invitation, error) {
+	inv, err := c.storer.QueryInvitationByID(ctx, id)
+	if err != nil {
+		return Invitation{}, fmt.Errorf("query: %w", err)
+	}
+
+	return inv, nil
+}
+
+// Accept grants the user the rights of the invitation. Only the user with the
+// invited email can accept it, and only once. Rights the user already had are
+// kept.
+func (c *Core) Accept(ctx context.Context, id uuid.UUID, token string, userID uuid.UUID, email string) (Access, error) {
+	var a Access
+
+	tran := func(s Storer) error {
+		inv, err := s.QueryInvitationByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case subtle.ConstantTimeCompare([]byte(hash(token)), []byte(inv.HashedToken)) != 1:
+			return ErrInvitationFailure
+		case !strings.EqualFold(inv.Email, email):
+			return ErrInvitationMismatch
+		case !inv.AcceptedAt.IsZero():
+			return ErrInvitationAccepted
+		case time.Now().After(inv.ExpiresAt):
+			return ErrInvitationExpired
+		}
+
+		now := time.Now()
+		a = Access{
+			TopicID:   inv.TopicID,
+			UserID:    userID,
+			Rights:    inv.Rights,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		existing, err := s.QueryAccess(ctx, inv.TopicID, userID)
+		switch {
+		case err == nil:
+			a.Rights = compact(append(existing.Rights, inv.Rights...))
+			a.CreatedAt = existing.CreatedAt
+		case !errors.Is(err, ErrAccessNotFound):
+			return fmt.Errorf("queryaccess: %w", err)
+		}
+
+		if err := s.UpsertAccess(ctx, a); err != nil {
+			return fmt.Errorf("upsertaccess: %w", err)
+		}
+
+		inv.AcceptedAt = now
+		if err := s.UpdateInvitation(ctx, inv); err != nil {
+			return fmt.Errorf("updateinvitation: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		for _, target := range []error{ErrInvitationNotFound, ErrInvitationFailure, ErrInvitationMismatch, ErrInvitationAccepted, ErrInvitationExpired} {
+			if errors.Is(err, target) {
+				return Access{}, target
+			}
+		}
+		return Access{}, fmt.Errorf("tran: %w", err)
+	}
+
+	return a, nil
+}
+
+// compact removes duplicated rights while keeping their order.
+func compact(rights []Right) []Right {
+	result := make([]Right, 0, len(rights))
+	for _, right := range rights {
+		if !slices.Contains(result, right) {
+			result = append(result, right)
+		}
+	}
+
+	return result
+}
+
+// newToken returns a random token safe to use in urls.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("rand: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hash returns the hex encoded SHA-256 of the token. Tokens carry enough
+// entropy that a slow hash isn't needed.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,8 +1,10 @@
 package topic_test
 
 import (
+	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/dudakovict/gotify/internal/core/topic"
 	mocktpc "github.com/dudakovict/gotify/internal/core/topic/mock"
@@ -13,6 +15,8 @@ import (
 )
 
 func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
 	tpc := randomTopic()
 
 	testCases := []struct {
@@ -24,13 +28,15 @@ func TestCreate(t *testing.T) {
 			name: "OK",
 			buildStubs: func(storer *mocktpc.MockStorer) {
 				arg := topic.Topic{
-					ID:        tpc.ID,
-					Name:      tpc.Name,
-					CreatedAt: tpc.CreatedAt,
+					ID:         tpc.ID,
+					Name:       tpc.Name,
+					Visibility: topic.VisibilityPublic,
+					Version:    1,
+					CreatedAt:  tpc.CreatedAt,
 				}
 
 				storer.EXPECT().
-					Create(arg).
+					Create(gomock.Any(), arg).
 					Times(1).
 					Return(nil)
 			},
@@ -45,7 +51,7 @@ func TestCreate(t *testing.T) {
 			name: "DuplicateName",
 			buildStubs: func(storer *mocktpc.MockStorer) {
 				storer.EXPECT().
-					Create(gomock.Any()).
+					Create(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(topic.ErrUniqueName)
 			},
@@ -67,7 +73,7 @@ func TestCreate(t *testing.T) {
 
 		tc.buildStubs(storer)
 
-		createdTpc, err := core.Create(topic.NewTopic{
+		createdTpc, err := core.Create(ctx, topic.NewTopic{
 			Name: tpc.Name,
 		})
 
@@ -76,6 +82,8 @@ func TestCreate(t *testing.T) {
 }
 
 func TestQueryByID(t *testing.T) {
+	ctx := context.Background()
+
 	tpc := randomTopic()
 
 	testCases := []struct {
@@ -87,7 +95,7 @@ func TestQueryByID(t *testing.T) {
 			name: "OK",
 			buildStubs: func(storer *mocktpc.MockStorer) {
 				storer.EXPECT().
-					QueryByID(tpc.ID).
+					QueryByID(gomock.Any(), tpc.ID).
 					Times(1).
 					Return(tpc, nil)
 			},
@@ -101,7 +109,7 @@ func TestQueryByID(t *testing.T) {
 			name: "NotFound",
 			buildStubs: func(storer *mocktpc.MockStorer) {
 				storer.EXPECT().
-					QueryByID(tpc.ID).
+					QueryByID(gomock.Any(), tpc.ID).
 					Times(1).
 					Return(topic.Topic{}, topic.ErrNotFound)
 			},
@@ -114,7 +122,7 @@ func TestQueryByID(t *testing.T) {
 			name: "InternalError",
 			buildStubs: func(storer *mocktpc.MockStorer) {
 				storer.EXPECT().
-					QueryByID(tpc.ID).
+					QueryByID(gomock.Any(), tpc.ID).
 					Times(1).
 					Return(topic.Topic{}, sql.ErrConnDone)
 			},
@@ -136,16 +144,21 @@ func TestQueryByID(t *testing.T) {
 
 		tc.buildStubs(storer)
 
-		queryTpc, err := core.QueryByID(tpc.ID)
+		queryTpc, err := core.QueryByID(ctx, tpc.ID)
 
 		tc.checkResponse(err, queryTpc)
 	}
 }
 
 func TestQuery(t *testing.T) {
+	ctx := context.Background()
+
 	pageNumber := 1
 	rowsPerPage := 10
 
+	userID := uuid.New()
+	filter := topic.QueryFilter{AccessibleTo: &userID}
+
 	tpcs := []topic.Topic{
 		randomTopic(),
 		randomTopic(),
@@ -161,7 +174,7 @@ func TestQuery(t *testing.T) {
 			name: "OK",
 			buildStubs: func(storer *mocktpc.MockStorer) {
 				storer.EXPECT().
-					Query(pageNumber, rowsPerPage).
+					Query(gomock.Any(), filter, topic.DefaultOrderBy, pageNumber, rowsPerPage).
 					Times(1).
 					Return(tpcs, nil)
 			},
@@ -175,7 +188,7 @@ func TestQuery(t *testing.T) {
 			name: "InternalError",
 			buildStubs: func(storer *mocktpc.MockStorer) {
 				storer.EXPECT().
-					Query(pageNumber, rowsPerPage).
+					Query(gomock.Any(), filter, topic.DefaultOrderBy, pageNumber, rowsPerPage).
 					Times(1).
 					Return(nil, sql.ErrConnDone)
 			},
@@ -197,16 +210,514 @@ func TestQuery(t *testing.T) {
 
 		tc.buildStubs(storer)
 
-		queryTpcs, err := core.Query(pageNumber, rowsPerPage)
+		queryTpcs, err := core.Query(ctx, filter, topic.DefaultOrderBy, pageNumber, rowsPerPage)
 
 		tc.checkResponse(err, queryTpcs)
 	}
 }
 
+func TestAllowed(t *testing.T) {
+	ctx := context.Background()
+
+	ownerID := uuid.New()
+	memberID := uuid.New()
+	strangerID := uuid.New()
+
+	tpc := randomTopic()
+	tpc.OwnerID = ownerID
+	tpc.Visibility = topic.VisibilityPrivate
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocktpc.NewMockStorer(ctrl)
+	storer.EXPECT().
+		QueryAccess(gomock.Any(), tpc.ID, memberID).
+		AnyTimes().
+		Return(topic.Access{Rights: []topic.Right{topic.RightSubscribe}}, nil)
+	storer.EXPECT().
+		QueryAccess(gomock.Any(), tpc.ID, strangerID).
+		AnyTimes().
+		Return(topic.Access{}, topic.ErrAccessNotFound)
+
+	core := topic.NewCore(nil, storer, gen)
+
+	testCases := []struct {
+		name    string
+		tpc     topic.Topic
+		userID  uuid.UUID
+		right   topic.Right
+		allowed bool
+	}{
+		{name: "Owner", tpc: tpc, userID: ownerID, right: topic.RightManage, allowed: true},
+		{name: "MemberSubscribe", tpc: tpc, userID: memberID, right: topic.RightSubscribe, allowed: true},
+		{name: "MemberPublish", tpc: tpc, userID: memberID, right: topic.RightPublish, allowed: false},
+		{name: "StrangerPrivate", tpc: tpc, userID: strangerID, right: topic.RightSubscribe, allowed: false},
+		{name: "StrangerPublic", tpc: publicTopic(tpc), userID: strangerID, right: topic.RightSubscribe, allowed: true},
+		{name: "StrangerPublicPublish", tpc: publicTopic(tpc), userID: strangerID, right: topic.RightPublish, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		allowed, err := core.Allowed(ctx, tc.tpc, tc.userID, tc.right)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.allowed, allowed, tc.name)
+	}
+}
+
+func TestSubscribers(t *testing.T) {
+	ctx := context.Background()
+
+	ownerID := uuid.New()
+	subscriberID := uuid.New()
+	managerID := uuid.New()
+	publisherID := uuid.New()
+	strangerID := uuid.New()
+
+	tpc := randomTopic()
+	tpc.OwnerID = ownerID
+	tpc.Visibility = topic.VisibilityPrivate
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocktpc.NewMockStorer(ctrl)
+	storer.EXPECT().
+		QueryAccessByTopicID(gomock.Any(), tpc.ID).
+		Times(1).
+		Return([]topic.Access{
+			{TopicID: tpc.ID, UserID: subscriberID, Rights: []topic.Right{topic.RightSubscribe}},
+			{TopicID: tpc.ID, UserID: managerID, Rights: []topic.Right{topic.RightManage}},
+			{TopicID: tpc.ID, UserID: publisherID, Rights: []topic.Right{topic.RightPublish}},
+		}, nil)
+
+	core := topic.NewCore(nil, storer, gen)
+
+	allowed, err := core.Subscribers(ctx, tpc)
+	require.NoError(t, err)
+
+	require.True(t, allowed(ownerID))
+	require.True(t, allowed(subscriberID))
+	require.True(t, allowed(managerID))
+	require.False(t, allowed(publisherID))
+	require.False(t, allowed(strangerID))
+
+	// Everyone may subscribe to public topics, which takes no query.
+	allowed, err = core.Subscribers(ctx, publicTopic(tpc))
+	require.NoError(t, err)
+	require.True(t, allowed(strangerID))
+}
+
+func TestManageImpliesAll(t *testing.T) {
+	a := topic.Access{Rights: []topic.Right{topic.RightManage}}
+
+	require.True(t, a.Has(topic.RightPublish))
+	require.True(t, a.Has(topic.RightSubscribe))
+}
+
+func TestGrant(t *testing.T) {
+	ctx := context.Background()
+
+	tpc := randomTopic()
+	tpc.OwnerID = uuid.New()
+	tpc.Visibility = topic.VisibilityPrivate
+
+	userID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocktpc.NewMockStorer(ctrl)
+	storer.EXPECT().
+		WithinTran(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, fn func(topic.Storer) error) error {
+			return fn(storer)
+		})
+	storer.EXPECT().
+		UpsertAccess(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, a topic.Access) error {
+			require.Equal(t, []topic.Right{topic.RightPublish}, a.Rights)
+			return nil
+		})
+
+	// Losing the subscribe right on a private topic ends the subscription.
+	storer.EXPECT().
+		DeleteSubscription(gomock.Any(), tpc.ID, userID).
+		Times(1).
+		Return(nil)
+
+	core := topic.NewCore(nil, storer, gen)
+
+	_, err := core.Grant(ctx, tpc, userID, []topic.Right{topic.RightPublish, topic.RightPublish})
+	require.NoError(t, err)
+
+	_, err = core.Grant(ctx, tpc, tpc.OwnerID, []topic.Right{topic.RightPublish})
+	require.ErrorIs(t, err, topic.ErrOwnerAccess)
+}
+
+func TestAccept(t *testing.T) {
+	ctx := context.Background()
+
+	tpc := randomTopic()
+	userID := uuid.New()
+	email := "invitee@example.com"
+
+	var inv topic.Invitation
+	var token string
+
+	{
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocktpc.NewMockStorer(ctrl)
+		storer.EXPECT().
+			CreateInvitation(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(nil)
+
+		core := topic.NewCore(nil, storer, uuid.New)
+
+		var err error
+		inv, token, err = core.Invite(ctx, topic.NewInvitation{
+			TopicID: tpc.ID,
+			Email:   email,
+			Rights:  []topic.Right{topic.RightPublish},
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, token, inv.HashedToken)
+	}
+
+	testCases := []struct {
+		name       string
+		token      string
+		email      string
+		buildStubs func(storer *mocktpc.MockStorer)
+		check      func(a topic.Access, err error)
+	}{
+		{
+			name:  "OK",
+			token: token,
+			email: "Invitee@Example.com",
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				storer.EXPECT().QueryInvitationByID(gomock.Any(), inv.ID).Times(1).Return(inv, nil)
+				storer.EXPECT().
+					QueryAccess(gomock.Any(), tpc.ID, userID).
+					Times(1).
+					Return(topic.Access{Rights: []topic.Right{topic.RightSubscribe}}, nil)
+				storer.EXPECT().UpsertAccess(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+				storer.EXPECT().
+					UpdateInvitation(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, inv topic.Invitation) error {
+						require.False(t, inv.AcceptedAt.IsZero())
+						return nil
+					})
+			},
+			check: func(a topic.Access, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []topic.Right{topic.RightSubscribe, topic.RightPublish}, a.Rights)
+			},
+		},
+		{
+			name:  "WrongToken",
+			token: "token",
+			email: email,
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				storer.EXPECT().QueryInvitationByID(gomock.Any(), inv.ID).Times(1).Return(inv, nil)
+				storer.EXPECT().UpsertAccess(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(a topic.Access, err error) {
+				require.ErrorIs(t, err, topic.ErrInvitationFailure)
+			},
+		},
+		{
+			name:  "OtherEmail",
+			token: token,
+			email: "other@example.com",
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				storer.EXPECT().QueryInvitationByID(gomock.Any(), inv.ID).Times(1).Return(inv, nil)
+				storer.EXPECT().UpsertAccess(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(a topic.Access, err error) {
+				require.ErrorIs(t, err, topic.ErrInvitationMismatch)
+			},
+		},
+		{
+			name:  "Accepted",
+			token: token,
+			email: email,
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				accepted := inv
+				accepted.AcceptedAt = time.Now()
+
+				storer.EXPECT().QueryInvitationByID(gomock.Any(), inv.ID).Times(1).Return(accepted, nil)
+				storer.EXPECT().UpsertAccess(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(a topic.Access, err error) {
+				require.ErrorIs(t, err, topic.ErrInvitationAccepted)
+			},
+		},
+		{
+			name:  "Expired",
+			token: token,
+			email: email,
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				expired := inv
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+				storer.EXPECT().QueryInvitationByID(gomock.Any(), inv.ID).Times(1).Return(expired, nil)
+				storer.EXPECT().UpsertAccess(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(a topic.Access, err error) {
+				require.ErrorIs(t, err, topic.ErrInvitationExpired)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocktpc.NewMockStorer(ctrl)
+		storer.EXPECT().
+			WithinTran(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, fn func(topic.Storer) error) error {
+				return fn(storer)
+			})
+
+		core := topic.NewCore(nil, storer, gen)
+
+		tc.buildStubs(storer)
+
+		a, err := core.Accept(ctx, inv.ID, tc.token, userID, tc.email)
+
+		tc.check(a, err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	tpc := randomTopic()
+	name := util.RandomString(6)
+	description := util.RandomString(12)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocktpc.NewMockStorer(ctrl)
+	storer.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(topic.ErrUniqueName)
+	storer.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, updated topic.Topic) error {
+			require.Equal(t, name, updated.Name)
+			require.Equal(t, description, updated.Description)
+			require.False(t, updated.UpdatedAt.IsZero())
+			return nil
+		})
+
+	core := topic.NewCore(nil, storer, gen)
+
+	_, err := core.Update(ctx, tpc, topic.UpdateTopic{Name: &name})
+	require.ErrorIs(t, err, topic.ErrUniqueName)
+
+	updated, err := core.Update(ctx, tpc, topic.UpdateTopic{Name: &name, Description: &description})
+	require.NoError(t, err)
+	require.Equal(t, tpc.ID, updated.ID)
+}
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+
+	tpc := randomTopic()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	storer := mocktpc.NewMockStorer(ctrl)
+	storer.EXPECT().
+		Update(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(nil)
+
+	core := topic.NewCore(nil, storer, gen)
+
+	archived, err := core.Archive(ctx, tpc)
+	require.NoError(t, err)
+	require.True(t, archived.Archived())
+
+	// Archiving again doesn't touch the store.
+	again, err := core.Archive(ctx, archived)
+	require.NoError(t, err)
+	require.Equal(t, archived.ArchivedAt, again.ArchivedAt)
+
+	unarchived, err := core.Unarchive(ctx, archived)
+	require.NoError(t, err)
+	require.False(t, unarchived.Archived())
+}
+
+func TestDeleteTx(t *testing.T) {
+	ctx := context.Background()
+
+	tpc := randomTopic()
+	userID := uuid.New()
+
+	var d topic.Deletion
+	var token string
+
+	{
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocktpc.NewMockStorer(ctrl)
+		storer.EXPECT().
+			UpsertDeletion(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(nil)
+
+		core := topic.NewCore(nil, storer, gen)
+
+		var err error
+		d, token, err = core.RequestDeletion(ctx, tpc, userID)
+		require.NoError(t, err)
+		require.Equal(t, tpc.ID, d.TopicID)
+		require.NotEqual(t, token, d.HashedToken)
+	}
+
+	testCases := []struct {
+		name       string
+		token      string
+		buildStubs func(storer *mocktpc.MockStorer)
+		check      func(err error, deleted bool)
+	}{
+		{
+			name:  "OK",
+			token: token,
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				storer.EXPECT().QueryDeletion(gomock.Any(), tpc.ID).Times(1).Return(d, nil)
+				storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(1).
+					DoAndReturn(func(_ context.Context, got topic.Topic) error {
+						require.Equal(t, tpc.ID, got.ID)
+						require.False(t, got.DeletedAt.IsZero())
+						return nil
+					})
+				storer.EXPECT().DeleteDeletion(gomock.Any(), tpc.ID).Times(1).Return(nil)
+			},
+			check: func(err error, deleted bool) {
+				require.NoError(t, err)
+				require.True(t, deleted)
+			},
+		},
+		{
+			name:  "NotRequested",
+			token: token,
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				storer.EXPECT().QueryDeletion(gomock.Any(), tpc.ID).Times(1).Return(topic.Deletion{}, topic.ErrDeletionNotFound)
+				storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error, deleted bool) {
+				require.ErrorIs(t, err, topic.ErrDeletionNotFound)
+				require.False(t, deleted)
+			},
+		},
+		{
+			name:  "WrongToken",
+			token: "token",
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				storer.EXPECT().QueryDeletion(gomock.Any(), tpc.ID).Times(1).Return(d, nil)
+				storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error, deleted bool) {
+				require.ErrorIs(t, err, topic.ErrDeletionFailure)
+				require.False(t, deleted)
+			},
+		},
+		{
+			name:  "Expired",
+			token: token,
+			buildStubs: func(storer *mocktpc.MockStorer) {
+				expired := d
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+
+				storer.EXPECT().QueryDeletion(gomock.Any(), tpc.ID).Times(1).Return(expired, nil)
+				storer.EXPECT().Delete(gomock.Any(), gomock.Any()).Times(0)
+			},
+			check: func(err error, deleted bool) {
+				require.ErrorIs(t, err, topic.ErrDeletionExpired)
+				require.False(t, deleted)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		storer := mocktpc.NewMockStorer(ctrl)
+		storer.EXPECT().
+			WithinTran(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, fn func(topic.Storer) error) error {
+				return fn(storer)
+			})
+
+		core := topic.NewCore(nil, storer, gen)
+
+		tc.buildStubs(storer)
+
+		var deleted bool
+		err := core.DeleteTx(ctx, tpc, topic.DeleteTopicTx{
+			Token: tc.token,
+			AfterDelete: func(topic.Topic) error {
+				deleted = true
+				return nil
+			},
+		})
+
+		tc.check(err, deleted)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"alerts", "infra.db.primary", "infra/db/primary", "Build Status"} {
+		require.NoError(t, topic.ValidateName(name), name)
+	}
+
+	for _, name := range []string{"", "infra.", "/infra", "infra..db", "infra.*", "infra.#", "in*fra"} {
+		require.ErrorIs(t, topic.ValidateName(name), topic.ErrInvalidName, name)
+	}
+}
+
+func TestValidateRetention(t *testing.T) {
+	for _, retention := range []time.Duration{0, time.Hour, 30 * 24 * time.Hour} {
+		require.NoError(t, topic.ValidateRetention(retention), retention)
+	}
+
+	for _, retention := range []time.Duration{time.Second, 59 * time.Minute, -time.Hour} {
+		require.ErrorIs(t, topic.ValidateRetention(retention), topic.ErrInvalidRetention, retention)
+	}
+}
+
+func publicTopic(tpc topic.Topic) topic.Topic {
+	tpc.Visibility = topic.VisibilityPublic
+	return tpc
+}
+
 func randomTopic() topic.Topic {
 	return topic.Topic{
-		ID:   gen(),
-		Name: util.RandomString(6),
+		ID:         gen(),
+		Name:       util.RandomString(6),
+		Visibility: topic.VisibilityPublic,
 	}
 }
 
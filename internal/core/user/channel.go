@@ -0,0 +1,57 @@
+package user
+
+import (
+	"github.com/dudakovict/gotify/internal/core/notification"
+)
+
+// ChannelDefault is a channel the user gets notifications on when a
+// subscription doesn't pick its own, optionally only from a minimum priority
+// up.
+type ChannelDefault struct {
+	notification.Route
+	MinPriority notification.Priority
+}
+
+// DefaultRoutes returns where the user gets a notification of the priority
+// when a subscription doesn't pick any channels. Users without defaults get
+// all notifications by email. Addresses that weren't confirmed are returned
+// as they are, for the deliveries to replace.
+func (u User) DefaultRoutes(priority notification.Priority) []notification.Route {
+	if len(u.Channels) == 0 {
+		return []notification.Route{{Channel: notification.ChannelEmail}}
+	}
+
+	var routes []notification.Route
+	for _, cd := range u.Channels {
+		if cd.MinPriority != (notification.Priority{}) && !priority.AtLeast(cd.MinPriority) {
+			continue
+		}
+		routes = append(routes, cd.Route)
+	}
+
+	return routes
+}
+
+// keepConfirmed returns the channels with the addresses that were confirmed
+// among the previous channels still confirmed, and every other one not.
+func keepConfirmed(channels []ChannelDefault, previous []ChannelDefault) []ChannelDefault {
+	routes := make([]notification.Route, len(channels))
+	for i, cd := range channels {
+		routes[i] = cd.Route
+	}
+
+	prev := make([]notification.Route, len(previous))
+	for i, cd := range previous {
+		prev[i] = cd.Route
+	}
+
+	routes = notification.KeepConfirmed(routes, prev)
+
+	kept := make([]ChannelDefault, len(channels))
+	for i, cd := range channels {
+		cd.Route = routes[i]
+		kept[i] = cd
+	}
+
+	return kept
+}
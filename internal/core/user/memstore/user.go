@@ -0,0 +1,294 @@
+// Package usermem contains user related CRUD functionality, kept in memory.
+package usermem
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/group"
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	table              = "users"
+	tableSubscriptions = "subscriptions"
+	tableGroupMembers  = "group_members"
+)
+
+// orderByFields maps the fields users can be ordered by to their comparisons.
+var orderByFields = map[string]memdb.Compare[user.User]{
+	user.OrderByID:        compareID,
+	user.OrderByEmail:     func(a, b user.User) int { return strings.Compare(a.Email, b.Email) },
+	user.OrderByCreatedAt: func(a, b user.User) int { return a.CreatedAt.Compare(b.CreatedAt) },
+}
+
+// Store manages the set of APIs for user access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Unique("users_email_key", memdb.Unique{
+		Table: table,
+		Key: func(row any) (string, bool) {
+			usr := row.(user.User)
+			return usr.Email, usr.DeletedAt.IsZero()
+		},
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(user.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new user into the database.
+func (s *Store) Create(ctx context.Context, usr user.User) error {
+	if err := memdb.Insert(s.db, table, usr.ID.String(), clone(usr)); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return user.ErrUniqueEmail
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update replaces a user document in the database, as long as it's still at
+// the version of the user.
+func (s *Store) Update(ctx context.Context, usr user.User) error {
+	stored, exists := memdb.Get[user.User](s.db, table, usr.ID.String())
+	if !exists || stored.Version != usr.Version {
+		return user.ErrVersionConflict
+	}
+
+	usr = clone(usr)
+	usr.CreatedAt = stored.CreatedAt
+	usr.Version++
+
+	if err := memdb.Put(s.db, table, usr.ID.String(), usr); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return user.ErrUniqueEmail
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Delete marks a user as deleted in the database, as long as it's
+// still at the version of the user.
+func (s *Store) Delete(ctx context.Context, usr user.User) error {
+	stored, exists := memdb.Get[user.User](s.db, table, usr.ID.String())
+	if !exists || !stored.DeletedAt.IsZero() || stored.Version != usr.Version {
+		return user.ErrVersionConflict
+	}
+
+	stored.DeletedAt = usr.DeletedAt
+
+	return memdb.Put(s.db, table, usr.ID.String(), stored)
+}
+
+// Restore clears the deletion of a user in the database.
+func (s *Store) Restore(ctx context.Context, usr user.User) error {
+	stored, exists := memdb.Get[user.User](s.db, table, usr.ID.String())
+	if !exists {
+		return nil
+	}
+
+	stored.DeletedAt = time.Time{}
+	stored.UpdatedAt = usr.UpdatedAt
+
+	if err := memdb.Put(s.db, table, usr.ID.String(), stored); err != nil {
+		if errors.Is(err, memdb.ErrUniqueViolation) {
+			return user.ErrUniqueEmail
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Purge removes up to limit users deleted before the given time from the
+// database.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	usrs := memdb.Rows(s.db, table, func(usr user.User) bool {
+		return !usr.DeletedAt.IsZero() && usr.DeletedAt.Before(before)
+	})
+
+	if len(usrs) > limit {
+		usrs = usrs[:limit]
+	}
+
+	for _, usr := range usrs {
+		memdb.Delete(s.db, table, usr.ID.String())
+	}
+
+	return len(usrs), nil
+}
+
+// Query retrieves a list of existing users from the database.
+func (s *Store) Query(ctx context.Context, filter user.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	usrs := memdb.Rows(s.db, table, matches(filter))
+
+	if err := memdb.Sort(usrs, orderBy, orderByFields, compareID); err != nil {
+		return nil, err
+	}
+
+	return cloneSlice(memdb.Page(usrs, pageNumber, rowsPerPage)), nil
+}
+
+// Count returns the number of users in the database matching the filter.
+func (s *Store) Count(ctx context.Context, filter user.QueryFilter) (int, error) {
+	return len(memdb.Rows(s.db, table, matches(filter))), nil
+}
+
+// QueryByID gets the specified user from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (user.User, error) {
+	usr, exists := memdb.Get[user.User](s.db, table, id.String())
+	if !exists || !usr.DeletedAt.IsZero() {
+		return user.User{}, user.ErrNotFound
+	}
+
+	return clone(usr), nil
+}
+
+// QueryDeletedByID gets the specified deleted user from the database by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (user.User, error) {
+	usr, exists := memdb.Get[user.User](s.db, table, id.String())
+	if !exists || usr.DeletedAt.IsZero() {
+		return user.User{}, user.ErrNotFound
+	}
+
+	return clone(usr), nil
+}
+
+// QueryByEmail gets the specified user from the database by email.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	usrs := memdb.Rows(s.db, table, func(usr user.User) bool {
+		return usr.Email == email && usr.DeletedAt.IsZero()
+	})
+
+	if len(usrs) == 0 {
+		return user.User{}, user.ErrNotFound
+	}
+
+	return clone(usrs[0]), nil
+}
+
+// QueryByTopicID gets the users subscribed to the topic from the database,
+// either themselves or through a group.
+func (s *Store) QueryByTopicID(ctx context.Context, topicID uuid.UUID) ([]user.User, error) {
+	ids := make(map[uuid.UUID]bool)
+	groupIDs := make(map[uuid.UUID]bool)
+
+	for _, sub := range memdb.Rows[subscription.Subscription](s.db, tableSubscriptions, nil) {
+		if sub.TopicID != topicID || !sub.DeletedAt.IsZero() {
+			continue
+		}
+
+		switch {
+		case sub.IsGroup():
+			groupIDs[sub.GroupID] = true
+		case sub.UserID != uuid.Nil:
+			ids[sub.UserID] = true
+		}
+	}
+
+	for _, mbr := range memdb.Rows[group.Member](s.db, tableGroupMembers, nil) {
+		if groupIDs[mbr.GroupID] {
+			ids[mbr.UserID] = true
+		}
+	}
+
+	usrs := memdb.Rows(s.db, table, func(usr user.User) bool {
+		return ids[usr.ID] && usr.DeletedAt.IsZero()
+	})
+
+	return cloneSlice(usrs), nil
+}
+
+// QueryByIDs gets the specified users from the database.
+func (s *Store) QueryByIDs(ctx context.Context, ids []uuid.UUID) ([]user.User, error) {
+	usrs := memdb.Rows(s.db, table, func(usr user.User) bool {
+		return slices.Contains(ids, usr.ID) && usr.DeletedAt.IsZero()
+	})
+
+	return cloneSlice(usrs), nil
+}
+
+// =============================================================================
+
+// matches returns whether a user matches the filter. The email matches any
+// part of the address, regardless of case, and deleted users only match when
+// the filter asks for them.
+func matches(filter user.QueryFilter) func(usr user.User) bool {
+	return func(usr user.User) bool {
+		if usr.DeletedAt.IsZero() == filter.Deleted {
+			return false
+		}
+
+		if filter.Email != nil && !strings.Contains(strings.ToLower(usr.Email), strings.ToLower(*filter.Email)) {
+			return false
+		}
+
+		if filter.Role != nil && !slices.ContainsFunc(usr.Roles, filter.Role.Equal) {
+			return false
+		}
+
+		if filter.Verified != nil && usr.Verified != *filter.Verified {
+			return false
+		}
+
+		return true
+	}
+}
+
+func compareID(a, b user.User) int {
+	return memdb.CompareIDs(a.ID, b.ID)
+}
+
+// clone copies the user, so the stored user shares no memory with the
+// callers.
+func clone(usr user.User) user.User {
+	usr.Roles = slices.Clone(usr.Roles)
+	usr.HashedPassword = slices.Clone(usr.HashedPassword)
+	usr.Channels = slices.Clone(usr.Channels)
+	return usr
+}
+
+func cloneSlice(usrs []user.User) []user.User {
+	for i := range usrs {
+		usrs[i] = clone(usrs[i])
+	}
+	return usrs
+}
@@ -5,9 +5,12 @@
 package mockusr
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	user "github.com/dudakovict/gotify/internal/core/user"
+	order "github.com/dudakovict/gotify/platform/order"
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
 )
@@ -35,118 +38,192 @@ func (m *MockStorer) EXPECT() *MockStorerMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *MockStorer) Count(arg0 context.Context, arg1 user.QueryFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockStorerMockRecorder) Count(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockStorer)(nil).Count), arg0, arg1)
+}
+
 // Create mocks base method.
-func (m *MockStorer) Create(arg0 user.User) error {
+func (m *MockStorer) Create(arg0 context.Context, arg1 user.User) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockStorerMockRecorder) Create(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Create(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockStorer)(nil).Create), arg0, arg1)
 }
 
 // Delete mocks base method.
-func (m *MockStorer) Delete(arg0 user.User) error {
+func (m *MockStorer) Delete(arg0 context.Context, arg1 user.User) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockStorerMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorer)(nil).Delete), arg0, arg1)
+}
+
+// Purge mocks base method.
+func (m *MockStorer) Purge(arg0 context.Context, arg1 time.Time, arg2 int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purge indicates an expected call of Purge.
+func (mr *MockStorerMockRecorder) Purge(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockStorer)(nil).Purge), arg0, arg1, arg2)
 }
 
 // Query mocks base method.
-func (m *MockStorer) Query(arg0, arg1 int) ([]user.User, error) {
+func (m *MockStorer) Query(arg0 context.Context, arg1 user.QueryFilter, arg2 order.By, arg3, arg4 int) ([]user.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Query", arg0, arg1)
+	ret := m.ctrl.Call(m, "Query", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].([]user.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Query indicates an expected call of Query.
-func (mr *MockStorerMockRecorder) Query(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Query(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStorer)(nil).Query), arg0, arg1, arg2, arg3, arg4)
 }
 
 // QueryByEmail mocks base method.
-func (m *MockStorer) QueryByEmail(arg0 string) (user.User, error) {
+func (m *MockStorer) QueryByEmail(arg0 context.Context, arg1 string) (user.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryByEmail", arg0)
+	ret := m.ctrl.Call(m, "QueryByEmail", arg0, arg1)
 	ret0, _ := ret[0].(user.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryByEmail indicates an expected call of QueryByEmail.
-func (mr *MockStorerMockRecorder) QueryByEmail(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) QueryByEmail(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByEmail", reflect.TypeOf((*MockStorer)(nil).QueryByEmail), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByEmail", reflect.TypeOf((*MockStorer)(nil).QueryByEmail), arg0, arg1)
 }
 
 // QueryByID mocks base method.
-func (m *MockStorer) QueryByID(arg0 uuid.UUID) (user.User, error) {
+func (m *MockStorer) QueryByID(arg0 context.Context, arg1 uuid.UUID) (user.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryByID", arg0)
+	ret := m.ctrl.Call(m, "QueryByID", arg0, arg1)
 	ret0, _ := ret[0].(user.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryByID indicates an expected call of QueryByID.
-func (mr *MockStorerMockRecorder) QueryByID(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) QueryByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0, arg1)
+}
+
+// QueryByIDs mocks base method.
+func (m *MockStorer) QueryByIDs(arg0 context.Context, arg1 []uuid.UUID) ([]user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryByIDs", arg0, arg1)
+	ret0, _ := ret[0].([]user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryByIDs indicates an expected call of QueryByIDs.
+func (mr *MockStorerMockRecorder) QueryByIDs(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByID", reflect.TypeOf((*MockStorer)(nil).QueryByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByIDs", reflect.TypeOf((*MockStorer)(nil).QueryByIDs), arg0, arg1)
 }
 
 // QueryByTopicID mocks base method.
-func (m *MockStorer) QueryByTopicID(arg0 uuid.UUID) ([]user.User, error) {
+func (m *MockStorer) QueryByTopicID(arg0 context.Context, arg1 uuid.UUID) ([]user.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "QueryByTopicID", arg0)
+	ret := m.ctrl.Call(m, "QueryByTopicID", arg0, arg1)
 	ret0, _ := ret[0].([]user.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // QueryByTopicID indicates an expected call of QueryByTopicID.
-func (mr *MockStorerMockRecorder) QueryByTopicID(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) QueryByTopicID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByTopicID", reflect.TypeOf((*MockStorer)(nil).QueryByTopicID), arg0, arg1)
+}
+
+// QueryDeletedByID mocks base method.
+func (m *MockStorer) QueryDeletedByID(arg0 context.Context, arg1 uuid.UUID) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueryDeletedByID", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryDeletedByID indicates an expected call of QueryDeletedByID.
+func (mr *MockStorerMockRecorder) QueryDeletedByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryDeletedByID", reflect.TypeOf((*MockStorer)(nil).QueryDeletedByID), arg0, arg1)
+}
+
+// Restore mocks base method.
+func (m *MockStorer) Restore(arg0 context.Context, arg1 user.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorerMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryByTopicID", reflect.TypeOf((*MockStorer)(nil).QueryByTopicID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorer)(nil).Restore), arg0, arg1)
 }
 
 // Update mocks base method.
-func (m *MockStorer) Update(arg0 user.User) error {
+func (m *MockStorer) Update(arg0 context.Context, arg1 user.User) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", arg0)
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockStorerMockRecorder) Update(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorer)(nil).Update), arg0, arg1)
 }
 
 // WithinTran mocks base method.
-func (m *MockStorer) WithinTran(arg0 func(user.Storer) error) error {
+func (m *MockStorer) WithinTran(arg0 context.Context, arg1 func(user.Storer) error) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WithinTran", arg0)
+	ret := m.ctrl.Call(m, "WithinTran", arg0, arg1)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // WithinTran indicates an expected call of WithinTran.
-func (mr *MockStorerMockRecorder) WithinTran(arg0 interface{}) *gomock.Call {
+func (mr *MockStorerMockRecorder) WithinTran(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithinTran", reflect.TypeOf((*MockStorer)(nil).WithinTran), arg0, arg1)
 }
@@ -13,8 +13,11 @@ type User struct {
 	Roles          []Role
 	Verified       bool
 	HashedPassword []byte
+	Channels       []ChannelDefault
+	Version        int
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	DeletedAt      time.Time
 }
 
 // NewUser contains information needed to create a new user.
@@ -30,6 +33,7 @@ type UpdateUser struct {
 	Roles    []Role
 	Verified *bool
 	Password *string
+	Channels []ChannelDefault
 }
 
 // NewUserTx contains information needed to create a new user
@@ -0,0 +1,26 @@
+package user
+
+import "github.com/dudakovict/gotify/platform/order"
+
+// Set of fields the users can be ordered by.
+const (
+	OrderByID        = "id"
+	OrderByEmail     = "email"
+	OrderByCreatedAt = "created_at"
+)
+
+// OrderByFields lists the fields the users can be ordered by.
+var OrderByFields = []string{OrderByID, OrderByEmail, OrderByCreatedAt}
+
+// DefaultOrderBy is the order users are listed in unless asked otherwise.
+var DefaultOrderBy = order.NewBy(OrderByCreatedAt, order.ASC)
+
+// QueryFilter holds the fields users can be filtered by. Fields left nil
+// match every user, and the email matches any part of the address. Deleted
+// users are only listed when Deleted is set, and then only them.
+type QueryFilter struct {
+	Email    *string
+	Role     *Role
+	Verified *bool
+	Deleted  bool
+}
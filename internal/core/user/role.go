@@ -1,36 +1,36 @@
 package user
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+)
 
-// Set of possible roles for a user.
+// Set of built-in roles. Further roles are defined in the database.
 var (
 	RoleAdmin = Role{"ADMIN"}
 	RoleUser  = Role{"USER"}
 )
 
-// Set of known roles.
-var roles = map[string]Role{
-	RoleAdmin.name: RoleAdmin,
-	RoleUser.name:  RoleUser,
-}
+// rolePattern is the format role names have to follow.
+var rolePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,63}$`)
 
 // Role represents a role in the system.
 type Role struct {
 	name string
 }
 
-// ParseRole parses the string value and returns a role if one exists.
+// ParseRole parses the string value and returns a role if it is a valid role
+// name. Whether the role is defined is up to the role package.
 func ParseRole(value string) (Role, error) {
-	role, exists := roles[value]
-	if !exists {
-		return Role{}, fmt.Errorf("invalid role %q", value)
+	if !rolePattern.MatchString(value) {
+		return Role{}, fmt.Errorf("%w %q", ErrInvalidRole, value)
 	}
 
-	return role, nil
+	return Role{value}, nil
 }
 
-// MustParseRole parses the string value and returns a role if one exists. If
-// an error occurs the function panics.
+// MustParseRole parses the string value and returns a role if it is a valid
+// role name. If an error occurs the function panics.
 func MustParseRole(value string) Role {
 	role, err := ParseRole(value)
 	if err != nil {
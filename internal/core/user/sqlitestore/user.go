@@ -0,0 +1,351 @@
+// Package userlite contains user related CRUD functionality, stored in SQLite.
+package userlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for user access in SQLite.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(user.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts a new user into the database.
+func (s *Store) Create(ctx context.Context, usr user.User) error {
+	const q = `
+	INSERT INTO users
+		(id, email, roles, verified, hashed_password, channels, version, created_at, updated_at)
+	VALUES
+		(:id, :email, :roles, :verified, :hashed_password, :channels, :version, :created_at, :updated_at)`
+
+	dbUsr, err := toDBUser(usr)
+	if err != nil {
+		return err
+	}
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbUsr); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return user.ErrUniqueEmail
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Update replaces a user document in the database, as long as it's still at
+// the version of the user.
+func (s *Store) Update(ctx context.Context, usr user.User) error {
+	const q = `
+	UPDATE
+		users
+	SET
+		email = :email,
+		roles = :roles,
+		verified = :verified,
+		hashed_password = :hashed_password,
+		channels = :channels,
+		updated_at = :updated_at,
+		version = version + 1
+	WHERE
+		id = :id
+		AND version = :version`
+
+	dbUsr, err := toDBUser(usr)
+	if err != nil {
+		return err
+	}
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, dbUsr)
+	if err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return user.ErrUniqueEmail
+		}
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return user.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Delete marks a user as deleted in the database, as long as it's
+// still at the version of the user.
+func (s *Store) Delete(ctx context.Context, usr user.User) error {
+	data := struct {
+		ID        uuid.UUID `db:"id"`
+		DeletedAt time.Time `db:"deleted_at"`
+		Version   int       `db:"version"`
+	}{
+		ID:        usr.ID,
+		DeletedAt: usr.DeletedAt.UTC(),
+		Version:   usr.Version,
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		deleted_at = :deleted_at
+	WHERE
+		id = :id AND deleted_at IS NULL
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return user.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Restore clears the deletion of a user in the database.
+func (s *Store) Restore(ctx context.Context, usr user.User) error {
+	data := struct {
+		ID        uuid.UUID `db:"id"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}{
+		ID:        usr.ID,
+		UpdatedAt: usr.UpdatedAt.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		deleted_at = NULL,
+		updated_at = :updated_at
+	WHERE
+		id = :id`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return user.ErrUniqueEmail
+		}
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+// Purge removes up to limit users deleted before the given time from the
+// database.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+		Limit  int       `db:"limit"`
+	}{
+		Before: before.UTC(),
+		Limit:  limit,
+	}
+
+	const q = `
+	DELETE FROM
+		users
+	WHERE
+		id IN (SELECT id FROM users WHERE deleted_at < :before LIMIT :limit)`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	return n, nil
+}
+
+// Query retrieves a list of existing users from the database.
+func (s *Store) Query(ctx context.Context, filter user.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM users`)
+	applyFilter(b, filter)
+
+	if err := b.OrderBy(orderBy, orderByFields, "id"); err != nil {
+		return nil, err
+	}
+	b.Page(pageNumber, rowsPerPage)
+
+	q, data := b.Query()
+
+	var dbUsrs []dbUser
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreUserSlice(dbUsrs)
+}
+
+// Count returns the number of users in the database matching the filter.
+func (s *Store) Count(ctx context.Context, filter user.QueryFilter) (int, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM users`)
+	applyFilter(b, filter)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
+// QueryByID gets the specified user from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (user.User, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM users WHERE id = :id AND deleted_at IS NULL`
+
+	var dbUsr dbUser
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return user.User{}, user.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreUser(dbUsr)
+}
+
+// QueryDeletedByID gets the specified deleted user from the database by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (user.User, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM users WHERE id = :id AND deleted_at IS NOT NULL`
+
+	var dbUsr dbUser
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return user.User{}, user.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreUser(dbUsr)
+}
+
+// QueryByEmail gets the specified user from the database by email.
+func (s *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
+	data := struct {
+		Email string `db:"email"`
+	}{
+		Email: email,
+	}
+
+	const q = `SELECT * FROM users WHERE email = :email AND deleted_at IS NULL`
+
+	var dbUsr dbUser
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return user.User{}, user.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreUser(dbUsr)
+}
+
+// QueryByTopicID gets the users subscribed to the topic from the database,
+// either themselves or through a group.
+func (s *Store) QueryByTopicID(ctx context.Context, topicID uuid.UUID) ([]user.User, error) {
+	data := struct {
+		TopicID uuid.UUID `db:"topic_id"`
+	}{
+		TopicID: topicID,
+	}
+
+	const q = `
+	SELECT u.id, u.email, u.roles, u.verified, u.hashed_password, u.channels, u.created_at, u.updated_at, u.deleted_at FROM
+		users u
+	WHERE
+		u.deleted_at IS NULL AND
+		u.id IN (
+			SELECT s.user_id FROM subscriptions s WHERE s.topic_id = :topic_id AND s.deleted_at IS NULL
+			UNION
+			SELECT m.user_id FROM group_members m JOIN subscriptions s ON s.group_id = m.group_id WHERE s.topic_id = :topic_id AND s.deleted_at IS NULL
+		)`
+
+	var dbUsrs []dbUser
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreUserSlice(dbUsrs)
+}
+
+// QueryByIDs gets the specified users from the database.
+func (s *Store) QueryByIDs(ctx context.Context, ids []uuid.UUID) ([]user.User, error) {
+	strIDs := make(database.JSONArray, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+
+	data := struct {
+		IDs database.JSONArray `db:"ids"`
+	}{
+		IDs: strIDs,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		users
+	WHERE
+		id IN (SELECT value FROM json_each(:ids)) AND deleted_at IS NULL`
+
+	var dbUsrs []dbUser
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreUserSlice(dbUsrs)
+}
@@ -1,9 +1,12 @@
 package userdb
 
 import (
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
 	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/google/uuid"
 
@@ -16,25 +19,55 @@ type dbUser struct {
 	Roles          pq.StringArray `db:"roles"`
 	HashedPassword []byte         `db:"hashed_password"`
 	Verified       bool           `db:"verified"`
+	Channels       string         `db:"channels"`
+	Version        int            `db:"version"`
 	CreatedAt      time.Time      `db:"created_at"`
 	UpdatedAt      time.Time      `db:"updated_at"`
+	DeletedAt      sql.NullTime   `db:"deleted_at"`
 }
 
-func toDBUser(usr user.User) dbUser {
+type dbChannel struct {
+	Channel     string `json:"channel"`
+	Address     string `json:"address,omitempty"`
+	Confirmed   bool   `json:"confirmed,omitempty"`
+	MinPriority string `json:"min_priority,omitempty"`
+}
+
+func toDBUser(usr user.User) (dbUser, error) {
 	roles := make([]string, len(usr.Roles))
 	for i, role := range usr.Roles {
 		roles[i] = role.Name()
 	}
 
-	return dbUser{
+	chs := make([]dbChannel, len(usr.Channels))
+	for i, cd := range usr.Channels {
+		chs[i] = dbChannel{
+			Channel:     cd.Channel.Name(),
+			Address:     cd.Address,
+			Confirmed:   cd.Confirmed,
+			MinPriority: cd.MinPriority.Name(),
+		}
+	}
+
+	channels, err := json.Marshal(chs)
+	if err != nil {
+		return dbUser{}, fmt.Errorf("marshal channels: %w", err)
+	}
+
+	dbUsr := dbUser{
 		ID:             usr.ID,
 		Email:          usr.Email,
 		Roles:          roles,
 		HashedPassword: usr.HashedPassword,
 		Verified:       usr.Verified,
+		Channels:       string(channels),
+		Version:        usr.Version,
 		CreatedAt:      usr.CreatedAt.UTC(),
 		UpdatedAt:      usr.UpdatedAt.UTC(),
+		DeletedAt:      toNullTime(usr.DeletedAt),
 	}
+
+	return dbUsr, nil
 }
 
 func toCoreUser(dbUsr dbUser) (user.User, error) {
@@ -47,16 +80,45 @@ func toCoreUser(dbUsr dbUser) (user.User, error) {
 		}
 	}
 
+	var chs []dbChannel
+	if err := json.Unmarshal([]byte(dbUsr.Channels), &chs); err != nil {
+		return user.User{}, fmt.Errorf("unmarshal channels: %w", err)
+	}
+
+	channels := make([]user.ChannelDefault, len(chs))
+	for i, ch := range chs {
+		channel, err := notification.ParseChannel(ch.Channel)
+		if err != nil {
+			return user.User{}, fmt.Errorf("parse channel: %w", err)
+		}
+		channels[i].Channel = channel
+		channels[i].Address = ch.Address
+		channels[i].Confirmed = ch.Confirmed
+
+		if ch.MinPriority != "" {
+			channels[i].MinPriority, err = notification.ParsePriority(ch.MinPriority)
+			if err != nil {
+				return user.User{}, fmt.Errorf("parse priority: %w", err)
+			}
+		}
+	}
+
 	usr := user.User{
 		ID:             dbUsr.ID,
 		Email:          dbUsr.Email,
 		Roles:          roles,
 		HashedPassword: dbUsr.HashedPassword,
 		Verified:       dbUsr.Verified,
+		Channels:       channels,
+		Version:        dbUsr.Version,
 		CreatedAt:      dbUsr.CreatedAt.In(time.Local),
 		UpdatedAt:      dbUsr.UpdatedAt.In(time.Local),
 	}
 
+	if dbUsr.DeletedAt.Valid {
+		usr.DeletedAt = dbUsr.DeletedAt.Time.In(time.Local)
+	}
+
 	return usr, nil
 }
 
@@ -73,3 +135,10 @@ func toCoreUserSlice(dbUsrs []dbUser) ([]user.User, error) {
 
 	return usrs, nil
 }
+
+func toNullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{
+		Time:  t.UTC(),
+		Valid: !t.IsZero(),
+	}
+}
@@ -0,0 +1,34 @@
+package userdb
+
+import (
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/platform/database"
+)
+
+// orderByFields maps the fields users can be ordered by to their columns.
+var orderByFields = map[string]string{
+	user.OrderByID:        "id",
+	user.OrderByEmail:     "email",
+	user.OrderByCreatedAt: "created_at",
+}
+
+// applyFilter adds the conditions of the filter to the query.
+func applyFilter(b *database.QueryBuilder, filter user.QueryFilter) {
+	if filter.Deleted {
+		b.Where("deleted_at IS NOT NULL", nil)
+	} else {
+		b.Where("deleted_at IS NULL", nil)
+	}
+
+	if filter.Email != nil {
+		b.Where("email ILIKE :email", map[string]any{"email": "%" + *filter.Email + "%"})
+	}
+
+	if filter.Role != nil {
+		b.Where(":role = ANY(roles)", map[string]any{"role": filter.Role.Name()})
+	}
+
+	if filter.Verified != nil {
+		b.Where("verified = :verified", map[string]any{"verified": *filter.Verified})
+	}
+}
@@ -2,20 +2,24 @@
 package userdb
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
 // Store manages the set of APIs for user database access.
 type Store struct {
 	log    *zerolog.Logger
-	db     sqlx.Ext
+	db     sqlx.ExtContext
 	inTran bool
 }
 
@@ -28,7 +32,7 @@ func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
 }
 
 // WithinTran runs passed function and do commit/rollback at the end.
-func (s *Store) WithinTran(fn func(user.Storer) error) error {
+func (s *Store) WithinTran(ctx context.Context, fn func(user.Storer) error) error {
 	if s.inTran {
 		return fn(s)
 	}
@@ -42,18 +46,23 @@ func (s *Store) WithinTran(fn func(user.Storer) error) error {
 		return fn(s)
 	}
 
-	return database.WithinTran(s.log, s.db.(*sqlx.DB), f)
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
 }
 
 // Create inserts a new user into the database.
-func (s *Store) Create(usr user.User) error {
+func (s *Store) Create(ctx context.Context, usr user.User) error {
 	const q = `
 	INSERT INTO users
-		(id, email, roles, verified, hashed_password, created_at, updated_at)
+		(id, email, roles, verified, hashed_password, channels, version, created_at, updated_at)
 	VALUES
-		(:id, :email, :roles, :verified, :hashed_password, :created_at, :updated_at)`
+		(:id, :email, :roles, :verified, :hashed_password, :channels, :version, :created_at, :updated_at)`
 
-	if err := database.NamedExec(s.log, s.db, q, toDBUser(usr)); err != nil {
+	dbUsr, err := toDBUser(usr)
+	if err != nil {
+		return err
+	}
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, dbUsr); err != nil {
 		if errors.Is(err, database.ErrUniqueViolation) {
 			return user.ErrUniqueEmail
 		}
@@ -63,8 +72,9 @@ func (s *Store) Create(usr user.User) error {
 	return nil
 }
 
-// Update replaces a user document in the database.
-func (s *Store) Update(usr user.User) error {
+// Update replaces a user document in the database, as long as it's still at
+// the version of the user.
+func (s *Store) Update(ctx context.Context, usr user.User) error {
 	const q = `
 	UPDATE
 		users
@@ -73,11 +83,87 @@ func (s *Store) Update(usr user.User) error {
 		roles = :roles,
 		verified = :verified,
 		hashed_password = :hashed_password,
+		channels = :channels,
+		updated_at = :updated_at,
+		version = version + 1
+	WHERE
+		id = :id
+		AND version = :version`
+
+	dbUsr, err := toDBUser(usr)
+	if err != nil {
+		return err
+	}
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, dbUsr)
+	if err != nil {
+		if errors.Is(err, database.ErrUniqueViolation) {
+			return user.ErrUniqueEmail
+		}
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return user.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Delete marks a user as deleted in the database, as long as it's
+// still at the version of the user.
+func (s *Store) Delete(ctx context.Context, usr user.User) error {
+	data := struct {
+		ID        uuid.UUID `db:"id"`
+		DeletedAt time.Time `db:"deleted_at"`
+		Version   int       `db:"version"`
+	}{
+		ID:        usr.ID,
+		DeletedAt: usr.DeletedAt.UTC(),
+		Version:   usr.Version,
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		deleted_at = :deleted_at
+	WHERE
+		id = :id AND deleted_at IS NULL
+		AND version = :version`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	if n == 0 {
+		return user.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// Restore clears the deletion of a user in the database.
+func (s *Store) Restore(ctx context.Context, usr user.User) error {
+	data := struct {
+		ID        uuid.UUID `db:"id"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}{
+		ID:        usr.ID,
+		UpdatedAt: usr.UpdatedAt.UTC(),
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		deleted_at = NULL,
 		updated_at = :updated_at
 	WHERE
 		id = :id`
 
-	if err := database.NamedExec(s.log, s.db, q, toDBUser(usr)); err != nil {
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
 		if errors.Is(err, database.ErrUniqueViolation) {
 			return user.ErrUniqueEmail
 		}
@@ -87,56 +173,97 @@ func (s *Store) Update(usr user.User) error {
 	return nil
 }
 
-// Delete removes a user from the database.
-func (s *Store) Delete(usr user.User) error {
+// Purge removes up to limit users deleted before the given time from the
+// database.
+func (s *Store) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
 	data := struct {
-		ID uuid.UUID `db:"id"`
+		Before time.Time `db:"before"`
+		Limit  int       `db:"limit"`
 	}{
-		ID: usr.ID,
+		Before: before.UTC(),
+		Limit:  limit,
 	}
 
 	const q = `
-		DELETE FROM
-			users
-		WHERE
-			id = :id`
+	DELETE FROM
+		users
+	WHERE
+		id IN (SELECT id FROM users WHERE deleted_at < :before LIMIT :limit)`
 
-	if err := database.NamedExec(s.log, s.db, q, data); err != nil {
-		return fmt.Errorf("namedexec: %w", err)
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
 	}
 
-	return nil
+	return n, nil
 }
 
 // Query retrieves a list of existing users from the database.
-func (s *Store) Query(pageNumber int, rowsPerPage int) ([]user.User, error) {
-	data := map[string]interface{}{
-		"offset":        (pageNumber - 1) * rowsPerPage,
-		"rows_per_page": rowsPerPage,
+func (s *Store) Query(ctx context.Context, filter user.QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]user.User, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM users`)
+	applyFilter(b, filter)
+
+	if err := b.OrderBy(orderBy, orderByFields, "id"); err != nil {
+		return nil, err
 	}
+	b.Page(pageNumber, rowsPerPage)
 
-	const q = `SELECT * FROM users`
+	q, data := b.Query()
 
 	var dbUsrs []dbUser
-	if err := database.NamedQuerySlice(s.log, s.db, q, data, &dbUsrs); err != nil {
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
 		return nil, fmt.Errorf("namedqueryslice: %w", err)
 	}
 
 	return toCoreUserSlice(dbUsrs)
 }
 
+// Count returns the number of users in the database matching the filter.
+func (s *Store) Count(ctx context.Context, filter user.QueryFilter) (int, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM users`)
+	applyFilter(b, filter)
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
 // QueryByID gets the specified user from the database by id.
-func (s *Store) QueryByID(id uuid.UUID) (user.User, error) {
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (user.User, error) {
 	data := struct {
 		ID uuid.UUID `db:"id"`
 	}{
 		ID: id,
 	}
 
-	const q = `SELECT * FROM users WHERE id = :id`
+	const q = `SELECT * FROM users WHERE id = :id AND deleted_at IS NULL`
 
 	var dbUsr dbUser
-	if err := database.NamedQueryStruct(s.log, s.db, q, data, &dbUsr); err != nil {
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return user.User{}, user.ErrNotFound
+		}
+		return user.User{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreUser(dbUsr)
+}
+
+// QueryDeletedByID gets the specified deleted user from the database by id.
+func (s *Store) QueryDeletedByID(ctx context.Context, id uuid.UUID) (user.User, error) {
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	const q = `SELECT * FROM users WHERE id = :id AND deleted_at IS NOT NULL`
+
+	var dbUsr dbUser
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return user.User{}, user.ErrNotFound
 		}
@@ -147,17 +274,17 @@ func (s *Store) QueryByID(id uuid.UUID) (user.User, error) {
 }
 
 // QueryByEmail gets the specified user from the database by email.
-func (s *Store) QueryByEmail(email string) (user.User, error) {
+func (s *Store) QueryByEmail(ctx context.Context, email string) (user.User, error) {
 	data := struct {
 		Email string `db:"email"`
 	}{
 		Email: email,
 	}
 
-	const q = `SELECT * FROM users WHERE email = :email`
+	const q = `SELECT * FROM users WHERE email = :email AND deleted_at IS NULL`
 
 	var dbUsr dbUser
-	if err := database.NamedQueryStruct(s.log, s.db, q, data, &dbUsr); err != nil {
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbUsr); err != nil {
 		if errors.Is(err, database.ErrNotFound) {
 			return user.User{}, user.ErrNotFound
 		}
@@ -167,7 +294,9 @@ func (s *Store) QueryByEmail(email string) (user.User, error) {
 	return toCoreUser(dbUsr)
 }
 
-func (s *Store) QueryByTopicID(topicID uuid.UUID) ([]user.User, error) {
+// QueryByTopicID gets the users subscribed to the topic from the database,
+// either themselves or through a group.
+func (s *Store) QueryByTopicID(ctx context.Context, topicID uuid.UUID) ([]user.User, error) {
 	data := struct {
 		TopicID uuid.UUID `db:"topic_id"`
 	}{
@@ -175,17 +304,47 @@ func (s *Store) QueryByTopicID(topicID uuid.UUID) ([]user.User, error) {
 	}
 
 	const q = `
-	SELECT u.id, u.email, u.roles, u.verified, u.hashed_password, u.created_at, u.updated_at FROM
+	SELECT u.id, u.email, u.roles, u.verified, u.hashed_password, u.channels, u.created_at, u.updated_at, u.deleted_at FROM
 		users u
-	JOIN
-		subscriptions s
-	ON
-		u.id = s.user_id
 	WHERE
-		s.topic_id = :topic_id`
+		u.deleted_at IS NULL AND
+		u.id IN (
+			SELECT s.user_id FROM subscriptions s WHERE s.topic_id = :topic_id AND s.deleted_at IS NULL
+			UNION
+			SELECT m.user_id FROM group_members m JOIN subscriptions s ON s.group_id = m.group_id WHERE s.topic_id = :topic_id AND s.deleted_at IS NULL
+		)`
+
+	var dbUsrs []dbUser
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
+		return nil, fmt.Errorf("namedqueryslice: %w", err)
+	}
+
+	return toCoreUserSlice(dbUsrs)
+}
+
+// QueryByIDs gets the specified users from the database.
+func (s *Store) QueryByIDs(ctx context.Context, ids []uuid.UUID) ([]user.User, error) {
+	strIDs := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+
+	data := struct {
+		IDs pq.StringArray `db:"ids"`
+	}{
+		IDs: strIDs,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		users
+	WHERE
+		id = ANY(:ids) AND deleted_at IS NULL`
 
 	var dbUsrs []dbUser
-	if err := database.NamedQuerySlice(s.log, s.db, q, data, &dbUsrs); err != nil {
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dbUsrs); err != nil {
 		return nil, fmt.Errorf("namedqueryslice: %w", err)
 	}
 
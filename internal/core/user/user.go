@@ -2,10 +2,14 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/platform/order"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
@@ -17,19 +21,27 @@ var (
 	ErrUniqueEmail           = errors.New("email is not unique")
 	ErrAuthenticationFailure = errors.New("authentication failed")
 	ErrAuthorizationFailure  = errors.New("authorization failed")
+	ErrInvalidRole           = errors.New("invalid role")
+	ErrVersionConflict       = errors.New("user was changed by someone else")
+	ErrChannelNotFound       = errors.New("user doesn't get notifications at the address")
 )
 
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
-	WithinTran(fn func(s Storer) error) error
-	Create(usr User) error
-	Update(usr User) error
-	Delete(usr User) error
-	Query(pageNumber int, rowsPerPage int) ([]User, error)
-	QueryByID(id uuid.UUID) (User, error)
-	QueryByEmail(email string) (User, error)
-	QueryByTopicID(topicID uuid.UUID) ([]User, error)
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, usr User) error
+	Update(ctx context.Context, usr User) error
+	Delete(ctx context.Context, usr User) error
+	Restore(ctx context.Context, usr User) error
+	Purge(ctx context.Context, before time.Time, limit int) (int, error)
+	Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error)
+	Count(ctx context.Context, filter QueryFilter) (int, error)
+	QueryByID(ctx context.Context, id uuid.UUID) (User, error)
+	QueryDeletedByID(ctx context.Context, id uuid.UUID) (User, error)
+	QueryByEmail(ctx context.Context, email string) (User, error)
+	QueryByTopicID(ctx context.Context, topicID uuid.UUID) ([]User, error)
+	QueryByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error)
 }
 
 // Core manages the set of APIs for user access.
@@ -46,7 +58,7 @@ func NewCore(log *zerolog.Logger, storer Storer) *Core {
 	}
 }
 
-func (c *Core) CreateTx(nuTx NewUserTx) (User, error) {
+func (c *Core) CreateTx(ctx context.Context, nuTx NewUserTx) (User, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(nuTx.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return User{}, fmt.Errorf("generatefrompassword: %w", err)
@@ -57,17 +69,18 @@ func (c *Core) CreateTx(nuTx NewUserTx) (User, error) {
 		Email:          nuTx.Email,
 		Roles:          nuTx.Roles,
 		HashedPassword: hashedPassword,
+		Version:        1,
 	}
 
 	tran := func(s Storer) error {
-		if err := s.Create(usr); err != nil {
+		if err := s.Create(ctx, usr); err != nil {
 			return fmt.Errorf("create: %w", err)
 		}
 
 		return nuTx.AfterCreate(usr)
 	}
 
-	if err := c.storer.WithinTran(tran); err != nil {
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
 		return User{}, fmt.Errorf("tran: %w", err)
 	}
 
@@ -75,7 +88,7 @@ func (c *Core) CreateTx(nuTx NewUserTx) (User, error) {
 }
 
 // Create adds a new user to the system.
-func (c *Core) Create(nu NewUser) (User, error) {
+func (c *Core) Create(ctx context.Context, nu NewUser) (User, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return User{}, fmt.Errorf("generatefrompassword: %w", err)
@@ -86,9 +99,10 @@ func (c *Core) Create(nu NewUser) (User, error) {
 		Email:          nu.Email,
 		Roles:          nu.Roles,
 		HashedPassword: hashedPassword,
+		Version:        1,
 	}
 
-	if err := c.storer.Create(usr); err != nil {
+	if err := c.storer.Create(ctx, usr); err != nil {
 		return User{}, fmt.Errorf("create: %w", err)
 	}
 
@@ -96,7 +110,7 @@ func (c *Core) Create(nu NewUser) (User, error) {
 }
 
 // Update modifies information about a user.
-func (c *Core) Update(usr User, uu UpdateUser) (User, error) {
+func (c *Core) Update(ctx context.Context, usr User, uu UpdateUser) (User, error) {
 	if uu.Email != nil {
 		usr.Email = *uu.Email
 	}
@@ -117,27 +131,99 @@ func (c *Core) Update(usr User, uu UpdateUser) (User, error) {
 		usr.Verified = *uu.Verified
 	}
 
+	if uu.Channels != nil {
+		for _, cd := range uu.Channels {
+			if err := cd.Validate(); err != nil {
+				return User{}, err
+			}
+		}
+		usr.Channels = keepConfirmed(uu.Channels, usr.Channels)
+	}
+
 	usr.UpdatedAt = time.Now()
 
-	if err := c.storer.Update(usr); err != nil {
+	if err := c.storer.Update(ctx, usr); err != nil {
 		return User{}, fmt.Errorf("update: %w", err)
 	}
+	usr.Version++
 
 	return usr, nil
 }
 
-// Delete removes the specified user.
-func (c *Core) Delete(usr User) error {
-	if err := c.storer.Delete(usr); err != nil {
+// ConfirmChannel starts delivering to the address of the default channels of
+// the user on the channel, once its owner confirmed it. Confirming it again
+// does nothing.
+func (c *Core) ConfirmChannel(ctx context.Context, usr User, channel notification.Channel, address string) (User, error) {
+	routes := make([]notification.Route, len(usr.Channels))
+	for i, cd := range usr.Channels {
+		routes[i] = cd.Route
+	}
+
+	if !notification.ConfirmAddress(routes, channel, address) {
+		return User{}, ErrChannelNotFound
+	}
+
+	channels := slices.Clone(usr.Channels)
+	for i := range channels {
+		channels[i].Route = routes[i]
+	}
+
+	if slices.Equal(channels, usr.Channels) {
+		return usr, nil
+	}
+
+	usr.Channels = channels
+	usr.UpdatedAt = time.Now()
+
+	if err := c.storer.Update(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("update: %w", err)
+	}
+	usr.Version++
+
+	return usr, nil
+}
+
+// Delete marks the specified user as deleted. Deleted users are left out of
+// every query, but keep their subscriptions and history until they are
+// purged, so they can be restored. It fails with ErrVersionConflict when the
+// user was changed since it was read.
+func (c *Core) Delete(ctx context.Context, usr User) error {
+	usr.DeletedAt = time.Now()
+
+	if err := c.storer.Delete(ctx, usr); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
 
 	return nil
 }
 
+// Restore brings back a deleted user. It fails with ErrUniqueEmail when the
+// email has been taken by another user in the meantime.
+func (c *Core) Restore(ctx context.Context, usr User) (User, error) {
+	usr.DeletedAt = time.Time{}
+	usr.UpdatedAt = time.Now()
+
+	if err := c.storer.Restore(ctx, usr); err != nil {
+		return User{}, fmt.Errorf("restore: %w", err)
+	}
+
+	return usr, nil
+}
+
+// Purge removes up to limit users deleted before the given time for good,
+// together with everything they own, and returns how many were removed.
+func (c *Core) Purge(ctx context.Context, before time.Time, limit int) (int, error) {
+	n, err := c.storer.Purge(ctx, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purge: %w", err)
+	}
+
+	return n, nil
+}
+
 // Query retrieves a list of existing users.
-func (c *Core) Query(pageNumber int, rowsPerPage int) ([]User, error) {
-	users, err := c.storer.Query(pageNumber, rowsPerPage)
+func (c *Core) Query(ctx context.Context, filter QueryFilter, orderBy order.By, pageNumber int, rowsPerPage int) ([]User, error) {
+	users, err := c.storer.Query(ctx, filter, orderBy, pageNumber, rowsPerPage)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -145,9 +231,29 @@ func (c *Core) Query(pageNumber int, rowsPerPage int) ([]User, error) {
 	return users, nil
 }
 
+// Count returns the number of users matching the filter.
+func (c *Core) Count(ctx context.Context, filter QueryFilter) (int, error) {
+	count, err := c.storer.Count(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("count: %w", err)
+	}
+
+	return count, nil
+}
+
 // QueryByID finds the user by the specified ID.
-func (c *Core) QueryByID(id uuid.UUID) (User, error) {
-	user, err := c.storer.QueryByID(id)
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (User, error) {
+	user, err := c.storer.QueryByID(ctx, id)
+	if err != nil {
+		return User{}, fmt.Errorf("query: %w", err)
+	}
+
+	return user, nil
+}
+
+// QueryDeletedByID finds the deleted user by the specified ID.
+func (c *Core) QueryDeletedByID(ctx context.Context, id uuid.UUID) (User, error) {
+	user, err := c.storer.QueryDeletedByID(ctx, id)
 	if err != nil {
 		return User{}, fmt.Errorf("query: %w", err)
 	}
@@ -156,8 +262,8 @@ func (c *Core) QueryByID(id uuid.UUID) (User, error) {
 }
 
 // QueryByEmail finds the user by a specified user email.
-func (c *Core) QueryByEmail(email string) (User, error) {
-	user, err := c.storer.QueryByEmail(email)
+func (c *Core) QueryByEmail(ctx context.Context, email string) (User, error) {
+	user, err := c.storer.QueryByEmail(ctx, email)
 	if err != nil {
 		return User{}, fmt.Errorf("query: email[%s]: %w", email, err)
 	}
@@ -165,8 +271,23 @@ func (c *Core) QueryByEmail(email string) (User, error) {
 	return user, nil
 }
 
-func (c *Core) QueryByTopicID(topicID uuid.UUID) ([]User, error) {
-	users, err := c.storer.QueryByTopicID(topicID)
+func (c *Core) QueryByTopicID(ctx context.Context, topicID uuid.UUID) ([]User, error) {
+	users, err := c.storer.QueryByTopicID(ctx, topicID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return users, nil
+}
+
+// QueryByIDs finds the users by the specified IDs. Missing users are left
+// out.
+func (c *Core) QueryByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	users, err := c.storer.QueryByIDs(ctx, ids)
 	if err != nil {
 		return nil, fmt.Errorf("query: %w", err)
 	}
@@ -174,8 +295,8 @@ func (c *Core) QueryByTopicID(topicID uuid.UUID) ([]User, error) {
 	return users, nil
 }
 
-func (c *Core) Authenticate(email string, password string) (User, error) {
-	usr, err := c.QueryByEmail(email)
+func (c *Core) Authenticate(ctx context.Context, email string, password string) (User, error) {
+	usr, err := c.QueryByEmail(ctx, email)
 	if err != nil {
 		return User{}, err
 	}
@@ -0,0 +1,122 @@
+// Package verificationmem contains verification related CRUD functionality,
+// kept in memory.
+package verificationmem
+
+import (
+	"context"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/verification"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const table = "verifications"
+
+// Store manages the set of APIs for verification access in memory.
+type Store struct {
+	log    *zerolog.Logger
+	db     memdb.Querier
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *memdb.DB) *Store {
+	db.Reference("verifications_user_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: "users",
+		Refers: func(row any, key string) bool { return row.(verification.Verificiation).UserID.String() == key },
+	})
+
+	db.Reference("verifications_subscription_id_fkey", memdb.Reference{
+		Table:  table,
+		Parent: "subscriptions",
+		Refers: func(row any, key string) bool {
+			return row.(verification.Verificiation).SubscriptionID.String() == key
+		},
+	})
+
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(verification.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *memdb.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return memdb.WithinTran(ctx, s.log, s.db.(*memdb.DB), f)
+}
+
+// Create inserts a new verification into the database.
+func (s *Store) Create(ctx context.Context, vrf verification.Verificiation) error {
+	return memdb.Insert(s.db, table, vrf.ID.String(), vrf)
+}
+
+// Update marks a verification as used, as long as the code matches and it
+// was neither used nor expired before.
+func (s *Store) Update(ctx context.Context, vrf verification.Verificiation) error {
+	stored, exists := memdb.Get[verification.Verificiation](s.db, table, vrf.ID.String())
+	if !exists || stored.Code != vrf.Code || stored.Used || !stored.ExpiredAt.After(time.Now()) {
+		return nil
+	}
+
+	stored.Used = vrf.Used
+
+	return memdb.Put(s.db, table, stored.ID.String(), stored)
+}
+
+// QueryByID gets the specified verification from the database by id.
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (verification.Verificiation, error) {
+	vrf, exists := memdb.Get[verification.Verificiation](s.db, table, id.String())
+	if !exists {
+		return verification.Verificiation{}, verification.ErrNotFound
+	}
+
+	return vrf, nil
+}
+
+// CountPending returns the number of verifications sent for the same user or
+// subscription, channel and address as the new one that weren't used and
+// haven't expired by now.
+func (s *Store) CountPending(ctx context.Context, nv verification.NewVerification, now time.Time) (int, error) {
+	vrfs := memdb.Rows(s.db, table, func(vrf verification.Verificiation) bool {
+		return (nv.UserID == uuid.Nil || vrf.UserID == nv.UserID) &&
+			(nv.SubscriptionID == uuid.Nil || vrf.SubscriptionID == nv.SubscriptionID) &&
+			vrf.Channel == nv.Channel && vrf.Email == nv.Email &&
+			!vrf.Used && vrf.ExpiredAt.After(now)
+	})
+
+	return len(vrfs), nil
+}
+
+// PurgeExpired removes up to limit verifications that were used or expired before the
+// given time from the database.
+func (s *Store) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	vrfs := memdb.Rows(s.db, table, func(vrf verification.Verificiation) bool {
+		return vrf.Used || vrf.ExpiredAt.Before(before)
+	})
+
+	if len(vrfs) > limit {
+		vrfs = vrfs[:limit]
+	}
+
+	for _, vrf := range vrfs {
+		memdb.Delete(s.db, table, vrf.ID.String())
+	}
+
+	return len(vrfs), nil
+}
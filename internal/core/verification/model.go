@@ -3,21 +3,33 @@ package verification
 import (
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
 	"github.com/google/uuid"
 )
 
+// Verificiation confirms an email address, either of a user or of a guest
+// subscription. Verifications with a channel confirm an address the user
+// gave for the channel instead, in a default channel of the user or a route
+// of the subscription, and that address is kept in Email even when it is a
+// phone number.
 type Verificiation struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	Email     string
-	Code      string
-	Used      bool
-	CreatedAt time.Time
-	ExpiredAt time.Time
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	SubscriptionID uuid.UUID
+	Channel        notification.Channel
+	Email          string
+	Code           string
+	Used           bool
+	CreatedAt      time.Time
+	ExpiredAt      time.Time
 }
 
+// NewVerification contains information needed to create a new verification.
+// Exactly one of the user and the subscription has to be set.
 type NewVerification struct {
-	UserID uuid.UUID
-	Email  string
-	Code   string
+	UserID         uuid.UUID
+	SubscriptionID uuid.UUID
+	Channel        notification.Channel
+	Email          string
+	Code           string
 }
@@ -0,0 +1,164 @@
+// Package verificationlite contains verification related CRUD functionality,
+// stored in SQLite.
+package verificationlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/verification"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Store manages the set of APIs for verification access in SQLite.
+type Store struct {
+	log    *zerolog.Logger
+	db     sqlx.ExtContext
+	inTran bool
+}
+
+// NewStore constructs the api for data access.
+func NewStore(log *zerolog.Logger, db *sqlx.DB) *Store {
+	return &Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// WithinTran runs passed function and do commit/rollback at the end.
+func (s *Store) WithinTran(ctx context.Context, fn func(verification.Storer) error) error {
+	if s.inTran {
+		return fn(s)
+	}
+
+	f := func(tx *sqlx.Tx) error {
+		s := &Store{
+			log:    s.log,
+			db:     tx,
+			inTran: true,
+		}
+		return fn(s)
+	}
+
+	return database.WithinTran(ctx, s.log, s.db.(*sqlx.DB), f)
+}
+
+// Create inserts a new verification into the database.
+func (s *Store) Create(ctx context.Context, vrf verification.Verificiation) error {
+	const q = `
+	INSERT INTO verifications
+		(id, user_id, subscription_id, channel, email, code, used, created_at, expired_at)
+	VALUES
+		(:id, :user_id, :subscription_id, :channel, :email, :code, :used, :created_at, :expired_at)`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, toDBVerification(vrf)); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) Update(ctx context.Context, vrf verification.Verificiation) error {
+	data := struct {
+		dbVerification
+		Now time.Time `db:"now"`
+	}{
+		dbVerification: toDBVerification(vrf),
+		Now:            time.Now().UTC(),
+	}
+
+	const q = `
+	UPDATE
+		verifications
+	SET
+		used = :used
+	WHERE
+		id = :id
+		AND code = :code
+		AND used = FALSE
+		AND expired_at > :now`
+
+	if err := database.NamedExec(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("namedexec: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Store) QueryByID(ctx context.Context, id uuid.UUID) (verification.Verificiation, error) {
+	const q = `SELECT * FROM verifications WHERE id = :id`
+
+	data := struct {
+		ID uuid.UUID `db:"id"`
+	}{
+		ID: id,
+	}
+
+	var dbVrf dbVerification
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dbVrf); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return verification.Verificiation{}, verification.ErrNotFound
+		}
+		return verification.Verificiation{}, fmt.Errorf("namedquerystruct: %w", err)
+	}
+
+	return toCoreVerification(dbVrf)
+}
+
+// CountPending returns the number of verifications sent for the same user or
+// subscription, channel and address as the new one that weren't used and
+// haven't expired by now.
+func (s *Store) CountPending(ctx context.Context, nv verification.NewVerification, now time.Time) (int, error) {
+	b := database.NewQueryBuilder(`SELECT * FROM verifications`)
+	if nv.UserID != uuid.Nil {
+		b.Where("user_id = :user_id", map[string]any{"user_id": nv.UserID})
+	}
+	if nv.SubscriptionID != uuid.Nil {
+		b.Where("subscription_id = :subscription_id", map[string]any{"subscription_id": nv.SubscriptionID})
+	}
+	if nv.Channel == (notification.Channel{}) {
+		b.Where("channel IS NULL", nil)
+	} else {
+		b.Where("channel = :channel", map[string]any{"channel": nv.Channel.Name()})
+	}
+	b.Where("email = :email", map[string]any{"email": nv.Email})
+	b.Where("NOT used AND expired_at > :now", map[string]any{"now": now.UTC()})
+
+	count, err := database.QueryCount(ctx, s.log, s.db, b)
+	if err != nil {
+		return 0, fmt.Errorf("querycount: %w", err)
+	}
+
+	return count, nil
+}
+
+// PurgeExpired removes up to limit verifications that were used or expired before the
+// given time from the database.
+func (s *Store) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	data := struct {
+		Before time.Time `db:"before"`
+		Limit  int       `db:"limit"`
+	}{
+		Before: before.UTC(),
+		Limit:  limit,
+	}
+
+	const q = `
+	DELETE FROM
+		verifications
+	WHERE
+		id IN (SELECT id FROM verifications WHERE used OR expired_at < :before LIMIT :limit)`
+
+	n, err := database.NamedExecCount(ctx, s.log, s.db, q, data)
+	if err != nil {
+		return 0, fmt.Errorf("namedexeccount: %w", err)
+	}
+
+	return n, nil
+}
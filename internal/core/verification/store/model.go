@@ -1,42 +1,69 @@
 package verificationdb
 
 import (
+	"database/sql"
+	"fmt"
 	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
 	"github.com/dudakovict/gotify/internal/core/verification"
 	"github.com/google/uuid"
 )
 
 type dbVerification struct {
-	ID        uuid.UUID `db:"id"`
-	UserID    uuid.UUID `db:"user_id"`
-	Email     string    `db:"email"`
-	Code      string    `db:"code"`
-	Used      bool      `db:"used"`
-	CreatedAt time.Time `db:"created_at"`
-	ExpiredAt time.Time `db:"expired_at"`
+	ID             uuid.UUID      `db:"id"`
+	UserID         uuid.NullUUID  `db:"user_id"`
+	SubscriptionID uuid.NullUUID  `db:"subscription_id"`
+	Channel        sql.NullString `db:"channel"`
+	Email          string         `db:"email"`
+	Code           string         `db:"code"`
+	Used           bool           `db:"used"`
+	CreatedAt      time.Time      `db:"created_at"`
+	ExpiredAt      time.Time      `db:"expired_at"`
 }
 
 func toDBVerification(vrf verification.Verificiation) dbVerification {
 	return dbVerification{
-		ID:        vrf.ID,
-		UserID:    vrf.UserID,
+		ID: vrf.ID,
+		UserID: uuid.NullUUID{
+			UUID:  vrf.UserID,
+			Valid: vrf.UserID != uuid.Nil,
+		},
+		SubscriptionID: uuid.NullUUID{
+			UUID:  vrf.SubscriptionID,
+			Valid: vrf.SubscriptionID != uuid.Nil,
+		},
+		Channel: sql.NullString{
+			String: vrf.Channel.Name(),
+			Valid:  vrf.Channel != (notification.Channel{}),
+		},
 		Email:     vrf.Email,
 		Code:      vrf.Code,
 		Used:      vrf.Used,
-		CreatedAt: vrf.CreatedAt,
-		ExpiredAt: vrf.ExpiredAt,
+		CreatedAt: vrf.CreatedAt.UTC(),
+		ExpiredAt: vrf.ExpiredAt.UTC(),
 	}
 }
 
-func toCoreVerification(dbVrf dbVerification) verification.Verificiation {
-	return verification.Verificiation{
-		ID:        dbVrf.ID,
-		UserID:    dbVrf.UserID,
-		Email:     dbVrf.Email,
-		Code:      dbVrf.Code,
-		Used:      dbVrf.Used,
-		CreatedAt: dbVrf.CreatedAt,
-		ExpiredAt: dbVrf.ExpiredAt,
+func toCoreVerification(dbVrf dbVerification) (verification.Verificiation, error) {
+	vrf := verification.Verificiation{
+		ID:             dbVrf.ID,
+		UserID:         dbVrf.UserID.UUID,
+		SubscriptionID: dbVrf.SubscriptionID.UUID,
+		Email:          dbVrf.Email,
+		Code:           dbVrf.Code,
+		Used:           dbVrf.Used,
+		CreatedAt:      dbVrf.CreatedAt.In(time.Local),
+		ExpiredAt:      dbVrf.ExpiredAt.In(time.Local),
 	}
+
+	if dbVrf.Channel.Valid {
+		channel, err := notification.ParseChannel(dbVrf.Channel.String)
+		if err != nil {
+			return verification.Verificiation{}, fmt.Errorf("parse channel: %w", err)
+		}
+		vrf.Channel = channel
+	}
+
+	return vrf, nil
 }
@@ -2,9 +2,13 @@
 package verification
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/subscription"
 	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -13,93 +17,218 @@ import (
 // Set of error variables for CRUD operations.
 var (
 	ErrNotFound = errors.New("verification not found")
+	ErrExpired  = errors.New("verification expired")
+)
+
+// Set of durations verifications are valid for. Guests get longer to confirm
+// subscriptions, and so do the owners of addresses, as nothing else is
+// waiting on it.
+const (
+	userExpiration         = 15 * time.Minute
+	subscriptionExpiration = 24 * time.Hour
 )
 
 // Storer interface declares the behavior this package needs to perists and
 // retrieve data.
 type Storer interface {
-	WithinTran(fn func(s Storer) error) error
-	Create(vrf Verificiation) error
-	Update(vrf Verificiation) error
-	QueryByID(id uuid.UUID) (Verificiation, error)
+	WithinTran(ctx context.Context, fn func(s Storer) error) error
+	Create(ctx context.Context, vrf Verificiation) error
+	Update(ctx context.Context, vrf Verificiation) error
+	QueryByID(ctx context.Context, id uuid.UUID) (Verificiation, error)
+	CountPending(ctx context.Context, nv NewVerification, now time.Time) (int, error)
+	PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error)
 }
 
 // Core manages the set of APIs for verification access.
 type Core struct {
 	log     *zerolog.Logger
 	usrCore *user.Core
+	subCore *subscription.Core
 	storer  Storer
 }
 
 // NewCore constructs a verification core API for use.
-func NewCore(log *zerolog.Logger, usrCore *user.Core, storer Storer) *Core {
+func NewCore(log *zerolog.Logger, usrCore *user.Core, subCore *subscription.Core, storer Storer) *Core {
 	return &Core{
 		log:     log,
 		usrCore: usrCore,
+		subCore: subCore,
 		storer:  storer,
 	}
 }
 
-func (c *Core) Verify(id uuid.UUID) (user.User, error) {
-	var updUsr user.User
-	tran := func(s Storer) error {
-		vrf, err := s.QueryByID(id)
+// Verify marks the user the verification was sent to as verified.
+func (c *Core) Verify(ctx context.Context, id uuid.UUID) (user.User, error) {
+	check := func(vrf Verificiation) error {
+		// Confirmations of guest subscriptions and addresses don't verify
+		// anyone.
+		if vrf.SubscriptionID != uuid.Nil || vrf.Channel != (notification.Channel{}) {
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	vrf, err := c.claim(ctx, id, check)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	usr, err := c.usrCore.QueryByEmail(ctx, vrf.Email)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	verified := true
+
+	updUsr, err := c.usrCore.Update(ctx, usr, user.UpdateUser{
+		Verified: &verified,
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+
+	return updUsr, nil
+}
+
+// Confirm confirms what the verification was sent for, the guest
+// subscription or the address of a subscription route or of a default
+// channel of a user, as long as the code matches and the verification wasn't
+// used or expired.
+func (c *Core) Confirm(ctx context.Context, id uuid.UUID, code string) error {
+	check := func(vrf Verificiation) error {
+		if (vrf.SubscriptionID == uuid.Nil && vrf.Channel == (notification.Channel{})) || vrf.Code != code {
+			return ErrNotFound
+		}
+
+		if vrf.Used || !time.Now().Before(vrf.ExpiredAt) {
+			return ErrExpired
+		}
+
+		return nil
+	}
+
+	vrf, err := c.claim(ctx, id, check)
+	if err != nil {
+		return err
+	}
+
+	return c.confirm(ctx, vrf)
+}
+
+// confirm confirms the guest subscription or the address the verification
+// was sent for.
+func (c *Core) confirm(ctx context.Context, vrf Verificiation) error {
+	switch {
+	case vrf.Channel == (notification.Channel{}):
+		sub, err := c.subCore.QueryByID(ctx, vrf.SubscriptionID)
 		if err != nil {
 			return err
 		}
 
-		vrf.Used = true
+		if _, err := c.subCore.Confirm(ctx, sub); err != nil {
+			return err
+		}
 
-		if err := s.Update(vrf); err != nil {
+	case vrf.SubscriptionID != uuid.Nil:
+		sub, err := c.subCore.QueryByID(ctx, vrf.SubscriptionID)
+		if err != nil {
 			return err
 		}
 
-		usr, err := c.usrCore.QueryByEmail(vrf.Email)
+		if _, err := c.subCore.ConfirmRoute(ctx, sub, vrf.Channel, vrf.Email); err != nil {
+			return err
+		}
+
+	default:
+		usr, err := c.usrCore.QueryByID(ctx, vrf.UserID)
 		if err != nil {
 			return err
 		}
 
-		verified := true
+		if _, err := c.usrCore.ConfirmChannel(ctx, usr, vrf.Channel, vrf.Email); err != nil {
+			return err
+		}
+	}
 
-		updUsr, err = c.usrCore.Update(usr, user.UpdateUser{
-			Verified: &verified,
-		})
+	return nil
+}
+
+// claim marks the verification as used once check lets it through, in a
+// transaction of its own. The user and subscription cores write through
+// storers of their own, so they only do once the claim is committed: SQLite
+// lets a single transaction write at a time, and theirs would wait on this
+// one. Being used, the verification can't be claimed twice, and when what it
+// was claimed for fails a new one has to be sent.
+func (c *Core) claim(ctx context.Context, id uuid.UUID, check func(vrf Verificiation) error) (Verificiation, error) {
+	var vrf Verificiation
+	tran := func(s Storer) error {
+		var err error
+		vrf, err = s.QueryByID(ctx, id)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		if err := check(vrf); err != nil {
+			return err
+		}
+
+		vrf.Used = true
+
+		return s.Update(ctx, vrf)
 	}
 
-	if err := c.storer.WithinTran(tran); err != nil {
-		return user.User{}, fmt.Errorf("tran: %w", err)
+	if err := c.storer.WithinTran(ctx, tran); err != nil {
+		return Verificiation{}, fmt.Errorf("tran: %w", err)
 	}
 
-	return updUsr, nil
+	return vrf, nil
+}
+
+// PendingConfirmation reports whether a confirmation like the new one was
+// already sent that wasn't used and hasn't expired, so that asking again
+// doesn't send another one.
+func (c *Core) PendingConfirmation(ctx context.Context, nv NewVerification) (bool, error) {
+	n, err := c.storer.CountPending(ctx, nv, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("countpending: %w", err)
+	}
+
+	return n > 0, nil
 }
 
 // Create adds a new verification to the system.
-func (c *Core) Create(nv NewVerification) (Verificiation, error) {
+func (c *Core) Create(ctx context.Context, nv NewVerification) (Verificiation, error) {
+	expiration := userExpiration
+	if nv.SubscriptionID != uuid.Nil || nv.Channel != (notification.Channel{}) {
+		expiration = subscriptionExpiration
+	}
+
+	now := time.Now()
+
 	vrf := Verificiation{
-		ID:     uuid.New(),
-		UserID: nv.UserID,
-		Email:  nv.Email,
-		Code:   nv.Code,
+		ID:             uuid.New(),
+		UserID:         nv.UserID,
+		SubscriptionID: nv.SubscriptionID,
+		Channel:        nv.Channel,
+		Email:          nv.Email,
+		Code:           nv.Code,
+		CreatedAt:      now,
+		ExpiredAt:      now.Add(expiration),
 	}
 
-	if err := c.storer.Create(vrf); err != nil {
+	if err := c.storer.Create(ctx, vrf); err != nil {
 		return Verificiation{}, fmt.Errorf("create: %w", err)
 	}
 
 	return vrf, nil
 }
 
-func (c *Core) Update(vrf Verificiation, used *bool) (Verificiation, error) {
+func (c *Core) Update(ctx context.Context, vrf Verificiation, used *bool) (Verificiation, error) {
 	if used != nil {
 		vrf.Used = *used
 	}
 
-	if err := c.storer.Update(vrf); err != nil {
+	if err := c.storer.Update(ctx, vrf); err != nil {
 		return Verificiation{}, fmt.Errorf("update: %w", err)
 	}
 
@@ -107,11 +236,22 @@ func (c *Core) Update(vrf Verificiation, used *bool) (Verificiation, error) {
 }
 
 // QueryByID finds the verification by the specified ID.
-func (c *Core) QueryByID(id uuid.UUID) (Verificiation, error) {
-	vrf, err := c.storer.QueryByID(id)
+func (c *Core) QueryByID(ctx context.Context, id uuid.UUID) (Verificiation, error) {
+	vrf, err := c.storer.QueryByID(ctx, id)
 	if err != nil {
 		return Verificiation{}, fmt.Errorf("query: %w", err)
 	}
 
 	return vrf, nil
 }
+
+// PurgeExpired removes up to limit verifications that were used or expired
+// before the given time and returns how many were removed.
+func (c *Core) PurgeExpired(ctx context.Context, before time.Time, limit int) (int, error) {
+	n, err := c.storer.PurgeExpired(ctx, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("purgeexpired: %w", err)
+	}
+
+	return n, nil
+}
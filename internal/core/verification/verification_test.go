@@ -0,0 +1,100 @@
+package verification_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/internal/core/verification"
+	"github.com/dudakovict/gotify/internal/stores"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/dudakovict/gotify/platform/migrations"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+var log = zerolog.Nop()
+
+// The verification core writes through the user and subscription cores, with
+// storers of their own, so it's tested against real stores. SQLite only lets
+// a single transaction write at a time.
+func TestVerifyMemory(t *testing.T) {
+	testVerify(t, stores.NewMemory(&log, memdb.New()))
+}
+
+func TestVerifySQLite(t *testing.T) {
+	db, err := database.OpenSQLite(database.SQLiteConfig{
+		Path: filepath.Join(t.TempDir(), "gotify.db"),
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, migrations.Migrate(context.Background(), db.DB, migrations.DriverSQLite))
+
+	testVerify(t, stores.NewSQLite(&log, db))
+}
+
+func testVerify(t *testing.T, s stores.Stores) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	usrCore := user.NewCore(&log, s.User)
+	subCore := subscription.NewCore(&log, s.Subscription)
+	tpcCore := topic.NewCore(&log, s.Topic, uuid.New)
+	core := verification.NewCore(&log, usrCore, subCore, s.Verification)
+
+	usr, err := usrCore.Create(ctx, user.NewUser{
+		Email:    "owner@example.com",
+		Roles:    []user.Role{user.RoleUser},
+		Password: "password",
+	})
+	require.NoError(t, err)
+
+	vrf, err := core.Create(ctx, verification.NewVerification{
+		UserID: usr.ID,
+		Email:  usr.Email,
+	})
+	require.NoError(t, err)
+
+	verified, err := core.Verify(ctx, vrf.ID)
+	require.NoError(t, err)
+	require.True(t, verified.Verified)
+
+	got, err := core.QueryByID(ctx, vrf.ID)
+	require.NoError(t, err)
+	require.True(t, got.Used)
+
+	tpc, err := tpcCore.Create(ctx, topic.NewTopic{
+		Name:       "alerts",
+		OwnerID:    usr.ID,
+		Visibility: topic.VisibilityPublic,
+	})
+	require.NoError(t, err)
+
+	sub, err := subCore.CreateGuest(ctx, subscription.NewGuestSubscription{
+		TopicID: tpc.ID,
+		Email:   "guest@example.com",
+	})
+	require.NoError(t, err)
+
+	vrf, err = core.Create(ctx, verification.NewVerification{
+		SubscriptionID: sub.ID,
+		Email:          sub.GuestEmail,
+		Code:           "code",
+	})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, core.Confirm(ctx, vrf.ID, "wrong"), verification.ErrNotFound)
+	require.NoError(t, core.Confirm(ctx, vrf.ID, "code"))
+	require.ErrorIs(t, core.Confirm(ctx, vrf.ID, "code"), verification.ErrExpired)
+
+	sub, err = subCore.QueryByID(ctx, sub.ID)
+	require.NoError(t, err)
+	require.True(t, sub.Confirmed)
+}
@@ -0,0 +1,128 @@
+// Package stores bundles the storers of the cores, backed by the same
+// database.
+package stores
+
+import (
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	apikeymem "github.com/dudakovict/gotify/internal/core/apikey/memstore"
+	apikeylite "github.com/dudakovict/gotify/internal/core/apikey/sqlitestore"
+	apikeydb "github.com/dudakovict/gotify/internal/core/apikey/store"
+	"github.com/dudakovict/gotify/internal/core/group"
+	groupmem "github.com/dudakovict/gotify/internal/core/group/memstore"
+	grouplite "github.com/dudakovict/gotify/internal/core/group/sqlitestore"
+	groupdb "github.com/dudakovict/gotify/internal/core/group/store"
+	"github.com/dudakovict/gotify/internal/core/mfa"
+	mfamem "github.com/dudakovict/gotify/internal/core/mfa/memstore"
+	mfalite "github.com/dudakovict/gotify/internal/core/mfa/sqlitestore"
+	mfadb "github.com/dudakovict/gotify/internal/core/mfa/store"
+	"github.com/dudakovict/gotify/internal/core/notification"
+	notificationmem "github.com/dudakovict/gotify/internal/core/notification/memstore"
+	notificationlite "github.com/dudakovict/gotify/internal/core/notification/sqlitestore"
+	notificationdb "github.com/dudakovict/gotify/internal/core/notification/store"
+	"github.com/dudakovict/gotify/internal/core/passkey"
+	passkeymem "github.com/dudakovict/gotify/internal/core/passkey/memstore"
+	passkeylite "github.com/dudakovict/gotify/internal/core/passkey/sqlitestore"
+	passkeydb "github.com/dudakovict/gotify/internal/core/passkey/store"
+	"github.com/dudakovict/gotify/internal/core/role"
+	rolemem "github.com/dudakovict/gotify/internal/core/role/memstore"
+	rolelite "github.com/dudakovict/gotify/internal/core/role/sqlitestore"
+	roledb "github.com/dudakovict/gotify/internal/core/role/store"
+	"github.com/dudakovict/gotify/internal/core/session"
+	sessionmem "github.com/dudakovict/gotify/internal/core/session/memstore"
+	sessionlite "github.com/dudakovict/gotify/internal/core/session/sqlitestore"
+	sessiondb "github.com/dudakovict/gotify/internal/core/session/store"
+	"github.com/dudakovict/gotify/internal/core/sso"
+	ssomem "github.com/dudakovict/gotify/internal/core/sso/memstore"
+	ssolite "github.com/dudakovict/gotify/internal/core/sso/sqlitestore"
+	ssodb "github.com/dudakovict/gotify/internal/core/sso/store"
+	"github.com/dudakovict/gotify/internal/core/subscription"
+	subscriptionmem "github.com/dudakovict/gotify/internal/core/subscription/memstore"
+	subscriptionlite "github.com/dudakovict/gotify/internal/core/subscription/sqlitestore"
+	subscriptiondb "github.com/dudakovict/gotify/internal/core/subscription/store"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	topicmem "github.com/dudakovict/gotify/internal/core/topic/memstore"
+	topiclite "github.com/dudakovict/gotify/internal/core/topic/sqlitestore"
+	topicdb "github.com/dudakovict/gotify/internal/core/topic/store"
+	"github.com/dudakovict/gotify/internal/core/user"
+	usermem "github.com/dudakovict/gotify/internal/core/user/memstore"
+	userlite "github.com/dudakovict/gotify/internal/core/user/sqlitestore"
+	userdb "github.com/dudakovict/gotify/internal/core/user/store"
+	"github.com/dudakovict/gotify/internal/core/verification"
+	verificationmem "github.com/dudakovict/gotify/internal/core/verification/memstore"
+	verificationlite "github.com/dudakovict/gotify/internal/core/verification/sqlitestore"
+	verificationdb "github.com/dudakovict/gotify/internal/core/verification/store"
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// Stores holds the storer of every core.
+type Stores struct {
+	APIKey       apikey.Storer
+	Group        group.Storer
+	MFA          mfa.Storer
+	Notification notification.Storer
+	Passkey      passkey.Storer
+	Role         role.Storer
+	Session      session.Storer
+	SSO          sso.Storer
+	Subscription subscription.Storer
+	Topic        topic.Storer
+	User         user.Storer
+	Verification verification.Storer
+}
+
+// NewPostgres constructs the storers backed by the Postgres database.
+func NewPostgres(log *zerolog.Logger, db *sqlx.DB) Stores {
+	return Stores{
+		APIKey:       apikeydb.NewStore(log, db),
+		Group:        groupdb.NewStore(log, db),
+		MFA:          mfadb.NewStore(log, db),
+		Notification: notificationdb.NewStore(log, db),
+		Passkey:      passkeydb.NewStore(log, db),
+		Role:         roledb.NewStore(log, db),
+		Session:      sessiondb.NewStore(log, db),
+		SSO:          ssodb.NewStore(log, db),
+		Subscription: subscriptiondb.NewStore(log, db),
+		Topic:        topicdb.NewStore(log, db),
+		User:         userdb.NewStore(log, db),
+		Verification: verificationdb.NewStore(log, db),
+	}
+}
+
+// NewSQLite constructs the storers backed by the SQLite database.
+func NewSQLite(log *zerolog.Logger, db *sqlx.DB) Stores {
+	return Stores{
+		APIKey:       apikeylite.NewStore(log, db),
+		Group:        grouplite.NewStore(log, db),
+		MFA:          mfalite.NewStore(log, db),
+		Notification: notificationlite.NewStore(log, db),
+		Passkey:      passkeylite.NewStore(log, db),
+		Role:         rolelite.NewStore(log, db),
+		Session:      sessionlite.NewStore(log, db),
+		SSO:          ssolite.NewStore(log, db),
+		Subscription: subscriptionlite.NewStore(log, db),
+		Topic:        topiclite.NewStore(log, db),
+		User:         userlite.NewStore(log, db),
+		Verification: verificationlite.NewStore(log, db),
+	}
+}
+
+// NewMemory constructs the storers backed by the in-memory database, seeded
+// with the built-in roles.
+func NewMemory(log *zerolog.Logger, db *memdb.DB) Stores {
+	return Stores{
+		APIKey:       apikeymem.NewStore(log, db),
+		Group:        groupmem.NewStore(log, db),
+		MFA:          mfamem.NewStore(log, db),
+		Notification: notificationmem.NewStore(log, db),
+		Passkey:      passkeymem.NewStore(log, db),
+		Role:         rolemem.NewStore(log, db),
+		Session:      sessionmem.NewStore(log, db),
+		SSO:          ssomem.NewStore(log, db),
+		Subscription: subscriptionmem.NewStore(log, db),
+		Topic:        topicmem.NewStore(log, db),
+		User:         usermem.NewStore(log, db),
+		Verification: verificationmem.NewStore(log, db),
+	}
+}
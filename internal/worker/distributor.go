@@ -3,8 +3,10 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog"
 )
@@ -21,20 +23,48 @@ type TaskDistributor interface {
 		payload *PayloadSendNotification,
 		opts ...asynq.Option,
 	) error
+	DistributeTaskSendTopicInvitation(
+		ctx context.Context,
+		payload *PayloadSendTopicInvitation,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendTopicDeleted(
+		ctx context.Context,
+		payload *PayloadSendTopicDeleted,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendConfirmSubscription(
+		ctx context.Context,
+		payload *PayloadSendConfirmSubscription,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskSendConfirmChannels(
+		ctx context.Context,
+		payload *PayloadSendConfirmChannels,
+		opts ...asynq.Option,
+	) error
+	DistributeTaskProcessSubscriptionJob(
+		ctx context.Context,
+		payload *PayloadProcessSubscriptionJob,
+		opts ...asynq.Option,
+	) error
+	CancelTopicTasks(ctx context.Context, topicID uuid.UUID) error
 }
 
 // RedisTaskDistributor distributes tasks using Redis.
 type RedisTaskDistributor struct {
-	log    *zerolog.Logger
-	client *asynq.Client
+	log       *zerolog.Logger
+	client    *asynq.Client
+	inspector *asynq.Inspector
 }
 
 // newRedisTaskDistributor initializes a new RedisTaskDistributor instance.
 func newRedisTaskDistributor(log *zerolog.Logger, redisOpt asynq.RedisClientOpt) TaskDistributor {
 	client := asynq.NewClient(redisOpt)
 	return &RedisTaskDistributor{
-		log:    log,
-		client: client,
+		log:       log,
+		client:    client,
+		inspector: asynq.NewInspector(redisOpt),
 	}
 }
 
@@ -86,3 +116,200 @@ func (distributor *RedisTaskDistributor) DistributeTaskSendNotification(ctx cont
 
 	return nil
 }
+
+const TaskSendTopicInvitation = "task:send_topic_invitation"
+
+// DistributeTaskSendTopicInvitation enqueues a task to send a topic
+// invitation email.
+func (distributor *RedisTaskDistributor) DistributeTaskSendTopicInvitation(ctx context.Context, payload *PayloadSendTopicInvitation, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskSendTopicInvitation, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	// The payload carries the invitation token so it isn't logged.
+	distributor.log.Info().
+		Str("type", task.Type()).
+		Str("invitation_id", payload.InvitationID.String()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+const TaskSendTopicDeleted = "task:send_topic_deleted"
+
+// DistributeTaskSendTopicDeleted enqueues a task to let the subscribers of a
+// deleted topic know it is gone.
+func (distributor *RedisTaskDistributor) DistributeTaskSendTopicDeleted(ctx context.Context, payload *PayloadSendTopicDeleted, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskSendTopicDeleted, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	distributor.log.Info().
+		Str("type", task.Type()).
+		Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+const TaskSendConfirmSubscription = "task:send_confirm_subscription"
+
+// DistributeTaskSendConfirmSubscription enqueues a task to ask a guest to
+// confirm their subscription, or to confirm the addresses its routes deliver
+// to.
+func (distributor *RedisTaskDistributor) DistributeTaskSendConfirmSubscription(ctx context.Context, payload *PayloadSendConfirmSubscription, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskSendConfirmSubscription, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	distributor.log.Info().
+		Str("type", task.Type()).
+		Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+const TaskSendConfirmChannels = "task:send_confirm_channels"
+
+// DistributeTaskSendConfirmChannels enqueues a task to confirm the addresses
+// the default channels of a user deliver to.
+func (distributor *RedisTaskDistributor) DistributeTaskSendConfirmChannels(ctx context.Context, payload *PayloadSendConfirmChannels, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskSendConfirmChannels, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	distributor.log.Info().
+		Str("type", task.Type()).
+		Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+const TaskProcessSubscriptionJob = "task:process_subscription_job"
+
+// DistributeTaskProcessSubscriptionJob enqueues a task to run a bulk
+// subscription job.
+func (distributor *RedisTaskDistributor) DistributeTaskProcessSubscriptionJob(ctx context.Context, payload *PayloadProcessSubscriptionJob, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskProcessSubscriptionJob, jsonPayload, opts...)
+	info, err := distributor.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	distributor.log.Info().
+		Str("type", task.Type()).
+		Bytes("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+// CancelTopicTasks deletes the notification tasks of the topic that are still
+// waiting to be processed.
+func (distributor *RedisTaskDistributor) CancelTopicTasks(ctx context.Context, topicID uuid.UUID) error {
+	const pageSize = 100
+
+	lists := []func(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error){
+		distributor.inspector.ListPendingTasks,
+		distributor.inspector.ListScheduledTasks,
+		distributor.inspector.ListRetryTasks,
+	}
+
+	for _, queue := range []string{QueueCritical, QueueDefault} {
+		// Tasks are collected first since deleting them while paging would
+		// shift the pages.
+		var ids []string
+
+		for _, list := range lists {
+			for page := 1; ; page++ {
+				tasks, err := list(queue, asynq.Page(page), asynq.PageSize(pageSize))
+				if err != nil {
+					if errors.Is(err, asynq.ErrQueueNotFound) {
+						break
+					}
+					return fmt.Errorf("failed to list tasks: %w", err)
+				}
+
+				for _, task := range tasks {
+					if task.Type != TaskSendNotification {
+						continue
+					}
+
+					var payload PayloadSendNotification
+					if err := json.Unmarshal(task.Payload, &payload); err != nil {
+						continue
+					}
+
+					if payload.TopicID == topicID {
+						ids = append(ids, task.ID)
+					}
+				}
+
+				if len(tasks) < pageSize {
+					break
+				}
+			}
+		}
+
+		for _, id := range ids {
+			if err := distributor.inspector.DeleteTask(queue, id); err != nil && !errors.Is(err, asynq.ErrTaskNotFound) {
+				return fmt.Errorf("failed to delete task: %w", err)
+			}
+		}
+
+		if len(ids) > 0 {
+			distributor.log.Info().
+				Str("topic_id", topicID.String()).
+				Str("queue", queue).
+				Int("tasks", len(ids)).
+				Msg("cancelled tasks")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog"
+)
+
+// defaultMaxRetry is how many times asynq retries a task unless told
+// otherwise.
+const defaultMaxRetry = 25
+
+// pendingTask is a task waiting in the in-process queue. Periodic tasks are
+// queued again every interval once they are processed.
+type pendingTask struct {
+	task     *asynq.Task
+	runAt    time.Time
+	retried  int
+	maxRetry int
+	every    time.Duration
+}
+
+// InProcessQueue distributes tasks to a processor running in the same
+// process, without Redis. Tasks are processed one at a time, honouring the
+// delay and retry options they were distributed with. Tasks still waiting are
+// lost when the process exits, so it's meant for dev mode and tests.
+type InProcessQueue struct {
+	*RedisTaskProcessor
+	log      *zerolog.Logger
+	mu       sync.Mutex
+	tasks    []*pendingTask
+	wake     chan struct{}
+	shutdown chan struct{}
+	done     chan struct{}
+}
+
+// newInProcessQueue initializes a new InProcessQueue instance processing the
+// tasks with the handlers of the processor.
+func newInProcessQueue(log *zerolog.Logger, processor *RedisTaskProcessor) *InProcessQueue {
+	return &InProcessQueue{
+		RedisTaskProcessor: processor,
+		log:                log,
+		wake:               make(chan struct{}, 1),
+		shutdown:           make(chan struct{}),
+		done:               make(chan struct{}),
+	}
+}
+
+// DistributeTaskSendVerifyEmail queues a task to send a verification email.
+func (queue *InProcessQueue) DistributeTaskSendVerifyEmail(ctx context.Context, payload *PayloadSendVerifyEmail, opts ...asynq.Option) error {
+	return queue.enqueue(TaskSendVerifyEmail, payload, opts)
+}
+
+// DistributeTaskSendNotification queues a task to deliver a notification.
+func (queue *InProcessQueue) DistributeTaskSendNotification(ctx context.Context, payload *PayloadSendNotification, opts ...asynq.Option) error {
+	return queue.enqueue(TaskSendNotification, payload, opts)
+}
+
+// DistributeTaskSendTopicInvitation queues a task to send a topic invitation
+// email.
+func (queue *InProcessQueue) DistributeTaskSendTopicInvitation(ctx context.Context, payload *PayloadSendTopicInvitation, opts ...asynq.Option) error {
+	return queue.enqueue(TaskSendTopicInvitation, payload, opts)
+}
+
+// DistributeTaskSendTopicDeleted queues a task to let the subscribers of a
+// deleted topic know it is gone.
+func (queue *InProcessQueue) DistributeTaskSendTopicDeleted(ctx context.Context, payload *PayloadSendTopicDeleted, opts ...asynq.Option) error {
+	return queue.enqueue(TaskSendTopicDeleted, payload, opts)
+}
+
+// DistributeTaskSendConfirmSubscription queues a task to ask a guest to
+// confirm their subscription, or to confirm the addresses its routes deliver
+// to.
+func (queue *InProcessQueue) DistributeTaskSendConfirmSubscription(ctx context.Context, payload *PayloadSendConfirmSubscription, opts ...asynq.Option) error {
+	return queue.enqueue(TaskSendConfirmSubscription, payload, opts)
+}
+
+// DistributeTaskSendConfirmChannels queues a task to confirm the addresses the
+// default channels of a user deliver to.
+func (queue *InProcessQueue) DistributeTaskSendConfirmChannels(ctx context.Context, payload *PayloadSendConfirmChannels, opts ...asynq.Option) error {
+	return queue.enqueue(TaskSendConfirmChannels, payload, opts)
+}
+
+// DistributeTaskProcessSubscriptionJob queues a task to run a bulk
+// subscription job.
+func (queue *InProcessQueue) DistributeTaskProcessSubscriptionJob(ctx context.Context, payload *PayloadProcessSubscriptionJob, opts ...asynq.Option) error {
+	return queue.enqueue(TaskProcessSubscriptionJob, payload, opts)
+}
+
+// CancelTopicTasks drops the notification tasks of the topic that are still
+// waiting to be processed.
+func (queue *InProcessQueue) CancelTopicTasks(ctx context.Context, topicID uuid.UUID) error {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	n := len(queue.tasks)
+	queue.tasks = slices.DeleteFunc(queue.tasks, func(pt *pendingTask) bool {
+		if pt.task.Type() != TaskSendNotification {
+			return false
+		}
+
+		var payload PayloadSendNotification
+		if err := json.Unmarshal(pt.task.Payload(), &payload); err != nil {
+			return false
+		}
+
+		return payload.TopicID == topicID
+	})
+
+	if cancelled := n - len(queue.tasks); cancelled > 0 {
+		queue.log.Info().
+			Str("topic_id", topicID.String()).
+			Int("tasks", cancelled).
+			Msg("cancelled tasks")
+	}
+
+	return nil
+}
+
+// Start starts processing the queued tasks in the background, along with the
+// periodic tasks.
+func (queue *InProcessQueue) Start() error {
+	for _, pt := range periodicTasks {
+		queue.push(&pendingTask{
+			task:  asynq.NewTask(pt.typename, nil),
+			runAt: time.Now().Add(pt.every),
+			every: pt.every,
+		})
+	}
+
+	go queue.run()
+
+	return nil
+}
+
+// Shutdown stops processing the queued tasks, waiting for the task being
+// processed to finish.
+func (queue *InProcessQueue) Shutdown() {
+	close(queue.shutdown)
+	<-queue.done
+}
+
+// =============================================================================
+
+func (queue *InProcessQueue) enqueue(typename string, payload any, opts []asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	pt := pendingTask{
+		task:     asynq.NewTask(typename, jsonPayload, opts...),
+		runAt:    time.Now(),
+		maxRetry: defaultMaxRetry,
+	}
+
+	for _, opt := range opts {
+		switch opt.Type() {
+		case asynq.MaxRetryOpt:
+			pt.maxRetry = opt.Value().(int)
+		case asynq.ProcessInOpt:
+			pt.runAt = time.Now().Add(opt.Value().(time.Duration))
+		case asynq.ProcessAtOpt:
+			pt.runAt = opt.Value().(time.Time)
+		}
+	}
+
+	queue.push(&pt)
+
+	queue.log.Info().
+		Str("type", typename).
+		Time("process_at", pt.runAt).
+		Int("max_retry", pt.maxRetry).
+		Msg("enqueued task")
+
+	return nil
+}
+
+func (queue *InProcessQueue) push(pt *pendingTask) {
+	queue.mu.Lock()
+	queue.tasks = append(queue.tasks, pt)
+	queue.mu.Unlock()
+
+	select {
+	case queue.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes the task due first from the queue when it's due, or returns
+// how long to wait for it otherwise.
+func (queue *InProcessQueue) next(now time.Time) (*pendingTask, time.Duration) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if len(queue.tasks) == 0 {
+		return nil, time.Hour
+	}
+
+	i := 0
+	for j, pt := range queue.tasks {
+		if pt.runAt.Before(queue.tasks[i].runAt) {
+			i = j
+		}
+	}
+
+	pt := queue.tasks[i]
+	if wait := pt.runAt.Sub(now); wait > 0 {
+		return nil, wait
+	}
+
+	queue.tasks = slices.Delete(queue.tasks, i, i+1)
+	return pt, 0
+}
+
+func (queue *InProcessQueue) run() {
+	defer close(queue.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for {
+		pt, wait := queue.next(time.Now())
+		if pt != nil {
+			queue.process(ctx, pt)
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-queue.shutdown:
+			timer.Stop()
+			return
+		case <-queue.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (queue *InProcessQueue) process(ctx context.Context, pt *pendingTask) {
+	err := queue.handle(ctx, pt.task)
+
+	// A periodic task that fails waits for its next run instead of being
+	// retried.
+	if pt.every > 0 {
+		queue.push(&pendingTask{
+			task:  pt.task,
+			runAt: time.Now().Add(pt.every),
+			every: pt.every,
+		})
+	}
+
+	if err == nil {
+		return
+	}
+
+	queue.log.Error().
+		Err(err).
+		Str("type", pt.task.Type()).
+		Bytes("payload", pt.task.Payload()).
+		Msg("process task failed")
+
+	if errors.Is(err, asynq.SkipRetry) || pt.retried >= pt.maxRetry {
+		return
+	}
+
+	pt.retried++
+	pt.runAt = time.Now().Add(asynq.DefaultRetryDelayFunc(pt.retried, err, pt.task))
+	queue.push(pt)
+}
+
+func (queue *InProcessQueue) handle(ctx context.Context, task *asynq.Task) error {
+	switch task.Type() {
+	case TaskSendVerifyEmail:
+		return queue.ProcessTaskSendVerifyEmail(ctx, task)
+	case TaskSendNotification:
+		return queue.ProcessTaskSendNotification(ctx, task)
+	case TaskSendTopicInvitation:
+		return queue.ProcessTaskSendTopicInvitation(ctx, task)
+	case TaskSendTopicDeleted:
+		return queue.ProcessTaskSendTopicDeleted(ctx, task)
+	case TaskSendConfirmSubscription:
+		return queue.ProcessTaskSendConfirmSubscription(ctx, task)
+	case TaskSendConfirmChannels:
+		return queue.ProcessTaskSendConfirmChannels(ctx, task)
+	case TaskProcessSubscriptionJob:
+		return queue.ProcessTaskProcessSubscriptionJob(ctx, task)
+	case TaskPurgeDeleted:
+		return queue.ProcessTaskPurgeDeleted(ctx, task)
+	case TaskPurgeSessions:
+		return queue.ProcessTaskPurgeSessions(ctx, task)
+	case TaskPurgeVerifications:
+		return queue.ProcessTaskPurgeVerifications(ctx, task)
+	case TaskPurgeNotifications:
+		return queue.ProcessTaskPurgeNotifications(ctx, task)
+	}
+
+	return fmt.Errorf("unknown task type %q: %w", task.Type(), asynq.SkipRetry)
+}
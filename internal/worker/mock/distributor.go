@@ -10,6 +10,7 @@ import (
 
 	worker "github.com/dudakovict/gotify/internal/worker"
 	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
 	asynq "github.com/hibiken/asynq"
 )
 
@@ -36,6 +37,77 @@ func (m *MockTaskDistributor) EXPECT() *MockTaskDistributorMockRecorder {
 	return m.recorder
 }
 
+// CancelTopicTasks mocks base method.
+func (m *MockTaskDistributor) CancelTopicTasks(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelTopicTasks", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelTopicTasks indicates an expected call of CancelTopicTasks.
+func (mr *MockTaskDistributorMockRecorder) CancelTopicTasks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelTopicTasks", reflect.TypeOf((*MockTaskDistributor)(nil).CancelTopicTasks), arg0, arg1)
+}
+
+// DistributeTaskProcessSubscriptionJob mocks base method.
+func (m *MockTaskDistributor) DistributeTaskProcessSubscriptionJob(arg0 context.Context, arg1 *worker.PayloadProcessSubscriptionJob, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskProcessSubscriptionJob", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskProcessSubscriptionJob indicates an expected call of DistributeTaskProcessSubscriptionJob.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskProcessSubscriptionJob(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskProcessSubscriptionJob", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskProcessSubscriptionJob), varargs...)
+}
+
+// DistributeTaskSendConfirmChannels mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendConfirmChannels(arg0 context.Context, arg1 *worker.PayloadSendConfirmChannels, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendConfirmChannels", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendConfirmChannels indicates an expected call of DistributeTaskSendConfirmChannels.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendConfirmChannels(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendConfirmChannels", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendConfirmChannels), varargs...)
+}
+
+// DistributeTaskSendConfirmSubscription mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendConfirmSubscription(arg0 context.Context, arg1 *worker.PayloadSendConfirmSubscription, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendConfirmSubscription", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendConfirmSubscription indicates an expected call of DistributeTaskSendConfirmSubscription.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendConfirmSubscription(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendConfirmSubscription", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendConfirmSubscription), varargs...)
+}
+
 // DistributeTaskSendNotification mocks base method.
 func (m *MockTaskDistributor) DistributeTaskSendNotification(arg0 context.Context, arg1 *worker.PayloadSendNotification, arg2 ...asynq.Option) error {
 	m.ctrl.T.Helper()
@@ -55,6 +127,44 @@ func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendNotification(arg0,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendNotification", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendNotification), varargs...)
 }
 
+// DistributeTaskSendTopicDeleted mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendTopicDeleted(arg0 context.Context, arg1 *worker.PayloadSendTopicDeleted, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendTopicDeleted", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendTopicDeleted indicates an expected call of DistributeTaskSendTopicDeleted.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendTopicDeleted(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendTopicDeleted", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendTopicDeleted), varargs...)
+}
+
+// DistributeTaskSendTopicInvitation mocks base method.
+func (m *MockTaskDistributor) DistributeTaskSendTopicInvitation(arg0 context.Context, arg1 *worker.PayloadSendTopicInvitation, arg2 ...asynq.Option) error {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DistributeTaskSendTopicInvitation", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DistributeTaskSendTopicInvitation indicates an expected call of DistributeTaskSendTopicInvitation.
+func (mr *MockTaskDistributorMockRecorder) DistributeTaskSendTopicInvitation(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTaskSendTopicInvitation", reflect.TypeOf((*MockTaskDistributor)(nil).DistributeTaskSendTopicInvitation), varargs...)
+}
+
 // DistributeTaskSendVerifyEmail mocks base method.
 func (m *MockTaskDistributor) DistributeTaskSendVerifyEmail(arg0 context.Context, arg1 *worker.PayloadSendVerifyEmail, arg2 ...asynq.Option) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,210 @@
+package worker
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+// Set of periodic task types.
+const (
+	// TaskPurgeDeleted is the task removing the rows deleted longer ago than
+	// the retention period for good.
+	TaskPurgeDeleted = "task:purge_deleted"
+
+	// TaskPurgeSessions is the task removing the expired sessions.
+	TaskPurgeSessions = "task:purge_sessions"
+
+	// TaskPurgeVerifications is the task removing the used or expired
+	// verifications.
+	TaskPurgeVerifications = "task:purge_verifications"
+
+	// TaskPurgeNotifications is the task removing the notifications older
+	// than the retention period of their topic.
+	TaskPurgeNotifications = "task:purge_notifications"
+)
+
+// defaultPurgeBatchSize is how many rows are removed at a time unless told
+// otherwise.
+const defaultPurgeBatchSize = 1000
+
+// purgedRows counts the rows removed by the purge tasks, by table. It's
+// published under /debug/vars.
+var purgedRows = expvar.NewMap("purged_rows")
+
+// periodicTask is a task the worker schedules itself at a fixed interval,
+// instead of being distributed by the app.
+type periodicTask struct {
+	typename string
+	every    time.Duration
+}
+
+// periodicTasks lists the tasks run on a schedule.
+var periodicTasks = []periodicTask{
+	{typename: TaskPurgeDeleted, every: time.Hour},
+	{typename: TaskPurgeSessions, every: time.Hour},
+	{typename: TaskPurgeVerifications, every: time.Hour},
+	{typename: TaskPurgeNotifications, every: time.Hour},
+}
+
+// ProcessTaskPurgeDeleted processes the task removing the notifications,
+// subscriptions, topics and users deleted longer ago than the retention
+// period. Purging a topic or a user removes whatever still belongs to them.
+func (processor *RedisTaskProcessor) ProcessTaskPurgeDeleted(ctx context.Context, task *asynq.Task) error {
+	before := time.Now().Add(-processor.deletedRetention)
+
+	ntfs, err := processor.purgeInBatches(ctx, "notifications", func(limit int) (int, error) {
+		return processor.ntfCore.Purge(ctx, before, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge notifications: %w", err)
+	}
+
+	subs, err := processor.purgeInBatches(ctx, "subscriptions", func(limit int) (int, error) {
+		return processor.subCore.Purge(ctx, before, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge subscriptions: %w", err)
+	}
+
+	tpcs, err := processor.purgeInBatches(ctx, "topics", func(limit int) (int, error) {
+		return processor.tpcCore.Purge(ctx, before, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge topics: %w", err)
+	}
+
+	usrs, err := processor.purgeInBatches(ctx, "users", func(limit int) (int, error) {
+		return processor.usrCore.Purge(ctx, before, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge users: %w", err)
+	}
+
+	log.Info().
+		Str("type", task.Type()).
+		Time("before", before).
+		Int("notifications", ntfs).
+		Int("subscriptions", subs).
+		Int("topics", tpcs).
+		Int("users", usrs).
+		Msg("processed task")
+
+	return nil
+}
+
+// ProcessTaskPurgeSessions processes the task removing the expired sessions.
+func (processor *RedisTaskProcessor) ProcessTaskPurgeSessions(ctx context.Context, task *asynq.Task) error {
+	before := time.Now()
+
+	sessns, err := processor.purgeInBatches(ctx, "sessions", func(limit int) (int, error) {
+		return processor.sessCore.PurgeExpired(ctx, before, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge sessions: %w", err)
+	}
+
+	log.Info().
+		Str("type", task.Type()).
+		Int("sessions", sessns).
+		Msg("processed task")
+
+	return nil
+}
+
+// ProcessTaskPurgeVerifications processes the task removing the verifications
+// that were used or expired.
+func (processor *RedisTaskProcessor) ProcessTaskPurgeVerifications(ctx context.Context, task *asynq.Task) error {
+	before := time.Now()
+
+	vrfs, err := processor.purgeInBatches(ctx, "verifications", func(limit int) (int, error) {
+		return processor.vrfCore.PurgeExpired(ctx, before, limit)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge verifications: %w", err)
+	}
+
+	log.Info().
+		Str("type", task.Type()).
+		Int("verifications", vrfs).
+		Msg("processed task")
+
+	return nil
+}
+
+// ProcessTaskPurgeNotifications processes the task removing the notifications
+// older than the retention period. Topics with a retention of their own use
+// it, the rest use the global one, and are left alone when it isn't set.
+func (processor *RedisTaskProcessor) ProcessTaskPurgeNotifications(ctx context.Context, task *asynq.Task) error {
+	now := time.Now()
+
+	tpcs, err := processor.tpcCore.QueryWithRetention(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get topics: %w", err)
+	}
+
+	var ntfs int
+	for _, tpc := range tpcs {
+		n, err := processor.purgeInBatches(ctx, "notifications", func(limit int) (int, error) {
+			return processor.ntfCore.PurgeOlder(ctx, tpc.ID, now.Add(-tpc.Retention), limit)
+		})
+		ntfs += n
+		if err != nil {
+			return fmt.Errorf("failed to purge notifications of topic %s: %w", tpc.ID, err)
+		}
+	}
+
+	if processor.notificationRetention > 0 {
+		n, err := processor.purgeInBatches(ctx, "notifications", func(limit int) (int, error) {
+			return processor.ntfCore.PurgeOlder(ctx, uuid.Nil, now.Add(-processor.notificationRetention), limit)
+		})
+		ntfs += n
+		if err != nil {
+			return fmt.Errorf("failed to purge notifications: %w", err)
+		}
+	}
+
+	log.Info().
+		Str("type", task.Type()).
+		Int("topics", len(tpcs)).
+		Int("notifications", ntfs).
+		Msg("processed task")
+
+	return nil
+}
+
+// =============================================================================
+
+// purgeInBatches calls purge with the batch size until a batch removes fewer
+// rows, so no single statement holds locks on a large part of the table. The
+// rows removed are counted towards the metrics of the table.
+func (processor *RedisTaskProcessor) purgeInBatches(ctx context.Context, table string, purge func(limit int) (int, error)) (int, error) {
+	batch := processor.purgeBatchSize
+	if batch <= 0 {
+		batch = defaultPurgeBatchSize
+	}
+
+	var total int
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		n, err := purge(batch)
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		purgedRows.Add(table, int64(n))
+
+		if n < batch {
+			return total, nil
+		}
+	}
+}
@@ -3,15 +3,24 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/dudakovict/gotify/internal/core/group"
 	"github.com/dudakovict/gotify/internal/core/notification"
+	"github.com/dudakovict/gotify/internal/core/session"
+	"github.com/dudakovict/gotify/internal/core/subscription"
 	"github.com/dudakovict/gotify/internal/core/topic"
 	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/dudakovict/gotify/internal/core/verification"
 	"github.com/dudakovict/gotify/pkg/mailer"
+	"github.com/dudakovict/gotify/pkg/signer"
+	"github.com/dudakovict/gotify/pkg/sms"
 	"github.com/dudakovict/gotify/pkg/util"
+	"github.com/dudakovict/gotify/pkg/webhook"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
@@ -24,26 +33,56 @@ type TaskProcessor interface {
 	Shutdown()
 	ProcessTaskSendVerifyEmail(ctx context.Context, task *asynq.Task) error
 	ProcessTaskSendNotification(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendTopicInvitation(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendTopicDeleted(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendConfirmSubscription(ctx context.Context, task *asynq.Task) error
+	ProcessTaskSendConfirmChannels(ctx context.Context, task *asynq.Task) error
+	ProcessTaskProcessSubscriptionJob(ctx context.Context, task *asynq.Task) error
+	ProcessTaskPurgeDeleted(ctx context.Context, task *asynq.Task) error
+	ProcessTaskPurgeSessions(ctx context.Context, task *asynq.Task) error
+	ProcessTaskPurgeVerifications(ctx context.Context, task *asynq.Task) error
+	ProcessTaskPurgeNotifications(ctx context.Context, task *asynq.Task) error
 }
 
 // RedisTaskProcessor processes tasks using Redis.
 type RedisTaskProcessor struct {
-	server  *asynq.Server
-	usrCore *user.Core
-	vrfCore *verification.Core
-	ntfCore *notification.Core
-	tpcCore *topic.Core
-	mailer  mailer.Mailer
+	server                *asynq.Server
+	scheduler             *asynq.Scheduler
+	usrCore               *user.Core
+	sessCore              *session.Core
+	vrfCore               *verification.Core
+	ntfCore               *notification.Core
+	tpcCore               *topic.Core
+	subCore               *subscription.Core
+	grpCore               *group.Core
+	mailer                mailer.Mailer
+	webhook               webhook.Sender
+	sms                   sms.Sender
+	signer                *signer.Signer
+	publicURL             string
+	deletedRetention      time.Duration
+	notificationRetention time.Duration
+	purgeBatchSize        int
 }
 
 // newRedisTaskProcessor initializes a new RedisTaskProcessor instance.
 func newRedisTaskProcessor(
 	redisOpt asynq.RedisClientOpt,
 	usrCore *user.Core,
+	sessCore *session.Core,
 	vrfCore *verification.Core,
 	ntfCore *notification.Core,
 	tpcCore *topic.Core,
+	subCore *subscription.Core,
+	grpCore *group.Core,
 	mailer mailer.Mailer,
+	webhook webhook.Sender,
+	sms sms.Sender,
+	signer *signer.Signer,
+	publicURL string,
+	deletedRetention time.Duration,
+	notificationRetention time.Duration,
+	purgeBatchSize int,
 ) TaskProcessor {
 	logger := NewLogger()
 	redis.SetLogger(logger)
@@ -66,13 +105,28 @@ func newRedisTaskProcessor(
 		},
 	)
 
+	scheduler := asynq.NewScheduler(redisOpt, &asynq.SchedulerOpts{
+		Logger: logger,
+	})
+
 	return &RedisTaskProcessor{
-		server:  server,
-		usrCore: usrCore,
-		vrfCore: vrfCore,
-		ntfCore: ntfCore,
-		tpcCore: tpcCore,
-		mailer:  mailer,
+		server:                server,
+		scheduler:             scheduler,
+		usrCore:               usrCore,
+		sessCore:              sessCore,
+		vrfCore:               vrfCore,
+		ntfCore:               ntfCore,
+		tpcCore:               tpcCore,
+		subCore:               subCore,
+		grpCore:               grpCore,
+		mailer:                mailer,
+		webhook:               webhook,
+		sms:                   sms,
+		signer:                signer,
+		publicURL:             publicURL,
+		deletedRetention:      deletedRetention,
+		notificationRetention: notificationRetention,
+		purgeBatchSize:        purgeBatchSize,
 	}
 }
 
@@ -81,11 +135,40 @@ func (processor *RedisTaskProcessor) Start() error {
 
 	mux.HandleFunc(TaskSendVerifyEmail, processor.ProcessTaskSendVerifyEmail)
 	mux.HandleFunc(TaskSendNotification, processor.ProcessTaskSendNotification)
+	mux.HandleFunc(TaskSendTopicInvitation, processor.ProcessTaskSendTopicInvitation)
+	mux.HandleFunc(TaskSendTopicDeleted, processor.ProcessTaskSendTopicDeleted)
+	mux.HandleFunc(TaskSendConfirmSubscription, processor.ProcessTaskSendConfirmSubscription)
+	mux.HandleFunc(TaskSendConfirmChannels, processor.ProcessTaskSendConfirmChannels)
+	mux.HandleFunc(TaskProcessSubscriptionJob, processor.ProcessTaskProcessSubscriptionJob)
+	mux.HandleFunc(TaskPurgeDeleted, processor.ProcessTaskPurgeDeleted)
+	mux.HandleFunc(TaskPurgeSessions, processor.ProcessTaskPurgeSessions)
+	mux.HandleFunc(TaskPurgeVerifications, processor.ProcessTaskPurgeVerifications)
+	mux.HandleFunc(TaskPurgeNotifications, processor.ProcessTaskPurgeNotifications)
+
+	// Every replica schedules the periodic tasks, so they are kept unique
+	// for their interval to run once however many replicas there are. A run
+	// that fails waits for the next one instead of being retried.
+	for _, pt := range periodicTasks {
+		opts := []asynq.Option{
+			asynq.Queue(QueueDefault),
+			asynq.MaxRetry(0),
+			asynq.Unique(pt.every),
+		}
+
+		if _, err := processor.scheduler.Register(fmt.Sprintf("@every %s", pt.every), asynq.NewTask(pt.typename, nil), opts...); err != nil {
+			return fmt.Errorf("failed to schedule %s: %w", pt.typename, err)
+		}
+	}
+
+	if err := processor.server.Start(mux); err != nil {
+		return err
+	}
 
-	return processor.server.Start(mux)
+	return processor.scheduler.Start()
 }
 
 func (processor *RedisTaskProcessor) Shutdown() {
+	processor.scheduler.Shutdown()
 	processor.server.Shutdown()
 }
 
@@ -100,12 +183,12 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
 	}
 
-	usr, err := processor.usrCore.QueryByEmail(payload.Email)
+	usr, err := processor.usrCore.QueryByEmail(ctx, payload.Email)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
 
-	verifyEmail, err := processor.vrfCore.Create(verification.NewVerification{
+	verifyEmail, err := processor.vrfCore.Create(ctx, verification.NewVerification{
 		UserID: usr.ID,
 		Email:  usr.Email,
 		Code:   util.RandomString(32),
@@ -115,7 +198,7 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 	}
 
 	subject := "Welcome!"
-	verifyURL := fmt.Sprintf("http://localhost:3000/api/v1/verify?id=%s&code=%s", verifyEmail.ID.String(), verifyEmail.Code)
+	verifyURL := fmt.Sprintf("%s/api/v1/verify?id=%s&code=%s", processor.publicURL, verifyEmail.ID.String(), verifyEmail.Code)
 	content := fmt.Sprintf(`Hello %s,<br/>
 	Thank you for registering with us!<br/>
 	Please <a href="%s">click here</a> to verify your email address.<br/>
@@ -136,6 +219,7 @@ func (processor *RedisTaskProcessor) ProcessTaskSendVerifyEmail(ctx context.Cont
 
 type PayloadSendNotification struct {
 	NotificationID uuid.UUID `json:"notification_id"`
+	TopicID        uuid.UUID `json:"topic_id"`
 }
 
 func (processor *RedisTaskProcessor) ProcessTaskSendNotification(ctx context.Context, task *asynq.Task) error {
@@ -144,34 +228,757 @@ func (processor *RedisTaskProcessor) ProcessTaskSendNotification(ctx context.Con
 		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
 	}
 
-	ntf, err := processor.ntfCore.QueryByID(payload.NotificationID)
+	ntf, err := processor.ntfCore.QueryByID(ctx, payload.NotificationID)
 	if err != nil {
+		// The notification is gone when it or its topic was deleted before the
+		// task ran, so there is nothing left to send.
+		if errors.Is(err, notification.ErrNotFound) {
+			return fmt.Errorf("failed to get notification: %w: %w", err, asynq.SkipRetry)
+		}
 		return fmt.Errorf("failed to get notification: %w", err)
 	}
 
-	tpc, err := processor.tpcCore.QueryByID(ntf.TopicID)
+	tpc, err := processor.tpcCore.QueryByID(ctx, ntf.TopicID)
 	if err != nil {
+		if errors.Is(err, topic.ErrNotFound) {
+			return fmt.Errorf("failed to get topic: %w: %w", err, asynq.SkipRetry)
+		}
 		return fmt.Errorf("failed to get topic: %w", err)
 	}
 
-	usrs, err := processor.usrCore.QueryByTopicID(ntf.TopicID)
+	subs, err := processor.subCore.QueryMatching(ctx, ntf.TopicID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscriptions to topic: [%s]: %w", ntf.TopicID, err)
+	}
+
+	// Muted subscriptions are recorded as skipped, so it can be told why a
+	// notification didn't go where it usually would.
+	subs, muted := subscription.SplitMuted(subs, time.Now())
+	for _, sub := range muted {
+		log.Info().
+			Str("type", task.Type()).
+			Str("notification_id", ntf.ID.String()).
+			Str("subscription_id", sub.ID.String()).
+			Str("user_id", sub.UserID.String()).
+			Msg("skipped: muted")
+
+		if err := processor.ntfCore.AddSkip(ctx, ntf, sub.ID, notification.SkipMuted); err != nil {
+			return fmt.Errorf("failed to record skipped delivery: %w", err)
+		}
+	}
+
+	// Groups are expanded now, so whoever is in them at the time gets the
+	// notification, as long as they may subscribe to the topic themselves.
+	members, err := processor.grpCore.MemberIDs(ctx, subscription.GroupIDs(subs))
+	if err != nil {
+		return fmt.Errorf("failed to get members of subscribed groups: %w", err)
+	}
+
+	allowed, err := processor.tpcCore.Subscribers(ctx, tpc)
+	if err != nil {
+		return fmt.Errorf("failed to get who may subscribe to topic: [%s]: %w", tpc.ID, err)
+	}
+	subs = subscription.ExpandGroups(subs, members, allowed)
+
+	// Filters are evaluated per subscriber, so only those who want the
+	// notification get it.
+	usrs, err := processor.usrCore.QueryByIDs(ctx, subscription.Recipients(subs, ntf))
 	if err != nil {
 		return fmt.Errorf("failed to get users subscribed to topic: [%s]: %w", ntf.TopicID, err)
 	}
 
-	to := make([]string, len(usrs))
-	for i, usr := range usrs {
-		to[i] = usr.Email
+	// Deliveries made by an earlier attempt of the task are skipped, so a
+	// retry only sends the notification where it hasn't gone yet.
+	made, err := processor.ntfCore.QueryDeliveries(ctx, ntf.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get deliveries of notification: [%s]: %w", ntf.ID, err)
+	}
+
+	d := newDeliveries(subs, usrs, ntf).without(made)
+
+	if d.empty() {
+		log.Info().
+			Str("type", task.Type()).
+			Bytes("payload", task.Payload()).
+			Msg("no recipients left after filtering and skipping deliveries made")
+		return nil
+	}
+
+	subject := tpc.Name
+	if ntf.Title != "" {
+		subject = fmt.Sprintf("[%s] %s", tpc.Name, ntf.Title)
+	}
+
+	// A failing delivery doesn't hold back the others. Every delivery made is
+	// recorded, so the task only fails, and is retried, for the ones that
+	// weren't. In-app deliveries are safe to repeat and aren't recorded.
+	var errs []error
+
+	// Every email gets its own unsubscribe link, so they go out one by one.
+	// Members reached through a group unsubscribe by leaving the group, as
+	// deleting its subscription would take the notifications from everyone in
+	// it.
+	for address, rcpt := range d.emails {
+		var content, unsubscribeURL string
+		switch {
+		case rcpt.groupID != uuid.Nil:
+			unsubscribeURL = processor.leaveGroupURL(rcpt.groupID, rcpt.userID)
+			content = fmt.Sprintf(`%s<br/><br/>
+	You get these notifications as a member of a group.
+	<a href="%s">Leave the group</a> to stop them.<br/>
+	`, ntf.Message, unsubscribeURL)
+
+		default:
+			unsubscribeURL = processor.unsubscribeURL(rcpt.subscriptionID)
+			content = fmt.Sprintf(`%s<br/><br/>
+	<a href="%s">Unsubscribe</a> from these notifications.<br/>
+	`, ntf.Message, unsubscribeURL)
+		}
+
+		headers := map[string]string{
+			"List-Unsubscribe":      fmt.Sprintf("<%s>", unsubscribeURL),
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+
+		if err := processor.mailer.SendEmailWithHeaders(subject, content, []string{address}, headers); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send a notification: %w", err))
+			continue
+		}
+		processor.addDelivery(ctx, task, ntf, notification.ChannelEmail, address)
+	}
+
+	if err := processor.ntfCore.AddToInbox(ctx, ntf, d.inbox); err != nil {
+		errs = append(errs, fmt.Errorf("failed to add notification to inbox: %w", err))
+	}
+
+	if len(d.webhooks) > 0 {
+		payload := newWebhookPayload(tpc, ntf)
+		for _, url := range d.webhooks {
+			if err := processor.webhook.Send(url, payload); err != nil {
+				errs = append(errs, fmt.Errorf("failed to post notification: %w", err))
+				continue
+			}
+			processor.addDelivery(ctx, task, ntf, notification.ChannelWebhook, url)
+		}
 	}
 
-	if err := processor.mailer.SendEmail(tpc.Name, ntf.Message, to, nil, nil, nil); err != nil {
-		return fmt.Errorf("failed to send a notification: %w", err)
+	if len(d.phones) > 0 {
+		switch processor.sms {
+		case nil:
+			log.Warn().
+				Str("type", task.Type()).
+				Str("phone", strings.Join(d.phones, ",")).
+				Msg("sms is not configured, skipping deliveries")
+
+		default:
+			content := fmt.Sprintf("%s: %s", subject, ntf.Message)
+			for _, phone := range d.phones {
+				if err := processor.sms.SendSMS(phone, content); err != nil {
+					errs = append(errs, fmt.Errorf("failed to send sms: %w", err))
+					continue
+				}
+				processor.addDelivery(ctx, task, ntf, notification.ChannelSMS, phone)
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
 	}
 	log.Info().
 		Str("type", task.Type()).
 		Bytes("payload", task.Payload()).
-		Str("email", strings.Join(to, ",")).
+		Int("emails", len(d.emails)).
+		Int("inbox", len(d.inbox)).
+		Int("webhooks", len(d.webhooks)).
+		Int("sms", len(d.phones)).
+		Msg("processed task")
+
+	return nil
+}
+
+// recipient is who an email is sent for: either a subscription, or a member
+// of a group the notification reaches through the group's subscription.
+type recipient struct {
+	subscriptionID uuid.UUID
+	groupID        uuid.UUID
+	userID         uuid.UUID
+}
+
+// deliveries holds the addresses a notification goes to, grouped by channel.
+// Emails are kept along with who they are sent for, which their unsubscribe
+// link depends on.
+type deliveries struct {
+	emails   map[string]recipient
+	inbox    []uuid.UUID
+	webhooks []string
+	phones   []string
+}
+
+// newDeliveries routes the notification to the users on the routes of their
+// subscriptions, or on their default channels when those don't pick any.
+// Guests only get it by email. Group subscriptions are expected to be expanded
+// to their members.
+func newDeliveries(subs []subscription.Subscription, usrs []user.User, ntf notification.Notification) deliveries {
+	d := deliveries{
+		emails: make(map[string]recipient),
+	}
+
+	add := func(addresses []string, address string) []string {
+		if slices.Contains(addresses, address) {
+			return addresses
+		}
+		return append(addresses, address)
+	}
+
+	for _, usr := range usrs {
+		for _, dlv := range subscription.Deliveries(subs, usr.ID, ntf, usr.DefaultRoutes(ntf.Priority)) {
+			switch dlv.Channel {
+			case notification.ChannelEmail:
+				address := dlv.Address
+				if address == "" {
+					address = usr.Email
+				}
+				if _, exists := d.emails[address]; !exists {
+					rcpt := recipient{subscriptionID: dlv.SubscriptionID}
+					if dlv.GroupID != uuid.Nil {
+						rcpt = recipient{groupID: dlv.GroupID, userID: usr.ID}
+					}
+					d.emails[address] = rcpt
+				}
+
+			case notification.ChannelInApp:
+				if !slices.Contains(d.inbox, usr.ID) {
+					d.inbox = append(d.inbox, usr.ID)
+				}
+
+			case notification.ChannelWebhook:
+				d.webhooks = add(d.webhooks, dlv.Address)
+
+			case notification.ChannelSMS:
+				d.phones = add(d.phones, dlv.Address)
+			}
+		}
+	}
+
+	for _, dlv := range subscription.GuestDeliveries(subs, ntf) {
+		if _, exists := d.emails[dlv.Address]; !exists {
+			d.emails[dlv.Address] = recipient{subscriptionID: dlv.SubscriptionID}
+		}
+	}
+
+	return d
+}
+
+// without leaves out the deliveries already made. In-app deliveries are kept
+// as they aren't recorded.
+func (d deliveries) without(made []notification.Delivery) deliveries {
+	for _, dlv := range made {
+		isAddress := func(address string) bool { return address == dlv.Address }
+
+		switch dlv.Channel {
+		case notification.ChannelEmail:
+			delete(d.emails, dlv.Address)
+		case notification.ChannelWebhook:
+			d.webhooks = slices.DeleteFunc(d.webhooks, isAddress)
+		case notification.ChannelSMS:
+			d.phones = slices.DeleteFunc(d.phones, isAddress)
+		}
+	}
+
+	return d
+}
+
+// empty reports whether the notification goes nowhere.
+func (d deliveries) empty() bool {
+	return len(d.emails) == 0 && len(d.inbox) == 0 && len(d.webhooks) == 0 && len(d.phones) == 0
+}
+
+// addDelivery records a delivery of the notification once it is made. The
+// notification was delivered even when recording fails, so the error is only
+// logged, at the risk of delivering it again if the task is retried.
+func (processor *RedisTaskProcessor) addDelivery(ctx context.Context, task *asynq.Task, ntf notification.Notification, channel notification.Channel, address string) {
+	if err := processor.ntfCore.AddDelivery(ctx, ntf, channel, address); err != nil {
+		log.Error().
+			Err(err).
+			Str("type", task.Type()).
+			Str("notification_id", ntf.ID.String()).
+			Str("channel", channel.Name()).
+			Msg("failed to record delivery")
+	}
+}
+
+// unsubscribeURL returns the signed link unsubscribing from the subscription
+// without signing in.
+func (processor *RedisTaskProcessor) unsubscribeURL(subID uuid.UUID) string {
+	signature := processor.signer.Sign(subID.String())
+	return fmt.Sprintf("%s/api/v1/unsubscribe?id=%s&signature=%s", processor.publicURL, subID.String(), signature)
+}
+
+// leaveGroupURL returns the signed link taking the user out of the group
+// without signing in.
+func (processor *RedisTaskProcessor) leaveGroupURL(groupID uuid.UUID, userID uuid.UUID) string {
+	signature := processor.signer.Sign(GroupMember(groupID, userID))
+	return fmt.Sprintf("%s/api/v1/unsubscribe?group_id=%s&user_id=%s&signature=%s", processor.publicURL, groupID.String(), userID.String(), signature)
+}
+
+// GroupMember returns what the links taking a user out of a group are signed
+// for, which can't be mistaken for the ID of a subscription.
+func GroupMember(groupID uuid.UUID, userID uuid.UUID) string {
+	return groupID.String() + "/" + userID.String()
+}
+
+type webhookPayload struct {
+	ID        uuid.UUID `json:"id"`
+	Topic     string    `json:"topic"`
+	Title     string    `json:"title,omitempty"`
+	Message   string    `json:"message"`
+	Priority  string    `json:"priority"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newWebhookPayload(tpc topic.Topic, ntf notification.Notification) webhookPayload {
+	return webhookPayload{
+		ID:        ntf.ID,
+		Topic:     tpc.Name,
+		Title:     ntf.Title,
+		Message:   ntf.Message,
+		Priority:  ntf.Priority.Name(),
+		Tags:      ntf.Tags,
+		CreatedAt: ntf.CreatedAt,
+	}
+}
+
+type PayloadSendTopicInvitation struct {
+	InvitationID uuid.UUID `json:"invitation_id"`
+	Token        string    `json:"token"`
+}
+
+// ProcessTaskSendTopicInvitation processes the task to send a topic
+// invitation email.
+func (processor *RedisTaskProcessor) ProcessTaskSendTopicInvitation(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendTopicInvitation
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	inv, err := processor.tpcCore.QueryInvitationByID(ctx, payload.InvitationID)
+	if err != nil {
+		return fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	tpc, err := processor.tpcCore.QueryByID(ctx, inv.TopicID)
+	if err != nil {
+		return fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	subject := fmt.Sprintf("You are invited to %s", tpc.Name)
+	acceptURL := fmt.Sprintf("%s/api/v1/topics/invitations/%s/accept?token=%s", processor.publicURL, inv.ID.String(), payload.Token)
+	content := fmt.Sprintf(`Hello %s,<br/>
+	You have been invited to the topic %s.<br/>
+	Sign in and <a href="%s">click here</a> to accept the invitation.<br/>
+	`, inv.Email, tpc.Name, acceptURL)
+	to := []string{inv.Email}
+
+	if err := processor.mailer.SendEmail(subject, content, to, nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to send topic invitation: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		Str("invitation_id", inv.ID.String()).
+		Str("email", inv.Email).
 		Msg("processed task")
 
 	return nil
 }
+
+type PayloadSendTopicDeleted struct {
+	TopicName string   `json:"topic_name"`
+	Emails    []string `json:"emails"`
+}
+
+// ProcessTaskSendTopicDeleted processes the task to let the subscribers of a
+// deleted topic know it is gone.
+func (processor *RedisTaskProcessor) ProcessTaskSendTopicDeleted(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendTopicDeleted
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	if len(payload.Emails) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("%s was deleted", payload.TopicName)
+	content := fmt.Sprintf(`Hello,<br/>
+	The topic %s you were subscribed to was deleted.<br/>
+	You won't receive any more notifications from it.<br/>
+	`, payload.TopicName)
+
+	if err := processor.mailer.SendEmail(subject, content, payload.Emails, nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to send topic deleted: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		Str("topic_name", payload.TopicName).
+		Str("email", strings.Join(payload.Emails, ",")).
+		Msg("processed task")
+
+	return nil
+}
+
+type PayloadSendConfirmSubscription struct {
+	SubscriptionID uuid.UUID `json:"subscription_id"`
+}
+
+// ProcessTaskSendConfirmSubscription processes the task to ask a guest to
+// confirm their subscription, or the owners of the addresses the routes of a
+// subscription deliver to to confirm them.
+func (processor *RedisTaskProcessor) ProcessTaskSendConfirmSubscription(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendConfirmSubscription
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	sub, err := processor.subCore.QueryByID(ctx, payload.SubscriptionID)
+	if err != nil {
+		// The guest may have unsubscribed or the topic been deleted before the
+		// task ran, so there is nobody left to ask.
+		if errors.Is(err, subscription.ErrNotFound) {
+			return fmt.Errorf("failed to get subscription: %w: %w", err, asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	if !sub.IsGuest() {
+		return processor.sendAddressConfirmations(ctx, task, verification.NewVerification{SubscriptionID: sub.ID}, sub.UserID, sub.Routes)
+	}
+
+	if sub.Confirmed {
+		return nil
+	}
+
+	// Anyone may ask for the subscription of any address, so another
+	// confirmation is only sent once the last one has expired.
+	pending, err := processor.vrfCore.PendingConfirmation(ctx, verification.NewVerification{
+		SubscriptionID: sub.ID,
+		Email:          sub.GuestEmail,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check pending confirmations: %w", err)
+	}
+
+	if pending {
+		log.Info().
+			Str("type", task.Type()).
+			Str("subscription_id", sub.ID.String()).
+			Msg("skipped: confirmation pending")
+		return nil
+	}
+
+	tpc, err := processor.tpcCore.QueryByID(ctx, sub.TopicID)
+	if err != nil {
+		return fmt.Errorf("failed to get topic: %w", err)
+	}
+
+	vrf, err := processor.vrfCore.Create(ctx, verification.NewVerification{
+		SubscriptionID: sub.ID,
+		Email:          sub.GuestEmail,
+		Code:           util.RandomString(32),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create subscription confirmation: %w", err)
+	}
+
+	subject := fmt.Sprintf("Confirm your subscription to %s", tpc.Name)
+	confirmURL := fmt.Sprintf("%s/api/v1/confirm?id=%s&code=%s", processor.publicURL, vrf.ID.String(), vrf.Code)
+	content := fmt.Sprintf(`Hello %s,<br/>
+	Someone subscribed this address to the notifications of %s.<br/>
+	Please <a href="%s">click here</a> to confirm the subscription.<br/>
+	If it wasn't you, ignore this email and you won't hear from us again.<br/>
+	`, sub.GuestEmail, tpc.Name, confirmURL)
+	to := []string{sub.GuestEmail}
+
+	if err := processor.mailer.SendEmail(subject, content, to, nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to send subscription confirmation: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		Str("subscription_id", sub.ID.String()).
+		Str("email", sub.GuestEmail).
+		Msg("processed task")
+
+	return nil
+}
+
+type PayloadSendConfirmChannels struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// ProcessTaskSendConfirmChannels processes the task to ask the owners of the
+// addresses the default channels of a user deliver to to confirm them.
+func (processor *RedisTaskProcessor) ProcessTaskSendConfirmChannels(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendConfirmChannels
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	usr, err := processor.usrCore.QueryByID(ctx, payload.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return fmt.Errorf("failed to get user: %w: %w", err, asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	routes := make([]notification.Route, len(usr.Channels))
+	for i, cd := range usr.Channels {
+		routes[i] = cd.Route
+	}
+
+	return processor.sendAddressConfirmations(ctx, task, verification.NewVerification{UserID: usr.ID}, usr.ID, routes)
+}
+
+// sendAddressConfirmations asks the owners of the addresses the routes
+// deliver to, and which they didn't confirm yet, to confirm them for the
+// target of the verifications, a subscription or a user. Nothing is
+// delivered to an address before, so users can't have notifications sent to
+// someone else.
+func (processor *RedisTaskProcessor) sendAddressConfirmations(ctx context.Context, task *asynq.Task, target verification.NewVerification, userID uuid.UUID, routes []notification.Route) error {
+	var unconfirmed []notification.Route
+	for _, route := range routes {
+		if route.Pending() {
+			unconfirmed = append(unconfirmed, route)
+		}
+	}
+
+	if len(unconfirmed) == 0 {
+		return nil
+	}
+
+	usr, err := processor.usrCore.QueryByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return fmt.Errorf("failed to get user: %w: %w", err, asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var errs []error
+	for _, route := range unconfirmed {
+		if route.Channel == notification.ChannelSMS && processor.sms == nil {
+			log.Warn().
+				Str("type", task.Type()).
+				Str("phone", route.Address).
+				Msg("sms is not configured, skipping confirmation")
+			continue
+		}
+
+		nv := target
+		nv.Channel = route.Channel
+		nv.Email = route.Address
+
+		// Setting the address again and again would otherwise flood its
+		// owner, so another confirmation is only sent once the last one has
+		// expired.
+		pending, err := processor.vrfCore.PendingConfirmation(ctx, nv)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check pending confirmations: %w", err))
+			continue
+		}
+
+		if pending {
+			log.Info().
+				Str("type", task.Type()).
+				Str("channel", route.Channel.Name()).
+				Str("address", route.Address).
+				Msg("skipped: confirmation pending")
+			continue
+		}
+
+		nv.Code = util.RandomString(32)
+
+		vrf, err := processor.vrfCore.Create(ctx, nv)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create address confirmation: %w", err))
+			continue
+		}
+
+		confirmURL := fmt.Sprintf("%s/api/v1/confirm?id=%s&code=%s", processor.publicURL, vrf.ID.String(), vrf.Code)
+
+		switch route.Channel {
+		case notification.ChannelSMS:
+			content := fmt.Sprintf("%s asked for their notifications to be sent to this number. Confirm it at %s or ignore this message.", usr.Email, confirmURL)
+			if err := processor.sms.SendSMS(route.Address, content); err != nil {
+				errs = append(errs, fmt.Errorf("failed to send address confirmation: %w", err))
+				continue
+			}
+
+		default:
+			subject := "Confirm your email address"
+			content := fmt.Sprintf(`Hello,<br/>
+	%s asked for their notifications to be sent to this address.<br/>
+	Please <a href="%s">click here</a> to confirm it.<br/>
+	If you don't know them, ignore this email and you won't hear from us again.<br/>
+	`, usr.Email, confirmURL)
+
+			if err := processor.mailer.SendEmail(subject, content, []string{route.Address}, nil, nil, nil); err != nil {
+				errs = append(errs, fmt.Errorf("failed to send address confirmation: %w", err))
+				continue
+			}
+		}
+		log.Info().
+			Str("type", task.Type()).
+			Str("user_id", usr.ID.String()).
+			Str("channel", route.Channel.Name()).
+			Str("address", route.Address).
+			Msg("processed task")
+	}
+
+	return errors.Join(errs...)
+}
+
+type PayloadProcessSubscriptionJob struct {
+	JobID uuid.UUID `json:"job_id"`
+}
+
+// jobProgressInterval is how many rows of a job are processed between saving
+// its progress.
+const jobProgressInterval = 100
+
+// errSubscribeDenied is reported for rows of users without the right to
+// subscribe to the topic.
+var errSubscribeDenied = errors.New("user may not subscribe to the topic")
+
+// ProcessTaskProcessSubscriptionJob processes the task to run a bulk
+// subscription job. A retried job carries on from the last saved row, and
+// rows subscribed twice count as existing.
+func (processor *RedisTaskProcessor) ProcessTaskProcessSubscriptionJob(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadProcessSubscriptionJob
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	job, err := processor.subCore.QueryJobByID(ctx, payload.JobID)
+	if err != nil {
+		if errors.Is(err, subscription.ErrJobNotFound) {
+			return fmt.Errorf("failed to get job: %w: %w", err, asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+
+	if job.Status == subscription.JobDone {
+		return nil
+	}
+
+	job, err = processor.subCore.UpdateJob(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to start job: %w", err)
+	}
+
+	for !job.Finished() {
+		err := processor.subscribeRow(ctx, job.Rows[job.Processed])
+
+		switch {
+		case err == nil:
+			job.Created++
+
+		case errors.Is(err, subscription.ErrUniqueViolation):
+			job.Existing++
+
+		case errors.Is(err, user.ErrNotFound), errors.Is(err, topic.ErrNotFound), errors.Is(err, errSubscribeDenied):
+			job.Errors = append(job.Errors, subscription.RowError{
+				Row:   job.Processed + 1,
+				Error: rowError(err),
+			})
+
+		default:
+			// The row is tried again when the task is retried.
+			if _, err := processor.subCore.UpdateJob(ctx, job); err != nil {
+				log.Error().
+					Err(err).
+					Str("type", task.Type()).
+					Str("job_id", job.ID.String()).
+					Msg("failed to save job progress")
+			}
+			return fmt.Errorf("failed to subscribe row %d: %w", job.Processed+1, err)
+		}
+
+		job.Processed++
+
+		if job.Processed%jobProgressInterval == 0 && !job.Finished() {
+			if job, err = processor.subCore.UpdateJob(ctx, job); err != nil {
+				return fmt.Errorf("failed to save job progress: %w", err)
+			}
+		}
+	}
+
+	job, err = processor.subCore.UpdateJob(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to finish job: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		Str("job_id", job.ID.String()).
+		Int("created", job.Created).
+		Int("existing", job.Existing).
+		Int("errors", len(job.Errors)).
+		Msg("processed task")
+
+	return nil
+}
+
+// subscribeRow subscribes the user of the row to its topic, the same as if
+// they subscribed themselves.
+func (processor *RedisTaskProcessor) subscribeRow(ctx context.Context, row subscription.JobRow) error {
+	var usr user.User
+	var err error
+	switch {
+	case row.UserID != uuid.Nil:
+		usr, err = processor.usrCore.QueryByID(ctx, row.UserID)
+	default:
+		usr, err = processor.usrCore.QueryByEmail(ctx, row.Email)
+	}
+	if err != nil {
+		return err
+	}
+
+	var tpc topic.Topic
+	switch {
+	case row.TopicID != uuid.Nil:
+		tpc, err = processor.tpcCore.QueryByID(ctx, row.TopicID)
+	default:
+		tpc, err = processor.tpcCore.QueryByName(ctx, row.Topic)
+	}
+	if err != nil {
+		return err
+	}
+
+	allowed, err := processor.tpcCore.Allowed(ctx, tpc, usr.ID, topic.RightSubscribe)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return errSubscribeDenied
+	}
+
+	_, err = processor.subCore.Create(ctx, subscription.NewSubscription{
+		TopicID: tpc.ID,
+		UserID:  usr.ID,
+	})
+
+	return err
+}
+
+// rowError returns why a row failed without the details of the lookups.
+func rowError(err error) string {
+	for _, target := range []error{user.ErrNotFound, topic.ErrNotFound, errSubscribeDenied} {
+		if errors.Is(err, target) {
+			return target.Error()
+		}
+	}
+
+	return err.Error()
+}
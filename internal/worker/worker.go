@@ -2,18 +2,22 @@
 package worker
 
 import (
+	"time"
+
+	"github.com/dudakovict/gotify/internal/core/group"
 	"github.com/dudakovict/gotify/internal/core/notification"
-	notificationdb "github.com/dudakovict/gotify/internal/core/notification/store"
+	"github.com/dudakovict/gotify/internal/core/session"
+	"github.com/dudakovict/gotify/internal/core/subscription"
 	"github.com/dudakovict/gotify/internal/core/topic"
-	topicdb "github.com/dudakovict/gotify/internal/core/topic/store"
 	"github.com/dudakovict/gotify/internal/core/user"
-	userdb "github.com/dudakovict/gotify/internal/core/user/store"
 	"github.com/dudakovict/gotify/internal/core/verification"
-	verificationdb "github.com/dudakovict/gotify/internal/core/verification/store"
+	"github.com/dudakovict/gotify/internal/stores"
 	"github.com/dudakovict/gotify/pkg/mailer"
+	"github.com/dudakovict/gotify/pkg/signer"
+	"github.com/dudakovict/gotify/pkg/sms"
+	"github.com/dudakovict/gotify/pkg/webhook"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 )
 
@@ -25,9 +29,27 @@ const (
 // Config represents the configuration needed to create a Worker instance.
 type Config struct {
 	Log      *zerolog.Logger
-	DB       *sqlx.DB
+	Stores   stores.Stores
 	RedisOpt asynq.RedisClientOpt
 	Mailer   mailer.Mailer
+	Webhook  webhook.Sender
+	SMS      sms.Sender
+	Signer   *signer.Signer
+
+	// PublicURL is the base URL the api is reached at, which the links in
+	// the emails point to.
+	PublicURL string
+
+	// DeletedRetention is how long deleted rows are kept, and can be
+	// restored, before they are purged.
+	DeletedRetention time.Duration
+
+	// NotificationRetention is how long notifications are kept in topics
+	// without a retention of their own. Zero keeps them forever.
+	NotificationRetention time.Duration
+
+	// PurgeBatchSize is how many rows are removed at a time when purging.
+	PurgeBatchSize int
 }
 
 // Worker contains configuration for task distribution and processing.
@@ -38,13 +60,53 @@ type Worker struct {
 
 // New initializes and returns a new Worker instance.
 func New(cfg Config) *Worker {
-	usrCore := user.NewCore(cfg.Log, userdb.NewStore(cfg.Log, cfg.DB))
-	vrfCore := verification.NewCore(cfg.Log, usrCore, verificationdb.NewStore(cfg.Log, cfg.DB))
-	ntfCore := notification.NewCore(cfg.Log, notificationdb.NewStore(cfg.Log, cfg.DB))
-	tpcCore := topic.NewCore(cfg.Log, topicdb.NewStore(cfg.Log, cfg.DB), uuid.New)
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	sessCore := session.NewCore(cfg.Log, cfg.Stores.Session)
+	subCore := subscription.NewCore(cfg.Log, cfg.Stores.Subscription)
+	vrfCore := verification.NewCore(cfg.Log, usrCore, subCore, cfg.Stores.Verification)
+	ntfCore := notification.NewCore(cfg.Log, cfg.Stores.Notification)
+	tpcCore := topic.NewCore(cfg.Log, cfg.Stores.Topic, uuid.New)
+	grpCore := group.NewCore(cfg.Log, cfg.Stores.Group)
 
 	return &Worker{
 		TaskDistributor: newRedisTaskDistributor(cfg.Log, cfg.RedisOpt),
-		TaskProcessor:   newRedisTaskProcessor(cfg.RedisOpt, usrCore, vrfCore, ntfCore, tpcCore, cfg.Mailer),
+		TaskProcessor:   newRedisTaskProcessor(cfg.RedisOpt, usrCore, sessCore, vrfCore, ntfCore, tpcCore, subCore, grpCore, cfg.Mailer, cfg.Webhook, cfg.SMS, cfg.Signer, cfg.PublicURL, cfg.DeletedRetention, cfg.NotificationRetention, cfg.PurgeBatchSize),
+	}
+}
+
+// NewInProcess initializes and returns a new Worker instance processing the
+// tasks in the same process, without Redis.
+func NewInProcess(cfg Config) *Worker {
+	usrCore := user.NewCore(cfg.Log, cfg.Stores.User)
+	sessCore := session.NewCore(cfg.Log, cfg.Stores.Session)
+	subCore := subscription.NewCore(cfg.Log, cfg.Stores.Subscription)
+	vrfCore := verification.NewCore(cfg.Log, usrCore, subCore, cfg.Stores.Verification)
+	ntfCore := notification.NewCore(cfg.Log, cfg.Stores.Notification)
+	tpcCore := topic.NewCore(cfg.Log, cfg.Stores.Topic, uuid.New)
+	grpCore := group.NewCore(cfg.Log, cfg.Stores.Group)
+
+	processor := &RedisTaskProcessor{
+		usrCore:               usrCore,
+		sessCore:              sessCore,
+		vrfCore:               vrfCore,
+		ntfCore:               ntfCore,
+		tpcCore:               tpcCore,
+		subCore:               subCore,
+		grpCore:               grpCore,
+		mailer:                cfg.Mailer,
+		webhook:               cfg.Webhook,
+		sms:                   cfg.SMS,
+		signer:                cfg.Signer,
+		publicURL:             cfg.PublicURL,
+		deletedRetention:      cfg.DeletedRetention,
+		notificationRetention: cfg.NotificationRetention,
+		purgeBatchSize:        cfg.PurgeBatchSize,
+	}
+
+	queue := newInProcessQueue(cfg.Log, processor)
+
+	return &Worker{
+		TaskDistributor: queue,
+		TaskProcessor:   queue,
 	}
 }
@@ -8,6 +8,8 @@ import (
 )
 
 type DB struct {
+	Driver       string `mapstructure:"DB_DRIVER"`
+	SQLitePath   string `mapstructure:"SQLITE_PATH"`
 	User         string `mapstructure:"DB_USER"`
 	Password     string `mapstructure:"DB_PASSWORD"`
 	Host         string `mapstructure:"DB_HOST"`
@@ -15,12 +17,39 @@ type DB struct {
 	MaxIdleConns int    `mapstructure:"MAX_IDLE_CONNS"`
 	MaxOpenConns int    `mapstructure:"MAX_OPEN_CONNS"`
 	DisableTLS   bool   `mapstructure:"DISABLE_TLS"`
+
+	// DisableAutoMigrate stops the app from migrating the database when it
+	// starts, leaving it to gotify migrate.
+	DisableAutoMigrate bool `mapstructure:"DISABLE_AUTO_MIGRATE"`
 }
 
 type Auth struct {
 	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
 	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
 	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	MFAIssuer            string        `mapstructure:"MFA_ISSUER"`
+	WebAuthnRPID         string        `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnRPName       string        `mapstructure:"WEBAUTHN_RP_NAME"`
+	WebAuthnRPOrigins    []string      `mapstructure:"WEBAUTHN_RP_ORIGINS"`
+
+	// MFAEnforce makes users whose roles grant the mfa:required permission
+	// enroll in MFA before they get a session.
+	MFAEnforce bool `mapstructure:"MFA_ENFORCE"`
+
+	// Deprecated: MFARequireAdmin is the former name of MFAEnforce and is
+	// only read to keep older configurations working.
+	MFARequireAdmin bool `mapstructure:"MFA_REQUIRE_ADMIN"`
+}
+
+type OIDC struct {
+	IssuerURL     string   `mapstructure:"OIDC_ISSUER_URL"`
+	ClientID      string   `mapstructure:"OIDC_CLIENT_ID"`
+	ClientSecret  string   `mapstructure:"OIDC_CLIENT_SECRET"`
+	RedirectURL   string   `mapstructure:"OIDC_REDIRECT_URL"`
+	Scopes        []string `mapstructure:"OIDC_SCOPES"`
+	GroupsClaim   string   `mapstructure:"OIDC_GROUPS_CLAIM"`
+	GroupRoles    []string `mapstructure:"OIDC_GROUP_ROLES"`
+	AutoProvision bool     `mapstructure:"OIDC_AUTO_PROVISION"`
 }
 
 type Mailer struct {
@@ -29,14 +58,38 @@ type Mailer struct {
 	EmailPassword string `mapstructure:"MAILER_EMAIL_PASSWORD"`
 }
 
+type Channels struct {
+	WebhookTimeout   time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	TwilioAccountSID string        `mapstructure:"TWILIO_ACCOUNT_SID"`
+	TwilioAuthToken  string        `mapstructure:"TWILIO_AUTH_TOKEN"`
+	SMSFromNumber    string        `mapstructure:"SMS_FROM_NUMBER"`
+}
+
+type Retention struct {
+	// DeletedRetention is how long deleted users, topics, subscriptions and
+	// notifications are kept before they are purged.
+	DeletedRetention time.Duration `mapstructure:"DELETED_RETENTION"`
+
+	// NotificationRetention is how long notifications are kept in topics
+	// without a retention of their own. Zero keeps them forever.
+	NotificationRetention time.Duration `mapstructure:"NOTIFICATION_RETENTION"`
+
+	// PurgeBatchSize is how many rows are removed at a time when purging.
+	PurgeBatchSize int `mapstructure:"PURGE_BATCH_SIZE"`
+}
+
 type Config struct {
 	DB                `mapstructure:",squash"`
 	Auth              `mapstructure:",squash"`
+	OIDC              `mapstructure:",squash"`
 	Mailer            `mapstructure:",squash"`
-	RedisHost         string `mapstructure:"REDIS_HOST"`
-	MigrationURL      string `mapstructure:"MIGRATION_URL"`
-	DatabaseURL       string `mapstructure:"DATABASE_URL"`
-	HTTPServerAddress string `mapstructure:"HTTP_SERVER_ADDRESS"`
+	Channels          `mapstructure:",squash"`
+	Retention         `mapstructure:",squash"`
+	RedisHost         string        `mapstructure:"REDIS_HOST"`
+	HTTPServerAddress string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	PublicURL         string        `mapstructure:"PUBLIC_URL"`
+	RequestTimeout    time.Duration `mapstructure:"REQUEST_TIMEOUT"`
+	ShutdownTimeout   time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
 }
 
 func Load(path string) (Config, error) {
@@ -59,5 +112,9 @@ func Load(path string) (Config, error) {
 		return cfg, err
 	}
 
+	if cfg.MFARequireAdmin {
+		cfg.MFAEnforce = true
+	}
+
 	return cfg, nil
 }
@@ -0,0 +1,83 @@
+// Package encrypt provides support for encrypting secrets at rest.
+package encrypt
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aead/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+var (
+	ErrInvalidCiphertext = errors.New("ciphertext is invalid")
+)
+
+// Encrypter encrypts and decrypts values using XChaCha20-Poly1305 with a key
+// derived from the application symmetric key.
+type Encrypter struct {
+	aead cipher.AEAD
+}
+
+// New derives a purpose specific key from the symmetric key and constructs
+// an Encrypter. The purpose makes sure that values encrypted for one use can't
+// be decrypted for another, and that the token key itself is never reused.
+func New(symmetricKey string, purpose string) (*Encrypter, error) {
+	if len(symmetricKey) != chacha20poly1305.KeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+	kdf := hkdf.New(sha256.New, []byte(symmetricKey), nil, []byte(purpose))
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewXCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+
+	return &Encrypter{
+		aead: aead,
+	}, nil
+}
+
+// Encrypt encrypts the plaintext and returns it base64 encoded together with
+// its nonce.
+func (e *Encrypter) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("read nonce: %w", err)
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, plaintext, nil)
+
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt.
+func (e *Encrypter) Decrypt(ciphertext string) ([]byte, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	if len(sealed) < e.aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, sealed := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+
+	plaintext, err := e.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,37 @@
+package encrypt
+
+import (
+	"testing"
+
+	"github.com/dudakovict/gotify/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypter(t *testing.T) {
+	key := util.RandomString(32)
+
+	enc, err := New(key, "test")
+	require.NoError(t, err)
+
+	plaintext := []byte(util.RandomString(20))
+
+	ciphertext, err := enc.Encrypt(plaintext)
+	require.NoError(t, err)
+	require.NotContains(t, ciphertext, string(plaintext))
+
+	decrypted, err := enc.Decrypt(ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, decrypted)
+
+	other, err := New(key, "other")
+	require.NoError(t, err)
+
+	_, err = other.Decrypt(ciphertext)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestInvalidKeySize(t *testing.T) {
+	enc, err := New(util.RandomString(31), "test")
+	require.Error(t, err)
+	require.Nil(t, enc)
+}
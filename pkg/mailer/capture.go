@@ -0,0 +1,84 @@
+package mailer
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Email is an email captured by the CaptureMailer.
+type Email struct {
+	Subject string            `json:"subject"`
+	Content string            `json:"content"`
+	To      []string          `json:"to"`
+	Cc      []string          `json:"cc,omitempty"`
+	Bcc     []string          `json:"bcc,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	SentAt  time.Time         `json:"sent_at"`
+}
+
+// CaptureMailer is an implementation of the Mailer interface that keeps
+// the emails in memory instead of sending them.
+type CaptureMailer struct {
+	mu     sync.Mutex
+	emails []Email
+}
+
+// NewCaptureMailer creates a new CaptureMailer instance.
+func NewCaptureMailer() *CaptureMailer {
+	return &CaptureMailer{}
+}
+
+func (mailer *CaptureMailer) SendEmail(
+	subject string,
+	content string,
+	to []string,
+	cc []string,
+	bcc []string,
+	attachFiles []string,
+) error {
+	mailer.capture(Email{
+		Subject: subject,
+		Content: content,
+		To:      slices.Clone(to),
+		Cc:      slices.Clone(cc),
+		Bcc:     slices.Clone(bcc),
+		SentAt:  time.Now(),
+	})
+
+	return nil
+}
+
+// SendEmailWithHeaders captures an email carrying extra headers.
+func (mailer *CaptureMailer) SendEmailWithHeaders(
+	subject string,
+	content string,
+	to []string,
+	headers map[string]string,
+) error {
+	mailer.capture(Email{
+		Subject: subject,
+		Content: content,
+		To:      slices.Clone(to),
+		Headers: maps.Clone(headers),
+		SentAt:  time.Now(),
+	})
+
+	return nil
+}
+
+// Emails returns the captured emails, oldest first.
+func (mailer *CaptureMailer) Emails() []Email {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	return slices.Clone(mailer.emails)
+}
+
+func (mailer *CaptureMailer) capture(e Email) {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	mailer.emails = append(mailer.emails, e)
+}
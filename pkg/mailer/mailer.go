@@ -23,6 +23,12 @@ type Mailer interface {
 		bcc []string,
 		attachFiles []string,
 	) error
+	SendEmailWithHeaders(
+		subject string,
+		content string,
+		to []string,
+		headers map[string]string,
+	) error
 }
 
 // GmailMailer is an implementation of the Mailer interface that
@@ -50,14 +56,9 @@ func (mailer *GmailMailer) SendEmail(
 	bcc []string,
 	attachFiles []string,
 ) error {
-	e := &email.Email{
-		From:    fmt.Sprintf("%s <%s>", mailer.name, mailer.fromEmailAddress),
-		Subject: subject,
-		HTML:    []byte(content),
-		To:      to,
-		Cc:      cc,
-		Bcc:     bcc,
-	}
+	e := mailer.newEmail(subject, content, to)
+	e.Cc = cc
+	e.Bcc = bcc
 
 	for _, f := range attachFiles {
 		_, err := e.AttachFile(f)
@@ -66,6 +67,37 @@ func (mailer *GmailMailer) SendEmail(
 		}
 	}
 
+	return mailer.send(e)
+}
+
+// SendEmailWithHeaders sends an email carrying extra headers, like the ones
+// for unsubscribing from mailing lists.
+func (mailer *GmailMailer) SendEmailWithHeaders(
+	subject string,
+	content string,
+	to []string,
+	headers map[string]string,
+) error {
+	e := mailer.newEmail(subject, content, to)
+
+	for key, value := range headers {
+		e.Headers.Set(key, value)
+	}
+
+	return mailer.send(e)
+}
+
+func (mailer *GmailMailer) newEmail(subject string, content string, to []string) *email.Email {
+	e := email.NewEmail()
+	e.From = fmt.Sprintf("%s <%s>", mailer.name, mailer.fromEmailAddress)
+	e.Subject = subject
+	e.HTML = []byte(content)
+	e.To = to
+
+	return e
+}
+
+func (mailer *GmailMailer) send(e *email.Email) error {
 	smtpAuth := smtp.PlainAuth("", mailer.fromEmailAddress, mailer.fromEmailPassword, smtpAuthAddress)
 
 	return e.Send(smtpServerAddress, smtpAuth)
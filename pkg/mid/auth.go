@@ -1,15 +1,21 @@
 package mid
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
+	"github.com/dudakovict/gotify/internal/core/apikey"
+	"github.com/dudakovict/gotify/internal/core/user"
 	"github.com/dudakovict/gotify/pkg/maker"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 // Authenticate authenticates user's payload and stores it in the context.
-func Authenticate(maker maker.Maker, accessibleRoles []string) fiber.Handler {
+// When keyCore is set api keys are accepted in place of PASETO tokens, and
+// the key is stored in the context as well.
+func Authenticate(maker maker.Maker, keyCore *apikey.Core) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get(authHeaderKey)
 		if len(authHeader) == 0 {
@@ -33,38 +39,93 @@ func Authenticate(maker maker.Maker, accessibleRoles []string) fiber.Handler {
 		}
 
 		accessToken := fields[1]
-		payload, err := maker.VerifyToken(accessToken)
+		payload, err := verifyToken(c, maker, keyCore, accessToken)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
 
-		if !hasPermission(payload.Roles, accessibleRoles) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "unauthorized",
+		c.Locals(AuthPayloadKey, payload)
+		return c.Next()
+	}
+}
+
+// RequireScope only lets through api keys granted the scope. Requests
+// authenticated with PASETO tokens aren't affected.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := c.Locals(APIKeyKey).(apikey.APIKey)
+		if ok && !key.HasScope(scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": fmt.Sprintf("api key is missing scope %s", scope),
 			})
 		}
 
-		c.Locals(AuthPayloadKey, payload)
 		return c.Next()
 	}
 }
 
-func hasPermission(userRoles []string, accessibleRoles []string) bool {
-	for _, role := range accessibleRoles {
-		roleFound := false
-		for _, userRole := range userRoles {
-			if role == userRole {
-				roleFound = true
-				break
-			}
-		}
+// KeyAllows reports whether the request may use the scope on the topic. Only
+// requests authenticated with an api key are limited to topics.
+func KeyAllows(c *fiber.Ctx, scope string, topicID uuid.UUID) bool {
+	key, ok := c.Locals(APIKeyKey).(apikey.APIKey)
+	return !ok || key.Allows(scope, topicID)
+}
 
-		if !roleFound {
-			return false
+// KeyTopics returns the topics the request may use the scope on, or nil when
+// it isn't limited to topics. Only requests authenticated with an api key are
+// limited to topics.
+func KeyTopics(c *fiber.Ctx, scope string) []uuid.UUID {
+	key, ok := c.Locals(APIKeyKey).(apikey.APIKey)
+	if !ok {
+		return nil
+	}
+	return key.Topics(scope)
+}
+
+// DenyAPIKeys rejects requests authenticated with an api key.
+func DenyAPIKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals(APIKeyKey).(apikey.APIKey); ok {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "api keys are not accepted",
+			})
 		}
+
+		return c.Next()
+	}
+}
+
+func verifyToken(c *fiber.Ctx, tokenMaker maker.Maker, keyCore *apikey.Core, token string) (*maker.Payload, error) {
+	if apikey.IsToken(token) {
+		return authenticateKey(c, keyCore, token)
+	}
+
+	return tokenMaker.VerifyToken(token)
+}
+
+// authenticateKey verifies an api key and builds a payload for its owner. Keys
+// only act with the USER role whatever the roles of their owner.
+func authenticateKey(c *fiber.Ctx, keyCore *apikey.Core, token string) (*maker.Payload, error) {
+	if keyCore == nil {
+		return nil, errors.New("api keys are not accepted")
+	}
+
+	key, err := keyCore.Authenticate(c.UserContext(), token)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Locals(APIKeyKey, key)
+
+	payload := maker.Payload{
+		ID:        key.ID,
+		UserID:    key.UserID,
+		Roles:     []string{user.RoleUser.Name()},
+		IssuedAt:  key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
 	}
 
-	return true
+	return &payload, nil
 }
@@ -0,0 +1,21 @@
+package mid
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Deadline gives every request the timeout to finish in. The request's user
+// context carries the deadline, so database queries and other work done for
+// the request are cancelled once it passes, or once the base context is done.
+func Deadline(base context.Context, timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(base, timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
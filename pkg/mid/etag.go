@@ -0,0 +1,68 @@
+package mid
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Set of error variables for conditional requests.
+var (
+	ErrPreconditionRequired = errors.New("request must send the ETag of the resource in If-Match")
+	ErrPreconditionFailed   = errors.New("resource was changed since it was read")
+)
+
+// SetETag sets the ETag header of the response to the version of what it
+// returns.
+func SetETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, etag(version))
+}
+
+// IfMatch checks the If-Match header of the request against the version of
+// what it changes. It fails with ErrPreconditionRequired when the header is
+// missing, so no change can overwrite an edit it didn't see, and with
+// ErrPreconditionFailed when no tag matches. Weak and unquoted tags are
+// compared by their version, as some clients and proxies rewrite the ETag
+// they got.
+func IfMatch(c *fiber.Ctx, version int) error {
+	header := c.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return ErrPreconditionRequired
+	}
+
+	want := strconv.Itoa(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || opaque(tag) == want {
+			return nil
+		}
+	}
+
+	return ErrPreconditionFailed
+}
+
+// PreconditionStatus returns the status code to respond with when IfMatch
+// fails with the error.
+func PreconditionStatus(err error) int {
+	if errors.Is(err, ErrPreconditionRequired) {
+		return fiber.StatusPreconditionRequired
+	}
+
+	return fiber.StatusPreconditionFailed
+}
+
+// opaque strips the weak prefix and the quotes of an entity tag.
+func opaque(tag string) string {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) >= 2 && strings.HasPrefix(tag, `"`) && strings.HasSuffix(tag, `"`) {
+		tag = tag[1 : len(tag)-1]
+	}
+
+	return tag
+}
+
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
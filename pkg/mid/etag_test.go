@@ -0,0 +1,63 @@
+package mid
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetETag(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		SetETag(c, 3)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	require.Equal(t, `"3"`, resp.Header.Get(fiber.HeaderETag))
+}
+
+func TestIfMatch(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "Missing", header: "", want: fiber.StatusPreconditionRequired},
+		{name: "Any", header: "*", want: fiber.StatusNoContent},
+		{name: "Quoted", header: `"3"`, want: fiber.StatusNoContent},
+		{name: "Unquoted", header: "3", want: fiber.StatusNoContent},
+		{name: "Weak", header: `W/"3"`, want: fiber.StatusNoContent},
+		{name: "List", header: `"1", W/"3"`, want: fiber.StatusNoContent},
+		{name: "Stale", header: `"2"`, want: fiber.StatusPreconditionFailed},
+		{name: "StaleUnquoted", header: "2", want: fiber.StatusPreconditionFailed},
+		{name: "StaleWeak", header: `W/"2"`, want: fiber.StatusPreconditionFailed},
+		{name: "StaleList", header: `"1", "2"`, want: fiber.StatusPreconditionFailed},
+		{name: "Prefix", header: `"33"`, want: fiber.StatusPreconditionFailed},
+		{name: "Garbage", header: `"`, want: fiber.StatusPreconditionFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Put("/", func(c *fiber.Ctx) error {
+				if err := IfMatch(c, 3); err != nil {
+					return c.SendStatus(PreconditionStatus(err))
+				}
+				return c.SendStatus(fiber.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(fiber.MethodPut, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(fiber.HeaderIfMatch, tc.header)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, resp.StatusCode)
+		})
+	}
+}
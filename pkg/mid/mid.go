@@ -6,4 +6,6 @@ const (
 	authTypeBearer = "bearer"
 	AuthPayloadKey = "authorization_payload"
 	UserKey        = "user"
+	APIKeyKey      = "api_key"
+	PermissionsKey = "permissions"
 )
@@ -0,0 +1,82 @@
+package mid
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/user"
+	"github.com/dudakovict/gotify/pkg/maker"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Authorize resolves the permissions of the request and stores them in the
+// context. Only roles both carried by the token and still held by the user
+// count, so tokens issued before a role was revoked lose it and tokens
+// carrying no real role, like mfa challenge tokens, are rejected. It has to
+// run after GetUser.
+func Authorize(roleCore *role.Core) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		payload, ok := c.Locals(AuthPayloadKey).(*maker.Payload)
+		if !ok {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to get user payload",
+			})
+		}
+
+		usr, ok := c.Locals(UserKey).(user.User)
+		if !ok {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to get user",
+			})
+		}
+
+		var roles []string
+		for _, r := range usr.Roles {
+			if slices.Contains(payload.Roles, r.Name()) {
+				roles = append(roles, r.Name())
+			}
+		}
+
+		if len(roles) == 0 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "unauthorized",
+			})
+		}
+
+		perms, err := roleCore.Permissions(c.UserContext(), roles)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		c.Locals(PermissionsKey, perms)
+		return c.Next()
+	}
+}
+
+// Require only lets through requests granted all the permissions. It has to
+// run after Authorize.
+func Require(perms ...role.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		set, _ := c.Locals(PermissionsKey).(role.Set)
+
+		for _, perm := range perms {
+			if !set.Has(perm) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": fmt.Sprintf("missing permission %s", perm.Name()),
+				})
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// Can reports whether the request was granted the permission. Handlers use it
+// for operations which are allowed on own resources without the permission.
+func Can(c *fiber.Ctx, perm role.Permission) bool {
+	set, _ := c.Locals(PermissionsKey).(role.Set)
+	return set.Has(perm)
+}
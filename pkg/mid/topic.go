@@ -0,0 +1,18 @@
+package mid
+
+import (
+	"github.com/dudakovict/gotify/internal/core/role"
+	"github.com/dudakovict/gotify/internal/core/topic"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// TopicAllows reports whether the user has the right on the topic. Requests
+// granted the topic:manage permission have every right on every topic.
+func TopicAllows(c *fiber.Ctx, tpcCore *topic.Core, tpc topic.Topic, userID uuid.UUID, right topic.Right) (bool, error) {
+	if Can(c, role.PermTopicManage) {
+		return true, nil
+	}
+
+	return tpcCore.Allowed(c.UserContext(), tpc, userID, right)
+}
@@ -18,7 +18,7 @@ func GetUser(usrCore *user.Core) fiber.Handler {
 			})
 		}
 
-		usr, err := usrCore.QueryByID(payload.UserID)
+		usr, err := usrCore.QueryByID(c.UserContext(), payload.UserID)
 		if err != nil {
 			if errors.Is(err, user.ErrNotFound) {
 				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -0,0 +1,70 @@
+// Package signer provides support for signing values handed out in links, so
+// they can be trusted when they come back.
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const keySize = 32
+
+var (
+	ErrInvalidSignature = errors.New("signature is invalid")
+)
+
+// Signer signs and verifies values using HMAC-SHA256 with a key derived from
+// the application symmetric key.
+type Signer struct {
+	key []byte
+}
+
+// New derives a purpose specific key from the symmetric key and constructs a
+// Signer. The purpose makes sure that signatures made for one use aren't valid
+// for another, and that the token key itself is never reused.
+func New(symmetricKey string, purpose string) (*Signer, error) {
+	if len(symmetricKey) != keySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", keySize)
+	}
+
+	key := make([]byte, keySize)
+	kdf := hkdf.New(sha256.New, []byte(symmetricKey), nil, []byte(purpose))
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	return &Signer{
+		key: key,
+	}, nil
+}
+
+// Sign returns the signature of the value, encoded to be safe in URLs.
+func (s *Signer) Sign(value string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify makes sure the signature was made for the value by this signer.
+func (s *Signer) Verify(value string, signature string) error {
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(value))
+
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/dudakovict/gotify/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner(t *testing.T) {
+	key := util.RandomString(32)
+
+	sgn, err := New(key, "test")
+	require.NoError(t, err)
+
+	value := util.RandomString(20)
+
+	signature := sgn.Sign(value)
+	require.NoError(t, sgn.Verify(value, signature))
+
+	require.ErrorIs(t, sgn.Verify(util.RandomString(20), signature), ErrInvalidSignature)
+	require.ErrorIs(t, sgn.Verify(value, signature[1:]), ErrInvalidSignature)
+	require.ErrorIs(t, sgn.Verify(value, "not base64!"), ErrInvalidSignature)
+
+	other, err := New(key, "other")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, other.Verify(value, signature), ErrInvalidSignature)
+}
+
+func TestInvalidKeySize(t *testing.T) {
+	sgn, err := New(util.RandomString(31), "test")
+	require.Error(t, err)
+	require.Nil(t, sgn)
+}
@@ -0,0 +1,67 @@
+// Package sms provides an interface and a Twilio implementation for sending
+// text messages.
+package sms
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const twilioAPIAddress = "https://api.twilio.com/2010-04-01"
+
+// Sender is the interface that defines the method for sending text messages.
+type Sender interface {
+	SendSMS(to string, content string) error
+}
+
+// TwilioSender is an implementation of the Sender interface that sends text
+// messages through the Twilio API.
+type TwilioSender struct {
+	accountSID string
+	authToken  string
+	fromNumber string
+	client     *http.Client
+}
+
+// NewTwilioSender creates a new TwilioSender instance.
+func NewTwilioSender(accountSID string, authToken string, fromNumber string) Sender {
+	return &TwilioSender{
+		accountSID: accountSID,
+		authToken:  authToken,
+		fromNumber: fromNumber,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (sender *TwilioSender) SendSMS(to string, content string) error {
+	form := url.Values{
+		"To":   {to},
+		"From": {sender.fromNumber},
+		"Body": {content},
+	}
+
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioAPIAddress, sender.accountSID)
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(sender.accountSID, sender.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := sender.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send sms to %s: %w", to, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("twilio answered with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package totp provides support for RFC 6238 time-based one-time passwords.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Set of parameters used for generating codes. These are the defaults
+// understood by every common authenticator app.
+const (
+	Digits = 6
+	Period = 30
+	Skew   = 1
+
+	secretSize = 20
+)
+
+var (
+	ErrInvalidSecret = errors.New("totp secret is invalid")
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a new random base32 encoded secret.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// Step returns the time step the specified time falls into.
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code generates the code for the specified secret and time.
+func Code(secret string, t time.Time) (string, error) {
+	return code(secret, Step(t))
+}
+
+// Validate checks the code against the specified secret and time allowing
+// for a clock skew of one step in both directions. It returns the step the
+// code matched so callers can reject reuse of the same code.
+func Validate(secret string, passcode string, t time.Time) (int64, bool, error) {
+	passcode = strings.TrimSpace(passcode)
+	if len(passcode) != Digits {
+		return 0, false, nil
+	}
+
+	current := Step(t)
+	for step := current - Skew; step <= current+Skew; step++ {
+		expected, err := code(secret, step)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if hmac.Equal([]byte(expected), []byte(passcode)) {
+			return step, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// URI returns the otpauth provisioning URI understood by authenticator apps.
+func URI(issuer string, account string, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(Period))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+
+	return u.String()
+}
+
+func code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// secret is the RFC 6238 SHA1 test key "12345678901234567890".
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(secret, time.Unix(tc.unix, 0))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	code, err := Code(secret, now.Add(-Period*time.Second))
+	require.NoError(t, err)
+
+	step, ok, err := Validate(secret, code, now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, Step(now)-1, step)
+
+	code, err = Code(secret, now.Add(-2*Period*time.Second))
+	require.NoError(t, err)
+
+	_, ok, err = Validate(secret, code, now)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = Validate("not base32!", "123456", now)
+	require.ErrorIs(t, err, ErrInvalidSecret)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	s, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, s, 32)
+
+	_, err = Code(s, time.Now())
+	require.NoError(t, err)
+
+	uri := URI("Gotify", "user@email.com", s)
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/Gotify:user@email.com?"))
+	require.Contains(t, uri, "secret="+s)
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"errors"
+
+	"github.com/dudakovict/gotify/platform/cursor"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrCursorWithPage is returned when a cursor is given along with a page
+// number or an order, which cursors don't support.
+var ErrCursorWithPage = errors.New("cursor can't be combined with page or order_by")
+
+// CursorPage represents the requested cursor and rows per page.
+type CursorPage struct {
+	Cursor      cursor.Cursor
+	RowsPerPage int
+}
+
+// UsesCursor reports whether the request asks for a page by cursor rather
+// than by number. The first page is asked for with an empty cursor.
+func UsesCursor(c *fiber.Ctx) bool {
+	return c.Context().QueryArgs().Has("cursor")
+}
+
+// ParseCursor parses the request for the cursor and rows query string.
+func ParseCursor(c *fiber.Ctx) (CursorPage, error) {
+	if c.Query("page") != "" || c.Query("order_by") != "" {
+		return CursorPage{}, ErrCursorWithPage
+	}
+
+	cur, err := cursor.Decode(c.Query("cursor"))
+	if err != nil {
+		return CursorPage{}, err
+	}
+
+	rowsPerPage, err := parseRows(c)
+	if err != nil {
+		return CursorPage{}, err
+	}
+
+	p := CursorPage{
+		Cursor:      cur,
+		RowsPerPage: rowsPerPage,
+	}
+
+	return p, nil
+}
+
+// CursorResponse is the envelope of a page of items read by cursor, along
+// with the cursors of the next and the previous page. A cursor is left out
+// when there is no such page.
+type CursorResponse[T any] struct {
+	Items       []T    `json:"items"`
+	Next        string `json:"next,omitempty"`
+	Prev        string `json:"prev,omitempty"`
+	RowsPerPage int    `json:"rows_per_page"`
+}
+
+// NewCursorResponse constructs the envelope of the page of items.
+func NewCursorResponse[T any](items []T, page cursor.Page, rowsPerPage int) CursorResponse[T] {
+	return CursorResponse[T]{
+		Items:       items,
+		Next:        page.Next.Encode(),
+		Prev:        page.Prev.Encode(),
+		RowsPerPage: rowsPerPage,
+	}
+}
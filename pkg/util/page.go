@@ -1,11 +1,19 @@
 package util
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// MaxRowsPerPage is the most rows a page can have.
+const MaxRowsPerPage = 100
+
+// ErrInvalidPage is returned when the page or the rows per page are out of
+// range.
+var ErrInvalidPage = errors.New("page has to be at least 1 and rows between 1 and 100")
+
 // Page represents the requested page and rows per page.
 type Page struct {
 	Number      int
@@ -24,19 +32,56 @@ func Parse(c *fiber.Ctx) (Page, error) {
 		}
 	}
 
+	rowsPerPage, err := parseRows(c)
+	if err != nil {
+		return Page{}, err
+	}
+
+	if number < 1 {
+		return Page{}, ErrInvalidPage
+	}
+
+	p := Page{
+		Number:      number,
+		RowsPerPage: rowsPerPage,
+	}
+
+	return p, nil
+}
+
+// parseRows parses the request for the rows query string, 10 by default.
+func parseRows(c *fiber.Ctx) (int, error) {
 	var rowsPerPage int
 	if rows := c.Query("rows", "10"); rows != "" {
 		var err error
 		rowsPerPage, err = strconv.Atoi(rows)
 		if err != nil {
-			return Page{}, err
+			return 0, err
 		}
 	}
 
-	p := Page{
-		Number:      number,
-		RowsPerPage: rowsPerPage,
+	if rowsPerPage < 1 || rowsPerPage > MaxRowsPerPage {
+		return 0, ErrInvalidPage
 	}
 
-	return p, nil
+	return rowsPerPage, nil
+}
+
+// PageResponse is the envelope of a page of items, along with the total
+// number of items across all pages.
+type PageResponse[T any] struct {
+	Items       []T `json:"items"`
+	Total       int `json:"total"`
+	Page        int `json:"page"`
+	RowsPerPage int `json:"rows_per_page"`
+}
+
+// NewPageResponse constructs the envelope of the page of items.
+func NewPageResponse[T any](items []T, total int, page Page) PageResponse[T] {
+	return PageResponse[T]{
+		Items:       items,
+		Total:       total,
+		Page:        page.Number,
+		RowsPerPage: page.RowsPerPage,
+	}
 }
@@ -0,0 +1,109 @@
+// Package webhook provides an interface and an HTTP implementation for
+// posting notifications to webhooks.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a webhook resolves to an address on
+// the host or the internal network, which the worker won't post to.
+var ErrForbiddenAddress = errors.New("webhook address is not allowed")
+
+// Sender is the interface that defines the method for posting to webhooks.
+type Sender interface {
+	Send(url string, payload any) error
+}
+
+// HTTPSender is an implementation of the Sender interface that posts the
+// payload as JSON.
+type HTTPSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender creates a new HTTPSender instance giving up on webhooks that
+// don't answer within the timeout.
+//
+// Webhook addresses are set by users, so the addresses they resolve to are
+// checked when connecting, redirects included, and loopback, link-local,
+// private and unspecified addresses are refused.
+func NewHTTPSender(timeout time.Duration) Sender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: checkAddress,
+	}
+
+	return &HTTPSender{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+func (sender *HTTPSender) Send(url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	resp, err := sender.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to post to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s answered with status %d", url, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// checkAddress refuses connections to addresses that aren't public. It runs
+// once the host is resolved, so names pointing at internal addresses are
+// caught too.
+func checkAddress(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	if !Public(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	return nil
+}
+
+// Public reports whether the address is one webhooks may be posted to.
+func Public(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	switch {
+	case !addr.IsValid(),
+		addr.IsUnspecified(),
+		addr.IsLoopback(),
+		addr.IsPrivate(),
+		addr.IsLinkLocalUnicast(),
+		addr.IsLinkLocalMulticast(),
+		addr.IsInterfaceLocalMulticast(),
+		addr.IsMulticast():
+		return false
+	}
+
+	return true
+}
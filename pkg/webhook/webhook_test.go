@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublic(t *testing.T) {
+	forbidden := []string{
+		"127.0.0.1",
+		"::1",
+		"0.0.0.0",
+		"::",
+		"10.1.2.3",
+		"172.16.0.1",
+		"192.168.1.1",
+		"169.254.169.254",
+		"fe80::1",
+		"fd00::1",
+		"::ffff:127.0.0.1",
+		"::ffff:169.254.169.254",
+	}
+	for _, addr := range forbidden {
+		require.False(t, Public(netip.MustParseAddr(addr)), addr)
+	}
+
+	allowed := []string{
+		"8.8.8.8",
+		"2606:4700:4700::1111",
+	}
+	for _, addr := range allowed {
+		require.True(t, Public(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestSendForbiddenAddress(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	sender := NewHTTPSender(time.Second)
+
+	err := sender.Send(srv.URL, map[string]string{"message": "test"})
+	require.ErrorIs(t, err, ErrForbiddenAddress)
+	require.False(t, called)
+}
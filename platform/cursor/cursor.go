@@ -0,0 +1,140 @@
+// Package cursor provides support for keyset pagination of lists ordered by
+// creation time, the newest first.
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a cursor can't be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a row of a list ordered by creation time and ID, the newest
+// first. A page read with the cursor holds the rows after the mark, or the
+// rows before it when read backward. The zero Cursor reads the first page.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Backward  bool
+}
+
+// New constructs a cursor reading the rows after the row with the creation
+// time and ID.
+func New(createdAt time.Time, id uuid.UUID) Cursor {
+	return Cursor{
+		CreatedAt: createdAt,
+		ID:        id,
+	}
+}
+
+// IsZero reports whether the cursor reads the first page.
+func (c Cursor) IsZero() bool {
+	return c.ID == uuid.Nil
+}
+
+// Reverse returns the cursor reading in the other direction from the same
+// row.
+func (c Cursor) Reverse() Cursor {
+	c.Backward = !c.Backward
+	return c
+}
+
+// token is the encoded form of a cursor. It's kept short, as it travels in
+// query strings.
+type token struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// Encode returns the cursor as an opaque string. The zero Cursor encodes as
+// an empty string.
+func (c Cursor) Encode() string {
+	if c.IsZero() {
+		return ""
+	}
+
+	data, err := json.Marshal(token(c))
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode parses a cursor encoded with Encode. An empty string decodes as the
+// zero Cursor.
+func Decode(value string) (Cursor, error) {
+	if value == "" {
+		return Cursor{}, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var t token
+	if err := json.Unmarshal(data, &t); err != nil || t.ID == uuid.Nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor(t), nil
+}
+
+// =============================================================================
+
+// Page holds the cursors of the pages next to a page of rows. A cursor is
+// zero when there is no such page.
+type Page struct {
+	Next Cursor
+	Prev Cursor
+}
+
+// Paginate turns the rows read with the cursor into a page of at most
+// rowsPerPage rows, the newest first, and finds the cursors of the pages next
+// to it. The rows are expected as the store reads them: in the direction of
+// the cursor, with one row more than the page holds when another page
+// follows. Mark returns the creation time and ID of a row.
+func Paginate[T any](rows []T, cur Cursor, rowsPerPage int, mark func(row T) (time.Time, uuid.UUID)) ([]T, Page) {
+	more := len(rows) > rowsPerPage
+	if more {
+		rows = rows[:rowsPerPage]
+	}
+
+	if cur.Backward {
+		slices.Reverse(rows)
+	}
+
+	var page Page
+	if len(rows) == 0 {
+		return rows, page
+	}
+
+	first := New(mark(rows[0]))
+	last := New(mark(rows[len(rows)-1]))
+
+	switch {
+	case cur.Backward:
+		if more {
+			page.Prev = first.Reverse()
+		}
+		page.Next = last
+
+	default:
+		if more {
+			page.Next = last
+		}
+		if !cur.IsZero() {
+			page.Prev = first.Reverse()
+		}
+	}
+
+	return rows, page
+}
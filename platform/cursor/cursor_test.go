@@ -0,0 +1,125 @@
+package cursor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/platform/cursor"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type row struct {
+	createdAt time.Time
+	id        uuid.UUID
+}
+
+func mark(r row) (time.Time, uuid.UUID) {
+	return r.createdAt, r.id
+}
+
+func TestEncode(t *testing.T) {
+	cur := cursor.New(time.Date(2024, 3, 1, 12, 30, 0, 123000, time.UTC), uuid.New())
+
+	for _, c := range []cursor.Cursor{cur, cur.Reverse()} {
+		decoded, err := cursor.Decode(c.Encode())
+		require.NoError(t, err)
+		require.True(t, c.CreatedAt.Equal(decoded.CreatedAt))
+		require.Equal(t, c.ID, decoded.ID)
+		require.Equal(t, c.Backward, decoded.Backward)
+	}
+
+	require.Empty(t, cursor.Cursor{}.Encode())
+
+	decoded, err := cursor.Decode("")
+	require.NoError(t, err)
+	require.True(t, decoded.IsZero())
+
+	for _, value := range []string{"not a cursor", "e30", "bnVsbA"} {
+		_, err := cursor.Decode(value)
+		require.ErrorIs(t, err, cursor.ErrInvalidCursor)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	now := time.Now()
+
+	// Rows of the list, the newest first.
+	rows := make([]row, 5)
+	for i := range rows {
+		rows[i] = row{createdAt: now.Add(-time.Duration(i) * time.Minute), id: uuid.New()}
+	}
+
+	reversed := func(rs []row) []row {
+		out := make([]row, len(rs))
+		for i, r := range rs {
+			out[len(rs)-1-i] = r
+		}
+		return out
+	}
+
+	at := func(i int) cursor.Cursor {
+		return cursor.New(mark(rows[i]))
+	}
+
+	testCases := []struct {
+		name  string
+		read  []row
+		cur   cursor.Cursor
+		items []row
+		page  cursor.Page
+	}{
+		{
+			name:  "FirstPage",
+			read:  rows[0:3],
+			items: rows[0:2],
+			page:  cursor.Page{Next: at(1)},
+		},
+		{
+			name:  "MiddlePage",
+			read:  rows[2:5],
+			cur:   at(1),
+			items: rows[2:4],
+			page:  cursor.Page{Next: at(3), Prev: at(2).Reverse()},
+		},
+		{
+			name:  "LastPage",
+			read:  rows[4:5],
+			cur:   at(3),
+			items: rows[4:5],
+			page:  cursor.Page{Prev: at(4).Reverse()},
+		},
+		{
+			name:  "BackwardPage",
+			read:  reversed(rows[1:4]),
+			cur:   at(4).Reverse(),
+			items: rows[2:4],
+			page:  cursor.Page{Next: at(3), Prev: at(2).Reverse()},
+		},
+		{
+			name:  "BackwardFirstPage",
+			read:  reversed(rows[0:2]),
+			cur:   at(2).Reverse(),
+			items: rows[0:2],
+			page:  cursor.Page{Next: at(1)},
+		},
+		{
+			name: "Empty",
+			cur:  at(4),
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			items, page := cursor.Paginate(tc.read, tc.cur, 2, mark)
+
+			require.Equal(t, len(tc.items), len(items))
+			for i := range items {
+				require.Equal(t, tc.items[i].id, items[i].id)
+			}
+			require.Equal(t, tc.page, page)
+		})
+	}
+}
@@ -82,7 +82,7 @@ func Ping(ctx context.Context, db *sqlx.DB) error {
 
 	var pingError error
 	for attempts := 1; ; attempts++ {
-		pingError = db.Ping()
+		pingError = db.PingContext(ctx)
 		if pingError == nil {
 			break
 		}
@@ -102,11 +102,12 @@ func Ping(ctx context.Context, db *sqlx.DB) error {
 	return db.QueryRowContext(ctx, q).Scan(&tmp)
 }
 
-// WithinTran runs passed function and do commit/rollback at the end.
-func WithinTran(log *zerolog.Logger, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
+// WithinTran runs passed function and do commit/rollback at the end. The
+// transaction is rolled back when the context is done before the commit.
+func WithinTran(ctx context.Context, log *zerolog.Logger, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
 	log.Info().Msg("begin tran")
 
-	tx, err := db.Beginx()
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin tran: %w", err)
 	}
@@ -124,7 +125,7 @@ func WithinTran(log *zerolog.Logger, db *sqlx.DB, fn func(*sqlx.Tx) error) error
 	}()
 
 	if err := fn(tx); err != nil {
-		if pqerr, ok := err.(*pgconn.PgError); ok && pqerr.Code == uniqueViolation {
+		if isUniqueViolation(err) {
 			return ErrUniqueViolation
 		}
 		return fmt.Errorf("exec tran: %w", err)
@@ -138,21 +139,19 @@ func WithinTran(log *zerolog.Logger, db *sqlx.DB, fn func(*sqlx.Tx) error) error
 	return nil
 }
 
-func NamedExec(log *zerolog.Logger, db sqlx.Ext, query string, data any) (err error) {
+func NamedExec(ctx context.Context, log *zerolog.Logger, db sqlx.ExtContext, query string, data any) (err error) {
 	defer func() {
 		if err != nil {
 			log.Error().Err(err).Str("query", query).Msg("database.NamedExec")
 		}
 	}()
 
-	if _, err := sqlx.NamedExec(db, query, data); err != nil {
-		if pqerr, ok := err.(*pgconn.PgError); ok {
-			switch pqerr.Code {
-			case undefinedTable:
-				return ErrUndefinedTable
-			case uniqueViolation:
-				return ErrUniqueViolation
-			}
+	if _, err := sqlx.NamedExecContext(ctx, db, query, data); err != nil {
+		switch {
+		case isUndefinedTable(err):
+			return ErrUndefinedTable
+		case isUniqueViolation(err):
+			return ErrUniqueViolation
 		}
 		return err
 	}
@@ -160,7 +159,35 @@ func NamedExec(log *zerolog.Logger, db sqlx.Ext, query string, data any) (err er
 	return nil
 }
 
-func NamedQueryStruct(log *zerolog.Logger, db sqlx.Ext, query string, data any, dest any) (err error) {
+// NamedExecCount is like NamedExec, but returns the number of rows the
+// statement affected.
+func NamedExecCount(ctx context.Context, log *zerolog.Logger, db sqlx.ExtContext, query string, data any) (n int, err error) {
+	defer func() {
+		if err != nil {
+			log.Error().Err(err).Str("query", query).Msg("database.NamedExecCount")
+		}
+	}()
+
+	res, err := sqlx.NamedExecContext(ctx, db, query, data)
+	if err != nil {
+		switch {
+		case isUndefinedTable(err):
+			return 0, ErrUndefinedTable
+		case isUniqueViolation(err):
+			return 0, ErrUniqueViolation
+		}
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
+
+func NamedQueryStruct(ctx context.Context, log *zerolog.Logger, db sqlx.ExtContext, query string, data any, dest any) (err error) {
 	defer func() {
 		if err != nil {
 			log.Error().Err(err).Str("query", query).Msg("database.NamedQueryStruct")
@@ -168,9 +195,9 @@ func NamedQueryStruct(log *zerolog.Logger, db sqlx.Ext, query string, data any,
 	}()
 
 	var rows *sqlx.Rows
-	rows, err = sqlx.NamedQuery(db, query, data)
+	rows, err = sqlx.NamedQueryContext(ctx, db, query, data)
 	if err != nil {
-		if pqerr, ok := err.(*pgconn.PgError); ok && pqerr.Code == undefinedTable {
+		if isUndefinedTable(err) {
 			return ErrUndefinedTable
 		}
 		return err
@@ -178,6 +205,9 @@ func NamedQueryStruct(log *zerolog.Logger, db sqlx.Ext, query string, data any,
 	defer rows.Close()
 
 	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
 		return ErrNotFound
 	}
 
@@ -188,7 +218,7 @@ func NamedQueryStruct(log *zerolog.Logger, db sqlx.Ext, query string, data any,
 	return nil
 }
 
-func NamedQuerySlice[T any](log *zerolog.Logger, db sqlx.Ext, query string, data any, dest *[]T) (err error) {
+func NamedQuerySlice[T any](ctx context.Context, log *zerolog.Logger, db sqlx.ExtContext, query string, data any, dest *[]T) (err error) {
 	defer func() {
 		if err != nil {
 			log.Error().Err(err).Str("query", query).Msg("database.NamedQuerySlice")
@@ -196,9 +226,9 @@ func NamedQuerySlice[T any](log *zerolog.Logger, db sqlx.Ext, query string, data
 	}()
 
 	var rows *sqlx.Rows
-	rows, err = sqlx.NamedQuery(db, query, data)
+	rows, err = sqlx.NamedQueryContext(ctx, db, query, data)
 	if err != nil {
-		if pqerr, ok := err.(*pgconn.PgError); ok && pqerr.Code == undefinedTable {
+		if isUndefinedTable(err) {
 			return ErrUndefinedTable
 		}
 		return err
@@ -215,7 +245,32 @@ func NamedQuerySlice[T any](log *zerolog.Logger, db sqlx.Ext, query string, data
 		slice = append(slice, *v)
 	}
 
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
 	*dest = slice
 
 	return nil
 }
+
+// =============================================================================
+
+// isUniqueViolation reports whether the error is a unique constraint
+// violation, raised by Postgres or SQLite.
+func isUniqueViolation(err error) bool {
+	if pqerr, ok := err.(*pgconn.PgError); ok {
+		return pqerr.Code == uniqueViolation
+	}
+
+	return isSQLiteUniqueViolation(err)
+}
+
+// isUndefinedTable reports whether the error is raised for a missing table.
+func isUndefinedTable(err error) bool {
+	if pqerr, ok := err.(*pgconn.PgError); ok {
+		return pqerr.Code == undefinedTable
+	}
+
+	return isSQLiteUndefinedTable(err)
+}
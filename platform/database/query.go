@@ -0,0 +1,162 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dudakovict/gotify/platform/cursor"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// QueryBuilder builds the list queries of the stores. Filters make up the
+// WHERE clause, the order is limited to an allow-list of columns and the page
+// is applied as LIMIT and OFFSET, which Postgres and SQLite both understand.
+// The same filters are used to count the rows of every page.
+type QueryBuilder struct {
+	query   string
+	where   []string
+	data    map[string]any
+	orderBy string
+	page    string
+}
+
+// NewQueryBuilder constructs a builder for the query, which selects the rows
+// without a WHERE clause, like SELECT * FROM users.
+func NewQueryBuilder(query string) *QueryBuilder {
+	return &QueryBuilder{
+		query: query,
+		data:  make(map[string]any),
+	}
+}
+
+// Where adds the condition to the WHERE clause, joined with the others by AND.
+// Named parameters of the condition are bound to the args.
+func (b *QueryBuilder) Where(condition string, args map[string]any) *QueryBuilder {
+	b.where = append(b.where, condition)
+	for name, value := range args {
+		b.data[name] = value
+	}
+
+	return b
+}
+
+// OrderBy orders the rows by the column the field maps to in columns, the
+// allow-list of fields the rows can be ordered by. Rows ordered the same are
+// ordered by the tie column, so pages stay stable.
+func (b *QueryBuilder) OrderBy(by order.By, columns map[string]string, tie string) error {
+	column, exists := columns[by.Field]
+	if !exists {
+		return fmt.Errorf("%w: unknown field %q", order.ErrInvalidOrder, by.Field)
+	}
+
+	if by.Direction != order.ASC && by.Direction != order.DESC {
+		return fmt.Errorf("%w: unknown direction %q", order.ErrInvalidOrder, by.Direction)
+	}
+
+	b.orderBy = fmt.Sprintf("ORDER BY %s %s", column, by.Direction)
+	if tie != "" && tie != column {
+		b.orderBy += fmt.Sprintf(", %s %s", tie, by.Direction)
+	}
+
+	return nil
+}
+
+// Page limits the rows to the page, numbered from 1.
+func (b *QueryBuilder) Page(pageNumber int, rowsPerPage int) *QueryBuilder {
+	b.data["offset"] = (pageNumber - 1) * rowsPerPage
+	b.data["rows_per_page"] = rowsPerPage
+	b.page = "LIMIT :rows_per_page OFFSET :offset"
+
+	return b
+}
+
+// Keyset limits the rows to the page read with the cursor, in a list ordered
+// by the column and the tie column, the newest first. Backward cursors read the
+// rows before the mark, the oldest first. One row more than the page holds is
+// read, so the caller knows whether another page follows. Keyset replaces the
+// order and the page set before.
+func (b *QueryBuilder) Keyset(cur cursor.Cursor, column string, tie string, rowsPerPage int) *QueryBuilder {
+	direction, comparison := order.DESC, "<"
+	if cur.Backward {
+		direction, comparison = order.ASC, ">"
+	}
+
+	if !cur.IsZero() {
+		condition := fmt.Sprintf("(%s, %s) %s (:cursor_created_at, :cursor_id)", column, tie, comparison)
+		b.Where(condition, map[string]any{
+			"cursor_created_at": cur.CreatedAt.UTC(),
+			"cursor_id":         cur.ID,
+		})
+	}
+
+	b.orderBy = fmt.Sprintf("ORDER BY %s %s, %s %s", column, direction, tie, direction)
+	b.data["rows_per_page"] = rowsPerPage + 1
+	delete(b.data, "offset")
+	b.page = "LIMIT :rows_per_page"
+
+	return b
+}
+
+// Query returns the query selecting the rows along with its parameters.
+func (b *QueryBuilder) Query() (string, map[string]any) {
+	parts := []string{b.query}
+	if where := b.whereClause(); where != "" {
+		parts = append(parts, where)
+	}
+	if b.orderBy != "" {
+		parts = append(parts, b.orderBy)
+	}
+	if b.page != "" {
+		parts = append(parts, b.page)
+	}
+
+	return strings.Join(parts, "\n"), b.data
+}
+
+// Count returns the query counting the rows matching the filters, regardless
+// of the page, along with its parameters. The count is returned as count.
+func (b *QueryBuilder) Count() (string, map[string]any) {
+	query := b.query
+	if where := b.whereClause(); where != "" {
+		query += "\n" + where
+	}
+
+	data := make(map[string]any, len(b.data))
+	for name, value := range b.data {
+		if name != "offset" && name != "rows_per_page" {
+			data[name] = value
+		}
+	}
+
+	return fmt.Sprintf("SELECT count(*) AS count FROM (%s) AS q", query), data
+}
+
+func (b *QueryBuilder) whereClause() string {
+	if len(b.where) == 0 {
+		return ""
+	}
+
+	conditions := make([]string, len(b.where))
+	for i, condition := range b.where {
+		conditions[i] = "(" + condition + ")"
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+// QueryCount runs the count query of the builder.
+func QueryCount(ctx context.Context, log *zerolog.Logger, db sqlx.ExtContext, b *QueryBuilder) (int, error) {
+	q, data := b.Count()
+
+	var dest struct {
+		Count int `db:"count"`
+	}
+	if err := NamedQueryStruct(ctx, log, db, q, data, &dest); err != nil {
+		return 0, err
+	}
+
+	return dest.Count, nil
+}
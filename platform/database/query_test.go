@@ -0,0 +1,139 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dudakovict/gotify/platform/cursor"
+	"github.com/dudakovict/gotify/platform/database"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	columns := map[string]string{
+		"name":       "name",
+		"created_at": "created_at",
+	}
+
+	testCases := []struct {
+		name      string
+		build     func(b *database.QueryBuilder) error
+		query     string
+		count     string
+		checkData func(t *testing.T, data map[string]any, countData map[string]any)
+		err       error
+	}{
+		{
+			name: "NoFilters",
+			build: func(b *database.QueryBuilder) error {
+				return b.OrderBy(order.NewBy("name", order.ASC), columns, "id")
+			},
+			query: "SELECT * FROM topics\nORDER BY name ASC, id ASC",
+			count: "SELECT count(*) AS count FROM (SELECT * FROM topics) AS q",
+		},
+		{
+			name: "FiltersAndPage",
+			build: func(b *database.QueryBuilder) error {
+				b.Where("name ILIKE :name", map[string]any{"name": "%infra%"})
+				b.Where("archived = :archived OR owner_id = :owner_id", map[string]any{"archived": false, "owner_id": "1"})
+				if err := b.OrderBy(order.NewBy("created_at", order.DESC), columns, "id"); err != nil {
+					return err
+				}
+				b.Page(3, 20)
+				return nil
+			},
+			query: "SELECT * FROM topics\nWHERE (name ILIKE :name) AND (archived = :archived OR owner_id = :owner_id)\nORDER BY created_at DESC, id DESC\nLIMIT :rows_per_page OFFSET :offset",
+			count: "SELECT count(*) AS count FROM (SELECT * FROM topics\nWHERE (name ILIKE :name) AND (archived = :archived OR owner_id = :owner_id)) AS q",
+			checkData: func(t *testing.T, data map[string]any, countData map[string]any) {
+				require.Equal(t, 40, data["offset"])
+				require.Equal(t, 20, data["rows_per_page"])
+				require.Equal(t, "%infra%", data["name"])
+
+				require.NotContains(t, countData, "offset")
+				require.NotContains(t, countData, "rows_per_page")
+				require.Len(t, countData, 3)
+			},
+		},
+		{
+			name: "TieIsOrderColumn",
+			build: func(b *database.QueryBuilder) error {
+				return b.OrderBy(order.NewBy("name", order.DESC), columns, "name")
+			},
+			query: "SELECT * FROM topics\nORDER BY name DESC",
+			count: "SELECT count(*) AS count FROM (SELECT * FROM topics) AS q",
+		},
+		{
+			name: "Keyset",
+			build: func(b *database.QueryBuilder) error {
+				b.Where("archived = :archived", map[string]any{"archived": false})
+				b.Keyset(cursor.New(time.Now(), uuid.New()), "created_at", "id", 10)
+				return nil
+			},
+			query: "SELECT * FROM topics\nWHERE (archived = :archived) AND ((created_at, id) < (:cursor_created_at, :cursor_id))\nORDER BY created_at DESC, id DESC\nLIMIT :rows_per_page",
+			count: "SELECT count(*) AS count FROM (SELECT * FROM topics\nWHERE (archived = :archived) AND ((created_at, id) < (:cursor_created_at, :cursor_id))) AS q",
+			checkData: func(t *testing.T, data map[string]any, countData map[string]any) {
+				require.Equal(t, 11, data["rows_per_page"])
+			},
+		},
+		{
+			name: "KeysetBackward",
+			build: func(b *database.QueryBuilder) error {
+				b.Keyset(cursor.New(time.Now(), uuid.New()).Reverse(), "created_at", "id", 10)
+				return nil
+			},
+			query: "SELECT * FROM topics\nWHERE ((created_at, id) > (:cursor_created_at, :cursor_id))\nORDER BY created_at ASC, id ASC\nLIMIT :rows_per_page",
+			count: "SELECT count(*) AS count FROM (SELECT * FROM topics\nWHERE ((created_at, id) > (:cursor_created_at, :cursor_id))) AS q",
+		},
+		{
+			name: "KeysetFirstPage",
+			build: func(b *database.QueryBuilder) error {
+				b.Keyset(cursor.Cursor{}, "created_at", "id", 10)
+				return nil
+			},
+			query: "SELECT * FROM topics\nORDER BY created_at DESC, id DESC\nLIMIT :rows_per_page",
+			count: "SELECT count(*) AS count FROM (SELECT * FROM topics) AS q",
+		},
+		{
+			name: "UnknownField",
+			build: func(b *database.QueryBuilder) error {
+				return b.OrderBy(order.NewBy("password; DROP TABLE users", order.ASC), columns, "id")
+			},
+			err: order.ErrInvalidOrder,
+		},
+		{
+			name: "UnknownDirection",
+			build: func(b *database.QueryBuilder) error {
+				return b.OrderBy(order.NewBy("name", "SIDEWAYS"), columns, "id")
+			},
+			err: order.ErrInvalidOrder,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			b := database.NewQueryBuilder("SELECT * FROM topics")
+
+			err := tc.build(b)
+			if tc.err != nil {
+				require.True(t, errors.Is(err, tc.err))
+				return
+			}
+			require.NoError(t, err)
+
+			query, data := b.Query()
+			require.Equal(t, tc.query, query)
+
+			count, countData := b.Count()
+			require.Equal(t, tc.count, count)
+
+			if tc.checkData != nil {
+				tc.checkData(t, data, countData)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Set of drivers the app can store its data with.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// regexps caches the regular expressions compiled for the REGEXP operator
+// of SQLite.
+var regexps sync.Map
+
+func init() {
+	sqlx.BindDriver(DriverSQLite, sqlx.QUESTION)
+
+	// SQLite has no regular expressions of its own, X REGEXP Y calls the
+	// regexp function with the expression first.
+	sqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		var expr string
+		switch v := args[0].(type) {
+		case nil:
+			return nil, nil
+		case string:
+			expr = v
+		default:
+			return nil, fmt.Errorf("regexp: unsupported expression type %T", args[0])
+		}
+
+		var value string
+		switch v := args[1].(type) {
+		case nil:
+			return nil, nil
+		case string:
+			value = v
+		case []byte:
+			value = string(v)
+		default:
+			return nil, fmt.Errorf("regexp: unsupported value type %T", args[1])
+		}
+
+		re, exists := regexps.Load(expr)
+		if !exists {
+			compiled, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("regexp: %w", err)
+			}
+			re, _ = regexps.LoadOrStore(expr, compiled)
+		}
+
+		return re.(*regexp.Regexp).MatchString(value), nil
+	})
+}
+
+type SQLiteConfig struct {
+	Path         string
+	MaxOpenConns int
+}
+
+// OpenSQLite knows how to open a SQLite database file based on the
+// configuration. Foreign keys are enforced, transactions take the write lock
+// when they begin and times are written in a format that sorts as text.
+func OpenSQLite(cfg SQLiteConfig) (*sqlx.DB, error) {
+	q := make(url.Values)
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Set("_txlock", "immediate")
+	q.Set("_time_format", "sqlite")
+
+	u := url.URL{
+		Scheme:   "file",
+		Opaque:   cfg.Path,
+		RawQuery: q.Encode(),
+	}
+
+	db, err := sqlx.Open(DriverSQLite, u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+
+	return db, nil
+}
+
+// JSONArray is a list of strings stored as a JSON array, for columns that
+// would be arrays in Postgres. SQLite reads them with json_each.
+type JSONArray []string
+
+// Value implements the driver.Valuer interface.
+func (a JSONArray) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal([]string(a))
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (a *JSONArray) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported type %T for a json array", src)
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*a = values
+
+	return nil
+}
+
+// =============================================================================
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return true
+	}
+
+	return false
+}
+
+func isSQLiteUndefinedTable(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code() == sqlite3.SQLITE_ERROR && strings.Contains(sqliteErr.Error(), "no such table")
+}
@@ -0,0 +1,424 @@
+// Package memdb provides an in-memory database for the stores, used in place
+// of Postgres in dev mode and end-to-end tests.
+package memdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// Set of error variables for the database.
+var (
+	ErrUniqueViolation = errors.New("duplicated entry")
+	ErrTxDone          = errors.New("transaction has already been committed or rolled back")
+)
+
+// Action describes what happens to the rows referencing a deleted row.
+type Action int
+
+// Set of actions on the rows referencing a deleted row.
+const (
+	Cascade Action = iota
+	SetNull
+)
+
+// Reference describes rows of the table referencing rows of the parent table,
+// like a foreign key. Refers reports whether the row references the parent row
+// with the key, and Clear returns the row without the reference, for
+// references with the SetNull action.
+type Reference struct {
+	Table  string
+	Parent string
+	Action Action
+	Refers func(row any, key string) bool
+	Clear  func(row any) any
+}
+
+// Unique describes a unique constraint on the rows of the table. Key returns
+// the value of the row that has to be unique, or false when the row isn't
+// constrained, like a row with a NULL column.
+type Unique struct {
+	Table string
+	Key   func(row any) (string, bool)
+}
+
+// Querier reads and writes the rows of the database. It's implemented by the
+// database, which commits every write on its own, and by its transactions.
+type Querier interface {
+	get(table string, key string) (any, bool)
+	rows(table string) map[string]any
+	put(table string, key string, row any) error
+	delete(table string, key string)
+	lock(table string, key string)
+}
+
+// =============================================================================
+
+// DB is an in-memory database of tables of rows, keyed by strings. It's safe
+// for concurrent use.
+type DB struct {
+	mu      sync.RWMutex
+	tables  map[string]map[string]any
+	refs    map[string]Reference
+	uniques map[string]Unique
+	locks   map[string]*rowLock
+}
+
+// rowLock is the lock of a row, kept while transactions hold or wait for it.
+type rowLock struct {
+	sync.Mutex
+	refs int
+}
+
+// New constructs an empty database.
+func New() *DB {
+	return &DB{
+		tables:  make(map[string]map[string]any),
+		refs:    make(map[string]Reference),
+		uniques: make(map[string]Unique),
+		locks:   make(map[string]*rowLock),
+	}
+}
+
+// Reference registers the reference by the name once, so every store can
+// register the references it needs. Deleting a row of the parent table then
+// applies the action to the rows of the table referencing it.
+func (db *DB) Reference(name string, ref Reference) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.refs[name]; !exists {
+		db.refs[name] = ref
+	}
+}
+
+// Unique registers the unique constraint by the name once, so every store can
+// register the constraints it needs. Writing a row with the same value as
+// another row of the table then fails with ErrUniqueViolation.
+func (db *DB) Unique(name string, unq Unique) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, exists := db.uniques[name]; !exists {
+		db.uniques[name] = unq
+	}
+}
+
+func (db *DB) get(table string, key string) (any, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	row, exists := db.tables[table][key]
+	return row, exists
+}
+
+func (db *DB) rows(table string) map[string]any {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	rows := make(map[string]any, len(db.tables[table]))
+	for key, row := range db.tables[table] {
+		rows[key] = row
+	}
+
+	return rows
+}
+
+func (db *DB) put(table string, key string, row any) error {
+	tx := db.begin()
+	if err := tx.put(table, key, row); err != nil {
+		return err
+	}
+
+	return tx.commit()
+}
+
+func (db *DB) delete(table string, key string) {
+	tx := db.begin()
+	tx.delete(table, key)
+
+	// Deleting rows violates no unique constraint, so the commit can't fail.
+	_ = tx.commit()
+}
+
+// lock does nothing, since every write of the database commits on its own.
+func (db *DB) lock(table string, key string) {}
+
+// acquire locks the row, waiting for the transaction holding it to finish.
+func (db *DB) acquire(name string) *rowLock {
+	db.mu.Lock()
+	l, exists := db.locks[name]
+	if !exists {
+		l = &rowLock{}
+		db.locks[name] = l
+	}
+	l.refs++
+	db.mu.Unlock()
+
+	l.Lock()
+	return l
+}
+
+// release unlocks the row, forgetting its lock once nobody waits for it.
+func (db *DB) release(name string, l *rowLock) {
+	l.Unlock()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(db.locks, name)
+	}
+}
+
+func (db *DB) references(parent string) []Reference {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var refs []Reference
+	for _, ref := range db.refs {
+		if ref.Parent == parent {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+func (db *DB) constraints(table string) []Unique {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var uniques []Unique
+	for _, unq := range db.uniques {
+		if unq.Table == table {
+			uniques = append(uniques, unq)
+		}
+	}
+
+	return uniques
+}
+
+func (db *DB) begin() *Tx {
+	return &Tx{
+		db:     db,
+		writes: make(map[string]map[string]any),
+		locks:  make(map[string]*rowLock),
+	}
+}
+
+// =============================================================================
+
+// Tx is a transaction of the database. Its writes are seen by its own reads
+// and applied to the database all at once when it's committed. The rows it
+// locks stay locked until it's committed or rolled back.
+type Tx struct {
+	db     *DB
+	writes map[string]map[string]any
+	locks  map[string]*rowLock
+	done   bool
+}
+
+func (tx *Tx) get(table string, key string) (any, bool) {
+	if row, exists := tx.writes[table][key]; exists {
+		return row, row != nil
+	}
+
+	return tx.db.get(table, key)
+}
+
+func (tx *Tx) rows(table string) map[string]any {
+	return overlay(tx.db.rows(table), tx.writes[table])
+}
+
+func (tx *Tx) put(table string, key string, row any) error {
+	if err := check(tx.db.constraints(table), key, row, tx.rows(table)); err != nil {
+		return err
+	}
+
+	tx.write(table, key, row)
+	return nil
+}
+
+func (tx *Tx) delete(table string, key string) {
+	if _, exists := tx.get(table, key); !exists {
+		return
+	}
+	tx.write(table, key, nil)
+
+	for _, ref := range tx.db.references(table) {
+		for childKey, child := range tx.rows(ref.Table) {
+			if !ref.Refers(child, key) {
+				continue
+			}
+
+			switch ref.Action {
+			case SetNull:
+				tx.write(ref.Table, childKey, ref.Clear(child))
+			default:
+				tx.delete(ref.Table, childKey)
+			}
+		}
+	}
+}
+
+func (tx *Tx) lock(table string, key string) {
+	name := table + "/" + key
+	if _, held := tx.locks[name]; held {
+		return
+	}
+
+	tx.locks[name] = tx.db.acquire(name)
+}
+
+// unlock releases the rows locked by the transaction.
+func (tx *Tx) unlock() {
+	for name, l := range tx.locks {
+		tx.db.release(name, l)
+	}
+	tx.locks = nil
+}
+
+// write records the row, or the deletion of the row when it's nil.
+func (tx *Tx) write(table string, key string, row any) {
+	if tx.writes[table] == nil {
+		tx.writes[table] = make(map[string]any)
+	}
+	tx.writes[table][key] = row
+}
+
+func (tx *Tx) commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+
+	defer tx.unlock()
+
+	uniques := make(map[string][]Unique, len(tx.writes))
+	for table := range tx.writes {
+		uniques[table] = tx.db.constraints(table)
+	}
+
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	// Other transactions may have committed since the rows were written, so
+	// they are checked again against the rows they are committed over.
+	for table, writes := range tx.writes {
+		rows := overlay(tx.db.tables[table], writes)
+		for key, row := range writes {
+			if err := check(uniques[table], key, row, rows); err != nil {
+				return err
+			}
+		}
+	}
+
+	for table, writes := range tx.writes {
+		for key, row := range writes {
+			if row == nil {
+				delete(tx.db.tables[table], key)
+				continue
+			}
+
+			if tx.db.tables[table] == nil {
+				tx.db.tables[table] = make(map[string]any)
+			}
+			tx.db.tables[table][key] = row
+		}
+	}
+
+	return nil
+}
+
+func (tx *Tx) rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.writes = nil
+	tx.unlock()
+
+	return nil
+}
+
+// =============================================================================
+
+// WithinTran runs the function within a transaction of the database. The
+// writes of the function are committed when it returns no error and dropped
+// otherwise.
+func WithinTran(ctx context.Context, log *zerolog.Logger, db *DB, fn func(*Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tx := db.begin()
+
+	defer func() {
+		if err := tx.rollback(); err != nil {
+			if errors.Is(err, ErrTxDone) {
+				return
+			}
+			log.Error().Err(err).Msg("unable to rollback tran")
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// overlay returns the rows with the writes applied, where a nil row is a
+// deleted row.
+func overlay(rows map[string]any, writes map[string]any) map[string]any {
+	merged := make(map[string]any, len(rows)+len(writes))
+	for key, row := range rows {
+		merged[key] = row
+	}
+
+	for key, row := range writes {
+		if row == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = row
+	}
+
+	return merged
+}
+
+// check verifies the row with the key violates none of the unique
+// constraints, given the rows of its table. Deleted rows violate none.
+func check(uniques []Unique, key string, row any, rows map[string]any) error {
+	if row == nil {
+		return nil
+	}
+
+	for _, unq := range uniques {
+		value, ok := unq.Key(row)
+		if !ok {
+			continue
+		}
+
+		for otherKey, other := range rows {
+			if otherKey == key {
+				continue
+			}
+			if v, ok := unq.Key(other); ok && v == value {
+				return ErrUniqueViolation
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package memdb_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/dudakovict/gotify/platform/memdb"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+type parent struct {
+	ID   string
+	Name string
+}
+
+type child struct {
+	ID       string
+	ParentID string
+}
+
+type link struct {
+	ID       string
+	ParentID *string
+}
+
+func newDB() *memdb.DB {
+	db := memdb.New()
+
+	db.Unique("parents_name_key", memdb.Unique{
+		Table: "parents",
+		Key:   func(row any) (string, bool) { return row.(parent).Name, true },
+	})
+
+	db.Reference("children_parent_id_fkey", memdb.Reference{
+		Table:  "children",
+		Parent: "parents",
+		Refers: func(row any, key string) bool { return row.(child).ParentID == key },
+	})
+
+	db.Reference("links_parent_id_fkey", memdb.Reference{
+		Table:  "links",
+		Parent: "parents",
+		Action: memdb.SetNull,
+		Refers: func(row any, key string) bool {
+			l := row.(link)
+			return l.ParentID != nil && *l.ParentID == key
+		},
+		Clear: func(row any) any {
+			l := row.(link)
+			l.ParentID = nil
+			return l
+		},
+	})
+
+	return db
+}
+
+func TestUnique(t *testing.T) {
+	db := newDB()
+
+	require.NoError(t, memdb.Insert(db, "parents", "1", parent{ID: "1", Name: "a"}))
+	require.ErrorIs(t, memdb.Insert(db, "parents", "1", parent{ID: "1", Name: "b"}), memdb.ErrUniqueViolation)
+	require.ErrorIs(t, memdb.Insert(db, "parents", "2", parent{ID: "2", Name: "a"}), memdb.ErrUniqueViolation)
+
+	// Replacing a row doesn't conflict with itself.
+	require.NoError(t, memdb.Put(db, "parents", "1", parent{ID: "1", Name: "a"}))
+}
+
+func TestDeleteReferences(t *testing.T) {
+	db := newDB()
+	id := "1"
+
+	require.NoError(t, memdb.Insert(db, "parents", id, parent{ID: id, Name: "a"}))
+	require.NoError(t, memdb.Insert(db, "children", "c", child{ID: "c", ParentID: id}))
+	require.NoError(t, memdb.Insert(db, "links", "l", link{ID: "l", ParentID: &id}))
+
+	memdb.Delete(db, "parents", id)
+
+	_, exists := memdb.Get[child](db, "children", "c")
+	require.False(t, exists)
+
+	l, exists := memdb.Get[link](db, "links", "l")
+	require.True(t, exists)
+	require.Nil(t, l.ParentID)
+}
+
+func TestWithinTran(t *testing.T) {
+	db := newDB()
+	log := zerolog.Nop()
+	errFailed := errors.New("failed")
+
+	err := memdb.WithinTran(context.Background(), &log, db, func(tx *memdb.Tx) error {
+		require.NoError(t, memdb.Insert(tx, "parents", "1", parent{ID: "1", Name: "a"}))
+
+		_, exists := memdb.Get[parent](tx, "parents", "1")
+		require.True(t, exists)
+
+		_, exists = memdb.Get[parent](db, "parents", "1")
+		require.False(t, exists)
+
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+
+	_, exists := memdb.Get[parent](db, "parents", "1")
+	require.False(t, exists)
+
+	err = memdb.WithinTran(context.Background(), &log, db, func(tx *memdb.Tx) error {
+		return memdb.Insert(tx, "parents", "1", parent{ID: "1", Name: "a"})
+	})
+	require.NoError(t, err)
+
+	_, exists = memdb.Get[parent](db, "parents", "1")
+	require.True(t, exists)
+}
+
+func TestLock(t *testing.T) {
+	db := newDB()
+	log := zerolog.Nop()
+
+	require.NoError(t, memdb.Insert(db, "counters", "1", 0))
+
+	const n = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := memdb.WithinTran(context.Background(), &log, db, func(tx *memdb.Tx) error {
+				memdb.Lock(tx, "counters", "1")
+
+				count, _ := memdb.Get[int](tx, "counters", "1")
+				return memdb.Put(tx, "counters", "1", count+1)
+			})
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	count, _ := memdb.Get[int](db, "counters", "1")
+	require.Equal(t, n, count)
+}
@@ -0,0 +1,151 @@
+package memdb
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dudakovict/gotify/platform/cursor"
+	"github.com/dudakovict/gotify/platform/order"
+	"github.com/google/uuid"
+)
+
+// Get returns the row of the table with the key, and whether it exists.
+func Get[T any](q Querier, table string, key string) (T, bool) {
+	row, exists := q.get(table, key)
+	if !exists {
+		var zero T
+		return zero, false
+	}
+
+	return row.(T), true
+}
+
+// Rows returns the rows of the table the keep function keeps, in no
+// particular order. A nil keep function keeps every row.
+func Rows[T any](q Querier, table string, keep func(row T) bool) []T {
+	var rows []T
+	for _, row := range q.rows(table) {
+		if keep == nil || keep(row.(T)) {
+			rows = append(rows, row.(T))
+		}
+	}
+
+	return rows
+}
+
+// Insert inserts the row into the table. It fails with ErrUniqueViolation
+// when the table already holds a row with the key.
+func Insert(q Querier, table string, key string, row any) error {
+	if _, exists := q.get(table, key); exists {
+		return ErrUniqueViolation
+	}
+
+	return q.put(table, key, row)
+}
+
+// Put inserts the row into the table, or replaces the row with the key.
+func Put(q Querier, table string, key string, row any) error {
+	return q.put(table, key, row)
+}
+
+// Lock locks the row of the table with the key until the transaction ends,
+// like SELECT ... FOR UPDATE, so transactions reading a row to update it take
+// turns. It does nothing outside of a transaction.
+func Lock(q Querier, table string, key string) {
+	q.lock(table, key)
+}
+
+// Delete removes the row of the table with the key, along with the rows
+// referencing it.
+func Delete(q Querier, table string, key string) {
+	q.delete(table, key)
+}
+
+// =============================================================================
+
+// Compare compares two rows, returning a negative number when a comes before
+// b, a positive number when it comes after, and zero otherwise.
+type Compare[T any] func(a T, b T) int
+
+// Sort orders the rows by the field, using the compare function the field maps
+// to in fields, the allow-list of fields the rows can be ordered by. Rows
+// ordered the same are ordered by the tie function, so pages stay stable.
+func Sort[T any](rows []T, by order.By, fields map[string]Compare[T], tie Compare[T]) error {
+	compare, exists := fields[by.Field]
+	if !exists {
+		return fmt.Errorf("%w: unknown field %q", order.ErrInvalidOrder, by.Field)
+	}
+
+	if by.Direction != order.ASC && by.Direction != order.DESC {
+		return fmt.Errorf("%w: unknown direction %q", order.ErrInvalidOrder, by.Direction)
+	}
+
+	slices.SortStableFunc(rows, func(a, b T) int {
+		c := compare(a, b)
+		if c == 0 && tie != nil {
+			c = tie(a, b)
+		}
+
+		if by.Direction == order.DESC {
+			return -c
+		}
+		return c
+	})
+
+	return nil
+}
+
+// Page returns the rows of the page, numbered from 1.
+func Page[T any](rows []T, pageNumber int, rowsPerPage int) []T {
+	offset := (pageNumber - 1) * rowsPerPage
+	if offset < 0 || offset >= len(rows) {
+		return nil
+	}
+
+	return rows[offset:min(offset+rowsPerPage, len(rows))]
+}
+
+// Keyset returns the rows of the page read with the cursor, in a list ordered
+// by creation time and ID, the newest first, the way the Postgres stores read
+// them: in the direction of the cursor, with one row more than the page holds.
+// Mark returns the creation time and ID of a row.
+func Keyset[T any](rows []T, cur cursor.Cursor, rowsPerPage int, mark func(row T) (time.Time, uuid.UUID)) []T {
+	compare := func(a, b T) int {
+		aAt, aID := mark(a)
+		bAt, bID := mark(b)
+		if c := aAt.Compare(bAt); c != 0 {
+			return c
+		}
+		return CompareIDs(aID, bID)
+	}
+
+	slices.SortFunc(rows, func(a, b T) int {
+		if cur.Backward {
+			return compare(a, b)
+		}
+		return compare(b, a)
+	})
+
+	if !cur.IsZero() {
+		rows = slices.DeleteFunc(rows, func(row T) bool {
+			at, id := mark(row)
+			c := at.Compare(cur.CreatedAt)
+			if c == 0 {
+				c = CompareIDs(id, cur.ID)
+			}
+			if cur.Backward {
+				return c <= 0
+			}
+			return c >= 0
+		})
+	}
+
+	return rows[:min(rowsPerPage+1, len(rows))]
+}
+
+// CompareIDs compares two IDs the way Postgres compares UUIDs.
+func CompareIDs(a uuid.UUID, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS mfa_secrets (
+    user_id UUID PRIMARY KEY,
+    secret VARCHAR NOT NULL,
+    enabled BOOL NOT NULL DEFAULT false,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    updated_at TIMESTAMPTZ NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    hashed_code VARCHAR NOT NULL,
+    used BOOL NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT unique_recovery_code_user UNIQUE (user_id, hashed_code)
+);
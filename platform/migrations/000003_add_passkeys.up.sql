@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS passkeys (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    name VARCHAR NOT NULL,
+    credential_id BYTEA NOT NULL,
+    public_key BYTEA NOT NULL,
+    attestation_type VARCHAR NOT NULL,
+    aaguid BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports VARCHAR[] NOT NULL DEFAULT '{}',
+    backup_eligible BOOL NOT NULL DEFAULT false,
+    backup_state BOOL NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    last_used_at TIMESTAMPTZ NULL,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT unique_passkey_credential UNIQUE (credential_id)
+);
+
+CREATE INDEX ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+    id UUID PRIMARY KEY,
+    user_id UUID NULL,
+    data BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS oidc_states (
+    id VARCHAR PRIMARY KEY,
+    nonce VARCHAR NOT NULL,
+    code_verifier VARCHAR NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer VARCHAR NOT NULL,
+    subject VARCHAR NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    PRIMARY KEY (issuer, subject),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR PRIMARY KEY,
+    description VARCHAR NOT NULL DEFAULT '',
+    permissions VARCHAR[] NOT NULL DEFAULT '{}',
+    built_in BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    updated_at TIMESTAMPTZ NULL
+);
+
+INSERT INTO roles
+    (name, description, permissions, built_in, updated_at)
+VALUES
+    (
+        'ADMIN',
+        'Manages every resource in the system.',
+        '{topic:create,topic:read,notification:create,notification:read,notification:delete,subscription:create,subscription:read,subscription:delete,subscription:manage,user:create,user:read,user:update,user:delete,role:read,role:manage}',
+        true,
+        now()
+    ),
+    (
+        'USER',
+        'Publishes notifications and manages their own subscriptions.',
+        '{topic:create,topic:read,notification:create,notification:read,subscription:create,subscription:read,subscription:delete}',
+        true,
+        now()
+    );
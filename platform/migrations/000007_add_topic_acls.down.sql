@@ -0,0 +1,7 @@
+UPDATE roles SET permissions = array_remove(permissions, 'topic:manage') WHERE name = 'ADMIN';
+
+DROP TABLE IF EXISTS topic_invitations;
+DROP TABLE IF EXISTS topic_acls;
+
+ALTER TABLE topics DROP COLUMN IF EXISTS visibility;
+ALTER TABLE topics DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,33 @@
+ALTER TABLE topics ADD COLUMN owner_id UUID NULL REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE topics ADD COLUMN visibility VARCHAR NOT NULL DEFAULT 'public';
+
+CREATE TABLE IF NOT EXISTS topic_acls (
+    topic_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    rights VARCHAR[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    updated_at TIMESTAMPTZ NULL,
+    PRIMARY KEY (topic_id, user_id),
+    FOREIGN KEY (topic_id) REFERENCES topics (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX ON topic_acls (user_id);
+
+CREATE TABLE IF NOT EXISTS topic_invitations (
+    id UUID PRIMARY KEY,
+    topic_id UUID NOT NULL,
+    email VARCHAR NOT NULL,
+    rights VARCHAR[] NOT NULL DEFAULT '{}',
+    hashed_token VARCHAR NOT NULL,
+    invited_by UUID NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    FOREIGN KEY (topic_id) REFERENCES topics (id) ON DELETE CASCADE,
+    FOREIGN KEY (invited_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX ON topic_invitations (topic_id);
+
+UPDATE roles SET permissions = array_append(permissions, 'topic:manage') WHERE name = 'ADMIN';
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS topic_deletions;
+
+ALTER TABLE topics DROP CONSTRAINT IF EXISTS unique_topic_name;
+
+ALTER TABLE topics DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE topics DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE topics DROP COLUMN IF EXISTS description;
@@ -0,0 +1,23 @@
+ALTER TABLE topics ADD COLUMN description VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE topics ADD COLUMN archived_at TIMESTAMPTZ NULL;
+ALTER TABLE topics ADD COLUMN updated_at TIMESTAMPTZ NULL;
+
+-- Names were never enforced to be unique, so topics sharing a name with an
+-- older one get the start of their id appended before adding the constraint.
+UPDATE topics t SET name = t.name || '-' || left(t.id::text, 8)
+WHERE EXISTS (
+    SELECT 1 FROM topics o
+    WHERE o.name = t.name AND (o.created_at, o.id) < (t.created_at, t.id)
+);
+
+ALTER TABLE topics ADD CONSTRAINT unique_topic_name UNIQUE (name);
+
+CREATE TABLE IF NOT EXISTS topic_deletions (
+    topic_id UUID PRIMARY KEY,
+    hashed_token VARCHAR NOT NULL,
+    requested_by UUID NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    FOREIGN KEY (topic_id) REFERENCES topics (id) ON DELETE CASCADE,
+    FOREIGN KEY (requested_by) REFERENCES users (id) ON DELETE SET NULL
+);
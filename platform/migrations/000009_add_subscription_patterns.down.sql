@@ -0,0 +1,11 @@
+DELETE FROM subscriptions WHERE pattern IS NOT NULL;
+
+DROP INDEX IF EXISTS subscriptions_pattern_root_idx;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_pattern_user;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscription_target;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS pattern_regex;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS pattern_root;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS pattern;
+ALTER TABLE subscriptions ALTER COLUMN topic_id SET NOT NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE subscriptions ALTER COLUMN topic_id DROP NOT NULL;
+ALTER TABLE subscriptions ADD COLUMN pattern VARCHAR NULL;
+ALTER TABLE subscriptions ADD COLUMN pattern_root VARCHAR NULL;
+ALTER TABLE subscriptions ADD COLUMN pattern_regex VARCHAR NULL;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscription_target CHECK ((topic_id IS NULL) <> (pattern IS NULL));
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_pattern_user UNIQUE (pattern, user_id);
+
+CREATE INDEX ON subscriptions (pattern_root) WHERE pattern IS NOT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS filter;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS tags;
+ALTER TABLE notifications DROP COLUMN IF EXISTS priority;
+ALTER TABLE notifications DROP COLUMN IF EXISTS title;
@@ -0,0 +1,6 @@
+ALTER TABLE notifications ADD COLUMN title VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE notifications ADD COLUMN priority VARCHAR NOT NULL DEFAULT 'normal';
+ALTER TABLE notifications ADD COLUMN tags VARCHAR[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE subscriptions ADD COLUMN filter JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE subscriptions ADD COLUMN updated_at TIMESTAMPTZ NULL;
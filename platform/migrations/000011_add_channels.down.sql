@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS inbox;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS routes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS channels;
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN channels JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE subscriptions ADD COLUMN routes JSONB NOT NULL DEFAULT '[]';
+
+CREATE TABLE IF NOT EXISTS inbox (
+    notification_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    PRIMARY KEY (user_id, notification_id),
+    FOREIGN KEY (notification_id) REFERENCES notifications (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX ON inbox (user_id, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE subscriptions ADD COLUMN muted BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE subscriptions ADD COLUMN muted_until TIMESTAMPTZ NULL;
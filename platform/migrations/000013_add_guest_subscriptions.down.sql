@@ -0,0 +1,15 @@
+DELETE FROM verifications WHERE subscription_id IS NOT NULL;
+
+ALTER TABLE verifications DROP CONSTRAINT IF EXISTS verification_target;
+ALTER TABLE verifications DROP COLUMN IF EXISTS subscription_id;
+ALTER TABLE verifications ALTER COLUMN user_id SET NOT NULL;
+
+DELETE FROM subscriptions WHERE guest_email IS NOT NULL;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_topic_guest;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS guest_subscription_topic;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscription_owner;
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS confirmed;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS guest_email;
+ALTER TABLE subscriptions ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,11 @@
+ALTER TABLE subscriptions ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE subscriptions ADD COLUMN guest_email VARCHAR NULL;
+ALTER TABLE subscriptions ADD COLUMN confirmed BOOLEAN NOT NULL DEFAULT true;
+
+ALTER TABLE subscriptions ADD CONSTRAINT subscription_owner CHECK ((user_id IS NULL) <> (guest_email IS NULL));
+ALTER TABLE subscriptions ADD CONSTRAINT guest_subscription_topic CHECK (guest_email IS NULL OR topic_id IS NOT NULL);
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_topic_guest UNIQUE (topic_id, guest_email);
+
+ALTER TABLE verifications ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE verifications ADD COLUMN subscription_id UUID NULL REFERENCES subscriptions (id) ON DELETE CASCADE;
+ALTER TABLE verifications ADD CONSTRAINT verification_target CHECK ((user_id IS NULL) <> (subscription_id IS NULL));
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS subscription_jobs (
+    id UUID PRIMARY KEY,
+    status VARCHAR NOT NULL DEFAULT 'pending',
+    rows JSONB NOT NULL DEFAULT '[]',
+    processed INT NOT NULL DEFAULT 0,
+    created INT NOT NULL DEFAULT 0,
+    existing INT NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    created_by UUID NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+);
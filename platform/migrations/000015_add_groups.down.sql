@@ -0,0 +1,14 @@
+UPDATE roles SET permissions = array_remove(permissions, 'group:manage');
+UPDATE roles SET permissions = array_remove(permissions, 'group:read');
+
+DELETE FROM subscriptions WHERE group_id IS NOT NULL;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_pattern_group;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_topic_group;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscription_owner;
+ALTER TABLE subscriptions ADD CONSTRAINT subscription_owner CHECK ((user_id IS NULL) <> (guest_email IS NULL));
+
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS group_id;
+
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
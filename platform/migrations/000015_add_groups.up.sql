@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS groups (
+    id UUID PRIMARY KEY,
+    name VARCHAR UNIQUE NOT NULL,
+    description VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX ON group_members (user_id);
+
+ALTER TABLE subscriptions ADD COLUMN group_id UUID NULL REFERENCES groups (id) ON DELETE CASCADE;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscription_owner;
+ALTER TABLE subscriptions ADD CONSTRAINT subscription_owner CHECK (num_nonnulls(user_id, guest_email, group_id) = 1);
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_topic_group UNIQUE (topic_id, group_id);
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_pattern_group UNIQUE (pattern, group_id);
+
+UPDATE roles SET permissions = array_append(permissions, 'group:read') WHERE name IN ('ADMIN', 'USER');
+UPDATE roles SET permissions = array_append(permissions, 'group:manage') WHERE name = 'ADMIN';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS notifications_topic_id_created_at_id_idx;
@@ -0,0 +1 @@
+CREATE INDEX ON notifications (topic_id, created_at, id);
@@ -0,0 +1,31 @@
+DELETE FROM notifications WHERE deleted_at IS NOT NULL;
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL;
+DELETE FROM topics WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS notifications_deleted_at_idx;
+DROP INDEX IF EXISTS subscriptions_deleted_at_idx;
+DROP INDEX IF EXISTS topics_deleted_at_idx;
+DROP INDEX IF EXISTS users_deleted_at_idx;
+
+DROP INDEX IF EXISTS unique_sub_pattern_group;
+DROP INDEX IF EXISTS unique_sub_topic_group;
+DROP INDEX IF EXISTS unique_sub_topic_guest;
+DROP INDEX IF EXISTS unique_sub_pattern_user;
+DROP INDEX IF EXISTS unique_sub_topic_user;
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_topic_user UNIQUE (topic_id, user_id);
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_pattern_user UNIQUE (pattern, user_id);
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_topic_guest UNIQUE (topic_id, guest_email);
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_topic_group UNIQUE (topic_id, group_id);
+ALTER TABLE subscriptions ADD CONSTRAINT unique_sub_pattern_group UNIQUE (pattern, group_id);
+
+DROP INDEX IF EXISTS unique_topic_name;
+ALTER TABLE topics ADD CONSTRAINT unique_topic_name UNIQUE (name);
+
+DROP INDEX IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE topics DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
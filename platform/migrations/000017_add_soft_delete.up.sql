@@ -0,0 +1,29 @@
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE topics ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE subscriptions ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE notifications ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+-- Deleted rows are kept until they are purged, so only the rows that aren't
+-- deleted have to be unique. Restoring a row fails when another row took its
+-- place in the meantime.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
+
+ALTER TABLE topics DROP CONSTRAINT IF EXISTS unique_topic_name;
+CREATE UNIQUE INDEX unique_topic_name ON topics (name) WHERE deleted_at IS NULL;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_topic_user;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_pattern_user;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_topic_guest;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_topic_group;
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS unique_sub_pattern_group;
+CREATE UNIQUE INDEX unique_sub_topic_user ON subscriptions (topic_id, user_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_pattern_user ON subscriptions (pattern, user_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_topic_guest ON subscriptions (topic_id, guest_email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_topic_group ON subscriptions (topic_id, group_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_pattern_group ON subscriptions (pattern, group_id) WHERE deleted_at IS NULL;
+
+CREATE INDEX ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX ON topics (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX ON subscriptions (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX ON notifications (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Topics keep their notifications for the global retention period unless
+-- they have one of their own.
+ALTER TABLE topics ADD COLUMN retention_seconds BIGINT NOT NULL DEFAULT 0;
+
+-- The purge tasks look rows up by the time they expire.
+CREATE INDEX ON sessions (expires_at);
+CREATE INDEX ON verifications (expired_at);
+CREATE INDEX ON notifications (created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE groups DROP COLUMN IF EXISTS version;
+ALTER TABLE subscriptions DROP COLUMN IF EXISTS version;
+ALTER TABLE topics DROP COLUMN IF EXISTS version;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS notifications_search_idx;
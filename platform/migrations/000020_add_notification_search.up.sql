@@ -0,0 +1,5 @@
+-- Notifications are searched by their title and message, the title weighing
+-- more. The search queries use the same expression, so they use the index.
+CREATE INDEX notifications_search_idx ON notifications USING GIN (
+    (setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', coalesce(message, '')), 'B'))
+);
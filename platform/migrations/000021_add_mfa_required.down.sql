@@ -0,0 +1 @@
+UPDATE roles SET permissions = array_remove(permissions, 'mfa:required');
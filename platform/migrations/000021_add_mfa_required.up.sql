@@ -0,0 +1,3 @@
+-- Admins have to use two-factor authentication when it is enforced. Which
+-- roles require it is managed like any other permission.
+UPDATE roles SET permissions = array_append(permissions, 'mfa:required') WHERE name = 'ADMIN';
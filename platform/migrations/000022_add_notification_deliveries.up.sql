@@ -0,0 +1,10 @@
+-- Deliveries made of a notification are recorded, so that retrying the
+-- delivery only sends it where it hasn't gone yet.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    notification_id UUID NOT NULL,
+    channel VARCHAR NOT NULL,
+    address VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    PRIMARY KEY (notification_id, channel, address),
+    FOREIGN KEY (notification_id) REFERENCES notifications (id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+-- Deliveries of a notification skipped, like those of muted subscriptions,
+-- are recorded along with the reason, so they can be audited.
+CREATE TABLE IF NOT EXISTS notification_skips (
+    notification_id UUID NOT NULL,
+    subscription_id UUID NOT NULL,
+    reason VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT (now()),
+    PRIMARY KEY (notification_id, subscription_id),
+    FOREIGN KEY (notification_id) REFERENCES notifications (id) ON DELETE CASCADE
+);
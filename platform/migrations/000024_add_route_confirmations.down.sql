@@ -0,0 +1,3 @@
+DELETE FROM verifications WHERE channel IS NOT NULL;
+
+ALTER TABLE verifications DROP COLUMN IF EXISTS channel;
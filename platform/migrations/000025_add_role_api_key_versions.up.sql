@@ -0,0 +1,4 @@
+-- Roles and api keys are versioned like the other resources, so concurrent
+-- edits of them don't silently overwrite each other either.
+ALTER TABLE roles ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE api_keys ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
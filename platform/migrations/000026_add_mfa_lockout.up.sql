@@ -0,0 +1,4 @@
+-- Failed mfa login attempts are counted so that guessing codes locks mfa
+-- login for a while.
+ALTER TABLE mfa_secrets ADD COLUMN failed_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mfa_secrets ADD COLUMN locked_until TIMESTAMPTZ NULL;
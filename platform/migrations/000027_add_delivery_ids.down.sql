@@ -0,0 +1,2 @@
+ALTER TABLE notification_skips DROP COLUMN IF EXISTS id;
+ALTER TABLE notification_deliveries DROP COLUMN IF EXISTS id;
@@ -0,0 +1,12 @@
+-- Deliveries and skipped deliveries get an ID, so that their log can be paged
+-- through with a cursor like the other lists. Rows recorded before get random
+-- ones.
+ALTER TABLE notification_deliveries ADD COLUMN id UUID NULL;
+UPDATE notification_deliveries SET id = md5(random()::text || clock_timestamp()::text)::uuid;
+ALTER TABLE notification_deliveries ALTER COLUMN id SET NOT NULL;
+ALTER TABLE notification_deliveries ADD CONSTRAINT notification_deliveries_id_key UNIQUE (id);
+
+ALTER TABLE notification_skips ADD COLUMN id UUID NULL;
+UPDATE notification_skips SET id = md5(random()::text || clock_timestamp()::text)::uuid;
+ALTER TABLE notification_skips ALTER COLUMN id SET NOT NULL;
+ALTER TABLE notification_skips ADD CONSTRAINT notification_skips_id_key UNIQUE (id);
@@ -0,0 +1,220 @@
+// Package migrations holds the migrations of the database schema. The
+// migrations of both Postgres and SQLite are embedded in the binary, so a
+// deployment needs nothing but the executable to bring its schema up to date.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// The names of the drivers migrations are kept for, matching the drivers of
+// the database package.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+//go:embed *.sql
+var postgresMigrations embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// ErrNoChange is returned when the database is already at the requested
+// version.
+var ErrNoChange = migrate.ErrNoChange
+
+// Status is the state of a single migration in the database.
+type Status struct {
+	Version uint
+	Name    string
+	Applied bool
+}
+
+// Migrator migrates the schema of a database with the embedded migrations.
+// On Postgres it holds an advisory lock while migrating, so replicas starting
+// at the same time wait for each other instead of racing.
+type Migrator struct {
+	m    *migrate.Migrate
+	src  source.Driver
+	conn *sql.Conn
+}
+
+// New constructs a migrator for the database of the driver. The migrator
+// holds on to a single connection of the database until it is closed.
+func New(ctx context.Context, db *sql.DB, driver string) (*Migrator, error) {
+	var (
+		fsys fs.FS
+		path string
+	)
+
+	switch driver {
+	case "", DriverPostgres:
+		fsys, path = postgresMigrations, "."
+	case DriverSQLite:
+		fsys, path = sqliteMigrations, "sqlite"
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+
+	src, err := iofs.New(fsys, path)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+
+	mgr := Migrator{
+		src: src,
+	}
+
+	var dbDriver database.Driver
+	switch driver {
+	case DriverSQLite:
+		// The migrations manage their own transactions, since rebuilding a
+		// table needs foreign keys turned off, which SQLite ignores inside a
+		// transaction.
+		dbDriver, err = sqlite.WithInstance(db, &sqlite.Config{NoTxWrap: true})
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: %w", err)
+		}
+
+	default:
+		// The connection is handed over instead of the database, since
+		// closing a migrator built on the database closes the database too.
+		mgr.conn, err = db.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("conn: %w", err)
+		}
+
+		dbDriver, err = postgres.WithConnection(ctx, mgr.conn, &postgres.Config{})
+		if err != nil {
+			mgr.conn.Close()
+			return nil, fmt.Errorf("postgres: %w", err)
+		}
+	}
+
+	mgr.m, err = migrate.NewWithInstance("iofs", src, driver, dbDriver)
+	if err != nil {
+		mgr.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &mgr, nil
+}
+
+// Close releases the connection held by the migrator. The database stays
+// open.
+func (mgr *Migrator) Close() error {
+	if mgr.conn != nil {
+		return mgr.conn.Close()
+	}
+
+	return nil
+}
+
+// Up applies the given number of pending migrations, or all of them when
+// steps is zero.
+func (mgr *Migrator) Up(steps int) error {
+	if steps == 0 {
+		return mgr.m.Up()
+	}
+
+	return mgr.m.Steps(steps)
+}
+
+// Down rolls back the given number of applied migrations, or all of them when
+// steps is zero.
+func (mgr *Migrator) Down(steps int) error {
+	if steps == 0 {
+		return mgr.m.Down()
+	}
+
+	return mgr.m.Steps(-steps)
+}
+
+// Force sets the version of the database without running any migrations and
+// clears the dirty flag, where version zero means no migrations are applied.
+// It is meant for recovering from a failed migration once the schema has been
+// fixed by hand.
+func (mgr *Migrator) Force(version int) error {
+	if version == 0 {
+		return mgr.m.Force(database.NilVersion)
+	}
+
+	return mgr.m.Force(version)
+}
+
+// Version returns the version of the database and whether the last migration
+// failed half way. A database without migrations is at version zero.
+func (mgr *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mgr.m.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
+
+// Status returns every embedded migration in order, marked whether it has
+// been applied to the database.
+func (mgr *Migrator) Status() ([]Status, error) {
+	current, _, err := mgr.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+
+	version, err := mgr.src.First()
+	for err == nil {
+		name := ""
+		if r, ident, err := mgr.src.ReadUp(version); err == nil {
+			r.Close()
+			name = ident
+		}
+
+		statuses = append(statuses, Status{
+			Version: version,
+			Name:    name,
+			Applied: version <= current,
+		})
+
+		version, err = mgr.src.Next(version)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// Migrate migrates the database of the driver to the latest version.
+func Migrate(ctx context.Context, db *sql.DB, driver string) error {
+	mgr, err := New(ctx, db, driver)
+	if err != nil {
+		return err
+	}
+	defer mgr.Close()
+
+	if err := mgr.Up(0); err != nil && !errors.Is(err, ErrNoChange) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+BEGIN;
+
+DROP TABLE IF EXISTS subscription_jobs;
+DROP TABLE IF EXISTS inbox;
+DROP TABLE IF EXISTS topic_deletions;
+DROP TABLE IF EXISTS topic_invitations;
+DROP TABLE IF EXISTS topic_acls;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS oidc_states;
+DROP TABLE IF EXISTS webauthn_ceremonies;
+DROP TABLE IF EXISTS passkeys;
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS mfa_secrets;
+DROP TABLE IF EXISTS verifications;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS topics;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
+
+COMMIT;
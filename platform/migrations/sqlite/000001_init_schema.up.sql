@@ -0,0 +1,265 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    roles TEXT NOT NULL DEFAULT '[]',
+    hashed_password BLOB NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT false,
+    channels TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    refresh_token TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    client_ip TEXT NOT NULL,
+    is_blocked BOOLEAN NOT NULL DEFAULT false,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS topics (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+    visibility TEXT NOT NULL DEFAULT 'public',
+    archived_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    CONSTRAINT unique_topic_name UNIQUE (name)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id TEXT PRIMARY KEY,
+    topic_id TEXT NOT NULL REFERENCES topics (id) ON DELETE CASCADE,
+    title TEXT NOT NULL DEFAULT '',
+    message TEXT,
+    priority TEXT NOT NULL DEFAULT 'normal',
+    tags TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS notifications_topic_id_created_at_id_idx ON notifications (topic_id, created_at, id);
+
+CREATE TABLE IF NOT EXISTS groups (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id TEXT NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS group_members_user_id_idx ON group_members (user_id);
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id TEXT PRIMARY KEY,
+    topic_id TEXT NULL REFERENCES topics (id) ON DELETE CASCADE,
+    pattern TEXT NULL,
+    pattern_root TEXT NULL,
+    pattern_regex TEXT NULL,
+    user_id TEXT NULL REFERENCES users (id) ON DELETE CASCADE,
+    guest_email TEXT NULL,
+    group_id TEXT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    confirmed BOOLEAN NOT NULL DEFAULT true,
+    filter TEXT NOT NULL DEFAULT '{}',
+    routes TEXT NOT NULL DEFAULT '[]',
+    muted BOOLEAN NOT NULL DEFAULT false,
+    muted_until TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    CONSTRAINT subscription_target CHECK ((topic_id IS NULL) <> (pattern IS NULL)),
+    CONSTRAINT subscription_owner CHECK ((user_id IS NOT NULL) + (guest_email IS NOT NULL) + (group_id IS NOT NULL) = 1),
+    CONSTRAINT guest_subscription_topic CHECK (guest_email IS NULL OR topic_id IS NOT NULL),
+    CONSTRAINT unique_sub_topic_user UNIQUE (topic_id, user_id),
+    CONSTRAINT unique_sub_pattern_user UNIQUE (pattern, user_id),
+    CONSTRAINT unique_sub_topic_guest UNIQUE (topic_id, guest_email),
+    CONSTRAINT unique_sub_topic_group UNIQUE (topic_id, group_id),
+    CONSTRAINT unique_sub_pattern_group UNIQUE (pattern, group_id)
+);
+
+CREATE INDEX IF NOT EXISTS subscriptions_pattern_root_idx ON subscriptions (pattern_root) WHERE pattern IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS verifications (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NULL REFERENCES users (id) ON DELETE CASCADE,
+    subscription_id TEXT NULL REFERENCES subscriptions (id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    code TEXT NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expired_at TIMESTAMP NOT NULL DEFAULT (datetime(CURRENT_TIMESTAMP, '+15 minutes')),
+    CONSTRAINT verification_target CHECK ((user_id IS NULL) <> (subscription_id IS NULL))
+);
+
+CREATE TABLE IF NOT EXISTS mfa_secrets (
+    user_id TEXT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    last_used_step INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    hashed_code TEXT NOT NULL,
+    used BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_recovery_code_user UNIQUE (user_id, hashed_code)
+);
+
+CREATE TABLE IF NOT EXISTS passkeys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    credential_id BLOB NOT NULL,
+    public_key BLOB NOT NULL,
+    attestation_type TEXT NOT NULL,
+    aaguid BLOB NOT NULL,
+    sign_count INTEGER NOT NULL DEFAULT 0,
+    transports TEXT NOT NULL DEFAULT '[]',
+    backup_eligible BOOLEAN NOT NULL DEFAULT false,
+    backup_state BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP NULL,
+    CONSTRAINT unique_passkey_credential UNIQUE (credential_id)
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_ceremonies (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NULL REFERENCES users (id) ON DELETE CASCADE,
+    data BLOB NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS oidc_states (
+    id TEXT PRIMARY KEY,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hashed_secret TEXT NOT NULL,
+    scopes TEXT NOT NULL DEFAULT '[]',
+    expires_at TIMESTAMP NULL,
+    last_used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    CONSTRAINT unique_api_key_prefix UNIQUE (prefix)
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL DEFAULT '[]',
+    built_in BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL
+);
+
+INSERT INTO roles
+    (name, description, permissions, built_in, updated_at)
+VALUES
+    (
+        'ADMIN',
+        'Manages every resource in the system.',
+        '["topic:create","topic:read","notification:create","notification:read","notification:delete","subscription:create","subscription:read","subscription:delete","subscription:manage","user:create","user:read","user:update","user:delete","role:read","role:manage","topic:manage","group:read","group:manage"]',
+        true,
+        CURRENT_TIMESTAMP
+    ),
+    (
+        'USER',
+        'Publishes notifications and manages their own subscriptions.',
+        '["topic:create","topic:read","notification:create","notification:read","subscription:create","subscription:read","subscription:delete","group:read"]',
+        true,
+        CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS topic_acls (
+    topic_id TEXT NOT NULL REFERENCES topics (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    rights TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    PRIMARY KEY (topic_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS topic_acls_user_id_idx ON topic_acls (user_id);
+
+CREATE TABLE IF NOT EXISTS topic_invitations (
+    id TEXT PRIMARY KEY,
+    topic_id TEXT NOT NULL REFERENCES topics (id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    rights TEXT NOT NULL DEFAULT '[]',
+    hashed_token TEXT NOT NULL,
+    invited_by TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS topic_invitations_topic_id_idx ON topic_invitations (topic_id);
+
+CREATE TABLE IF NOT EXISTS topic_deletions (
+    topic_id TEXT PRIMARY KEY REFERENCES topics (id) ON DELETE CASCADE,
+    hashed_token TEXT NOT NULL,
+    requested_by TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS inbox (
+    notification_id TEXT NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, notification_id)
+);
+
+CREATE INDEX IF NOT EXISTS inbox_user_id_created_at_idx ON inbox (user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS subscription_jobs (
+    id TEXT PRIMARY KEY,
+    status TEXT NOT NULL DEFAULT 'pending',
+    rows TEXT NOT NULL DEFAULT '[]',
+    processed INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    existing INTEGER NOT NULL DEFAULT 0,
+    errors TEXT NOT NULL DEFAULT '[]',
+    created_by TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
@@ -0,0 +1,84 @@
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+DELETE FROM notifications WHERE deleted_at IS NOT NULL;
+DELETE FROM subscriptions WHERE deleted_at IS NOT NULL;
+DELETE FROM topics WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS notifications_deleted_at_idx;
+ALTER TABLE notifications DROP COLUMN deleted_at;
+
+CREATE TABLE subscriptions_old (
+    id TEXT PRIMARY KEY,
+    topic_id TEXT NULL REFERENCES topics (id) ON DELETE CASCADE,
+    pattern TEXT NULL,
+    pattern_root TEXT NULL,
+    pattern_regex TEXT NULL,
+    user_id TEXT NULL REFERENCES users (id) ON DELETE CASCADE,
+    guest_email TEXT NULL,
+    group_id TEXT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    confirmed BOOLEAN NOT NULL DEFAULT true,
+    filter TEXT NOT NULL DEFAULT '{}',
+    routes TEXT NOT NULL DEFAULT '[]',
+    muted BOOLEAN NOT NULL DEFAULT false,
+    muted_until TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    CONSTRAINT subscription_target CHECK ((topic_id IS NULL) <> (pattern IS NULL)),
+    CONSTRAINT subscription_owner CHECK ((user_id IS NOT NULL) + (guest_email IS NOT NULL) + (group_id IS NOT NULL) = 1),
+    CONSTRAINT guest_subscription_topic CHECK (guest_email IS NULL OR topic_id IS NOT NULL),
+    CONSTRAINT unique_sub_topic_user UNIQUE (topic_id, user_id),
+    CONSTRAINT unique_sub_pattern_user UNIQUE (pattern, user_id),
+    CONSTRAINT unique_sub_topic_guest UNIQUE (topic_id, guest_email),
+    CONSTRAINT unique_sub_topic_group UNIQUE (topic_id, group_id),
+    CONSTRAINT unique_sub_pattern_group UNIQUE (pattern, group_id)
+);
+
+INSERT INTO subscriptions_old (id, topic_id, pattern, pattern_root, pattern_regex, user_id, guest_email, group_id, confirmed, filter, routes, muted, muted_until, created_at, updated_at)
+SELECT id, topic_id, pattern, pattern_root, pattern_regex, user_id, guest_email, group_id, confirmed, filter, routes, muted, muted_until, created_at, updated_at FROM subscriptions;
+
+DROP TABLE subscriptions;
+ALTER TABLE subscriptions_old RENAME TO subscriptions;
+
+CREATE INDEX subscriptions_pattern_root_idx ON subscriptions (pattern_root) WHERE pattern IS NOT NULL;
+
+CREATE TABLE topics_old (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+    visibility TEXT NOT NULL DEFAULT 'public',
+    archived_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    CONSTRAINT unique_topic_name UNIQUE (name)
+);
+
+INSERT INTO topics_old (id, name, description, owner_id, visibility, archived_at, created_at, updated_at)
+SELECT id, name, description, owner_id, visibility, archived_at, created_at, updated_at FROM topics;
+
+DROP TABLE topics;
+ALTER TABLE topics_old RENAME TO topics;
+
+CREATE TABLE users_old (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    roles TEXT NOT NULL DEFAULT '[]',
+    hashed_password BLOB NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT false,
+    channels TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL
+);
+
+INSERT INTO users_old (id, email, roles, hashed_password, verified, channels, created_at, updated_at)
+SELECT id, email, roles, hashed_password, verified, channels, created_at, updated_at FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
@@ -0,0 +1,93 @@
+-- SQLite can't drop a unique constraint, so the tables are rebuilt with
+-- unique indexes covering only the rows that aren't deleted. Foreign keys are
+-- turned off while rebuilding, since dropping the old tables would cascade to
+-- the rows referencing them otherwise.
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE users_new (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL,
+    roles TEXT NOT NULL DEFAULT '[]',
+    hashed_password BLOB NOT NULL,
+    verified BOOLEAN NOT NULL DEFAULT false,
+    channels TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL
+);
+
+INSERT INTO users_new (id, email, roles, hashed_password, verified, channels, created_at, updated_at)
+SELECT id, email, roles, hashed_password, verified, channels, created_at, updated_at FROM users;
+
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE UNIQUE INDEX users_email_key ON users (email) WHERE deleted_at IS NULL;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE topics_new (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id TEXT NULL REFERENCES users (id) ON DELETE SET NULL,
+    visibility TEXT NOT NULL DEFAULT 'public',
+    archived_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL
+);
+
+INSERT INTO topics_new (id, name, description, owner_id, visibility, archived_at, created_at, updated_at)
+SELECT id, name, description, owner_id, visibility, archived_at, created_at, updated_at FROM topics;
+
+DROP TABLE topics;
+ALTER TABLE topics_new RENAME TO topics;
+
+CREATE UNIQUE INDEX unique_topic_name ON topics (name) WHERE deleted_at IS NULL;
+CREATE INDEX topics_deleted_at_idx ON topics (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE subscriptions_new (
+    id TEXT PRIMARY KEY,
+    topic_id TEXT NULL REFERENCES topics (id) ON DELETE CASCADE,
+    pattern TEXT NULL,
+    pattern_root TEXT NULL,
+    pattern_regex TEXT NULL,
+    user_id TEXT NULL REFERENCES users (id) ON DELETE CASCADE,
+    guest_email TEXT NULL,
+    group_id TEXT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    confirmed BOOLEAN NOT NULL DEFAULT true,
+    filter TEXT NOT NULL DEFAULT '{}',
+    routes TEXT NOT NULL DEFAULT '[]',
+    muted BOOLEAN NOT NULL DEFAULT false,
+    muted_until TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NULL,
+    deleted_at TIMESTAMP NULL,
+    CONSTRAINT subscription_target CHECK ((topic_id IS NULL) <> (pattern IS NULL)),
+    CONSTRAINT subscription_owner CHECK ((user_id IS NOT NULL) + (guest_email IS NOT NULL) + (group_id IS NOT NULL) = 1),
+    CONSTRAINT guest_subscription_topic CHECK (guest_email IS NULL OR topic_id IS NOT NULL)
+);
+
+INSERT INTO subscriptions_new (id, topic_id, pattern, pattern_root, pattern_regex, user_id, guest_email, group_id, confirmed, filter, routes, muted, muted_until, created_at, updated_at)
+SELECT id, topic_id, pattern, pattern_root, pattern_regex, user_id, guest_email, group_id, confirmed, filter, routes, muted, muted_until, created_at, updated_at FROM subscriptions;
+
+DROP TABLE subscriptions;
+ALTER TABLE subscriptions_new RENAME TO subscriptions;
+
+CREATE INDEX subscriptions_pattern_root_idx ON subscriptions (pattern_root) WHERE pattern IS NOT NULL;
+CREATE UNIQUE INDEX unique_sub_topic_user ON subscriptions (topic_id, user_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_pattern_user ON subscriptions (pattern, user_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_topic_guest ON subscriptions (topic_id, guest_email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_topic_group ON subscriptions (topic_id, group_id) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX unique_sub_pattern_group ON subscriptions (pattern, group_id) WHERE deleted_at IS NULL;
+CREATE INDEX subscriptions_deleted_at_idx ON subscriptions (deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE notifications ADD COLUMN deleted_at TIMESTAMP NULL;
+
+CREATE INDEX notifications_deleted_at_idx ON notifications (deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
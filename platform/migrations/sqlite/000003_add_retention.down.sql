@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP INDEX IF EXISTS notifications_created_at_idx;
+DROP INDEX IF EXISTS verifications_expired_at_idx;
+DROP INDEX IF EXISTS sessions_expires_at_idx;
+
+ALTER TABLE topics DROP COLUMN retention_seconds;
+
+COMMIT;
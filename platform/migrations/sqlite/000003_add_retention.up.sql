@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Topics keep their notifications for the global retention period unless
+-- they have one of their own.
+ALTER TABLE topics ADD COLUMN retention_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- The purge tasks look rows up by the time they expire.
+CREATE INDEX sessions_expires_at_idx ON sessions (expires_at);
+CREATE INDEX verifications_expired_at_idx ON verifications (expired_at);
+CREATE INDEX notifications_created_at_idx ON notifications (created_at);
+
+COMMIT;
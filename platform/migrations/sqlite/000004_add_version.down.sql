@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE groups DROP COLUMN version;
+ALTER TABLE subscriptions DROP COLUMN version;
+ALTER TABLE topics DROP COLUMN version;
+ALTER TABLE users DROP COLUMN version;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Updates only go through when the row is still at the version they read,
+-- so concurrent edits don't silently overwrite each other.
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE topics ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE subscriptions ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE groups ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+COMMIT;
@@ -0,0 +1,4 @@
+UPDATE roles
+SET permissions = (
+    SELECT json_group_array(value) FROM json_each(roles.permissions) WHERE value <> 'mfa:required'
+);
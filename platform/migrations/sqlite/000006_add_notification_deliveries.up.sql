@@ -0,0 +1,9 @@
+-- Deliveries made of a notification are recorded, so that retrying the
+-- delivery only sends it where it hasn't gone yet.
+CREATE TABLE IF NOT EXISTS notification_deliveries (
+    notification_id TEXT NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+    channel TEXT NOT NULL,
+    address TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (notification_id, channel, address)
+);
@@ -0,0 +1,9 @@
+-- Deliveries of a notification skipped, like those of muted subscriptions,
+-- are recorded along with the reason, so they can be audited.
+CREATE TABLE IF NOT EXISTS notification_skips (
+    notification_id TEXT NOT NULL REFERENCES notifications (id) ON DELETE CASCADE,
+    subscription_id TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (notification_id, subscription_id)
+);
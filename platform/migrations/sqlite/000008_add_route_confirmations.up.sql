@@ -0,0 +1,4 @@
+-- Email addresses and phone numbers given for subscriptions and default
+-- channels are confirmed like guest subscriptions, with the channel of the
+-- address kept along.
+ALTER TABLE verifications ADD COLUMN channel TEXT NULL;
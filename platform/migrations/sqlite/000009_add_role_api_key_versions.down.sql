@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE api_keys DROP COLUMN version;
+ALTER TABLE roles DROP COLUMN version;
+
+COMMIT;
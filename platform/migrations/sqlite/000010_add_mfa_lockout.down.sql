@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE mfa_secrets DROP COLUMN locked_until;
+ALTER TABLE mfa_secrets DROP COLUMN failed_attempts;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS notification_skips_id_key;
+ALTER TABLE notification_skips DROP COLUMN id;
+DROP INDEX IF EXISTS notification_deliveries_id_key;
+ALTER TABLE notification_deliveries DROP COLUMN id;
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Deliveries and skipped deliveries get an ID, so that their log can be paged
+-- through with a cursor like the other lists. Rows recorded before get random
+-- ones.
+ALTER TABLE notification_deliveries ADD COLUMN id TEXT NULL;
+UPDATE notification_deliveries SET id = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(6)));
+CREATE UNIQUE INDEX IF NOT EXISTS notification_deliveries_id_key ON notification_deliveries (id);
+
+ALTER TABLE notification_skips ADD COLUMN id TEXT NULL;
+UPDATE notification_skips SET id = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(2)) || '-' || hex(randomblob(6)));
+CREATE UNIQUE INDEX IF NOT EXISTS notification_skips_id_key ON notification_skips (id);
+
+COMMIT;
@@ -0,0 +1,59 @@
+// Package order provides support for describing the ordering of data.
+package order
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Set of directions for data ordering.
+const (
+	ASC  = "ASC"
+	DESC = "DESC"
+)
+
+// ErrInvalidOrder is returned when the order isn't in the expected format or
+// uses a field that can't be ordered by.
+var ErrInvalidOrder = errors.New("invalid order")
+
+// By represents a field used to order by and the direction.
+type By struct {
+	Field     string
+	Direction string
+}
+
+// NewBy constructs a new By value with no checks.
+func NewBy(field string, direction string) By {
+	return By{
+		Field:     field,
+		Direction: direction,
+	}
+}
+
+// Parse parses the order from the value, given as field,direction like
+// created_at,desc. The direction is optional and ascending by default. The
+// field has to be one of the fields, and an empty value gives the default.
+func Parse(value string, defaultOrder By, fields ...string) (By, error) {
+	if value == "" {
+		return defaultOrder, nil
+	}
+
+	field, direction, _ := strings.Cut(value, ",")
+	field = strings.TrimSpace(field)
+
+	if !slices.Contains(fields, field) {
+		return By{}, fmt.Errorf("%w: unknown field %q", ErrInvalidOrder, field)
+	}
+
+	switch direction = strings.ToUpper(strings.TrimSpace(direction)); direction {
+	case "":
+		direction = ASC
+	case ASC, DESC:
+	default:
+		return By{}, fmt.Errorf("%w: unknown direction %q", ErrInvalidOrder, direction)
+	}
+
+	return NewBy(field, direction), nil
+}